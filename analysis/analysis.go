@@ -0,0 +1,86 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package analysis examines results for things of interest beyond the mere
+// existence of a resource, attaching findings to the results.
+package analysis
+
+import (
+	"github.com/Matir/webborer/results"
+)
+
+// A Rule examines a result and attaches any findings to it.
+type Rule interface {
+	// Name of the rule, used to identify its findings
+	Name() string
+	// Check the result, adding findings as appropriate
+	Check(*results.Result)
+}
+
+// RuleFunc adapts a function to the Rule interface.
+type RuleFunc struct {
+	RuleName string
+	F        func(string, *results.Result)
+}
+
+func (r RuleFunc) Name() string {
+	return r.RuleName
+}
+
+func (r RuleFunc) Check(res *results.Result) {
+	r.F(r.RuleName, res)
+}
+
+// Analyzer runs a set of rules over each result passing through it.
+type Analyzer struct {
+	rules []Rule
+	// Status codes the scan reports
+	report results.ReportCodes
+}
+
+func (a *Analyzer) SetReportCodes(codes results.ReportCodes) {
+	a.report = codes
+}
+
+func NewAnalyzer(rules ...Rule) *Analyzer {
+	return &Analyzer{rules: rules}
+}
+
+// Add additional rules to the analyzer.
+func (a *Analyzer) AddRules(rules ...Rule) {
+	a.rules = append(a.rules, rules...)
+}
+
+// Run all rules against a single result.  Results that will not be reported
+// are not analyzed.
+func (a *Analyzer) Analyze(r *results.Result) {
+	if !a.report.Result(r) {
+		return
+	}
+	for _, rule := range a.rules {
+		rule.Check(r)
+	}
+}
+
+func (a *Analyzer) Process(in <-chan *results.Result) <-chan *results.Result {
+	out := make(chan *results.Result, cap(in))
+	go func() {
+		defer close(out)
+		for r := range in {
+			a.Analyze(r)
+			out <- r
+		}
+	}()
+	return out
+}
@@ -0,0 +1,71 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Products identified by the Shodan-style hash of their default favicon.
+var FaviconProducts = map[int32]string{
+	116323821:   "Spring Boot",
+	81586312:    "Jenkins",
+	-335242539:  "F5 BIG-IP",
+	945408572:   "Fortinet FortiGate",
+	-1292923998: "Citrix Gateway",
+	1768726119:  "Microsoft Outlook Web App",
+	1485257654:  "SonarQube",
+	2123863676:  "Grafana",
+	1278323681:  "GitLab",
+	-305179312:  "Atlassian Confluence",
+}
+
+// Identify the product a favicon hash belongs to, or "" if it is unknown.
+func LookupFavicon(hash int32) string {
+	return FaviconProducts[hash]
+}
+
+// Add favicon hashes from a file to FaviconProducts, one per line as
+// "<hash> <product>".  Blank lines and lines starting with # are ignored.
+func LoadFaviconDB(path string) error {
+	fp, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	scanner := bufio.NewScanner(fp)
+	lineno := 0
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) < 2 {
+			return fmt.Errorf("Invalid favicon entry on line %d.", lineno)
+		}
+		hash, err := strconv.ParseInt(fields[0], 10, 32)
+		if err != nil {
+			return fmt.Errorf("Invalid favicon hash on line %d: %s", lineno, fields[0])
+		}
+		FaviconProducts[int32(hash)] = strings.TrimSpace(fields[1])
+	}
+	return scanner.Err()
+}
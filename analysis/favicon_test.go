@@ -0,0 +1,47 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadFaviconDB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "favicon")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "favicons.txt")
+	ioutil.WriteFile(path, []byte("# Test products\n\n-12345 Some Router\n678 Another Thing\n"), 0644)
+	if err := LoadFaviconDB(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer delete(FaviconProducts, -12345)
+	defer delete(FaviconProducts, 678)
+	if LookupFavicon(-12345) != "Some Router" || LookupFavicon(678) != "Another Thing" {
+		t.Errorf("Expected loaded products, got %v", FaviconProducts)
+	}
+	if LookupFavicon(116323821) != "Spring Boot" {
+		t.Error("Expected built-in products to remain.")
+	}
+	ioutil.WriteFile(path, []byte("notahash Product\n"), 0644)
+	if err := LoadFaviconDB(path); err == nil {
+		t.Error("Expected error for invalid hash.")
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"github.com/Matir/webborer/results"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Headers that disclose the technology or version in use
+var disclosureHeaders = []string{
+	"X-Powered-By",
+	"X-AspNet-Version",
+	"X-AspNetMvc-Version",
+	"X-Generator",
+}
+
+// Headers only expected from applications in debug mode
+var debugHeaders = []string{
+	"X-Debug-Token",
+	"X-Debug-Token-Link",
+	"X-Debug",
+	"X-Debug-Info",
+	"X-ChromeLogger-Data",
+	"X-ChromePhp-Data",
+	"X-Backend-Server",
+}
+
+// Security headers expected on HTML responses
+var securityHeaders = []string{
+	"Content-Security-Policy",
+	"X-Content-Type-Options",
+	"X-Frame-Options",
+}
+
+var internalNets []*net.IPNet
+
+func init() {
+	for _, cidr := range []string{
+		"10.0.0.0/8",
+		"172.16.0.0/12",
+		"192.168.0.0/16",
+		"127.0.0.0/8",
+		"169.254.0.0/16",
+		"fc00::/7",
+		"fe80::/10",
+		"::1/128",
+	} {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		internalNets = append(internalNets, n)
+	}
+}
+
+var ipv4Pattern = regexp.MustCompile(`\b\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}\b`)
+
+// Rules that examine response headers
+var HeaderRules = []Rule{
+	RuleFunc{"cors-wildcard", checkCORS},
+	RuleFunc{"missing-security-headers", checkSecurityHeaders},
+	RuleFunc{"technology-disclosure", checkDisclosure},
+	RuleFunc{"internal-address", checkInternalAddress},
+	RuleFunc{"debug-headers", checkDebugHeaders},
+}
+
+// Attach a finding from a header rule, with the header line that gave rise to
+// it as evidence.
+func reportHeader(r *results.Result, rule string, severity results.Severity, evidence, format string, args ...interface{}) {
+	f := results.NewFinding(results.CategoryHeaders, rule, severity, format, args...)
+	f.Evidence = evidence
+	r.Report(f)
+}
+
+func checkCORS(name string, r *results.Result) {
+	origin := r.ResponseHeader.Get("Access-Control-Allow-Origin")
+	if origin != "*" {
+		return
+	}
+	if strings.EqualFold(r.ResponseHeader.Get("Access-Control-Allow-Credentials"), "true") {
+		reportHeader(r, name, results.SeverityHigh, "Access-Control-Allow-Credentials: true", "CORS allows any origin with credentials")
+		return
+	}
+	reportHeader(r, name, results.SeverityMedium, "Access-Control-Allow-Origin: *", "CORS allows any origin")
+}
+
+func checkSecurityHeaders(name string, r *results.Result) {
+	if !strings.HasPrefix(strings.ToLower(r.ContentType), "text/html") {
+		return
+	}
+	if r.Code < 200 || r.Code >= 300 {
+		return
+	}
+	missing := make([]string, 0)
+	for _, h := range securityHeaders {
+		if r.ResponseHeader.Get(h) == "" {
+			missing = append(missing, h)
+		}
+	}
+	if r.URL.Scheme == "https" && r.ResponseHeader.Get("Strict-Transport-Security") == "" {
+		missing = append(missing, "Strict-Transport-Security")
+	}
+	if len(missing) > 0 {
+		reportHeader(r, name, results.SeverityLow, "", "Missing security headers: %s", strings.Join(missing, ", "))
+	}
+}
+
+func checkDisclosure(name string, r *results.Result) {
+	for _, h := range disclosureHeaders {
+		if v := r.ResponseHeader.Get(h); v != "" {
+			reportHeader(r, name, results.SeverityInfo, h+": "+v, "%s: %s", h, v)
+		}
+	}
+}
+
+func checkDebugHeaders(name string, r *results.Result) {
+	for _, h := range debugHeaders {
+		if v := r.ResponseHeader.Get(h); v != "" {
+			reportHeader(r, name, results.SeverityMedium, h+": "+v, "Debug header %s: %s", h, v)
+		}
+	}
+}
+
+func checkInternalAddress(name string, r *results.Result) {
+	if loc := r.ResponseHeader.Get("Location"); loc != "" {
+		if u, err := url.Parse(loc); err == nil && u.Host != "" {
+			if ip := net.ParseIP(u.Hostname()); ip != nil && isInternalIP(ip) && !isInternalHost(r.URL) {
+				reportHeader(r, name, results.SeverityMedium, "Location: "+loc, "Internal address in Location: %s", loc)
+			}
+		}
+	}
+	for _, h := range []string{"Via", "X-Forwarded-For", "X-Real-IP", "X-Backend-Server"} {
+		for _, v := range r.ResponseHeader[h] {
+			for _, addr := range ipv4Pattern.FindAllString(v, -1) {
+				if ip := net.ParseIP(addr); ip != nil && isInternalIP(ip) && !isInternalHost(r.URL) {
+					reportHeader(r, name, results.SeverityMedium, h+": "+v, "Internal address in %s: %s", h, v)
+					break
+				}
+			}
+		}
+	}
+}
+
+func isInternalIP(ip net.IP) bool {
+	for _, n := range internalNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Internal addresses aren't interesting when scanning an internal host.
+func isInternalHost(u *url.URL) bool {
+	if u == nil {
+		return false
+	}
+	ip := net.ParseIP(u.Hostname())
+	return ip != nil && isInternalIP(ip)
+}
@@ -0,0 +1,125 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"github.com/Matir/webborer/results"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func headerResult(scheme string, header map[string]string) *results.Result {
+	r := &results.Result{
+		URL:            &url.URL{Scheme: scheme, Host: "example.com", Path: "/"},
+		Code:           200,
+		ResponseHeader: make(http.Header),
+	}
+	for k, v := range header {
+		r.ResponseHeader.Set(k, v)
+	}
+	r.ContentType = r.ResponseHeader.Get("Content-Type")
+	return r
+}
+
+func findingRules(r *results.Result) map[string]results.Severity {
+	rules := make(map[string]results.Severity)
+	for _, f := range r.Findings {
+		rules[f.Rule] = f.Severity
+	}
+	return rules
+}
+
+func TestHeaderRules(t *testing.T) {
+	secure := map[string]string{
+		"Content-Type":              "text/html",
+		"Content-Security-Policy":   "default-src 'self'",
+		"X-Content-Type-Options":    "nosniff",
+		"X-Frame-Options":           "DENY",
+		"Strict-Transport-Security": "max-age=31536000",
+	}
+	cases := []struct {
+		scheme   string
+		header   map[string]string
+		expected map[string]results.Severity
+	}{
+		{"https", secure, map[string]results.Severity{}},
+		{"https", map[string]string{"Content-Type": "text/html"}, map[string]results.Severity{
+			"missing-security-headers": results.SeverityLow,
+		}},
+		{"http", map[string]string{"Access-Control-Allow-Origin": "*"}, map[string]results.Severity{
+			"cors-wildcard": results.SeverityMedium,
+		}},
+		{"http", map[string]string{
+			"Access-Control-Allow-Origin":      "*",
+			"Access-Control-Allow-Credentials": "true",
+		}, map[string]results.Severity{
+			"cors-wildcard": results.SeverityHigh,
+		}},
+		{"http", map[string]string{"X-Powered-By": "PHP/5.4"}, map[string]results.Severity{
+			"technology-disclosure": results.SeverityInfo,
+		}},
+		{"http", map[string]string{"Location": "http://10.1.2.3/login"}, map[string]results.Severity{
+			"internal-address": results.SeverityMedium,
+		}},
+		{"http", map[string]string{"Via": "1.1 192.168.1.1 (squid)"}, map[string]results.Severity{
+			"internal-address": results.SeverityMedium,
+		}},
+		{"http", map[string]string{"Via": "1.1 8.8.8.8"}, map[string]results.Severity{}},
+		{"http", map[string]string{"X-Debug-Token": "abc123"}, map[string]results.Severity{
+			"debug-headers": results.SeverityMedium,
+		}},
+	}
+	analyzer := NewAnalyzer(HeaderRules...)
+	for i, c := range cases {
+		r := headerResult(c.scheme, c.header)
+		analyzer.Analyze(r)
+		found := findingRules(r)
+		if len(found) != len(c.expected) {
+			t.Errorf("Case %d: expected %v, got %v", i, c.expected, found)
+			continue
+		}
+		for rule, sev := range c.expected {
+			if found[rule] != sev {
+				t.Errorf("Case %d: expected %s for %s, got %s", i, sev, rule, found[rule])
+			}
+		}
+	}
+}
+
+func TestHeaderRules_Evidence(t *testing.T) {
+	r := headerResult("http", map[string]string{"X-Powered-By": "PHP/5.4"})
+	NewAnalyzer(HeaderRules...).Analyze(r)
+	if len(r.Findings) != 1 {
+		t.Fatalf("Expected one finding, got %v", r.Findings)
+	}
+	f := r.Findings[0]
+	if f.Category != results.CategoryHeaders || f.Evidence != "X-Powered-By: PHP/5.4" || f.URL != r.URL {
+		t.Errorf("Unexpected finding %+v", f)
+	}
+}
+
+func TestAnalyzer_SkipsUnreported(t *testing.T) {
+	r := headerResult("http", map[string]string{"X-Powered-By": "PHP"})
+	r.Code = 404
+	in := make(chan *results.Result, 1)
+	in <- r
+	close(in)
+	for r := range NewAnalyzer(HeaderRules...).Process(in) {
+		if len(r.Findings) != 0 {
+			t.Errorf("Expected no findings for 404, got %v", r.Findings)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/task"
+	"gopkg.in/yaml.v2"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Bytes of each body searched for a rule's content
+const sensitiveBodySize = 64 * 1024
+
+// Bytes of matching content quoted as evidence
+const sensitiveEvidenceSize = 40
+
+// A SensitiveRule confirms a known sensitive file by its content.
+type SensitiveRule struct {
+	Name        string
+	Severity    results.Severity
+	Description string
+	// Paths the file is found at.  URLs whose path ends with one match.
+	Paths []string
+	// Content that confirms the file
+	Match *regexp.Regexp
+}
+
+// A rule as written in a rules file
+type sensitiveRuleSpec struct {
+	Name        string   `yaml:"name"`
+	Severity    string   `yaml:"severity"`
+	Description string   `yaml:"description"`
+	Paths       []string `yaml:"paths"`
+	Match       string   `yaml:"match"`
+}
+
+// Parse a YAML list of rules, like:
+//
+//	# sensitive.yaml
+//	- name: dotenv
+//	  severity: high
+//	  description: Environment file exposing configuration and secrets
+//	  paths: [/.env, /.env.local]
+//	  match: '(?m)^[A-Z][A-Z0-9_]*\s*='
+func ParseSensitiveRules(data []byte) ([]*SensitiveRule, error) {
+	var specs []sensitiveRuleSpec
+	if err := yaml.Unmarshal(data, &specs); err != nil {
+		return nil, err
+	}
+	rules := make([]*SensitiveRule, 0, len(specs))
+	for i, spec := range specs {
+		if spec.Name == "" {
+			return nil, fmt.Errorf("Rule %d has no name.", i+1)
+		}
+		if len(spec.Paths) == 0 || spec.Match == "" {
+			return nil, fmt.Errorf("Rule %s needs paths and a match.", spec.Name)
+		}
+		severity, err := results.ParseSeverity(spec.Severity)
+		if err != nil {
+			return nil, fmt.Errorf("Rule %s: %s", spec.Name, err.Error())
+		}
+		match, err := regexp.Compile(spec.Match)
+		if err != nil {
+			return nil, fmt.Errorf("Rule %s: %s", spec.Name, err.Error())
+		}
+		rules = append(rules, &SensitiveRule{
+			Name:        spec.Name,
+			Severity:    severity,
+			Description: spec.Description,
+			Paths:       spec.Paths,
+			Match:       match,
+		})
+	}
+	return rules, nil
+}
+
+// Load rules from a YAML file, or the built-in rules if path is empty.
+func LoadSensitiveRules(path string) ([]*SensitiveRule, error) {
+	if path == "" {
+		return ParseSensitiveRules([]byte(DefaultSensitiveRules))
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSensitiveRules(data)
+}
+
+// Whether the rule covers a URL path
+func (r *SensitiveRule) Covers(path string) bool {
+	for _, p := range r.Paths {
+		if strings.HasSuffix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// SensitiveChecker is a page worker confirming known sensitive files by their
+// content, attaching a finding for each one confirmed.
+type SensitiveChecker struct {
+	rules []*SensitiveRule
+}
+
+func NewSensitiveChecker(rules []*SensitiveRule) (*SensitiveChecker, error) {
+	if len(rules) == 0 {
+		return nil, errors.New("No sensitive file rules given.")
+	}
+	return &SensitiveChecker{rules: rules}, nil
+}
+
+func (c *SensitiveChecker) Eligible(resp *http.Response) bool {
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
+
+func (c *SensitiveChecker) Handle(t *task.Task, body io.Reader, result *results.Result) {
+	var candidates []*SensitiveRule
+	for _, rule := range c.rules {
+		if rule.Covers(t.URL.Path) {
+			candidates = append(candidates, rule)
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+	content, err := ioutil.ReadAll(io.LimitReader(body, sensitiveBodySize))
+	if err != nil {
+		return
+	}
+	for _, rule := range candidates {
+		if match := rule.Match.Find(content); match != nil {
+			f := results.NewFinding(results.CategorySensitiveFile, rule.Name, rule.Severity, "%s", rule.Description)
+			f.Evidence = sensitiveEvidence(match)
+			result.Report(f)
+			result.Confidence = results.ConfidenceVerified
+		}
+	}
+}
+
+// Quote the content that matched a rule, shortened so secrets it contains
+// aren't reproduced in full.
+func sensitiveEvidence(match []byte) string {
+	if len(match) > sensitiveEvidenceSize {
+		return fmt.Sprintf("%q...", match[:sensitiveEvidenceSize])
+	}
+	return fmt.Sprintf("%q", match)
+}
+
+// Paths to request in each starting directory to look for the files, without
+// their leading slash.  Rules matching only by suffix, like ".sql", have none.
+func (c *SensitiveChecker) ProbePaths() []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, rule := range c.rules {
+		for _, p := range rule.Paths {
+			if !strings.HasPrefix(p, "/") || seen[p] {
+				continue
+			}
+			seen[p] = true
+			paths = append(paths, strings.TrimPrefix(p, "/"))
+		}
+	}
+	return paths
+}
@@ -0,0 +1,163 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+// Built-in rules for SensitiveChecker, in the format read by
+// ParseSensitiveRules.
+var DefaultSensitiveRules = `
+- name: dotenv
+  severity: high
+  description: Environment file exposing configuration and secrets
+  paths: [/.env, /.env.local, /.env.production, /.env.dev, /.env.backup, /.env.bak]
+  match: '(?m)^[A-Z][A-Z0-9_]*\s*=\s*\S'
+
+- name: git-config
+  severity: high
+  description: Git repository exposed, the source and its history may be downloadable
+  paths: [/.git/config]
+  match: '\[core\]'
+
+- name: git-head
+  severity: high
+  description: Git repository exposed, the source and its history may be downloadable
+  paths: [/.git/HEAD]
+  match: '^ref: refs/'
+
+- name: svn
+  severity: medium
+  description: Subversion working copy exposed, file names and source may be downloadable
+  paths: [/.svn/entries, /.svn/wc.db]
+  match: '^(\d+\s|SQLite format 3)'
+
+- name: ds-store
+  severity: low
+  description: macOS folder metadata listing the files in the directory
+  paths: [/.DS_Store]
+  match: "^\x00\x00\x00\x01Bud1"
+
+- name: htpasswd
+  severity: high
+  description: Password file with hashes that may be cracked
+  paths: [/.htpasswd]
+  match: '(?m)^[^:\s]+:(\$apr1\$|\$2[aby]\$|\{SHA\}|\$[56]\$)'
+
+- name: phpinfo
+  severity: medium
+  description: phpinfo() output disclosing configuration, paths and environment
+  paths: [/phpinfo.php, /info.php, /php_info.php, /php.php, /_profiler/phpinfo]
+  match: 'phpinfo\(\)|<h1 class="p">PHP Version'
+
+- name: wp-config-backup
+  severity: high
+  description: Backup of the WordPress configuration with database credentials
+  paths: [/wp-config.php.bak, /wp-config.php~, /wp-config.php.old, /wp-config.php.save, /wp-config.bak, /wp-config.txt]
+  match: 'DB_PASSWORD'
+
+- name: sql-dump
+  severity: high
+  description: Database dump
+  paths: [.sql, /dump.sql.txt]
+  match: '(?i)(CREATE TABLE|INSERT INTO|-- MySQL dump|PostgreSQL database dump)'
+
+- name: private-key
+  severity: high
+  description: Private key
+  paths: [/id_rsa, /id_dsa, /id_ecdsa, /id_ed25519, /.ssh/id_rsa, /server.key, /private.key, .pem]
+  match: '-----BEGIN (RSA |DSA |EC |OPENSSH |ENCRYPTED )?PRIVATE KEY-----'
+
+- name: aws-credentials
+  severity: high
+  description: AWS credentials file
+  paths: [/.aws/credentials]
+  match: '(?i)aws_secret_access_key'
+
+- name: docker-config
+  severity: high
+  description: Docker client configuration with registry credentials
+  paths: [/.docker/config.json]
+  match: '"auths"\s*:'
+
+- name: npmrc
+  severity: high
+  description: npm configuration with a registry token
+  paths: [/.npmrc]
+  match: '_authToken\s*='
+
+- name: actuator-env
+  severity: high
+  description: Spring Boot actuator exposing the environment, often including secrets
+  paths: [/actuator/env, /env]
+  match: '"(activeProfiles|propertySources)"'
+
+- name: actuator-heapdump
+  severity: high
+  description: Spring Boot actuator heap dump, containing memory of the application
+  paths: [/actuator/heapdump, /heapdump]
+  match: '^JAVA PROFILE'
+
+- name: actuator
+  severity: medium
+  description: Spring Boot actuator endpoints listed
+  paths: [/actuator]
+  match: '"_links"\s*:\s*\{'
+
+- name: werkzeug-console
+  severity: high
+  description: Werkzeug debugger console, which can execute code
+  paths: [/console]
+  match: '__debugger__|Werkzeug'
+
+- name: laravel-ignition
+  severity: medium
+  description: Laravel Ignition debug endpoint
+  paths: [/_ignition/health-check]
+  match: '"can_execute_commands"'
+
+- name: symfony-profiler
+  severity: medium
+  description: Symfony profiler disclosing requests and configuration
+  paths: [/_profiler, /app_dev.php/_profiler]
+  match: 'Symfony Profiler|sf-toolbar'
+
+- name: server-status
+  severity: medium
+  description: Apache server status disclosing client addresses and requests
+  paths: [/server-status]
+  match: 'Apache Server Status for'
+
+- name: elmah
+  severity: medium
+  description: ELMAH error log disclosing errors and request details
+  paths: [/elmah.axd]
+  match: 'Error Log for'
+
+- name: trace-axd
+  severity: medium
+  description: ASP.NET trace disclosing requests and their details
+  paths: [/trace.axd]
+  match: 'Application Trace'
+
+- name: openapi
+  severity: low
+  description: API definition describing the available endpoints
+  paths: [/swagger.json, /openapi.json, /v2/api-docs, /v3/api-docs, /swagger/v1/swagger.json]
+  match: '"(swagger|openapi)"\s*:'
+
+- name: crossdomain
+  severity: low
+  description: Flash cross-domain policy allowing any domain
+  paths: [/crossdomain.xml]
+  match: 'allow-access-from\s+domain="\*"'
+`
@@ -0,0 +1,109 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/task"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func defaultChecker(t *testing.T) *SensitiveChecker {
+	rules, err := LoadSensitiveRules("")
+	if err != nil {
+		t.Fatalf("Unable to parse built-in rules: %v", err)
+	}
+	checker, err := NewSensitiveChecker(rules)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return checker
+}
+
+func checkBody(checker *SensitiveChecker, path, body string) *results.Result {
+	tsk := task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: path})
+	r := results.NewResultForTask(tsk)
+	r.Code = 200
+	checker.Handle(tsk, strings.NewReader(body), r)
+	return r
+}
+
+func TestSensitiveChecker(t *testing.T) {
+	checker := defaultChecker(t)
+	if !checker.Eligible(&http.Response{StatusCode: 200}) || checker.Eligible(&http.Response{StatusCode: 404}) {
+		t.Error("Expected only successful responses to be eligible.")
+	}
+	cases := []struct {
+		path, body, rule string
+	}{
+		{"/.env", "APP_KEY=base64:abc\nDB_PASSWORD=secret\n", "dotenv"},
+		{"/.env", "<html><body>Not found</body></html>", ""},
+		{"/app/.git/config", "[core]\n\trepositoryformatversion = 0\n", "git-config"},
+		{"/.DS_Store", "\x00\x00\x00\x01Bud1\x00\x00", "ds-store"},
+		{"/backup/db.sql", "-- MySQL dump 10.13\nCREATE TABLE users", "sql-dump"},
+		{"/actuator/env", `{"activeProfiles":["prod"]}`, "actuator-env"},
+		{"/index.html", "APP_KEY=abc", ""},
+	}
+	for _, c := range cases {
+		r := checkBody(checker, c.path, c.body)
+		if c.rule == "" {
+			if len(r.Findings) != 0 {
+				t.Errorf("%s: expected no findings, got %v", c.path, r.Findings)
+			}
+			continue
+		}
+		if len(r.Findings) != 1 || r.Findings[0].Rule != c.rule {
+			t.Errorf("%s: expected %s finding, got %v", c.path, c.rule, r.Findings)
+			continue
+		}
+		if r.Confidence != results.ConfidenceVerified {
+			t.Errorf("%s: expected verified confidence, got %s", c.path, r.Confidence)
+		}
+	}
+}
+
+func TestSensitiveChecker_ProbePaths(t *testing.T) {
+	paths := defaultChecker(t).ProbePaths()
+	seen := make(map[string]bool)
+	for _, p := range paths {
+		if strings.HasPrefix(p, "/") || strings.HasPrefix(p, ".sql") || seen[p] {
+			t.Errorf("Unexpected probe path %q", p)
+		}
+		seen[p] = true
+	}
+	if !seen[".env"] || !seen["actuator/env"] {
+		t.Errorf("Expected .env and actuator/env to be probed, got %v", paths)
+	}
+}
+
+func TestParseSensitiveRules_Invalid(t *testing.T) {
+	for _, data := range []string{
+		"- name: x\n  severity: high\n  paths: [/x]\n",
+		"- name: x\n  severity: dire\n  paths: [/x]\n  match: x\n",
+		"- name: x\n  severity: low\n  paths: [/x]\n  match: '('\n",
+		"- severity: low\n  paths: [/x]\n  match: x\n",
+		"not: a list",
+	} {
+		if _, err := ParseSensitiveRules([]byte(data)); err == nil {
+			t.Errorf("Expected error parsing %q", data)
+		}
+	}
+	if _, err := NewSensitiveChecker(nil); err == nil {
+		t.Error("Expected error without rules.")
+	}
+}
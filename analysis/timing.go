@@ -0,0 +1,113 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"fmt"
+	"github.com/Matir/webborer/results"
+	"sort"
+	"time"
+)
+
+const (
+	// Response times kept for each host
+	timingWindow = 200
+	// Response times needed from a host before any are flagged
+	timingMinSamples = 20
+	// How many scaled median absolute deviations above the median a
+	// response time must be
+	timingDeviations = 6
+	// How far above the median a response time must be, so hosts with very
+	// steady times don't flag small differences
+	timingMinDelta = 500 * time.Millisecond
+	// Scales the median absolute deviation to estimate a standard deviation
+	madScale = 1.4826
+)
+
+// TimingAnomalies flags results whose response time is far above what is
+// usual for their host, as judged from the results before them.  Slow
+// responses often mean heavy processing in the backend, such as a query
+// run on some input, which is worth following up by hand.
+type TimingAnomalies struct {
+	// Recent response times for each host, oldest first
+	hosts map[string][]time.Duration
+	// Status codes the scan reports
+	report results.ReportCodes
+}
+
+func (a *TimingAnomalies) SetReportCodes(codes results.ReportCodes) {
+	a.report = codes
+}
+
+func NewTimingAnomalies() *TimingAnomalies {
+	return &TimingAnomalies{hosts: make(map[string][]time.Duration)}
+}
+
+func (a *TimingAnomalies) Process(in <-chan *results.Result) <-chan *results.Result {
+	out := make(chan *results.Result, cap(in))
+	go func() {
+		defer close(out)
+		for r := range in {
+			a.Check(r)
+			out <- r
+		}
+	}()
+	return out
+}
+
+// Check a single result against the times for its host, then add its time
+// to them.  Every result counts towards the baseline, but only results that
+// will be reported are flagged.
+func (a *TimingAnomalies) Check(r *results.Result) {
+	// Results without timings, such as those from remote workers
+	if r.Duration <= 0 {
+		return
+	}
+	host := r.Host
+	if host == "" {
+		host = r.URL.Host
+	}
+	times := a.hosts[host]
+	if len(times) >= timingMinSamples && a.report.Result(r) {
+		median, mad := medianDeviation(times)
+		delta := r.Duration - median
+		if delta >= timingMinDelta && float64(delta) > timingDeviations*madScale*float64(mad) {
+			f := results.NewFinding(results.CategoryTiming, "timing-anomaly", results.SeverityInfo, "Response took %s, against %s usual for the host",
+				r.Duration.Round(time.Millisecond), median.Round(time.Millisecond))
+			f.Evidence = fmt.Sprintf("%s over %d responses, deviation %s", median.Round(time.Millisecond), len(times), mad.Round(time.Millisecond))
+			r.Report(f)
+		}
+	}
+	if len(times) >= timingWindow {
+		times = times[1:]
+	}
+	a.hosts[host] = append(times, r.Duration)
+}
+
+// Get the median of times and the median absolute deviation from it.
+func medianDeviation(times []time.Duration) (time.Duration, time.Duration) {
+	sorted := append([]time.Duration(nil), times...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	median := sorted[len(sorted)/2]
+	for i, d := range sorted {
+		if d < median {
+			sorted[i] = median - d
+		} else {
+			sorted[i] = d - median
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return median, sorted[len(sorted)/2]
+}
@@ -0,0 +1,70 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"github.com/Matir/webborer/results"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func timedResult(host string, code int, d time.Duration) *results.Result {
+	return &results.Result{
+		URL:      &url.URL{Scheme: "http", Host: host, Path: "/"},
+		Code:     code,
+		Duration: d,
+	}
+}
+
+func TestTimingAnomalies(t *testing.T) {
+	a := NewTimingAnomalies()
+	// Too few samples to judge
+	early := timedResult("a", 200, 5*time.Second)
+	a.Check(early)
+	if len(early.Findings) != 0 {
+		t.Errorf("Expected no findings before a baseline, got %v", early.Findings)
+	}
+	for i := 0; i < timingMinSamples; i++ {
+		a.Check(timedResult("a", 404, time.Duration(100+i%10)*time.Millisecond))
+	}
+	slow := timedResult("a", 200, 3*time.Second)
+	a.Check(slow)
+	if len(slow.Findings) != 1 || slow.Findings[0].Rule != "timing-anomaly" {
+		t.Errorf("Expected a timing anomaly, got %v", slow.Findings)
+	}
+	normal := timedResult("a", 200, 150*time.Millisecond)
+	a.Check(normal)
+	if len(normal.Findings) != 0 {
+		t.Errorf("Expected no findings for a usual time, got %v", normal.Findings)
+	}
+	// Each host has its own baseline
+	other := timedResult("b", 200, 3*time.Second)
+	a.Check(other)
+	if len(other.Findings) != 0 {
+		t.Errorf("Expected no findings without a baseline for the host, got %v", other.Findings)
+	}
+}
+
+func TestMedianDeviation(t *testing.T) {
+	times := []time.Duration{1, 2, 3, 4, 100}
+	median, mad := medianDeviation(times)
+	if median != 3 || mad != 1 {
+		t.Errorf("Expected median 3 and deviation 1, got %d and %d", median, mad)
+	}
+	if times[4] != 100 {
+		t.Error("Expected times not to be modified.")
+	}
+}
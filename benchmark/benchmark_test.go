@@ -0,0 +1,78 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package benchmark
+
+import (
+	"github.com/Matir/webborer/worker"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServer(t *testing.T) {
+	s, err := NewServer()
+	if err != nil {
+		t.Fatalf("Unable to start server: %v", err)
+	}
+	defer s.Close()
+	cases := map[string]int{
+		"":                 http.StatusOK,
+		"admin/":           http.StatusOK,
+		"admin/login.php":  http.StatusOK,
+		"admin/secret.php": http.StatusNotFound,
+		"missing":          http.StatusNotFound,
+	}
+	for path, code := range cases {
+		resp, err := http.Get(s.URL() + path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != code {
+			t.Errorf("/%s: expected %d, got %d", path, code, resp.StatusCode)
+		}
+		if path == "admin/" && !strings.Contains(string(body), `href="/admin/login.php"`) {
+			t.Errorf("Expected links in directory page, got %q", body)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	timings := worker.NewTimings()
+	for i := 0; i < 10; i++ {
+		timings.Add(worker.StageRequest, time.Millisecond)
+	}
+	timings.Add(worker.StageBody, time.Millisecond)
+	timings.Add(worker.StageWait, 20*time.Millisecond)
+	report := &Report{Elapsed: time.Second, Workers: 2, Timings: timings, Mallocs: 100}
+	if report.Rate() != 10 {
+		t.Errorf("Expected 10 req/s, got %f", report.Rate())
+	}
+	if report.Bottleneck() != worker.StageWait {
+		t.Errorf("Expected waiting for work as the bottleneck, got %s", report.Bottleneck())
+	}
+	buf := &strings.Builder{}
+	if err := report.WriteSummary(buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{"10.0 req/s", "10 per request", "requests:", "queue is the limit"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %q in summary:\n%s", want, buf.String())
+		}
+	}
+}
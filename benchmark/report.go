@@ -0,0 +1,126 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package benchmark
+
+import (
+	"fmt"
+	"github.com/Matir/webborer/worker"
+	"io"
+	"runtime"
+	"time"
+)
+
+// Report describes how fast a scan ran and where its workers spent their
+// time.
+type Report struct {
+	Elapsed time.Duration
+	Workers int
+	Timings *worker.Timings
+	// Allocations and bytes allocated during the scan
+	Mallocs    uint64
+	AllocBytes uint64
+	GCs        uint32
+}
+
+// Measure a scan: call Start before it begins and Finish once it's done.
+func Start(workers int, timings *worker.Timings) *Measurement {
+	m := &Measurement{report: &Report{Workers: workers, Timings: timings}}
+	runtime.ReadMemStats(&m.before)
+	m.start = time.Now()
+	return m
+}
+
+// Measurement is a Report in progress.
+type Measurement struct {
+	report *Report
+	start  time.Time
+	before runtime.MemStats
+}
+
+func (m *Measurement) Finish() *Report {
+	m.report.Elapsed = time.Since(m.start)
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	m.report.Mallocs = after.Mallocs - m.before.Mallocs
+	m.report.AllocBytes = after.TotalAlloc - m.before.TotalAlloc
+	m.report.GCs = after.NumGC - m.before.NumGC
+	return m.report
+}
+
+// Requests per second
+func (r *Report) Rate() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Timings.Requests()) / r.Elapsed.Seconds()
+}
+
+// The stage the workers spent the most time in
+func (r *Report) Bottleneck() worker.Stage {
+	best := worker.StageRequest
+	for _, stage := range worker.WorkerStages() {
+		if r.Timings.Duration(stage) > r.Timings.Duration(best) {
+			best = stage
+		}
+	}
+	return best
+}
+
+// What the bottleneck means for tuning
+var bottleneckAdvice = map[worker.Stage]string{
+	worker.StageWait:    "Workers are mostly idle waiting for work; the tool's queue is the limit, and fewer workers would do as well.",
+	worker.StageRequest: "Workers are mostly waiting for responses; the target or network is the limit.  More workers may help if the target can take them.",
+	worker.StageBody:    "Workers are mostly reading and parsing bodies; try -headers-only or disabling HTML parsing.",
+	worker.StageResults: "Workers are mostly waiting for results to be processed; result stages or output are the limit.",
+}
+
+func (r *Report) WriteSummary(w io.Writer) error {
+	requests := r.Timings.Requests()
+	perRequest := func(n uint64) uint64 {
+		if requests == 0 {
+			return 0
+		}
+		return n / uint64(requests)
+	}
+	fmt.Fprintf(w, "Requests:    %d in %s with %d workers\n", requests, r.Elapsed.Round(time.Millisecond), r.Workers)
+	fmt.Fprintf(w, "Rate:        %.1f req/s\n", r.Rate())
+	fmt.Fprintf(w, "Allocations: %d per request, %d bytes per request, %d GCs\n", perRequest(r.Mallocs), perRequest(r.AllocBytes), r.GCs)
+	total := time.Duration(0)
+	for _, stage := range worker.WorkerStages() {
+		total += r.Timings.Duration(stage)
+	}
+	fmt.Fprintf(w, "Worker time:\n")
+	for _, stage := range worker.WorkerStages() {
+		d := r.Timings.Duration(stage)
+		pct := 0.0
+		if total > 0 {
+			pct = 100 * float64(d) / float64(total)
+		}
+		avg := time.Duration(0)
+		if requests > 0 {
+			avg = d / time.Duration(requests)
+		}
+		fmt.Fprintf(w, "  %-18s %5.1f%%  %s per request\n", stage.String()+":", pct, avg)
+	}
+	fmt.Fprintf(w, "Time per task (p50/p99):\n")
+	for _, stage := range worker.Stages() {
+		if r.Timings.Count(stage) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "  %-18s %s/%s\n", stage.String()+":", r.Timings.Percentile(stage, 50), r.Timings.Percentile(stage, 99))
+	}
+	_, err := fmt.Fprintf(w, "%s\n", bottleneckAdvice[r.Bottleneck()])
+	return err
+}
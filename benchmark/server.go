@@ -0,0 +1,100 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package benchmark measures how fast scans run and what limits them.
+package benchmark
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Paths that exist on the test server.  Directories link to everything
+// below them, so spidering finds them all.
+var serverPaths = []string{
+	"/",
+	"/admin/",
+	"/admin/index.php",
+	"/admin/login.php",
+	"/images/",
+	"/images/logo.png",
+	"/index.html",
+	"/js/",
+	"/js/app.js",
+	"/robots.txt",
+}
+
+// Server is a local web server for measuring scans without a target's own
+// limits getting in the way.  A few paths exist; everything else is a 404.
+type Server struct {
+	ln     net.Listener
+	server *http.Server
+	pages  map[string]string
+}
+
+// Start a Server on a free local port.
+func NewServer() (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{ln: ln, pages: makePages()}
+	s.server = &http.Server{Handler: s}
+	go s.server.Serve(ln)
+	return s, nil
+}
+
+// Base URL of the server
+func (s *Server) URL() string {
+	return "http://" + s.ln.Addr().String() + "/"
+}
+
+func (s *Server) Close() error {
+	return s.server.Close()
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	page, ok := s.pages[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if strings.HasSuffix(r.URL.Path, "/") || strings.HasSuffix(r.URL.Path, ".html") {
+		w.Header().Set("Content-Type", "text/html")
+	}
+	fmt.Fprint(w, page)
+}
+
+// Build the body of each path
+func makePages() map[string]string {
+	pages := make(map[string]string, len(serverPaths))
+	for _, dir := range serverPaths {
+		if !strings.HasSuffix(dir, "/") {
+			pages[dir] = fmt.Sprintf("Contents of %s\n", dir)
+			continue
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "<html><head><title>Index of %s</title></head><body><ul>\n", dir)
+		for _, p := range serverPaths {
+			if p != dir && strings.HasPrefix(p, dir) {
+				fmt.Fprintf(&b, "<li><a href=\"%s\">%s</a></li>\n", p, p)
+			}
+		}
+		b.WriteString("</ul></body></html>\n")
+		pages[dir] = b.String()
+	}
+	return pages
+}
@@ -0,0 +1,70 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package browser
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Renderer loads pages in headless Chrome and returns the DOM after scripts
+// have run, so links added by client-side code can be found.
+//
+// Chrome makes its own requests, so custom headers, cookies and the Host
+// override are not applied to rendered pages.
+type Renderer struct {
+	chrome  string
+	timeout time.Duration
+	// Time to allow scripts to run after load
+	budget time.Duration
+	run    commandRunner
+}
+
+func NewRenderer(chrome string) (*Renderer, error) {
+	chrome, err := findChrome(chrome)
+	if err != nil {
+		return nil, err
+	}
+	return &Renderer{
+		chrome:  chrome,
+		timeout: defaultTimeout,
+		budget:  5 * time.Second,
+		run:     runCommand,
+	}, nil
+}
+
+// Render a page, returning the serialized DOM.
+func (r *Renderer) Render(u *url.URL) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	args := []string{
+		"--headless",
+		"--disable-gpu",
+		"--virtual-time-budget=" + durationMillis(r.budget),
+		"--dump-dom",
+		u.String(),
+	}
+	out, err := r.run(ctx, r.chrome, args...)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func durationMillis(d time.Duration) string {
+	return strconv.FormatInt(int64(d/time.Millisecond), 10)
+}
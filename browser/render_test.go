@@ -0,0 +1,52 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package browser
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRenderer_Render(t *testing.T) {
+	var gotArgs []string
+	r := &Renderer{
+		chrome:  "chrome",
+		timeout: defaultTimeout,
+		run: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			gotArgs = args
+			return []byte("<html></html>"), nil
+		},
+	}
+	u := &url.URL{Scheme: "http", Host: "localhost", Path: "/"}
+	dom, err := r.Render(u)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if dom != "<html></html>" {
+		t.Errorf("Unexpected DOM: %s", dom)
+	}
+	if gotArgs[len(gotArgs)-1] != u.String() || !strings.Contains(strings.Join(gotArgs, " "), "--dump-dom") {
+		t.Errorf("Unexpected args: %v", gotArgs)
+	}
+	r.run = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return nil, errors.New("failed")
+	}
+	if _, err := r.Render(u); err == nil {
+		t.Error("Expected error from failed render.")
+	}
+}
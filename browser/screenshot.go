@@ -0,0 +1,197 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package browser drives a headless browser to capture screenshots of
+// results for visual triage.
+package browser
+
+import (
+	"context"
+	"fmt"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/results"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultWidth  = 1280
+	defaultHeight = 800
+	// Longest name derived from a URL for a screenshot file
+	maxNameLength = 100
+	// Screenshots taken at once
+	defaultCaptures = 4
+	// Hits waiting for a screenshot before more are passed on without one
+	defaultCaptureBacklog = 64
+)
+
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Screenshotter captures a screenshot of each hit passing through it using
+// headless Chrome, and records the path on the result.  Screenshots are taken
+// by a small pool of goroutines, so other results pass straight through, and
+// hits arriving while the pool is backed up are passed on without one.
+type Screenshotter struct {
+	chrome string
+	dir    string
+	// Directory the screenshot paths are made relative to
+	linkBase string
+	timeout  time.Duration
+	width    int
+	height   int
+	count    int64
+	// Screenshots taken at once
+	captures int
+	// Hits waiting for a screenshot before more are skipped
+	backlog int
+	run     commandRunner
+	// Status codes the scan reports
+	report results.ReportCodes
+}
+
+func (s *Screenshotter) SetReportCodes(codes results.ReportCodes) {
+	s.report = codes
+}
+
+// Create a Screenshotter that saves images to dir.  If chrome is empty, the
+// PATH is searched for a Chrome or Chromium binary.
+func NewScreenshotter(chrome, dir string) (*Screenshotter, error) {
+	chrome, err := findChrome(chrome)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Screenshotter{
+		chrome:   chrome,
+		dir:      dir,
+		timeout:  defaultTimeout,
+		width:    defaultWidth,
+		height:   defaultHeight,
+		captures: defaultCaptures,
+		backlog:  defaultCaptureBacklog,
+		run:      runCommand,
+	}, nil
+}
+
+// Make recorded screenshot paths relative to dir, such as the directory
+// containing an HTML report.
+func (s *Screenshotter) SetLinkBase(dir string) {
+	s.linkBase = dir
+}
+
+func (s *Screenshotter) Process(in <-chan *results.Result) <-chan *results.Result {
+	out := make(chan *results.Result, cap(in))
+	pending := make(chan *results.Result, s.backlog)
+	wg := sync.WaitGroup{}
+	for i := 0; i < s.captures; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range pending {
+				s.Capture(r)
+				out <- r
+			}
+		}()
+	}
+	go func() {
+		skipped := 0
+		for r := range in {
+			if s.Interesting(r) {
+				select {
+				case pending <- r:
+					continue
+				default:
+					skipped++
+				}
+			}
+			out <- r
+		}
+		close(pending)
+		wg.Wait()
+		if skipped > 0 {
+			logging.Logf(logging.LogWarning, "Skipped screenshots of %d hits while capture was backed up.", skipped)
+		}
+		close(out)
+	}()
+	return out
+}
+
+// Check if a result is worth a screenshot.  Redirects and errors are not.
+func (s *Screenshotter) Interesting(r *results.Result) bool {
+	return s.report.Result(r) && r.Redir == nil && r.Code < 400
+}
+
+// Take a screenshot of a single result.
+func (s *Screenshotter) Capture(r *results.Result) {
+	count := atomic.AddInt64(&s.count, 1)
+	name := fmt.Sprintf("%05d_%s.png", count, screenshotName(r))
+	path := filepath.Join(s.dir, name)
+	abspath, err := filepath.Abs(path)
+	if err != nil {
+		abspath = path
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	args := []string{
+		"--headless",
+		"--disable-gpu",
+		"--hide-scrollbars",
+		fmt.Sprintf("--window-size=%d,%d", s.width, s.height),
+		"--screenshot=" + abspath,
+		r.URL.String(),
+	}
+	logging.Logf(logging.LogDebug, "Capturing screenshot of %s", r.URL.String())
+	if _, err := s.run(ctx, s.chrome, args...); err != nil {
+		logging.Logf(logging.LogWarning, "Unable to capture screenshot of %s: %s", r.URL.String(), err.Error())
+		return
+	}
+	if _, err := os.Stat(abspath); err != nil {
+		logging.Logf(logging.LogWarning, "No screenshot captured for %s", r.URL.String())
+		return
+	}
+	r.Screenshot = s.link(path)
+}
+
+func (s *Screenshotter) link(path string) string {
+	if s.linkBase == "" {
+		return path
+	}
+	abspath, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	base, err := filepath.Abs(s.linkBase)
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(base, abspath); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// Build a filesystem-safe name from a result's URL.
+func screenshotName(r *results.Result) string {
+	name := unsafeNameChars.ReplaceAllString(r.URL.Host+r.URL.Path, "_")
+	if len(name) > maxNameLength {
+		name = name[:maxNameLength]
+	}
+	return name
+}
@@ -0,0 +1,136 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Matir/webborer/results"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func fakeChrome(fail bool) commandRunner {
+	return func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if fail {
+			return nil, errors.New("chrome failed")
+		}
+		for _, a := range args {
+			if strings.HasPrefix(a, "--screenshot=") {
+				return nil, ioutil.WriteFile(strings.TrimPrefix(a, "--screenshot="), []byte("png"), 0644)
+			}
+		}
+		return nil, errors.New("no screenshot flag")
+	}
+}
+
+func testScreenshotter(t *testing.T, fail bool) (*Screenshotter, string) {
+	dir, err := ioutil.TempDir("", "webborer")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	s, err := NewScreenshotter("/usr/bin/true", filepath.Join(dir, "shots"))
+	if err != nil {
+		t.Fatalf("Unable to create screenshotter: %v", err)
+	}
+	s.run = fakeChrome(fail)
+	s.SetLinkBase(dir)
+	return s, dir
+}
+
+func TestScreenshotter_Process(t *testing.T) {
+	s, dir := testScreenshotter(t, false)
+	defer os.RemoveAll(dir)
+	in := make(chan *results.Result, 3)
+	hit := &results.Result{URL: &url.URL{Scheme: "http", Host: "localhost:8080", Path: "/admin/index.php"}, Code: 200}
+	missing := &results.Result{URL: &url.URL{Scheme: "http", Host: "localhost", Path: "/x"}, Code: 404}
+	redir := &results.Result{URL: &url.URL{Scheme: "http", Host: "localhost", Path: "/y"}, Code: 302, Redir: &url.URL{Path: "/z"}}
+	in <- hit
+	in <- missing
+	in <- redir
+	close(in)
+	for range s.Process(in) {
+	}
+	expected := "shots/00001_localhost_8080_admin_index.php.png"
+	if hit.Screenshot != expected {
+		t.Errorf("Expected screenshot %s, got %s", expected, hit.Screenshot)
+	}
+	if _, err := os.Stat(filepath.Join(dir, expected)); err != nil {
+		t.Errorf("Expected screenshot file: %v", err)
+	}
+	if missing.Screenshot != "" || redir.Screenshot != "" {
+		t.Error("Expected no screenshots of uninteresting results.")
+	}
+}
+
+func TestScreenshotter_Backlog(t *testing.T) {
+	s, dir := testScreenshotter(t, false)
+	defer os.RemoveAll(dir)
+	s.captures = 1
+	s.backlog = 1
+	release := make(chan bool)
+	run := s.run
+	s.run = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		<-release
+		return run(ctx, name, args...)
+	}
+	hits := make([]*results.Result, 5)
+	in := make(chan *results.Result, len(hits))
+	out := s.Process(in)
+	for i := range hits {
+		hits[i] = &results.Result{URL: &url.URL{Scheme: "http", Host: "localhost", Path: fmt.Sprintf("/%d", i)}, Code: 200}
+	}
+	// At most one is being captured and one waits, so the rest pass straight
+	// through.
+	for _, r := range hits {
+		in <- r
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-out:
+		case <-time.After(time.Second):
+			t.Fatal("Expected hits past the backlog to pass through.")
+		}
+	}
+	close(release)
+	close(in)
+	captured := 0
+	for range out {
+	}
+	for _, r := range hits {
+		if r.Screenshot != "" {
+			captured++
+		}
+	}
+	if captured < 1 || captured > 2 {
+		t.Errorf("Expected 1 or 2 screenshots, got %d", captured)
+	}
+}
+
+func TestScreenshotter_Failure(t *testing.T) {
+	s, dir := testScreenshotter(t, true)
+	defer os.RemoveAll(dir)
+	r := &results.Result{URL: &url.URL{Scheme: "http", Host: "localhost", Path: "/"}, Code: 200}
+	s.Capture(r)
+	if r.Screenshot != "" {
+		t.Errorf("Expected no screenshot on failure, got %s", r.Screenshot)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webborer
+
+import (
+	"github.com/Matir/webborer/client"
+	"github.com/Matir/webborer/logging"
+	ss "github.com/Matir/webborer/settings"
+	"net/url"
+)
+
+// Build an HTTP client factory configured according to settings.
+func NewClientFactory(settings *ss.ScanSettings) (*client.ProxyClientFactory, error) {
+	factory, err := client.NewProxyClientFactory(settings.Proxies, settings.Timeout, settings.UserAgent)
+	if err != nil {
+		return nil, err
+	}
+	factory.SetUsernamePassword(settings.HTTPUsername, settings.HTTPPassword)
+	if settings.CredentialsPath != "" {
+		creds, err := client.LoadCredentialsFile(settings.CredentialsPath)
+		if err != nil {
+			return nil, err
+		}
+		factory.SetCredentials(creds)
+	}
+	if settings.EngagementID != "" {
+		factory.SetFixedHeader(settings.EngagementHeader, settings.EngagementID)
+	}
+	if settings.TokenRefreshCommand != "" || settings.TokenRefreshURL != "" {
+		factory.SetTokenRefresher(client.NewTokenRefresher(settings.TokenRefreshCommand, settings.TokenRefreshURL, settings.Timeout))
+	}
+	if settings.HeaderProfile != "" {
+		profile, err := client.GetHeaderProfile(settings.HeaderProfile)
+		if err != nil {
+			return nil, err
+		}
+		factory.SetHeaderProfile(profile)
+	}
+	if settings.RandomAgent {
+		if settings.AgentRotation == ss.RotatePerScan {
+			factory.SetRandomFingerprint()
+		} else {
+			factory.SetRandomProfiles(settings.AgentRotation == ss.RotatePerRequest)
+		}
+	}
+	if len(settings.HeaderOrder) == 1 && settings.HeaderOrder[0] == "profile" {
+		factory.SetProfileHeaderOrder()
+	} else if len(settings.HeaderOrder) > 0 {
+		factory.SetHeaderOrder(settings.HeaderOrder)
+	}
+	if settings.RawPaths {
+		factory.SetRawTargets()
+	}
+	if settings.AbsoluteURI {
+		factory.SetAbsoluteURI()
+	}
+	if settings.AdaptKeepAlive {
+		factory.SetKeepAliveTracking()
+	}
+	if settings.NoKeepAlive {
+		factory.SetDisableKeepAlives()
+	}
+	factory.SetConnectionLimits(settings.MaxIdlePerHost, settings.MaxConnsPerHost)
+	if settings.TLSResumption {
+		factory.SetTLSSessionResumption()
+	}
+	if settings.HTTP2 {
+		factory.SetHTTP2Multiplexing(settings.HTTP2Conns, settings.HTTP2Streams)
+		if len(settings.HeaderOrder) > 0 || settings.RawPaths || settings.AbsoluteURI {
+			logging.Logf(logging.LogWarning, "-http2 is ignored, as the header order and raw path settings use HTTP/1.1.")
+		}
+		if len(settings.Proxies) > 0 {
+			logging.Logf(logging.LogWarning, "Requests via proxies are not multiplexed with -http2.")
+		}
+	}
+	if settings.SourcePorts != "" {
+		ports, err := client.ParsePortRange(settings.SourcePorts)
+		if err != nil {
+			return nil, err
+		}
+		factory.SetSourcePorts(ports)
+	}
+	// Without DNS settings, connections are left to net/http's dialer
+	dnsSettings := settings.Resolver != "" || len(settings.Resolve) > 0 || settings.PreferIPv4 || settings.PreferIPv6
+	if dnsSettings {
+		resolver := client.NewResolver(settings.Resolver, settings.Timeout)
+		for _, spec := range settings.Resolve {
+			if err := resolver.AddOverride(spec); err != nil {
+				return nil, err
+			}
+		}
+		if settings.PreferIPv4 {
+			resolver.SetPreferredFamily(4)
+		} else if settings.PreferIPv6 {
+			resolver.SetPreferredFamily(6)
+		}
+		factory.SetResolver(resolver)
+	}
+	if settings.UnixSocket != "" {
+		factory.SetDialer(client.NewUnixSocketDialer(settings.UnixSocket, settings.Timeout))
+	}
+	if len(settings.Proxies) > 0 && dnsSettings {
+		logging.Logf(logging.LogWarning, "DNS settings are not used for connections via proxies.")
+	}
+	factory.SetRequestTracing()
+	if settings.Cookies {
+		factory.SetCookieJar(client.NewIsolatedCookieJar(cookieKeyFunc(settings.CookieIsolation)))
+	}
+	return factory, nil
+}
+
+// Build the key function used to separate cookie jars
+func cookieKeyFunc(isolation ss.CookieIsolationOption) func(*url.URL) string {
+	switch isolation {
+	case ss.CookiesPerHost:
+		return client.HostCookieKey
+	case ss.CookiesPerGroup:
+		return client.HostnameCookieKey
+	}
+	return nil
+}
@@ -19,6 +19,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/util"
 	"net/http"
 	"net/url"
 	"strings"
@@ -46,6 +47,26 @@ type httpClient struct {
 	HTTPUsername string
 	HTTPPassword string
 	basicAuthStr string
+	// Header profile to send with every request
+	Profile *HeaderProfile
+	// Pick a new random profile for every request
+	RandomProfile bool
+	// Credentials for particular groups of hosts
+	Credentials CredentialSet
+	// Gets new bearer tokens when they stop working, if set
+	Refresher *TokenRefresher
+	// Adapts to servers that drop persistent connections
+	KeepAlive *KeepAliveTracker
+	// Send headers in the order of the header profile
+	ProfileOrder bool
+	// Send the request path without normalization or escaping
+	RawTarget bool
+	// Send the absolute URI in the request line
+	AbsoluteURI bool
+	// Trace each request's headers and timings
+	Trace bool
+	// Headers sent with every request, whatever else is sent
+	FixedHeader http.Header
 }
 
 // Request the URL given.
@@ -60,11 +81,20 @@ func (c *httpClient) RequestURL(u *url.URL) (*http.Response, error) {
 //
 // Handles HTTP Authentication & Custom Headers
 func (c *httpClient) Request(u *url.URL, host, method string, header http.Header) (*http.Response, error) {
-	req := c.makeRequest(u, method, host, header)
-	resp, err := c.Client.Do(req)
+	cred := c.credentialFor(u, host)
+	req, generation := c.makeAuthRequest(u, method, host, header, cred)
+	resp, err := c.do(req)
 	if err != nil {
 		return resp, err
 	}
+	// Retry once if the bearer token has been replaced
+	if generation >= 0 && c.Refresher.update(generation, u, resp) {
+		resp.Body.Close()
+		req, _ = c.makeAuthRequest(u, method, host, header, cred)
+		if resp, err = c.do(req); err != nil {
+			return resp, err
+		}
+	}
 	// Handle an authentication required response
 	if resp.StatusCode == 401 {
 		authHeader := resp.Header.Get("WWW-Authenticate")
@@ -72,17 +102,24 @@ func (c *httpClient) Request(u *url.URL, host, method string, header http.Header
 		if authHeader == "" {
 			return resp, nil
 		}
+		username, password := c.HTTPUsername, c.HTTPPassword
+		if cred != nil {
+			if cred.Type != CredentialBasic {
+				return resp, nil
+			}
+			username, password = cred.Username, cred.Password
+		}
 		// No U/P available
-		if c.HTTPUsername == "" && c.HTTPPassword == "" {
+		if username == "" && password == "" {
 			return resp, nil
 		}
 		req = c.makeRequest(u, method, host, header)
-		err = c.addAuthHeader(req, authHeader)
+		err = c.addAuthHeader(req, authHeader, username, password)
 		if err != nil {
 			logging.Logf(logging.LogInfo, err.Error())
 			return resp, nil
 		}
-		resp, err = c.Client.Do(req)
+		resp, err = c.do(req)
 		if err != nil {
 			return resp, err
 		}
@@ -90,19 +127,88 @@ func (c *httpClient) Request(u *url.URL, host, method string, header http.Header
 	return resp, nil
 }
 
+// Send a single request
+func (c *httpClient) do(req *http.Request) (*http.Response, error) {
+	if c.KeepAlive != nil {
+		return c.KeepAlive.Do(c.Client, req)
+	}
+	return c.Client.Do(req)
+}
+
+// Find the credentials for a request, using the Host override if given
+func (c *httpClient) credentialFor(u *url.URL, host string) *Credential {
+	if len(c.Credentials) == 0 {
+		return nil
+	}
+	if host == "" {
+		host = u.Host
+	}
+	return c.Credentials.ForHost(host)
+}
+
+// Build a request with the credentials sent without being asked for.  Returns
+// the generation of the bearer token sent, or -1 if there's no refresher or
+// bearer token.
+func (c *httpClient) makeAuthRequest(u *url.URL, method, host string, header http.Header, cred *Credential) (*http.Request, int) {
+	req := c.makeRequest(u, method, host, header)
+	if cred != nil {
+		cred.applyPreemptive(req.Header)
+	}
+	if c.Refresher == nil {
+		return req, -1
+	}
+	return req, c.Refresher.apply(req.Header)
+}
+
 // Build a request with our preferred options
 func (c *httpClient) makeRequest(u *url.URL, method, host string, header http.Header) *http.Request {
 	req, _ := http.NewRequest(method, u.String(), nil)
-	req.Host = host
+	req.Host = util.BracketHost(host)
 	if header != nil {
-		req.Header = header
+		// Copy so we never modify the task's headers
+		req.Header = header.Clone()
+	}
+	if profile := c.getProfile(); profile != nil {
+		profile.Apply(req.Header)
+		if c.ProfileOrder && profile.Order != nil {
+			req = req.WithContext(WithHeaderOrder(req.Context(), profile.Order))
+		}
 	}
 	if _, ok := req.Header["User-Agent"]; !ok {
 		req.Header.Set("User-Agent", c.UserAgent)
 	}
+	for k, v := range c.FixedHeader {
+		req.Header[k] = v
+	}
+	if c.RawTarget || c.AbsoluteURI {
+		req = req.WithContext(WithRequestTarget(req.Context(), c.requestTarget(u)))
+	}
+	if c.Trace {
+		req = withRequestTrace(req)
+	}
 	return req
 }
 
+// Get the request-target to send for u
+func (c *httpClient) requestTarget(u *url.URL) string {
+	target := u.RequestURI()
+	if c.RawTarget {
+		target = RawRequestTarget(u)
+	}
+	if c.AbsoluteURI {
+		target = u.Scheme + "://" + u.Host + target
+	}
+	return target
+}
+
+// Get the header profile for the next request, if any
+func (c *httpClient) getProfile() *HeaderProfile {
+	if c.RandomProfile {
+		return RandomHeaderProfile()
+	}
+	return c.Profile
+}
+
 func (c *httpClient) SetCheckRedirect(checker func(*http.Request, []*http.Request) error) {
 	cli, ok := c.Client.(*http.Client)
 	if !ok {
@@ -113,10 +219,15 @@ func (c *httpClient) SetCheckRedirect(checker func(*http.Request, []*http.Reques
 }
 
 // Add an authentication header in response to authHeader
-func (c *httpClient) addAuthHeader(req *http.Request, authHeader string) error {
+func (c *httpClient) addAuthHeader(req *http.Request, authHeader, username, password string) error {
 	pieces := strings.SplitN(authHeader, " ", 2)
 	if strings.ToLower(pieces[0]) == "basic" {
-		req.Header.Add("Authorization", "Basic "+c.getBasicAuthStr())
+		if username == c.HTTPUsername && password == c.HTTPPassword {
+			req.Header.Add("Authorization", "Basic "+c.getBasicAuthStr())
+		} else {
+			userpass := username + ":" + password
+			req.Header.Add("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(userpass)))
+		}
 		return nil
 	}
 	return fmt.Errorf("Unsupported WWW-Authenticate Method: %s", pieces[0])
@@ -0,0 +1,76 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+)
+
+// IsolatedCookieJar keeps a separate cookie jar for each key returned by its
+// key function.  This keeps session cookies for one target from ever being
+// sent to another, even if the targets share a parent domain.
+type IsolatedCookieJar struct {
+	keyFunc func(*url.URL) string
+	jars    map[string]*cookiejar.Jar
+	sync.Mutex
+}
+
+// Create a new IsolatedCookieJar.  If keyFunc is nil, all URLs share a single
+// jar.
+func NewIsolatedCookieJar(keyFunc func(*url.URL) string) *IsolatedCookieJar {
+	return &IsolatedCookieJar{
+		keyFunc: keyFunc,
+		jars:    make(map[string]*cookiejar.Jar),
+	}
+}
+
+// Key function to isolate cookies by host (including port).
+func HostCookieKey(u *url.URL) string {
+	return u.Host
+}
+
+// Key function to isolate cookies by host name, so the ports and schemes of a
+// host share cookies as they would in a browser.
+func HostnameCookieKey(u *url.URL) string {
+	return u.Hostname()
+}
+
+func (j *IsolatedCookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jarFor(u).SetCookies(u, cookies)
+}
+
+func (j *IsolatedCookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jarFor(u).Cookies(u)
+}
+
+// Get or create the jar for a URL
+func (j *IsolatedCookieJar) jarFor(u *url.URL) *cookiejar.Jar {
+	var key string
+	if j.keyFunc != nil {
+		key = j.keyFunc(u)
+	}
+	j.Lock()
+	defer j.Unlock()
+	if jar, ok := j.jars[key]; ok {
+		return jar
+	}
+	// cookiejar.New never returns an error with nil options
+	jar, _ := cookiejar.New(nil)
+	j.jars[key] = jar
+	return jar
+}
@@ -0,0 +1,46 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestIsolatedCookieJar_PerHost(t *testing.T) {
+	jar := NewIsolatedCookieJar(HostCookieKey)
+	a, _ := url.Parse("http://a.example.com/")
+	b, _ := url.Parse("http://b.example.com/")
+	cookie := &http.Cookie{Name: "session", Value: "x", Domain: "example.com"}
+	jar.SetCookies(a, []*http.Cookie{cookie})
+	if len(jar.Cookies(a)) != 1 {
+		t.Errorf("Expected cookie for %s", a)
+	}
+	if len(jar.Cookies(b)) != 0 {
+		t.Errorf("Cookie leaked to %s", b)
+	}
+}
+
+func TestIsolatedCookieJar_Shared(t *testing.T) {
+	jar := NewIsolatedCookieJar(nil)
+	a, _ := url.Parse("http://a.example.com/")
+	b, _ := url.Parse("http://b.example.com/")
+	cookie := &http.Cookie{Name: "session", Value: "x", Domain: "example.com"}
+	jar.SetCookies(a, []*http.Cookie{cookie})
+	if len(jar.Cookies(b)) != 1 {
+		t.Errorf("Expected shared cookie for %s", b)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// Types of credentials that may be assigned to a group of targets.
+const (
+	CredentialBasic  = "basic"
+	CredentialBearer = "bearer"
+	CredentialHeader = "header"
+)
+
+// A Credential applies to all hosts matching Pattern, a glob as understood by
+// path.Match (e.g. *.corp.example.com).
+type Credential struct {
+	Pattern string
+	Type    string
+	// For basic auth
+	Username string
+	Password string
+	// For bearer tokens
+	Token string
+	// For arbitrary headers
+	HeaderName  string
+	HeaderValue string
+}
+
+// A CredentialSet is an ordered list of credentials.  The first matching
+// pattern wins.
+type CredentialSet []*Credential
+
+// Load credentials from a file.
+func LoadCredentialsFile(filename string) (CredentialSet, error) {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	return ParseCredentials(fp)
+}
+
+// Parse credentials, one per line, in the format:
+//
+//	<host pattern> basic <username>:<password>
+//	<host pattern> bearer <token>
+//	<host pattern> header <name>: <value>
+//
+// Blank lines and lines starting with # are ignored.
+func ParseCredentials(rdr io.Reader) (CredentialSet, error) {
+	creds := make(CredentialSet, 0)
+	scanner := bufio.NewScanner(rdr)
+	lineno := 0
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("Credentials line %d: expected pattern, type, and value", lineno)
+		}
+		cred := &Credential{
+			Pattern: fields[0],
+			Type:    strings.ToLower(fields[1]),
+		}
+		if _, err := path.Match(cred.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("Credentials line %d: bad pattern %s: %s", lineno, cred.Pattern, err.Error())
+		}
+		value := strings.TrimSpace(fields[2])
+		switch cred.Type {
+		case CredentialBasic:
+			pieces := strings.SplitN(value, ":", 2)
+			if len(pieces) != 2 {
+				return nil, fmt.Errorf("Credentials line %d: basic credentials are username:password", lineno)
+			}
+			cred.Username, cred.Password = pieces[0], pieces[1]
+		case CredentialBearer:
+			cred.Token = value
+		case CredentialHeader:
+			pieces := strings.SplitN(value, ":", 2)
+			if len(pieces) != 2 {
+				return nil, fmt.Errorf("Credentials line %d: header credentials are name: value", lineno)
+			}
+			cred.HeaderName = strings.TrimSpace(pieces[0])
+			cred.HeaderValue = strings.TrimSpace(pieces[1])
+		default:
+			return nil, fmt.Errorf("Credentials line %d: unknown type %s", lineno, cred.Type)
+		}
+		creds = append(creds, cred)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
+// Find the credential for a host, which may include a port.  Returns nil if
+// no credential matches.
+func (cs CredentialSet) ForHost(host string) *Credential {
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	for _, cred := range cs {
+		if ok, _ := path.Match(cred.Pattern, host); ok {
+			return cred
+		}
+		if ok, _ := path.Match(cred.Pattern, hostname); ok {
+			return cred
+		}
+	}
+	return nil
+}
+
+// Add headers that are sent without being asked for.
+func (c *Credential) applyPreemptive(header http.Header) {
+	switch c.Type {
+	case CredentialBearer:
+		header.Set("Authorization", "Bearer "+c.Token)
+	case CredentialHeader:
+		header.Set(c.HeaderName, c.HeaderValue)
+	}
+}
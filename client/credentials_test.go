@@ -0,0 +1,101 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+var testCredentials = `
+# Customer A
+*.a.example.com basic user:pass
+api.b.example.org bearer abc123
+b.example.org:8443 header X-Api-Key: secret
+`
+
+func TestParseCredentials(t *testing.T) {
+	creds, err := ParseCredentials(strings.NewReader(testCredentials))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(creds) != 3 {
+		t.Fatalf("Expected 3 credentials, got %d", len(creds))
+	}
+	if creds[0].Username != "user" || creds[0].Password != "pass" {
+		t.Errorf("Bad basic credential: %v", creds[0])
+	}
+	if creds[2].HeaderName != "X-Api-Key" || creds[2].HeaderValue != "secret" {
+		t.Errorf("Bad header credential: %v", creds[2])
+	}
+}
+
+func TestParseCredentials_Invalid(t *testing.T) {
+	for _, line := range []string{"host", "host basic nocolon", "host digest x", "[ basic a:b"} {
+		if _, err := ParseCredentials(strings.NewReader(line)); err == nil {
+			t.Errorf("Expected error parsing %q", line)
+		}
+	}
+}
+
+func TestCredentialSet_ForHost(t *testing.T) {
+	creds, _ := ParseCredentials(strings.NewReader(testCredentials))
+	cases := map[string]string{
+		"www.a.example.com":      CredentialBasic,
+		"www.a.example.com:8080": CredentialBasic,
+		"api.b.example.org":      CredentialBearer,
+		"b.example.org:8443":     CredentialHeader,
+		"b.example.org":          "",
+	}
+	for host, ctype := range cases {
+		cred := creds.ForHost(host)
+		if ctype == "" {
+			if cred != nil {
+				t.Errorf("Expected no credential for %s", host)
+			}
+		} else if cred == nil || cred.Type != ctype {
+			t.Errorf("Expected %s credential for %s, got %v", ctype, host, cred)
+		}
+	}
+}
+
+func TestRequest_BearerCredential(t *testing.T) {
+	creds, _ := ParseCredentials(strings.NewReader(testCredentials))
+	mockResp := &http.Response{StatusCode: 200}
+	c := &httpClient{Client: makeMockHttpClient(mockResp), Credentials: creds}
+	u := &url.URL{Scheme: "http", Host: "api.b.example.org", Path: "/"}
+	resp, err := c.Request(u, "", "GET", nil)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if auth := resp.Request.Header.Get("Authorization"); auth != "Bearer abc123" {
+		t.Errorf("Expected bearer token, got %q", auth)
+	}
+}
+
+func TestRequest_BasicCredential(t *testing.T) {
+	creds, _ := ParseCredentials(strings.NewReader(testCredentials))
+	c := &httpClient{Client: &mockAuthHttpClient{}, Credentials: creds}
+	u := &url.URL{Scheme: "http", Host: "www.a.example.com", Path: "/"}
+	resp, err := c.Request(u, "", "GET", nil)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Errorf("Expected per-host basic auth to succeed, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A PortRange is an inclusive range of local ports.
+type PortRange struct {
+	Low  int
+	High int
+}
+
+// Parse a port range such as "40000-41000", or a single port.
+func ParsePortRange(s string) (*PortRange, error) {
+	pieces := strings.SplitN(s, "-", 2)
+	low, err := strconv.Atoi(strings.TrimSpace(pieces[0]))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid port range %q: %s", s, err.Error())
+	}
+	high := low
+	if len(pieces) == 2 {
+		if high, err = strconv.Atoi(strings.TrimSpace(pieces[1])); err != nil {
+			return nil, fmt.Errorf("Invalid port range %q: %s", s, err.Error())
+		}
+	}
+	if low < 1 || high > 65535 || low > high {
+		return nil, fmt.Errorf("Invalid port range %q.", s)
+	}
+	return &PortRange{Low: low, High: high}, nil
+}
+
+// Number of ports in the range
+func (r *PortRange) Size() int {
+	return r.High - r.Low + 1
+}
+
+func (r *PortRange) String() string {
+	return fmt.Sprintf("%d-%d", r.Low, r.High)
+}
+
+// A Dialer makes the connections for requests, for transports other than
+// plain TCP, such as SSH tunnels.  addr is the host and port of the URL.
+type Dialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// UnixSocketDialer connects to a Unix domain socket for every request,
+// whatever its URL, so URLs are interpreted against the server on the
+// socket.
+type UnixSocketDialer struct {
+	path    string
+	timeout time.Duration
+}
+
+func NewUnixSocketDialer(path string, timeout time.Duration) *UnixSocketDialer {
+	return &UnixSocketDialer{path: path, timeout: timeout}
+}
+
+func (d *UnixSocketDialer) DialContext(ctx context.Context, _, _ string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: d.timeout}
+	return dialer.DialContext(ctx, "unix", d.path)
+}
+
+// SourcePortDialer makes connections from local ports within a range,
+// rotating through the range so ports are reused as rarely as possible.
+type SourcePortDialer struct {
+	ports   *PortRange
+	timeout time.Duration
+	next    int
+	sync.Mutex
+}
+
+// Maximum number of ports to try for a single connection
+const maxSourcePortAttempts = 16
+
+func NewSourcePortDialer(ports *PortRange, timeout time.Duration) *SourcePortDialer {
+	return &SourcePortDialer{
+		ports:   ports,
+		timeout: timeout,
+	}
+}
+
+// Get the next port to try
+func (d *SourcePortDialer) nextPort() int {
+	d.Lock()
+	defer d.Unlock()
+	port := d.ports.Low + d.next
+	d.next = (d.next + 1) % d.ports.Size()
+	return port
+}
+
+func (d *SourcePortDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	attempts := d.ports.Size()
+	if attempts > maxSourcePortAttempts {
+		attempts = maxSourcePortAttempts
+	}
+	var err error
+	for i := 0; i < attempts; i++ {
+		dialer := &net.Dialer{
+			Timeout:   d.timeout,
+			LocalAddr: &net.TCPAddr{Port: d.nextPort()},
+		}
+		var conn net.Conn
+		if conn, err = dialer.DialContext(ctx, network, addr); err == nil {
+			return conn, nil
+		}
+		if !isAddrInUse(err) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("No source port available in %s: %s", d.ports.String(), err.Error())
+}
+
+// Check if a dial failed because the local address was in use.
+func isAddrInUse(err error) bool {
+	return strings.Contains(err.Error(), "address already in use") ||
+		strings.Contains(err.Error(), "can't assign requested address") ||
+		strings.Contains(err.Error(), "Only one usage of each socket address")
+}
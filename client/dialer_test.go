@@ -0,0 +1,119 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParsePortRange(t *testing.T) {
+	good := map[string]PortRange{
+		"40000-41000": {40000, 41000},
+		"8000":        {8000, 8000},
+		" 1 - 2 ":     {1, 2},
+	}
+	for s, expected := range good {
+		r, err := ParsePortRange(s)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %q: %v", s, err)
+			continue
+		}
+		if *r != expected {
+			t.Errorf("ParsePortRange(%q): expected %v, got %v", s, expected, *r)
+		}
+	}
+	for _, s := range []string{"", "a-b", "0-10", "10-5", "1-70000"} {
+		if _, err := ParsePortRange(s); err == nil {
+			t.Errorf("Expected error parsing %q", s)
+		}
+	}
+}
+
+func TestSourcePortDialer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen: %v", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	// Pick a free port for the range
+	probe, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen: %v", err)
+	}
+	port := probe.Addr().(*net.TCPAddr).Port
+	probe.Close()
+	dialer := NewSourcePortDialer(&PortRange{port, port}, time.Second)
+	conn, err := dialer.DialContext(context.Background(), "tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Unable to dial: %v", err)
+	}
+	defer conn.Close()
+	_, local, _ := net.SplitHostPort(conn.LocalAddr().String())
+	if local != strconv.Itoa(port) {
+		t.Errorf("Expected local port %d, got %s", port, local)
+	}
+	// The only port is in use now
+	if _, err := dialer.DialContext(context.Background(), "tcp", l.Addr().String()); err == nil {
+		t.Error("Expected error with no free source ports.")
+	}
+}
+
+func TestUnixSocketDialer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webborer")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Unable to listen: %v", err)
+	}
+	defer l.Close()
+	go http.Serve(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host + r.URL.Path))
+	}))
+
+	factory, _ := NewProxyClientFactory(nil, time.Second, "")
+	factory.SetDialer(NewUnixSocketDialer(path, time.Second))
+	u, _ := url.Parse("http://docker/containers/json")
+	resp, err := factory.Get().RequestURL(u)
+	if err != nil {
+		t.Fatalf("Unexpected error requesting over socket: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != "docker/containers/json" {
+		t.Errorf("Expected request for the URL over the socket, got %q", body)
+	}
+}
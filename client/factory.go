@@ -15,13 +15,16 @@
 package client
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"github.com/Matir/webborer/logging"
 	"h12.io/socks"
 	"math/rand"
+	"net"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 )
 
@@ -46,6 +49,55 @@ type ProxyClientFactory struct {
 	userAgent    string
 	httpUsername string
 	httpPassword string
+	// Header profile for all clients
+	profile *HeaderProfile
+	// Use random header profiles
+	randomProfiles bool
+	// Pick a new random profile for each request rather than each client
+	randomPerRequest bool
+	// Cookie jar shared by all clients
+	jar http.CookieJar
+	// Credentials for groups of targets
+	credentials CredentialSet
+	// Gets new bearer tokens for all clients
+	refresher *TokenRefresher
+	// Headers sent with every request
+	fixedHeader http.Header
+	// Local ports to connect from
+	sourcePorts *SourcePortDialer
+	// Hostname lookups for direct connections
+	resolver *Resolver
+	// Makes direct connections instead of TCP, if set
+	dialer Dialer
+	// Idle connections to keep per host (0 for the default)
+	maxIdlePerHost int
+	// Connections per host (0 for no limit)
+	maxConnsPerHost int
+	// Close connections after each request
+	disableKeepAlives bool
+	// TLS sessions to resume, shared by all clients
+	sessionCache tls.ClientSessionCache
+	// Transports shared by all clients, by proxy, when connections are
+	// limited per host
+	transports map[string]http.RoundTripper
+	sync.Mutex
+	// Shared by all clients to learn about servers' connection limits
+	keepAlive *KeepAliveTracker
+	// Exact order and casing of request headers
+	headerOrder []string
+	// Send headers in the order used by the header profile
+	profileOrder bool
+	// Send request paths exactly as given
+	rawTargets bool
+	// Send absolute URIs in request lines
+	absoluteURI bool
+	// Trace the headers and timings of each request
+	trace bool
+	// HTTP/2 connections per host and streams per connection, when
+	// multiplexing direct requests
+	multiplexConns   int
+	multiplexStreams int
+	multiplexer      *MultiplexTransport
 }
 
 // Create a ProxyClientFactory for the provided list of proxies.
@@ -75,44 +127,331 @@ func (factory *ProxyClientFactory) SetUsernamePassword(username, password string
 	factory.httpPassword = password
 }
 
+// Send the headers from profile with each request.
+func (factory *ProxyClientFactory) SetHeaderProfile(profile *HeaderProfile) {
+	factory.profile = profile
+}
+
+// Use a single random browser fingerprint for every client from this
+// factory.
+func (factory *ProxyClientFactory) SetRandomFingerprint() {
+	factory.randomProfiles = false
+	factory.profile = RandomFingerprint()
+	logging.Logf(logging.LogInfo, "Using %s fingerprint: %s", factory.profile.Name, factory.profile.Header.Get("User-Agent"))
+}
+
+// Use random header profiles, rotated either per request or per client.
+func (factory *ProxyClientFactory) SetRandomProfiles(perRequest bool) {
+	factory.randomProfiles = true
+	factory.randomPerRequest = perRequest
+}
+
+// Use per-target-group credentials.
+func (factory *ProxyClientFactory) SetCredentials(creds CredentialSet) {
+	factory.credentials = creds
+}
+
+// Refresh bearer tokens that stop working with refresher, shared by all
+// clients.
+func (factory *ProxyClientFactory) SetTokenRefresher(refresher *TokenRefresher) {
+	factory.refresher = refresher
+}
+
+// Send a header with every request from every client, such as to identify
+// the engagement a scan is part of.
+func (factory *ProxyClientFactory) SetFixedHeader(name, value string) {
+	if factory.fixedHeader == nil {
+		factory.fixedHeader = make(http.Header)
+	}
+	factory.fixedHeader.Set(name, value)
+}
+
+// Make direct connections from local ports in the given range.  Connections
+// through proxies are made by the proxy library and are not restricted.
+func (factory *ProxyClientFactory) SetSourcePorts(ports *PortRange) {
+	factory.sourcePorts = NewSourcePortDialer(ports, factory.timeout)
+	if len(factory.proxyURLs) > 0 {
+		logging.Logf(logging.LogWarning, "Source ports are not restricted for connections via proxies.")
+	}
+}
+
+// Look up hostnames for direct connections with resolver.  Connections
+// through proxies are resolved by the proxy library.
+func (factory *ProxyClientFactory) SetResolver(resolver *Resolver) {
+	factory.resolver = resolver
+}
+
+// Get the resolver set with SetResolver, or nil if there isn't one.
+func (factory *ProxyClientFactory) Resolver() *Resolver {
+	return factory.resolver
+}
+
+// Make direct connections with dialer, such as to a Unix socket.  The source
+// ports and resolver are not used, as dialer is given the host from each
+// URL.
+func (factory *ProxyClientFactory) SetDialer(dialer Dialer) {
+	factory.dialer = dialer
+	if len(factory.proxyURLs) > 0 {
+		logging.Logf(logging.LogWarning, "Connections via proxies do not use the dialer.")
+	}
+}
+
+// Keep up to maxIdle idle connections per host, and open at most maxConns
+// connections to each host across all clients from this factory.  Zero
+// leaves either at its default: net/http's idle limit and no connection
+// limit.
+func (factory *ProxyClientFactory) SetConnectionLimits(maxIdle, maxConns int) {
+	factory.maxIdlePerHost = maxIdle
+	factory.maxConnsPerHost = maxConns
+}
+
+// Send direct HTTPS requests to each host over up to conns HTTP/2
+// connections shared by all clients, with up to streams requests on each at
+// once, for hosts that support HTTP/2.  Zero uses the defaults.  The header
+// order and raw path settings use HTTP/1.1 and turn this off.
+func (factory *ProxyClientFactory) SetHTTP2Multiplexing(conns, streams int) {
+	if conns <= 0 {
+		conns = DefaultMultiplexConns
+	}
+	if streams <= 0 {
+		streams = DefaultMultiplexStreams
+	}
+	factory.multiplexConns = conns
+	factory.multiplexStreams = streams
+}
+
+// Get how requests were multiplexed, if they are.
+func (factory *ProxyClientFactory) MultiplexStats() (MultiplexStats, bool) {
+	factory.Lock()
+	defer factory.Unlock()
+	if factory.multiplexer == nil {
+		return MultiplexStats{}, false
+	}
+	return factory.multiplexer.Stats(), true
+}
+
+// Whether direct requests are multiplexed over HTTP/2
+func (factory *ProxyClientFactory) multiplexing() bool {
+	return factory.multiplexConns > 0 && !factory.useRawTransport()
+}
+
+// Close every connection after a single request.
+func (factory *ProxyClientFactory) SetDisableKeepAlives() {
+	factory.disableKeepAlives = true
+}
+
+// Resume TLS sessions across connections and clients, saving a full
+// handshake for each new connection.
+func (factory *ProxyClientFactory) SetTLSSessionResumption() {
+	factory.sessionCache = tls.NewLRUClientSessionCache(0)
+}
+
+// Detect servers that drop persistent connections and adapt to them.
+func (factory *ProxyClientFactory) SetKeepAliveTracking() {
+	factory.keepAlive = NewKeepAliveTracker()
+}
+
+// Send request headers in exactly this order and casing.  This replaces
+// net/http's transport, so requests are made with HTTP/1.1 only.
+func (factory *ProxyClientFactory) SetHeaderOrder(order []string) {
+	factory.headerOrder = order
+}
+
+// Send request headers in the order used by the browser the header profile
+// mimics.
+func (factory *ProxyClientFactory) SetProfileHeaderOrder() {
+	factory.profileOrder = true
+}
+
+// Send request paths exactly as given, without net/http's escaping.  Like
+// SetHeaderOrder, this replaces net/http's transport.
+func (factory *ProxyClientFactory) SetRawTargets() {
+	factory.rawTargets = true
+}
+
+// Send absolute URIs in request lines, as sent to a proxy, to test how
+// servers route them when they differ from the Host header.  Like
+// SetHeaderOrder, this replaces net/http's transport.
+func (factory *ProxyClientFactory) SetAbsoluteURI() {
+	factory.absoluteURI = true
+}
+
+// Record the headers written and the timings of each request, for TraceFor.
+func (factory *ProxyClientFactory) SetRequestTracing() {
+	factory.trace = true
+}
+
+// Keep cookies in jar for all clients from this factory.
+func (factory *ProxyClientFactory) SetCookieJar(jar http.CookieJar) {
+	factory.jar = jar
+}
+
 // Get a single client instance from the factory
 func (factory *ProxyClientFactory) Get() Client {
-	if len(factory.proxyURLs) == 0 {
-		return &httpClient{
-			Client: &http.Client{
-				Timeout: factory.timeout,
-				Transport: &http.Transport{
-					TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-				},
-			},
-			UserAgent:    factory.userAgent,
-			HTTPUsername: factory.httpUsername,
-			HTTPPassword: factory.httpPassword,
-		}
+	cli := factory.getClient()
+	if hc, ok := cli.Client.(*http.Client); ok {
+		hc.Jar = factory.jar
 	}
-	var cli *httpClient
-	if len(factory.proxyURLs) == 1 {
-		cli = clientForProxy(factory.proxyURLs[0], factory.timeout, factory.userAgent)
-	} else {
-		proxy := factory.proxyURLs[rand.Intn(len(factory.proxyURLs))]
-		cli = clientForProxy(proxy, factory.timeout, factory.userAgent)
+	cli.Profile = factory.profile
+	cli.Credentials = factory.credentials
+	cli.Refresher = factory.refresher
+	cli.FixedHeader = factory.fixedHeader
+	cli.KeepAlive = factory.keepAlive
+	cli.ProfileOrder = factory.profileOrder
+	cli.RawTarget = factory.rawTargets
+	cli.AbsoluteURI = factory.absoluteURI
+	cli.Trace = factory.trace
+	if factory.randomProfiles {
+		if factory.randomPerRequest {
+			cli.RandomProfile = true
+		} else {
+			cli.Profile = RandomHeaderProfile()
+		}
 	}
-	cli.HTTPUsername = factory.httpUsername
-	cli.HTTPPassword = factory.httpPassword
 	return cli
 }
 
-// Build a client for a particular proxy instance
-func clientForProxy(proxy *url.URL, timeout time.Duration, agent string) *httpClient {
+func (factory *ProxyClientFactory) getClient() *httpClient {
+	var proxy *url.URL
+	switch len(factory.proxyURLs) {
+	case 0:
+	case 1:
+		proxy = factory.proxyURLs[0]
+	default:
+		proxy = factory.proxyURLs[rand.Intn(len(factory.proxyURLs))]
+	}
+	transport := factory.getTransport(proxy)
+	return &httpClient{
+		Client: &http.Client{
+			Timeout:   factory.timeout,
+			Transport: transport,
+		},
+		UserAgent:    factory.userAgent,
+		HTTPUsername: factory.httpUsername,
+		HTTPPassword: factory.httpPassword,
+	}
+}
+
+// Get a transport for a proxy, or for direct connections if proxy is nil.
+// Transports are only shared when connections per host are limited, as the
+// limit would otherwise apply to each client, or when multiplexing.
+func (factory *ProxyClientFactory) getTransport(proxy *url.URL) http.RoundTripper {
+	build := func() http.RoundTripper {
+		if proxy == nil {
+			return factory.directTransport()
+		}
+		return factory.proxyTransport(proxy)
+	}
+	if factory.maxConnsPerHost <= 0 && !(proxy == nil && factory.multiplexing()) {
+		return build()
+	}
+	key := ""
+	if proxy != nil {
+		key = proxy.String()
+	}
+	factory.Lock()
+	defer factory.Unlock()
+	if factory.transports == nil {
+		factory.transports = make(map[string]http.RoundTripper)
+	}
+	if transport, ok := factory.transports[key]; ok {
+		return transport
+	}
+	transport := build()
+	factory.transports[key] = transport
+	return transport
+}
+
+// Whether requests must be written by a RawTransport
+func (factory *ProxyClientFactory) useRawTransport() bool {
+	return factory.headerOrder != nil || factory.profileOrder || factory.rawTargets || factory.absoluteURI
+}
+
+// Build a transport for direct connections
+func (factory *ProxyClientFactory) directTransport() http.RoundTripper {
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+	if factory.useRawTransport() {
+		return factory.tuneRaw(&RawTransport{
+			TLSClientConfig: tlsConfig,
+			HeaderOrder:     factory.rawHeaderOrder(),
+			DialContext:     factory.directDialer(),
+		})
+	}
+	transport := factory.tune(&http.Transport{
+		TLSClientConfig: tlsConfig,
+		DialContext:     factory.directDialer(),
+	})
+	if !factory.multiplexing() {
+		return transport
+	}
+	// Called with the lock held, as the transport is shared
+	factory.multiplexer = NewMultiplexTransport(transport, factory.directDialer(), transport.TLSClientConfig, factory.multiplexConns, factory.multiplexStreams)
+	return factory.multiplexer
+}
+
+// Apply the connection settings to a transport
+func (factory *ProxyClientFactory) tune(transport *http.Transport) *http.Transport {
+	transport.MaxIdleConnsPerHost = factory.maxIdlePerHost
+	transport.MaxConnsPerHost = factory.maxConnsPerHost
+	transport.DisableKeepAlives = factory.disableKeepAlives
+	if factory.sessionCache != nil {
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.ClientSessionCache = factory.sessionCache
+	}
+	return transport
+}
+
+// Apply the connection settings to a RawTransport
+func (factory *ProxyClientFactory) tuneRaw(transport *RawTransport) *RawTransport {
+	transport.MaxIdleConnsPerHost = factory.maxIdlePerHost
+	transport.MaxConnsPerHost = factory.maxConnsPerHost
+	transport.DisableKeepAlives = factory.disableKeepAlives
+	if factory.sessionCache != nil {
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.ClientSessionCache = factory.sessionCache
+	}
+	return transport
+}
+
+// Get the function for making direct connections, or nil for the default
+func (factory *ProxyClientFactory) directDialer() func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if factory.dialer != nil {
+		return factory.dialer.DialContext
+	}
+	var dial func(ctx context.Context, network, addr string) (net.Conn, error)
+	if factory.sourcePorts != nil {
+		dial = factory.sourcePorts.DialContext
+	}
+	if factory.resolver == nil {
+		return dial
+	}
+	return factory.resolver.DialVia(dial)
+}
+
+// Build a transport for a particular proxy instance
+func (factory *ProxyClientFactory) proxyTransport(proxy *url.URL) http.RoundTripper {
 	proto := proxyTypeMap[proxy.Scheme]
 	dialer := socks.DialSocksProxy(proto, proxy.Host)
-	cl := &httpClient{
-		Client: &http.Client{
-			Transport: &http.Transport{
-				Dial: dialer,
+	if factory.useRawTransport() {
+		return factory.tuneRaw(&RawTransport{
+			DialContext: func(_ context.Context, network, addr string) (net.Conn, error) {
+				return dialer(network, addr)
 			},
-			Timeout: timeout,
-		},
-		UserAgent: agent}
-	return cl
+			HeaderOrder: factory.rawHeaderOrder(),
+		})
+	}
+	return factory.tune(&http.Transport{
+		Dial: dialer,
+	})
+}
+
+func (factory *ProxyClientFactory) rawHeaderOrder() []string {
+	if factory.headerOrder != nil {
+		return factory.headerOrder
+	}
+	return DefaultHeaderOrder
 }
@@ -15,6 +15,7 @@
 package client
 
 import (
+	"net/http"
 	"testing"
 	"time"
 )
@@ -95,3 +96,55 @@ func TestPCFGet_TwoProxies(t *testing.T) {
 		t.Errorf("Got nil client for two proxies.")
 	}
 }
+
+func TestPCFGet_ConnectionLimits(t *testing.T) {
+	fac, _ := NewProxyClientFactory([]string{}, time.Second, "")
+	fac.SetConnectionLimits(8, 0)
+	a, b := fac.getClient(), fac.getClient()
+	if a.Client.(*http.Client).Transport == b.Client.(*http.Client).Transport {
+		t.Error("Transports should not be shared without a connection limit.")
+	}
+	if tr := a.Client.(*http.Client).Transport.(*http.Transport); tr.MaxIdleConnsPerHost != 8 {
+		t.Errorf("Expected 8 idle connections, got %d", tr.MaxIdleConnsPerHost)
+	}
+	fac.SetConnectionLimits(0, 4)
+	fac.SetDisableKeepAlives()
+	fac.SetTLSSessionResumption()
+	a, b = fac.getClient(), fac.getClient()
+	if a.Client.(*http.Client).Transport != b.Client.(*http.Client).Transport {
+		t.Error("Transports should be shared with a connection limit.")
+	}
+	tr := a.Client.(*http.Client).Transport.(*http.Transport)
+	if tr.MaxConnsPerHost != 4 || !tr.DisableKeepAlives || tr.TLSClientConfig.ClientSessionCache == nil {
+		t.Errorf("Transport not tuned: %+v", tr)
+	}
+}
+
+func TestPCFGet_Multiplexing(t *testing.T) {
+	fac, _ := NewProxyClientFactory([]string{}, time.Second, "")
+	if _, ok := fac.MultiplexStats(); ok {
+		t.Error("Expected no multiplexing stats before it is set.")
+	}
+	fac.SetHTTP2Multiplexing(0, 0)
+	a, b := fac.getClient(), fac.getClient()
+	mux, ok := a.Client.(*http.Client).Transport.(*MultiplexTransport)
+	if !ok {
+		t.Fatalf("Expected a MultiplexTransport, got %T", a.Client.(*http.Client).Transport)
+	}
+	if mux != b.Client.(*http.Client).Transport {
+		t.Error("Transports should be shared when multiplexing.")
+	}
+	if mux.maxConns != DefaultMultiplexConns || mux.maxStreams != DefaultMultiplexStreams {
+		t.Errorf("Expected default limits, got %d and %d", mux.maxConns, mux.maxStreams)
+	}
+	if _, ok := fac.MultiplexStats(); !ok {
+		t.Error("Expected multiplexing stats.")
+	}
+	// Raw requests are written as HTTP/1.1
+	fac, _ = NewProxyClientFactory([]string{}, time.Second, "")
+	fac.SetHTTP2Multiplexing(2, 10)
+	fac.SetRawTargets()
+	if _, ok := fac.getClient().Client.(*http.Client).Transport.(*MultiplexTransport); ok {
+		t.Error("Expected raw requests not to be multiplexed.")
+	}
+}
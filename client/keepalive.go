@@ -0,0 +1,277 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"errors"
+	"github.com/Matir/webborer/logging"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeepAliveTracker learns how servers treat persistent connections.  Some
+// servers drop a connection after a fixed number of requests, or when too many
+// connections are open, which otherwise shows up as spurious EOF errors.  When
+// a connection is dropped, the tracker retries the request on a new
+// connection, closes future connections to that host before the limit is
+// reached, and reduces the number of concurrent requests to the host.  The
+// concurrency recovers gradually while requests succeed.
+type KeepAliveTracker struct {
+	hosts map[string]*hostKeepAlive
+	sync.Mutex
+}
+
+// Successful requests, per concurrent request allowed, before another
+// concurrent request is allowed
+const keepAliveRecoverAfter = 10
+
+// Connections idle for longer are assumed to have been closed, as they are by
+// the default transport
+const keepAliveIdleTimeout = 90 * time.Second
+
+type hostKeepAlive struct {
+	// Requests allowed per connection, 0 if unlimited
+	perConn int
+	// Concurrent requests allowed, 0 if unlimited
+	concurrency int
+	active      int
+	// Successful requests since concurrency was last changed
+	successes int
+	// Requests made on each open connection
+	conns map[net.Conn]*connCount
+	mu    sync.Mutex
+	cond  *sync.Cond
+}
+
+func NewKeepAliveTracker() *KeepAliveTracker {
+	return &KeepAliveTracker{
+		hosts: make(map[string]*hostKeepAlive),
+	}
+}
+
+func (k *KeepAliveTracker) getHost(host string) *hostKeepAlive {
+	k.Lock()
+	defer k.Unlock()
+	h, ok := k.hosts[host]
+	if !ok {
+		h = &hostKeepAlive{conns: make(map[net.Conn]*connCount)}
+		h.cond = sync.NewCond(&h.mu)
+		k.hosts[host] = h
+	}
+	return h
+}
+
+// Get the number of requests allowed per connection and concurrently for a
+// host, 0 meaning no limit has been detected.
+func (k *KeepAliveTracker) Limits(host string) (int, int) {
+	h := k.getHost(host)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.perConn, h.concurrency
+}
+
+// Perform a request with client, applying what has been learned about the
+// host.
+func (k *KeepAliveTracker) Do(client httpClientInt, req *http.Request) (*http.Response, error) {
+	h := k.getHost(req.URL.Host)
+	h.acquire()
+	defer h.release()
+	resp, use, err := h.do(client, req)
+	if err == nil {
+		h.succeeded()
+	}
+	if err == nil || !isConnDrop(err) {
+		return resp, err
+	}
+	h.dropped(use)
+	retry := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		// The body has been read, so it can only be sent again if it can be
+		// recreated
+		if req.GetBody == nil {
+			return resp, err
+		}
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	logging.Logf(logging.LogDebug, "Connection to %s dropped after %d requests, retrying.", req.URL.Host, use.count)
+	retry.Close = true
+	resp, _, err = h.do(client, retry)
+	if err == nil {
+		h.succeeded()
+	}
+	return resp, err
+}
+
+// Requests made on an open connection, and when it was last used
+type connCount struct {
+	count int
+	last  time.Time
+}
+
+// The connection a request was made on
+type connUse struct {
+	conn net.Conn
+	// The connection was used for an earlier request
+	reused bool
+	// Requests made on the connection, including this one
+	count int
+}
+
+// Make a single request, tracking the connection used.  Whether the
+// connection is closed afterwards is decided before the request is made, as
+// the transport owns the request once it is sent.
+func (h *hostKeepAlive) do(client httpClientInt, req *http.Request) (*http.Response, *connUse, error) {
+	use := &connUse{}
+	closing := req.Close || h.shouldClose()
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			use.conn = info.Conn
+			use.reused = info.Reused
+			use.count = h.used(info.Conn)
+			if closing {
+				h.forget(info.Conn)
+			}
+		},
+	}
+	traced := req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	// Close the connection before the server does
+	traced.Close = closing
+	resp, err := client.Do(traced)
+	if use.conn != nil && (err != nil || resp.Close) {
+		// Not reused, so there's nothing more to track
+		h.forget(use.conn)
+	}
+	return resp, use, err
+}
+
+func (h *hostKeepAlive) acquire() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for h.concurrency > 0 && h.active >= h.concurrency {
+		h.cond.Wait()
+	}
+	h.active++
+}
+
+func (h *hostKeepAlive) release() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.active--
+	h.cond.Broadcast()
+}
+
+func (h *hostKeepAlive) used(conn net.Conn) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c, ok := h.conns[conn]
+	if !ok {
+		c = &connCount{}
+		h.conns[conn] = c
+	}
+	c.count++
+	c.last = time.Now()
+	return c.count
+}
+
+func (h *hostKeepAlive) forget(conn net.Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.conns, conn)
+}
+
+// Check if the next request should close its connection.  Which idle
+// connection the transport picks isn't known in advance, so once any open
+// connection is one request short of the limit, every request closes its
+// connection until that one has been used.  Closing early only costs a new
+// connection, while closing late costs a dropped request.  Connections idle
+// for too long are forgotten, whether or not there is a limit.
+func (h *hostKeepAlive) shouldClose() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	closing := false
+	for conn, c := range h.conns {
+		if time.Since(c.last) > keepAliveIdleTimeout {
+			// Closed by the transport or the server since
+			delete(h.conns, conn)
+		} else if h.perConn > 0 && c.count+1 >= h.perConn {
+			closing = true
+		}
+	}
+	if h.perConn == 0 {
+		return false
+	}
+	if closing {
+		return true
+	}
+	return h.perConn == 1
+}
+
+// Record a successful request, allowing another concurrent request once
+// enough have succeeded.
+func (h *hostKeepAlive) succeeded() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.concurrency == 0 {
+		return
+	}
+	h.successes++
+	if h.successes >= h.concurrency*keepAliveRecoverAfter {
+		h.concurrency++
+		h.successes = 0
+		h.cond.Broadcast()
+	}
+}
+
+// Record a dropped connection.  A reused connection tells us how many
+// requests the server allows per connection; any drop suggests too many
+// concurrent connections.
+func (h *hostKeepAlive) dropped(use *connUse) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if use.conn != nil {
+		delete(h.conns, use.conn)
+	}
+	count := use.count
+	if use.reused && count > 1 && (h.perConn == 0 || count-1 < h.perConn) {
+		h.perConn = count - 1
+	}
+	concurrency := h.active / 2
+	if h.concurrency > 0 && h.concurrency/2 < concurrency {
+		concurrency = h.concurrency / 2
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	h.concurrency = concurrency
+	h.successes = 0
+}
+
+// Check if an error indicates the server dropped the connection.
+func isConnDrop(err error) bool {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "connection reset by peer") ||
+		strings.Contains(msg, "server closed idle connection") ||
+		strings.Contains(msg, "broken pipe")
+}
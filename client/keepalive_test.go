@@ -0,0 +1,172 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Simulates a server that drops each connection after perConn requests.
+type droppingClient struct {
+	perConn  int
+	conn     net.Conn
+	count    int
+	requests int
+	closed   int
+}
+
+func (c *droppingClient) Do(req *http.Request) (*http.Response, error) {
+	c.requests++
+	reused := c.conn != nil
+	if c.conn == nil {
+		c.conn, _ = net.Pipe()
+		c.count = 0
+	}
+	c.count++
+	if trace := httptrace.ContextClientTrace(req.Context()); trace != nil && trace.GotConn != nil {
+		trace.GotConn(httptrace.GotConnInfo{Conn: c.conn, Reused: reused})
+	}
+	if c.count > c.perConn {
+		c.conn = nil
+		return nil, &url.Error{Op: "Get", URL: req.URL.String(), Err: io.EOF}
+	}
+	if req.Close {
+		c.closed++
+		c.conn = nil
+	}
+	return &http.Response{StatusCode: 200}, nil
+}
+
+func TestKeepAliveTracker(t *testing.T) {
+	k := NewKeepAliveTracker()
+	client := &droppingClient{perConn: 3}
+	u := &url.URL{Scheme: "http", Host: "localhost", Path: "/"}
+	for i := 0; i < 10; i++ {
+		req, _ := http.NewRequest("GET", u.String(), nil)
+		if _, err := k.Do(client, req); err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+	}
+	perConn, concurrency := k.Limits("localhost")
+	if perConn != 3 {
+		t.Errorf("Expected 3 requests per connection, got %d", perConn)
+	}
+	if concurrency != 1 {
+		t.Errorf("Expected concurrency of 1, got %d", concurrency)
+	}
+	// One failed request, then connections are closed before the limit
+	if client.requests != 11 {
+		t.Errorf("Expected 11 requests, got %d", client.requests)
+	}
+	if client.closed == 0 {
+		t.Error("Expected connections to be closed proactively.")
+	}
+}
+
+func TestKeepAliveTracker_RetryBody(t *testing.T) {
+	k := NewKeepAliveTracker()
+	client := &droppingClient{perConn: 1}
+	u := "http://localhost/"
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("POST", u, strings.NewReader("body"))
+		if _, err := k.Do(client, req); err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+	}
+	// Without GetBody, the body can't be sent again
+	k = NewKeepAliveTracker()
+	client = &droppingClient{perConn: 1}
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("POST", u, ioutil.NopCloser(strings.NewReader("body")))
+		_, err := k.Do(client, req)
+		if i == 1 && err == nil {
+			t.Error("Expected a request with a body that can't be recreated not to be retried.")
+		}
+	}
+}
+
+func TestKeepAliveTracker_Recover(t *testing.T) {
+	k := NewKeepAliveTracker()
+	h := k.getHost("localhost")
+	h.active = 4
+	h.dropped(&connUse{})
+	if _, concurrency := k.Limits("localhost"); concurrency != 2 {
+		t.Fatalf("Expected concurrency of 2 after a drop, got %d", concurrency)
+	}
+	for i := 0; i < 2*keepAliveRecoverAfter; i++ {
+		h.succeeded()
+	}
+	if _, concurrency := k.Limits("localhost"); concurrency != 3 {
+		t.Errorf("Expected concurrency to recover to 3, got %d", concurrency)
+	}
+}
+
+// Simulates a server that closes every connection after one request.
+type closingClient struct{}
+
+func (c *closingClient) Do(req *http.Request) (*http.Response, error) {
+	conn, _ := net.Pipe()
+	if trace := httptrace.ContextClientTrace(req.Context()); trace != nil && trace.GotConn != nil {
+		trace.GotConn(httptrace.GotConnInfo{Conn: conn})
+	}
+	return &http.Response{StatusCode: 200, Close: true}, nil
+}
+
+func TestKeepAliveTracker_ForgetsConns(t *testing.T) {
+	k := NewKeepAliveTracker()
+	for i := 0; i < 1000; i++ {
+		req, _ := http.NewRequest("GET", "http://localhost/", nil)
+		if _, err := k.Do(&closingClient{}, req); err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+	}
+	h := k.getHost("localhost")
+	if n := len(h.conns); n != 0 {
+		t.Errorf("Expected closed connections to be forgotten, %d kept", n)
+	}
+	// Idle connections are forgotten without a limit per connection
+	for i := 0; i < 1000; i++ {
+		conn, _ := net.Pipe()
+		h.conns[conn] = &connCount{count: 1, last: time.Now().Add(-2 * keepAliveIdleTimeout)}
+	}
+	h.shouldClose()
+	if n := len(h.conns); n != 0 {
+		t.Errorf("Expected idle connections to be forgotten, %d kept", n)
+	}
+}
+
+func TestIsConnDrop(t *testing.T) {
+	cases := map[error]bool{
+		io.EOF: true,
+		&url.Error{Op: "Get", Err: io.ErrUnexpectedEOF}:                  true,
+		errors.New("read tcp: connection reset by peer"):                 true,
+		errors.New("dial tcp: connection refused"):                       false,
+		&url.Error{Op: "Get", Err: errors.New("tls: handshake failure")}: false,
+	}
+	for err, expected := range cases {
+		if isConnDrop(err) != expected {
+			t.Errorf("isConnDrop(%v) != %v", err, expected)
+		}
+	}
+}
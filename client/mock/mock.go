@@ -24,22 +24,31 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"sync"
 )
 
 type MockClientFactory struct {
 	ForeverClient *MockClient
 	NextClient    *MockClient
+	sync.Mutex
 }
 
 type MockClient struct {
 	ForeverResponse *http.Response
 	NextResponse    *http.Response
 	Requests        []*url.URL
+	Methods         []string
 	Redir           *url.URL
 	CheckRedirect   func(*http.Request, []*http.Request) error
+	// Body of ForeverResponse, so each request can read it
+	foreverBody []byte
+	// A forever client is shared by workers
+	sync.Mutex
 }
 
 func (f *MockClientFactory) Get() client.Client {
+	f.Lock()
+	defer f.Unlock()
 	if f.NextClient != nil {
 		c := f.NextClient
 		f.NextClient = nil
@@ -56,7 +65,10 @@ func (c *MockClient) RequestURL(u *url.URL) (*http.Response, error) {
 }
 
 func (c *MockClient) Request(u *url.URL, host, method string, header http.Header) (*http.Response, error) {
+	c.Lock()
+	defer c.Unlock()
 	c.Requests = append(c.Requests, u)
+	c.Methods = append(c.Methods, method)
 	if c.Redir != nil && c.CheckRedirect != nil {
 		req := &http.Request{URL: c.Redir}
 		if err := c.CheckRedirect(req, []*http.Request{}); err != nil {
@@ -64,7 +76,7 @@ func (c *MockClient) Request(u *url.URL, host, method string, header http.Header
 		}
 	}
 	if c.ForeverResponse != nil {
-		return c.ForeverResponse, nil
+		return c.forever(), nil
 	}
 	if c.NextResponse == nil {
 		return nil, errors.New("No NextResponse for MockClient.")
@@ -74,7 +86,23 @@ func (c *MockClient) Request(u *url.URL, host, method string, header http.Header
 	return r, nil
 }
 
+// Get a copy of ForeverResponse with a body of its own.
+func (c *MockClient) forever() *http.Response {
+	if c.ForeverResponse.Body != nil {
+		c.foreverBody, _ = ioutil.ReadAll(c.ForeverResponse.Body)
+		c.ForeverResponse.Body.Close()
+		c.ForeverResponse.Body = nil
+	}
+	resp := *c.ForeverResponse
+	if c.foreverBody != nil {
+		resp.Body = ioutil.NopCloser(bytes.NewReader(c.foreverBody))
+	}
+	return &resp
+}
+
 func (c *MockClient) SetCheckRedirect(f func(*http.Request, []*http.Request) error) {
+	c.Lock()
+	defer c.Unlock()
 	c.CheckRedirect = f
 }
 
@@ -0,0 +1,286 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"golang.org/x/net/http2"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// Defaults for multiplexing requests over HTTP/2
+const (
+	DefaultMultiplexConns   = 2
+	DefaultMultiplexStreams = 100
+)
+
+// MultiplexTransport sends requests for each host over a few HTTP/2
+// connections, each carrying a limited number of concurrent requests as
+// streams, rather than a connection per worker.  Hosts that don't negotiate
+// HTTP/2, and plain HTTP, use the fallback transport.  It must be shared by
+// every client to have any effect.
+type MultiplexTransport struct {
+	fallback  http.RoundTripper
+	h2        *http2.Transport
+	dial      func(ctx context.Context, network, addr string) (net.Conn, error)
+	tlsConfig *tls.Config
+	// Connections per host
+	maxConns int
+	// Concurrent streams per connection
+	maxStreams int
+	hosts      map[string]*muxHost
+	stats      MultiplexStats
+	sync.Mutex
+}
+
+// Connections to a single host
+type muxHost struct {
+	conns []*muxConn
+	// Connections being made
+	dialing int
+	// The host didn't negotiate HTTP/2
+	http1 bool
+	// Closed, and replaced, when a stream or connection slot may be free
+	ready chan struct{}
+}
+
+// Wake requests waiting for a stream or connection slot.  Must be called with
+// the lock held.
+func (h *muxHost) broadcast() {
+	close(h.ready)
+	h.ready = make(chan struct{})
+}
+
+type muxConn struct {
+	cc   *http2.ClientConn
+	host *muxHost
+	// Requests in progress
+	active int
+}
+
+// How requests were sent by a MultiplexTransport.
+type MultiplexStats struct {
+	// Requests sent over HTTP/2, and HTTP/2 connections made for them
+	Requests    int64
+	Connections int64
+	// Most requests in progress on a single connection at once
+	PeakStreams int
+	// Requests sent by the fallback transport
+	Fallback int64
+}
+
+// Requests sent per HTTP/2 connection
+func (s MultiplexStats) PerConnection() float64 {
+	if s.Connections == 0 {
+		return 0
+	}
+	return float64(s.Requests) / float64(s.Connections)
+}
+
+func (s MultiplexStats) String() string {
+	return fmt.Sprintf("%d requests over %d HTTP/2 connections (%.0f per connection, up to %d at once), %d over HTTP/1.1",
+		s.Requests, s.Connections, s.PerConnection(), s.PeakStreams, s.Fallback)
+}
+
+// Create a MultiplexTransport making up to maxConns connections per host with
+// up to maxStreams requests on each.  Connections are made with dial, or a
+// net.Dialer if it is nil.
+func NewMultiplexTransport(fallback http.RoundTripper, dial func(ctx context.Context, network, addr string) (net.Conn, error), tlsConfig *tls.Config, maxConns, maxStreams int) *MultiplexTransport {
+	if maxConns <= 0 {
+		maxConns = DefaultMultiplexConns
+	}
+	if maxStreams <= 0 {
+		maxStreams = DefaultMultiplexStreams
+	}
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	tlsConfig = tlsConfig.Clone()
+	tlsConfig.NextProtos = []string{"h2", "http/1.1"}
+	return &MultiplexTransport{
+		fallback:   fallback,
+		h2:         &http2.Transport{TLSClientConfig: tlsConfig},
+		dial:       dial,
+		tlsConfig:  tlsConfig,
+		maxConns:   maxConns,
+		maxStreams: maxStreams,
+		hosts:      make(map[string]*muxHost),
+	}
+}
+
+// Get how requests have been sent so far.
+func (t *MultiplexTransport) Stats() MultiplexStats {
+	t.Lock()
+	defer t.Unlock()
+	return t.stats
+}
+
+func (t *MultiplexTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "https" {
+		return t.roundTripFallback(req)
+	}
+	mc, err := t.acquire(req)
+	if err != nil {
+		return nil, err
+	}
+	if mc == nil {
+		return t.roundTripFallback(req)
+	}
+	resp, err := mc.cc.RoundTrip(req)
+	if err != nil {
+		t.release(mc)
+		return nil, err
+	}
+	resp.Body = &muxBody{ReadCloser: resp.Body, release: func() { t.release(mc) }}
+	return resp, nil
+}
+
+func (t *MultiplexTransport) roundTripFallback(req *http.Request) (*http.Response, error) {
+	t.Lock()
+	t.stats.Fallback++
+	t.Unlock()
+	return t.fallback.RoundTrip(req)
+}
+
+// Get a connection with a free stream for req, waiting for one if every
+// connection is full, or until the request is cancelled.  Returns nil if the
+// host should use the fallback.
+func (t *MultiplexTransport) acquire(req *http.Request) (*muxConn, error) {
+	addr := canonicalAddr(req.URL.Scheme, req.URL.Host)
+	t.Lock()
+	defer t.Unlock()
+	h, ok := t.hosts[addr]
+	if !ok {
+		h = &muxHost{ready: make(chan struct{})}
+		t.hosts[addr] = h
+	}
+	for {
+		if h.http1 {
+			return nil, nil
+		}
+		if mc := t.pick(h); mc != nil {
+			mc.active++
+			t.stats.Requests++
+			if mc.active > t.stats.PeakStreams {
+				t.stats.PeakStreams = mc.active
+			}
+			return mc, nil
+		}
+		if len(h.conns)+h.dialing < t.maxConns {
+			h.dialing++
+			t.Unlock()
+			cc, http1, err := t.connect(req.Context(), addr, req.URL.Hostname())
+			t.Lock()
+			h.dialing--
+			h.broadcast()
+			if err != nil {
+				return nil, err
+			}
+			if http1 {
+				h.http1 = true
+				return nil, nil
+			}
+			h.conns = append(h.conns, &muxConn{cc: cc, host: h})
+			t.stats.Connections++
+			continue
+		}
+		ready := h.ready
+		t.Unlock()
+		select {
+		case <-ready:
+			t.Lock()
+		case <-req.Context().Done():
+			t.Lock()
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// Pick the least busy connection with a free stream, dropping connections
+// that are closed or closing.  Must be called with the lock held.
+func (t *MultiplexTransport) pick(h *muxHost) *muxConn {
+	var best *muxConn
+	live := h.conns[:0]
+	for _, mc := range h.conns {
+		if !mc.cc.CanTakeNewRequest() {
+			if mc.active == 0 {
+				mc.cc.Close()
+				continue
+			}
+		} else if mc.active < t.maxStreams && (best == nil || mc.active < best.active) {
+			best = mc
+		}
+		live = append(live, mc)
+	}
+	h.conns = live
+	return best
+}
+
+// Make a connection to addr, and an HTTP/2 client connection over it if the
+// server negotiates HTTP/2.  Reports http1 if it doesn't.
+func (t *MultiplexTransport) connect(ctx context.Context, addr, serverName string) (*http2.ClientConn, bool, error) {
+	raw, err := t.dial(ctx, "tcp", addr)
+	if err != nil {
+		return nil, false, err
+	}
+	cfg := t.tlsConfig.Clone()
+	if cfg.ServerName == "" && net.ParseIP(serverName) == nil {
+		cfg.ServerName = serverName
+	}
+	conn := tls.Client(raw, cfg)
+	if err := conn.HandshakeContext(ctx); err != nil {
+		raw.Close()
+		return nil, false, err
+	}
+	if conn.ConnectionState().NegotiatedProtocol != "h2" {
+		conn.Close()
+		return nil, true, nil
+	}
+	cc, err := t.h2.NewClientConn(conn)
+	if err != nil {
+		conn.Close()
+		return nil, false, err
+	}
+	return cc, false, nil
+}
+
+// Free the stream used by a request.
+func (t *MultiplexTransport) release(mc *muxConn) {
+	t.Lock()
+	defer t.Unlock()
+	mc.active--
+	mc.host.broadcast()
+}
+
+// muxBody frees its stream when closed.
+type muxBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *muxBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -0,0 +1,129 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMultiplexTransport(t *testing.T) {
+	var lock sync.Mutex
+	active, peak := 0, 0
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		active++
+		if active > peak {
+			peak = active
+		}
+		lock.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		lock.Lock()
+		active--
+		lock.Unlock()
+		w.Write([]byte(r.Proto))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+	mux := NewMultiplexTransport(&http.Transport{TLSClientConfig: tlsConfig}, nil, tlsConfig, 1, 4)
+	cli := &http.Client{Transport: mux}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := cli.Get(server.URL + "/")
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+			defer resp.Body.Close()
+			if body, _ := ioutil.ReadAll(resp.Body); string(body) != "HTTP/2.0" {
+				t.Errorf("Expected an HTTP/2 request, got %q", body)
+			}
+		}()
+	}
+	wg.Wait()
+	stats := mux.Stats()
+	if stats.Requests != 20 || stats.Connections != 1 || stats.Fallback != 0 {
+		t.Errorf("Expected 20 requests over 1 connection, got %+v", stats)
+	}
+	if peak > 4 || stats.PeakStreams > 4 {
+		t.Errorf("Expected at most 4 requests at once, got %d (%d reported)", peak, stats.PeakStreams)
+	}
+}
+
+func TestMultiplexTransport_Fallback(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	defer server.Close()
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	defer plain.Close()
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+	mux := NewMultiplexTransport(&http.Transport{TLSClientConfig: tlsConfig}, nil, tlsConfig, 2, 10)
+	cli := &http.Client{Transport: mux}
+	for _, u := range []string{server.URL, server.URL, plain.URL} {
+		resp, err := cli.Get(u + "/")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "HTTP/1.1" {
+			t.Errorf("Expected an HTTP/1.1 request, got %q", body)
+		}
+	}
+	if stats := mux.Stats(); stats.Requests != 0 || stats.Fallback != 3 {
+		t.Errorf("Expected every request to fall back, got %+v", stats)
+	}
+}
+
+func TestMultiplexTransport_Cancel(t *testing.T) {
+	release := make(chan bool)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+	defer close(release)
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+	mux := NewMultiplexTransport(&http.Transport{TLSClientConfig: tlsConfig}, nil, tlsConfig, 1, 1)
+	cli := &http.Client{Transport: mux}
+	go cli.Get(server.URL + "/")
+	for mux.Stats().Requests == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/", nil)
+	if _, err := cli.Do(req); err == nil {
+		t.Error("Expected a request waiting for a stream to be cancelled.")
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// A HeaderProfile is a coherent set of headers sent by a real-world client.
+// Sending a complete set avoids the trivial fingerprint of a lone User-Agent.
+type HeaderProfile struct {
+	Name   string
+	Header http.Header
+	// Order and casing the client sends headers in
+	Order []string
+	// Crawlers announce themselves, so are not used for random fingerprints
+	Crawler bool
+}
+
+// Header orders of real HTTP/1.1 clients
+var (
+	chromeOrder = []string{
+		"Host",
+		"Connection",
+		"Upgrade-Insecure-Requests",
+		"User-Agent",
+		"Accept",
+		"Sec-Fetch-Site",
+		"Sec-Fetch-Mode",
+		"Sec-Fetch-User",
+		"Sec-Fetch-Dest",
+		"Accept-Encoding",
+		"Accept-Language",
+		"Cookie",
+	}
+	firefoxOrder = []string{
+		"Host",
+		"User-Agent",
+		"Accept",
+		"Accept-Language",
+		"Accept-Encoding",
+		"Connection",
+		"Cookie",
+		"Upgrade-Insecure-Requests",
+		"Sec-Fetch-Dest",
+		"Sec-Fetch-Mode",
+		"Sec-Fetch-Site",
+		"Sec-Fetch-User",
+	}
+	safariOrder = []string{
+		"Host",
+		"Accept",
+		"Cookie",
+		"User-Agent",
+		"Accept-Language",
+		"Accept-Encoding",
+		"Connection",
+	}
+	googlebotOrder = []string{
+		"Host",
+		"Connection",
+		"Accept",
+		"From",
+		"User-Agent",
+		"Accept-Encoding",
+	}
+)
+
+const (
+	browserAccept   = "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8"
+	chromeUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/66.0.3359.181 Safari/537.36"
+)
+
+var headerProfiles = []*HeaderProfile{
+	{
+		Name: "chrome",
+		Header: http.Header{
+			"User-Agent":                {chromeUserAgent},
+			"Accept":                    {"text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,image/apng,*/*;q=0.8"},
+			"Accept-Language":           {"en-US,en;q=0.9"},
+			"Upgrade-Insecure-Requests": {"1"},
+			"Sec-Fetch-Dest":            {"document"},
+			"Sec-Fetch-Mode":            {"navigate"},
+			"Sec-Fetch-Site":            {"none"},
+			"Sec-Fetch-User":            {"?1"},
+		},
+		Order: chromeOrder,
+	},
+	{
+		Name: "chrome-mac",
+		Header: http.Header{
+			"User-Agent":                {"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_13_4) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/66.0.3359.181 Safari/537.36"},
+			"Accept":                    {"text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,image/apng,*/*;q=0.8"},
+			"Accept-Language":           {"en-US,en;q=0.9"},
+			"Upgrade-Insecure-Requests": {"1"},
+			"Sec-Fetch-Dest":            {"document"},
+			"Sec-Fetch-Mode":            {"navigate"},
+			"Sec-Fetch-Site":            {"none"},
+			"Sec-Fetch-User":            {"?1"},
+		},
+		Order: chromeOrder,
+	},
+	{
+		Name: "firefox",
+		Header: http.Header{
+			"User-Agent":                {"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:60.0) Gecko/20100101 Firefox/60.0"},
+			"Accept":                    {browserAccept},
+			"Accept-Language":           {"en-US,en;q=0.5"},
+			"Upgrade-Insecure-Requests": {"1"},
+			"Sec-Fetch-Dest":            {"document"},
+			"Sec-Fetch-Mode":            {"navigate"},
+			"Sec-Fetch-Site":            {"none"},
+			"Sec-Fetch-User":            {"?1"},
+		},
+		Order: firefoxOrder,
+	},
+	{
+		Name: "firefox-mobile",
+		Header: http.Header{
+			"User-Agent":                {"Mozilla/5.0 (Android 8.0.0; Mobile; rv:60.0) Gecko/60.0 Firefox/60.0"},
+			"Accept":                    {browserAccept},
+			"Accept-Language":           {"en-US,en;q=0.5"},
+			"Upgrade-Insecure-Requests": {"1"},
+			"Sec-Fetch-Dest":            {"document"},
+			"Sec-Fetch-Mode":            {"navigate"},
+			"Sec-Fetch-Site":            {"none"},
+			"Sec-Fetch-User":            {"?1"},
+		},
+		Order: firefoxOrder,
+	},
+	{
+		Name: "safari",
+		Header: http.Header{
+			"User-Agent":      {"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_13_4) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/11.1 Safari/605.1.15"},
+			"Accept":          {"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"},
+			"Accept-Language": {"en-us"},
+		},
+		Order: safariOrder,
+	},
+	{
+		Name: "googlebot",
+		Header: http.Header{
+			"User-Agent": {"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"},
+			"Accept":     {"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"},
+			"From":       {"googlebot(at)googlebot.com"},
+		},
+		Order:   googlebotOrder,
+		Crawler: true,
+	},
+}
+
+// Get a header profile by name.
+func GetHeaderProfile(name string) (*HeaderProfile, error) {
+	for _, p := range headerProfiles {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("Unknown header profile: %s (options: %s)", name, strings.Join(HeaderProfileNames(), ", "))
+}
+
+// Get the names of all known header profiles.
+func HeaderProfileNames() []string {
+	names := make([]string, 0, len(headerProfiles))
+	for _, p := range headerProfiles {
+		names = append(names, p.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Pick a header profile at random.
+func RandomHeaderProfile() *HeaderProfile {
+	return headerProfiles[rand.Intn(len(headerProfiles))]
+}
+
+// Accept-Language values seen from real browsers
+var acceptLanguages = []string{
+	"en-US,en;q=0.9",
+	"en-US,en;q=0.5",
+	"en-GB,en;q=0.9,en-US;q=0.8",
+	"en-CA,en;q=0.9,fr-CA;q=0.8",
+	"en-AU,en;q=0.9",
+	"de-DE,de;q=0.9,en-US;q=0.8,en;q=0.7",
+	"fr-FR,fr;q=0.9,en-US;q=0.8,en;q=0.7",
+	"es-ES,es;q=0.9,en;q=0.8",
+}
+
+// Build a random browser fingerprint: a browser profile with a randomly
+// chosen Accept-Language.  Using the result for every request in a scan keeps
+// the fingerprint consistent, which per-request rotation does not.
+func RandomFingerprint() *HeaderProfile {
+	browsers := make([]*HeaderProfile, 0, len(headerProfiles))
+	for _, p := range headerProfiles {
+		if !p.Crawler {
+			browsers = append(browsers, p)
+		}
+	}
+	base := browsers[rand.Intn(len(browsers))]
+	fp := &HeaderProfile{
+		Name:   base.Name,
+		Header: base.Header.Clone(),
+		Order:  base.Order,
+	}
+	fp.Header.Set("Accept-Language", acceptLanguages[rand.Intn(len(acceptLanguages))])
+	return fp
+}
+
+// Apply the profile to a set of headers.  Headers already present are kept so
+// that explicitly requested headers always win.
+func (p *HeaderProfile) Apply(header http.Header) {
+	for k, v := range p.Header {
+		if _, ok := header[k]; !ok {
+			header[k] = v
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestGetHeaderProfile(t *testing.T) {
+	for _, name := range []string{"chrome", "firefox-mobile", "googlebot"} {
+		p, err := GetHeaderProfile(name)
+		if err != nil {
+			t.Errorf("Expected profile %s, got error: %v", name, err)
+			continue
+		}
+		if p.Header.Get("User-Agent") == "" {
+			t.Errorf("Profile %s has no User-Agent.", name)
+		}
+	}
+	if _, err := GetHeaderProfile("netscape"); err == nil {
+		t.Error("Expected error for unknown profile.")
+	}
+}
+
+func TestRandomHeaderProfile(t *testing.T) {
+	if RandomHeaderProfile() == nil {
+		t.Error("Expected a random profile, got nil.")
+	}
+}
+
+func TestMakeRequest_Profile(t *testing.T) {
+	profile, _ := GetHeaderProfile("chrome")
+	c := &httpClient{UserAgent: "default", Profile: profile}
+	u := &url.URL{Scheme: "http", Host: "localhost", Path: "/"}
+	header := http.Header{"Accept": {"text/plain"}}
+	req := c.makeRequest(u, "GET", "", header)
+	if req.Header.Get("User-Agent") != profile.Header.Get("User-Agent") {
+		t.Errorf("Expected profile User-Agent, got %s", req.Header.Get("User-Agent"))
+	}
+	if req.Header.Get("Accept") != "text/plain" {
+		t.Errorf("Expected explicit Accept header to win, got %s", req.Header.Get("Accept"))
+	}
+	if req.Header.Get("Sec-Fetch-Mode") != "navigate" {
+		t.Errorf("Expected Sec-Fetch-Mode from profile.")
+	}
+	if _, ok := header["User-Agent"]; ok {
+		t.Error("Task headers were modified by request.")
+	}
+}
+
+func TestPCFGet_RandomProfiles(t *testing.T) {
+	fac, _ := NewProxyClientFactory([]string{}, time.Nanosecond, "")
+	fac.SetRandomProfiles(false)
+	if cli := fac.Get().(*httpClient); cli.Profile == nil || cli.RandomProfile {
+		t.Error("Expected a fixed random profile per client.")
+	}
+	fac.SetRandomProfiles(true)
+	if cli := fac.Get().(*httpClient); !cli.RandomProfile {
+		t.Error("Expected per-request random profiles.")
+	}
+}
+
+func TestRandomFingerprint(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		fp := RandomFingerprint()
+		if fp.Crawler || fp.Name == "googlebot" {
+			t.Errorf("Crawler profile %s used as fingerprint.", fp.Name)
+		}
+		if fp.Header.Get("Accept-Language") == "" {
+			t.Errorf("Fingerprint has no Accept-Language.")
+		}
+		base, _ := GetHeaderProfile(fp.Name)
+		if base.Header.Get("User-Agent") != fp.Header.Get("User-Agent") {
+			t.Errorf("Fingerprint User-Agent does not match %s profile.", fp.Name)
+		}
+	}
+}
+
+func TestPCFGet_RandomFingerprint(t *testing.T) {
+	fac, _ := NewProxyClientFactory([]string{}, time.Nanosecond, "")
+	fac.SetRandomProfiles(true)
+	fac.SetRandomFingerprint()
+	first := fac.Get().(*httpClient)
+	if first.RandomProfile || first.Profile == nil {
+		t.Fatal("Expected a fixed fingerprint.")
+	}
+	for i := 0; i < 5; i++ {
+		if cli := fac.Get().(*httpClient); cli.Profile != first.Profile {
+			t.Error("Expected the same fingerprint for every client.")
+		}
+	}
+}
+
+func TestMakeRequest_ProfileOrder(t *testing.T) {
+	profile, _ := GetHeaderProfile("firefox")
+	c := &httpClient{UserAgent: "default", Profile: profile, ProfileOrder: true}
+	u := &url.URL{Scheme: "http", Host: "localhost", Path: "/"}
+	req := c.makeRequest(u, "GET", "", nil)
+	order, ok := req.Context().Value(headerOrderKey{}).([]string)
+	if !ok || len(order) == 0 || order[1] != "User-Agent" {
+		t.Errorf("Expected firefox header order, got %v", order)
+	}
+}
+
+func TestPCFGet_HeaderOrder(t *testing.T) {
+	fac, _ := NewProxyClientFactory([]string{}, time.Nanosecond, "")
+	if _, ok := fac.Get().(*httpClient).Client.(*http.Client).Transport.(*http.Transport); !ok {
+		t.Error("Expected default transport.")
+	}
+	fac.SetHeaderOrder([]string{"Host", "User-Agent"})
+	rt, ok := fac.Get().(*httpClient).Client.(*http.Client).Transport.(*RawTransport)
+	if !ok {
+		t.Fatal("Expected RawTransport for ordered headers.")
+	}
+	if len(rt.HeaderOrder) != 2 {
+		t.Errorf("Expected header order to be set, got %v", rt.HeaderOrder)
+	}
+}
@@ -0,0 +1,460 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Idle connections kept per host
+const maxIdleRawConns = 4
+
+type headerOrderKey struct{}
+
+type requestTargetKey struct{}
+
+// Attach a header order to a context, for requests sent by a RawTransport.
+func WithHeaderOrder(ctx context.Context, order []string) context.Context {
+	return context.WithValue(ctx, headerOrderKey{}, order)
+}
+
+// Attach a request-target to a context, for requests sent by a RawTransport.
+// The target is written to the request line exactly as given.
+func WithRequestTarget(ctx context.Context, target string) context.Context {
+	return context.WithValue(ctx, requestTargetKey{}, target)
+}
+
+// The request-target for u without any normalization: the escaped path as
+// it was parsed if that still matches, otherwise the path as it is, so
+// payloads like %2e%2e or /..;/ reach the server untouched.
+func RawRequestTarget(u *url.URL) string {
+	target := u.Path
+	if u.RawPath != "" {
+		if p, err := url.PathUnescape(u.RawPath); err == nil && p == u.Path {
+			target = u.RawPath
+		}
+	}
+	if target == "" {
+		target = "/"
+	}
+	if u.ForceQuery || u.RawQuery != "" {
+		target += "?" + u.RawQuery
+	}
+	return target
+}
+
+// RawTransport is an HTTP/1.1 RoundTripper that writes requests itself, so
+// headers are sent in a chosen order and with exactly the casing given rather
+// than net/http's sorted, canonicalized form.  Some WAFs fingerprint clients
+// by their headers.  It can also send request-targets that net/http would
+// normalize or escape.
+type RawTransport struct {
+	// Dial new connections; defaults to a net.Dialer
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+	// TLS settings for https; ServerName defaults to the request's host
+	TLSClientConfig *tls.Config
+	// Header names, in the order and casing to send them, for requests
+	// without an order of their own.  Headers not listed follow in sorted
+	// order.
+	HeaderOrder []string
+	// Idle connections to keep per host; defaults to maxIdleRawConns
+	MaxIdleConnsPerHost int
+	// Close every connection after a single request
+	DisableKeepAlives bool
+	// Requests in progress per host, and so connections (0 for no limit)
+	MaxConnsPerHost int
+	// Idle connections by scheme and address
+	idle map[string][]*rawConn
+	// Slots for requests in progress by scheme and address
+	slots map[string]chan struct{}
+	sync.Mutex
+}
+
+type rawConn struct {
+	net.Conn
+	br  *bufio.Reader
+	key string
+}
+
+// Default headers to send, in order, when none is given.  Host is always
+// sent first unless listed elsewhere.
+var DefaultHeaderOrder = []string{
+	"Host",
+	"Connection",
+	"User-Agent",
+	"Accept",
+	"Accept-Encoding",
+	"Accept-Language",
+}
+
+func (t *RawTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return nil, fmt.Errorf("Unsupported protocol scheme: %s", req.URL.Scheme)
+	}
+	release, err := t.acquireSlot(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.roundTripConn(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	if body, ok := resp.Body.(*rawBody); ok {
+		body.release = release
+	} else {
+		release()
+	}
+	return resp, nil
+}
+
+// Wait for a slot for a request to the request's host, if they are limited.
+// The returned function releases the slot.
+func (t *RawTransport) acquireSlot(req *http.Request) (func(), error) {
+	if t.MaxConnsPerHost <= 0 {
+		return func() {}, nil
+	}
+	key := connKey(req.URL.Scheme, req.URL.Host)
+	t.Lock()
+	if t.slots == nil {
+		t.slots = make(map[string]chan struct{})
+	}
+	slots, ok := t.slots[key]
+	if !ok {
+		slots = make(chan struct{}, t.MaxConnsPerHost)
+		t.slots[key] = slots
+	}
+	t.Unlock()
+	select {
+	case slots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	once := sync.Once{}
+	return func() {
+		once.Do(func() { <-slots })
+	}, nil
+}
+
+func (t *RawTransport) roundTripConn(req *http.Request) (*http.Response, error) {
+	// A reused connection may have been closed by the server while idle, so
+	// retry once on a new connection.
+	conn, reused, err := t.getConn(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.roundTrip(conn, reused, req)
+	if err != nil && reused && req.Body == nil {
+		conn, reused, err = t.dial(req)
+		if err != nil {
+			return nil, err
+		}
+		resp, err = t.roundTrip(conn, reused, req)
+	}
+	return resp, err
+}
+
+// Close all idle connections.
+func (t *RawTransport) CloseIdleConnections() {
+	t.Lock()
+	defer t.Unlock()
+	for _, conns := range t.idle {
+		for _, c := range conns {
+			c.Close()
+		}
+	}
+	t.idle = nil
+}
+
+func (t *RawTransport) roundTrip(conn *rawConn, reused bool, req *http.Request) (*http.Response, error) {
+	if trace := httptrace.ContextClientTrace(req.Context()); trace != nil && trace.GotConn != nil {
+		trace.GotConn(httptrace.GotConnInfo{Conn: conn.Conn, Reused: reused})
+	}
+	if deadline, ok := req.Context().Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if err := t.writeRequest(conn, req); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	trace := httptrace.ContextClientTrace(req.Context())
+	if trace != nil && trace.GotFirstResponseByte != nil {
+		if _, err := conn.br.Peek(1); err == nil {
+			trace.GotFirstResponseByte()
+		}
+	}
+	resp, err := http.ReadResponse(conn.br, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	reusable := !resp.Close && !req.Close && !t.DisableKeepAlives
+	if resp.Body == http.NoBody || req.Method == "HEAD" {
+		if reusable {
+			t.putIdle(conn)
+		} else {
+			conn.Close()
+		}
+		return resp, nil
+	}
+	resp.Body = &rawBody{ReadCloser: resp.Body, t: t, conn: conn, reusable: reusable}
+	return resp, nil
+}
+
+// Write the request line, headers, and body.
+func (t *RawTransport) writeRequest(conn *rawConn, req *http.Request) error {
+	w := bufio.NewWriter(conn)
+	target := req.URL.RequestURI()
+	if t, ok := req.Context().Value(requestTargetKey{}).(string); ok {
+		target = t
+	}
+	fmt.Fprintf(w, "%s %s HTTP/1.1\r\n", req.Method, target)
+
+	header := make(http.Header, len(req.Header)+2)
+	for k, v := range req.Header {
+		header[k] = v
+	}
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	header["Host"] = []string{host}
+	if t.DisableKeepAlives && headerKey(header, "Connection") == "" {
+		header["Connection"] = []string{"close"}
+	}
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return err
+		}
+		req.Body.Close()
+		header["Content-Length"] = []string{fmt.Sprintf("%d", len(body))}
+	}
+	order := t.HeaderOrder
+	if o, ok := req.Context().Value(headerOrderKey{}).([]string); ok {
+		order = o
+	}
+	trace := httptrace.ContextClientTrace(req.Context())
+	writeOrderedHeader(w, header, order, trace)
+	if trace != nil && trace.WroteHeaders != nil {
+		trace.WroteHeaders()
+	}
+	w.WriteString("\r\n")
+	w.Write(body)
+	err := w.Flush()
+	if trace != nil && trace.WroteRequest != nil {
+		trace.WroteRequest(httptrace.WroteRequestInfo{Err: err})
+	}
+	return err
+}
+
+// Write headers listed in order first, with the casing given there, then the
+// rest sorted.  Each header written is passed to the trace, if any.
+func writeOrderedHeader(w io.Writer, header http.Header, order []string, trace *httptrace.ClientTrace) {
+	written := make(map[string]bool, len(header))
+	writeHeader := func(name, key string) {
+		for _, v := range header[key] {
+			fmt.Fprintf(w, "%s: %s\r\n", name, v)
+		}
+		if trace != nil && trace.WroteHeaderField != nil {
+			trace.WroteHeaderField(name, header[key])
+		}
+		written[key] = true
+	}
+	if _, ok := header["Host"]; ok && !containsFold(order, "Host") {
+		writeHeader("Host", "Host")
+	}
+	for _, name := range order {
+		key := headerKey(header, name)
+		if key != "" && !written[key] {
+			writeHeader(name, key)
+		}
+	}
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		if !written[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		writeHeader(k, k)
+	}
+}
+
+// Find the key in header matching name, ignoring case.
+func headerKey(header http.Header, name string) string {
+	if _, ok := header[name]; ok {
+		return name
+	}
+	if canon := http.CanonicalHeaderKey(name); header[canon] != nil {
+		return canon
+	}
+	for k := range header {
+		if strings.EqualFold(k, name) {
+			return k
+		}
+	}
+	return ""
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get an idle connection for the request, or dial a new one.
+func (t *RawTransport) getConn(req *http.Request) (*rawConn, bool, error) {
+	key := connKey(req.URL.Scheme, req.URL.Host)
+	t.Lock()
+	if conns := t.idle[key]; len(conns) > 0 {
+		conn := conns[len(conns)-1]
+		t.idle[key] = conns[:len(conns)-1]
+		t.Unlock()
+		return conn, true, nil
+	}
+	t.Unlock()
+	return t.dial(req)
+}
+
+func (t *RawTransport) dial(req *http.Request) (*rawConn, bool, error) {
+	addr := canonicalAddr(req.URL.Scheme, req.URL.Host)
+	dial := t.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	conn, err := dial(req.Context(), "tcp", addr)
+	if err != nil {
+		return nil, false, err
+	}
+	if req.URL.Scheme == "https" {
+		cfg := &tls.Config{}
+		if t.TLSClientConfig != nil {
+			cfg = t.TLSClientConfig.Clone()
+		}
+		if cfg.ServerName == "" {
+			cfg.ServerName = req.URL.Hostname()
+		}
+		tlsConn := tls.Client(conn, cfg)
+		if deadline, ok := req.Context().Deadline(); ok {
+			tlsConn.SetDeadline(deadline)
+		}
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, false, err
+		}
+		conn = tlsConn
+	}
+	return &rawConn{
+		Conn: conn,
+		br:   bufio.NewReader(conn),
+		key:  connKey(req.URL.Scheme, req.URL.Host),
+	}, false, nil
+}
+
+// Keep a connection for reuse.
+func (t *RawTransport) putIdle(conn *rawConn) {
+	conn.SetDeadline(time.Time{})
+	t.Lock()
+	defer t.Unlock()
+	if t.idle == nil {
+		t.idle = make(map[string][]*rawConn)
+	}
+	maxIdle := t.MaxIdleConnsPerHost
+	if maxIdle <= 0 {
+		maxIdle = maxIdleRawConns
+	}
+	if len(t.idle[conn.key]) >= maxIdle {
+		conn.Close()
+		return
+	}
+	t.idle[conn.key] = append(t.idle[conn.key], conn)
+}
+
+func connKey(scheme, host string) string {
+	return scheme + "://" + canonicalAddr(scheme, host)
+}
+
+// Add the default port if needed.
+func canonicalAddr(scheme, host string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	if scheme == "https" {
+		return net.JoinHostPort(strings.Trim(host, "[]"), "443")
+	}
+	return net.JoinHostPort(strings.Trim(host, "[]"), "80")
+}
+
+// rawBody returns its connection for reuse once the body has been read.
+type rawBody struct {
+	io.ReadCloser
+	t        *RawTransport
+	conn     *rawConn
+	eof      bool
+	reusable bool
+	closed   bool
+	// Release the request's slot, if any
+	release func()
+}
+
+func (b *rawBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.eof = true
+	}
+	return n, err
+}
+
+func (b *rawBody) Close() error {
+	if b.closed {
+		return errors.New("Body already closed.")
+	}
+	b.closed = true
+	if b.release != nil {
+		defer b.release()
+	}
+	if !b.eof || !b.reusable {
+		// Don't wait to drain what's left
+		b.conn.Close()
+		b.ReadCloser.Close()
+		return nil
+	}
+	if err := b.ReadCloser.Close(); err != nil {
+		b.conn.Close()
+		return nil
+	}
+	b.t.putIdle(b.conn)
+	return nil
+}
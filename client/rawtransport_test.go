@@ -0,0 +1,328 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Serve canned responses on a raw listener, recording each request's header
+// lines and counting connections.
+type rawServer struct {
+	ln       net.Listener
+	requests chan []string
+	conns    chan bool
+}
+
+func newRawServer(t *testing.T) *rawServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen: %s", err)
+	}
+	s := &rawServer{ln: ln, requests: make(chan []string, 10), conns: make(chan bool, 10)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.conns <- true
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *rawServer) serve(conn net.Conn) {
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+	for {
+		var lines []string
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			if line == "" {
+				break
+			}
+			lines = append(lines, line)
+		}
+		s.requests <- lines
+		conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok"))
+	}
+}
+
+func (s *rawServer) Close() {
+	s.ln.Close()
+}
+
+func TestRawTransport_Order(t *testing.T) {
+	s := newRawServer(t)
+	defer s.Close()
+	transport := &RawTransport{HeaderOrder: []string{"user-agent", "HOST", "Accept"}}
+	req, _ := http.NewRequest("GET", "http://"+s.ln.Addr().String()+"/path?q=1", nil)
+	req.Header.Set("Accept", "*/*")
+	req.Header.Set("User-Agent", "test")
+	req.Header.Set("X-Other", "1")
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ok" {
+		t.Errorf("Unexpected body: %q", body)
+	}
+	lines := <-s.requests
+	expected := []string{
+		"GET /path?q=1 HTTP/1.1",
+		"user-agent: test",
+		"HOST: " + s.ln.Addr().String(),
+		"Accept: */*",
+		"X-Other: 1",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, lines)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("Line %d: expected %q, got %q", i, expected[i], lines[i])
+		}
+	}
+}
+
+func TestRawTransport_ContextOrder(t *testing.T) {
+	s := newRawServer(t)
+	defer s.Close()
+	transport := &RawTransport{}
+	req, _ := http.NewRequest("GET", "http://"+s.ln.Addr().String()+"/", nil)
+	req.Header.Set("Accept", "*/*")
+	req = req.WithContext(WithHeaderOrder(req.Context(), []string{"accept", "Host"}))
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	resp.Body.Close()
+	lines := <-s.requests
+	if len(lines) != 3 || lines[1] != "accept: */*" || !strings.HasPrefix(lines[2], "Host: ") {
+		t.Errorf("Unexpected request: %v", lines)
+	}
+}
+
+func TestRawTransport_RequestTarget(t *testing.T) {
+	s := newRawServer(t)
+	defer s.Close()
+	cli := &httpClient{
+		Client:    &http.Client{Transport: &RawTransport{}, Timeout: 5 * time.Second},
+		RawTarget: true,
+	}
+	for _, word := range []string{"%2e%2e/admin", "..;/admin", "a/./b//c"} {
+		u := &url.URL{Scheme: "http", Host: s.ln.Addr().String(), Path: "/app/" + word}
+		resp, err := cli.Request(u, "", "GET", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		lines := <-s.requests
+		if expected := "GET /app/" + word + " HTTP/1.1"; lines[0] != expected {
+			t.Errorf("Expected %q, got %q", expected, lines[0])
+		}
+	}
+}
+
+func TestRawTransport_AbsoluteURI(t *testing.T) {
+	s := newRawServer(t)
+	defer s.Close()
+	cli := &httpClient{
+		Client:      &http.Client{Transport: &RawTransport{}, Timeout: 5 * time.Second},
+		AbsoluteURI: true,
+	}
+	u := &url.URL{Scheme: "http", Host: s.ln.Addr().String(), Path: "/a b"}
+	resp, err := cli.Request(u, "internal.example", "GET", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	lines := <-s.requests
+	if expected := "GET http://" + u.Host + "/a%20b HTTP/1.1"; lines[0] != expected {
+		t.Errorf("Expected %q, got %q", expected, lines[0])
+	}
+	if lines[1] != "Host: internal.example" {
+		t.Errorf("Unexpected Host header: %q", lines[1])
+	}
+}
+
+func TestRawRequestTarget(t *testing.T) {
+	cases := map[string]string{
+		"http://localhost":                 "/",
+		"http://localhost/%2e%2e/admin":    "/%2e%2e/admin",
+		"http://localhost/..;/admin?a=%41": "/..;/admin?a=%41",
+		"http://localhost/a%2fb?":          "/a%2fb?",
+	}
+	for in, expected := range cases {
+		u, _ := url.Parse(in)
+		if got := RawRequestTarget(u); got != expected {
+			t.Errorf("RawRequestTarget(%s): expected %s, got %s", in, expected, got)
+		}
+	}
+	// The escaped form is stale once the path changes
+	u, _ := url.Parse("http://localhost/%2e%2e/")
+	u.Path += "%2e"
+	if got := RawRequestTarget(u); got != "/../%2e" {
+		t.Errorf("Expected /../%%2e, got %s", got)
+	}
+}
+
+func TestRawTransport_Reuse(t *testing.T) {
+	s := newRawServer(t)
+	defer s.Close()
+	transport := &RawTransport{}
+	defer transport.CloseIdleConnections()
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", "http://"+s.ln.Addr().String()+"/", nil)
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		<-s.requests
+	}
+	if len(s.conns) != 1 {
+		t.Errorf("Expected a single connection, got %d", len(s.conns))
+	}
+}
+
+func TestRawTransport_TLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Test")))
+	}))
+	defer server.Close()
+	cli := &http.Client{
+		Transport: &RawTransport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		Timeout:   5 * time.Second,
+	}
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	req.Header.Set("X-Test", "secure")
+	resp, err := cli.Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer resp.Body.Close()
+	if body, _ := ioutil.ReadAll(resp.Body); string(body) != "secure" {
+		t.Errorf("Unexpected body: %q", body)
+	}
+}
+
+func TestRawTransport_Timeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen: %s", err)
+	}
+	defer ln.Close()
+	go func() {
+		// Accept and never answer
+		if conn, err := ln.Accept(); err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequest("GET", "http://"+ln.Addr().String()+"/", nil)
+	if _, err := (&RawTransport{}).RoundTrip(req.WithContext(ctx)); err == nil {
+		t.Error("Expected timeout error.")
+	}
+}
+
+func TestRawTransport_DisableKeepAlives(t *testing.T) {
+	s := newRawServer(t)
+	defer s.Close()
+	transport := &RawTransport{DisableKeepAlives: true}
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", "http://"+s.ln.Addr().String()+"/", nil)
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		lines := <-s.requests
+		if len(lines) != 3 || lines[2] != "Connection: close" {
+			t.Errorf("Unexpected request: %v", lines)
+		}
+	}
+	if len(s.conns) != 2 {
+		t.Errorf("Expected 2 connections, got %d", len(s.conns))
+	}
+}
+
+func TestRawTransport_MaxConnsPerHost(t *testing.T) {
+	s := newRawServer(t)
+	defer s.Close()
+	transport := &RawTransport{MaxConnsPerHost: 1}
+	defer transport.CloseIdleConnections()
+	get := func(timeout time.Duration) (*http.Response, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		req, _ := http.NewRequest("GET", "http://"+s.ln.Addr().String()+"/", nil)
+		return transport.RoundTrip(req.WithContext(ctx))
+	}
+	first, err := get(5 * time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, err := get(50 * time.Millisecond); err == nil {
+		t.Error("Expected to wait for the first request.")
+	}
+	ioutil.ReadAll(first.Body)
+	first.Body.Close()
+	second, err := get(5 * time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error after release: %s", err)
+	}
+	ioutil.ReadAll(second.Body)
+	second.Body.Close()
+}
+
+func TestCanonicalAddr(t *testing.T) {
+	cases := map[string]string{
+		"http localhost":      "localhost:80",
+		"https localhost":     "localhost:443",
+		"http localhost:8080": "localhost:8080",
+		"https [::1]":         "[::1]:443",
+		"http [::1]:8080":     "[::1]:8080",
+	}
+	for in, expected := range cases {
+		pieces := strings.SplitN(in, " ", 2)
+		if got := canonicalAddr(pieces[0], pieces[1]); got != expected {
+			t.Errorf("canonicalAddr(%s): expected %s, got %s", in, expected, got)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Matir/webborer/logging"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Shortest time between refreshes, so a page that always needs more than the
+// token gives isn't a refresh on every request
+const minTokenRefreshInterval = 30 * time.Second
+
+// Largest token response read from a refresh URL
+const maxTokenResponseSize = 64 * 1024
+
+// Most URLs remembered as having worked with a token
+const maxKnownGoodURLs = 1000
+
+// A TokenRefresher gets a new bearer token when one stops working, shared by
+// all clients.  A token has stopped working when the server says so, with
+// error="invalid_token" in a Bearer challenge, or when a request gets a 401
+// from a URL that an earlier request succeeded on.  Other 401s are just
+// protected pages.  The new token is taken from the output of a command or
+// the body of a URL, either as it is or from the access_token or token field
+// of a JSON object.
+type TokenRefresher struct {
+	command []string
+	url     string
+	client  *http.Client
+	timeout time.Duration
+	// The latest token, if refreshed at all
+	token string
+	// Incremented for every new token
+	generation int
+	// URLs requests have succeeded on
+	good map[string]bool
+	// When the token was last refreshed, or refreshing failed
+	refreshed time.Time
+	// Closed when the refresh in progress, if any, is finished
+	refreshing chan struct{}
+	sync.Mutex
+}
+
+// Create a TokenRefresher that runs command, or fetches refreshURL, to get a
+// new token.  If both are given, the command is used.
+func NewTokenRefresher(command, refreshURL string, timeout time.Duration) *TokenRefresher {
+	return &TokenRefresher{
+		command: strings.Fields(command),
+		url:     refreshURL,
+		client:  &http.Client{Timeout: timeout},
+		timeout: timeout,
+		good:    make(map[string]bool),
+	}
+}
+
+// Replace the bearer token in header with the latest token, if there's been
+// a refresh.  Returns the generation of the token sent, or -1 if there is no
+// bearer token.
+func (r *TokenRefresher) apply(header http.Header) int {
+	if !isBearer(header) {
+		return -1
+	}
+	r.Lock()
+	defer r.Unlock()
+	if r.token != "" {
+		header.Set("Authorization", "Bearer "+r.token)
+	}
+	return r.generation
+}
+
+// Note how a request for u sent with the token of generation went.  Returns
+// true if the token has changed since and the request should be retried with
+// the new one.
+func (r *TokenRefresher) update(generation int, u *url.URL, resp *http.Response) bool {
+	r.Lock()
+	if generation != r.generation {
+		r.Unlock()
+		return resp.StatusCode == http.StatusUnauthorized
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		if resp.StatusCode < 400 && len(r.good) < maxKnownGoodURLs {
+			r.good[u.String()] = true
+		}
+		r.Unlock()
+		return false
+	}
+	if !r.good[u.String()] && !tokenExpired(resp) {
+		r.Unlock()
+		return false
+	}
+	// Other clients wait for a refresh in progress, since their requests
+	// would fail with the old token
+	if wait := r.refreshing; wait != nil {
+		r.Unlock()
+		<-wait
+		r.Lock()
+		defer r.Unlock()
+		return generation != r.generation
+	}
+	if time.Since(r.refreshed) < minTokenRefreshInterval {
+		r.Unlock()
+		return false
+	}
+	r.refreshed = time.Now()
+	done := make(chan struct{})
+	r.refreshing = done
+	r.Unlock()
+
+	token, err := r.fetch()
+	r.Lock()
+	defer r.Unlock()
+	r.refreshing = nil
+	close(done)
+	if err != nil {
+		logging.Logf(logging.LogWarning, "Unable to refresh token: %s", err.Error())
+		return false
+	}
+	logging.Logf(logging.LogInfo, "Refreshed bearer token after a 401.")
+	r.token = token
+	r.generation++
+	return true
+}
+
+// Check if a 401 says the bearer token has expired or been revoked.
+func tokenExpired(resp *http.Response) bool {
+	for _, challenge := range resp.Header["Www-Authenticate"] {
+		challenge = strings.ToLower(challenge)
+		if strings.HasPrefix(challenge, "bearer") && strings.Contains(challenge, "invalid_token") {
+			return true
+		}
+	}
+	return false
+}
+
+// Get a new token from the command or URL.
+func (r *TokenRefresher) fetch() (string, error) {
+	var out []byte
+	var err error
+	if len(r.command) > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		if r.timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		}
+		defer cancel()
+		out, err = exec.CommandContext(ctx, r.command[0], r.command[1:]...).Output()
+	} else if r.url != "" {
+		out, err = r.get()
+	} else {
+		return "", errors.New("no refresh command or URL")
+	}
+	if err != nil {
+		return "", err
+	}
+	token := parseToken(out)
+	if token == "" {
+		return "", errors.New("no token returned")
+	}
+	return token, nil
+}
+
+func (r *TokenRefresher) get() ([]byte, error) {
+	resp, err := r.client.Get(r.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", r.url, resp.Status)
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, maxTokenResponseSize))
+}
+
+// Get the token from output, either the whole of it or a field of a JSON
+// object.
+func parseToken(out []byte) string {
+	text := strings.TrimSpace(string(out))
+	if !strings.HasPrefix(text, "{") {
+		return strings.TrimPrefix(text, "Bearer ")
+	}
+	var fields struct {
+		AccessToken string `json:"access_token"`
+		Token       string `json:"token"`
+	}
+	if err := json.Unmarshal([]byte(text), &fields); err != nil {
+		return ""
+	}
+	if fields.AccessToken != "" {
+		return fields.AccessToken
+	}
+	return fields.Token
+}
+
+func isBearer(header http.Header) bool {
+	auth := header.Get("Authorization")
+	return len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ")
+}
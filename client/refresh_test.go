@@ -0,0 +1,116 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func codeResponse(code int) *http.Response {
+	return &http.Response{StatusCode: code, Body: ioutil.NopCloser(strings.NewReader(""))}
+}
+
+func TestParseToken(t *testing.T) {
+	cases := map[string]string{
+		"abc\n":                           "abc",
+		"Bearer abc":                      "abc",
+		`{"access_token": "abc"}`:         "abc",
+		`{"token": "abc", "expires": 60}`: "abc",
+		`{"other": "abc"}`:                "",
+		`{bad json`:                       "",
+	}
+	for out, expected := range cases {
+		if got := parseToken([]byte(out)); got != expected {
+			t.Errorf("Expected %q from %q, got %q", expected, out, got)
+		}
+	}
+}
+
+func TestTokenRefresher(t *testing.T) {
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		fmt.Fprintf(w, `{"access_token": "new%d"}`, fetches)
+	}))
+	defer server.Close()
+	refresher := NewTokenRefresher("", server.URL, time.Second)
+	mockClient := makeMockHttpClient(codeResponse(401), codeResponse(200), codeResponse(401), codeResponse(200))
+	c := &httpClient{Client: mockClient, Refresher: refresher}
+	u := &url.URL{Scheme: "http", Host: "localhost", Path: "/"}
+	header := http.Header{"Authorization": []string{"Bearer old"}}
+
+	// A token that has never worked isn't refreshed
+	resp, _ := c.Request(u, "", "GET", header)
+	if resp.StatusCode != 401 || fetches != 0 {
+		t.Fatalf("Expected a 401 without a refresh, got %d after %d fetches", resp.StatusCode, fetches)
+	}
+	c.Request(u, "", "GET", header)
+	resp, _ = c.Request(u, "", "GET", header)
+	if fetches != 1 {
+		t.Fatalf("Expected a refresh, got %d fetches", fetches)
+	}
+	if resp.StatusCode != 200 || resp.Request.Header.Get("Authorization") != "Bearer new1" {
+		t.Errorf("Expected a retry with the new token, got %d with %q", resp.StatusCode, resp.Request.Header.Get("Authorization"))
+	}
+	if header.Get("Authorization") != "Bearer old" {
+		t.Errorf("Expected the caller's header left alone, got %q", header.Get("Authorization"))
+	}
+	// Refreshes are limited, however a page responds
+	mockClient.resps = []*http.Response{codeResponse(401)}
+	c.Request(u, "", "GET", header)
+	if fetches != 1 {
+		t.Errorf("Expected no second refresh so soon, got %d fetches", fetches)
+	}
+}
+
+func TestTokenRefresher_Expired(t *testing.T) {
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		fmt.Fprint(w, "new")
+	}))
+	defer server.Close()
+	refresher := NewTokenRefresher("", server.URL, time.Second)
+	expired := codeResponse(401)
+	expired.Header = http.Header{"Www-Authenticate": []string{`Bearer error="invalid_token"`}}
+	mockClient := makeMockHttpClient(codeResponse(200), codeResponse(401), expired, codeResponse(200))
+	c := &httpClient{Client: mockClient, Refresher: refresher}
+	header := http.Header{"Authorization": []string{"Bearer old"}}
+
+	c.Request(&url.URL{Scheme: "http", Host: "localhost", Path: "/a"}, "", "GET", header)
+	// A 401 from another page is just a protected page
+	c.Request(&url.URL{Scheme: "http", Host: "localhost", Path: "/private"}, "", "GET", header)
+	if fetches != 0 {
+		t.Fatalf("Expected no refresh for a protected page, got %d fetches", fetches)
+	}
+	resp, _ := c.Request(&url.URL{Scheme: "http", Host: "localhost", Path: "/b"}, "", "GET", header)
+	if fetches != 1 || resp.StatusCode != 200 {
+		t.Errorf("Expected a refresh when the token is said to be invalid, got %d fetches and %d", fetches, resp.StatusCode)
+	}
+}
+
+func TestTokenRefresher_NoBearer(t *testing.T) {
+	refresher := NewTokenRefresher("", "http://localhost:1/", time.Second)
+	if g := refresher.apply(http.Header{"Authorization": []string{"Basic abc"}}); g != -1 {
+		t.Errorf("Expected no generation without a bearer token, got %d", g)
+	}
+}
@@ -0,0 +1,311 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// How long looked up addresses are kept.  The TTLs of the DNS records
+	// aren't available from net.Resolver, so this is used whatever they are.
+	resolverCacheTTL = 5 * time.Minute
+	// Delay before also trying addresses of the other family, as in net.Dialer
+	resolverFallbackDelay = 300 * time.Millisecond
+	// Shortest time given to each address when they share the timeout
+	minAddressTimeout = 2 * time.Second
+)
+
+// Resolver looks up hostnames for direct connections, caching the results
+// for every client of a factory.  Addresses can be overridden per host and
+// port, like curl's --resolve, and lookups can be made with a particular DNS
+// server instead of the system's.
+type Resolver struct {
+	// Addresses by "host:port", or "host:*" for any port
+	overrides map[string]string
+	resolver  *net.Resolver
+	cache     map[string]resolverEntry
+	// Timeout for each address tried
+	timeout time.Duration
+	// Address family to try first, 4 or 6, or 0 for the order looked up
+	family int
+	sync.Mutex
+}
+
+type resolverEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+// Create a Resolver using the DNS server at server (with an optional port),
+// or the system resolver if server is empty.
+func NewResolver(server string, timeout time.Duration) *Resolver {
+	r := &Resolver{
+		overrides: make(map[string]string),
+		resolver:  net.DefaultResolver,
+		cache:     make(map[string]resolverEntry),
+		timeout:   timeout,
+	}
+	if server != "" {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(strings.Trim(server, "[]"), "53")
+		}
+		r.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				d := &net.Dialer{Timeout: timeout}
+				return d.DialContext(ctx, network, server)
+			},
+		}
+	}
+	return r
+}
+
+// Try addresses of the given family, 4 or 6, before those of the other, for
+// hosts with both.  0 keeps the order they were looked up in.
+func (r *Resolver) SetPreferredFamily(family int) {
+	r.family = family
+}
+
+// Put the addresses of the preferred family first, otherwise keeping their
+// order.
+func (r *Resolver) ordered(addrs []string) []string {
+	if r.family == 0 {
+		return addrs
+	}
+	sorted := make([]string, 0, len(addrs))
+	var rest []string
+	for _, addr := range addrs {
+		if addressFamily(addr) == r.family {
+			sorted = append(sorted, addr)
+		} else {
+			rest = append(rest, addr)
+		}
+	}
+	return append(sorted, rest...)
+}
+
+// Get the family of an IP address, 4 or 6.
+func addressFamily(addr string) int {
+	if ip := net.ParseIP(addr); ip != nil && ip.To4() != nil {
+		return 4
+	}
+	return 6
+}
+
+// Add an override in the form host:port:address, where port may be * for
+// any port.
+func (r *Resolver) AddOverride(spec string) error {
+	pieces := strings.SplitN(spec, ":", 3)
+	if len(pieces) != 3 || pieces[0] == "" {
+		return fmt.Errorf("Invalid resolve override %q, expected host:port:address.", spec)
+	}
+	if pieces[1] != "*" {
+		if port, err := strconv.Atoi(pieces[1]); err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("Invalid port in resolve override %q.", spec)
+		}
+	}
+	addr := strings.Trim(pieces[2], "[]")
+	if net.ParseIP(addr) == nil {
+		return fmt.Errorf("Invalid address in resolve override %q.", spec)
+	}
+	r.overrides[strings.ToLower(pieces[0])+":"+pieces[1]] = addr
+	return nil
+}
+
+// Get the addresses for host when connecting to port.
+func (r *Resolver) Lookup(ctx context.Context, host, port string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+	host = strings.ToLower(host)
+	for _, key := range []string{host + ":" + port, host + ":*"} {
+		if addr, ok := r.overrides[key]; ok {
+			return []string{addr}, nil
+		}
+	}
+	r.Lock()
+	entry, ok := r.cache[host]
+	r.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return r.ordered(entry.addrs), nil
+	}
+	addrs, err := r.resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	r.Lock()
+	r.cache[host] = resolverEntry{addrs: addrs, expires: time.Now().Add(resolverCacheTTL)}
+	r.Unlock()
+	return r.ordered(addrs), nil
+}
+
+// Resolve the host of addr and connect to the first address that answers.
+func (r *Resolver) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return r.dial(ctx, network, addr, (&net.Dialer{Timeout: r.timeout}).DialContext)
+}
+
+// Get a function that resolves the host of addr, then connects with dial.
+func (r *Resolver) DialVia(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if dial == nil {
+		return r.DialContext
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return r.dial(ctx, network, addr, dial)
+	}
+}
+
+type dialResult struct {
+	conn    net.Conn
+	err     error
+	primary bool
+}
+
+// Connect to the addresses of the host of addr, like net.Dialer: those of the
+// family looked up first in turn, and those of the other family too if that
+// is slow or fails.
+func (r *Resolver) dial(ctx context.Context, network, addr string, dial func(ctx context.Context, network, addr string) (net.Conn, error)) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := r.Lookup(ctx, host, port)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("No addresses found for %s", host)
+	}
+	primaries, fallbacks := splitFamilies(addrs)
+	if len(fallbacks) == 0 {
+		return r.dialSerial(ctx, network, port, primaries, dial)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan dialResult, 2)
+	start := func(addrs []string, primary bool) {
+		go func() {
+			conn, err := r.dialSerial(ctx, network, port, addrs, dial)
+			results <- dialResult{conn: conn, err: err, primary: primary}
+		}()
+	}
+	start(primaries, true)
+	fallback := time.NewTimer(resolverFallbackDelay)
+	defer fallback.Stop()
+	pending, fellBack := 1, false
+	var primaryErr, fallbackErr error
+	for {
+		select {
+		case <-fallback.C:
+			if !fellBack {
+				start(fallbacks, false)
+				pending, fellBack = pending+1, true
+			}
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				if pending > 0 {
+					// Close the other connection if it is made anyway
+					go func() {
+						if other := <-results; other.conn != nil {
+							other.conn.Close()
+						}
+					}()
+				}
+				return res.conn, nil
+			}
+			if res.primary {
+				primaryErr = res.err
+			} else {
+				fallbackErr = res.err
+			}
+			if !fellBack {
+				fallback.Stop()
+				start(fallbacks, false)
+				pending, fellBack = pending+1, true
+			} else if pending == 0 {
+				if primaryErr != nil {
+					return nil, primaryErr
+				}
+				return nil, fallbackErr
+			}
+		}
+	}
+}
+
+// Connect to each of addrs in turn, sharing the timeout between them.
+func (r *Resolver) dialSerial(ctx context.Context, network, port string, addrs []string, dial func(ctx context.Context, network, addr string) (net.Conn, error)) (net.Conn, error) {
+	var deadline time.Time
+	if r.timeout > 0 {
+		deadline = time.Now().Add(r.timeout)
+	}
+	if d, ok := ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+		deadline = d
+	}
+	var err error
+	for i, ip := range addrs {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		dialCtx, cancel := ctx, func() {}
+		if !deadline.IsZero() {
+			dialCtx, cancel = context.WithDeadline(ctx, addressDeadline(deadline, len(addrs)-i))
+		}
+		var conn net.Conn
+		conn, err = dial(dialCtx, network, net.JoinHostPort(ip, port))
+		cancel()
+		if err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// Get the deadline for the next of remaining addresses sharing the time until
+// deadline, giving each at least minAddressTimeout if there's time.
+func addressDeadline(deadline time.Time, remaining int) time.Time {
+	left := time.Until(deadline)
+	each := left / time.Duration(remaining)
+	if each < minAddressTimeout {
+		each = minAddressTimeout
+		if left < each {
+			each = left
+		}
+	}
+	return time.Now().Add(each)
+}
+
+// Split addresses into those of the family of the first, and the rest.
+func splitFamilies(addrs []string) ([]string, []string) {
+	if len(addrs) == 0 {
+		return addrs, nil
+	}
+	family := addressFamily(addrs[0])
+	var primaries, fallbacks []string
+	for _, addr := range addrs {
+		if addressFamily(addr) == family {
+			primaries = append(primaries, addr)
+		} else {
+			fallbacks = append(fallbacks, addr)
+		}
+	}
+	return primaries, fallbacks
+}
@@ -0,0 +1,128 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestResolver_AddOverride(t *testing.T) {
+	r := NewResolver("", time.Second)
+	for _, good := range []string{"example.com:443:10.0.0.1", "example.com:*:::1", "Example.com:80:[::1]"} {
+		if err := r.AddOverride(good); err != nil {
+			t.Errorf("Unexpected error for %q: %v", good, err)
+		}
+	}
+	for _, bad := range []string{"example.com", "example.com:443", ":443:10.0.0.1", "example.com:http:10.0.0.1", "example.com:443:example.org"} {
+		if err := r.AddOverride(bad); err == nil {
+			t.Errorf("Expected error for %q.", bad)
+		}
+	}
+}
+
+func TestResolver_Lookup(t *testing.T) {
+	r := NewResolver("", time.Second)
+	r.AddOverride("example.com:443:10.0.0.1")
+	r.AddOverride("example.com:*:10.0.0.2")
+	r.cache["cached.example"] = resolverEntry{addrs: []string{"10.0.0.3"}, expires: time.Now().Add(time.Minute)}
+	cases := []struct {
+		host, port, expected string
+	}{
+		{"example.com", "443", "10.0.0.1"},
+		{"EXAMPLE.com", "80", "10.0.0.2"},
+		{"cached.example", "80", "10.0.0.3"},
+		{"192.0.2.1", "80", "192.0.2.1"},
+	}
+	for _, c := range cases {
+		addrs, err := r.Lookup(context.Background(), c.host, c.port)
+		if err != nil || len(addrs) != 1 || addrs[0] != c.expected {
+			t.Errorf("Lookup(%s, %s): expected %s, got %v (%v)", c.host, c.port, c.expected, addrs, err)
+		}
+	}
+}
+
+func TestResolver_DialContext(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen: %s", err)
+	}
+	defer ln.Close()
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	r := NewResolver("", time.Second)
+	r.AddOverride("preprod.invalid:" + port + ":127.0.0.1")
+	conn, err := r.DialContext(context.Background(), "tcp", "preprod.invalid:"+port)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	conn.Close()
+}
+
+func TestResolver_Fallback(t *testing.T) {
+	r := NewResolver("", 30*time.Second)
+	r.cache["dual.example"] = resolverEntry{addrs: []string{"2001:db8::1", "127.0.0.1"}, expires: time.Now().Add(time.Minute)}
+	dialed := make(chan string, 2)
+	dial := r.DialVia(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed <- addr
+		if host, _, _ := net.SplitHostPort(addr); addressFamily(host) == 6 {
+			// Broken IPv6 never answers
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		conn, _ := net.Pipe()
+		return conn, nil
+	})
+	start := time.Now()
+	conn, err := dial(context.Background(), "tcp", "dual.example:80")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	conn.Close()
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected a quick fallback to IPv4, took %s", elapsed)
+	}
+	if first := <-dialed; first != "[2001:db8::1]:80" {
+		t.Errorf("Expected IPv6 to be tried first, got %s", first)
+	}
+	if second := <-dialed; second != "127.0.0.1:80" {
+		t.Errorf("Expected a fallback to IPv4, got %s", second)
+	}
+}
+
+func TestResolver_PreferredFamily(t *testing.T) {
+	addrs := []string{"2001:db8::1", "192.0.2.1", "2001:db8::2", "192.0.2.2"}
+	r := NewResolver("", time.Second)
+	if got := r.ordered(addrs); !reflect.DeepEqual(got, addrs) {
+		t.Errorf("Expected lookup order without a preference, got %v", got)
+	}
+	r.SetPreferredFamily(4)
+	expected := []string{"192.0.2.1", "192.0.2.2", "2001:db8::1", "2001:db8::2"}
+	if got := r.ordered(addrs); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	r.SetPreferredFamily(6)
+	expected = []string{"2001:db8::1", "2001:db8::2", "192.0.2.1", "192.0.2.2"}
+	if got := r.ordered(addrs); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// How long each phase of a request took, as recorded in HAR files.  Phases
+// that didn't happen, such as connecting on a reused connection, are -1.
+type Timings struct {
+	// Waiting for a connection
+	Blocked time.Duration
+	DNS     time.Duration
+	// Connecting, including the TLS handshake
+	Connect time.Duration
+	SSL     time.Duration
+	Send    time.Duration
+	// Waiting for the first byte of the response
+	Wait time.Duration
+	// From starting the request to the first byte of the response
+	TTFB time.Duration
+}
+
+// RequestTrace records the headers written for a request and how long each
+// phase took.  Only the last request is recorded when redirects are
+// followed.
+type RequestTrace struct {
+	mu      sync.Mutex
+	header  http.Header
+	timings Timings
+	// When each phase started
+	start, dnsStart, connectStart, tlsStart, gotConn, wrote time.Time
+}
+
+type requestTraceKey struct{}
+
+func newRequestTrace() *RequestTrace {
+	rt := &RequestTrace{start: time.Now()}
+	rt.reset()
+	return rt
+}
+
+// Record a trace of req.
+func withRequestTrace(req *http.Request) *http.Request {
+	rt := newRequestTrace()
+	ctx := context.WithValue(req.Context(), requestTraceKey{}, rt)
+	ctx = httptrace.WithClientTrace(ctx, rt.clientTrace())
+	return req.WithContext(ctx)
+}
+
+// Get the trace of the request that produced resp, if it was traced.
+func TraceFor(resp *http.Response) *RequestTrace {
+	if resp == nil || resp.Request == nil {
+		return nil
+	}
+	rt, _ := resp.Request.Context().Value(requestTraceKey{}).(*RequestTrace)
+	return rt
+}
+
+// The request headers as they were written, including any added by the
+// transport.
+func (rt *RequestTrace) Header() http.Header {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return rt.header.Clone()
+}
+
+// How long each phase of the request took.
+func (rt *RequestTrace) Timings() Timings {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return rt.timings
+}
+
+// Forget an earlier request, such as one that was redirected.
+func (rt *RequestTrace) reset() {
+	rt.header = make(http.Header)
+	rt.timings = Timings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Send: -1, Wait: -1, TTFB: -1}
+	rt.dnsStart, rt.connectStart, rt.tlsStart = time.Time{}, time.Time{}, time.Time{}
+	rt.gotConn, rt.wrote = time.Time{}, time.Time{}
+}
+
+func (rt *RequestTrace) clientTrace() *httptrace.ClientTrace {
+	// Every hook runs with the lock held
+	locked := func(f func(now time.Time)) {
+		now := time.Now()
+		rt.mu.Lock()
+		defer rt.mu.Unlock()
+		f(now)
+	}
+	return &httptrace.ClientTrace{
+		GetConn: func(string) {
+			locked(func(now time.Time) {
+				if !rt.gotConn.IsZero() {
+					rt.reset()
+					rt.start = now
+				}
+			})
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			locked(func(now time.Time) { rt.dnsStart = now })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			locked(func(now time.Time) {
+				if !rt.dnsStart.IsZero() {
+					rt.timings.DNS = now.Sub(rt.dnsStart)
+				}
+			})
+		},
+		ConnectStart: func(string, string) {
+			locked(func(now time.Time) {
+				if rt.connectStart.IsZero() {
+					rt.connectStart = now
+				}
+			})
+		},
+		ConnectDone: func(string, string, error) {
+			locked(func(now time.Time) {
+				if !rt.connectStart.IsZero() {
+					rt.timings.Connect = now.Sub(rt.connectStart)
+				}
+			})
+		},
+		TLSHandshakeStart: func() {
+			locked(func(now time.Time) { rt.tlsStart = now })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			locked(func(now time.Time) {
+				if !rt.tlsStart.IsZero() {
+					rt.timings.SSL = now.Sub(rt.tlsStart)
+					if rt.timings.Connect >= 0 {
+						rt.timings.Connect += rt.timings.SSL
+					}
+				}
+			})
+		},
+		GotConn: func(httptrace.GotConnInfo) {
+			locked(func(now time.Time) {
+				rt.gotConn = now
+				blocked := now.Sub(rt.start)
+				if rt.timings.DNS > 0 {
+					blocked -= rt.timings.DNS
+				}
+				if rt.timings.Connect > 0 {
+					blocked -= rt.timings.Connect
+				}
+				if blocked < 0 {
+					blocked = 0
+				}
+				rt.timings.Blocked = blocked
+			})
+		},
+		WroteHeaderField: func(key string, value []string) {
+			locked(func(time.Time) {
+				rt.header[key] = append(rt.header[key], value...)
+			})
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			locked(func(now time.Time) {
+				rt.wrote = now
+				if !rt.gotConn.IsZero() {
+					rt.timings.Send = now.Sub(rt.gotConn)
+				}
+			})
+		},
+		GotFirstResponseByte: func() {
+			locked(func(now time.Time) {
+				if !rt.wrote.IsZero() {
+					rt.timings.Wait = now.Sub(rt.wrote)
+				}
+				rt.timings.TTFB = now.Sub(rt.start)
+			})
+		},
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestRequestTrace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	c := &httpClient{Client: &http.Client{}, UserAgent: "test", Trace: true}
+	u, _ := url.Parse(server.URL + "/path")
+	resp, err := c.Request(u, "", "GET", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	resp.Body.Close()
+	trace := TraceFor(resp)
+	if trace == nil {
+		t.Fatal("Expected a trace of the request.")
+	}
+	header := trace.Header()
+	// Accept-Encoding is added by the transport
+	if header.Get("User-Agent") != "test" || header.Get("Accept-Encoding") == "" {
+		t.Errorf("Unexpected headers: %v", header)
+	}
+	timings := trace.Timings()
+	if timings.DNS != -1 || timings.Connect < 0 || timings.Send < 0 || timings.Wait < 0 || timings.TTFB < timings.Wait {
+		t.Errorf("Unexpected timings: %+v", timings)
+	}
+	if TraceFor(&http.Response{}) != nil {
+		t.Error("Expected no trace for an untraced response.")
+	}
+}
+
+func TestRequestTrace_RawTransport(t *testing.T) {
+	s := newRawServer(t)
+	defer s.Close()
+	c := &httpClient{Client: &http.Client{Transport: &RawTransport{}}, UserAgent: "test", Trace: true}
+	u, _ := url.Parse("http://" + s.ln.Addr().String() + "/")
+	resp, err := c.Request(u, "", "GET", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	resp.Body.Close()
+	<-s.requests
+	trace := TraceFor(resp)
+	if trace == nil {
+		t.Fatal("Expected a trace of the request.")
+	}
+	if header := trace.Header(); header.Get("Host") != s.ln.Addr().String() || header.Get("User-Agent") != "test" {
+		t.Errorf("Unexpected headers: %v", header)
+	}
+	if timings := trace.Timings(); timings.Wait < 0 || timings.Send < 0 {
+		t.Errorf("Unexpected timings: %+v", timings)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"github.com/Matir/webborer/benchmark"
+	"github.com/Matir/webborer/logging"
+	ss "github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/worker"
+	"os"
+)
+
+// Run a scan with the configured pipeline and report how fast it went and
+// what limited it.  Without a URL, the built-in test server is scanned.
+func runBenchmark(settings *ss.ScanSettings) {
+	if len(settings.BaseURLs) == 0 {
+		server, err := benchmark.NewServer()
+		if err != nil {
+			logging.Logf(logging.LogFatal, "Unable to start benchmark server: %s", err.Error())
+			return
+		}
+		defer server.Close()
+		settings.BaseURLs = ss.StringSliceFlag{server.URL()}
+		logging.Logf(logging.LogInfo, "Benchmarking against %s", server.URL())
+	}
+	timings := worker.NewTimings()
+	measurement := benchmark.Start(settings.Workers, timings)
+	runScan(settings, timings)
+	report := measurement.Finish()
+	fmt.Fprintf(os.Stderr, "Benchmark:\n")
+	if err := report.WriteSummary(os.Stderr); err != nil {
+		logging.Logf(logging.LogError, "Unable to write benchmark report: %s", err.Error())
+	}
+}
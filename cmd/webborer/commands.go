@@ -0,0 +1,160 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/Matir/webborer"
+	"github.com/Matir/webborer/logging"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+const commandHelp = `Commands:
+  workers [+|-]N      Set, add or remove workers
+  verbosity LEVEL     Log at debug, info, warning or error
+  exclude URL|PATH    Skip a URL, or a path on every target, and what's under it
+  skip [HOST]         Stop scanning a host, by default the one of the latest result
+  stats               Show progress
+  help                Show this help`
+
+var (
+	stdinLinesOnce sync.Once
+	stdinLines     chan string
+)
+
+// Read lines from stdin for the life of the process.  Reads can't be
+// interrupted, so one reader is shared by every scan.
+func readStdinLines() <-chan string {
+	stdinLinesOnce.Do(func() {
+		stdinLines = make(chan string)
+		go func() {
+			defer close(stdinLines)
+			scanner := bufio.NewScanner(os.Stdin)
+			for scanner.Scan() {
+				stdinLines <- scanner.Text()
+			}
+		}()
+	})
+	return stdinLines
+}
+
+// Run commands typed on the terminal against the scanner until the returned
+// function is called.
+func watchCommands(scanner *webborer.Scanner, out io.Writer) func() {
+	done := make(chan bool)
+	finished := make(chan bool)
+	lines := readStdinLines()
+	fmt.Fprintf(out, "Type \"help\" for commands during the scan.\n")
+	go func() {
+		defer close(finished)
+		for {
+			select {
+			case <-done:
+				return
+			case line, ok := <-lines:
+				if !ok {
+					return
+				}
+				if err := runCommand(scanner, line, out); err != nil {
+					fmt.Fprintf(out, "%s\n", err.Error())
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// Run a single command against the scanner.
+func runCommand(scanner *webborer.Scanner, line string, out io.Writer) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+	args := fields[1:]
+	switch strings.ToLower(fields[0]) {
+	case "workers", "w":
+		if len(args) != 1 {
+			return fmt.Errorf("Usage: workers [+|-]N")
+		}
+		n, err := scanner.AdjustWorkers(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Running %d workers.\n", n)
+	case "verbosity", "v":
+		if len(args) != 1 || !isLogLevel(args[0]) {
+			return fmt.Errorf("Usage: verbosity debug|info|warning|error")
+		}
+		logging.SetLogLevel(args[0])
+	case "exclude", "x":
+		if len(args) != 1 {
+			return fmt.Errorf("Usage: exclude URL|PATH")
+		}
+		return scanner.Exclude(args[0])
+	case "skip", "s":
+		switch len(args) {
+		case 0:
+			u := scanner.CurrentURL()
+			if u == nil {
+				return fmt.Errorf("No host to skip yet.")
+			}
+			return scanner.SkipHost(u)
+		case 1:
+			if strings.Contains(args[0], "://") {
+				u, err := url.Parse(args[0])
+				if err != nil {
+					return err
+				}
+				return scanner.SkipHost(u)
+			}
+			// Without a scheme, skip the host over either
+			for _, scheme := range []string{"http", "https"} {
+				if err := scanner.SkipHost(&url.URL{Scheme: scheme, Host: args[0]}); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("Usage: skip [HOST]")
+		}
+	case "stats":
+		progress, err := scanner.Progress()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s\n", progress)
+	case "help", "?":
+		fmt.Fprintf(out, "%s\n", commandHelp)
+	default:
+		return fmt.Errorf("Unknown command %q, try \"help\".", fields[0])
+	}
+	return nil
+}
+
+func isLogLevel(level string) bool {
+	for _, ll := range logging.LogLevelStrings {
+		if strings.EqualFold(ll, level) {
+			return true
+		}
+	}
+	return false
+}
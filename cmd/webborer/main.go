@@ -0,0 +1,385 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// WebBorer is a directory-enumeration tool written in Go.
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/Matir/webborer"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/remote"
+	"github.com/Matir/webborer/results"
+	ss "github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/util"
+	"github.com/Matir/webborer/worker"
+	"math/rand"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Load settings from flags
+func loadSettings() (*ss.ScanSettings, error) {
+	// Load scan settings
+	settings, err := ss.GetScanSettings()
+	if err != nil {
+		logging.Logf(logging.LogFatal, err.Error())
+		return nil, err
+	}
+	logging.ResetLog(settings.LogfilePath, settings.LogLevel)
+	logging.Logf(logging.LogInfo, "Flags: %s", settings)
+	return settings, nil
+}
+
+// This is the main runner for webborer.
+func main() {
+	util.EnableStackTraces()
+	rand.Seed(time.Now().UnixNano())
+
+	settings, err := loadSettings()
+	if err != nil {
+		return
+	}
+
+	// Enable CPU profiling
+	var cpuProfStop func()
+	if settings.DebugCPUProf {
+		cpuProfStop = util.EnableCPUProfiling("webborer.prof")
+	}
+	var traceStop func()
+	if settings.TracePath != "" {
+		traceStop = util.EnableTracing(settings.TracePath)
+	}
+
+	// Set number of threads
+	logging.Logf(logging.LogDebug, "Setting GOMAXPROCS to %d.", settings.Threads)
+	runtime.GOMAXPROCS(settings.Threads)
+
+	// Agents get their work from the coordinator
+	if settings.Agent != "" {
+		clientFactory, err := webborer.NewClientFactory(settings)
+		if err != nil {
+			logging.Logf(logging.LogFatal, "Unable to build client factory: %s", err.Error())
+			return
+		}
+		agent := remote.NewAgent(settings.Agent, settings.RemoteToken, settings, clientFactory)
+		if settings.RemoteCA != "" {
+			if err := agent.SetCA(settings.RemoteCA); err != nil {
+				logging.Logf(logging.LogFatal, "Unable to load CA: %s", err.Error())
+				return
+			}
+		}
+		logging.Logf(logging.LogInfo, "Running as agent for %s", settings.Agent)
+		if err := agent.Run(); err != nil {
+			logging.Logf(logging.LogFatal, "Agent failed: %s", err.Error())
+		}
+		return
+	}
+
+	// Diff output is only useful with baselines to compare against
+	if settings.OutputFormat == "diff" {
+		settings.Calibrate = true
+	}
+
+	if settings.Benchmark {
+		runBenchmark(settings)
+	} else if settings.DryRun {
+		runDryRun(settings)
+	} else if settings.RecipePath != "" {
+		runRecipe(settings)
+	} else if settings.Monitor.IsSet() {
+		runMonitor(settings)
+	} else {
+		runScan(settings, nil)
+	}
+
+	if cpuProfStop != nil {
+		cpuProfStop()
+	}
+	if traceStop != nil {
+		traceStop()
+	}
+	logging.Logf(logging.LogDebug, "Done!")
+}
+
+// Run one scan and write its results, adding up stage timings if timings is
+// not nil or statistics are wanted.
+func runScan(settings *ss.ScanSettings, timings *worker.Timings) {
+	scanner := webborer.NewScanner(settings)
+	if !confirmScan(settings, scanner) {
+		return
+	}
+
+	logging.Logf(logging.LogDebug, "Creating results manager...")
+	resultsManager, err := results.GetResultsManager(settings)
+	if err != nil {
+		logging.Logf(logging.LogFatal, "Unable to start results manager: %s", err.Error())
+		return
+	}
+
+	var scorer *results.Scorer
+	if settings.ScoreSummary || settings.ScorePath != "" {
+		scorer = results.NewScorer()
+		scanner.AddResultStage(scorer)
+	}
+	var wordHits *results.WordHits
+	if settings.LearnWordlistPath != "" {
+		wordHits = results.NewWordHits()
+		scanner.AddResultStage(wordHits)
+	}
+	var stats *results.ScanStats
+	if settings.StatsSummary || settings.StatsPath != "" {
+		stats = results.NewScanStats()
+		stats.SetReportCodes(results.NewReportCodes(settings))
+		scanner.OnFound(stats.Add)
+		if timings == nil {
+			timings = worker.NewTimings()
+		}
+	}
+	if settings.Live {
+		if settings.OutputPath == "" {
+			logging.Logf(logging.LogWarning, "Without -outfile, -live prints results twice.")
+		}
+		live := results.NewLiveWriter(os.Stdout, results.UseColor(settings.Color, os.Stdout))
+		live.SetRedirects(settings.IncludeRedirects)
+		live.SetReportCodes(results.NewReportCodes(settings))
+		scanner.OnFound(live.Write)
+	}
+	if settings.ProgressBar {
+		scanner.OnProgress(newProgressBar())
+	}
+	scanner.SetTimings(timings)
+
+	logging.Logf(logging.LogDebug, "Starting results manager...")
+	resultsManager.Run(scanner.Results())
+
+	if err := scanner.Start(context.Background()); err != nil {
+		logging.Logf(logging.LogFatal, "Unable to start scan: %s", err.Error())
+		return
+	}
+	if !settings.ReadsStdin() && isTerminal(os.Stdin) {
+		stop := watchCommands(scanner, os.Stderr)
+		defer stop()
+	}
+	if settings.QueueDumpPath != "" {
+		stop := util.OnSignal(util.QueueDumpSignal, func() {
+			if err := scanner.DumpQueue(settings.QueueDumpPath); err != nil {
+				logging.Logf(logging.LogError, "Unable to write queue snapshot: %s", err.Error())
+			}
+		})
+		defer stop()
+	}
+
+	// Wait for work to be done
+	logging.Logf(logging.LogDebug, "Main goroutine waiting for scan...")
+	if err := scanner.Wait(); err != nil {
+		logging.Logf(logging.LogError, "Scan failed: %s", err.Error())
+	}
+
+	logging.Debugf("Waiting for results manager.")
+	resultsManager.Wait()
+	if scorer != nil {
+		writeScores(settings, scorer)
+	}
+	if wordHits != nil {
+		writeLearnedWordlist(settings, wordHits)
+	}
+	if stats != nil {
+		stats.Stages = timings.Summary()
+		writeStats(settings, stats)
+	}
+}
+
+// Run the stages of a recipe, writing the results of all of them together.
+func runRecipe(settings *ss.ScanSettings) {
+	recipe, err := ss.LoadRecipe(settings.RecipePath)
+	if err != nil {
+		logging.Logf(logging.LogFatal, "Unable to load recipe: %s", err.Error())
+		return
+	}
+
+	logging.Logf(logging.LogDebug, "Creating results manager...")
+	resultsManager, err := results.GetResultsManager(settings)
+	if err != nil {
+		logging.Logf(logging.LogFatal, "Unable to start results manager: %s", err.Error())
+		return
+	}
+
+	runner := webborer.NewRecipeRunner(settings, recipe)
+	var live *results.LiveWriter
+	if settings.Live {
+		if settings.OutputPath == "" {
+			logging.Logf(logging.LogWarning, "Without -outfile, -live prints results twice.")
+		}
+		live = results.NewLiveWriter(os.Stdout, results.UseColor(settings.Color, os.Stdout))
+		live.SetRedirects(settings.IncludeRedirects)
+	}
+	runner.OnStage(func(stage *ss.RecipeStage, scanner *webborer.Scanner) {
+		fmt.Fprintf(os.Stderr, "Recipe stage: %s\n", stage.Name)
+		if live != nil {
+			scanner.OnFound(live.Write)
+		}
+		if settings.ProgressBar {
+			scanner.OnProgress(newProgressBar())
+		}
+	})
+
+	logging.Logf(logging.LogDebug, "Starting results manager...")
+	resultsManager.Run(runner.Results())
+
+	if err := runner.Start(context.Background()); err != nil {
+		logging.Logf(logging.LogFatal, "Unable to start recipe: %s", err.Error())
+		return
+	}
+	if err := runner.Wait(); err != nil {
+		logging.Logf(logging.LogError, "Recipe failed: %s", err.Error())
+	}
+	resultsManager.Wait()
+}
+
+// List the requests a scan would send, to the output file or stdout, and
+// count them.
+func runDryRun(settings *ss.ScanSettings) {
+	scanner := webborer.NewScanner(settings)
+	if est, err := scanner.Estimate(); err == nil {
+		fmt.Fprintf(os.Stderr, "%s\n", est)
+	}
+	if settings.OutputPath != "" {
+		fp, err := os.Create(settings.OutputPath)
+		if err != nil {
+			logging.Logf(logging.LogFatal, "Unable to open output file: %s", err.Error())
+			return
+		}
+		defer fp.Close()
+		scanner.SetDryRunOutput(fp)
+	}
+	if settings.ProgressBar {
+		scanner.OnProgress(newProgressBar())
+	}
+	resultChan := scanner.Results()
+	if err := scanner.Start(context.Background()); err != nil {
+		logging.Logf(logging.LogFatal, "Unable to start scan: %s", err.Error())
+		return
+	}
+	// Nothing is requested, so nothing should be found
+	for range resultChan {
+	}
+	if err := scanner.Wait(); err != nil {
+		logging.Logf(logging.LogError, "Scan failed: %s", err.Error())
+	}
+	requests, hosts := scanner.Planned()
+	fmt.Fprintf(os.Stderr, "Dry run: %d requests to %d hosts.\n", requests, hosts)
+}
+
+// Print the estimated size of the scan, and if it's larger than
+// -confirm-above, ask whether to go ahead.
+func confirmScan(settings *ss.ScanSettings, scanner *webborer.Scanner) bool {
+	est, err := scanner.Estimate()
+	if err != nil {
+		logging.Logf(logging.LogFatal, "Unable to start scan: %s", err.Error())
+		return false
+	}
+	fmt.Fprintf(os.Stderr, "%s\n", est)
+	if settings.AssumeYes || settings.ConfirmAbove <= 0 || est.Requests <= settings.ConfirmAbove {
+		return true
+	}
+	if settings.ReadsStdin() || !isTerminal(os.Stdin) {
+		logging.Logf(logging.LogFatal, "Not starting a scan of more than %d requests without -y.", settings.ConfirmAbove)
+		return false
+	}
+	fmt.Fprintf(os.Stderr, "Start the scan? [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// Check if fp is a terminal someone could answer from.
+func isTerminal(fp *os.File) bool {
+	info, err := fp.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Repeat the scan on the schedule until the process is stopped.
+func runMonitor(settings *ss.ScanSettings) {
+	for {
+		start := time.Now()
+		logging.Logf(logging.LogInfo, "Starting scheduled scan.")
+		runScan(settings, nil)
+		// Only ask before the first scan
+		settings.AssumeYes = true
+		next := settings.Monitor.Next(start)
+		logging.Logf(logging.LogInfo, "Next scan at %s.", next.Format(time.RFC3339))
+		time.Sleep(time.Until(next))
+	}
+}
+
+// Output the exposure scores computed during the scan
+func writeScores(settings *ss.ScanSettings, scorer *results.Scorer) {
+	if settings.ScoreSummary {
+		fmt.Fprintf(os.Stderr, "Exposure scores:\n")
+		if err := scorer.WriteSummary(os.Stderr); err != nil {
+			logging.Logf(logging.LogError, "Unable to write score summary: %s", err.Error())
+		}
+	}
+	if settings.ScorePath != "" {
+		if err := scorer.AppendToFile(settings.ScorePath); err != nil {
+			logging.Logf(logging.LogError, "Unable to write scores: %s", err.Error())
+		}
+	}
+}
+
+// Output the wordlist entries that found something, adding up hits from
+// earlier scans if a history is kept
+func writeLearnedWordlist(settings *ss.ScanSettings, wordHits *results.WordHits) {
+	fmt.Fprintf(os.Stderr, "Wordlist hits:\n")
+	if err := wordHits.WriteSummary(os.Stderr); err != nil {
+		logging.Logf(logging.LogError, "Unable to write wordlist summary: %s", err.Error())
+	}
+	if settings.HistoryDir != "" {
+		history, err := results.OpenHistory(settings.HistoryDir)
+		if err == nil {
+			var earlier map[string]int
+			if earlier, err = history.WordHits(); err == nil {
+				wordHits.Merge(earlier)
+				err = history.SaveWordHits(wordHits.Counts())
+			}
+		}
+		if err != nil {
+			logging.Logf(logging.LogError, "Unable to keep wordlist hits in history: %s", err.Error())
+		}
+	}
+	if err := wordHits.WriteLearned(settings.LearnWordlistPath); err != nil {
+		logging.Logf(logging.LogError, "Unable to write learned wordlist: %s", err.Error())
+	}
+}
+
+// Output the statistics for the scan
+func writeStats(settings *ss.ScanSettings, stats *results.ScanStats) {
+	stats.Finish()
+	if settings.StatsSummary {
+		fmt.Fprintf(os.Stderr, "Scan statistics:\n")
+		if err := stats.WriteSummary(os.Stderr); err != nil {
+			logging.Logf(logging.LogError, "Unable to write statistics: %s", err.Error())
+		}
+	}
+	if settings.StatsPath != "" {
+		if err := stats.WriteToFile(settings.StatsPath); err != nil {
+			logging.Logf(logging.LogError, "Unable to write statistics: %s", err.Error())
+		}
+	}
+}
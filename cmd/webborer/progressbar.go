@@ -16,19 +16,18 @@
 package main
 
 import (
-	"github.com/Matir/webborer/workqueue"
 	"gopkg.in/cheggaaa/pb.v1"
 )
 
-func initProgressBar(wc *workqueue.WorkCounter) {
+// Start a progress bar and return the callback that updates it.
+func newProgressBar() func(done, total int64) {
 	bar := pb.New(1)
 	bar.ManualUpdate = true
 	bar.ShowTimeLeft = false
-	var callback = func(done, total int64) {
+	bar.Start()
+	return func(done, total int64) {
 		bar.Total = total
 		bar.Set64(done)
 		bar.Update()
 	}
-	wc.SetStatusCallback(callback)
-	bar.Start()
 }
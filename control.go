@@ -0,0 +1,171 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webborer
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Matir/webborer/logging"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Changes to a running scan, such as from commands typed while it runs.
+
+var ErrNoWorkers = errors.New("Scan has no local workers.")
+
+// Progress of a running scan.
+type ScanProgress struct {
+	// Tasks done and known about so far
+	Done, Total int64
+	// Results delivered
+	Results int64
+	Workers int
+	Elapsed time.Duration
+}
+
+// Requests per second so far.
+func (p ScanProgress) Rate() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Done) / p.Elapsed.Seconds()
+}
+
+func (p ScanProgress) String() string {
+	return fmt.Sprintf("%d/%d tasks done, %d results, %d workers, %.1f requests/second over %s",
+		p.Done, p.Total, p.Results, p.Workers, p.Rate(), p.Elapsed.Round(time.Second))
+}
+
+// Check the scan is running.  Must be called with the lock held.
+func (s *Scanner) checkRunning() error {
+	if !s.started {
+		return ErrNotStarted
+	}
+	if s.stopped {
+		return ErrFinished
+	}
+	return nil
+}
+
+// Number of local workers, which is 0 when they are remote.
+func (s *Scanner) workerCount() int {
+	if s.pool == nil {
+		return 0
+	}
+	return s.pool.Size()
+}
+
+// Get the progress of a running scan.
+func (s *Scanner) Progress() (ScanProgress, error) {
+	s.Lock()
+	defer s.Unlock()
+	if err := s.checkRunning(); err != nil {
+		return ScanProgress{}, err
+	}
+	done, total := s.queue.GetCounter().Counts()
+	return ScanProgress{
+		Done:    done,
+		Total:   total,
+		Results: atomic.LoadInt64(&s.delivered),
+		Workers: s.workerCount(),
+		Elapsed: time.Since(s.startTime),
+	}, nil
+}
+
+// Most workers a running scan can be resized to.
+const MaxWorkers = 1024
+
+// Change the number of workers in a running scan to n, at least 1 and at
+// most MaxWorkers.  Workers
+// being removed finish their current request first.  Returns the new number
+// of workers.
+func (s *Scanner) SetWorkers(n int) (int, error) {
+	s.Lock()
+	defer s.Unlock()
+	if err := s.checkRunning(); err != nil {
+		return 0, err
+	}
+	if s.pool == nil {
+		return 0, ErrNoWorkers
+	}
+	if n > MaxWorkers {
+		n = MaxWorkers
+	}
+	return s.pool.Resize(n), nil
+}
+
+// Change the number of workers as given by arg: N for that many, or +N or -N
+// to add or remove some.
+func (s *Scanner) AdjustWorkers(arg string) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid number of workers: %s", arg)
+	}
+	if strings.HasPrefix(arg, "+") || strings.HasPrefix(arg, "-") {
+		progress, err := s.Progress()
+		if err != nil {
+			return 0, err
+		}
+		n += progress.Workers
+	}
+	return s.SetWorkers(n)
+}
+
+// Exclude a URL and everything under it from the rest of the scan.  A path
+// without a host is excluded on every target.
+func (s *Scanner) Exclude(ref string) error {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return err
+	}
+	s.Lock()
+	defer s.Unlock()
+	if err := s.checkRunning(); err != nil {
+		return err
+	}
+	if u.Host != "" {
+		logging.Logf(logging.LogInfo, "Excluding %s", u.String())
+		s.filter.FilterURL(u)
+		return nil
+	}
+	for _, target := range s.scope {
+		excluded := target.ResolveReference(u)
+		logging.Logf(logging.LogInfo, "Excluding %s", excluded.String())
+		s.filter.FilterURL(excluded)
+	}
+	return nil
+}
+
+// Stop scanning the host of u, dropping the work queued for it.
+func (s *Scanner) SkipHost(u *url.URL) error {
+	s.Lock()
+	defer s.Unlock()
+	if err := s.checkRunning(); err != nil {
+		return err
+	}
+	logging.Logf(logging.LogInfo, "Skipping the rest of %s://%s", u.Scheme, u.Host)
+	s.hosts.Get(u).Skip()
+	return nil
+}
+
+// Get the URL of the latest result, or nil if there are none yet.
+func (s *Scanner) CurrentURL() *url.URL {
+	u, _ := s.current.Load().(*url.URL)
+	return u
+}
@@ -0,0 +1,112 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webborer
+
+import (
+	"fmt"
+	ss "github.com/Matir/webborer/settings"
+	"strings"
+	"time"
+)
+
+// Time a request is assumed to take, for a rough rate without -sleep
+const assumedRequestTime = 50 * time.Millisecond
+
+// ScanEstimate is a rough forecast of the size of a scan, made before it
+// starts.
+type ScanEstimate struct {
+	// Starting URLs
+	Targets int
+	// Requests made in each starting directory
+	PerTarget int64
+	// Requests made for the starting directories alone
+	Requests int64
+	// Spidering or recursion will add requests for each directory found
+	Unbounded bool
+	// Requests per second expected
+	Rate float64
+}
+
+// Estimate the number of requests the scan will send and how long they will
+// take.  Must be called before Start; the wordlist loaded is kept for the
+// scan.
+func (s *Scanner) Estimate() (*ScanEstimate, error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.started {
+		return nil, ErrAlreadyStarted
+	}
+	settings := s.settings
+	if err := s.loadWords(); err != nil {
+		return nil, err
+	}
+	scope, err := settings.GetScopes()
+	if err != nil {
+		return nil, err
+	}
+	est := &ScanEstimate{Targets: len(scope), PerTarget: 1, Rate: estimateRate(settings)}
+	switch settings.RunMode {
+	case ss.RunModeEnumeration:
+		expander, err := s.newWordlistExpander()
+		if err != nil {
+			return nil, err
+		}
+		est.PerTarget = expander.CountPerDirectory(len(settings.Extensions))
+		est.Unbounded = true
+	case ss.RunModeDotProduct:
+		est.PerTarget = int64(1 + len(s.words))
+	case ss.RunModeLinkCheck:
+		est.Unbounded = true
+	}
+	if settings.RunMode != ss.RunModeDotProduct {
+		// Each request is also made with each optional header, and each of
+		// those with each virtual host
+		headers := int64(1)
+		for _, values := range settings.OptionalHeader.Header() {
+			headers += int64(len(values))
+		}
+		est.PerTarget *= headers * int64(1+len(settings.VirtualHosts))
+	}
+	est.Requests = est.PerTarget * int64(est.Targets)
+	return est, nil
+}
+
+// Get the rough number of requests per second for settings: each worker
+// sleeps between requests, or is assumed to wait on the server.
+func estimateRate(settings *ss.ScanSettings) float64 {
+	perRequest := settings.SleepTime + settings.Jitter/2
+	if perRequest <= 0 {
+		perRequest = assumedRequestTime
+	}
+	return float64(settings.Workers) / perRequest.Seconds()
+}
+
+// Rough time the requests will take.
+func (e *ScanEstimate) ETA() time.Duration {
+	if e.Rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(e.Requests) / e.Rate * float64(time.Second)).Round(time.Second)
+}
+
+func (e *ScanEstimate) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "About %d requests (%d targets x %d each), taking roughly %s at %.0f requests/s", e.Requests, e.Targets, e.PerTarget, e.ETA(), e.Rate)
+	if e.Unbounded {
+		b.WriteString(", plus more for each directory found")
+	}
+	b.WriteString(".")
+	return b.String()
+}
@@ -3,11 +3,13 @@ package filter
 import (
 	"github.com/Matir/webborer/task"
 	"github.com/Matir/webborer/workqueue"
+	"time"
 )
 
 type DotProductExpander struct {
 	Hostlist []string
 	adder    workqueue.QueueAddCount
+	timer    StageTimer
 }
 
 func NewDotProductExpander(hostlist []string) *DotProductExpander {
@@ -18,6 +20,10 @@ func (dp *DotProductExpander) SetAddCount(adder workqueue.QueueAddCount) {
 	dp.adder = adder
 }
 
+func (dp *DotProductExpander) SetTimer(timer StageTimer) {
+	dp.timer = timer
+}
+
 func (dp *DotProductExpander) Expand(inchan <-chan *task.Task) <-chan *task.Task {
 	outChan := make(chan *task.Task, cap(inchan))
 	go func() {
@@ -25,8 +31,11 @@ func (dp *DotProductExpander) Expand(inchan <-chan *task.Task) <-chan *task.Task
 		for it := range inchan {
 			outChan <- it
 			for _, host := range dp.Hostlist {
+				start := time.Now()
 				newIt := it.Copy()
 				newIt.Host = host
+				newIt.Provenance = task.NewProvenance(task.OriginVariant, it.URL, "Host: "+host)
+				dp.timer.since(start)
 				dp.adder(1)
 				outChan <- newIt
 			}
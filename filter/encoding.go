@@ -0,0 +1,78 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"fmt"
+	ss "github.com/Matir/webborer/settings"
+	"strings"
+	"unicode/utf8"
+)
+
+// Manglers for each of the alternate encodings of words.  Slashes are left
+// as they are, so directory entries stay directories.
+var encodingManglers = [...]WordMangler{
+	ss.EncodeURL:       encodeBytes(urlEncodeByte),
+	ss.EncodeDouble:    encodeBytes(doubleEncodeByte),
+	ss.EncodeOverlong:  encodeBytes(overlongEncodeByte),
+	ss.EncodeFullwidth: encodeBytes(fullwidthEncodeByte),
+}
+
+// Build a mangler encoding every byte of a word but slashes.
+func encodeBytes(encode func(byte) string) WordMangler {
+	return func(word string) string {
+		var b strings.Builder
+		for i := 0; i < len(word); i++ {
+			if word[i] == '/' {
+				b.WriteByte('/')
+			} else {
+				b.WriteString(encode(word[i]))
+			}
+		}
+		return b.String()
+	}
+}
+
+func urlEncodeByte(c byte) string {
+	return fmt.Sprintf("%%%02x", c)
+}
+
+func doubleEncodeByte(c byte) string {
+	return fmt.Sprintf("%%25%02x", c)
+}
+
+// The two-byte form of an ASCII character, which lenient UTF-8 decoders
+// accept.
+func overlongEncodeByte(c byte) string {
+	if c >= utf8.RuneSelf {
+		return urlEncodeByte(c)
+	}
+	return fmt.Sprintf("%%%02x%%%02x", 0xc0|c>>6, 0x80|c&0x3f)
+}
+
+// The fullwidth form of a printable ASCII character, which NFKC
+// normalization maps back to the character.
+func fullwidthEncodeByte(c byte) string {
+	if c < 0x21 || c > 0x7e {
+		return urlEncodeByte(c)
+	}
+	buf := make([]byte, utf8.UTFMax)
+	n := utf8.EncodeRune(buf, rune(c)+0xfee0)
+	var b strings.Builder
+	for _, x := range buf[:n] {
+		b.WriteString(urlEncodeByte(x))
+	}
+	return b.String()
+}
@@ -17,6 +17,7 @@ package filter
 import (
 	"github.com/Matir/webborer/task"
 	"github.com/Matir/webborer/workqueue"
+	"time"
 )
 
 // An Expander is responsible for taking input URLs and expanding them to the
@@ -24,4 +25,15 @@ import (
 type Expander interface {
 	Expand(in <-chan *task.Task) <-chan *task.Task
 	SetAddCount(workqueue.QueueAddCount)
+	SetTimer(StageTimer)
+}
+
+// A StageTimer is told how long a stage spent on each task.
+type StageTimer func(time.Duration)
+
+// Report the time since start, if there is a timer.
+func (timer StageTimer) since(start time.Time) {
+	if timer != nil {
+		timer(time.Since(start))
+	}
 }
@@ -20,12 +20,14 @@ import (
 	"github.com/Matir/webborer/workqueue"
 	"net/url"
 	"strings"
+	"time"
 )
 
 // Expand extensions when none are given
 type ExtensionExpander struct {
 	extensions []string
 	adder      workqueue.QueueAddCount
+	timer      StageTimer
 }
 
 func NewExtensionExpander(extensions []string) *ExtensionExpander {
@@ -36,6 +38,10 @@ func (e *ExtensionExpander) SetAddCount(adder workqueue.QueueAddCount) {
 	e.adder = adder
 }
 
+func (e *ExtensionExpander) SetTimer(timer StageTimer) {
+	e.timer = timer
+}
+
 func (e *ExtensionExpander) Expand(in <-chan *task.Task) <-chan *task.Task {
 	outChan := make(chan *task.Task)
 	go func() {
@@ -51,9 +57,13 @@ func (e *ExtensionExpander) Expand(in <-chan *task.Task) <-chan *task.Task {
 				continue
 			}
 			e.adder(numExtensions)
+			from := it.URL.String()
 			for _, ext := range e.extensions {
+				start := time.Now()
 				t := it.Copy()
 				t.URL.Path = fmt.Sprintf("%s.%s", it.URL.Path, ext)
+				t.Provenance = task.Provenance{Origin: task.OriginExtension, From: from, Detail: ext}
+				e.timer.since(start)
 				outChan <- t
 			}
 		}
@@ -18,7 +18,9 @@
 package filter
 
 import (
+	"fmt"
 	"github.com/Matir/webborer/client"
+	"github.com/Matir/webborer/hosts"
 	"github.com/Matir/webborer/logging"
 	"github.com/Matir/webborer/robots"
 	ss "github.com/Matir/webborer/settings"
@@ -26,22 +28,48 @@ import (
 	"github.com/Matir/webborer/util"
 	"github.com/Matir/webborer/workqueue"
 	"net/url"
+	"strings"
+	"sync"
+	"time"
 )
 
 // WorkFilter is responsible for making sure that a given URL is only tested
 // once, and also for applying any exclusion rules to prevent URLs from being
 // scanned.
 type WorkFilter struct {
-	done     map[string]bool
+	done     *visitedSet
 	settings *ss.ScanSettings
-	// Excluded paths
-	exclusions []*url.URL
+	// Excluded paths, which may be added to while the filter runs
+	exclusions     []*url.URL
+	exclusionsLock sync.Mutex
 	// Count the work that has been dropped
 	counter workqueue.QueueDoneFunc
+	// Requests made under each directory, for the request budget
+	budgets map[string]int
+	// Directories the budget does not apply to
+	budgetRoots []*url.URL
+	// Crawler traps, and those already reported
+	traps        *TrapDetector
+	trapsSkipped map[string]bool
+	// Called when part of a directory is skipped
+	skipped func(dir *url.URL, note string)
+	// Shared state of each host
+	hosts *hosts.Registry
+	// Told how long each task took to check
+	timer StageTimer
 }
 
 func NewWorkFilter(settings *ss.ScanSettings, counter workqueue.QueueDoneFunc) *WorkFilter {
-	wf := &WorkFilter{done: make(map[string]bool), settings: settings, counter: counter}
+	wf := &WorkFilter{done: newVisitedSet(settings.VisitedMemory()), settings: settings, counter: counter}
+	if settings.DirBudget > 0 {
+		wf.budgets = make(map[string]int)
+		// Limiting a starting URL would limit the whole scan
+		wf.budgetRoots, _ = settings.GetScopes()
+	}
+	if settings.TrapLimit > 0 {
+		wf.traps = NewTrapDetector(settings.TrapLimit)
+		wf.trapsSkipped = make(map[string]bool)
+	}
 	wf.exclusions = make([]*url.URL, 0, len(settings.ExcludePaths))
 	for _, path := range settings.ExcludePaths {
 		if u, err := url.Parse(path); err != nil {
@@ -57,35 +85,239 @@ func NewWorkFilter(settings *ss.ScanSettings, counter workqueue.QueueDoneFunc) *
 func (f *WorkFilter) RunFilter(src <-chan *task.Task) <-chan *task.Task {
 	c := make(chan *task.Task, f.settings.QueueSize)
 	go func() {
-	taskLoop:
 		for t := range src {
-			// Fragment is irrelevant for requests to server
-			t.URL.Fragment = ""
-			// TODO: make a more efficient ID function?
-			taskURL := t.String()
-			if _, ok := f.done[taskURL]; ok {
-				f.reject(t, "already done")
-				continue
+			start := time.Now()
+			t, allowed := f.allow(t)
+			f.timer.since(start)
+			if allowed {
+				c <- t
 			}
-			f.done[taskURL] = true
-			for _, exclusion := range f.exclusions {
-				if util.URLIsSubpath(exclusion, t.URL) {
-					f.reject(t, "excluded")
-					continue taskLoop
-				}
-			}
-			c <- t
 		}
 		close(c)
 	}()
 	return c
 }
 
-// Add another URL to filter
+// Check whether a task should be tried, rejecting it if not.  Returns the
+// task to try, normalized on a copy as the original's URL may be shared
+// with results and other tasks.
+func (f *WorkFilter) allow(orig *task.Task) (*task.Task, bool) {
+	t := normalizable(orig)
+	// Fragment is irrelevant for requests to server
+	t.URL.Fragment = ""
+	// So the same host written differently is only scanned once
+	util.CanonicalizeURLHost(t.URL)
+	// Hosts that redirect every http request are requested over https
+	if f.hosts != nil {
+		t.URL, _ = f.hosts.Upgrade(t.URL)
+	}
+	// Before marking it done, as it may yet be found by a shorter route
+	if f.settings.MaxDepth > 0 && t.Depth > f.settings.MaxDepth {
+		f.reject(t, "beyond maximum depth")
+		return nil, false
+	}
+	// Before marking it done, so it may still be found as a directory
+	if f.redundantSlash(t) {
+		f.reject(t, "same as without trailing slash")
+		return nil, false
+	}
+	// TODO: make a more efficient ID function?
+	if f.done.visit(f.doneKey(t)) {
+		f.reject(t, "already done")
+		return nil, false
+	}
+	if f.excluded(t.URL) {
+		f.reject(t, "excluded")
+		return nil, false
+	}
+	if f.hosts != nil && f.hosts.Get(t.URL).Skipped() {
+		f.reject(t, "host skipped")
+		return nil, false
+	}
+	if target := f.settings.TargetFor(t.URL); target != nil && target.TooDeep(t.URL) {
+		f.reject(t, "too deep")
+		return nil, false
+	}
+	if f.overBudget(t.URL) {
+		f.reject(t, "over budget")
+		return nil, false
+	}
+	if f.inTrap(t.URL) {
+		f.reject(t, "crawl trap")
+		return nil, false
+	}
+	if f.hosts != nil && f.hosts.Get(t.URL).SkipWildcard(t.URL.Path) {
+		f.reject(t, "wildcard directory")
+		return nil, false
+	}
+	return t, true
+}
+
+// A copy of t with its own URL, keeping everything else.
+func normalizable(t *task.Task) *task.Task {
+	u := *t.URL
+	return &task.Task{
+		URL:        &u,
+		Host:       t.Host,
+		Header:     t.Header,
+		Referrer:   t.Referrer,
+		External:   t.External,
+		Provenance: t.Provenance,
+		ParentURL:  t.ParentURL,
+		Depth:      t.Depth,
+	}
+}
+
+// Tell timer how long each task takes to check.
+func (f *WorkFilter) SetTimer(timer StageTimer) {
+	f.timer = timer
+}
+
+// Set the registry of host state, so paths on case-insensitive hosts are only
+// tried once regardless of case.
+func (f *WorkFilter) SetHosts(r *hosts.Registry) {
+	f.hosts = r
+}
+
+// Key identifying a task in the set of those done.
+func (f *WorkFilter) doneKey(t *task.Task) string {
+	if f.hosts == nil || f.hosts.Get(t.URL).CaseSensitivity() != hosts.CaseInsensitive {
+		return t.String()
+	}
+	u := *t.URL
+	u.Path = strings.ToLower(u.Path)
+	u.RawPath = strings.ToLower(u.RawPath)
+	if t.Host != "" {
+		return fmt.Sprintf("%s (%s)", u.String(), t.Host)
+	}
+	return u.String()
+}
+
+// Check whether a wordlist entry with a trailing slash is pointless, because
+// the same path without one was already tried on a host where that tells as
+// much.
+func (f *WorkFilter) redundantSlash(t *task.Task) bool {
+	if f.hosts == nil || t.Provenance.Origin != task.OriginWordlist || t.URL.Path == "/" || !strings.HasSuffix(t.URL.Path, "/") {
+		return false
+	}
+	if !f.hosts.Get(t.URL).SlashBehavior().Redundant() {
+		return false
+	}
+	bare := t.Copy()
+	bare.URL.Path = strings.TrimSuffix(t.URL.Path, "/")
+	bare.URL.RawPath = strings.TrimSuffix(t.URL.RawPath, "/")
+	return f.done.contains(f.doneKey(bare))
+}
+
+// Set a function to call when part of a directory is skipped, because its
+// request budget ran out or it contains a crawler trap.
+func (f *WorkFilter) OnSkip(fn func(dir *url.URL, note string)) {
+	f.skipped = fn
+}
+
+// Check the request budget of every directory containing u, and count the
+// request against them if none has run out.
+func (f *WorkFilter) overBudget(u *url.URL) bool {
+	if f.budgets == nil {
+		return false
+	}
+	dirs := f.budgetDirs(u)
+	for _, dir := range dirs {
+		key := dir.String()
+		count := f.budgets[key]
+		if count < f.settings.DirBudget {
+			continue
+		}
+		if count == f.settings.DirBudget {
+			// Only report each directory once
+			f.budgets[key]++
+			logging.Logf(logging.LogWarning, "Request budget of %d exhausted for %s, skipping the rest.", f.settings.DirBudget, key)
+			if f.skipped != nil {
+				f.skipped(dir, fmt.Sprintf("request budget of %d exhausted, rest of directory skipped", f.settings.DirBudget))
+			}
+		}
+		return true
+	}
+	for _, dir := range dirs {
+		f.budgets[dir.String()]++
+	}
+	return false
+}
+
+// Check whether u is in a crawler trap, and report each trap once.
+func (f *WorkFilter) inTrap(u *url.URL) bool {
+	if f.traps == nil {
+		return false
+	}
+	trap := f.traps.Check(u)
+	if trap == "" {
+		return false
+	}
+	if !f.trapsSkipped[trap] {
+		f.trapsSkipped[trap] = true
+		logging.Logf(logging.LogWarning, "Crawl trap detected: %s, skipping matching URLs.", trap)
+		if f.skipped != nil {
+			dir := *u
+			dir.Path = u.Path[:strings.LastIndex(u.Path, "/")+1]
+			dir.RawQuery = ""
+			f.skipped(&dir, fmt.Sprintf("crawl trap: %s, matching URLs skipped", trap))
+		}
+	}
+	return true
+}
+
+// Directories containing u that have a budget, outermost first.
+func (f *WorkFilter) budgetDirs(u *url.URL) []*url.URL {
+	var dirs []*url.URL
+	for i := 1; i < len(u.Path)-1; i++ {
+		if u.Path[i] != '/' {
+			continue
+		}
+		dir := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path[:i+1]}
+		if !f.isBudgetRoot(dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// Whether dir is, or contains, a starting URL.
+func (f *WorkFilter) isBudgetRoot(dir *url.URL) bool {
+	for _, root := range f.budgetRoots {
+		if root.Scheme == dir.Scheme && root.Host == dir.Host && strings.HasPrefix(root.Path, dir.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Set the starting URLs that budgets don't apply to, when they differ from
+// those in the settings, such as after probing schemes.  Must be called before
+// the filter is run.
+func (f *WorkFilter) SetBudgetRoots(roots []*url.URL) {
+	if f.budgets != nil {
+		f.budgetRoots = roots
+	}
+}
+
+// Add another URL to filter.  This is safe to call while the filter runs.
 func (f *WorkFilter) FilterURL(u *url.URL) {
+	f.exclusionsLock.Lock()
+	defer f.exclusionsLock.Unlock()
 	f.exclusions = append(f.exclusions, u)
 }
 
+func (f *WorkFilter) excluded(u *url.URL) bool {
+	f.exclusionsLock.Lock()
+	defer f.exclusionsLock.Unlock()
+	for _, exclusion := range f.exclusions {
+		if util.URLIsSubpath(exclusion, u) {
+			return true
+		}
+	}
+	return false
+}
+
 // Filter data from robots.txt
 func (f *WorkFilter) AddRobotsFilter(scope []*url.URL, clientFactory client.ClientFactory) {
 	for _, scopeURL := range scope {
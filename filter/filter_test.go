@@ -15,11 +15,15 @@
 package filter
 
 import (
+	"fmt"
 	"github.com/Matir/webborer/client/mock"
+	"github.com/Matir/webborer/hosts"
 	"github.com/Matir/webborer/settings"
 	"github.com/Matir/webborer/task"
 	"net/url"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestFilterDuplicates(t *testing.T) {
@@ -106,3 +110,239 @@ func TestRobotsFilter_Fail(t *testing.T) {
 		t.Errorf("Expected no exclusions, got %d", len(wf.exclusions))
 	}
 }
+
+func TestFilterTargetDepth(t *testing.T) {
+	ss := &settings.ScanSettings{}
+	ss.AddTarget(&settings.Target{URL: &url.URL{Path: "/app/"}, Depth: 1})
+	src := make(chan *task.Task, 4)
+	for _, p := range []string{"/app/a/", "/app/a/b", "/app/a/b/c", "/other/a/b/c"} {
+		src <- task.NewTaskFromURL(&url.URL{Path: p})
+	}
+	close(src)
+	rejected := 0
+	filter := NewWorkFilter(ss, func(i int) { rejected += i })
+	var paths []string
+	for u := range filter.RunFilter(src) {
+		paths = append(paths, u.URL.Path)
+	}
+	if len(paths) != 3 || paths[2] != "/other/a/b/c" {
+		t.Errorf("Unexpected paths: %v", paths)
+	}
+	if rejected != 1 {
+		t.Errorf("Expected 1 rejected, got %d", rejected)
+	}
+}
+
+func TestFilterDirBudget(t *testing.T) {
+	ss := &settings.ScanSettings{
+		BaseURLs:  []string{"http://localhost/app/"},
+		DirBudget: 2,
+	}
+	src := make(chan *task.Task, 8)
+	for _, p := range []string{"/app/cal/", "/app/cal/2018/", "/app/cal/2018/01", "/app/cal/2019/", "/app/cal/2020/", "/app/a", "/app/b", "/app/c"} {
+		src <- task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: p})
+	}
+	close(src)
+	rejected := 0
+	filter := NewWorkFilter(ss, func(i int) { rejected += i })
+	var exhausted []string
+	filter.OnSkip(func(dir *url.URL, note string) {
+		exhausted = append(exhausted, dir.String())
+	})
+	var paths []string
+	for u := range filter.RunFilter(src) {
+		paths = append(paths, u.URL.Path)
+	}
+	expected := []string{"/app/cal/", "/app/cal/2018/", "/app/cal/2018/01", "/app/a", "/app/b", "/app/c"}
+	if len(paths) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, paths)
+	}
+	for i, p := range expected {
+		if paths[i] != p {
+			t.Errorf("Expected %s, got %s", p, paths[i])
+		}
+	}
+	if rejected != 2 {
+		t.Errorf("Expected 2 rejected, got %d", rejected)
+	}
+	if len(exhausted) != 1 || exhausted[0] != "http://localhost/app/cal/" {
+		t.Errorf("Unexpected exhausted directories: %v", exhausted)
+	}
+}
+
+func TestFilterTraps(t *testing.T) {
+	ss := &settings.ScanSettings{TrapLimit: 2}
+	src := make(chan *task.Task, 8)
+	for _, p := range []string{"/cal/2018/01", "/cal/2018/02", "/cal/2018/03", "/a/b/a/b/a/b/", "/cal/2018/", "/index"} {
+		src <- task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: p})
+	}
+	close(src)
+	rejected := 0
+	filter := NewWorkFilter(ss, func(i int) { rejected += i })
+	notes := make(map[string]string)
+	filter.OnSkip(func(dir *url.URL, note string) {
+		notes[dir.Path] = note
+	})
+	var paths []string
+	for u := range filter.RunFilter(src) {
+		paths = append(paths, u.URL.Path)
+	}
+	if len(paths) != 4 || paths[2] != "/cal/2018/" {
+		t.Errorf("Unexpected paths: %v", paths)
+	}
+	if rejected != 2 {
+		t.Errorf("Expected 2 rejected, got %d", rejected)
+	}
+	if !strings.Contains(notes["/cal/2018/"], "/cal/{n}/{n}") || !strings.Contains(notes["/a/b/a/b/a/b/"], "http://localhost/a/b/") {
+		t.Errorf("Unexpected notes: %v", notes)
+	}
+}
+
+func TestFilterCaseInsensitive(t *testing.T) {
+	reg := hosts.NewRegistry()
+	src := make(chan *task.Task, 6)
+	for _, s := range []string{"http://a/Admin", "http://a/ADMIN", "http://a/admin", "http://b/Admin", "http://b/admin"} {
+		u, _ := url.Parse(s)
+		src <- task.NewTaskFromURL(u)
+	}
+	close(src)
+	reg.Get(&url.URL{Scheme: "http", Host: "a"}).SetCaseSensitivity(hosts.CaseInsensitive)
+	filter := NewWorkFilter(&settings.ScanSettings{}, func(int) {})
+	filter.SetHosts(reg)
+	var found []string
+	for u := range filter.RunFilter(src) {
+		found = append(found, u.URL.String())
+	}
+	if len(found) != 3 || found[0] != "http://a/Admin" {
+		t.Errorf("Unexpected tasks: %v", found)
+	}
+}
+
+func TestFilterRedundantSlash(t *testing.T) {
+	reg := hosts.NewRegistry()
+	src := make(chan *task.Task, 8)
+	for _, s := range []string{"http://a/admin", "http://a/admin/", "http://a/other/", "http://b/admin", "http://b/admin/", "http://c/admin", "http://c/admin/"} {
+		u, _ := url.Parse(s)
+		t := task.NewTaskFromURL(u)
+		t.Provenance = task.Provenance{Origin: task.OriginWordlist}
+		src <- t
+	}
+	// Only wordlist entries are skipped
+	u, _ := url.Parse("http://a/admin/")
+	src <- task.NewTaskFromURL(u)
+	close(src)
+	reg.Get(&url.URL{Scheme: "http", Host: "a"}).SetSlashBehavior(hosts.SlashRedirects)
+	reg.Get(&url.URL{Scheme: "http", Host: "b"}).SetSlashBehavior(hosts.SlashDistinct)
+	reg.Get(&url.URL{Scheme: "http", Host: "c"}).SetSlashBehavior(hosts.SlashIdentical)
+	rejected := 0
+	filter := NewWorkFilter(&settings.ScanSettings{}, func(n int) { rejected += n })
+	filter.SetHosts(reg)
+	var found []string
+	for t := range filter.RunFilter(src) {
+		found = append(found, t.URL.String())
+	}
+	expected := "http://a/admin http://a/other/ http://b/admin http://b/admin/ http://c/admin http://a/admin/"
+	if got := strings.Join(found, " "); got != expected || rejected != 2 {
+		t.Errorf("Unexpected tasks, %d rejected: %s", rejected, got)
+	}
+}
+
+func TestFilterWildcard(t *testing.T) {
+	reg := hosts.NewRegistry()
+	src := make(chan *task.Task, 3)
+	for _, s := range []string{"http://a/", "http://a/admin", "http://b/admin"} {
+		u, _ := url.Parse(s)
+		src <- task.NewTaskFromURL(u)
+	}
+	close(src)
+	reg.Get(&url.URL{Scheme: "http", Host: "a"}).SetWildcard("/")
+	rejected := 0
+	filter := NewWorkFilter(&settings.ScanSettings{}, func(n int) { rejected += n })
+	filter.SetHosts(reg)
+	var found []string
+	for u := range filter.RunFilter(src) {
+		found = append(found, u.URL.String())
+	}
+	if len(found) != 2 || found[0] != "http://a/" || found[1] != "http://b/admin" || rejected != 1 {
+		t.Errorf("Unexpected tasks: %v", found)
+	}
+}
+
+func TestFilterMaxDepth(t *testing.T) {
+	src := make(chan *task.Task, 3)
+	deep := task.NewTaskFromURL(&url.URL{Path: "/a"})
+	deep.Depth = 3
+	shallow := task.NewTaskFromURL(&url.URL{Path: "/a"})
+	shallow.Depth = 1
+	src <- deep
+	src <- shallow
+	close(src)
+	filter := NewWorkFilter(&settings.ScanSettings{MaxDepth: 2}, func(int) {})
+	var found []*task.Task
+	for t := range filter.RunFilter(src) {
+		found = append(found, t)
+	}
+	if len(found) != 1 || found[0].Depth != shallow.Depth {
+		t.Errorf("Expected only the shallow task, got %v", found)
+	}
+}
+
+func TestFilterLeavesOriginal(t *testing.T) {
+	src := make(chan *task.Task, 1)
+	orig := task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "EXAMPLE.com:80", Path: "/a", Fragment: "f"})
+	src <- orig
+	close(src)
+	filter := NewWorkFilter(&settings.ScanSettings{}, func(int) {})
+	for u := range filter.RunFilter(src) {
+		if u.URL.String() != "http://example.com/a" {
+			t.Errorf("Expected normalized URL, got %s", u.URL.String())
+		}
+	}
+	if orig.URL.String() != "http://EXAMPLE.com:80/a#f" {
+		t.Errorf("Expected original URL to be left alone, got %s", orig.URL.String())
+	}
+}
+
+func TestFilterTimer(t *testing.T) {
+	src := make(chan *task.Task, 3)
+	for _, p := range []string{"/a", "/b", "/a"} {
+		src <- task.NewTaskFromURL(&url.URL{Path: p})
+	}
+	close(src)
+	filter := NewWorkFilter(&settings.ScanSettings{}, func(int) {})
+	timed := 0
+	filter.SetTimer(func(time.Duration) { timed++ })
+	for range filter.RunFilter(src) {
+	}
+	if timed != 3 {
+		t.Errorf("Expected every task to be timed, got %d", timed)
+	}
+}
+
+func TestVisitedSet(t *testing.T) {
+	v := newVisitedSet(10 * visitedEntrySize)
+	for i := 0; i < 20; i++ {
+		if v.visit(fmt.Sprintf("/%d", i)) {
+			t.Errorf("/%d wrongly visited", i)
+		}
+		// Half the limit is for the exact set
+		if i == 5 && v.bloom == nil {
+			t.Error("Expected a switch past half the limit.")
+		}
+	}
+	if v.bloom == nil || v.exact != nil {
+		t.Fatal("Expected a switch to a Bloom filter.")
+	}
+	for i := 0; i < 20; i++ {
+		if !v.visit(fmt.Sprintf("/%d", i)) {
+			t.Errorf("/%d forgotten", i)
+		}
+	}
+	unlimited := newVisitedSet(0)
+	for i := 0; i < 100; i++ {
+		unlimited.visit(fmt.Sprintf("/%d", i))
+	}
+	if unlimited.bloom != nil || len(unlimited.exact) != 100 {
+		t.Error("Expected an exact set without a limit.")
+	}
+}
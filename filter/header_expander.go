@@ -18,12 +18,14 @@ import (
 	"github.com/Matir/webborer/task"
 	"github.com/Matir/webborer/workqueue"
 	"net/http"
+	"time"
 )
 
 // Header expander tries each of the headers known to it in turn
 type HeaderExpander struct {
 	Header http.Header
 	adder  workqueue.QueueAddCount
+	timer  StageTimer
 }
 
 func NewHeaderExpander(header http.Header) *HeaderExpander {
@@ -36,6 +38,10 @@ func (e *HeaderExpander) SetAddCount(adder workqueue.QueueAddCount) {
 	e.adder = adder
 }
 
+func (e *HeaderExpander) SetTimer(timer StageTimer) {
+	e.timer = timer
+}
+
 func (e *HeaderExpander) Expand(in <-chan *task.Task) <-chan *task.Task {
 	outChan := make(chan *task.Task)
 	go func() {
@@ -45,8 +51,11 @@ func (e *HeaderExpander) Expand(in <-chan *task.Task) <-chan *task.Task {
 			outChan <- it
 			for k, vals := range e.Header {
 				for _, v := range vals {
+					start := time.Now()
 					newIt := it.Copy()
 					newIt.Header.Set(k, v)
+					newIt.Provenance = task.NewProvenance(task.OriginVariant, it.URL, k+": "+v)
+					e.timer.since(start)
 					e.adder(1)
 					outChan <- newIt
 				}
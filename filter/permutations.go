@@ -0,0 +1,92 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/wordlist"
+	"time"
+)
+
+// Prefixes, words and suffixes whose combinations are tried in each
+// directory.
+type permutations struct {
+	prefixes []string
+	words    []string
+	suffixes []string
+	// Most combinations to try in each directory (0 for no limit)
+	limit int
+}
+
+// Also try every combination of a prefix, a word and a suffix in each
+// directory, such as dev-api or backup2019, up to limit in each directory
+// (0 for no limit).  Either list of affixes may be empty, but not both, and
+// ranges such as {2015-2025} in them are expanded.  The combinations are made
+// as they are needed, so they are never all held in memory.
+func (e *WordlistExpander) SetPermutations(prefixes, words, suffixes []string, limit int) {
+	prefixes = wordlist.ExpandGenerators(prefixes)
+	suffixes = wordlist.ExpandGenerators(suffixes)
+	if len(prefixes) == 0 {
+		prefixes = []string{""}
+	}
+	if len(suffixes) == 0 {
+		suffixes = []string{""}
+	}
+	e.permutations = &permutations{
+		prefixes: prefixes,
+		words:    words,
+		suffixes: suffixes,
+		limit:    limit,
+	}
+}
+
+// Get the number of combinations in full, and that will be tried in each
+// directory.
+func (e *WordlistExpander) PermutationCount() (int64, int64) {
+	if e.permutations == nil {
+		return 0, 0
+	}
+	p := e.permutations
+	total := int64(len(p.prefixes)) * int64(len(p.words)) * int64(len(p.suffixes))
+	if p.limit > 0 && total > int64(p.limit) {
+		return total, int64(p.limit)
+	}
+	return total, total
+}
+
+func (e *WordlistExpander) expandPermutations(it *task.Task, out chan<- *task.Task) {
+	_, count := e.PermutationCount()
+	e.adder(int(count))
+	from := it.URL.String()
+	p := e.permutations
+	for _, prefix := range p.prefixes {
+		for _, word := range p.words {
+			for _, suffix := range p.suffixes {
+				if count == 0 {
+					return
+				}
+				count--
+				start := time.Now()
+				candidate := prefix + word + suffix
+				t := it.Copy()
+				t.URL = ExtendURL(t.URL, candidate)
+				t.Provenance = task.Provenance{Origin: task.OriginPermutation, From: from, Detail: candidate}
+				t.SetParent(it)
+				e.timer.since(start)
+				out <- t
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"github.com/Matir/webborer/task"
+	"net/url"
+	"testing"
+)
+
+func expandPermutations(e *WordlistExpander) []*task.Task {
+	ch := make(chan *task.Task, 1)
+	ch <- task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/foo/"})
+	close(ch)
+	var tasks []*task.Task
+	for t := range e.Expand(ch) {
+		tasks = append(tasks, t)
+	}
+	return tasks[1:]
+}
+
+func TestExpandPermutations(t *testing.T) {
+	added := 0
+	expander := &WordlistExpander{adder: func(n int) { added += n }}
+	expander.SetPermutations([]string{"dev-", "old-"}, []string{"api"}, []string{"", "2"}, 0)
+	tasks := expandPermutations(expander)
+	expected := []string{"/foo/dev-api", "/foo/dev-api2", "/foo/old-api", "/foo/old-api2"}
+	if len(tasks) != len(expected) {
+		t.Fatalf("Expected %d tasks, got %d", len(expected), len(tasks))
+	}
+	for i, exp := range expected {
+		if tasks[i].URL.Path != exp {
+			t.Errorf("Expected %s, got %s", exp, tasks[i].URL.Path)
+		}
+	}
+	if added != len(expected) {
+		t.Errorf("Expected %d tasks added, got %d", len(expected), added)
+	}
+	if got := tasks[0].Provenance.Origin; got != task.OriginPermutation {
+		t.Errorf("Expected permutation provenance, got %s", got)
+	}
+	if tasks[0].Provenance.Detail != "dev-api" || tasks[0].Depth != 1 {
+		t.Errorf("Unexpected provenance %s at depth %d", tasks[0].Provenance, tasks[0].Depth)
+	}
+}
+
+func TestExpandPermutations_Limit(t *testing.T) {
+	added := 0
+	expander := &WordlistExpander{adder: func(n int) { added += n }}
+	expander.SetPermutations(nil, []string{"a", "b", "c"}, []string{".bak", ".old"}, 4)
+	if total, tried := expander.PermutationCount(); total != 6 || tried != 4 {
+		t.Errorf("Expected 4 of 6 permutations, got %d of %d", tried, total)
+	}
+	tasks := expandPermutations(expander)
+	if len(tasks) != 4 || added != 4 {
+		t.Errorf("Expected 4 tasks, got %d (%d added)", len(tasks), added)
+	}
+	if tasks[3].URL.Path != "/foo/b.old" {
+		t.Errorf("Expected /foo/b.old last, got %s", tasks[3].URL.Path)
+	}
+}
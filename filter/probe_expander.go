@@ -0,0 +1,123 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/workqueue"
+	"time"
+)
+
+// Dotfiles requested in every directory.  They are cheap to check and often
+// reveal source code, credentials or configuration.
+var DefaultDotfiles = []string{
+	".bash_history",
+	".bzr/README",
+	".DS_Store",
+	".env",
+	".git/config",
+	".git/HEAD",
+	".gitignore",
+	".hg/requires",
+	".htaccess",
+	".htpasswd",
+	".npmrc",
+	".ssh/id_rsa",
+	".svn/entries",
+	".svn/wc.db",
+}
+
+// Entries under /.well-known/ requested once on every host.
+var DefaultWellKnown = []string{
+	"apple-app-site-association",
+	"assetlinks.json",
+	"change-password",
+	"host-meta",
+	"mta-sts.txt",
+	"oauth-authorization-server",
+	"openid-configuration",
+	"security.txt",
+}
+
+// ProbeExpander adds a fixed set of paths to every directory, and to the root
+// of every host, regardless of the wordlist.  Host paths are only added when
+// the root itself is scanned, as expanded tasks are not checked against the
+// scope again.
+type ProbeExpander struct {
+	dirPaths  []string
+	hostPaths []string
+	adder     workqueue.QueueAddCount
+	timer     StageTimer
+	// Directories already probed
+	seen map[string]bool
+}
+
+func NewProbeExpander(dirPaths, hostPaths []string) *ProbeExpander {
+	return &ProbeExpander{
+		dirPaths:  dirPaths,
+		hostPaths: hostPaths,
+		seen:      make(map[string]bool),
+	}
+}
+
+func (e *ProbeExpander) SetAddCount(adder workqueue.QueueAddCount) {
+	e.adder = adder
+}
+
+func (e *ProbeExpander) SetTimer(timer StageTimer) {
+	e.timer = timer
+}
+
+func (e *ProbeExpander) Expand(in <-chan *task.Task) <-chan *task.Task {
+	outChan := make(chan *task.Task, cap(in))
+	go func() {
+		defer close(outChan)
+		for it := range in {
+			// Probed from a copy, as it belongs downstream once sent
+			parent := it.Copy()
+			outChan <- it
+			if !isDirectory(parent.URL) {
+				continue
+			}
+			key := parent.String()
+			if e.seen[key] {
+				continue
+			}
+			e.seen[key] = true
+			e.probe(outChan, parent, parent.URL.Path, e.dirPaths)
+			if parent.URL.Path == "/" {
+				e.probe(outChan, parent, "/.well-known/", e.hostPaths)
+			}
+		}
+	}()
+	return outChan
+}
+
+// Send tasks for each of paths under the directory dir on the host of t.
+func (e *ProbeExpander) probe(out chan<- *task.Task, t *task.Task, dir string, paths []string) {
+	e.adder(len(paths))
+	from := t.URL.String()
+	for _, p := range paths {
+		start := time.Now()
+		nt := t.Copy()
+		nt.URL.Path = dir + p
+		nt.URL.RawPath = ""
+		nt.URL.RawQuery = ""
+		nt.Provenance = task.Provenance{Origin: task.OriginProbe, From: from}
+		nt.SetParent(t)
+		e.timer.since(start)
+		out <- nt
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"github.com/Matir/webborer/task"
+	"net/url"
+	"testing"
+)
+
+func TestProbeExpander(t *testing.T) {
+	expander := NewProbeExpander([]string{".env", ".git/HEAD"}, []string{"security.txt"})
+	added := 0
+	expander.SetAddCount(func(n int) { added += n })
+	ch := make(chan *task.Task, 4)
+	for _, p := range []string{"/", "/app/", "/app/", "/index.php"} {
+		ch <- task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: p})
+	}
+	close(ch)
+	var paths []string
+	for t := range expander.Expand(ch) {
+		paths = append(paths, t.URL.Path)
+	}
+	expected := []string{
+		"/", "/.env", "/.git/HEAD", "/.well-known/security.txt",
+		"/app/", "/app/.env", "/app/.git/HEAD",
+		"/app/",
+		"/index.php",
+	}
+	if len(paths) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, paths)
+	}
+	for i := range expected {
+		if paths[i] != expected[i] {
+			t.Errorf("Expected %s, got %s", expected[i], paths[i])
+		}
+	}
+	if added != 5 {
+		t.Errorf("Expected 5 tasks to be counted, got %d", added)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Maximum length, in segments, of a repeated run of path segments
+const maxRepeatLength = 3
+
+// Number of repetitions of a run of path segments that makes a trap
+const trapRepeats = 3
+
+var digitsRE = regexp.MustCompile(`[0-9]+`)
+
+// TrapDetector recognizes URLs in crawler traps: infinite URL spaces such as
+// calendars that differ only by numbers or dates, paths repeating the same
+// segments, and pages with unbounded query permutations.
+type TrapDetector struct {
+	// URLs allowed to share a pattern before it is a trap
+	limit    int
+	patterns map[string]int
+}
+
+func NewTrapDetector(limit int) *TrapDetector {
+	return &TrapDetector{limit: limit, patterns: make(map[string]int)}
+}
+
+// Check returns a description of the trap u is in, or "" if it is not in one.
+// Each URL should only be checked once.
+func (d *TrapDetector) Check(u *url.URL) string {
+	base := u.Scheme + "://" + u.Host
+	if prefix := repeatedPrefix(u.Path); prefix != "" {
+		return "repeating path segments at " + base + prefix
+	}
+	// Numbers and dates, in the path or the query
+	path := u.Path
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	if pattern := digitsRE.ReplaceAllString(path, "{n}"); pattern != path {
+		if d.count(base + pattern) {
+			return "numbered URLs matching " + base + pattern
+		}
+	}
+	if u.RawQuery != "" {
+		if d.count(base + u.Path + "?*") {
+			return "query permutations of " + base + u.Path
+		}
+	}
+	return ""
+}
+
+// Count a URL matching a pattern, and return true if there are too many.
+func (d *TrapDetector) count(pattern string) bool {
+	d.patterns[pattern]++
+	return d.patterns[pattern] > d.limit
+}
+
+// Find a run of path segments repeated trapRepeats times in a row, and
+// return the path up to and including its first occurrence.
+func repeatedPrefix(p string) string {
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	for n := 1; n <= maxRepeatLength; n++ {
+		for i := 0; i+n*trapRepeats <= len(segments); i++ {
+			if repeats(segments[i:i+n*trapRepeats], n) {
+				return "/" + strings.Join(segments[:i+n], "/") + "/"
+			}
+		}
+	}
+	return ""
+}
+
+// Whether segs consists of the first n segments repeated.
+func repeats(segs []string, n int) bool {
+	for j := n; j < len(segs); j++ {
+		if segs[j] != segs[j-n] {
+			return false
+		}
+	}
+	return true
+}
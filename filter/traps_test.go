@@ -0,0 +1,63 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestTrapDetector(t *testing.T) {
+	d := NewTrapDetector(2)
+	check := func(s string) string {
+		u, err := url.Parse(s)
+		if err != nil {
+			t.Fatalf("Unable to parse %s: %v", s, err)
+		}
+		return d.Check(u)
+	}
+	for _, s := range []string{"http://localhost/day?d=2018-01-01", "http://localhost/day?d=2018-01-02"} {
+		if trap := check(s); trap != "" {
+			t.Errorf("Unexpected trap for %s: %s", s, trap)
+		}
+	}
+	if trap := check("http://localhost/day?d=2018-01-03"); trap != "numbered URLs matching http://localhost/day?d={n}-{n}-{n}" {
+		t.Errorf("Unexpected trap: %q", trap)
+	}
+	for _, s := range []string{"http://localhost/list?sort=a", "http://localhost/list?sort=b&order=c"} {
+		if trap := check(s); trap != "" {
+			t.Errorf("Unexpected trap for %s: %s", s, trap)
+		}
+	}
+	if trap := check("http://localhost/list?order=d"); trap != "query permutations of http://localhost/list" {
+		t.Errorf("Unexpected trap: %q", trap)
+	}
+}
+
+func TestRepeatedPrefix(t *testing.T) {
+	cases := map[string]string{
+		"/a/a/a":                 "/a/",
+		"/x/a/b/a/b/a/b/":        "/x/a/b/",
+		"/x/a/b/c/a/b/c/a/b/c/d": "/x/a/b/c/",
+		"/a/a/b":                 "",
+		"/a/b/a/b":               "",
+		"/":                      "",
+	}
+	for p, expected := range cases {
+		if got := repeatedPrefix(p); got != expected {
+			t.Errorf("repeatedPrefix(%q) = %q, expected %q", p, got, expected)
+		}
+	}
+}
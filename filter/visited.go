@@ -0,0 +1,73 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/util"
+)
+
+// Rough size of each URL remembered exactly, including its key
+const visitedEntrySize = 128
+
+// visitedSet remembers the tasks already tried.  It starts out exact, and
+// switches to a Bloom filter once it uses half of its memory limit, so the
+// exact set and the Bloom filter built from it fit in the limit together.
+type visitedSet struct {
+	exact map[string]bool
+	bloom *util.BloomFilter
+	// Bytes to use, or 0 for no limit
+	limit int64
+}
+
+func newVisitedSet(limit int64) *visitedSet {
+	return &visitedSet{exact: make(map[string]bool), limit: limit}
+}
+
+// Mark key as visited, returning whether it already was.
+func (v *visitedSet) visit(key string) bool {
+	if v.bloom != nil {
+		if v.bloom.Contains(key) {
+			return true
+		}
+		v.bloom.Add(key)
+		return false
+	}
+	if v.exact[key] {
+		return true
+	}
+	v.exact[key] = true
+	if v.limit > 0 && int64(len(v.exact))*visitedEntrySize > v.limit/2 {
+		v.switchToBloom()
+	}
+	return false
+}
+
+// Check whether key was visited, without marking it.
+func (v *visitedSet) contains(key string) bool {
+	if v.bloom != nil {
+		return v.bloom.Contains(key)
+	}
+	return v.exact[key]
+}
+
+func (v *visitedSet) switchToBloom() {
+	logging.Logf(logging.LogWarning, "Remembering more than %d URLs exactly could exceed the memory limit, switching to a Bloom filter.  A few URLs may be wrongly skipped as already tried.", len(v.exact))
+	v.bloom = util.NewBloomFilter(v.limit / 2)
+	for key := range v.exact {
+		v.bloom.Add(key)
+	}
+	v.exact = nil
+}
@@ -15,11 +15,16 @@
 package filter
 
 import (
+	ss "github.com/Matir/webborer/settings"
 	"github.com/Matir/webborer/task"
 	"github.com/Matir/webborer/util"
+	"github.com/Matir/webborer/wordlist"
 	"github.com/Matir/webborer/workqueue"
+	"math/rand"
 	"net/url"
+	"path"
 	"strings"
+	"time"
 )
 
 // An Expander is responsible for taking input URLs and expanding them to
@@ -27,12 +32,33 @@ import (
 type WordlistExpander struct {
 	// List of words to expand
 	Wordlist []string
+	// Lists of words to expand instead within some scopes
+	scopeWordlists []scopeWordlist
 	// Function to count new instances
 	adder workqueue.QueueAddCount
+	// Told how long each new instance took
+	timer StageTimer
 	// Whether to add slashes
 	addSlashes bool
+	// Whether to try words as directories, files or both
+	slashMode ss.SlashModeOption
 	// Whether to mangle cases
 	mangleCases bool
+	// Whether to randomize the order of words
+	shuffle bool
+	// Alternate encodings of words to add
+	encodings []ss.WordEncoding
+	// Number of words of each wordlist to encode (0 for all)
+	encodeLimit int
+	// Prefixes, words and suffixes to combine, if any
+	permutations *permutations
+	// Entries containing ranges, such as {2015-2025}, by entry
+	generators map[string]*wordlist.Generator
+}
+
+type scopeWordlist struct {
+	scope    *url.URL
+	wordlist []string
 }
 
 // A WordMangler is responsible for modifying a wordlist entry to produce
@@ -56,11 +82,49 @@ func NewWordlistExpander(Wordlist []string, addSlashes, mangleCases bool) *Wordl
 	}
 }
 
-// Update the wordlist to contain directory & non-directory entries
+// Use a different wordlist for URLs within scope.  The most specific scope
+// containing a URL wins.  Must be called before ProcessWordlist.
+func (e *WordlistExpander) AddScopeWordlist(scope *url.URL, wordlist []string) {
+	e.scopeWordlists = append(e.scopeWordlists, scopeWordlist{scope: scope, wordlist: wordlist})
+}
+
+// Update the wordlists to contain directory & non-directory entries
 func (e *WordlistExpander) ProcessWordlist() {
-	newList := e.Wordlist[:]
+	e.Wordlist = e.processWordlist(e.Wordlist)
+	e.parseGenerators(e.Wordlist)
+	for i := range e.scopeWordlists {
+		e.scopeWordlists[i].wordlist = e.processWordlist(e.scopeWordlists[i].wordlist)
+		e.parseGenerators(e.scopeWordlists[i].wordlist)
+	}
+}
+
+// Find the entries of words containing ranges.
+func (e *WordlistExpander) parseGenerators(words []string) {
+	for _, w := range words {
+		if g := wordlist.ParseGenerator(w); g != nil {
+			if e.generators == nil {
+				e.generators = make(map[string]*wordlist.Generator)
+			}
+			e.generators[w] = g
+		}
+	}
+}
+
+// Get the number of words wordlist makes, counting each word made by ranges.
+func (e *WordlistExpander) wordCount(wordlist []string) int {
+	count := len(wordlist)
+	for _, w := range wordlist {
+		if g, ok := e.generators[w]; ok {
+			count += g.Count() - 1
+		}
+	}
+	return count
+}
+
+func (e *WordlistExpander) processWordlist(wordlist []string) []string {
+	newList := append([]string(nil), wordlist...)
 	if e.mangleCases {
-		for _, w := range e.Wordlist {
+		for _, w := range wordlist {
 			for _, mangler := range caseManglers {
 				newList = append(newList, mangler(w))
 			}
@@ -78,7 +142,81 @@ func (e *WordlistExpander) ProcessWordlist() {
 			newList = append(newList, w+"/")
 		}
 	}
-	e.Wordlist = util.DedupeStrings(newList)
+	newList = e.applySlashMode(newList)
+	if len(e.encodings) > 0 {
+		newList = append(newList, e.encodeWords(newList, wordlist)...)
+	}
+	return util.DedupeStrings(newList)
+}
+
+// Add or remove the trailing slashes of words for the slash mode.  Words
+// with a slash are expanded further as directories, while those without one
+// are tried with extensions and mangled as files.
+func (e *WordlistExpander) applySlashMode(words []string) []string {
+	if e.slashMode == ss.SlashAsIs {
+		return words
+	}
+	newList := make([]string, 0, len(words))
+	for _, w := range words {
+		file := strings.TrimRight(w, "/")
+		if file == "" {
+			continue
+		}
+		switch e.slashMode {
+		case ss.SlashDirs:
+			newList = append(newList, file+"/")
+		case ss.SlashFiles:
+			newList = append(newList, file)
+		case ss.SlashBoth:
+			newList = append(newList, file, file+"/")
+		}
+	}
+	return newList
+}
+
+// Encode the entries of list that come from the first encodeLimit words of
+// words, with and without slashes, but not their case variants.  Entries
+// containing ranges aren't encoded.
+func (e *WordlistExpander) encodeWords(list, words []string) []string {
+	if e.encodeLimit > 0 && len(words) > e.encodeLimit {
+		words = words[:e.encodeLimit]
+	}
+	encode := make(map[string]bool, len(words))
+	for _, w := range words {
+		if wordlist.ParseGenerator(w) != nil {
+			continue
+		}
+		encode[strings.TrimSuffix(w, "/")] = true
+	}
+	var encoded []string
+	for _, w := range list {
+		if !encode[strings.TrimSuffix(w, "/")] {
+			continue
+		}
+		for _, enc := range e.encodings {
+			encoded = append(encoded, encodingManglers[enc](w))
+		}
+	}
+	return encoded
+}
+
+// Try words as directories, files or both.  Must be called before
+// ProcessWordlist.
+func (e *WordlistExpander) SetSlashMode(mode ss.SlashModeOption) {
+	e.slashMode = mode
+}
+
+// Randomize the order in which words are expanded.  Each task gets a
+// different order so the requests don't follow an obvious pattern.
+func (e *WordlistExpander) SetShuffle(shuffle bool) {
+	e.shuffle = shuffle
+}
+
+// Also try the first limit words of each wordlist (or all of them, if limit
+// is 0) in each of the encodings.  Must be called before ProcessWordlist.
+func (e *WordlistExpander) SetEncodings(encodings []ss.WordEncoding, limit int) {
+	e.encodings = encodings
+	e.encodeLimit = limit
 }
 
 func (e *WordlistExpander) Expand(in <-chan *task.Task) <-chan *task.Task {
@@ -86,11 +224,20 @@ func (e *WordlistExpander) Expand(in <-chan *task.Task) <-chan *task.Task {
 	go func() {
 		for it := range in {
 			out <- it
-			e.adder(len(e.Wordlist))
-			for _, word := range e.Wordlist {
-				t := it.Copy()
-				t.URL = ExtendURL(t.URL, word)
-				out <- t
+			wordlist := e.wordlistFor(it.URL)
+			e.adder(e.wordCount(wordlist))
+			from := it.URL.String()
+			for _, word := range e.orderedWords(wordlist) {
+				if g, ok := e.generators[word]; ok {
+					g.Each(func(w string) {
+						out <- e.wordTask(it, from, w)
+					})
+					continue
+				}
+				out <- e.wordTask(it, from, word)
+			}
+			if e.permutations != nil {
+				e.expandPermutations(it, out)
 			}
 		}
 		close(out)
@@ -99,16 +246,86 @@ func (e *WordlistExpander) Expand(in <-chan *task.Task) <-chan *task.Task {
 	return out
 }
 
+// Count the tasks made for each directory, including the directory itself,
+// when extensions are added to the names without one.  Scope wordlists are
+// not counted.
+func (e *WordlistExpander) CountPerDirectory(extensions int) int64 {
+	count := int64(1)
+	for _, w := range e.Wordlist {
+		n := int64(1)
+		if g, ok := e.generators[w]; ok {
+			n = int64(g.Count())
+		}
+		if !strings.HasSuffix(w, "/") && !strings.Contains(path.Base(w), ".") {
+			n *= int64(1 + extensions)
+		}
+		count += n
+	}
+	_, tried := e.PermutationCount()
+	return count + tried
+}
+
+// Make the task trying word in it.
+func (e *WordlistExpander) wordTask(it *task.Task, from, word string) *task.Task {
+	start := time.Now()
+	t := it.Copy()
+	t.URL = ExtendURL(t.URL, word)
+	t.Provenance = task.Provenance{Origin: task.OriginWordlist, From: from, Detail: word}
+	t.SetParent(it)
+	e.timer.since(start)
+	return t
+}
+
+// Get the wordlist to expand u with
+func (e *WordlistExpander) wordlistFor(u *url.URL) []string {
+	var best *scopeWordlist
+	for i, sw := range e.scopeWordlists {
+		if !util.URLIsSubpath(sw.scope, u) {
+			continue
+		}
+		if best == nil || len(sw.scope.Path) > len(best.scope.Path) {
+			best = &e.scopeWordlists[i]
+		}
+	}
+	if best == nil {
+		return e.Wordlist
+	}
+	return best.wordlist
+}
+
+// Get the words in the order they should be expanded
+func (e *WordlistExpander) orderedWords(wordlist []string) []string {
+	if !e.shuffle {
+		return wordlist
+	}
+	words := make([]string, len(wordlist))
+	for i, j := range rand.Perm(len(wordlist)) {
+		words[i] = wordlist[j]
+	}
+	return words
+}
+
 func (e *WordlistExpander) SetAddCount(adder workqueue.QueueAddCount) {
 	e.adder = adder
 }
 
+func (e *WordlistExpander) SetTimer(timer StageTimer) {
+	e.timer = timer
+}
+
 func ExtendURL(u *url.URL, tail string) *url.URL {
 	extended := *u
 	if !util.URLIsDir(u) {
-		extended.Path += "/" + tail
-	} else {
-		extended.Path += tail
+		tail = "/" + tail
+	}
+	// Words with escapes are sent as they are, rather than escaping the '%'
+	if strings.Contains(tail, "%") {
+		if unescaped, err := url.PathUnescape(tail); err == nil {
+			extended.RawPath = u.EscapedPath() + tail
+			extended.Path += unescaped
+			return &extended
+		}
 	}
+	extended.Path += tail
 	return &extended
 }
@@ -15,8 +15,10 @@
 package filter
 
 import (
+	ss "github.com/Matir/webborer/settings"
 	"github.com/Matir/webborer/task"
 	"net/url"
+	"strings"
 	"testing"
 )
 
@@ -35,6 +37,23 @@ func TestProcessWordlist(t *testing.T) {
 	}
 }
 
+func TestProcessWordlist_SlashMode(t *testing.T) {
+	cases := map[ss.SlashModeOption]string{
+		ss.SlashAsIs:  "a b/ c.txt /",
+		ss.SlashDirs:  "a/ b/ c.txt/",
+		ss.SlashFiles: "a b c.txt",
+		ss.SlashBoth:  "a a/ b b/ c.txt c.txt/",
+	}
+	for mode, expected := range cases {
+		expander := &WordlistExpander{Wordlist: []string{"a", "b/", "c.txt", "/"}}
+		expander.SetSlashMode(mode)
+		expander.ProcessWordlist()
+		if got := strings.Join(expander.Wordlist, " "); got != expected {
+			t.Errorf("Slash mode %s: expected %q, got %q", mode.String(), expected, got)
+		}
+	}
+}
+
 func TestExpand(t *testing.T) {
 	wl := []string{"a", "b"}
 	expander := &WordlistExpander{Wordlist: wl, adder: func(_ int) {}}
@@ -59,3 +78,132 @@ func TestExpand(t *testing.T) {
 		t.Errorf("Expected closed channel, read an item!")
 	}
 }
+
+func TestExpand_Provenance(t *testing.T) {
+	expander := &WordlistExpander{Wordlist: []string{"a"}, adder: func(_ int) {}}
+	ch := make(chan *task.Task, 1)
+	seed := task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/foo/"})
+	seed.Provenance = task.Provenance{Origin: task.OriginSeed}
+	ch <- seed
+	close(ch)
+	res := expander.Expand(ch)
+	if item := <-res; item.Provenance.Origin != task.OriginSeed {
+		t.Errorf("Expected the seed to keep its provenance, got %s", item.Provenance)
+	}
+	item := <-res
+	if got := item.Provenance.String(); got != `wordlist "a" from http://localhost/foo/` {
+		t.Errorf("Unexpected provenance: %s", got)
+	}
+	if item.Depth != 1 || item.ParentURL != seed.URL {
+		t.Errorf("Expected depth 1 under the seed, got %d under %v", item.Depth, item.ParentURL)
+	}
+}
+
+func TestExpand_Shuffle(t *testing.T) {
+	wl := []string{"a", "b", "c", "d"}
+	expander := &WordlistExpander{Wordlist: wl, adder: func(_ int) {}}
+	expander.SetShuffle(true)
+	ch := make(chan *task.Task, 1)
+	ch <- &task.Task{URL: &url.URL{Path: "/"}}
+	close(ch)
+	seen := make(map[string]bool)
+	for item := range expander.Expand(ch) {
+		seen[item.URL.Path] = true
+	}
+	for _, w := range wl {
+		if !seen["/"+w] {
+			t.Errorf("Expected /%s in shuffled output.", w)
+		}
+	}
+	if len(seen) != len(wl)+1 {
+		t.Errorf("Expected %d items, got %d.", len(wl)+1, len(seen))
+	}
+}
+
+func TestExpand_ScopeWordlist(t *testing.T) {
+	expander := &WordlistExpander{Wordlist: []string{"a"}, adder: func(_ int) {}}
+	expander.AddScopeWordlist(&url.URL{Path: "/api/"}, []string{"v1", "v2"})
+	expander.AddScopeWordlist(&url.URL{Path: "/api/v1/"}, []string{"users"})
+	ch := make(chan *task.Task, 3)
+	for _, p := range []string{"/", "/api/", "/api/v1/"} {
+		ch <- &task.Task{URL: &url.URL{Path: p}}
+	}
+	close(ch)
+	expected := []string{"/", "/a", "/api/", "/api/v1", "/api/v2", "/api/v1/", "/api/v1/users"}
+	i := 0
+	for item := range expander.Expand(ch) {
+		if i < len(expected) && item.URL.Path != expected[i] {
+			t.Errorf("Expected %s, got %s.", expected[i], item.URL.Path)
+		}
+		i++
+	}
+	if i != len(expected) {
+		t.Errorf("Expected %d items, got %d.", len(expected), i)
+	}
+}
+
+func TestProcessWordlist_Encodings(t *testing.T) {
+	expander := &WordlistExpander{Wordlist: []string{"ab", "c.txt", "skipped"}, addSlashes: true}
+	expander.SetEncodings([]ss.WordEncoding{ss.EncodeURL, ss.EncodeDouble, ss.EncodeOverlong, ss.EncodeFullwidth}, 2)
+	expander.ProcessWordlist()
+	seen := make(map[string]bool)
+	for _, w := range expander.Wordlist {
+		seen[w] = true
+	}
+	for _, w := range []string{
+		"%61%62",
+		"%61%62/",
+		"%2561%2562/",
+		"%c1%a1%c1%a2",
+		"%ef%bd%81%ef%bd%82/",
+		"%63%2e%74%78%74",
+	} {
+		if !seen[w] {
+			t.Errorf("Expected %s in wordlist.", w)
+		}
+	}
+	if seen["%63%2e%74%78%74/"] {
+		t.Error("Files should not get encoded directory entries.")
+	}
+	// 3 words, 1 extra directory, 3 encoded words with 4 encodings each
+	if len(expander.Wordlist) != 3+2+3*4 {
+		t.Errorf("Unexpected wordlist: %v", expander.Wordlist)
+	}
+}
+
+func TestExtendURL_Escaped(t *testing.T) {
+	u := &url.URL{Scheme: "http", Host: "localhost", Path: "/app/"}
+	extended := ExtendURL(u, "%2e%2e/admin")
+	if extended.Path != "/app/../admin" || extended.String() != "http://localhost/app/%2e%2e/admin" {
+		t.Errorf("Unexpected URL: %s (%s)", extended.String(), extended.Path)
+	}
+	extended = ExtendURL(&url.URL{Path: "/app"}, "100%")
+	if extended.String() != "/app/100%25" {
+		t.Errorf("Unexpected URL: %s", extended.String())
+	}
+}
+
+func TestExpand_Generator(t *testing.T) {
+	added := 0
+	expander := &WordlistExpander{Wordlist: []string{"a", "db_{2019-2020}.sql"}, adder: func(n int) { added += n }}
+	expander.ProcessWordlist()
+	ch := make(chan *task.Task, 1)
+	ch <- &task.Task{URL: &url.URL{Path: "/"}}
+	close(ch)
+	var paths []string
+	for item := range expander.Expand(ch) {
+		paths = append(paths, item.URL.Path)
+	}
+	expected := []string{"/", "/a", "/db_2019.sql", "/db_2020.sql"}
+	if len(paths) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, paths)
+	}
+	for i, exp := range expected {
+		if paths[i] != exp {
+			t.Errorf("Expected %s, got %s", exp, paths[i])
+		}
+	}
+	if added != 3 {
+		t.Errorf("Expected 3 tasks added, got %d", added)
+	}
+}
@@ -4,7 +4,9 @@ go 1.15
 
 require (
 	github.com/mattn/go-runewidth v0.0.13 // indirect
+	go.starlark.net v0.0.0-20210223155950-e043a3d3c984
 	golang.org/x/net v0.0.0-20210525063256-abc453219eb5
 	gopkg.in/cheggaaa/pb.v1 v1.0.28
+	gopkg.in/yaml.v2 v2.4.0
 	h12.io/socks v1.0.2
 )
@@ -0,0 +1,135 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hosts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/Matir/webborer/client"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/util"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The first host resolves to the same addresses as the second, and gives the
+// same response to a content probe if one was made
+const AliasBackend = "backend"
+
+// Lookups, or content probes, made at once when deduplicating hosts
+const dedupeConcurrency = 16
+
+// Bytes of each page compared by a content probe
+const fingerprintBodySize = 64 * 1024
+
+// A LookupFunc gets the addresses of host when connecting to port.
+type LookupFunc func(ctx context.Context, host, port string) ([]string, error)
+
+// Look up hosts with the system resolver.
+func SystemLookup(ctx context.Context, host, _ string) ([]string, error) {
+	return net.DefaultResolver.LookupHost(ctx, host)
+}
+
+// Find the starting URLs whose hosts are the same backend: those that resolve
+// to the same set of addresses with the same scheme, port and path, and, if
+// fingerprint is given, whose fingerprints match.  Only the first URL of each
+// set is returned, with aliases recording the rest.  URLs whose host can't be
+// resolved are kept.
+func Dedupe(scope []*url.URL, lookup LookupFunc, fingerprint func(*url.URL) string) ([]*url.URL, []*Alias) {
+	keys := make([]string, len(scope))
+	eachURL(scope, func(i int, u *url.URL) {
+		port := u.Port()
+		if port == "" {
+			port = util.DefaultPort(u.Scheme)
+		}
+		addrs, err := lookup(context.Background(), u.Hostname(), port)
+		if err != nil || len(addrs) == 0 {
+			logging.Logf(logging.LogDebug, "Unable to resolve %s for deduplication: %v", u.Hostname(), err)
+			return
+		}
+		addrs = append([]string(nil), addrs...)
+		sort.Strings(addrs)
+		keys[i] = fmt.Sprintf("%s://%s:%s", u.Scheme, strings.Join(addrs, ","), port) + u.EscapedPath()
+	})
+	if fingerprint != nil {
+		eachURL(scope, func(i int, u *url.URL) {
+			if keys[i] != "" {
+				keys[i] += " " + fingerprint(u)
+			}
+		})
+	}
+	var kept []*url.URL
+	var aliases []*Alias
+	first := make(map[string]*url.URL)
+	for i, u := range scope {
+		if keys[i] == "" {
+			kept = append(kept, u)
+			continue
+		}
+		if rep, ok := first[keys[i]]; ok {
+			aliases = append(aliases, &Alias{From: u, To: rep, Relation: AliasBackend})
+			continue
+		}
+		first[keys[i]] = u
+		kept = append(kept, u)
+	}
+	return kept, aliases
+}
+
+// Call fn for each URL, a few at once.
+func eachURL(scope []*url.URL, fn func(int, *url.URL)) {
+	sem := make(chan bool, dedupeConcurrency)
+	var wg sync.WaitGroup
+	for i, u := range scope {
+		wg.Add(1)
+		sem <- true
+		go func(i int, u *url.URL) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(i, u)
+		}(i, u)
+	}
+	wg.Wait()
+}
+
+// Get a fingerprint function that requests each URL with c, identifying the
+// response by its status code and the start of its body, with the host name
+// blanked out so pages linking to themselves still match.  Requests that fail
+// get a fingerprint of their own, so their hosts are not merged.
+func ContentFingerprint(c client.Client) func(*url.URL) string {
+	return func(u *url.URL) string {
+		resp, err := c.RequestURL(u)
+		if err != nil {
+			return "error " + u.Host
+		}
+		defer util.DrainBody(resp.Body)
+		body, err := ioutil.ReadAll(io.LimitReader(resp.Body, fingerprintBodySize))
+		if err != nil {
+			return "error " + u.Host
+		}
+		body = []byte(strings.Replace(string(body), u.Hostname(), "", -1))
+		sum := sha256.Sum256(body)
+		return fmt.Sprintf("%d %s", resp.StatusCode, hex.EncodeToString(sum[:]))
+	}
+}
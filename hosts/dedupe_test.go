@@ -0,0 +1,70 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hosts
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestDedupe(t *testing.T) {
+	addrs := map[string][]string{
+		"a.example.com": {"10.0.0.1", "10.0.0.2"},
+		"b.example.com": {"10.0.0.2", "10.0.0.1"},
+		"c.example.com": {"10.0.0.3"},
+		"d.example.com": {"10.0.0.1", "10.0.0.2"},
+	}
+	lookup := func(_ context.Context, host, _ string) ([]string, error) {
+		if a, ok := addrs[host]; ok {
+			return a, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	parse := func(s string) *url.URL {
+		u, _ := url.Parse(s)
+		return u
+	}
+	scope := []*url.URL{
+		parse("http://a.example.com/"),
+		parse("http://b.example.com/"),
+		parse("http://c.example.com/"),
+		parse("https://d.example.com/"),
+		parse("http://unknown.example.com/"),
+		parse("http://d.example.com/"),
+	}
+	kept, aliases := Dedupe(scope, lookup, nil)
+	expected := []*url.URL{scope[0], scope[2], scope[3], scope[4]}
+	if len(kept) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, kept)
+	}
+	for i, u := range expected {
+		if kept[i] != u {
+			t.Errorf("Expected %s, got %s", u.String(), kept[i].String())
+		}
+	}
+	if len(aliases) != 2 || aliases[0].From != scope[1] || aliases[1].From != scope[5] || aliases[1].To != scope[0] {
+		t.Errorf("Unexpected aliases %v", aliases)
+	}
+
+	// A content probe can tell them apart
+	fingerprint := func(u *url.URL) string {
+		return u.Host
+	}
+	if kept, aliases := Dedupe(scope, lookup, fingerprint); len(kept) != len(scope) || len(aliases) != 0 {
+		t.Errorf("Expected no duplicates with differing content, got %v", aliases)
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package hosts keeps track of what has been learned about each host during a
+// scan, so it can be shared by the workers and filters.
+package hosts
+
+import (
+	"github.com/Matir/webborer/util"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Whether a host treats paths differing only by case as the same
+type CaseSensitivity int
+
+const (
+	// Not yet known
+	CaseUnknown = CaseSensitivity(iota)
+	// A worker is finding out
+	CaseProbing
+	CaseSensitive
+	CaseInsensitive
+)
+
+// Host is the state of a single host.
+type Host struct {
+	caseSensitivity CaseSensitivity
+	slashBehavior   SlashBehavior
+	faviconClaimed  bool
+	// Directories where every path gives the same response
+	wildcardDirs []string
+	// Paths skipped because they are in one of wildcardDirs
+	wildcardSkipped int
+	// Slots for workers on the host, if limited
+	slots chan bool
+	// No requests are sent to the host until then, after rate limiting
+	pausedUntil time.Time
+	// No more requests are sent to the host at all
+	skipped bool
+	// Some http request got a response other than a redirect to https
+	servesHTTP bool
+	// Redirects in a row to the same path on upgradeCandidate over https
+	upgradeRedirects int
+	upgradeCandidate string
+	// Host that http requests are rewritten to use https on, once known
+	upgradeHost string
+	sync.Mutex
+}
+
+// Registry holds the state of every host in a scan.
+type Registry struct {
+	hosts map[string]*Host
+	// Workers allowed on each host at once, or 0 for no limit
+	concurrency int
+	// Relationships between hosts found by probing targets
+	aliases []*Alias
+	// Called when a host is found to upgrade every request to https
+	onUpgrade func(from, to *url.URL)
+	sync.Mutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{hosts: make(map[string]*Host)}
+}
+
+// Key identifies the host of u, including its scheme and port.
+func Key(u *url.URL) string {
+	return u.Scheme + "://" + util.CanonicalHost(u.Scheme, u.Host)
+}
+
+// Get the state of the host of u, creating it if needed.
+func (r *Registry) Get(u *url.URL) *Host {
+	key := Key(u)
+	r.Lock()
+	defer r.Unlock()
+	h, ok := r.hosts[key]
+	if !ok {
+		h = &Host{}
+		if r.concurrency > 0 {
+			h.slots = make(chan bool, r.concurrency)
+		}
+		r.hosts[key] = h
+	}
+	return h
+}
+
+// Call fn with the key and state of every host seen so far.
+func (r *Registry) Each(fn func(key string, h *Host)) {
+	r.Lock()
+	hosts := make(map[string]*Host, len(r.hosts))
+	for k, h := range r.hosts {
+		hosts[k] = h
+	}
+	r.Unlock()
+	for k, h := range hosts {
+		fn(k, h)
+	}
+}
+
+// Record relationships found between hosts.
+func (r *Registry) AddAliases(aliases ...*Alias) {
+	r.Lock()
+	defer r.Unlock()
+	r.aliases = append(r.aliases, aliases...)
+}
+
+// Get the relationships found between hosts.
+func (r *Registry) Aliases() []*Alias {
+	r.Lock()
+	defer r.Unlock()
+	return append([]*Alias(nil), r.aliases...)
+}
+
+// Allow at most n workers on each host at once (0 for no limit).  Must be
+// called before any hosts are used.
+func (r *Registry) SetConcurrency(n int) {
+	r.Lock()
+	defer r.Unlock()
+	r.concurrency = n
+}
+
+// Wait for a worker slot on the host.  Every Acquire must be followed by a
+// Release.
+func (h *Host) Acquire() {
+	if h.slots != nil {
+		h.slots <- true
+	}
+}
+
+func (h *Host) Release() {
+	if h.slots != nil {
+		<-h.slots
+	}
+}
+
+// Stop sending requests to the host for d, such as when it asks to be retried
+// later.  A shorter pause never cuts an existing one short.
+func (h *Host) Pause(d time.Duration) {
+	h.Lock()
+	defer h.Unlock()
+	if until := time.Now().Add(d); until.After(h.pausedUntil) {
+		h.pausedUntil = until
+	}
+}
+
+// Get how much longer the host is paused for, or 0 if it isn't.
+func (h *Host) PausedFor() time.Duration {
+	h.Lock()
+	defer h.Unlock()
+	if d := time.Until(h.pausedUntil); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Wait until the host is no longer paused.  The pause may be extended while
+// waiting.
+func (h *Host) WaitPause() {
+	for d := h.PausedFor(); d > 0; d = h.PausedFor() {
+		time.Sleep(d)
+	}
+}
+
+// Stop scanning the host.  Work already queued for it is dropped.
+func (h *Host) Skip() {
+	h.Lock()
+	defer h.Unlock()
+	h.skipped = true
+}
+
+func (h *Host) Skipped() bool {
+	h.Lock()
+	defer h.Unlock()
+	return h.skipped
+}
+
+func (h *Host) CaseSensitivity() CaseSensitivity {
+	h.Lock()
+	defer h.Unlock()
+	return h.caseSensitivity
+}
+
+// Claim the job of finding out whether the host is case sensitive.  Returns
+// false if it is already known or another worker is finding out.
+func (h *Host) StartCaseProbe() bool {
+	h.Lock()
+	defer h.Unlock()
+	if h.caseSensitivity != CaseUnknown {
+		return false
+	}
+	h.caseSensitivity = CaseProbing
+	return true
+}
+
+// Record the outcome of a case probe.  CaseUnknown allows another try.
+func (h *Host) SetCaseSensitivity(c CaseSensitivity) {
+	h.Lock()
+	defer h.Unlock()
+	h.caseSensitivity = c
+}
+
+// Claim the job of fetching the host's favicon.  Returns false if another
+// worker already has.
+func (h *Host) ClaimFavicon() bool {
+	h.Lock()
+	defer h.Unlock()
+	if h.faviconClaimed {
+		return false
+	}
+	h.faviconClaimed = true
+	return true
+}
+
+// Record that every path under dir gives the same response, so the rest of
+// the directory need not be requested.
+func (h *Host) SetWildcard(dir string) {
+	h.Lock()
+	defer h.Unlock()
+	h.wildcardDirs = append(h.wildcardDirs, dir)
+}
+
+// Check whether path is inside a wildcard directory, counting it as skipped
+// if so.  The directory itself is not skipped.
+func (h *Host) SkipWildcard(path string) bool {
+	h.Lock()
+	defer h.Unlock()
+	for _, dir := range h.wildcardDirs {
+		if path != dir && strings.HasPrefix(path, dir) {
+			h.wildcardSkipped++
+			return true
+		}
+	}
+	return false
+}
+
+// Number of paths skipped by SkipWildcard.
+func (h *Host) WildcardSkipped() int {
+	h.Lock()
+	defer h.Unlock()
+	return h.wildcardSkipped
+}
@@ -0,0 +1,128 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hosts
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	a := r.Get(&url.URL{Scheme: "http", Host: "Example.com", Path: "/a"})
+	if b := r.Get(&url.URL{Scheme: "http", Host: "example.com", Path: "/b"}); a != b {
+		t.Error("Expected the same host regardless of case and path.")
+	}
+	if c := r.Get(&url.URL{Scheme: "https", Host: "example.com"}); a == c {
+		t.Error("Expected a different host for https.")
+	}
+}
+
+func TestHost_CaseProbe(t *testing.T) {
+	h := &Host{}
+	if !h.StartCaseProbe() {
+		t.Fatal("Expected to start the first probe.")
+	}
+	if h.StartCaseProbe() {
+		t.Error("Expected only one probe at a time.")
+	}
+	h.SetCaseSensitivity(CaseUnknown)
+	if !h.StartCaseProbe() {
+		t.Error("Expected to retry after an unknown outcome.")
+	}
+	h.SetCaseSensitivity(CaseInsensitive)
+	if h.StartCaseProbe() || h.CaseSensitivity() != CaseInsensitive {
+		t.Error("Expected no probe once known.")
+	}
+}
+
+func TestHost_ClaimFavicon(t *testing.T) {
+	h := &Host{}
+	if !h.ClaimFavicon() {
+		t.Fatal("Expected the first claim to succeed.")
+	}
+	if h.ClaimFavicon() {
+		t.Error("Expected only one claim.")
+	}
+}
+
+func TestHost_SkipWildcard(t *testing.T) {
+	h := &Host{}
+	if h.SkipWildcard("/app/a") {
+		t.Error("Expected no skip before a wildcard is set.")
+	}
+	h.SetWildcard("/app/")
+	for _, p := range []string{"/app/a", "/app/b/c"} {
+		if !h.SkipWildcard(p) {
+			t.Errorf("Expected %s to be skipped.", p)
+		}
+	}
+	for _, p := range []string{"/app/", "/other"} {
+		if h.SkipWildcard(p) {
+			t.Errorf("Expected %s not to be skipped.", p)
+		}
+	}
+	if n := h.WildcardSkipped(); n != 2 {
+		t.Errorf("Expected 2 skipped, got %d", n)
+	}
+}
+
+func TestHost_Acquire(t *testing.T) {
+	r := NewRegistry()
+	r.SetConcurrency(2)
+	h := r.Get(&url.URL{Scheme: "http", Host: "example.com"})
+	h.Acquire()
+	h.Acquire()
+	acquired := make(chan bool)
+	go func() {
+		h.Acquire()
+		acquired <- true
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("Expected the third worker to wait.")
+	case <-time.After(20 * time.Millisecond):
+	}
+	h.Release()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the third worker to get a slot after a release.")
+	}
+	// Other hosts are unaffected
+	o := r.Get(&url.URL{Scheme: "http", Host: "other.com"})
+	o.Acquire()
+	o.Release()
+}
+
+func TestHost_Pause(t *testing.T) {
+	h := &Host{}
+	if d := h.PausedFor(); d != 0 {
+		t.Errorf("Expected no pause, got %s", d)
+	}
+	h.Pause(time.Hour)
+	h.Pause(time.Minute)
+	if d := h.PausedFor(); d <= time.Minute {
+		t.Errorf("Expected a shorter pause not to cut the longer one, got %s", d)
+	}
+	h = &Host{}
+	h.Pause(10 * time.Millisecond)
+	start := time.Now()
+	h.WaitPause()
+	if time.Since(start) < 10*time.Millisecond || h.PausedFor() != 0 {
+		t.Errorf("Expected to wait out the pause, waited %s", time.Since(start))
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hosts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"github.com/Matir/webborer/client"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/util"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// Ports tried for each scheme, besides its default, when a target is given
+// without a scheme
+var AltPorts = map[string]string{
+	"http":  "8080",
+	"https": "8443",
+}
+
+// How two live origins of a probed target are related
+const (
+	// The first redirects to the second
+	AliasRedirect = "redirect"
+	// Both present the same TLS certificate
+	AliasCertificate = "certificate"
+)
+
+// An Alias records a relationship between two origins found by probing the
+// same target, which are likely to serve the same site.
+type Alias struct {
+	From     *url.URL
+	To       *url.URL
+	Relation string
+}
+
+func (a *Alias) String() string {
+	if a.Relation == AliasRedirect {
+		return fmt.Sprintf("%s redirects to %s", a.From.String(), a.To.String())
+	}
+	return fmt.Sprintf("%s shares a %s with %s", a.From.String(), a.Relation, a.To.String())
+}
+
+// What probing a single origin found
+type probeResult struct {
+	live bool
+	// Location of a redirect, if the origin gave one
+	redirect *url.URL
+	// Hash of the TLS certificate, if any
+	cert []byte
+}
+
+// Get the origins to probe for a target given without a scheme, with the path
+// of u: http and https on their default ports and on AltPorts, or on the port
+// given, if there is one.
+func ProbeCandidates(u *url.URL) []*url.URL {
+	var candidates []*url.URL
+	add := func(scheme, host string) {
+		c := *u
+		c.Scheme = scheme
+		c.Host = host
+		candidates = append(candidates, &c)
+	}
+	if u.Port() != "" {
+		add("https", u.Host)
+		add("http", u.Host)
+		return candidates
+	}
+	host := util.BracketHost(u.Hostname())
+	for _, scheme := range []string{"https", "http"} {
+		add(scheme, host)
+	}
+	for _, scheme := range []string{"https", "http"} {
+		add(scheme, net.JoinHostPort(u.Hostname(), AltPorts[scheme]))
+	}
+	return candidates
+}
+
+// Request each candidate origin at once, returning those that respond and the
+// relationships between them.  Origins that only redirect to another live
+// origin are left out, as the site is scanned there.  The candidates are kept
+// in order, so the first live one is preferred.
+func Probe(c client.Client, candidates []*url.URL) ([]*url.URL, []*Alias) {
+	c.SetCheckRedirect(func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	})
+	found := make([]*probeResult, len(candidates))
+	var wg sync.WaitGroup
+	for i, u := range candidates {
+		wg.Add(1)
+		go func(i int, u *url.URL) {
+			defer wg.Done()
+			found[i] = probeOrigin(c, u)
+		}(i, u)
+	}
+	wg.Wait()
+
+	var live []*url.URL
+	var aliases []*Alias
+	for i, u := range candidates {
+		res := found[i]
+		if !res.live {
+			continue
+		}
+		if to := redirectTarget(candidates, found, i); to != nil {
+			aliases = append(aliases, &Alias{From: u, To: to, Relation: AliasRedirect})
+			continue
+		}
+		for j := 0; j < i; j++ {
+			if found[j].live && res.cert != nil && bytes.Equal(res.cert, found[j].cert) {
+				aliases = append(aliases, &Alias{From: u, To: candidates[j], Relation: AliasCertificate})
+				break
+			}
+		}
+		live = append(live, u)
+	}
+	return live, aliases
+}
+
+func probeOrigin(c client.Client, u *url.URL) *probeResult {
+	res := &probeResult{}
+	resp, err := c.RequestURL(u)
+	if err != nil {
+		logging.Logf(logging.LogDebug, "Probing %s: %s", u.String(), err.Error())
+		return res
+	}
+	defer util.DrainBody(resp.Body)
+	res.live = true
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		if loc, err := resp.Location(); err == nil {
+			res.redirect = loc
+		}
+	}
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		sum := sha256.Sum256(resp.TLS.PeerCertificates[0].Raw)
+		res.cert = sum[:]
+	}
+	return res
+}
+
+// Find the live candidate that the ith redirects to, if any.
+func redirectTarget(candidates []*url.URL, found []*probeResult, i int) *url.URL {
+	loc := found[i].redirect
+	if loc == nil {
+		return nil
+	}
+	for j, u := range candidates {
+		if j == i || !found[j].live {
+			continue
+		}
+		if loc.Scheme == u.Scheme && util.CanonicalHost(loc.Scheme, loc.Host) == util.CanonicalHost(u.Scheme, u.Host) {
+			return u
+		}
+	}
+	return nil
+}
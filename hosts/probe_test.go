@@ -0,0 +1,93 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hosts
+
+import (
+	"github.com/Matir/webborer/client"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestProbeCandidates(t *testing.T) {
+	cases := []struct {
+		target   string
+		expected []string
+	}{
+		{"http://example.com/foo/", []string{
+			"https://example.com/foo/",
+			"http://example.com/foo/",
+			"https://example.com:8443/foo/",
+			"http://example.com:8080/foo/",
+		}},
+		{"http://example.com:8000/", []string{
+			"https://example.com:8000/",
+			"http://example.com:8000/",
+		}},
+		{"http://[::1]/", []string{
+			"https://[::1]/",
+			"http://[::1]/",
+			"https://[::1]:8443/",
+			"http://[::1]:8080/",
+		}},
+	}
+	for _, c := range cases {
+		u, _ := url.Parse(c.target)
+		got := ProbeCandidates(u)
+		if len(got) != len(c.expected) {
+			t.Errorf("%s: expected %v, got %v", c.target, c.expected, got)
+			continue
+		}
+		for i, e := range c.expected {
+			if got[i].String() != e {
+				t.Errorf("%s: expected %s, got %s", c.target, e, got[i].String())
+			}
+		}
+	}
+}
+
+func TestProbe(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	secure := httptest.NewTLSServer(ok)
+	defer secure.Close()
+	// httptest servers all present the same certificate
+	alt := httptest.NewTLSServer(ok)
+	defer alt.Close()
+	plain := httptest.NewServer(http.RedirectHandler(secure.URL+"/", http.StatusMovedPermanently))
+	defer plain.Close()
+	dead := httptest.NewServer(ok)
+	dead.Close()
+	parse := func(s string) *url.URL {
+		u, _ := url.Parse(s + "/")
+		return u
+	}
+	candidates := []*url.URL{parse(secure.URL), parse(plain.URL), parse(alt.URL), parse(dead.URL)}
+	factory, _ := client.NewProxyClientFactory(nil, 5*time.Second, "")
+	live, aliases := Probe(factory.Get(), candidates)
+	if len(live) != 2 || live[0] != candidates[0] || live[1] != candidates[2] {
+		t.Errorf("Expected both TLS servers to be live, got %v", live)
+	}
+	if len(aliases) != 2 {
+		t.Fatalf("Expected 2 aliases, got %v", aliases)
+	}
+	if a := aliases[0]; a.Relation != AliasRedirect || a.From != candidates[1] || a.To != candidates[0] {
+		t.Errorf("Expected redirect alias, got %s", a.String())
+	}
+	if a := aliases[1]; a.Relation != AliasCertificate || a.From != candidates[2] || a.To != candidates[0] {
+		t.Errorf("Expected certificate alias, got %s", a.String())
+	}
+}
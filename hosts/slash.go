@@ -0,0 +1,62 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hosts
+
+// How a host treats a path with and without a trailing slash
+type SlashBehavior int
+
+const (
+	// Not yet known
+	SlashUnknown = SlashBehavior(iota)
+	// A worker is finding out
+	SlashProbing
+	// The two forms may give different responses
+	SlashDistinct
+	// The form without a slash redirects to the one with it
+	SlashRedirects
+	// Both forms give the same response
+	SlashIdentical
+)
+
+// Check whether the response to a path without a trailing slash tells as much
+// as the response with one.
+func (b SlashBehavior) Redundant() bool {
+	return b == SlashRedirects || b == SlashIdentical
+}
+
+func (h *Host) SlashBehavior() SlashBehavior {
+	h.Lock()
+	defer h.Unlock()
+	return h.slashBehavior
+}
+
+// Claim the job of finding out how the host treats trailing slashes.  Returns
+// false if it is already known or another worker is finding out.
+func (h *Host) StartSlashProbe() bool {
+	h.Lock()
+	defer h.Unlock()
+	if h.slashBehavior != SlashUnknown {
+		return false
+	}
+	h.slashBehavior = SlashProbing
+	return true
+}
+
+// Record the outcome of a slash probe.  SlashUnknown allows another try.
+func (h *Host) SetSlashBehavior(b SlashBehavior) {
+	h.Lock()
+	defer h.Unlock()
+	h.slashBehavior = b
+}
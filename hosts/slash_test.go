@@ -0,0 +1,40 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hosts
+
+import (
+	"testing"
+)
+
+func TestHost_SlashProbe(t *testing.T) {
+	h := &Host{}
+	if !h.StartSlashProbe() {
+		t.Fatal("Expected to start the first probe.")
+	}
+	if h.StartSlashProbe() || h.SlashBehavior().Redundant() {
+		t.Error("Expected only one probe at a time.")
+	}
+	h.SetSlashBehavior(SlashUnknown)
+	if !h.StartSlashProbe() {
+		t.Error("Expected to retry after an unknown outcome.")
+	}
+	h.SetSlashBehavior(SlashRedirects)
+	if h.StartSlashProbe() || !h.SlashBehavior().Redundant() {
+		t.Error("Expected no probe once known.")
+	}
+	if SlashDistinct.Redundant() || !SlashIdentical.Redundant() {
+		t.Error("Only hosts that redirect or ignore slashes make them redundant.")
+	}
+}
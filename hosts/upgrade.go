@@ -0,0 +1,92 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hosts
+
+import (
+	"net/url"
+)
+
+// Redirects to https in a row, with no other responses, before a host is
+// taken to redirect every http request
+const UpgradeThreshold = 5
+
+// Note a response to an http request to the host of u, which redirected to
+// the same path over https at upgradeHost, or "" for any other response.
+// Returns true for the response that shows the host redirects every http
+// request, after which Upgrade rewrites its URLs.
+func (r *Registry) NoteHTTPResponse(u *url.URL, upgradeHost string) bool {
+	if !r.Get(u).noteHTTPResponse(upgradeHost) {
+		return false
+	}
+	r.Lock()
+	fn := r.onUpgrade
+	r.Unlock()
+	if fn != nil {
+		from := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}
+		fn(from, &url.URL{Scheme: "https", Host: upgradeHost, Path: "/"})
+	}
+	return true
+}
+
+// Call fn with the http and https origins of each host found to redirect
+// every http request.
+func (r *Registry) OnUpgrade(fn func(from, to *url.URL)) {
+	r.Lock()
+	defer r.Unlock()
+	r.onUpgrade = fn
+}
+
+// Get an https copy of an http URL if its host redirects every http
+// request, leaving u as is since others may hold it.  Returns u and false
+// if it isn't upgraded.
+func (r *Registry) Upgrade(u *url.URL) (*url.URL, bool) {
+	if u.Scheme != "http" {
+		return u, false
+	}
+	to := r.Get(u).UpgradeHost()
+	if to == "" {
+		return u, false
+	}
+	upgraded := *u
+	upgraded.Scheme = "https"
+	upgraded.Host = to
+	return &upgraded, true
+}
+
+// Get the host http requests are upgraded to, or "" if they aren't.
+func (h *Host) UpgradeHost() string {
+	h.Lock()
+	defer h.Unlock()
+	return h.upgradeHost
+}
+
+func (h *Host) noteHTTPResponse(upgradeHost string) bool {
+	h.Lock()
+	defer h.Unlock()
+	if h.servesHTTP || h.upgradeHost != "" {
+		return false
+	}
+	if upgradeHost == "" || (h.upgradeRedirects > 0 && upgradeHost != h.upgradeCandidate) {
+		h.servesHTTP = true
+		return false
+	}
+	h.upgradeCandidate = upgradeHost
+	h.upgradeRedirects++
+	if h.upgradeRedirects < UpgradeThreshold {
+		return false
+	}
+	h.upgradeHost = upgradeHost
+	return true
+}
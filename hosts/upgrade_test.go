@@ -0,0 +1,69 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hosts
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestRegistry_Upgrade(t *testing.T) {
+	r := NewRegistry()
+	var from, to *url.URL
+	r.OnUpgrade(func(f, t *url.URL) {
+		from, to = f, t
+	})
+	u := &url.URL{Scheme: "http", Host: "example.com", Path: "/a"}
+	for i := 1; i < UpgradeThreshold; i++ {
+		if r.NoteHTTPResponse(u, "example.com") {
+			t.Fatalf("Expected no upgrade after %d redirects.", i)
+		}
+	}
+	if !r.NoteHTTPResponse(u, "example.com") {
+		t.Fatal("Expected upgrade once the threshold is reached.")
+	}
+	if from.String() != "http://example.com/" || to.String() != "https://example.com/" {
+		t.Errorf("Unexpected upgrade from %v to %v", from, to)
+	}
+	if r.NoteHTTPResponse(u, "example.com") {
+		t.Error("Expected the upgrade to be reported once.")
+	}
+	task := &url.URL{Scheme: "http", Host: "example.com", Path: "/b"}
+	if upgraded, ok := r.Upgrade(task); !ok || upgraded.String() != "https://example.com/b" {
+		t.Errorf("Expected URL to be upgraded, got %s", upgraded.String())
+	}
+	if task.String() != "http://example.com/b" {
+		t.Errorf("Expected original URL to be left alone, got %s", task.String())
+	}
+	other := &url.URL{Scheme: "http", Host: "other.com", Path: "/b"}
+	if _, ok := r.Upgrade(other); ok {
+		t.Errorf("Expected other host not to be upgraded.")
+	}
+}
+
+func TestRegistry_UpgradeServesHTTP(t *testing.T) {
+	r := NewRegistry()
+	u := &url.URL{Scheme: "http", Host: "example.com", Path: "/a"}
+	r.NoteHTTPResponse(u, "example.com")
+	r.NoteHTTPResponse(u, "")
+	for i := 0; i < UpgradeThreshold*2; i++ {
+		if r.NoteHTTPResponse(u, "example.com") {
+			t.Fatal("Expected no upgrade for a host that serves http.")
+		}
+	}
+	if r.Get(u).UpgradeHost() != "" {
+		t.Error("Expected no upgrade host.")
+	}
+}
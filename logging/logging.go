@@ -17,6 +17,7 @@ package logging
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"strings"
@@ -42,6 +43,11 @@ var LogLevelStrings = [...]string{
 var logLevel = LogWarning
 var defaultLogger = log.New(os.Stderr, "", log.Ldate|log.Ltime|log.Lshortfile)
 
+// Send log output to w, such as ioutil.Discard when embedding webborer.
+func SetOutput(w io.Writer) {
+	defaultLogger = log.New(w, "", log.Ldate|log.Ltime|log.Lshortfile)
+}
+
 func ResetLog(logfilePath, logLevel string) {
 	if len(logfilePath) > 0 {
 		if fp, err := os.Create(logfilePath); err == nil {
@@ -0,0 +1,217 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package notify sends webhook notifications when interesting results are
+// found and when a scan is complete, so long scans can run unattended.
+package notify
+
+import (
+	"fmt"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/results"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Maximum number of hit notifications per scan, so a noisy rule doesn't flood
+// the channel.  Further hits are counted in the summary.
+const DefaultMaxHits = 20
+
+// Time allowed to deliver a notification
+const sendTimeout = 30 * time.Second
+
+// Notifier is a result stage that notifies sinks of results matching its
+// rules, and of a summary once the results are finished.
+type Notifier struct {
+	sinks   []Sink
+	rules   []*Rule
+	maxHits int
+	// Description of the scan for the summary
+	name    string
+	started time.Time
+	counts  map[int]int
+	hits    int
+	dropped int
+	queue   chan *Message
+	sent    chan bool
+
+	// Only notify of results that changed since the last scan
+	changesOnly bool
+	changes     int
+	// Status codes the scan reports
+	report results.ReportCodes
+}
+
+func (n *Notifier) SetReportCodes(codes results.ReportCodes) {
+	n.report = codes
+}
+
+// Create a notifier sending to the given webhook URLs.
+func NewNotifier(urls []string) (*Notifier, error) {
+	client := &http.Client{Timeout: sendTimeout}
+	n := &Notifier{
+		maxHits: DefaultMaxHits,
+		counts:  make(map[int]int),
+	}
+	for _, u := range urls {
+		sink, err := NewSink(u, client.Post)
+		if err != nil {
+			return nil, err
+		}
+		n.sinks = append(n.sinks, sink)
+	}
+	return n, nil
+}
+
+// Add a sink in addition to those from URLs.
+func (n *Notifier) AddSink(sink Sink) {
+	n.sinks = append(n.sinks, sink)
+}
+
+// Add a rule for results to notify about, as parsed by ParseRule.
+func (n *Notifier) AddRule(spec string) error {
+	rule, err := ParseRule(spec)
+	if err != nil {
+		return err
+	}
+	n.rules = append(n.rules, rule)
+	return nil
+}
+
+// Describe the scan in the summary, e.g. with its starting URL.
+func (n *Notifier) SetName(name string) {
+	n.name = name
+}
+
+// Only notify of results that are new, changed or removed since the last
+// scan, and skip the summary if there are none.  Without rules, every change
+// is notified.
+func (n *Notifier) SetChangesOnly(changesOnly bool) {
+	n.changesOnly = changesOnly
+}
+
+func (n *Notifier) Process(in <-chan *results.Result) <-chan *results.Result {
+	out := make(chan *results.Result, cap(in))
+	n.started = time.Now()
+	n.queue = make(chan *Message, n.maxHits)
+	n.sent = make(chan bool)
+	// Deliver in the background so slow webhooks don't stall the scan
+	go n.deliver()
+	go func() {
+		defer close(out)
+		for r := range in {
+			n.consider(r)
+			out <- r
+		}
+		close(n.queue)
+		<-n.sent
+		if !n.changesOnly || n.changes > 0 {
+			n.send(n.summary())
+		}
+	}()
+	return out
+}
+
+func (n *Notifier) consider(r *results.Result) {
+	if !n.report.Result(r) {
+		return
+	}
+	n.counts[r.Code]++
+	if n.changesOnly {
+		if r.Change == "" {
+			return
+		}
+		n.changes++
+		if len(n.rules) > 0 && !n.matches(r) {
+			return
+		}
+	} else if !n.matches(r) {
+		return
+	}
+	n.hits++
+	if n.hits > n.maxHits {
+		n.dropped++
+		return
+	}
+	text := fmt.Sprintf("webborer: %d %s", r.Code, r.URL.String())
+	if len(r.Notes) > 0 {
+		text += fmt.Sprintf(" [%s]", strings.Join(r.Notes, "; "))
+	}
+	for _, f := range r.Findings {
+		text += "\n" + f.String()
+	}
+	n.queue <- &Message{Event: "hit", Text: text, URL: r.URL.String(), Code: r.Code}
+}
+
+func (n *Notifier) matches(r *results.Result) bool {
+	for _, rule := range n.rules {
+		if rule.Match(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *Notifier) deliver() {
+	defer close(n.sent)
+	for m := range n.queue {
+		n.send(m)
+	}
+}
+
+func (n *Notifier) send(m *Message) {
+	for _, sink := range n.sinks {
+		if err := sink.Send(m); err != nil {
+			logging.Logf(logging.LogWarning, "Unable to send notification: %s", err.Error())
+		}
+	}
+}
+
+// Build the message sent when the scan is complete.
+func (n *Notifier) summary() *Message {
+	codes := make([]int, 0, len(n.counts))
+	total := 0
+	for code, count := range n.counts {
+		codes = append(codes, code)
+		total += count
+	}
+	sort.Ints(codes)
+	counts := make(map[string]int, len(codes))
+	parts := make([]string, len(codes))
+	for i, code := range codes {
+		counts[strconv.Itoa(code)] = n.counts[code]
+		parts[i] = fmt.Sprintf("%d: %d", code, n.counts[code])
+	}
+	text := "webborer: scan"
+	if n.name != "" {
+		text += " of " + n.name
+	}
+	text += fmt.Sprintf(" complete after %s, %d results", time.Since(n.started).Round(time.Second), total)
+	if len(parts) > 0 {
+		text += " (" + strings.Join(parts, ", ") + ")"
+	}
+	if n.hits > 0 {
+		text += fmt.Sprintf(", %d matching notification rules", n.hits)
+	}
+	if n.dropped > 0 {
+		text += fmt.Sprintf(" (%d not sent)", n.dropped)
+	}
+	if n.changes > 0 {
+		text += fmt.Sprintf(", %d changed since the last scan", n.changes)
+	}
+	return &Message{Event: "complete", Text: text + ".", Counts: counts}
+}
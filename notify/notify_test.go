@@ -0,0 +1,212 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Matir/webborer/results"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type recordingSink struct {
+	messages []*Message
+	sync.Mutex
+}
+
+func (s *recordingSink) Send(m *Message) error {
+	s.Lock()
+	defer s.Unlock()
+	s.messages = append(s.messages, m)
+	return nil
+}
+
+func testResult(path string, code int) *results.Result {
+	r := results.NewResult(&url.URL{Scheme: "http", Host: "localhost", Path: path}, "")
+	r.Code = code
+	return r
+}
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule(`code=200|403 path=/\.git/ severity=medium`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	r := testResult("/.git/config", 200)
+	if rule.Match(r) {
+		t.Errorf("Expected no match without findings.")
+	}
+	r.AddFinding("test", results.SeverityHigh, "exposed")
+	if !rule.Match(r) {
+		t.Errorf("Expected match.")
+	}
+	r.Code = 301
+	if rule.Match(r) {
+		t.Errorf("Expected no match for 301.")
+	}
+	for _, spec := range []string{"", "code=abc", "path=(", "severity=dire", "color=red", "code"} {
+		if _, err := ParseRule(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
+
+func TestNotifier(t *testing.T) {
+	n, err := NewNotifier(nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	sink := &recordingSink{}
+	n.AddSink(sink)
+	n.maxHits = 1
+	if err := n.AddRule(`path=^/\.`); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	n.SetName("http://localhost/")
+	in := make(chan *results.Result, 4)
+	in <- testResult("/.git/", 200)
+	in <- testResult("/.env", 200)
+	in <- testResult("/index", 301)
+	in <- testResult("/.missing", 404)
+	close(in)
+	count := 0
+	for range n.Process(in) {
+		count++
+	}
+	if count != 4 {
+		t.Errorf("Expected all results to pass through, got %d", count)
+	}
+	if len(sink.messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(sink.messages))
+	}
+	if m := sink.messages[0]; m.Event != "hit" || m.URL != "http://localhost/.git/" {
+		t.Errorf("Unexpected hit message: %+v", m)
+	}
+	summary := sink.messages[1]
+	if summary.Event != "complete" || summary.Counts["200"] != 2 || summary.Counts["301"] != 1 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	if !strings.Contains(summary.Text, "of http://localhost/") || !strings.Contains(summary.Text, "(1 not sent)") {
+		t.Errorf("Unexpected summary text: %s", summary.Text)
+	}
+}
+
+func TestNotifier_ChangesOnly(t *testing.T) {
+	run := func(changes ...string) []*Message {
+		n, err := NewNotifier(nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		sink := &recordingSink{}
+		n.AddSink(sink)
+		n.SetChangesOnly(true)
+		in := make(chan *results.Result, len(changes))
+		for i, change := range changes {
+			r := testResult(fmt.Sprintf("/%d", i), 200)
+			r.Change = change
+			in <- r
+		}
+		close(in)
+		for range n.Process(in) {
+		}
+		return sink.messages
+	}
+	if messages := run("", ""); len(messages) != 0 {
+		t.Errorf("Expected no messages without changes, got %d", len(messages))
+	}
+	messages := run("", results.ChangeNew, results.ChangeRemoved)
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(messages))
+	}
+	if summary := messages[2]; !strings.Contains(summary.Text, "2 changed since the last scan") {
+		t.Errorf("Unexpected summary text: %s", summary.Text)
+	}
+}
+
+func TestNewSink_Payloads(t *testing.T) {
+	cases := map[string]string{
+		"https://hooks.slack.com/services/T/B/X":                     `{"text":"hello"}`,
+		"https://discord.com/api/webhooks/1/abc":                     `{"content":"hello"}`,
+		"https://api.telegram.org/bot123:abc/sendMessage?chat_id=42": `{"chat_id":"42","text":"hello"}`,
+		"https://example.com/hook":                                   `{"event":"hit","text":"hello"}`,
+	}
+	for u, expected := range cases {
+		var body string
+		post := func(_, contentType string, r io.Reader) (*http.Response, error) {
+			data, _ := ioutil.ReadAll(r)
+			body = string(data)
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+		}
+		sink, err := NewSink(u, post)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %s", u, err)
+			continue
+		}
+		if err := sink.Send(&Message{Event: "hit", Text: "hello"}); err != nil {
+			t.Errorf("Unexpected error sending to %s: %s", u, err)
+		}
+		if body != expected {
+			t.Errorf("%s: expected %s, got %s", u, expected, body)
+		}
+	}
+	for _, u := range []string{"ftp://example.com/", "https://api.telegram.org/bot123:abc/sendMessage"} {
+		if _, err := NewSink(u, nil); err == nil {
+			t.Errorf("Expected error for %s", u)
+		}
+	}
+}
+
+func TestWebhookSink_Server(t *testing.T) {
+	var received Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+	n, err := NewNotifier([]string{server.URL + "/hook"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	n.send(&Message{Event: "complete", Text: "done"})
+	if received.Text != "done" {
+		t.Errorf("Expected message to be received, got %+v", received)
+	}
+}
+
+func TestWebhookSink_Errors(t *testing.T) {
+	failing := func(string, string, io.Reader) (*http.Response, error) {
+		return nil, &url.Error{Op: "Post", URL: "https://example.com/secret", Err: errors.New("refused")}
+	}
+	sink, _ := NewSink("https://example.com/secret", failing)
+	if err := sink.Send(&Message{}); err == nil || strings.Contains(err.Error(), "secret") {
+		t.Errorf("Expected error without URL, got %v", err)
+	}
+	rejected := func(string, string, io.Reader) (*http.Response, error) {
+		return &http.Response{StatusCode: 403, Status: "403 Forbidden", Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}
+	sink, _ = NewSink("https://example.com/hook", rejected)
+	if err := sink.Send(&Message{}); err == nil {
+		t.Errorf("Expected error for rejected notification.")
+	}
+}
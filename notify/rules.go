@@ -0,0 +1,107 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"fmt"
+	"github.com/Matir/webborer/results"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A Rule selects results worth notifying about.  All of the conditions set
+// must match.
+type Rule struct {
+	// Status codes to match, or any if empty
+	Codes []int
+	// Pattern the URL path must match, or nil for any
+	Path *regexp.Regexp
+	// Minimum severity of the result's findings, or -1 for any
+	Severity results.Severity
+}
+
+// Parse a rule such as "code=200|403 path=/\.git/ severity=high".
+func ParseRule(spec string) (*Rule, error) {
+	rule := &Rule{Severity: -1}
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("Empty notification rule")
+	}
+	for _, field := range fields {
+		pieces := strings.SplitN(field, "=", 2)
+		if len(pieces) != 2 {
+			return nil, fmt.Errorf("Rule conditions are key=value: %s", field)
+		}
+		value := pieces[1]
+		switch pieces[0] {
+		case "code":
+			for _, c := range strings.Split(value, "|") {
+				code, err := strconv.Atoi(c)
+				if err != nil {
+					return nil, fmt.Errorf("Invalid code: %s", c)
+				}
+				rule.Codes = append(rule.Codes, code)
+			}
+		case "path":
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return nil, err
+			}
+			rule.Path = re
+		case "severity":
+			severity, err := parseSeverity(value)
+			if err != nil {
+				return nil, err
+			}
+			rule.Severity = severity
+		default:
+			return nil, fmt.Errorf("Unknown rule condition: %s", pieces[0])
+		}
+	}
+	return rule, nil
+}
+
+func parseSeverity(value string) (results.Severity, error) {
+	for s := results.SeverityInfo; s <= results.SeverityHigh; s++ {
+		if s.String() == value {
+			return s, nil
+		}
+	}
+	return -1, fmt.Errorf("Unknown severity: %s", value)
+}
+
+// Check if a result matches the rule.
+func (rule *Rule) Match(r *results.Result) bool {
+	if len(rule.Codes) > 0 {
+		found := false
+		for _, code := range rule.Codes {
+			if r.Code == code {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if rule.Path != nil && !rule.Path.MatchString(r.URL.Path) {
+		return false
+	}
+	if rule.Severity >= 0 && r.MaxSeverity() < rule.Severity {
+		return false
+	}
+	return true
+}
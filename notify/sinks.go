@@ -0,0 +1,112 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// A Message is a notification about a hit or the end of a scan.
+type Message struct {
+	// "hit" or "complete"
+	Event string `json:"event"`
+	// Human-readable text
+	Text string `json:"text"`
+	// URL of the hit
+	URL string `json:"url,omitempty"`
+	// Status code of the hit
+	Code int `json:"code,omitempty"`
+	// Number of results per status code when complete
+	Counts map[string]int `json:"counts,omitempty"`
+}
+
+// A Sink delivers messages somewhere.
+type Sink interface {
+	Send(*Message) error
+}
+
+// Function to POST a request, replaceable for testing.
+type poster func(url, contentType string, body io.Reader) (*http.Response, error)
+
+// webhookSink posts messages as JSON, in a shape determined by payload.
+type webhookSink struct {
+	url     string
+	payload func(*Message) interface{}
+	post    poster
+}
+
+// Build a sink for a webhook URL.  Slack, Discord, and Telegram webhooks are
+// recognized by their URLs; anything else receives the Message as JSON.
+func NewSink(rawurl string, post poster) (Sink, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("Notification URL must be http or https: %s", rawurl)
+	}
+	sink := &webhookSink{url: rawurl, post: post}
+	switch {
+	case u.Host == "hooks.slack.com":
+		sink.payload = func(m *Message) interface{} {
+			return map[string]string{"text": m.Text}
+		}
+	case (u.Host == "discord.com" || u.Host == "discordapp.com") && strings.HasPrefix(u.Path, "/api/webhooks/"):
+		sink.payload = func(m *Message) interface{} {
+			return map[string]string{"content": m.Text}
+		}
+	case u.Host == "api.telegram.org":
+		chatID := u.Query().Get("chat_id")
+		if chatID == "" {
+			return nil, fmt.Errorf("Telegram URL needs a chat_id parameter")
+		}
+		sink.payload = func(m *Message) interface{} {
+			return map[string]string{"chat_id": chatID, "text": m.Text}
+		}
+	default:
+		sink.payload = func(m *Message) interface{} {
+			return m
+		}
+	}
+	return sink, nil
+}
+
+func (s *webhookSink) Send(m *Message) error {
+	body, err := json.Marshal(s.payload(m))
+	if err != nil {
+		return err
+	}
+	resp, err := s.post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// Webhook URLs are secrets, so leave them out of errors
+		if ue, ok := err.(*url.Error); ok {
+			return ue.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Notification failed: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webborer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/Matir/webborer/logging"
+	ss "github.com/Matir/webborer/settings"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"sort"
+	"time"
+)
+
+// An entry in the operator log, recording when, where from and how a scan
+// ran, for showing it kept to the rules of engagement.
+type operatorLogEntry struct {
+	Event        string   `json:"event"`
+	Time         string   `json:"time"`
+	EngagementID string   `json:"engagement_id,omitempty"`
+	Operator     string   `json:"operator,omitempty"`
+	Hostname     string   `json:"hostname,omitempty"`
+	Targets      []string `json:"targets,omitempty"`
+	SourceIPs    []string `json:"source_ips,omitempty"`
+	Proxies      []string `json:"proxies,omitempty"`
+	SettingsHash string   `json:"settings_sha256,omitempty"`
+	Elapsed      string   `json:"elapsed,omitempty"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// Record the start of a scan of scope in the operator log, if kept.
+func (s *Scanner) logOperatorStart(scope []*url.URL) {
+	settings := s.settings
+	if settings.OperatorLogPath == "" {
+		return
+	}
+	entry := &operatorLogEntry{
+		Event:        "start",
+		Time:         s.startTime.UTC().Format(time.RFC3339),
+		EngagementID: settings.EngagementID,
+		Targets:      make([]string, 0, len(scope)),
+		SourceIPs:    sourceIPs(scope),
+		Proxies:      settings.Proxies,
+		SettingsHash: settingsHash(settings),
+	}
+	if u, err := user.Current(); err == nil {
+		entry.Operator = u.Username
+	}
+	entry.Hostname, _ = os.Hostname()
+	for _, u := range scope {
+		entry.Targets = append(entry.Targets, u.String())
+	}
+	appendOperatorLog(settings.OperatorLogPath, entry)
+}
+
+// Record the end of a scan in the operator log, if kept.
+func (s *Scanner) logOperatorStop() {
+	settings := s.settings
+	if settings.OperatorLogPath == "" {
+		return
+	}
+	entry := &operatorLogEntry{
+		Event:        "stop",
+		Time:         time.Now().UTC().Format(time.RFC3339),
+		EngagementID: settings.EngagementID,
+		Elapsed:      time.Since(s.startTime).Round(time.Second).String(),
+	}
+	if s.err != nil {
+		entry.Error = s.err.Error()
+	}
+	appendOperatorLog(settings.OperatorLogPath, entry)
+}
+
+// Append entry to the log at path as a line of JSON.  Failing to log isn't
+// fatal, but is warned about, since the record may be needed later.
+func appendOperatorLog(path string, entry *operatorLogEntry) {
+	buf, err := json.Marshal(entry)
+	if err == nil {
+		var fp *os.File
+		if fp, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644); err == nil {
+			_, err = fp.Write(append(buf, '\n'))
+			if cerr := fp.Close(); err == nil {
+				err = cerr
+			}
+		}
+	}
+	if err != nil {
+		logging.Logf(logging.LogWarning, "Unable to write operator log: %s", err.Error())
+	}
+}
+
+// Get the local addresses that connections to each host in scope would come
+// from.  No packets are sent, as connecting a UDP socket only picks a route.
+// The addresses seen by the targets differ if a proxy or NAT is in the way.
+func sourceIPs(scope []*url.URL) []string {
+	seen := make(map[string]bool)
+	for _, u := range scope {
+		port := u.Port()
+		if port == "" {
+			port = "80"
+		}
+		conn, err := net.Dial("udp", net.JoinHostPort(u.Hostname(), port))
+		if err != nil {
+			continue
+		}
+		if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+			seen[addr.IP.String()] = true
+		}
+		conn.Close()
+	}
+	ips := make([]string, 0, len(seen))
+	for ip := range seen {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	return ips
+}
+
+// Hash the settings, as given on the command line with secrets redacted, so
+// scans can be shown to have run with the same settings.
+func settingsHash(settings *ss.ScanSettings) string {
+	sum := sha256.Sum256([]byte(settings.String()))
+	return hex.EncodeToString(sum[:])
+}
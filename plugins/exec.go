@@ -0,0 +1,185 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugins
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/wire"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+func init() {
+	Register("exec", func() Plugin { return &ExecPlugin{} })
+}
+
+// ExecRequest is written to the program as a single line of JSON.  Exactly
+// one of Result and Task is set.
+type ExecRequest struct {
+	Result *wire.Result `json:"result,omitempty"`
+	Task   *wire.Task   `json:"task,omitempty"`
+}
+
+// ExecResponse is read from the program as a single line of JSON in reply to
+// each request.
+type ExecResponse struct {
+	// Drop the result or task
+	Drop bool `json:"drop,omitempty"`
+	// Replacement task, for task requests
+	Task *wire.Task `json:"task,omitempty"`
+	// Notes to add to the result, for result requests
+	Notes []string `json:"notes,omitempty"`
+}
+
+// ExecPlugin hands results and tasks to an external program, which replies to
+// each in turn.  Options:
+//
+//	cmd: the command line to run (required)
+//	results: whether to send results (default true)
+//	tasks: whether to send tasks (default false)
+type ExecPlugin struct {
+	command     []string
+	sendResults bool
+	sendTasks   bool
+	cmd         *exec.Cmd
+	stdin       io.WriteCloser
+	enc         *json.Encoder
+	dec         *json.Decoder
+	// The program stopped responding, so everything is passed through
+	failed bool
+	sync.Mutex
+}
+
+func (p *ExecPlugin) Name() string {
+	if len(p.command) == 0 {
+		return "exec"
+	}
+	return fmt.Sprintf("exec(%s)", p.command[0])
+}
+
+func (p *ExecPlugin) Configure(options map[string]string) error {
+	p.command = strings.Fields(options["cmd"])
+	if len(p.command) == 0 {
+		return errors.New("cmd option is required")
+	}
+	var err error
+	if p.sendResults, err = boolOption(options, "results", true); err != nil {
+		return err
+	}
+	if p.sendTasks, err = boolOption(options, "tasks", false); err != nil {
+		return err
+	}
+	p.cmd = exec.Command(p.command[0], p.command[1:]...)
+	p.cmd.Stderr = os.Stderr
+	if p.stdin, err = p.cmd.StdinPipe(); err != nil {
+		return err
+	}
+	stdout, err := p.cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := p.cmd.Start(); err != nil {
+		return err
+	}
+	p.enc = json.NewEncoder(p.stdin)
+	p.dec = json.NewDecoder(stdout)
+	return nil
+}
+
+func (p *ExecPlugin) Keep(r *results.Result) bool {
+	if !p.sendResults {
+		return true
+	}
+	resp, err := p.exchange(&ExecRequest{Result: wire.FromResult(r)})
+	if err != nil {
+		return true
+	}
+	for _, note := range resp.Notes {
+		r.AddNote("%s", note)
+	}
+	return !resp.Drop
+}
+
+func (p *ExecPlugin) Mutate(t *task.Task) *task.Task {
+	if !p.sendTasks {
+		return t
+	}
+	resp, err := p.exchange(&ExecRequest{Task: wire.FromTask(t)})
+	if err != nil {
+		return t
+	}
+	if resp.Drop {
+		return nil
+	}
+	if resp.Task != nil {
+		nt, err := resp.Task.Task()
+		if err != nil {
+			logging.Logf(logging.LogWarning, "Plugin %s returned invalid task: %s", p.Name(), err.Error())
+			return t
+		}
+		return nt
+	}
+	return t
+}
+
+func (p *ExecPlugin) Close() error {
+	if p.cmd == nil {
+		return nil
+	}
+	p.stdin.Close()
+	return p.cmd.Wait()
+}
+
+// Send a request and wait for the reply.  After the first failure the program
+// is no longer consulted.
+func (p *ExecPlugin) exchange(req *ExecRequest) (*ExecResponse, error) {
+	p.Lock()
+	defer p.Unlock()
+	if p.failed {
+		return nil, errors.New("plugin failed")
+	}
+	resp := &ExecResponse{}
+	err := p.enc.Encode(req)
+	if err == nil {
+		err = p.dec.Decode(resp)
+	}
+	if err != nil {
+		logging.Logf(logging.LogError, "Plugin %s failed, ignoring it: %s", p.Name(), err.Error())
+		p.failed = true
+		return nil, err
+	}
+	return resp, nil
+}
+
+func boolOption(options map[string]string, name string, def bool) (bool, error) {
+	val, ok := options[name]
+	if !ok {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, fmt.Errorf("Invalid value for %s: %s", name, val)
+	}
+	return b, nil
+}
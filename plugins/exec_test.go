@@ -0,0 +1,110 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugins
+
+import (
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/task"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Drops anything mentioning /drop, rewrites tasks to /rewritten, and annotates
+// everything else.
+const execScript = `#!/bin/sh
+while read line; do
+	case "$line" in
+	*/drop*) echo '{"drop": true}' ;;
+	*'"task"'*) echo '{"task": {"url": "http://localhost/rewritten"}}' ;;
+	*) echo '{"notes": ["checked"]}' ;;
+	esac
+done
+`
+
+func newExecPlugin(t *testing.T, options map[string]string) *ExecPlugin {
+	dir, err := ioutil.TempDir("", "webborer-exec")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %s", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	script := filepath.Join(dir, "plugin.sh")
+	if err := ioutil.WriteFile(script, []byte(execScript), 0755); err != nil {
+		t.Fatalf("Unable to write script: %s", err)
+	}
+	options["cmd"] = script
+	p := &ExecPlugin{}
+	if err := p.Configure(options); err != nil {
+		t.Fatalf("Unable to configure plugin: %s", err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func TestExecPlugin_Keep(t *testing.T) {
+	p := newExecPlugin(t, map[string]string{})
+	r := results.NewResult(&url.URL{Scheme: "http", Host: "localhost", Path: "/keep"}, "")
+	if !p.Keep(r) {
+		t.Error("Expected /keep to be kept.")
+	}
+	if len(r.Notes) != 1 || r.Notes[0] != "checked" {
+		t.Errorf("Expected note from plugin, got %v", r.Notes)
+	}
+	r = results.NewResult(&url.URL{Scheme: "http", Host: "localhost", Path: "/drop"}, "")
+	if p.Keep(r) {
+		t.Error("Expected /drop to be dropped.")
+	}
+	// Tasks are not sent by default
+	tsk := task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/task"})
+	if p.Mutate(tsk) != tsk {
+		t.Error("Expected task to pass through.")
+	}
+}
+
+func TestExecPlugin_Mutate(t *testing.T) {
+	p := newExecPlugin(t, map[string]string{"tasks": "true", "results": "false"})
+	tsk := task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/task"})
+	if nt := p.Mutate(tsk); nt == nil || nt.URL.Path != "/rewritten" {
+		t.Errorf("Expected rewritten task, got %v", nt)
+	}
+	tsk = task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/drop"})
+	if p.Mutate(tsk) != nil {
+		t.Error("Expected task to be dropped.")
+	}
+}
+
+func TestExecPlugin_Failed(t *testing.T) {
+	p := &ExecPlugin{}
+	if err := p.Configure(map[string]string{"cmd": "true"}); err != nil {
+		t.Fatalf("Unable to configure plugin: %s", err)
+	}
+	defer p.Close()
+	r := results.NewResult(&url.URL{Scheme: "http", Host: "localhost", Path: "/"}, "")
+	if !p.Keep(r) || !p.failed {
+		t.Error("Expected failed plugin to pass results through.")
+	}
+}
+
+func TestExecPlugin_Configure(t *testing.T) {
+	p := &ExecPlugin{}
+	if err := p.Configure(map[string]string{}); err == nil {
+		t.Error("Expected error without cmd.")
+	}
+	if err := p.Configure(map[string]string{"cmd": "cat", "tasks": "maybe"}); err == nil {
+		t.Error("Expected error for invalid bool.")
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package plugins allows scans to be extended with custom page workers, result
+// filters, and task mutators without modifying webborer itself.
+//
+// Plugins written in Go register a Factory under a name, typically from an
+// init function, and are enabled with -plugin.  Programs that embed the
+// scanner can also hand plugin instances to it directly.  The built-in "exec"
+// plugin speaks JSON over the stdin and stdout of an external program, so
+// plugins may be written in any language.
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/workqueue"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Plugin is implemented by every plugin.  A plugin also implements one or
+// more of PageWorker, ResultFilter, TaskMutator and Dialer to do its work.
+type Plugin interface {
+	// Name of the plugin, used in log messages
+	Name() string
+	// Configure the plugin before the scan starts
+	Configure(options map[string]string) error
+	// Release any resources once the scan is done
+	Close() error
+}
+
+// A PageWorker examines response bodies, like the HTML link parser.  A single
+// instance is shared by all workers, so Handle must be safe for concurrent
+// use.
+type PageWorker interface {
+	Eligible(*http.Response) bool
+	Handle(*task.Task, io.Reader, *results.Result)
+}
+
+// A ResultFilter decides whether results are reported.  It may also annotate
+// the result it is given.
+type ResultFilter interface {
+	Keep(*results.Result) bool
+}
+
+// A TaskMutator rewrites tasks before they are filtered and requested, so
+// rewritten tasks are still checked against the scope.  Returning nil drops
+// the task.
+type TaskMutator interface {
+	Mutate(*task.Task) *task.Task
+}
+
+// A Dialer makes the connections for requests, for unusual transports such as
+// SSH tunnels.  addr is the host and port of the URL being requested.  Only
+// the first plugin that is a Dialer is used.
+type Dialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// An Enqueuer is given a function to add tasks to the scan.  Tasks must only
+// be added while handling a response, as a PageWorker, so they are counted
+// before the response itself is done.
+type Enqueuer interface {
+	SetAdder(workqueue.QueueAddFunc)
+}
+
+// A Factory builds a new, unconfigured, instance of a plugin.
+type Factory func() Plugin
+
+var (
+	registry     = make(map[string]Factory)
+	registryLock sync.Mutex
+)
+
+// Register a plugin factory under name.  Registering the same name twice
+// panics, as it indicates conflicting plugins.
+func Register(name string, factory Factory) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("Plugin %s registered twice.", name))
+	}
+	registry[name] = factory
+}
+
+// Get the names of all registered plugins.
+func Names() []string {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse a plugin specification of the form name[:key=value,key=value].
+func ParseSpec(spec string) (string, map[string]string, error) {
+	options := make(map[string]string)
+	pieces := strings.SplitN(spec, ":", 2)
+	name := strings.TrimSpace(pieces[0])
+	if name == "" {
+		return "", nil, fmt.Errorf("Missing plugin name in %q", spec)
+	}
+	if len(pieces) == 1 || pieces[1] == "" {
+		return name, options, nil
+	}
+	for _, opt := range strings.Split(pieces[1], ",") {
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return "", nil, fmt.Errorf("Invalid option %q for plugin %s", opt, name)
+		}
+		options[kv[0]] = kv[1]
+	}
+	return name, options, nil
+}
+
+// Create and configure a registered plugin from a specification.
+func New(spec string) (Plugin, error) {
+	name, options, err := ParseSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	registryLock.Lock()
+	factory, ok := registry[name]
+	registryLock.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("Unknown plugin: %s (available plugins: %s)", name, strings.Join(Names(), ", "))
+	}
+	p := factory()
+	if err := p.Configure(options); err != nil {
+		return nil, fmt.Errorf("Unable to configure plugin %s: %s", name, err.Error())
+	}
+	return p, nil
+}
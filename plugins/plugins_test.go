@@ -0,0 +1,114 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugins
+
+import (
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/task"
+	"testing"
+)
+
+type testPlugin struct {
+	options map[string]string
+	closed  bool
+}
+
+func (p *testPlugin) Name() string {
+	return "test"
+}
+
+func (p *testPlugin) Configure(options map[string]string) error {
+	p.options = options
+	return nil
+}
+
+func (p *testPlugin) Close() error {
+	p.closed = true
+	return nil
+}
+
+// Drops results for /drop and tasks for /skip
+type dropPlugin struct {
+	testPlugin
+}
+
+func (p *dropPlugin) Keep(r *results.Result) bool {
+	return r.URL.Path != "/drop"
+}
+
+func (p *dropPlugin) Mutate(t *task.Task) *task.Task {
+	if t.URL.Path == "/skip" {
+		return nil
+	}
+	return t
+}
+
+func init() {
+	Register("test", func() Plugin { return &testPlugin{} })
+}
+
+func TestParseSpec(t *testing.T) {
+	name, options, err := ParseSpec("exec:cmd=/bin/cat,tasks=true")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if name != "exec" {
+		t.Errorf("Expected name exec, got %s", name)
+	}
+	if options["cmd"] != "/bin/cat" || options["tasks"] != "true" {
+		t.Errorf("Unexpected options: %v", options)
+	}
+	if name, options, err := ParseSpec("test"); err != nil || name != "test" || len(options) != 0 {
+		t.Errorf("Unexpected parse of bare name: %s %v %v", name, options, err)
+	}
+	for _, bad := range []string{"", ":cmd=x", "exec:cmd", "exec:=x"} {
+		if _, _, err := ParseSpec(bad); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	p, err := New("test:foo=bar")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if p.(*testPlugin).options["foo"] != "bar" {
+		t.Errorf("Plugin not configured: %v", p.(*testPlugin).options)
+	}
+	if _, err := New("nonexistent"); err == nil {
+		t.Error("Expected error for unknown plugin.")
+	}
+}
+
+func TestRegister_Duplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic registering duplicate plugin.")
+		}
+	}()
+	Register("test", func() Plugin { return &testPlugin{} })
+}
+
+func TestNames(t *testing.T) {
+	var foundExec, foundTest bool
+	for _, name := range Names() {
+		foundExec = foundExec || name == "exec"
+		foundTest = foundTest || name == "test"
+	}
+	if !foundExec || !foundTest {
+		t.Errorf("Expected exec and test plugins, got %v", Names())
+	}
+}
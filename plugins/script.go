@@ -0,0 +1,329 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/workqueue"
+	"go.starlark.net/starlark"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("script", func() Plugin { return &ScriptPlugin{} })
+}
+
+// Most of a body passed to after_response
+const maxScriptBodySize = 64 * 1024
+
+// Longest a command started with run() may take
+const scriptRunTimeout = 30 * time.Second
+
+// Keys for thread locals
+const (
+	scriptLocalResult = "result"
+	scriptLocalURL    = "url"
+)
+
+// ScriptPlugin runs hooks written in Starlark, a dialect of Python.  The
+// script may define any of:
+//
+//	before_request(req): req is a dict with url, host and headers, which may
+//	    be modified in place.  Return False to skip the request.
+//	after_response(resp): resp is a dict with url, code, length,
+//	    content_type, headers and body.  Return False to drop the result.
+//	on_hit(result): called for each result that found something.
+//
+// Hooks may call log(msg), note(msg) to annotate the current result,
+// enqueue(url) to request another URL (after_response only), and
+// run(cmd, *args) to run a command and get its output.  Commands are killed
+// if they take longer than 30 seconds.
+//
+// Options:
+//
+//	file: the script to load (required)
+type ScriptPlugin struct {
+	path          string
+	beforeRequest starlark.Value
+	afterResponse starlark.Value
+	onHit         starlark.Value
+	predeclared   starlark.StringDict
+	adder         workqueue.QueueAddFunc
+	// Status codes the scan reports
+	report results.ReportCodes
+}
+
+func (p *ScriptPlugin) SetReportCodes(codes results.ReportCodes) {
+	p.report = codes
+}
+
+func (p *ScriptPlugin) Name() string {
+	return fmt.Sprintf("script(%s)", p.path)
+}
+
+func (p *ScriptPlugin) Configure(options map[string]string) error {
+	p.path = options["file"]
+	if p.path == "" {
+		return errors.New("file option is required")
+	}
+	src, err := ioutil.ReadFile(p.path)
+	if err != nil {
+		return err
+	}
+	p.predeclared = starlark.StringDict{
+		"log":     starlark.NewBuiltin("log", scriptLog),
+		"note":    starlark.NewBuiltin("note", scriptNote),
+		"enqueue": starlark.NewBuiltin("enqueue", p.scriptEnqueue),
+		"run":     starlark.NewBuiltin("run", scriptRun),
+	}
+	globals, err := starlark.ExecFile(p.thread(), p.path, src, p.predeclared)
+	if err != nil {
+		return err
+	}
+	// Hooks are called from many goroutines
+	globals.Freeze()
+	p.beforeRequest = globals["before_request"]
+	p.afterResponse = globals["after_response"]
+	p.onHit = globals["on_hit"]
+	if p.beforeRequest == nil && p.afterResponse == nil && p.onHit == nil {
+		return errors.New("script defines no hooks")
+	}
+	return nil
+}
+
+func (p *ScriptPlugin) Close() error {
+	return nil
+}
+
+func (p *ScriptPlugin) SetAdder(adder workqueue.QueueAddFunc) {
+	p.adder = adder
+}
+
+// before_request
+func (p *ScriptPlugin) Mutate(t *task.Task) *task.Task {
+	if p.beforeRequest == nil {
+		return t
+	}
+	req := starlark.NewDict(3)
+	req.SetKey(starlark.String("url"), starlark.String(t.URL.String()))
+	req.SetKey(starlark.String("host"), starlark.String(t.Host))
+	req.SetKey(starlark.String("headers"), headerToDict(t.Header))
+	rv, err := p.call(p.beforeRequest, req, nil, t.URL)
+	if err != nil {
+		return t
+	}
+	if rv == starlark.False {
+		return nil
+	}
+	nt := t.Copy()
+	if s, ok := dictString(req, "url"); ok && s != t.URL.String() {
+		u, err := url.Parse(s)
+		if err != nil {
+			logging.Logf(logging.LogWarning, "Script %s returned invalid URL %s: %s", p.path, s, err.Error())
+			return t
+		}
+		nt.URL = u
+	}
+	if s, ok := dictString(req, "host"); ok {
+		nt.Host = s
+	}
+	if v, found, _ := req.Get(starlark.String("headers")); found {
+		if d, ok := v.(*starlark.Dict); ok {
+			nt.Header = dictToHeader(d)
+		}
+	}
+	return nt
+}
+
+// after_response runs as a page worker, while the response is being handled,
+// so URLs it enqueues are counted before the task is done.
+func (p *ScriptPlugin) Eligible(*http.Response) bool {
+	return p.afterResponse != nil
+}
+
+func (p *ScriptPlugin) Handle(t *task.Task, body io.Reader, r *results.Result) {
+	buf, err := ioutil.ReadAll(io.LimitReader(body, maxScriptBodySize))
+	if err != nil {
+		logging.Logf(logging.LogInfo, "Error reading body for %s: %s", t.String(), err.Error())
+	}
+	resp := resultToDict(r)
+	resp.SetKey(starlark.String("body"), starlark.String(buf))
+	if rv, err := p.call(p.afterResponse, resp, r, t.URL); err == nil && rv == starlark.False {
+		r.PluginDropped = true
+	}
+}
+
+// Drop results rejected by after_response, and run on_hit for the others.
+func (p *ScriptPlugin) Keep(r *results.Result) bool {
+	if r.PluginDropped {
+		return false
+	}
+	if p.onHit != nil && p.report.Code(r.Code) {
+		p.call(p.onHit, resultToDict(r), r, nil)
+	}
+	return true
+}
+
+// Call a hook with a single argument.  Errors are logged and returned.
+func (p *ScriptPlugin) call(fn starlark.Value, arg starlark.Value, r *results.Result, u *url.URL) (starlark.Value, error) {
+	thread := p.thread()
+	if r != nil {
+		thread.SetLocal(scriptLocalResult, r)
+	}
+	if u != nil {
+		thread.SetLocal(scriptLocalURL, u)
+	}
+	rv, err := starlark.Call(thread, fn, starlark.Tuple{arg}, nil)
+	if err != nil {
+		if evalErr, ok := err.(*starlark.EvalError); ok {
+			logging.Logf(logging.LogError, "Script error: %s", evalErr.Backtrace())
+		} else {
+			logging.Logf(logging.LogError, "Script error: %s", err.Error())
+		}
+	}
+	return rv, err
+}
+
+func (p *ScriptPlugin) thread() *starlark.Thread {
+	return &starlark.Thread{
+		Name: p.path,
+		Print: func(_ *starlark.Thread, msg string) {
+			logging.Logf(logging.LogInfo, "%s: %s", p.path, msg)
+		},
+	}
+}
+
+func (p *ScriptPlugin) scriptEnqueue(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var target string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &target); err != nil {
+		return nil, err
+	}
+	base, ok := thread.Local(scriptLocalURL).(*url.URL)
+	if !ok || p.adder == nil || thread.Local(scriptLocalResult) == nil {
+		return nil, errors.New("enqueue may only be called from after_response")
+	}
+	u, err := base.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	t := task.NewTaskFromURL(u)
+	t.Provenance = task.NewProvenance(task.OriginPlugin, base, p.Name())
+	t.ParentURL = base
+	p.adder(t)
+	return starlark.None, nil
+}
+
+func scriptLog(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var msg string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &msg); err != nil {
+		return nil, err
+	}
+	logging.Logf(logging.LogWarning, "%s: %s", thread.Name, msg)
+	return starlark.None, nil
+}
+
+func scriptNote(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var msg string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &msg); err != nil {
+		return nil, err
+	}
+	r, ok := thread.Local(scriptLocalResult).(*results.Result)
+	if !ok {
+		return nil, errors.New("note may only be called for a result")
+	}
+	r.AddNote("%s", msg)
+	return starlark.None, nil
+}
+
+func scriptRun(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if len(args) == 0 || len(kwargs) != 0 {
+		return nil, errors.New("run: expected command and positional arguments")
+	}
+	argv := make([]string, len(args))
+	for i, a := range args {
+		s, ok := starlark.AsString(a)
+		if !ok {
+			return nil, fmt.Errorf("run: argument %d is not a string", i)
+		}
+		argv[i] = s
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), scriptRunTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, argv[0], argv[1:]...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("run: %s", err.Error())
+	}
+	return starlark.String(out), nil
+}
+
+func resultToDict(r *results.Result) *starlark.Dict {
+	d := starlark.NewDict(6)
+	d.SetKey(starlark.String("url"), starlark.String(r.URL.String()))
+	d.SetKey(starlark.String("code"), starlark.MakeInt(r.Code))
+	d.SetKey(starlark.String("length"), starlark.MakeInt64(r.Length))
+	d.SetKey(starlark.String("content_type"), starlark.String(r.ContentType))
+	redir := ""
+	if r.Redir != nil {
+		redir = r.Redir.String()
+	}
+	d.SetKey(starlark.String("redirect"), starlark.String(redir))
+	d.SetKey(starlark.String("headers"), headerToDict(r.ResponseHeader))
+	return d
+}
+
+func headerToDict(h http.Header) *starlark.Dict {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	d := starlark.NewDict(len(h))
+	for _, k := range keys {
+		d.SetKey(starlark.String(k), starlark.String(strings.Join(h[k], ", ")))
+	}
+	return d
+}
+
+func dictToHeader(d *starlark.Dict) http.Header {
+	h := make(http.Header)
+	for _, item := range d.Items() {
+		k, kok := starlark.AsString(item[0])
+		v, vok := starlark.AsString(item[1])
+		if kok && vok {
+			h.Set(k, v)
+		}
+	}
+	return h
+}
+
+func dictString(d *starlark.Dict, key string) (string, bool) {
+	v, found, _ := d.Get(starlark.String(key))
+	if !found {
+		return "", false
+	}
+	return starlark.AsString(v)
+}
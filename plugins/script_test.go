@@ -0,0 +1,139 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugins
+
+import (
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/task"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testScript = `
+def before_request(req):
+    if req["url"].endswith("/skip"):
+        return False
+    req["headers"]["X-Script"] = "yes"
+    if req["url"].endswith("/old"):
+        req["url"] = req["url"].replace("/old", "/new")
+
+def after_response(resp):
+    if "secret" in resp["body"]:
+        note("body contains a secret")
+        enqueue("backup/")
+    return resp["code"] != 418
+
+def on_hit(result):
+    note("hit " + str(result["code"]))
+`
+
+func newScriptPlugin(t *testing.T, src string) (*ScriptPlugin, error) {
+	dir, err := ioutil.TempDir("", "webborer-script")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %s", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "hooks.star")
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatalf("Unable to write script: %s", err)
+	}
+	p := &ScriptPlugin{}
+	return p, p.Configure(map[string]string{"file": path})
+}
+
+func TestScriptPlugin_BeforeRequest(t *testing.T) {
+	p, err := newScriptPlugin(t, testScript)
+	if err != nil {
+		t.Fatalf("Unable to configure script: %s", err)
+	}
+	tsk := task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/old"})
+	nt := p.Mutate(tsk)
+	if nt == nil {
+		t.Fatal("Task unexpectedly dropped.")
+	}
+	if nt.URL.Path != "/new" {
+		t.Errorf("Expected rewritten URL, got %s", nt.URL)
+	}
+	if nt.Header.Get("X-Script") != "yes" {
+		t.Errorf("Expected header from script, got %v", nt.Header)
+	}
+	if tsk.URL.Path != "/old" || tsk.Header.Get("X-Script") != "" {
+		t.Error("Original task was modified.")
+	}
+	if p.Mutate(task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/skip"})) != nil {
+		t.Error("Expected task to be dropped.")
+	}
+}
+
+func TestScriptPlugin_AfterResponse(t *testing.T) {
+	p, err := newScriptPlugin(t, testScript)
+	if err != nil {
+		t.Fatalf("Unable to configure script: %s", err)
+	}
+	var added []*task.Task
+	p.SetAdder(func(tasks ...*task.Task) {
+		added = append(added, tasks...)
+	})
+	if !p.Eligible(&http.Response{}) {
+		t.Fatal("Expected script to be eligible.")
+	}
+	u := &url.URL{Scheme: "http", Host: "localhost", Path: "/dir/page"}
+	tsk := task.NewTaskFromURL(u)
+	r := results.NewResultForTask(tsk)
+	r.Code = 200
+	p.Handle(tsk, strings.NewReader("a secret"), r)
+	if len(added) != 1 || added[0].URL.Path != "/dir/backup/" {
+		t.Errorf("Expected /dir/backup/ to be enqueued, got %v", added)
+	}
+	if !p.Keep(r) {
+		t.Error("Expected result to be kept.")
+	}
+	if len(r.Notes) != 2 || r.Notes[0] != "body contains a secret" || r.Notes[1] != "hit 200" {
+		t.Errorf("Unexpected notes: %v", r.Notes)
+	}
+
+	teapot := results.NewResultForTask(tsk)
+	teapot.Code = 418
+	p.Handle(tsk, strings.NewReader(""), teapot)
+	if p.Keep(teapot) {
+		t.Error("Expected result to be dropped.")
+	}
+}
+
+func TestScriptPlugin_Errors(t *testing.T) {
+	if _, err := newScriptPlugin(t, "x = 1\n"); err == nil {
+		t.Error("Expected error for script without hooks.")
+	}
+	if _, err := newScriptPlugin(t, "def on_hit(:\n"); err == nil {
+		t.Error("Expected syntax error.")
+	}
+	p, err := newScriptPlugin(t, "def on_hit(r):\n    fail('oops')\n")
+	if err != nil {
+		t.Fatalf("Unable to configure script: %s", err)
+	}
+	r := results.NewResult(&url.URL{Scheme: "http", Host: "localhost", Path: "/"}, "")
+	r.Code = 200
+	if !p.Keep(r) {
+		t.Error("Expected result to be kept despite script error.")
+	}
+	if err := (&ScriptPlugin{}).Configure(map[string]string{}); err == nil {
+		t.Error("Expected error without file.")
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugins
+
+import (
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/workqueue"
+)
+
+// A Set is the collection of plugins used by a scan.
+type Set struct {
+	plugins []Plugin
+}
+
+// Create and configure the plugins described by specs and add them to the
+// set.
+func (s *Set) Load(specs []string) error {
+	for _, spec := range specs {
+		p, err := New(spec)
+		if err != nil {
+			return err
+		}
+		s.Add(p)
+	}
+	return nil
+}
+
+// Add an already configured plugin to the set.
+func (s *Set) Add(p Plugin) {
+	logging.Logf(logging.LogInfo, "Using plugin %s", p.Name())
+	s.plugins = append(s.plugins, p)
+}
+
+// Number of plugins in the set.
+func (s *Set) Len() int {
+	return len(s.plugins)
+}
+
+// Get the plugins that are page workers.
+func (s *Set) PageWorkers() []PageWorker {
+	var workers []PageWorker
+	for _, p := range s.plugins {
+		if pw, ok := p.(PageWorker); ok {
+			workers = append(workers, pw)
+		}
+	}
+	return workers
+}
+
+// Get the plugins that are result filters.
+func (s *Set) ResultFilters() []ResultFilter {
+	var filters []ResultFilter
+	for _, p := range s.plugins {
+		if rf, ok := p.(ResultFilter); ok {
+			filters = append(filters, rf)
+		}
+	}
+	return filters
+}
+
+// Get the plugins that are task mutators.
+func (s *Set) TaskMutators() []TaskMutator {
+	var mutators []TaskMutator
+	for _, p := range s.plugins {
+		if tm, ok := p.(TaskMutator); ok {
+			mutators = append(mutators, tm)
+		}
+	}
+	return mutators
+}
+
+// Get the first plugin that is a dialer, or nil if there is none.
+func (s *Set) Dialer() Dialer {
+	for _, p := range s.plugins {
+		if d, ok := p.(Dialer); ok {
+			return d
+		}
+	}
+	return nil
+}
+
+// Give plugins that add tasks the function to do so.
+func (s *Set) SetAdder(adder workqueue.QueueAddFunc) {
+	for _, p := range s.plugins {
+		if e, ok := p.(Enqueuer); ok {
+			e.SetAdder(adder)
+		}
+	}
+}
+
+// Tell plugins that only handle reported results which codes are reported.
+func (s *Set) SetReportCodes(codes results.ReportCodes) {
+	for _, p := range s.plugins {
+		results.SetReportCodes(p, codes)
+	}
+}
+
+// Run results through the result filters, dropping those any filter rejects.
+func (s *Set) Process(in <-chan *results.Result) <-chan *results.Result {
+	filters := s.ResultFilters()
+	if len(filters) == 0 {
+		return in
+	}
+	out := make(chan *results.Result, cap(in))
+	go func() {
+		defer close(out)
+	resultLoop:
+		for r := range in {
+			for _, f := range filters {
+				if !f.Keep(r) {
+					logging.Logf(logging.LogDebug, "Plugin dropped result %s", r.URL.String())
+					continue resultLoop
+				}
+			}
+			out <- r
+		}
+	}()
+	return out
+}
+
+// Run tasks through the task mutators.  Dropped tasks are marked done.
+func (s *Set) MutateTasks(in <-chan *task.Task, done workqueue.QueueDoneFunc) <-chan *task.Task {
+	mutators := s.TaskMutators()
+	if len(mutators) == 0 {
+		return in
+	}
+	out := make(chan *task.Task, cap(in))
+	go func() {
+		defer close(out)
+		for t := range in {
+			for _, m := range mutators {
+				if t = m.Mutate(t); t == nil {
+					break
+				}
+			}
+			if t == nil {
+				done(1)
+				continue
+			}
+			out <- t
+		}
+	}()
+	return out
+}
+
+// Close all plugins, returning the first error encountered.
+func (s *Set) Close() error {
+	var rv error
+	for _, p := range s.plugins {
+		if err := p.Close(); err != nil {
+			logging.Logf(logging.LogWarning, "Error closing plugin %s: %s", p.Name(), err.Error())
+			if rv == nil {
+				rv = err
+			}
+		}
+	}
+	return rv
+}
@@ -0,0 +1,108 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugins
+
+import (
+	"context"
+	"errors"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/task"
+	"net"
+	"net/url"
+	"testing"
+)
+
+func TestSet_Process(t *testing.T) {
+	set := &Set{}
+	set.Add(&dropPlugin{})
+	in := make(chan *results.Result, 2)
+	for _, path := range []string{"/keep", "/drop"} {
+		in <- results.NewResult(&url.URL{Scheme: "http", Host: "localhost", Path: path}, "")
+	}
+	close(in)
+	var kept []string
+	for r := range set.Process(in) {
+		kept = append(kept, r.URL.Path)
+	}
+	if len(kept) != 1 || kept[0] != "/keep" {
+		t.Errorf("Expected only /keep, got %v", kept)
+	}
+}
+
+func TestSet_MutateTasks(t *testing.T) {
+	set := &Set{}
+	set.Add(&dropPlugin{})
+	in := make(chan *task.Task, 2)
+	for _, path := range []string{"/keep", "/skip"} {
+		in <- task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: path})
+	}
+	close(in)
+	dropped := 0
+	var kept []string
+	for tsk := range set.MutateTasks(in, func(n int) { dropped += n }) {
+		kept = append(kept, tsk.URL.Path)
+	}
+	if len(kept) != 1 || kept[0] != "/keep" {
+		t.Errorf("Expected only /keep, got %v", kept)
+	}
+	if dropped != 1 {
+		t.Errorf("Expected 1 dropped task to be done, got %d", dropped)
+	}
+}
+
+func TestSet_Passthrough(t *testing.T) {
+	set := &Set{}
+	set.Add(&testPlugin{})
+	in := make(chan *results.Result)
+	if set.Process(in) != (<-chan *results.Result)(in) {
+		t.Error("Expected results to pass through without filters.")
+	}
+	if len(set.PageWorkers()) != 0 || len(set.TaskMutators()) != 0 {
+		t.Error("Expected no page workers or mutators.")
+	}
+}
+
+func TestSet_Close(t *testing.T) {
+	set := &Set{}
+	p := &testPlugin{}
+	set.Add(p)
+	if err := set.Close(); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if !p.closed {
+		t.Error("Expected plugin to be closed.")
+	}
+}
+
+type dialPlugin struct {
+	testPlugin
+}
+
+func (p *dialPlugin) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return nil, errors.New("not dialing")
+}
+
+func TestSet_Dialer(t *testing.T) {
+	set := &Set{}
+	set.Add(&dropPlugin{})
+	if set.Dialer() != nil {
+		t.Error("Expected no dialer without a dialer plugin.")
+	}
+	dialer := &dialPlugin{}
+	set.Add(dialer)
+	if got := set.Dialer(); got != dialer {
+		t.Errorf("Expected the dialer plugin, got %v", got)
+	}
+}
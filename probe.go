@@ -0,0 +1,97 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webborer
+
+import (
+	"github.com/Matir/webborer/client"
+	"github.com/Matir/webborer/hosts"
+	"github.com/Matir/webborer/logging"
+	ss "github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/util"
+	"net/url"
+)
+
+// Replace starting URLs given without a scheme with the live schemes and
+// ports found for them.
+func (s *Scanner) probeScopes(scope []*url.URL) []*url.URL {
+	bare := make(map[string]bool)
+	for _, target := range s.settings.BaseURLs {
+		if target == ss.StdinURL || util.HasScheme(target) {
+			continue
+		}
+		if u, err := util.ParseTargetURL(target); err == nil {
+			bare[u.String()] = true
+		}
+	}
+	probed := make([]*url.URL, 0, len(scope))
+	for _, u := range scope {
+		if bare[u.String()] {
+			probed = append(probed, s.probeTarget(u)...)
+		} else {
+			probed = append(probed, u)
+		}
+	}
+	return probed
+}
+
+// Find the live origins of a target given without a scheme, recording how
+// they are related.  If none respond, the target is scanned as given.
+func (s *Scanner) probeTarget(u *url.URL) []*url.URL {
+	logging.Logf(logging.LogInfo, "Probing schemes and ports for %s", u.Host)
+	live, aliases := hosts.Probe(s.factory.Get(), hosts.ProbeCandidates(u))
+	for _, a := range aliases {
+		logging.Logf(logging.LogInfo, "%s", a.String())
+	}
+	s.hosts.AddAliases(aliases...)
+	if len(live) == 0 {
+		logging.Logf(logging.LogWarning, "Nothing responded for %s, scanning %s.", u.Host, u.String())
+		return []*url.URL{u}
+	}
+	for _, l := range live {
+		logging.Logf(logging.LogInfo, "Scanning %s for %s", l.String(), u.Host)
+	}
+	return live
+}
+
+// Scan only one of each set of starting URLs whose hosts are the same
+// backend, recording the rest as aliases.
+func (s *Scanner) dedupeScopes(scope []*url.URL) []*url.URL {
+	lookup := hosts.SystemLookup
+	if factory, ok := s.factory.(*client.ProxyClientFactory); ok && factory.Resolver() != nil {
+		lookup = factory.Resolver().Lookup
+	}
+	var fingerprint func(*url.URL) string
+	if s.settings.DedupeContent {
+		fingerprint = hosts.ContentFingerprint(s.factory.Get())
+	}
+	logging.Logf(logging.LogInfo, "Looking for duplicates among %d starting URLs", len(scope))
+	kept, aliases := hosts.Dedupe(scope, lookup, fingerprint)
+	for _, a := range aliases {
+		logging.Logf(logging.LogInfo, "Not scanning %s, which shares a backend with %s", a.From.String(), a.To.String())
+	}
+	s.hosts.AddAliases(aliases...)
+	return kept
+}
+
+// Get the relationships found between starting hosts: redirects from one to
+// another, shared certificates, and duplicate backends.
+func (s *Scanner) Aliases() []*hosts.Alias {
+	s.Lock()
+	defer s.Unlock()
+	if s.hosts == nil {
+		return nil
+	}
+	return s.hosts.Aliases()
+}
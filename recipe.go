@@ -0,0 +1,180 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webborer
+
+import (
+	"context"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/results"
+	ss "github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/util"
+	"sync"
+)
+
+// RecipeRunner runs the stages of a recipe one after another, each as a scan
+// of its own starting from what the stages before it found.  The results of
+// all of the stages are delivered together, without repeating those an
+// earlier stage already reported.
+type RecipeRunner struct {
+	sync.Mutex
+	settings *ss.ScanSettings
+	recipe   *ss.Recipe
+	results  chan *results.Result
+	onStage  []func(*ss.RecipeStage, *Scanner)
+	started  bool
+	finished chan bool
+	err      error
+}
+
+// Create a runner for the recipe.  Each stage's settings are those given with
+// the stage's changes applied.
+func NewRecipeRunner(settings *ss.ScanSettings, recipe *ss.Recipe) *RecipeRunner {
+	return &RecipeRunner{
+		settings: settings,
+		recipe:   recipe,
+		results:  make(chan *results.Result, settings.QueueSize),
+		finished: make(chan bool),
+	}
+}
+
+// Register a callback invoked with each stage's scanner before it starts, to
+// add callbacks or stages to it.
+func (r *RecipeRunner) OnStage(f func(*ss.RecipeStage, *Scanner)) {
+	r.onStage = append(r.onStage, f)
+}
+
+// Get the channel of results from all of the stages, closed when the last
+// one finishes.  It must be drained, or the scan will stall.
+func (r *RecipeRunner) Results() <-chan *results.Result {
+	return r.results
+}
+
+// Start running the stages.  Errors setting a stage up end the run early, and
+// are returned by Wait.
+func (r *RecipeRunner) Start(ctx context.Context) error {
+	r.Lock()
+	defer r.Unlock()
+	if r.started {
+		return ErrAlreadyStarted
+	}
+	r.started = true
+	go r.run(ctx)
+	return nil
+}
+
+// Wait for the last stage to finish.
+func (r *RecipeRunner) Wait() error {
+	r.Lock()
+	started := r.started
+	r.Unlock()
+	if !started {
+		return ErrNotStarted
+	}
+	<-r.finished
+	return r.err
+}
+
+func (r *RecipeRunner) run(ctx context.Context) {
+	defer close(r.finished)
+	defer close(r.results)
+	reported := make(map[string]bool)
+	var found []*results.Result
+	for i, stage := range r.recipe.Stages {
+		if ctx.Err() != nil {
+			return
+		}
+		var targets []string
+		if stage.From != "" {
+			if targets = recipeTargets(found, stage.From); len(targets) == 0 {
+				logging.Logf(logging.LogInfo, "Recipe stage %s has nothing to start from, stopping.", stage.Name)
+				return
+			}
+		}
+		logging.Logf(logging.LogInfo, "Starting recipe stage %d of %d: %s", i+1, len(r.recipe.Stages), stage.Name)
+		scanner, err := r.stageScanner(stage, targets)
+		if err != nil {
+			r.err = err
+			return
+		}
+		for _, f := range r.onStage {
+			f(stage, scanner)
+		}
+		report := results.NewReportCodes(scanner.settings)
+		resultChan := scanner.Results()
+		if err := scanner.Start(ctx); err != nil {
+			r.err = err
+			return
+		}
+		for res := range resultChan {
+			if report.Result(res) {
+				key := res.URL.String()
+				if reported[key] {
+					continue
+				}
+				reported[key] = true
+				found = append(found, res)
+			}
+			r.results <- res
+		}
+		if err := scanner.Wait(); err != nil {
+			r.err = err
+			return
+		}
+	}
+}
+
+// Build the scanner for a stage, starting from targets if there are any.
+func (r *RecipeRunner) stageScanner(stage *ss.RecipeStage, targets []string) (*Scanner, error) {
+	settings, err := r.settings.WithValues(stage.Settings)
+	if err != nil {
+		return nil, err
+	}
+	if targets != nil {
+		settings.BaseURLs = targets
+	}
+	scanner := NewScanner(settings)
+	if stage.From == ss.RecipeFromFiles {
+		// Files can't be extended with words; the stage only requests them
+		// again, to mangle them or try other settings
+		scanner.SetWords([]string{})
+	}
+	return scanner, nil
+}
+
+// Get the URLs of the results to start a stage from, which may be the
+// directories or files found so far, or both.  A redirect to the same
+// path with a trailing slash counts as finding the directory.
+func recipeTargets(found []*results.Result, from string) []string {
+	seen := make(map[string]bool)
+	var targets []string
+	for _, res := range found {
+		u := res.URL
+		if res.Redir != nil {
+			if res.Redir.Host != u.Host || res.Redir.Path != u.Path+"/" {
+				continue
+			}
+			u = res.Redir
+		}
+		isDir := util.URLIsDir(u)
+		if (from == ss.RecipeFromDirs && !isDir) || (from == ss.RecipeFromFiles && isDir) {
+			continue
+		}
+		if key := u.String(); !seen[key] {
+			seen[key] = true
+			targets = append(targets, key)
+		}
+	}
+	return targets
+}
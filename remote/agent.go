@@ -0,0 +1,244 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Matir/webborer/client"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/results"
+	ss "github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/wire"
+	"github.com/Matir/webborer/worker"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Time between syncs while busy
+	defaultSyncInterval = time.Second
+	// Number of consecutive failed syncs before giving up
+	maxSyncFailures = 10
+)
+
+// Agent runs workers on tasks leased from a coordinator.  The agent's own
+// settings control how requests are made (workers, proxies, headers and so
+// on); the coordinator decides what is requested.
+type Agent struct {
+	coordinator string
+	token       string
+	name        string
+	interval    time.Duration
+	client      *http.Client
+	workers     []*worker.Worker
+	leases      chan *Lease
+	rchan       chan *results.Result
+	completions chan uint64
+	// Outgoing data for the next sync
+	pending *SyncRequest
+	sync.Mutex
+}
+
+func NewAgent(coordinator, token string, settings *ss.ScanSettings, factory client.ClientFactory) *Agent {
+	hostname, _ := os.Hostname()
+	a := &Agent{
+		coordinator: strings.TrimRight(coordinator, "/"),
+		token:       token,
+		name:        fmt.Sprintf("%s/%d", hostname, os.Getpid()),
+		interval:    defaultSyncInterval,
+		client:      &http.Client{Timeout: time.Minute},
+		leases:      make(chan *Lease, settings.Workers*2),
+		rchan:       make(chan *results.Result),
+		completions: make(chan uint64),
+		pending:     &SyncRequest{},
+	}
+	a.workers = worker.NewWorkers(settings, factory, nil, a.addTasks, func(int) {}, a.rchan)
+	for _, w := range a.workers {
+		w.SetSpider(a.spiderTasks)
+	}
+	return a
+}
+
+// Trust the coordinator's certificate if it is signed by the CA in the PEM
+// file at path.
+func (a *Agent) SetCA(path string) error {
+	pem, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return errors.New("No certificates found in " + path)
+	}
+	a.client.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}
+	return nil
+}
+
+// Run the agent until the coordinator reports the scan is done.
+func (a *Agent) Run() error {
+	collectorDone := make(chan bool)
+	go a.collect(collectorDone)
+	wg := sync.WaitGroup{}
+	for _, w := range a.workers {
+		wg.Add(1)
+		go func(w *worker.Worker) {
+			defer wg.Done()
+			a.work(w)
+		}(w)
+	}
+	err := a.syncLoop()
+	close(a.leases)
+	wg.Wait()
+	close(a.rchan)
+	<-collectorDone
+	return err
+}
+
+// Handle leased tasks until there are no more.
+func (a *Agent) work(w *worker.Worker) {
+	for l := range a.leases {
+		t, err := l.Task.Task()
+		if err != nil {
+			logging.Logf(logging.LogWarning, "Invalid task from coordinator: %s", err.Error())
+		} else {
+			w.HandleTask(t)
+		}
+		a.completions <- l.ID
+	}
+}
+
+// Collect results and completions in the order they happen, so a completion
+// is never reported before the results of its task.
+func (a *Agent) collect(done chan<- bool) {
+	defer close(done)
+	for {
+		select {
+		case r, ok := <-a.rchan:
+			if !ok {
+				return
+			}
+			a.Lock()
+			a.pending.Results = append(a.pending.Results, wire.FromResult(r))
+			a.Unlock()
+		case id := <-a.completions:
+			a.Lock()
+			a.pending.Completed = append(a.pending.Completed, id)
+			a.Unlock()
+		}
+	}
+}
+
+func (a *Agent) addTasks(tasks ...*task.Task) {
+	a.Lock()
+	defer a.Unlock()
+	for _, t := range tasks {
+		a.pending.Tasks = append(a.pending.Tasks, wire.FromTask(t))
+	}
+}
+
+// Send tasks found by spidering separately, for the coordinator to check
+// against the spider policy.
+func (a *Agent) spiderTasks(tasks ...*task.Task) {
+	a.Lock()
+	defer a.Unlock()
+	for _, t := range tasks {
+		a.pending.Spidered = append(a.pending.Spidered, wire.FromTask(t))
+	}
+}
+
+func (a *Agent) syncLoop() error {
+	failures := 0
+	for {
+		resp, err := a.sync()
+		if err != nil {
+			failures++
+			logging.Logf(logging.LogWarning, "Sync with coordinator failed: %s", err.Error())
+			if failures >= maxSyncFailures {
+				return err
+			}
+			time.Sleep(a.interval)
+			continue
+		}
+		failures = 0
+		if resp.Done {
+			return nil
+		}
+		for _, l := range resp.Leases {
+			a.leases <- l
+		}
+		if len(resp.Leases) == 0 {
+			time.Sleep(a.interval)
+		}
+	}
+}
+
+// Send pending data to the coordinator and get more work.  If the sync fails,
+// the data is kept for the next attempt.
+func (a *Agent) sync() (*SyncResponse, error) {
+	a.Lock()
+	req := a.pending
+	a.pending = &SyncRequest{}
+	a.Unlock()
+	req.Agent = a.name
+	req.Want = cap(a.leases) - len(a.leases)
+	resp, err := a.post(req)
+	if err != nil {
+		a.Lock()
+		a.pending.Results = append(req.Results, a.pending.Results...)
+		a.pending.Completed = append(req.Completed, a.pending.Completed...)
+		a.pending.Tasks = append(req.Tasks, a.pending.Tasks...)
+		a.pending.Spidered = append(req.Spidered, a.pending.Spidered...)
+		a.Unlock()
+	}
+	return resp, err
+}
+
+func (a *Agent) post(req *SyncRequest) (*SyncResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequest("POST", a.coordinator+SyncPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if a.token != "" {
+		httpReq.Header.Set(TokenHeader, a.token)
+	}
+	httpResp, err := a.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Coordinator returned %s", httpResp.Status)
+	}
+	resp := &SyncResponse{}
+	if err := json.NewDecoder(httpResp.Body).Decode(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"github.com/Matir/webborer/client/mock"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/task"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAgent_EndToEnd(t *testing.T) {
+	src := testTasks("/a", "/b", "/c", "/d")
+	rchan := make(chan *results.Result, 10)
+	var c *Coordinator
+	doneMu := sync.Mutex{}
+	done := 0
+	c = NewCoordinator(src, func(...*task.Task) {}, func(n int) {
+		doneMu.Lock()
+		defer doneMu.Unlock()
+		done += n
+		if done == 4 {
+			// Stop waits for the sync that called this to finish
+			go c.Stop()
+		}
+	}, rchan, "secret")
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	resp := mock.ResponseFromString("")
+	resp.StatusCode = 200
+	factory := &mock.MockClientFactory{ForeverClient: &mock.MockClient{ForeverResponse: resp}}
+	ss := &settings.ScanSettings{Workers: 2, RenderDepth: -1}
+	agent := NewAgent(server.URL, "secret", ss, factory)
+	agent.interval = 10 * time.Millisecond
+	if err := agent.Run(); err != nil {
+		t.Fatalf("Agent failed: %v", err)
+	}
+	if len(rchan) != 4 {
+		t.Errorf("Expected 4 results, got %d", len(rchan))
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/wire"
+	"github.com/Matir/webborer/workqueue"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Default time an agent has to complete a lease before it is handed to
+// another agent.
+const DefaultLeaseTimeout = 5 * time.Minute
+
+// Maximum number of tasks handed out in a single sync
+const maxLeaseBatch = 1000
+
+type lease struct {
+	id      uint64
+	task    *task.Task
+	expires time.Time
+}
+
+// Coordinator hands out tasks from the filtered work channel to agents in
+// place of local workers, and feeds their results and discoveries back into
+// the pipeline.
+type Coordinator struct {
+	src          <-chan *task.Task
+	adder        workqueue.QueueAddFunc
+	spider       workqueue.SpiderFunc
+	done         workqueue.QueueDoneFunc
+	rchan        chan<- *results.Result
+	token        string
+	leaseTimeout time.Duration
+	nextID       uint64
+	leases       map[uint64]*lease
+	stopped      bool
+	// Certificate to serve agents over TLS with, if any
+	cert *tls.Certificate
+	// Syncs sending results, which Stop waits for
+	syncing sync.WaitGroup
+	sync.Mutex
+}
+
+func NewCoordinator(src <-chan *task.Task,
+	adder workqueue.QueueAddFunc,
+	done workqueue.QueueDoneFunc,
+	rchan chan<- *results.Result,
+	token string) *Coordinator {
+	return &Coordinator{
+		src:          src,
+		adder:        adder,
+		done:         done,
+		rchan:        rchan,
+		token:        token,
+		leaseTimeout: DefaultLeaseTimeout,
+		leases:       make(map[uint64]*lease),
+	}
+}
+
+// Queue tasks agents discover by spidering with spider instead of the adder,
+// so they pass the same policy as those found by local workers.
+func (c *Coordinator) SetSpider(spider workqueue.SpiderFunc) {
+	c.spider = spider
+}
+
+// Set how long an agent has to complete a task before it is reassigned.
+func (c *Coordinator) SetLeaseTimeout(timeout time.Duration) {
+	c.leaseTimeout = timeout
+}
+
+// Serve agents over TLS with the certificate and key in the given files.
+func (c *Coordinator) SetTLS(certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	c.cert = &cert
+	return nil
+}
+
+// Start serving agents on addr in the background.  A token is required, as
+// agents are sent request headers and send back results.
+func (c *Coordinator) ListenAndServe(addr string) error {
+	if c.token == "" {
+		return errors.New("A token is required to serve agents.")
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	if c.cert != nil {
+		l = tls.NewListener(l, &tls.Config{Certificates: []tls.Certificate{*c.cert}})
+	} else {
+		logging.Logf(logging.LogWarning, "Serving agents without TLS; the token and results are sent in the clear.")
+	}
+	logging.Logf(logging.LogInfo, "Coordinator listening on %s", l.Addr().String())
+	mux := http.NewServeMux()
+	mux.Handle(SyncPath, c)
+	go func() {
+		if err := http.Serve(l, mux); err != nil {
+			logging.Logf(logging.LogError, "Coordinator stopped: %s", err.Error())
+		}
+	}()
+	return nil
+}
+
+// Tell agents the scan is finished, waiting for syncs still sending results.
+// Must be called before the result channel is closed.
+func (c *Coordinator) Stop() {
+	c.Lock()
+	c.stopped = true
+	c.Unlock()
+	c.syncing.Wait()
+}
+
+func (c *Coordinator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if c.token == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get(TokenHeader)), []byte(c.token)) != 1 {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	req := &SyncRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp := c.Sync(req)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Process a sync from an agent.  Results are sent on before new tasks are
+// added and completed tasks are marked done, so the queue cannot finish
+// while results are outstanding.  The lock isn't held while results and
+// tasks are passed on, so a full pipeline doesn't hold up other agents.
+func (c *Coordinator) Sync(req *SyncRequest) *SyncResponse {
+	c.Lock()
+	if c.stopped {
+		c.Unlock()
+		return &SyncResponse{Done: true}
+	}
+	c.syncing.Add(1)
+	c.Unlock()
+	defer c.syncing.Done()
+	for _, wr := range req.Results {
+		if r, err := wr.Result(); err != nil {
+			logging.Logf(logging.LogWarning, "Invalid result from agent %s: %s", req.Agent, err.Error())
+		} else {
+			c.rchan <- r
+		}
+	}
+	if tasks := c.tasks(req.Agent, req.Tasks); len(tasks) > 0 {
+		c.adder(tasks...)
+	}
+	if tasks := c.tasks(req.Agent, req.Spidered); len(tasks) > 0 {
+		if c.spider != nil {
+			c.spider(tasks...)
+		} else {
+			c.adder(tasks...)
+		}
+	}
+	want := req.Want
+	if want > maxLeaseBatch {
+		want = maxLeaseBatch
+	}
+	c.Lock()
+	completed := 0
+	for _, id := range req.Completed {
+		if _, ok := c.leases[id]; ok {
+			delete(c.leases, id)
+			completed++
+		}
+	}
+	leases := c.lease(want)
+	c.Unlock()
+	if completed > 0 {
+		c.done(completed)
+	}
+	return &SyncResponse{Leases: leases}
+}
+
+// Decode the tasks sent by an agent, skipping any that are invalid.
+func (c *Coordinator) tasks(agent string, wts []*wire.Task) []*task.Task {
+	tasks := make([]*task.Task, 0, len(wts))
+	for _, wt := range wts {
+		if t, err := wt.Task(); err != nil {
+			logging.Logf(logging.LogWarning, "Invalid task from agent %s: %s", agent, err.Error())
+		} else {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks
+}
+
+// Lease up to n tasks, reassigning expired leases first.  Never blocks
+// waiting for new work.
+func (c *Coordinator) lease(n int) []*Lease {
+	now := time.Now()
+	leases := make([]*Lease, 0)
+	for _, l := range c.leases {
+		if len(leases) >= n {
+			return leases
+		}
+		if now.After(l.expires) {
+			logging.Logf(logging.LogInfo, "Reassigning expired lease for %s", l.task.String())
+			l.expires = now.Add(c.leaseTimeout)
+			leases = append(leases, &Lease{ID: l.id, Task: wire.FromTask(l.task)})
+		}
+	}
+	for len(leases) < n {
+		select {
+		case t, ok := <-c.src:
+			if !ok {
+				return leases
+			}
+			c.nextID++
+			l := &lease{id: c.nextID, task: t, expires: now.Add(c.leaseTimeout)}
+			c.leases[l.id] = l
+			leases = append(leases, &Lease{ID: l.id, Task: wire.FromTask(t)})
+		default:
+			return leases
+		}
+	}
+	return leases
+}
@@ -0,0 +1,106 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/wire"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testTasks(paths ...string) chan *task.Task {
+	src := make(chan *task.Task, len(paths))
+	for _, p := range paths {
+		src <- task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: p})
+	}
+	return src
+}
+
+func TestCoordinator_Sync(t *testing.T) {
+	src := testTasks("/a", "/b", "/c")
+	added := 0
+	spidered := 0
+	done := 0
+	rchan := make(chan *results.Result, 10)
+	c := NewCoordinator(src, func(t ...*task.Task) { added += len(t) }, func(n int) { done += n }, rchan, "")
+	c.SetSpider(func(t ...*task.Task) { spidered += len(t) })
+	resp := c.Sync(&SyncRequest{Want: 2})
+	if len(resp.Leases) != 2 || resp.Done {
+		t.Fatalf("Expected 2 leases, got %d", len(resp.Leases))
+	}
+	req := &SyncRequest{
+		Want:      5,
+		Completed: []uint64{resp.Leases[0].ID, resp.Leases[0].ID, 999},
+		Results:   []*wire.Result{{URL: "http://localhost/a", Code: 200}},
+		Tasks:     []*wire.Task{{URL: "http://localhost/d"}},
+		Spidered:  []*wire.Task{{URL: "http://localhost/e"}, {URL: "http://localhost/f"}},
+	}
+	resp = c.Sync(req)
+	if len(resp.Leases) != 1 || resp.Leases[0].Task.URL != "http://localhost/c" {
+		t.Errorf("Expected lease for /c, got %v", resp.Leases)
+	}
+	if done != 1 || added != 1 || len(rchan) != 1 {
+		t.Errorf("Expected 1 done, added and result, got %d, %d, %d", done, added, len(rchan))
+	}
+	if spidered != 2 {
+		t.Errorf("Expected 2 spidered tasks, got %d", spidered)
+	}
+	// Expire the outstanding leases
+	c.SetLeaseTimeout(0)
+	for _, l := range c.leases {
+		l.expires = time.Now().Add(-time.Second)
+	}
+	resp = c.Sync(&SyncRequest{Want: 5})
+	if len(resp.Leases) != 2 {
+		t.Errorf("Expected 2 reassigned leases, got %d", len(resp.Leases))
+	}
+	c.Stop()
+	if resp = c.Sync(&SyncRequest{Want: 5}); !resp.Done {
+		t.Error("Expected done after stop.")
+	}
+}
+
+func TestCoordinator_RequiresToken(t *testing.T) {
+	c := NewCoordinator(testTasks(), func(...*task.Task) {}, func(int) {}, make(chan *results.Result), "")
+	if err := c.ListenAndServe("127.0.0.1:0"); err == nil {
+		t.Error("Expected serving without a token to fail.")
+	}
+	req := httptest.NewRequest("POST", SyncPath, strings.NewReader("{}"))
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, req)
+	if rec.Code != 403 {
+		t.Errorf("Expected 403 without a token, got %d", rec.Code)
+	}
+}
+
+func TestCoordinator_Token(t *testing.T) {
+	c := NewCoordinator(testTasks(), func(...*task.Task) {}, func(int) {}, make(chan *results.Result), "secret")
+	for token, expected := range map[string]int{"": 403, "wrong": 403, "secret": 200} {
+		req := httptest.NewRequest("POST", SyncPath, strings.NewReader("{}"))
+		if token != "" {
+			req.Header.Set(TokenHeader, token)
+		}
+		rec := httptest.NewRecorder()
+		c.ServeHTTP(rec, req)
+		if rec.Code != expected {
+			t.Errorf("Token %q: expected %d, got %d", token, expected, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package remote allows spreading a scan across several machines.  A
+// coordinator owns the workqueue, filters and results, while agents lease
+// batches of tasks from it over HTTP, perform the requests, and send back
+// results and newly discovered tasks.
+package remote
+
+import (
+	"github.com/Matir/webborer/wire"
+)
+
+// Path of the sync endpoint on the coordinator
+const SyncPath = "/sync"
+
+// Header used to authenticate agents to the coordinator
+const TokenHeader = "X-Webborer-Token"
+
+// A Lease is a task handed to an agent, identified so completion can be
+// reported.
+type Lease struct {
+	ID   uint64     `json:"id"`
+	Task *wire.Task `json:"task"`
+}
+
+// SyncRequest is sent by an agent to report progress and ask for work.
+type SyncRequest struct {
+	// Name of the agent, for logging
+	Agent string `json:"agent"`
+	// Number of tasks the agent can accept
+	Want int `json:"want"`
+	// Leases that have been completed
+	Completed []uint64 `json:"completed,omitempty"`
+	// Results of completed work
+	Results []*wire.Result `json:"results,omitempty"`
+	// Tasks generated, e.g. by mangling or redirects
+	Tasks []*wire.Task `json:"tasks,omitempty"`
+	// Tasks discovered by spidering, which are checked against the spider
+	// policy rather than the scope
+	Spidered []*wire.Task `json:"spidered,omitempty"`
+}
+
+// SyncResponse is the coordinator's reply to an agent.
+type SyncResponse struct {
+	Leases []*Lease `json:"leases,omitempty"`
+	// The scan is finished and the agent should exit
+	Done bool `json:"done,omitempty"`
+}
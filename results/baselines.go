@@ -0,0 +1,82 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"github.com/Matir/webborer/logging"
+)
+
+// Baselines tracks calibration samples, and the baseline built from them, for
+// each directory.
+type Baselines struct {
+	baselines map[string]*BaselineResult
+	// Most recent calibration samples, by baseline key
+	samples map[string][]Result
+	// Number of samples to keep per baseline
+	sampleSize int
+}
+
+func NewBaselines() *Baselines {
+	return &Baselines{
+		baselines:  make(map[string]*BaselineResult),
+		samples:    make(map[string][]Result),
+		sampleSize: defaultBaselineSamples,
+	}
+}
+
+// Set the number of calibration samples that make up a baseline.
+func (b *Baselines) SetSampleSize(n int) {
+	if n > 0 {
+		b.sampleSize = n
+	}
+}
+
+// Set the baseline stored under key.
+func (b *Baselines) Set(key string, baseline *BaselineResult) {
+	b.baselines[key] = baseline
+}
+
+// Add a calibration sample and rebuild the baseline for its directory.  Only
+// the most recent samples are kept so that refreshed calibrations replace
+// stale ones.
+func (b *Baselines) AddSample(result *Result) {
+	key := BaselineKey(result.URL)
+	samples := append(b.samples[key], *result)
+	if len(samples) > b.sampleSize {
+		samples = samples[len(samples)-b.sampleSize:]
+	}
+	b.samples[key] = samples
+	if baseline, err := NewBaselineResult(samples...); err == nil {
+		logging.Debugf("Updated baseline for %s from %d samples.", key, len(samples))
+		b.baselines[key] = baseline
+	}
+}
+
+// Find the baseline for the deepest calibrated directory containing result.
+func (b *Baselines) For(result *Result) *BaselineResult {
+	if baseline, ok := b.baselines[result.ResultGroup]; ok {
+		return baseline
+	}
+	u := BaselineDir(result.URL)
+	for {
+		if baseline, ok := b.baselines[baselineKeyForDir(u)]; ok {
+			return baseline
+		}
+		if u.Path == "/" || u.Path == "" {
+			return nil
+		}
+		u = BaselineDir(u)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/Matir/webborer/logging"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// BodyStore saves response bodies in a directory named by their SHA-256
+// hash, so thousands of identical error pages are only saved once.  Bodies
+// are spread over subdirectories named by the first two characters of the
+// hash, as in ab/abcdef....  Bodies already in the directory, such as from
+// an earlier scan, aren't saved again.
+type BodyStore struct {
+	dir  string
+	keep bool
+	// Hashes of the bodies known to be saved
+	saved map[string]bool
+	// Number of results referring to a saved body
+	count int
+}
+
+// Create a BodyStore that saves to dir.
+func NewBodyStore(dir string) (*BodyStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &BodyStore{dir: dir, saved: make(map[string]bool)}, nil
+}
+
+// Leave the exchanges on results after saving their bodies, for later stages
+// that also need them.
+func (s *BodyStore) KeepExchanges() {
+	s.keep = true
+}
+
+func (s *BodyStore) Process(in <-chan *Result) <-chan *Result {
+	out := make(chan *Result, cap(in))
+	go func() {
+		defer close(out)
+		for r := range in {
+			if r.Exchange != nil {
+				s.Store(r)
+				// Don't hold bodies in memory for the output
+				if !s.keep {
+					r.Exchange = nil
+				}
+			}
+			out <- r
+		}
+		if s.count > 0 {
+			logging.Logf(logging.LogInfo, "Saved %d distinct bodies for %d results in %s.", len(s.saved), s.count, s.dir)
+		}
+	}()
+	return out
+}
+
+// Save the body of a single result, if it has one, and refer to it from the
+// result.
+func (s *BodyStore) Store(r *Result) {
+	if len(r.Exchange.Body) == 0 {
+		return
+	}
+	sum := sha256.Sum256(r.Exchange.Body)
+	hash := hex.EncodeToString(sum[:])
+	path := filepath.Join(s.dir, hash[:2], hash)
+	if !s.saved[hash] {
+		if err := s.save(path, r.Exchange.Body); err != nil {
+			logging.Logf(logging.LogWarning, "Unable to save body of %s: %s", r.URL.String(), err.Error())
+			return
+		}
+		s.saved[hash] = true
+	}
+	s.count++
+	r.BodyFile = path
+}
+
+// Save body at path, unless it's already there.  The body is written to a
+// temporary file first so a partial body is never left under its hash.
+func (s *BodyStore) save(path string, body []byte) error {
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	fp, err := ioutil.TempFile(dir, ".body")
+	if err != nil {
+		return err
+	}
+	if _, err := fp.Write(body); err != nil {
+		fp.Close()
+		os.Remove(fp.Name())
+		return err
+	}
+	if err := fp.Close(); err != nil {
+		os.Remove(fp.Name())
+		return err
+	}
+	return os.Rename(fp.Name(), path)
+}
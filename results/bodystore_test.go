@@ -0,0 +1,69 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBodyStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bodies")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewBodyStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := recordedResult("/c", 200)
+	other.Exchange.Body = []byte("other")
+	empty := recordedResult("/d", 200)
+	empty.Exchange.Body = nil
+	in := make(chan *Result, 4)
+	in <- recordedResult("/a", 404)
+	in <- recordedResult("/b", 404)
+	in <- other
+	in <- empty
+	close(in)
+	var out []*Result
+	for r := range store.Process(in) {
+		out = append(out, r)
+	}
+	if len(out) != 4 || out[0].Exchange != nil {
+		t.Fatalf("Expected results passed on without exchanges, got %v", out)
+	}
+	// sha256("hello")
+	expected := filepath.Join(dir, "2c", "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824")
+	if out[0].BodyFile != expected || out[1].BodyFile != expected {
+		t.Errorf("Expected both bodies in %s, got %s and %s", expected, out[0].BodyFile, out[1].BodyFile)
+	}
+	if out[2].BodyFile == expected || out[2].BodyFile == "" {
+		t.Errorf("Expected a different body file, got %s", out[2].BodyFile)
+	}
+	if out[3].BodyFile != "" {
+		t.Errorf("Expected no file for an empty body, got %s", out[3].BodyFile)
+	}
+	if buf, err := ioutil.ReadFile(expected); err != nil || string(buf) != "hello" {
+		t.Errorf("Expected the body saved, got %q (%v)", buf, err)
+	}
+	entries, err := ioutil.ReadDir(filepath.Join(dir, "2c"))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("Expected only the body in its directory, got %v (%v)", entries, err)
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+)
+
+// Kinds of change found by comparing against a previous scan.
+const (
+	ChangeNew     = "new"
+	ChangeChanged = "changed"
+	ChangeRemoved = "removed"
+)
+
+// Comparison passes on only the results that differ from a previous scan:
+// new results, results whose code or length changed, and, once the scan is
+// done, results from the previous scan that were not found again.
+type Comparison struct {
+	previous map[string]*JSONResult
+	seen     map[string]bool
+	// Status codes the scan reports
+	report ReportCodes
+}
+
+func (c *Comparison) SetReportCodes(codes ReportCodes) {
+	c.report = codes
+}
+
+// Load a previous scan written with the json output format.
+func LoadComparisonFile(filename string) (*Comparison, error) {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	return ParseComparison(fp)
+}
+
+// Parse a previous scan from a stream of JSON results.
+func ParseComparison(rdr io.Reader) (*Comparison, error) {
+	c := &Comparison{
+		previous: make(map[string]*JSONResult),
+		seen:     make(map[string]bool),
+	}
+	dec := json.NewDecoder(rdr)
+	for i := 1; ; i++ {
+		line := &struct {
+			JSONResult
+			jsonFindingRecord
+		}{}
+		if err := dec.Decode(line); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("Invalid result %d: %s", i, err.Error())
+		}
+		if line.Finding != nil {
+			// Findings follow the results
+			continue
+		}
+		rec := &line.JSONResult
+		if rec.URL == "" {
+			return nil, fmt.Errorf("Result %d has no URL.", i)
+		}
+		c.previous[compareKey(rec.URL, rec.Host)] = rec
+	}
+	return c, nil
+}
+
+// Get the result for u and host in the previous scan, or nil if there wasn't
+// one.  Safe to call from many goroutines.
+func (c *Comparison) Previous(u *url.URL, host string) *JSONResult {
+	return c.previous[compareKey(u.String(), host)]
+}
+
+func compareKey(u, host string) string {
+	if host == "" {
+		return u
+	}
+	return u + " " + host
+}
+
+func (c *Comparison) Process(in <-chan *Result) <-chan *Result {
+	out := make(chan *Result, cap(in))
+	go func() {
+		defer close(out)
+		for r := range in {
+			if !c.report.Result(r) || r.URL == nil {
+				out <- r
+				continue
+			}
+			key := compareKey(r.URL.String(), r.Host)
+			c.seen[key] = true
+			if c.compare(r, c.previous[key]) {
+				out <- r
+			}
+		}
+		for _, r := range c.removed() {
+			out <- r
+		}
+	}()
+	return out
+}
+
+// Mark how r differs from its previous result.  Returns false if it is the
+// same.
+func (c *Comparison) compare(r *Result, prev *JSONResult) bool {
+	if prev == nil {
+		r.Change = ChangeNew
+		r.AddNote("%s", ChangeNew)
+		return true
+	}
+	if prev.Code != r.Code {
+		r.Change = ChangeChanged
+		r.AddNote("%s: code %d -> %d", ChangeChanged, prev.Code, r.Code)
+		return true
+	}
+	if prev.Length >= 0 && r.Length >= 0 && prev.Length != r.Length {
+		r.Change = ChangeChanged
+		r.AddNote("%s: length %d -> %d", ChangeChanged, prev.Length, r.Length)
+		return true
+	}
+	return false
+}
+
+// Build results for the previous results that were not seen, in URL order.
+func (c *Comparison) removed() []*Result {
+	var keys []string
+	for key := range c.previous {
+		if !c.seen[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	removed := make([]*Result, 0, len(keys))
+	for _, key := range keys {
+		prev := c.previous[key]
+		u, err := url.Parse(prev.URL)
+		if err != nil {
+			continue
+		}
+		r := NewResult(u, prev.Host)
+		r.Code = prev.Code
+		r.Length = prev.Length
+		r.ContentType = prev.ContentType
+		if prev.Redirect != "" {
+			r.Redir, _ = url.Parse(prev.Redirect)
+		}
+		r.Change = ChangeRemoved
+		r.AddNote("%s", ChangeRemoved)
+		removed = append(removed, r)
+	}
+	return removed
+}
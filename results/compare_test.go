@@ -0,0 +1,111 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const testPrevious = `{"url":"http://localhost/same","code":200,"length":10}
+{"url":"http://localhost/code","code":200,"length":10}
+{"url":"http://localhost/length","code":200,"length":10}
+{"url":"http://localhost/gone","code":403,"length":5}
+{"url":"http://localhost/missing","code":200,"length":-1}
+`
+
+func compareResult(path string, code int, length int64) *Result {
+	return &Result{
+		URL:    &url.URL{Scheme: "http", Host: "localhost", Path: path},
+		Code:   code,
+		Length: length,
+	}
+}
+
+func TestComparison(t *testing.T) {
+	c, err := ParseComparison(strings.NewReader(testPrevious))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	in := make(chan *Result, 10)
+	in <- compareResult("/same", 200, 10)
+	in <- compareResult("/code", 401, 10)
+	in <- compareResult("/length", 200, 20)
+	in <- compareResult("/gone", 404, 0)
+	in <- compareResult("/added", 200, 1)
+	errResult := compareResult("/missing", 0, 0)
+	errResult.Error = errors.New("timeout")
+	in <- errResult
+	close(in)
+
+	changes := make(map[string]string)
+	for r := range c.Process(in) {
+		if (ReportCodes{}).Result(r) {
+			changes[r.URL.Path] = r.Change
+		}
+	}
+	expected := map[string]string{
+		"/code":    ChangeChanged,
+		"/length":  ChangeChanged,
+		"/gone":    ChangeRemoved,
+		"/missing": ChangeRemoved,
+		"/added":   ChangeNew,
+	}
+	if len(changes) != len(expected) {
+		t.Errorf("Expected %d results, got %v", len(expected), changes)
+	}
+	for path, change := range expected {
+		if changes[path] != change {
+			t.Errorf("%s: expected %q, got %q", path, change, changes[path])
+		}
+	}
+}
+
+func TestParseComparison_Invalid(t *testing.T) {
+	for _, bad := range []string{"{", `{"code":200}`, "not json"} {
+		if _, err := ParseComparison(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected error for %q.", bad)
+		}
+	}
+}
+
+func TestJSONResultsManager_RoundTrip(t *testing.T) {
+	buf := &strings.Builder{}
+	rm := NewJSONResultsManager(buf)
+	res := make(chan *Result)
+	rm.Run(res)
+	found := compareResult("/found", 200, 42)
+	found.AddFinding("test", SeverityLow, "finding")
+	res <- found
+	res <- compareResult("/notfound", 404, 0)
+	close(res)
+	rm.Wait()
+	if strings.Count(buf.String(), "\n") != 2 || !strings.Contains(buf.String(), `{"finding":{"rule":"test"`) {
+		t.Fatalf("Expected a single result and finding, got %q", buf.String())
+	}
+	c, err := ParseComparison(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(c.previous) != 1 {
+		t.Errorf("Expected findings to be skipped, got %d results", len(c.previous))
+	}
+	prev := c.previous["http://localhost/found"]
+	if prev == nil || prev.Code != 200 || prev.Length != 42 {
+		t.Errorf("Unexpected record: %+v", prev)
+	}
+}
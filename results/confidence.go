@@ -0,0 +1,163 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// How well a result has been confirmed
+type Confidence int
+
+const (
+	// No response to judge
+	ConfidenceUnknown = Confidence(iota)
+	// The content contradicts the URL, e.g. an HTML page for a .zip
+	ConfidenceContradicted
+	// Only the status code supports the result
+	ConfidenceStatus
+	// The content confirms the result: it differs from the directory's
+	// not-found baseline, or its type matches the URL
+	ConfidenceContent
+	// The body starts with the signature of the file type in the URL
+	ConfidenceVerified
+	confidenceMax
+)
+
+var confidenceStrings = [...]string{
+	"",
+	"contradicted",
+	"status",
+	"content",
+	"verified",
+}
+
+func (c Confidence) String() string {
+	if c < 0 || c >= confidenceMax {
+		return fmt.Sprintf("Confidence(%d)", int(c))
+	}
+	return confidenceStrings[c]
+}
+
+// Bytes of a body needed to judge its content
+const ConfidenceSampleSize = 512
+
+// Signatures of binary file types, by extension
+var magicSignatures = map[string][]string{
+	"7z":     {"7z\xbc\xaf\x27\x1c"},
+	"bz2":    {"BZh"},
+	"class":  {"\xca\xfe\xba\xbe"},
+	"db":     {"SQLite format 3\x00"},
+	"dll":    {"MZ"},
+	"docx":   {"PK\x03\x04"},
+	"exe":    {"MZ"},
+	"gif":    {"GIF87a", "GIF89a"},
+	"gz":     {"\x1f\x8b"},
+	"jar":    {"PK\x03\x04"},
+	"jpeg":   {"\xff\xd8\xff"},
+	"jpg":    {"\xff\xd8\xff"},
+	"pdf":    {"%PDF-"},
+	"png":    {"\x89PNG\r\n\x1a\n"},
+	"rar":    {"Rar!\x1a\x07"},
+	"sqlite": {"SQLite format 3\x00"},
+	"tgz":    {"\x1f\x8b"},
+	"war":    {"PK\x03\x04"},
+	"xlsx":   {"PK\x03\x04"},
+	"xz":     {"\xfd7zXZ\x00"},
+	"zip":    {"PK\x03\x04", "PK\x05\x06"},
+}
+
+// Types text files should be sniffed as, by extension.  A text file sniffed
+// as HTML is usually an error page.
+var sniffedTypes = map[string]string{
+	"conf": "text/plain",
+	"css":  "text/plain",
+	"env":  "text/plain",
+	"htm":  "text/html",
+	"html": "text/html",
+	"ini":  "text/plain",
+	"js":   "text/plain",
+	"json": "text/plain",
+	"log":  "text/plain",
+	"sql":  "text/plain",
+	"txt":  "text/plain",
+	"xml":  "text/xml",
+	"yaml": "text/plain",
+	"yml":  "text/plain",
+}
+
+// Judge how well the start of a body confirms a result for u.  If the content
+// contradicts the URL, a note explaining why is returned.
+func ContentConfidence(u *url.URL, sample []byte) (Confidence, string) {
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(u.Path), "."))
+	if sigs, ok := magicSignatures[ext]; ok {
+		for _, sig := range sigs {
+			if bytes.HasPrefix(sample, []byte(sig)) {
+				return ConfidenceVerified, ""
+			}
+		}
+		return ConfidenceContradicted, fmt.Sprintf("content is not a .%s file", ext)
+	}
+	if want, ok := sniffedTypes[ext]; ok && len(sample) > 0 {
+		got := http.DetectContentType(sample)
+		if strings.HasPrefix(got, want) {
+			return ConfidenceContent, ""
+		}
+		return ConfidenceContradicted, fmt.Sprintf("content looks like %s, not .%s", strings.SplitN(got, ";", 2)[0], ext)
+	}
+	return ConfidenceStatus, ""
+}
+
+// ConfidenceScorer raises the confidence of results that differ from their
+// directory's calibration baseline.
+type ConfidenceScorer struct {
+	baselines *Baselines
+}
+
+func NewConfidenceScorer(samples int) *ConfidenceScorer {
+	baselines := NewBaselines()
+	baselines.SetSampleSize(samples)
+	return &ConfidenceScorer{baselines: baselines}
+}
+
+func (s *ConfidenceScorer) Process(in <-chan *Result) <-chan *Result {
+	out := make(chan *Result, cap(in))
+	go func() {
+		defer close(out)
+		for r := range in {
+			s.score(r)
+			out <- r
+		}
+	}()
+	return out
+}
+
+func (s *ConfidenceScorer) score(r *Result) {
+	if r.Baseline {
+		s.baselines.AddSample(r)
+		return
+	}
+	if r.Confidence != ConfidenceStatus {
+		return
+	}
+	if baseline := s.baselines.For(r); baseline != nil && !baseline.Matches(r) {
+		r.Confidence = ConfidenceContent
+	}
+}
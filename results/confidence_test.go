@@ -0,0 +1,87 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestConfidenceStrings(t *testing.T) {
+	if len(confidenceStrings) != int(confidenceMax) {
+		t.Errorf("confidenceStrings != enum: %d vs %d", len(confidenceStrings), confidenceMax)
+	}
+	if ConfidenceVerified.String() != "verified" {
+		t.Errorf("Unexpected string: %s", ConfidenceVerified)
+	}
+}
+
+func TestContentConfidence(t *testing.T) {
+	cases := []struct {
+		path       string
+		sample     string
+		confidence Confidence
+		note       bool
+	}{
+		{"/a.pdf", "%PDF-1.4", ConfidenceVerified, false},
+		{"/a.PNG", "\x89PNG\r\n\x1a\nIHDR", ConfidenceVerified, false},
+		{"/a.gz", "<!DOCTYPE html>", ConfidenceContradicted, true},
+		{"/config.json", "{\"a\": 1}", ConfidenceContent, false},
+		{"/config.json", "<html><body>Not found</body></html>", ConfidenceContradicted, true},
+		{"/index.html", "<html></html>", ConfidenceContent, false},
+		{"/admin", "<html></html>", ConfidenceStatus, false},
+		{"/empty.txt", "", ConfidenceStatus, false},
+	}
+	for _, c := range cases {
+		u := &url.URL{Scheme: "http", Host: "localhost", Path: c.path}
+		confidence, note := ContentConfidence(u, []byte(c.sample))
+		if confidence != c.confidence {
+			t.Errorf("%s: expected %s, got %s", c.path, c.confidence, confidence)
+		}
+		if (note != "") != c.note {
+			t.Errorf("%s: unexpected note %q", c.path, note)
+		}
+	}
+}
+
+func TestConfidenceScorer(t *testing.T) {
+	in := make(chan *Result, 4)
+	for i := 0; i < 2; i++ {
+		baseline := NewResult(&url.URL{Scheme: "http", Host: "localhost", Path: "/dir/random" + string(rune('a'+i))}, "")
+		baseline.Code = 200
+		baseline.Length = 100
+		baseline.Baseline = true
+		in <- baseline
+	}
+	same := NewResult(&url.URL{Scheme: "http", Host: "localhost", Path: "/dir/same"}, "")
+	same.Code = 200
+	same.Length = 100
+	same.Confidence = ConfidenceStatus
+	in <- same
+	different := NewResult(&url.URL{Scheme: "http", Host: "localhost", Path: "/dir/different"}, "")
+	different.Code = 200
+	different.Length = 5000
+	different.Confidence = ConfidenceStatus
+	in <- different
+	close(in)
+	for range NewConfidenceScorer(2).Process(in) {
+	}
+	if same.Confidence != ConfidenceStatus {
+		t.Errorf("Expected result matching baseline to stay at status, got %s", same.Confidence)
+	}
+	if different.Confidence != ConfidenceContent {
+		t.Errorf("Expected result differing from baseline to be content, got %s", different.Confidence)
+	}
+}
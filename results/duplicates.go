@@ -0,0 +1,119 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"github.com/Matir/webborer/util"
+)
+
+// DuplicateGrouper groups results whose bodies share a hash, reporting each
+// group once with a count.  Results without a body hash are passed through
+// immediately; hashed results are held until the input is finished.
+type DuplicateGrouper struct {
+	// Minimum number of identical bodies to group
+	threshold int
+	// Maximum fuzzy hash distance to consider bodies the same, or -1 to only
+	// group identical bodies
+	distance int
+	groups   []*duplicateGroup
+	byHash   map[string]*duplicateGroup
+	// Status codes the scan reports
+	report ReportCodes
+}
+
+func (g *DuplicateGrouper) SetReportCodes(codes ReportCodes) {
+	g.report = codes
+}
+
+type duplicateGroup struct {
+	hash      string
+	fuzzyHash uint64
+	results   []*Result
+	count     int
+}
+
+func NewDuplicateGrouper(threshold, distance int) *DuplicateGrouper {
+	return &DuplicateGrouper{
+		threshold: threshold,
+		distance:  distance,
+		byHash:    make(map[string]*duplicateGroup),
+	}
+}
+
+func (g *DuplicateGrouper) Process(in <-chan *Result) <-chan *Result {
+	out := make(chan *Result, cap(in))
+	go func() {
+		defer close(out)
+		for r := range in {
+			if r.BodyHash == "" || !g.report.Result(r) {
+				out <- r
+				continue
+			}
+			g.add(r)
+		}
+		g.flush(out)
+	}()
+	return out
+}
+
+func (g *DuplicateGrouper) add(r *Result) {
+	group := g.find(r)
+	if group == nil {
+		group = &duplicateGroup{hash: r.BodyHash, fuzzyHash: r.FuzzyHash}
+		g.groups = append(g.groups, group)
+	}
+	g.byHash[r.BodyHash] = group
+	group.count++
+	if group.count < g.threshold || len(group.results) == 0 {
+		group.results = append(group.results, r)
+	} else {
+		group.results = group.results[:1]
+	}
+}
+
+// Find the group a result belongs in, or nil if it is the first of its kind.
+func (g *DuplicateGrouper) find(r *Result) *duplicateGroup {
+	if group, ok := g.byHash[r.BodyHash]; ok {
+		return group
+	}
+	if g.distance < 0 {
+		return nil
+	}
+	for _, group := range g.groups {
+		if util.FuzzyDistance(group.fuzzyHash, r.FuzzyHash) <= g.distance {
+			return group
+		}
+	}
+	return nil
+}
+
+func (g *DuplicateGrouper) flush(out chan<- *Result) {
+	for _, group := range g.groups {
+		if group.count < g.threshold {
+			for _, r := range group.results {
+				out <- r
+			}
+			continue
+		}
+		r := group.results[0]
+		r.GroupCount = group.count
+		if g.distance < 0 {
+			r.AddNote("%d responses with identical content", group.count)
+		} else {
+			r.AddNote("%d responses with similar content", group.count)
+		}
+		out <- r
+	}
+}
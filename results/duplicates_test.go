@@ -0,0 +1,79 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+)
+
+func hashedResult(path, hash string, fuzzy uint64) *Result {
+	return &Result{
+		URL:       &url.URL{Scheme: "http", Host: "localhost", Path: path},
+		Code:      200,
+		BodyHash:  hash,
+		FuzzyHash: fuzzy,
+	}
+}
+
+func TestDuplicateGrouper(t *testing.T) {
+	in := make(chan *Result, 20)
+	for i := 0; i < 10; i++ {
+		in <- hashedResult(fmt.Sprintf("/%d", i), "aaaa", 0)
+	}
+	in <- hashedResult("/b1", "bbbb", 0xff)
+	in <- hashedResult("/b2", "bbbb", 0xff)
+	in <- hashedResult("/nohash", "", 0)
+	close(in)
+	out := make([]*Result, 0)
+	for r := range NewDuplicateGrouper(3, -1).Process(in) {
+		out = append(out, r)
+	}
+	if len(out) != 4 {
+		t.Fatalf("Expected 4 results, got %d", len(out))
+	}
+	if out[0].URL.Path != "/nohash" {
+		t.Errorf("Expected unhashed result first, got %s", out[0].URL.Path)
+	}
+	if out[1].URL.Path != "/0" || out[1].GroupCount != 10 || len(out[1].Notes) != 1 {
+		t.Errorf("Expected grouped result, got %s (%d)", out[1].URL.Path, out[1].GroupCount)
+	}
+	if out[2].URL.Path != "/b1" || out[3].URL.Path != "/b2" || out[2].GroupCount != 0 {
+		t.Errorf("Expected ungrouped results below threshold.")
+	}
+}
+
+func TestDuplicateGrouper_Fuzzy(t *testing.T) {
+	in := make(chan *Result, 10)
+	in <- hashedResult("/a", "a", 0xf0)
+	in <- hashedResult("/b", "b", 0xf1)
+	in <- hashedResult("/c", "c", 0xf3)
+	in <- hashedResult("/d", "d", 0x0f)
+	close(in)
+	out := make([]*Result, 0)
+	for r := range NewDuplicateGrouper(2, 2).Process(in) {
+		out = append(out, r)
+	}
+	if len(out) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(out))
+	}
+	if out[0].GroupCount != 3 {
+		t.Errorf("Expected 3 similar results, got %d", out[0].GroupCount)
+	}
+	if out[1].URL.Path != "/d" {
+		t.Errorf("Expected /d to be separate, got %s", out[1].URL.Path)
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"fmt"
+	"github.com/Matir/webborer/logging"
+	ss "github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/util"
+	"regexp"
+)
+
+// Bytes of each body kept for filters that match against it
+const FilterSampleSize = 64 * 1024
+
+// A ResultFilter decides which results are reported.  Filters only see
+// results that would otherwise be reported, one at a time.
+type ResultFilter interface {
+	// Whether to keep a result
+	Keep(*Result) bool
+}
+
+// FilterChain is a result stage dropping reported results that any of its
+// filters reject.  Filters are applied in the order they were added, and a
+// chain is itself a ResultFilter.
+type FilterChain struct {
+	filters []ResultFilter
+	// Status codes the scan reports
+	report ReportCodes
+}
+
+func (c *FilterChain) SetReportCodes(codes ReportCodes) {
+	c.report = codes
+}
+
+func NewFilterChain(filters ...ResultFilter) *FilterChain {
+	return &FilterChain{filters: filters}
+}
+
+func (c *FilterChain) Add(f ResultFilter) {
+	c.filters = append(c.filters, f)
+}
+
+// Number of filters in the chain
+func (c *FilterChain) Len() int {
+	return len(c.filters)
+}
+
+func (c *FilterChain) Keep(r *Result) bool {
+	for _, f := range c.filters {
+		if !f.Keep(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *FilterChain) Process(in <-chan *Result) <-chan *Result {
+	out := make(chan *Result, cap(in))
+	go func() {
+		defer close(out)
+		for r := range in {
+			if c.report.Result(r) && !c.Keep(r) {
+				logging.Logf(logging.LogDebug, "Filtered result %s", r.String())
+				continue
+			}
+			// The sample is only needed by filters
+			r.BodySample = nil
+			out <- r
+		}
+	}()
+	return out
+}
+
+// Build the chain of filters given in the settings.
+func NewFilterChainFromSettings(settings *ss.ScanSettings) (*FilterChain, error) {
+	chain := NewFilterChain()
+	if len(settings.FilterCodes) > 0 {
+		chain.Add(CodeFilter(settings.FilterCodes))
+	}
+	if len(settings.FilterLengths) > 0 {
+		chain.Add(LengthFilter(settings.FilterLengths))
+	}
+	if settings.FilterRegex != "" {
+		f, err := NewRegexFilter(settings.FilterRegex)
+		if err != nil {
+			return nil, err
+		}
+		chain.Add(f)
+	}
+	if settings.Dedupe {
+		chain.Add(NewDedupeFilter())
+	}
+	if settings.FilterSimilar > 0 {
+		chain.Add(NewSimilarityFilter(settings.FilterSimilar, settings.FuzzyDistance))
+	}
+	return chain, nil
+}
+
+// CodeFilter drops results with the given status codes.
+type CodeFilter ss.CodeRangeFlag
+
+func (f CodeFilter) Keep(r *Result) bool {
+	return !ss.CodeRangeFlag(f).Contains(r.Code)
+}
+
+// LengthFilter drops results with the given content lengths.
+type LengthFilter ss.CodeRangeFlag
+
+func (f LengthFilter) Keep(r *Result) bool {
+	return r.Length < 0 || !ss.CodeRangeFlag(f).Contains(int(r.Length))
+}
+
+// RegexFilter drops results whose body sample matches a regular expression.
+type RegexFilter struct {
+	re *regexp.Regexp
+}
+
+func NewRegexFilter(pattern string) (*RegexFilter, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid filter regex %q: %s", pattern, err.Error())
+	}
+	return &RegexFilter{re: re}, nil
+}
+
+func (f *RegexFilter) Keep(r *Result) bool {
+	return !f.re.Match(r.BodySample)
+}
+
+// DedupeFilter drops results for a URL, Host and status code already kept.
+type DedupeFilter struct {
+	seen map[string]bool
+}
+
+func NewDedupeFilter() *DedupeFilter {
+	return &DedupeFilter{seen: make(map[string]bool)}
+}
+
+func (f *DedupeFilter) Keep(r *Result) bool {
+	if r.URL == nil {
+		return true
+	}
+	key := fmt.Sprintf("%s %s %d", r.URL.String(), r.Host, r.Code)
+	if f.seen[key] {
+		return false
+	}
+	f.seen[key] = true
+	return true
+}
+
+// SimilarityFilter keeps only the first results with each body, dropping
+// the rest.  Unlike grouping, results are not held until the scan finishes.
+type SimilarityFilter struct {
+	// Results with similar bodies to keep
+	limit int
+	// Maximum fuzzy hash distance for bodies to be similar, or -1 for
+	// identical bodies only
+	distance int
+	clusters []*similarCluster
+}
+
+type similarCluster struct {
+	hash      string
+	fuzzyHash uint64
+	count     int
+}
+
+func NewSimilarityFilter(limit, distance int) *SimilarityFilter {
+	return &SimilarityFilter{limit: limit, distance: distance}
+}
+
+func (f *SimilarityFilter) Keep(r *Result) bool {
+	if r.BodyHash == "" {
+		return true
+	}
+	cluster := f.find(r)
+	if cluster == nil {
+		cluster = &similarCluster{hash: r.BodyHash, fuzzyHash: r.FuzzyHash}
+		f.clusters = append(f.clusters, cluster)
+	}
+	cluster.count++
+	return cluster.count <= f.limit
+}
+
+func (f *SimilarityFilter) find(r *Result) *similarCluster {
+	for _, cluster := range f.clusters {
+		if cluster.hash == r.BodyHash {
+			return cluster
+		}
+		if f.distance >= 0 && util.FuzzyDistance(cluster.fuzzyHash, r.FuzzyHash) <= f.distance {
+			return cluster
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"fmt"
+	ss "github.com/Matir/webborer/settings"
+	"net/url"
+	"testing"
+)
+
+func TestFilterChain(t *testing.T) {
+	settings := ss.DefaultScanSettings()
+	settings.FilterCodes.Set("401")
+	settings.FilterLengths.Set("0,100-200")
+	settings.FilterRegex = "(?i)not found"
+	settings.Dedupe = true
+	chain, err := NewFilterChainFromSettings(settings)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if chain.Len() != 4 {
+		t.Fatalf("Expected 4 filters, got %d", chain.Len())
+	}
+	newResult := func(path string, code int, length int64, body string) *Result {
+		r := NewResult(&url.URL{Scheme: "http", Host: "localhost", Path: path}, "")
+		r.Code = code
+		r.Length = length
+		r.BodySample = []byte(body)
+		return r
+	}
+	in := make(chan *Result, 10)
+	in <- newResult("/keep", 200, 50, "hello")
+	in <- newResult("/denied", 401, 50, "")
+	in <- newResult("/empty", 200, 0, "")
+	in <- newResult("/sized", 200, 150, "")
+	in <- newResult("/soft404", 200, -1, "<h1>Page Not Found</h1>")
+	in <- newResult("/keep", 200, 50, "hello")
+	in <- newResult("/missing", 404, 0, "")
+	close(in)
+	var paths []string
+	for r := range chain.Process(in) {
+		paths = append(paths, r.URL.Path)
+		if r.BodySample != nil {
+			t.Errorf("Expected body sample to be released for %s", r.URL.Path)
+		}
+	}
+	if len(paths) != 2 || paths[0] != "/keep" || paths[1] != "/missing" {
+		t.Errorf("Unexpected results: %v", paths)
+	}
+}
+
+func TestFilterChain_InvalidRegex(t *testing.T) {
+	settings := ss.DefaultScanSettings()
+	settings.FilterRegex = "("
+	if _, err := NewFilterChainFromSettings(settings); err == nil {
+		t.Error("Expected error for invalid regex.")
+	}
+}
+
+func TestSimilarityFilter(t *testing.T) {
+	f := NewSimilarityFilter(2, 2)
+	kept := 0
+	for i := 0; i < 5; i++ {
+		if f.Keep(hashedResult(fmt.Sprintf("/%d", i), fmt.Sprintf("hash%d", i), 0xf0|uint64(i&1))) {
+			kept++
+		}
+	}
+	if kept != 2 {
+		t.Errorf("Expected 2 similar results kept, got %d", kept)
+	}
+	if !f.Keep(hashedResult("/other", "other", 0xffff0000)) {
+		t.Error("Expected a different body to be kept.")
+	}
+	if !f.Keep(hashedResult("/nohash", "", 0)) {
+		t.Error("Expected unhashed result to be kept.")
+	}
+	exact := NewSimilarityFilter(1, -1)
+	if !exact.Keep(hashedResult("/a", "aaaa", 0)) || !exact.Keep(hashedResult("/b", "bbbb", 0)) {
+		t.Error("Expected different bodies to be kept without fuzzy matching.")
+	}
+	if exact.Keep(hashedResult("/c", "aaaa", 0)) {
+		t.Error("Expected identical body to be dropped.")
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+)
+
+// Severity of a finding, in increasing order.
+type Severity int
+
+const (
+	SeverityInfo = Severity(iota)
+	SeverityLow
+	SeverityMedium
+	SeverityHigh
+	severityMax
+)
+
+var severityStrings = [...]string{
+	"info",
+	"low",
+	"medium",
+	"high",
+}
+
+func (s Severity) String() string {
+	if s < 0 || s >= severityMax {
+		return "unknown"
+	}
+	return severityStrings[s]
+}
+
+// Parse the name of a severity.
+func ParseSeverity(name string) (Severity, error) {
+	for i, s := range severityStrings {
+		if s == name {
+			return Severity(i), nil
+		}
+	}
+	return SeverityInfo, fmt.Errorf("Invalid severity %q, must be one of %v", name, severityStrings)
+}
+
+// Categories of analysis that produce findings
+const (
+	CategoryFingerprint   = "fingerprint"
+	CategorySensitiveFile = "sensitive-file"
+	CategoryHeaders       = "headers"
+	CategoryTiming        = "timing"
+)
+
+// A Finding is something of interest noticed about a result by analysis,
+// beyond the mere existence of the resource.
+type Finding struct {
+	// Kind of analysis that produced the finding
+	Category string
+	// Name of the rule that produced the finding
+	Rule string
+	// How serious the finding is
+	Severity Severity
+	// Human-readable description
+	Message string
+	// What was seen in the response to support the finding
+	Evidence string
+	// URL the finding is about
+	URL *url.URL `json:"-"`
+}
+
+// Create a finding in the given category.
+func NewFinding(category, rule string, severity Severity, format string, args ...interface{}) *Finding {
+	return &Finding{
+		Category: category,
+		Rule:     rule,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+func (f *Finding) String() string {
+	return fmt.Sprintf("%s: %s", f.Severity.String(), f.Message)
+}
+
+// Format a finding as a line of the findings section of text output.
+func (f *Finding) Line() string {
+	name := f.Rule
+	if f.Category != "" {
+		name = f.Category + "/" + f.Rule
+	}
+	line := fmt.Sprintf("[%s] %s %s: %s", f.Severity.String(), name, maybeStringURL(f.URL), f.Message)
+	if f.Evidence != "" {
+		line += fmt.Sprintf(" (%s)", f.Evidence)
+	}
+	return line
+}
+
+// Attach a finding to this result.  The finding is about the result's URL
+// unless it names another.
+func (r *Result) Report(f *Finding) {
+	if f.URL == nil {
+		f.URL = r.URL
+	}
+	r.Findings = append(r.Findings, f)
+}
+
+// Attach a finding with no category or evidence to this result.
+func (r *Result) AddFinding(rule string, severity Severity, format string, args ...interface{}) {
+	r.Report(NewFinding("", rule, severity, format, args...))
+}
+
+// Get the highest severity of all findings, or -1 if there are none.
+func (r *Result) MaxSeverity() Severity {
+	max := Severity(-1)
+	for _, f := range r.Findings {
+		if f.Severity > max {
+			max = f.Severity
+		}
+	}
+	return max
+}
+
+// A findingList gathers the findings of reported results while output is
+// written, so each format can list them in a section of their own.
+type findingList []*Finding
+
+func (l *findingList) add(r *Result, codes ReportCodes) {
+	if codes.Result(r) {
+		*l = append(*l, r.Findings...)
+	}
+}
+
+// Get the findings, most severe first, and by URL within each severity.
+func (l findingList) sorted() []*Finding {
+	sorted := append([]*Finding(nil), l...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Severity != sorted[j].Severity {
+			return sorted[i].Severity > sorted[j].Severity
+		}
+		return maybeStringURL(sorted[i].URL) < maybeStringURL(sorted[j].URL)
+	})
+	return sorted
+}
+
+// Write the findings section of text output, if there are any findings.
+func (l findingList) writeText(w io.Writer) {
+	if len(l) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\nFindings:\n")
+	for _, f := range l.sorted() {
+		fmt.Fprintln(w, f.Line())
+	}
+}
+
+// Column names of the findings section of CSV output
+var findingCSVHeader = []string{"severity", "category", "rule", "url", "message", "evidence"}
+
+func (f *Finding) csvRecord() []string {
+	return []string{f.Severity.String(), f.Category, f.Rule, maybeStringURL(f.URL), f.Message, f.Evidence}
+}
+
+// JSONFinding is the record written for each finding by the JSON and HAR
+// outputs.
+type JSONFinding struct {
+	Category string `json:"category,omitempty"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	URL      string `json:"url"`
+	Message  string `json:"message"`
+	Evidence string `json:"evidence,omitempty"`
+}
+
+func NewJSONFinding(f *Finding) *JSONFinding {
+	return &JSONFinding{
+		Category: f.Category,
+		Rule:     f.Rule,
+		Severity: f.Severity.String(),
+		URL:      maybeStringURL(f.URL),
+		Message:  f.Message,
+		Evidence: f.Evidence,
+	}
+}
+
+func (l findingList) jsonFindings() []*JSONFinding {
+	records := make([]*JSONFinding, 0, len(l))
+	for _, f := range l.sorted() {
+		records = append(records, NewJSONFinding(f))
+	}
+	return records
+}
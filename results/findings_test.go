@@ -0,0 +1,101 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSeverityStrings(t *testing.T) {
+	if len(severityStrings) != int(severityMax) {
+		t.Errorf("Expected %d severity strings, got %d", severityMax, len(severityStrings))
+	}
+	if Severity(42).String() != "unknown" {
+		t.Errorf("Expected unknown severity, got %s", Severity(42).String())
+	}
+}
+
+func TestAddFinding(t *testing.T) {
+	r := &Result{}
+	if r.MaxSeverity() != -1 {
+		t.Errorf("Expected no severity, got %d", r.MaxSeverity())
+	}
+	r.AddFinding("a", SeverityLow, "low %d", 1)
+	r.AddFinding("b", SeverityHigh, "high")
+	r.AddFinding("c", SeverityInfo, "info")
+	if r.MaxSeverity() != SeverityHigh {
+		t.Errorf("Expected high severity, got %s", r.MaxSeverity())
+	}
+	if s := r.Findings[0].String(); s != "low: low 1" {
+		t.Errorf("Unexpected finding string: %s", s)
+	}
+}
+
+func findingResult(path string, code int, findings ...*Finding) *Result {
+	r := &Result{URL: &url.URL{Scheme: "http", Host: "localhost", Path: path}, Code: code}
+	for _, f := range findings {
+		r.Report(f)
+	}
+	return r
+}
+
+func TestPlainResultsManager_Findings(t *testing.T) {
+	buf := &bytes.Buffer{}
+	mgr := &PlainResultsManager{writer: buf}
+	rchan := make(chan *Result)
+	mgr.Run(rchan)
+	low := NewFinding(CategoryHeaders, "missing-security-headers", SeverityLow, "Missing security headers")
+	high := NewFinding(CategorySensitiveFile, "dotenv", SeverityHigh, "Environment file")
+	high.Evidence = `"DB_PASSWORD="`
+	rchan <- findingResult("/", 200, low)
+	rchan <- findingResult("/.env", 200, high)
+	rchan <- findingResult("/missing", 404, NewFinding("", "ignored", SeverityHigh, "Not reported"))
+	close(rchan)
+	mgr.Wait()
+	sections := strings.SplitN(buf.String(), "\nFindings:\n", 2)
+	if len(sections) != 2 {
+		t.Fatalf("Expected a findings section, got %q", buf.String())
+	}
+	if strings.Count(sections[0], "\n") != 2 || strings.Contains(sections[0], "Environment") {
+		t.Errorf("Expected only results before the findings, got %q", sections[0])
+	}
+	expected := `[high] sensitive-file/dotenv http://localhost/.env: Environment file ("DB_PASSWORD=")
+[low] headers/missing-security-headers http://localhost/: Missing security headers
+`
+	if sections[1] != expected {
+		t.Errorf("Expected findings %q, got %q", expected, sections[1])
+	}
+}
+
+func TestCSVResultsManager_Findings(t *testing.T) {
+	buf := &bytes.Buffer{}
+	rm := &CSVResultsManager{writer: csv.NewWriter(buf)}
+	rchan := make(chan *Result)
+	rm.Run(rchan)
+	rchan <- findingResult("/", 200, NewFinding(CategoryTiming, "timing-anomaly", SeverityInfo, "Slow"))
+	close(rchan)
+	rm.Wait()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 || lines[2] != "" || lines[3] != strings.Join(findingCSVHeader, ",") {
+		t.Fatalf("Expected results then findings, got %q", buf.String())
+	}
+	if lines[4] != "info,timing,timing-anomaly,http://localhost/,Slow," {
+		t.Errorf("Unexpected finding record %q", lines[4])
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/Matir/webborer/logging"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layout of the names of history files, which sort by time
+const historyTimeFormat = "20060102T150405Z"
+
+// History keeps the results of each run of a scan in a directory, as a JSON
+// results file named for the time the run started.
+type History struct {
+	dir string
+}
+
+// Open a history directory, creating it if needed.
+func OpenHistory(dir string) (*History, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &History{dir: dir}, nil
+}
+
+// Runs returns the paths of the completed runs, oldest first.
+func (h *History) Runs() ([]string, error) {
+	runs, err := filepath.Glob(filepath.Join(h.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(runs)
+	return runs, nil
+}
+
+// Latest returns the path of the most recent completed run, or "" if there
+// is none.
+func (h *History) Latest() (string, error) {
+	runs, err := h.Runs()
+	if err != nil || len(runs) == 0 {
+		return "", err
+	}
+	return runs[len(runs)-1], nil
+}
+
+// Begin recording a run that started at the given time.  The run only
+// becomes part of the history once its results are complete.
+func (h *History) Begin(start time.Time) (*HistoryRun, error) {
+	path := filepath.Join(h.dir, start.UTC().Format(historyTimeFormat)+".json")
+	fp, err := os.Create(path + ".partial")
+	if err != nil {
+		return nil, err
+	}
+	return &HistoryRun{path: path, fp: fp, enc: json.NewEncoder(fp)}, nil
+}
+
+// HistoryRun is a result stage recording the reported results of one run.
+type HistoryRun struct {
+	path string
+	fp   *os.File
+	enc  *json.Encoder
+	err  error
+	// Status codes the scan reports
+	report ReportCodes
+}
+
+func (run *HistoryRun) SetReportCodes(codes ReportCodes) {
+	run.report = codes
+}
+
+func (run *HistoryRun) Process(in <-chan *Result) <-chan *Result {
+	out := make(chan *Result, cap(in))
+	go func() {
+		defer close(out)
+		for r := range in {
+			run.record(r)
+			out <- r
+		}
+		run.finish()
+	}()
+	return out
+}
+
+func (run *HistoryRun) record(r *Result) {
+	if run.err != nil || !run.report.Result(r) || r.URL == nil {
+		return
+	}
+	run.err = run.enc.Encode(NewJSONResult(r))
+}
+
+// Move the results into place, unless they could not all be written.
+func (run *HistoryRun) finish() {
+	partial := run.fp.Name()
+	if err := run.fp.Close(); err != nil && run.err == nil {
+		run.err = err
+	}
+	if run.err == nil {
+		run.err = os.Rename(partial, run.path)
+	}
+	if run.err != nil {
+		logging.Logf(logging.LogError, "Unable to record history: %s", run.err.Error())
+	}
+}
+
+// Path returns where the run is kept once complete.
+func (run *HistoryRun) Path() string {
+	return run.path
+}
+
+// Name of the file keeping wordlist hits across runs
+const wordHitsFile = "word-hits.txt"
+
+// WordHits returns the number of hits for each wordlist entry in earlier
+// runs.
+func (h *History) WordHits() (map[string]int, error) {
+	hits := make(map[string]int)
+	fp, err := os.Open(filepath.Join(h.dir, wordHitsFile))
+	if os.IsNotExist(err) {
+		return hits, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	scanner := bufio.NewScanner(fp)
+	for scanner.Scan() {
+		pieces := strings.SplitN(scanner.Text(), "\t", 2)
+		if len(pieces) != 2 {
+			continue
+		}
+		n, err := strconv.Atoi(pieces[0])
+		if err != nil {
+			continue
+		}
+		hits[pieces[1]] += n
+	}
+	return hits, scanner.Err()
+}
+
+// SaveWordHits replaces the number of hits kept for each wordlist entry.
+func (h *History) SaveWordHits(hits map[string]int) error {
+	words := make([]string, 0, len(hits))
+	for word := range hits {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	path := filepath.Join(h.dir, wordHitsFile)
+	fp, err := os.Create(path + ".partial")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(fp)
+	for _, word := range words {
+		fmt.Fprintf(w, "%d\t%s\n", hits[word], word)
+	}
+	if err := w.Flush(); err != nil {
+		fp.Close()
+		return err
+	}
+	if err := fp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(path+".partial", path)
+}
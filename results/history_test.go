@@ -0,0 +1,93 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webborer-history")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	history, err := OpenHistory(filepath.Join(dir, "runs"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if latest, err := history.Latest(); err != nil || latest != "" {
+		t.Errorf("Expected no runs, got %q, %v", latest, err)
+	}
+
+	start := time.Date(2018, 3, 4, 5, 6, 7, 0, time.UTC)
+	for i, code := range []int{200, 403} {
+		run, err := history.Begin(start.Add(time.Duration(i) * time.Hour))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		in := make(chan *Result, 2)
+		in <- compareResult("/admin", code, 10)
+		in <- compareResult("/missing", 404, 0)
+		close(in)
+		for range run.Process(in) {
+		}
+	}
+
+	runs, err := history.Runs()
+	if err != nil || len(runs) != 2 {
+		t.Fatalf("Expected 2 runs, got %v, %v", runs, err)
+	}
+	latest, _ := history.Latest()
+	if filepath.Base(latest) != "20180304T060607Z.json" {
+		t.Errorf("Unexpected latest run: %s", latest)
+	}
+	c, err := LoadComparisonFile(latest)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(c.previous) != 1 || c.previous["http://localhost/admin"].Code != 403 {
+		t.Errorf("Unexpected results in latest run: %v", c.previous)
+	}
+}
+
+func TestHistory_WordHits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webborer-history")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	history, err := OpenHistory(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if hits, err := history.WordHits(); err != nil || len(hits) != 0 {
+		t.Errorf("Expected no hits, got %v, %v", hits, err)
+	}
+	if err := history.SaveWordHits(map[string]int{"admin": 3, "old backup": 1}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	hits, err := history.WordHits()
+	if err != nil || hits["admin"] != 3 || hits["old backup"] != 1 || len(hits) != 2 {
+		t.Errorf("Unexpected hits: %v, %v", hits, err)
+	}
+	if latest, err := history.Latest(); err != nil || latest != "" {
+		t.Errorf("Expected hits not to count as a run, got %q, %v", latest, err)
+	}
+}
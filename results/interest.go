@@ -0,0 +1,184 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"sort"
+	"strings"
+)
+
+// Results scoring at least this are flagged as interesting in the output
+const InterestHigh = 50
+
+// Highest interest score
+const maxInterest = 100
+
+// Points for paths containing each keyword, matched case-insensitively.  Only
+// the highest scoring keyword counts.
+var InterestKeywords = map[string]int{
+	".env":       50,
+	".git":       50,
+	".svn":       45,
+	".htpasswd":  50,
+	"backup":     40,
+	".bak":       40,
+	".old":       35,
+	".sql":       45,
+	".zip":       30,
+	".tar":       30,
+	".gz":        30,
+	"~":          30,
+	"password":   40,
+	"passwd":     40,
+	"secret":     40,
+	"credential": 40,
+	"id_rsa":     50,
+	"config":     30,
+	"admin":      30,
+	"phpinfo":    30,
+	"debug":      25,
+	"console":    25,
+	"private":    25,
+	".log":       25,
+	"swagger":    20,
+	"actuator":   25,
+	"internal":   20,
+	"upload":     15,
+	"login":      15,
+	"staging":    15,
+	"dev":        10,
+	"test":       10,
+	"api":        10,
+}
+
+// Points for each status code class, with exact codes taking precedence
+var interestCodes = map[int]int{
+	200: 20,
+	401: 10,
+	403: 10,
+	2:   15,
+	5:   15,
+}
+
+// Points for content types, by prefix
+var interestContentTypes = []struct {
+	prefix string
+	points int
+}{
+	{"application/octet-stream", 20},
+	{"application/zip", 20},
+	{"application/x-", 20},
+	{"application/sql", 20},
+	{"text/plain", 10},
+	{"application/json", 5},
+	{"application/xml", 5},
+	{"text/xml", 5},
+}
+
+// Points for each finding on a result, by severity
+var interestFindings = map[Severity]int{
+	SeverityInfo:   2,
+	SeverityLow:    5,
+	SeverityMedium: 15,
+	SeverityHigh:   40,
+}
+
+// Points taken off when the content contradicts the URL
+const contradictedInterest = 20
+
+// Score how interesting a result is likely to be to review, from 0 to 100,
+// by its path, status code, content type and findings.
+func InterestScore(r *Result) int {
+	score := 0
+	if r.URL != nil {
+		path := strings.ToLower(r.URL.Path)
+		best := 0
+		for keyword, points := range InterestKeywords {
+			if points > best && strings.Contains(path, keyword) {
+				best = points
+			}
+		}
+		score += best
+	}
+	if points, ok := interestCodes[r.Code]; ok {
+		score += points
+	} else {
+		score += interestCodes[r.Code/100]
+	}
+	ctype := strings.ToLower(r.ContentType)
+	for _, ct := range interestContentTypes {
+		if strings.HasPrefix(ctype, ct.prefix) {
+			score += ct.points
+			break
+		}
+	}
+	for _, f := range r.Findings {
+		score += interestFindings[f.Severity]
+	}
+	if r.Confidence == ConfidenceContradicted {
+		score -= contradictedInterest
+	}
+	if score < 0 {
+		return 0
+	}
+	if score > maxInterest {
+		return maxInterest
+	}
+	return score
+}
+
+// InterestScorer sets the interest score of reported results.  When sorting,
+// results are held until the input is finished and then passed on most
+// interesting first.
+type InterestScorer struct {
+	sort bool
+	// Status codes the scan reports
+	report ReportCodes
+}
+
+func (s *InterestScorer) SetReportCodes(codes ReportCodes) {
+	s.report = codes
+}
+
+func NewInterestScorer(sortResults bool) *InterestScorer {
+	return &InterestScorer{sort: sortResults}
+}
+
+func (s *InterestScorer) Process(in <-chan *Result) <-chan *Result {
+	out := make(chan *Result, cap(in))
+	go func() {
+		defer close(out)
+		var held []*Result
+		for r := range in {
+			if !s.report.Result(r) {
+				out <- r
+				continue
+			}
+			r.Interest = InterestScore(r)
+			if s.sort {
+				held = append(held, r)
+			} else {
+				out <- r
+			}
+		}
+		sort.SliceStable(held, func(i, j int) bool {
+			return held[i].Interest > held[j].Interest
+		})
+		for _, r := range held {
+			out <- r
+		}
+	}()
+	return out
+}
@@ -0,0 +1,89 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"net/url"
+	"testing"
+)
+
+func interestResult(path string, code int, ctype string) *Result {
+	r := NewResult(&url.URL{Scheme: "http", Host: "localhost", Path: path}, "")
+	r.Code = code
+	r.ContentType = ctype
+	return r
+}
+
+func TestInterestScore(t *testing.T) {
+	env := interestResult("/.env", 200, "text/plain")
+	if score := InterestScore(env); score != 80 {
+		t.Errorf("Expected 80 for .env, got %d", score)
+	}
+	admin := interestResult("/Admin/", 403, "text/html")
+	if score := InterestScore(admin); score != 40 {
+		t.Errorf("Expected 40 for forbidden admin, got %d", score)
+	}
+	plain := interestResult("/about.html", 200, "text/html")
+	if score := InterestScore(plain); score != 20 {
+		t.Errorf("Expected 20 for a plain page, got %d", score)
+	}
+	plain.Confidence = ConfidenceContradicted
+	if score := InterestScore(plain); score != 0 {
+		t.Errorf("Expected 0 for contradicted page, got %d", score)
+	}
+	backup := interestResult("/backup/db.sql.gz", 200, "application/octet-stream")
+	backup.AddFinding("test", SeverityHigh, "dump")
+	if score := InterestScore(backup); score != maxInterest {
+		t.Errorf("Expected score capped at %d, got %d", maxInterest, score)
+	}
+	if score := InterestScore(interestResult("/error", 500, "")); score != 15 {
+		t.Errorf("Expected 15 for a server error, got %d", score)
+	}
+}
+
+func TestInterestScorer(t *testing.T) {
+	in := make(chan *Result, 5)
+	in <- interestResult("/about.html", 200, "text/html")
+	in <- interestResult("/.git/config", 200, "text/plain")
+	in <- interestResult("/missing", 404, "text/html")
+	in <- interestResult("/admin/", 401, "text/html")
+	close(in)
+	var paths []string
+	for r := range NewInterestScorer(true).Process(in) {
+		paths = append(paths, r.URL.Path)
+	}
+	expected := []string{"/missing", "/.git/config", "/admin/", "/about.html"}
+	if len(paths) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, paths)
+	}
+	for i := range expected {
+		if paths[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, paths)
+			break
+		}
+	}
+}
+
+func TestAnnotationString_Interest(t *testing.T) {
+	r := interestResult("/.env", 200, "text/plain")
+	r.Interest = InterestHigh
+	if s := annotationString(r); s != " [interest: 50]" {
+		t.Errorf("Unexpected annotation %q", s)
+	}
+	r.Interest = InterestHigh - 1
+	if s := annotationString(r); s != "" {
+		t.Errorf("Expected no annotation, got %q", s)
+	}
+}
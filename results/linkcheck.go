@@ -34,6 +34,7 @@ type LinkCheckResultsManager struct {
 	missing    int
 	writerImpl linkCheckWriter
 	baseURL    string
+	findings   findingList
 }
 
 func (rm *LinkCheckResultsManager) init() error {
@@ -63,46 +64,83 @@ func (rm *LinkCheckResultsManager) Run(resChan <-chan *Result) {
 			rm.done()
 		}()
 
-		var keys []string
 		for res := range resChan {
-			key := res.URL.String()
-			rm.resMap[key] = res
-			keys = append(keys, key)
+			rm.resMap[res.URL.String()] = res
+			rm.findings.add(res, rm.report)
 		}
-		sort.Strings(keys)
+		broken := rm.brokenLinks()
+		var sources []string
+		for src := range broken {
+			sources = append(sources, src)
+		}
+		sort.Strings(sources)
 		rm.writerImpl.writeHeader(rm.baseURL)
 		count := 0
 
-		for _, resKey := range keys {
-			groupWritten := false
-			res := rm.resMap[resKey]
-			for k, t := range res.Links {
-				if rm.linkIsBroken(k) {
-					if !groupWritten {
-						groupWritten = true
-						rm.writerImpl.writeGroup(k)
-					}
-					rm.writerImpl.writeBrokenLink(resKey, k, LinkTypes[t])
-					count++
-				}
+		for _, src := range sources {
+			rm.writerImpl.writeGroup(src)
+			var dsts []string
+			for dst := range broken[src] {
+				dsts = append(dsts, dst)
+			}
+			sort.Strings(dsts)
+			for _, dst := range dsts {
+				rm.writerImpl.writeBrokenLink(src, dst, LinkTypes[broken[src][dst]])
+				count++
 			}
 		}
 
 		rm.writerImpl.writeFooter(count)
+		rm.writerImpl.writeFindings(rm.findings.sorted())
 	}()
 }
 
+// Find the broken links on each page, from the links found on it and the
+// referrers of broken results.
+func (rm *LinkCheckResultsManager) brokenLinks() map[string]map[string]LinkType {
+	broken := make(map[string]map[string]LinkType)
+	add := func(src, dst string, t LinkType) {
+		if broken[src] == nil {
+			broken[src] = make(map[string]LinkType)
+		}
+		broken[src][dst] = t
+	}
+	for src, res := range rm.resMap {
+		for dst, t := range res.Links {
+			if rm.linkIsBroken(dst) {
+				add(src, dst, t)
+			}
+		}
+	}
+	for dst, res := range rm.resMap {
+		if res.Referrer == nil || !resultIsBroken(res) {
+			continue
+		}
+		src := res.Referrer.String()
+		if _, ok := broken[src][dst]; !ok {
+			add(src, dst, LinkUnknown)
+		}
+	}
+	return broken
+}
+
 // Check if an HTTP code is broken, consider all 400/500s
 func codeIsBroken(code int) bool {
 	return code >= 400
 }
 
+// Check if a result is broken: an error, such as for a host that can't be
+// reached, or a broken code.
+func resultIsBroken(r *Result) bool {
+	return r.Error != nil || codeIsBroken(r.Code)
+}
+
 func (rm *LinkCheckResultsManager) linkIsBroken(url string) bool {
 	if r, ok := rm.resMap[url]; !ok {
 		rm.missing++
 		return false
 	} else {
-		return codeIsBroken(r.Code)
+		return resultIsBroken(r)
 	}
 }
 
@@ -111,6 +149,7 @@ type linkCheckWriter interface {
 	writeFooter(int)
 	writeGroup(string)
 	writeBrokenLink(src, dst, ltype string)
+	writeFindings([]*Finding)
 	flush()
 }
 
@@ -139,6 +178,17 @@ func (w *linkCheckCSVWriter) writeBrokenLink(src, dst, ltype string) {
 	w.csvWriter.Write([]string{src, dst, ltype})
 }
 
+func (w *linkCheckCSVWriter) writeFindings(findings []*Finding) {
+	if len(findings) == 0 {
+		return
+	}
+	w.csvWriter.Write([]string{""})
+	w.csvWriter.Write(findingCSVHeader)
+	for _, f := range findings {
+		w.csvWriter.Write(f.csvRecord())
+	}
+}
+
 func (w *linkCheckCSVWriter) flush() {
 	w.csvWriter.Flush()
 }
@@ -169,7 +219,7 @@ func (w *linkCheckHTMLWriter) writeHeader(baseURL string) {
 }
 
 func (w *linkCheckHTMLWriter) writeFooter(count int) {
-	footer := `{{define "FOOTER"}}</table><p>Total Broken Links Found: <b>{{.Count}}</b></html>{{end}}`
+	footer := `{{define "FOOTER"}}</table><p>Total Broken Links Found: <b>{{.Count}}</b>{{end}}`
 	t, err := template.New("linkCheckHTMLWriter").Parse(footer)
 	if err != nil {
 		logging.Logf(logging.LogWarning, "Error parsing a template: %s", err.Error())
@@ -218,5 +268,16 @@ func (w *linkCheckHTMLWriter) writeBrokenLink(src, dst, ltype string) {
 	}
 }
 
+func (w *linkCheckHTMLWriter) writeFindings(findings []*Finding) {
+	t, err := template.New("linkCheckHTMLWriter").Parse(`{{define "FINDINGS"}}` + htmlFindingsTemplate + `{{end}}`)
+	if err != nil {
+		logging.Logf(logging.LogWarning, "Error parsing a template: %s", err.Error())
+	}
+	if err := t.ExecuteTemplate(w.writer, "FINDINGS", findings); err != nil {
+		logging.Logf(logging.LogWarning, "Error writing template output: %s", err.Error())
+	}
+}
+
 func (w *linkCheckHTMLWriter) flush() {
+	io.WriteString(w.writer, "</html>")
 }
@@ -16,7 +16,9 @@ package results
 
 import (
 	"bytes"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 	"testing"
 )
@@ -66,6 +68,39 @@ func TestCodeIsBroken(t *testing.T) {
 	}
 }
 
+func TestLinkCheckRun(t *testing.T) {
+	page := NewResult(&url.URL{Scheme: "http", Host: "localhost", Path: "/"}, "")
+	page.Code = http.StatusOK
+	page.AddLink(&url.URL{Scheme: "http", Host: "localhost", Path: "/missing"}, LinkUnknown)
+	page.AddLink(&url.URL{Scheme: "http", Host: "localhost", Path: "/ok"}, LinkUnknown)
+	missing := NewResult(&url.URL{Scheme: "http", Host: "localhost", Path: "/missing"}, "")
+	missing.Code = http.StatusNotFound
+	ok := NewResult(&url.URL{Scheme: "http", Host: "localhost", Path: "/ok"}, "")
+	ok.Code = http.StatusOK
+	external := NewResult(&url.URL{Scheme: "http", Host: "example.invalid", Path: "/"}, "")
+	external.Referrer = &url.URL{Scheme: "http", Host: "localhost", Path: "/ok"}
+	external.Error = fmt.Errorf("no such host")
+
+	buf := &bytes.Buffer{}
+	rm := &LinkCheckResultsManager{writer: buf, format: "csv"}
+	if err := rm.init(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ch := make(chan *Result, 4)
+	for _, r := range []*Result{page, missing, ok, external} {
+		ch <- r
+	}
+	close(ch)
+	rm.Run(ch)
+	rm.Wait()
+	expected := "Source URL,Destination URL,Type\n" +
+		"http://localhost/,http://localhost/missing,\n" +
+		"http://localhost/ok,http://example.invalid/,\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
 func exerciseLinkCheckWriter(w linkCheckWriter) {
 	w.writeHeader("http://localhost/")
 	w.writeGroup("src")
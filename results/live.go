@@ -0,0 +1,73 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"fmt"
+	"github.com/Matir/webborer/util"
+	"io"
+	"sync"
+)
+
+// ANSI colors for status code groups
+var statusColors = map[int]string{
+	200: "\x1b[32m",
+	300: "\x1b[36m",
+	400: "\x1b[33m",
+	500: "\x1b[31m",
+}
+
+const colorReset = "\x1b[0m"
+
+// LiveWriter prints each reported result as soon as it is found, so a scan
+// can be watched while the report is written elsewhere.  Results are shown
+// before grouping, verification, and the like, so the report may differ.
+type LiveWriter struct {
+	writer io.Writer
+	color  bool
+	redirs bool
+	sync.Mutex
+	// Status codes the scan reports
+	report ReportCodes
+}
+
+func (lw *LiveWriter) SetReportCodes(codes ReportCodes) {
+	lw.report = codes
+}
+
+func NewLiveWriter(writer io.Writer, color bool) *LiveWriter {
+	return &LiveWriter{writer: writer, color: color}
+}
+
+// Show redirects as well as other results.
+func (lw *LiveWriter) SetRedirects(redirs bool) {
+	lw.redirs = redirs
+}
+
+// Print a result if it would be reported.
+func (lw *LiveWriter) Write(r *Result) {
+	line, ok := plainLine(r, lw.report, lw.redirs)
+	if !ok {
+		return
+	}
+	if lw.color {
+		if color, ok := statusColors[util.StatusCodeGroup(r.Code)]; ok {
+			line = color + line + colorReset
+		}
+	}
+	lw.Lock()
+	defer lw.Unlock()
+	fmt.Fprintln(lw.writer, line)
+}
@@ -0,0 +1,50 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"bytes"
+	"net/url"
+	"testing"
+)
+
+func TestLiveWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lw := NewLiveWriter(buf, false)
+	hit := NewResult(&url.URL{Scheme: "http", Host: "localhost", Path: "/admin"}, "")
+	hit.Code = 200
+	hit.Length = 5
+	lw.Write(hit)
+	miss := NewResult(&url.URL{Scheme: "http", Host: "localhost", Path: "/missing"}, "")
+	miss.Code = 404
+	lw.Write(miss)
+	redir := NewResult(&url.URL{Scheme: "http", Host: "localhost", Path: "/old"}, "")
+	redir.Code = 301
+	redir.Redir = &url.URL{Scheme: "http", Host: "localhost", Path: "/new"}
+	lw.Write(redir)
+	expected := "200 http://localhost/admin (5 bytes)\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	lw = NewLiveWriter(buf, true)
+	lw.SetRedirects(true)
+	lw.Write(redir)
+	expected = "\x1b[36m301 http://localhost/old -> http://localhost/new\x1b[0m\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
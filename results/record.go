@@ -0,0 +1,410 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/Matir/webborer/logging"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Longest name derived from a URL for a recorded exchange
+const maxRecordNameLength = 100
+
+var unsafeRecordChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// The raw request and response for a result.
+type Exchange struct {
+	Method        string
+	URL           *url.URL
+	Host          string
+	Proto         string
+	RequestHeader http.Header
+	// Response status line, e.g. "200 OK"
+	Status         string
+	Code           int
+	ResponseProto  string
+	ResponseHeader http.Header
+	// Start of the response body
+	Body []byte
+	// The body was longer than what was kept
+	Truncated bool
+	// When the request was sent
+	Started time.Time
+	// How long each phase took, as in HAR files, or -1 for phases that
+	// didn't happen
+	Blocked time.Duration
+	DNS     time.Duration
+	Connect time.Duration
+	SSL     time.Duration
+	Send    time.Duration
+	Wait    time.Duration
+	Receive time.Duration
+}
+
+// Write the exchange as raw HTTP: the request, a blank line, then the
+// response.
+func (e *Exchange) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	host := e.Host
+	if host == "" {
+		host = e.URL.Host
+	}
+	fmt.Fprintf(bw, "%s %s %s\r\n", e.Method, e.URL.RequestURI(), e.Proto)
+	fmt.Fprintf(bw, "Host: %s\r\n", host)
+	e.RequestHeader.Write(bw)
+	fmt.Fprintf(bw, "\r\n%s %s\r\n", e.ResponseProto, e.Status)
+	e.ResponseHeader.Write(bw)
+	bw.WriteString("\r\n")
+	bw.Write(e.Body)
+	if e.Truncated {
+		bw.WriteString("\n[truncated]\n")
+	}
+	return bw.Flush()
+}
+
+// Recorder saves the raw requests and responses of results passing through
+// it, either as a file per result in a directory, or as a single HAR file.
+type Recorder struct {
+	dir      string
+	har      *harWriter
+	harFile  *os.File
+	hitsOnly bool
+	keep     bool
+	count    int
+	// Status codes the scan reports
+	report ReportCodes
+}
+
+func (rec *Recorder) SetReportCodes(codes ReportCodes) {
+	rec.report = codes
+}
+
+// Create a Recorder that saves to path.  Paths ending in .har are written as
+// a HAR file, and anything else is a directory.  If hitsOnly is set, only
+// results that would be reported are recorded.
+func NewRecorder(path string, hitsOnly bool) (*Recorder, error) {
+	rec := &Recorder{hitsOnly: hitsOnly}
+	if strings.EqualFold(filepath.Ext(path), ".har") {
+		fp, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		rec.harFile = fp
+		rec.har = newHARWriter(fp)
+		return rec, nil
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+	rec.dir = path
+	return rec, nil
+}
+
+// Leave the exchanges on results after recording them, for output that also
+// needs them.
+func (rec *Recorder) KeepExchanges() {
+	rec.keep = true
+}
+
+func (rec *Recorder) Process(in <-chan *Result) <-chan *Result {
+	out := make(chan *Result, cap(in))
+	go func() {
+		defer close(out)
+		for r := range in {
+			if r.Exchange != nil {
+				if !rec.hitsOnly || rec.report.Result(r) {
+					rec.Record(r)
+				}
+				// Don't hold bodies in memory for the output
+				if !rec.keep {
+					r.Exchange = nil
+				}
+			}
+			out <- r
+		}
+		if err := rec.Close(); err != nil {
+			logging.Logf(logging.LogWarning, "Error finishing recording: %s", err.Error())
+		}
+	}()
+	return out
+}
+
+// Record the exchange for a single result.
+func (rec *Recorder) Record(r *Result) {
+	rec.count++
+	if rec.har != nil {
+		if err := rec.har.add(r.Exchange, r.Tags); err != nil {
+			logging.Logf(logging.LogWarning, "Unable to record %s: %s", r.URL.String(), err.Error())
+		}
+		return
+	}
+	name := unsafeRecordChars.ReplaceAllString(r.URL.Host+r.URL.Path, "_")
+	if len(name) > maxRecordNameLength {
+		name = name[:maxRecordNameLength]
+	}
+	path := filepath.Join(rec.dir, fmt.Sprintf("%05d_%s.txt", rec.count, name))
+	fp, err := os.Create(path)
+	if err != nil {
+		logging.Logf(logging.LogWarning, "Unable to record %s: %s", r.URL.String(), err.Error())
+		return
+	}
+	defer fp.Close()
+	if err := r.Exchange.Write(fp); err != nil {
+		logging.Logf(logging.LogWarning, "Unable to record %s: %s", r.URL.String(), err.Error())
+		return
+	}
+	r.Evidence = path
+}
+
+// Finish the recording.
+func (rec *Recorder) Close() error {
+	if rec.har == nil {
+		return nil
+	}
+	err := rec.har.finish(nil)
+	if cerr := rec.harFile.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Writes HAR 1.2 entries as they are added, so they don't all have to be
+// kept in memory.
+type harWriter struct {
+	w       io.Writer
+	entries int
+	err     error
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	// Tags of the result, as a custom field
+	Tags []string `json:"_tags,omitempty"`
+}
+
+const harHeader = `{"log":{"version":"1.2","creator":{"name":"WebBorer","version":"0.01"},"entries":[`
+
+func newHARWriter(w io.Writer) *harWriter {
+	h := &harWriter{w: w}
+	_, h.err = io.WriteString(w, harHeader)
+	return h
+}
+
+func (h *harWriter) add(e *Exchange, tags []string) error {
+	if h.err != nil {
+		return h.err
+	}
+	entry := newHAREntry(e)
+	entry.Tags = tags
+	buf, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if h.entries > 0 {
+		buf = append([]byte{','}, buf...)
+	}
+	h.entries++
+	_, h.err = h.w.Write(append(buf, '\n'))
+	return h.err
+}
+
+// Finish the HAR file after the last entry.  Findings, if any, are added to
+// the log as a custom field, since HAR has no place for them.
+func (h *harWriter) finish(findings []*JSONFinding) error {
+	if h.err != nil {
+		return h.err
+	}
+	if len(findings) > 0 {
+		buf, err := json.Marshal(findings)
+		if err != nil {
+			return err
+		}
+		_, h.err = fmt.Fprintf(h.w, "],\"_findings\":%s}}\n", buf)
+		return h.err
+	}
+	_, h.err = io.WriteString(h.w, "]}}\n")
+	return h.err
+}
+
+// Milliseconds for a HAR timing, or -1 if it didn't happen
+func harMillis(d time.Duration) float64 {
+	if d < 0 {
+		return -1
+	}
+	return float64(d) / float64(time.Millisecond)
+}
+
+func newHAREntry(e *Exchange) *harEntry {
+	timings := harTimings{
+		Blocked: harMillis(e.Blocked),
+		DNS:     harMillis(e.DNS),
+		Connect: harMillis(e.Connect),
+		SSL:     harMillis(e.SSL),
+		Send:    harMillis(e.Send),
+		Wait:    harMillis(e.Wait),
+		Receive: harMillis(e.Receive),
+	}
+	// Send, wait and receive are required
+	for _, ms := range []*float64{&timings.Send, &timings.Wait, &timings.Receive} {
+		if *ms < 0 {
+			*ms = 0
+		}
+	}
+	// SSL time is already part of connecting
+	total := 0.0
+	for _, ms := range []float64{timings.Blocked, timings.DNS, timings.Connect, timings.Send, timings.Wait, timings.Receive} {
+		if ms > 0 {
+			total += ms
+		}
+	}
+	requestHeader := harHeaders(e.RequestHeader)
+	host := e.Host
+	if host == "" {
+		host = e.URL.Host
+	}
+	requestHeader = append([]harNameValue{{"Host", host}}, requestHeader...)
+	query := []harNameValue{}
+	for k, vals := range e.URL.Query() {
+		for _, v := range vals {
+			query = append(query, harNameValue{k, v})
+		}
+	}
+	sort.SliceStable(query, func(i, j int) bool {
+		return query[i].Name < query[j].Name
+	})
+	content := harContent{
+		Size:     len(e.Body),
+		MimeType: e.ResponseHeader.Get("Content-Type"),
+	}
+	if utf8.Valid(e.Body) {
+		content.Text = string(e.Body)
+	} else {
+		content.Text = base64.StdEncoding.EncodeToString(e.Body)
+		content.Encoding = "base64"
+	}
+	if e.Truncated {
+		content.Comment = "truncated"
+	}
+	statusText := e.Status
+	if pieces := strings.SplitN(e.Status, " ", 2); len(pieces) == 2 {
+		statusText = pieces[1]
+	}
+	return &harEntry{
+		StartedDateTime: e.Started.Format(time.RFC3339Nano),
+		Time:            total,
+		Request: harRequest{
+			Method:      e.Method,
+			URL:         e.URL.String(),
+			HTTPVersion: e.Proto,
+			Cookies:     []harNameValue{},
+			Headers:     requestHeader,
+			QueryString: query,
+			HeadersSize: -1,
+			BodySize:    0,
+		},
+		Response: harResponse{
+			Status:      e.Code,
+			StatusText:  statusText,
+			HTTPVersion: e.ResponseProto,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(e.ResponseHeader),
+			Content:     content,
+			RedirectURL: e.ResponseHeader.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    len(e.Body),
+		},
+		Timings: timings,
+	}
+}
+
+// Headers in a stable order
+func harHeaders(header http.Header) []harNameValue {
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	rv := []harNameValue{}
+	for _, k := range keys {
+		for _, v := range header[k] {
+			rv = append(rv, harNameValue{k, v})
+		}
+	}
+	return rv
+}
@@ -0,0 +1,137 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func recordedResult(path string, code int) *Result {
+	u := &url.URL{Scheme: "http", Host: "localhost", Path: path, RawQuery: "q=1"}
+	r := NewResult(u, "")
+	r.Code = code
+	r.Exchange = &Exchange{
+		Method:         "GET",
+		URL:            u,
+		Proto:          "HTTP/1.1",
+		RequestHeader:  http.Header{"User-Agent": []string{"test"}},
+		Status:         "200 OK",
+		Code:           code,
+		ResponseProto:  "HTTP/1.1",
+		ResponseHeader: http.Header{"Content-Type": []string{"text/plain"}},
+		Body:           []byte("hello"),
+		Started:        time.Unix(0, 0),
+		Wait:           5 * time.Millisecond,
+	}
+	return r
+}
+
+func runRecorder(rec *Recorder, rs ...*Result) []*Result {
+	in := make(chan *Result, len(rs))
+	for _, r := range rs {
+		in <- r
+	}
+	close(in)
+	var out []*Result
+	for r := range rec.Process(in) {
+		out = append(out, r)
+	}
+	return out
+}
+
+func TestRecorder_Dir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "record")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rec, err := NewRecorder(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := runRecorder(rec, recordedResult("/a", 200), recordedResult("/b", 404))
+	if len(out) != 2 || out[0].Exchange != nil || out[1].Exchange != nil {
+		t.Fatalf("Expected results passed on without exchanges, got %v", out)
+	}
+	if out[1].Evidence != "" {
+		t.Errorf("Expected only hits recorded, got %s", out[1].Evidence)
+	}
+	buf, err := ioutil.ReadFile(out[0].Evidence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "GET /a?q=1 HTTP/1.1\r\nHost: localhost\r\nUser-Agent: test\r\n\r\n" +
+		"HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\n\r\nhello"
+	if string(buf) != expected {
+		t.Errorf("Unexpected recording: %q", buf)
+	}
+	if filepath.Dir(out[0].Evidence) != dir {
+		t.Errorf("Expected recording in %s, got %s", dir, out[0].Evidence)
+	}
+}
+
+func TestRecorder_HAR(t *testing.T) {
+	dir, err := ioutil.TempDir("", "record")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "scan.har")
+	rec, err := NewRecorder(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary := recordedResult("/b", 404)
+	binary.Exchange.Body = []byte{0xff, 0xfe}
+	runRecorder(rec, recordedResult("/a", 200), binary)
+	fp, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fp.Close()
+	var har struct {
+		Log struct {
+			Version string
+			Entries []harEntry
+		}
+	}
+	if err := json.NewDecoder(fp).Decode(&har); err != nil {
+		t.Fatalf("Invalid HAR: %s", err)
+	}
+	if har.Log.Version != "1.2" || len(har.Log.Entries) != 2 {
+		t.Fatalf("Unexpected HAR log: %+v", har.Log)
+	}
+	entry := har.Log.Entries[0]
+	if entry.Request.URL != "http://localhost/a?q=1" || entry.Time != 5 {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	if len(entry.Request.QueryString) != 1 || entry.Request.QueryString[0].Value != "1" {
+		t.Errorf("Unexpected query string: %v", entry.Request.QueryString)
+	}
+	if entry.Response.StatusText != "OK" || entry.Response.Content.Text != "hello" {
+		t.Errorf("Unexpected response: %+v", entry.Response)
+	}
+	if content := har.Log.Entries[1].Response.Content; content.Encoding != "base64" || !strings.HasPrefix(content.Text, "//4") {
+		t.Errorf("Expected base64 content, got %+v", content)
+	}
+}
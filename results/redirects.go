@@ -0,0 +1,213 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"net/url"
+)
+
+// Minimum number of redirects before a host is considered for fan-out
+// detection.
+const DefaultFanoutMin = 50
+
+// RedirectCollapser groups redirects that all end up at the same place (such
+// as a login page) into a single annotated result.  Results that are not
+// redirects are passed through immediately; redirects are held until the
+// input is finished.
+//
+// Hosts where nearly every path redirects (often to a search or landing page
+// with the path in the query) are "fan-out" hosts, and all of their redirects
+// are summarized as a single result.
+type RedirectCollapser struct {
+	// Minimum number of redirects to the same target to collapse
+	threshold int
+	groups    map[string]*redirectGroup
+	// Keys in the order first seen, for stable output
+	order []string
+	// Fraction of results that must be redirects for a fan-out host
+	fanoutRatio float64
+	// Minimum number of redirects for a fan-out host
+	fanoutMin int
+	hosts     map[string]*hostRedirects
+	// Status codes the scan reports
+	report ReportCodes
+}
+
+func (c *RedirectCollapser) SetReportCodes(codes ReportCodes) {
+	c.report = codes
+}
+
+type redirectGroup struct {
+	host    string
+	target  *url.URL
+	results []*Result
+	count   int
+}
+
+// Counts of results by source host
+type hostRedirects struct {
+	total     int
+	redirects int
+	// Set once the host's summary has been emitted
+	flushed bool
+}
+
+func NewRedirectCollapser(threshold int) *RedirectCollapser {
+	return &RedirectCollapser{
+		threshold: threshold,
+		groups:    make(map[string]*redirectGroup),
+		fanoutMin: DefaultFanoutMin,
+		hosts:     make(map[string]*hostRedirects),
+	}
+}
+
+// Summarize hosts where at least ratio of results are redirects, once they
+// have at least min redirects.  A ratio of 0 disables fan-out detection.
+func (c *RedirectCollapser) SetFanout(ratio float64, min int) {
+	c.fanoutRatio = ratio
+	c.fanoutMin = min
+}
+
+func (c *RedirectCollapser) Process(in <-chan *Result) <-chan *Result {
+	out := make(chan *Result, cap(in))
+	go func() {
+		defer close(out)
+		for r := range in {
+			target := redirectTarget(r)
+			c.count(r, target)
+			if target == nil || r.Error != nil {
+				out <- r
+				continue
+			}
+			c.add(r, target)
+		}
+		c.flush(out)
+	}()
+	return out
+}
+
+func (c *RedirectCollapser) count(r *Result, target *url.URL) {
+	if r.URL == nil || !c.report.Result(r) {
+		return
+	}
+	host, ok := c.hosts[r.URL.Host]
+	if !ok {
+		host = &hostRedirects{}
+		c.hosts[r.URL.Host] = host
+	}
+	host.total++
+	if target != nil {
+		host.redirects++
+	}
+}
+
+func (c *RedirectCollapser) add(r *Result, target *url.URL) {
+	var host string
+	if r.URL != nil {
+		host = r.URL.Host
+	}
+	key := host + " " + redirectKey(target)
+	group, ok := c.groups[key]
+	if !ok {
+		group = &redirectGroup{host: host, target: target}
+		c.groups[key] = group
+		c.order = append(c.order, key)
+	}
+	group.count++
+	if group.count < c.threshold || len(group.results) == 0 {
+		group.results = append(group.results, r)
+	} else {
+		// Only the first result is needed once we know we'll collapse
+		group.results = group.results[:1]
+	}
+}
+
+// Check if a host redirects nearly every path.
+func (c *RedirectCollapser) isFanout(host string) bool {
+	counts, ok := c.hosts[host]
+	if !ok || c.fanoutRatio <= 0 || counts.redirects < c.fanoutMin {
+		return false
+	}
+	return float64(counts.redirects) >= c.fanoutRatio*float64(counts.total)
+}
+
+// Summarize all redirects from a fan-out host in its first result.
+func (c *RedirectCollapser) flushFanout(host string, out chan<- *Result) {
+	counts := c.hosts[host]
+	if counts.flushed {
+		return
+	}
+	counts.flushed = true
+	var first *Result
+	targets := 0
+	for _, key := range c.order {
+		group := c.groups[key]
+		if group.host != host {
+			continue
+		}
+		targets++
+		if first == nil {
+			first = group.results[0]
+		}
+	}
+	first.GroupCount = counts.redirects
+	first.AddNote("%d of %d paths redirect to %d targets", counts.redirects, counts.total, targets)
+	out <- first
+}
+
+func (c *RedirectCollapser) flush(out chan<- *Result) {
+	for _, key := range c.order {
+		group := c.groups[key]
+		if c.isFanout(group.host) {
+			c.flushFanout(group.host, out)
+			continue
+		}
+		if group.count < c.threshold {
+			for _, r := range group.results {
+				out <- r
+			}
+			continue
+		}
+		r := group.results[0]
+		r.GroupCount = group.count
+		r.AddNote("%d redirects to %s", group.count, group.target.String())
+		out <- r
+	}
+}
+
+// Find where a result was redirected to, or nil if it was not.
+func redirectTarget(r *Result) *url.URL {
+	if r.Redir != nil {
+		return r.Redir
+	}
+	if len(r.RedirectChain) > 0 {
+		return r.RedirectChain[len(r.RedirectChain)-1]
+	}
+	return nil
+}
+
+// Query strings often carry the original path (e.g. ?next=/admin), so they
+// are ignored when deciding if two redirects go to the same place.
+func redirectKey(target *url.URL) string {
+	return target.Scheme + "://" + target.Host + target.Path
+}
+
+// Check if target is the same resource as src with a trailing slash added.
+func IsSlashRedirect(src, target *url.URL) bool {
+	if src.Host != target.Host && target.Host != "" {
+		return false
+	}
+	return target.Path == src.Path+"/"
+}
@@ -0,0 +1,109 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+)
+
+func redirectResult(path, target string) *Result {
+	u := &url.URL{Scheme: "http", Host: "localhost", Path: path}
+	r := &Result{URL: u, Code: 302}
+	if target != "" {
+		r.Redir, _ = url.Parse(target)
+	} else {
+		r.Code = 200
+	}
+	return r
+}
+
+func TestRedirectCollapser(t *testing.T) {
+	in := make(chan *Result, 20)
+	for i := 0; i < 10; i++ {
+		path := fmt.Sprintf("/%d", i)
+		in <- redirectResult(path, "http://localhost/login?next="+path)
+	}
+	in <- redirectResult("/a", "http://localhost/elsewhere")
+	in <- redirectResult("/b", "")
+	close(in)
+	var out []*Result
+	for r := range NewRedirectCollapser(5).Process(in) {
+		out = append(out, r)
+	}
+	if len(out) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(out))
+	}
+	if out[0].URL.Path != "/b" {
+		t.Errorf("Expected non-redirect first, got %s", out[0].URL.Path)
+	}
+	if out[1].GroupCount != 10 || len(out[1].Notes) != 1 {
+		t.Errorf("Expected collapsed group of 10 with note, got %d %v", out[1].GroupCount, out[1].Notes)
+	}
+	if out[2].GroupCount != 0 {
+		t.Errorf("Expected single redirect not to be grouped.")
+	}
+}
+
+func TestIsSlashRedirect(t *testing.T) {
+	src := &url.URL{Scheme: "http", Host: "localhost", Path: "/admin"}
+	cases := map[string]bool{
+		"http://localhost/admin/": true,
+		"/admin/":                 true,
+		"http://other/admin/":     false,
+		"http://localhost/login":  false,
+	}
+	for target, expected := range cases {
+		u, _ := url.Parse(target)
+		if IsSlashRedirect(src, u) != expected {
+			t.Errorf("IsSlashRedirect(%s, %s) != %v", src, target, expected)
+		}
+	}
+}
+
+func TestRedirectCollapser_Fanout(t *testing.T) {
+	in := make(chan *Result, 200)
+	for i := 0; i < 95; i++ {
+		path := fmt.Sprintf("/%d", i)
+		in <- redirectResult(path, fmt.Sprintf("http://localhost/search/%d", i))
+	}
+	for i := 0; i < 5; i++ {
+		in <- redirectResult(fmt.Sprintf("/page%d", i), "")
+	}
+	other := redirectResult("/x", "http://localhost/search/x")
+	other.URL.Host = "otherhost"
+	in <- other
+	close(in)
+	collapser := NewRedirectCollapser(5)
+	collapser.SetFanout(0.9, 50)
+	var out []*Result
+	for r := range collapser.Process(in) {
+		out = append(out, r)
+	}
+	if len(out) != 7 {
+		t.Fatalf("Expected 7 results, got %d", len(out))
+	}
+	summary := out[5]
+	if summary.URL.Path != "/0" || summary.GroupCount != 95 {
+		t.Errorf("Expected summary of 95 redirects, got %s %d", summary.URL.Path, summary.GroupCount)
+	}
+	if len(summary.Notes) != 1 || summary.Notes[0] != "95 of 100 paths redirect to 95 targets" {
+		t.Errorf("Unexpected notes: %v", summary.Notes)
+	}
+	if out[6].URL.Host != "otherhost" || out[6].GroupCount != 0 {
+		t.Errorf("Expected other host to be unaffected.")
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"time"
 )
 
 // Types of links
@@ -57,6 +58,8 @@ type Result struct {
 	Error error
 	// Redirect URL
 	Redir *url.URL
+	// Redirects followed to reach the final response
+	RedirectChain []*url.URL
 	// Content length
 	Length int64
 	// Content-type header
@@ -65,10 +68,68 @@ type Result struct {
 	RequestHeader http.Header
 	// Response headers
 	ResponseHeader http.Header
+	// Page that linked to the result, if any
+	Referrer *url.URL
+	// Why the result was requested
+	Provenance task.Provenance
+	// URL of the task the result was found from, if any
+	ParentURL *url.URL
+	// Steps from a starting URL
+	Depth int
 	// Group used for potentially bucketing results
 	ResultGroup string
 	// Links contained in result
 	Links map[string]LinkType
+	// Notes explaining how the result was interpreted
+	Notes []string
+	// Labels attached by tag rules, for triage
+	Tags []string
+	// Number of results this one stands for when similar results are grouped
+	GroupCount int
+	// Result is a calibration sample rather than a real finding
+	Baseline bool
+	// Findings from analysis of the response
+	Findings []*Finding
+	// SHA-256 of the response body, if hashed
+	BodyHash string
+	// Fuzzy hash of the response body, for finding near-duplicates
+	FuzzyHash uint64
+	// Shodan-style hash of a favicon, if the result is one
+	FaviconHash int32
+	// Start of the response body, kept only for filters that need it
+	BodySample []byte
+	// Path to a screenshot of the page
+	Screenshot string
+	// Time to connect, or 0 if an open connection was reused
+	ConnectTime time.Duration
+	// Time from sending the request to the first byte of the response
+	TTFB time.Duration
+	// Time from sending the request to reading the whole response
+	Duration time.Duration
+	// The response took at least the slow threshold
+	Slow bool
+	// The result didn't reproduce when requested again after the scan
+	Transient bool
+	// A plugin rejected the result while it was handled, so the plugin's
+	// filter drops it
+	PluginDropped bool
+	// Raw request and response, kept only until they are recorded
+	Exchange *Exchange
+	// Path to the recorded request and response
+	Evidence string
+	// Path to the saved response body
+	BodyFile string
+	// Validators of the response, for conditional requests
+	ETag         string
+	LastModified string
+	// The server said the result hasn't changed since the previous scan
+	NotModified bool
+	// How well the result has been confirmed
+	Confidence Confidence
+	// How interesting the result is likely to be to review, from 0 to 100
+	Interest int
+	// How the result differs from a previous scan: new, changed or removed
+	Change string
 }
 
 // Create a new result.
@@ -84,9 +145,21 @@ func NewResult(URL *url.URL, host string) *Result {
 func NewResultForTask(t *task.Task) *Result {
 	rv := NewResult(t.URL, t.Host)
 	rv.RequestHeader = t.Header
+	rv.Referrer = t.Referrer
+	rv.Provenance = t.Provenance
+	rv.ParentURL = t.ParentURL
+	rv.Depth = t.Depth
 	return rv
 }
 
+// Describe why the result was requested, or "" if that wasn't recorded.
+func (r *Result) FoundBy() string {
+	if r.Provenance.Origin == task.OriginUnknown {
+		return ""
+	}
+	return r.Provenance.String()
+}
+
 type ResultGroupGenerator func(*Result) string
 
 var GetResultGroup ResultGroupGenerator = func(*Result) string { return "" }
@@ -112,6 +185,21 @@ func (r *Result) AddLink(URL *url.URL, ltype LinkType) {
 	r.Links[URL.String()] = ltype
 }
 
+// Add a note to this result.
+func (r *Result) AddNote(format string, args ...interface{}) {
+	r.Notes = append(r.Notes, fmt.Sprintf(format, args...))
+}
+
+// Add a tag to this result, unless it already has it.
+func (r *Result) AddTag(tag string) {
+	for _, t := range r.Tags {
+		if t == tag {
+			return
+		}
+	}
+	r.Tags = append(r.Tags, tag)
+}
+
 // ResultsManager provides an interface for reading results from a channel and
 // writing them to some form of output.
 type ResultsManager interface {
@@ -125,10 +213,11 @@ type ResultsManager interface {
 
 type baseResultsManager struct {
 	finished chan bool
+	report   ReportCodes
 }
 
 // Available output formats as strings.
-var OutputFormats = []string{"text", "csv", "html", "diff"}
+var OutputFormats = []string{"text", "csv", "html", "diff", "human", "json", "tree", "har"}
 
 func init() {
 	ss.SetOutputFormats(OutputFormats)
@@ -144,9 +233,46 @@ func FoundSomething(code int) bool {
 		code != http.StatusGatewayTimeout)
 }
 
+// The status codes a scan reports.  Include overrides FoundSomething when
+// non-empty, and Exclude codes are never reported, so the zero value reports
+// whatever FoundSomething accepts.
+type ReportCodes struct {
+	Include ss.CodeRangeFlag
+	Exclude ss.CodeRangeFlag
+}
+
+// Get the status codes reported with these settings.
+func NewReportCodes(settings *ss.ScanSettings) ReportCodes {
+	return ReportCodes{Include: settings.IncludeCodes, Exclude: settings.ExcludeCodes}
+}
+
+// Returns true if results with this code should be reported
+func (c ReportCodes) Code(code int) bool {
+	if c.Exclude.Contains(code) {
+		return false
+	}
+	if len(c.Include) > 0 {
+		return c.Include.Contains(code)
+	}
+	return FoundSomething(code)
+}
+
 // Returns true if this result should be included in reports
-func ReportResult(res *Result) bool {
-	return res.Error == nil && FoundSomething(res.Code)
+func (c ReportCodes) Result(res *Result) bool {
+	return res.Error == nil && !res.Baseline && c.Code(res.Code)
+}
+
+// Satisfied by stages and managers that only handle reported results, so
+// they can be told which codes the scan reports.
+type ReportCodesSetter interface {
+	SetReportCodes(ReportCodes)
+}
+
+// Tell v which codes are reported, if it needs to know.
+func SetReportCodes(v interface{}, codes ReportCodes) {
+	if setter, ok := v.(ReportCodesSetter); ok {
+		setter.SetReportCodes(codes)
+	}
 }
 
 // Construct a ResultsManager for the given settings in the ss.ScanSettings.
@@ -156,7 +282,6 @@ func GetResultsManager(settings *ss.ScanSettings) (ResultsManager, error) {
 	var fp *os.File
 	var err error
 
-	format := settings.OutputFormat
 	if settings.OutputPath == "" {
 		writer = os.Stdout
 	} else {
@@ -167,8 +292,37 @@ func GetResultsManager(settings *ss.ScanSettings) (ResultsManager, error) {
 		}
 	}
 
+	rm, err := newResultsManager(settings, writer, fp)
+	if err != nil {
+		return nil, err
+	}
+	if settings.SplitDir != "" {
+		split, err := NewSplitResultsManager(settings, rm)
+		if err != nil {
+			return nil, err
+		}
+		split.SetReportCodes(NewReportCodes(settings))
+		return split, nil
+	}
+	return rm, nil
+}
+
+// Construct a ResultsManager writing to writer, which is fp if writing to a
+// file.
+func newResultsManager(settings *ss.ScanSettings, writer io.WriteCloser, fp *os.File) (ResultsManager, error) {
+	rm, err := newFormatManager(settings, writer, fp)
+	if err != nil {
+		return nil, err
+	}
+	SetReportCodes(rm, NewReportCodes(settings))
+	return rm, nil
+}
+
+// Construct the ResultsManager for the output format in the settings.
+func newFormatManager(settings *ss.ScanSettings, writer io.WriteCloser, fp *os.File) (ResultsManager, error) {
+	format := settings.OutputFormat
 	if settings.RunMode == ss.RunModeLinkCheck {
-		rm := &LinkCheckResultsManager{writer: writer, fp: fp, format: format, baseURL: settings.BaseURLs[0]}
+		rm := &LinkCheckResultsManager{writer: writer, fp: fp, format: format, baseURL: settings.FirstBaseURL()}
 		if err := rm.init(); err != nil {
 			return nil, err
 		}
@@ -182,15 +336,43 @@ func GetResultsManager(settings *ss.ScanSettings) (ResultsManager, error) {
 		return &CSVResultsManager{writer: csv.NewWriter(writer), fp: fp}, nil
 	case format == "html":
 		// TODO: do more than the first BaseURL
-		return &HTMLResultsManager{writer: writer, fp: fp, BaseURL: settings.BaseURLs[0]}, nil
+		// Bad rules are reported when the tagger is built
+		rules, _ := ParseTagRules(settings.TagRules)
+		return &HTMLResultsManager{writer: writer, fp: fp, BaseURL: settings.FirstBaseURL(), Tags: TagNames(rules)}, nil
+	case format == "human":
+		verbosity := VerbosityNormal
+		if settings.Quiet {
+			verbosity = VerbosityQuiet
+		} else if settings.Verbose {
+			verbosity = VerbosityVerbose
+		}
+		rm := NewHumanResultsManager(writer, UseColor(settings.Color, writer), settings.IncludeRedirects, verbosity)
+		rm.fp = fp
+		return rm, nil
+	case format == "tree":
+		return &TreeResultsManager{writer: writer, fp: fp, redirs: settings.IncludeRedirects}, nil
+	case format == "har":
+		rm := NewHARResultsManager(writer, settings.RecordHits)
+		rm.fp = fp
+		return rm, nil
+	case format == "json":
+		rm := NewJSONResultsManager(writer)
+		rm.fp = fp
+		return rm, nil
 	case format == "diff":
 		GetResultGroup = func(r *Result) string { return r.URL.Host }
-		return NewDiffResultsManager(writer), nil
+		drm := NewDiffResultsManager(writer)
+		drm.SetSampleSize(settings.CalibrationSamples)
+		return drm, nil
 	}
 
 	return nil, fmt.Errorf("Invalid output type: %s", format)
 }
 
+func (b *baseResultsManager) SetReportCodes(codes ReportCodes) {
+	b.report = codes
+}
+
 func (b *baseResultsManager) start() {
 	b.finished = make(chan bool)
 }
@@ -19,19 +19,22 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"strings"
 )
 
 // CSVResultsManager writes a CSV containing all of the results.
 type CSVResultsManager struct {
 	baseResultsManager
-	writer *csv.Writer
-	fp     *os.File
+	writer   *csv.Writer
+	fp       *os.File
+	findings findingList
 }
 
 func (rm *CSVResultsManager) Run(res <-chan *Result) {
 	go func() {
 		rm.start()
 		defer func() {
+			rm.writeFindings()
 			rm.writer.Flush()
 			if rm.fp != nil {
 				rm.fp.Close()
@@ -40,16 +43,17 @@ func (rm *CSVResultsManager) Run(res <-chan *Result) {
 		}()
 
 		// Header line
-		rm.writer.Write([]string{"code", "url", "content_length", "redirect_url"})
+		rm.writer.Write([]string{"code", "url", "content_length", "redirect_url", "confidence", "interest", "provenance", "ttfb_ms", "duration_ms", "tags"})
 
 		for r := range res {
 			rm.runOne(r)
+			rm.findings.add(r, rm.report)
 		}
 	}()
 }
 
 func (rm *CSVResultsManager) runOne(res *Result) {
-	if !ReportResult(res) {
+	if !rm.report.Result(res) {
 		return
 	}
 	var clen string
@@ -61,10 +65,29 @@ func (rm *CSVResultsManager) runOne(res *Result) {
 		res.URL.String(),
 		clen,
 		maybeStringURL(res.Redir),
+		res.Confidence.String(),
+		fmt.Sprintf("%d", res.Interest),
+		res.FoundBy(),
+		fmt.Sprintf("%d", res.TTFB.Milliseconds()),
+		fmt.Sprintf("%d", res.Duration.Milliseconds()),
+		strings.Join(res.Tags, " "),
 	}
 	rm.writer.Write(record)
 }
 
+// Write the findings after the results, separated by an empty line and with
+// their own header line.
+func (rm *CSVResultsManager) writeFindings() {
+	if len(rm.findings) == 0 {
+		return
+	}
+	rm.writer.Write([]string{""})
+	rm.writer.Write(findingCSVHeader)
+	for _, f := range rm.findings.sorted() {
+		rm.writer.Write(f.csvRecord())
+	}
+}
+
 func maybeStringURL(u *url.URL) string {
 	if u == nil {
 		return ""
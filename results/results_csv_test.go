@@ -39,15 +39,15 @@ func TestWriteCSV(t *testing.T) {
 	if len(lines) != 4 {
 		t.Fatalf("Expected 2 lines of output, got %d.", len(lines))
 	}
-	hdr := "code,url,content_length,redirect_url"
+	hdr := "code,url,content_length,redirect_url,confidence,interest,provenance,ttfb_ms,duration_ms,tags"
 	if lines[0] != hdr {
 		t.Errorf("Expected header \"%s\", got header \"%s\".", hdr, lines[0])
 	}
-	resStr := "200,http://localhost/,0,"
+	resStr := "200,http://localhost/,0,,,0,,0,0,"
 	if lines[1] != resStr {
 		t.Errorf("Expected result string \"%s\", got result string \"%s\".", resStr, lines[1])
 	}
-	resStr = "301,http://localhost/.git,0,https://localhost/.git"
+	resStr = "301,http://localhost/.git,0,https://localhost/.git,,0,,0,0,"
 	if lines[2] != resStr {
 		t.Errorf("Expected result string \"%s\", got result string \"%s\".", resStr, lines[1])
 	}
@@ -6,6 +6,7 @@ import (
 	"github.com/Matir/webborer/logging"
 	"github.com/Matir/webborer/util"
 	"io"
+	"net/url"
 	"strings"
 )
 
@@ -21,24 +22,43 @@ type BaselineResult struct {
 	PathSignificant    bool
 	HeadersSignificant []string
 	CodeSignificant    bool
+	LengthSignificant  bool
 }
 
+// Number of calibration samples used for each baseline by default.
+const defaultBaselineSamples = 3
+
 type DiffResultsManager struct {
-	baselines map[string]*BaselineResult
+	baselines *Baselines
 	done      chan interface{}
 	keep      map[string][]*Result
 	fp        io.WriteCloser
+	// Results seen before any applicable baseline
+	pending []*Result
+	// Findings to list after the results
+	findings findingList
+	// Status codes the scan reports
+	report ReportCodes
+}
+
+func (drm *DiffResultsManager) SetReportCodes(codes ReportCodes) {
+	drm.report = codes
 }
 
 func NewDiffResultsManager(fp io.WriteCloser) *DiffResultsManager {
 	return &DiffResultsManager{
-		baselines: make(map[string]*BaselineResult),
+		baselines: NewBaselines(),
 		done:      make(chan interface{}),
 		keep:      make(map[string][]*Result),
 		fp:        fp,
 	}
 }
 
+// Set the number of calibration samples that make up a baseline.
+func (drm *DiffResultsManager) SetSampleSize(n int) {
+	drm.baselines.SetSampleSize(n)
+}
+
 func NewBaselineResult(results ...Result) (*BaselineResult, error) {
 	if len(results) == 0 {
 		return nil, fmt.Errorf("Need at least one result.")
@@ -49,6 +69,7 @@ func NewBaselineResult(results ...Result) (*BaselineResult, error) {
 		PathSignificant:    true,
 		HeadersSignificant: make([]string, 0),
 		CodeSignificant:    true,
+		LengthSignificant:  results[0].Length >= 0,
 	}
 
 	for i := 0; i < len(results)-1; i++ {
@@ -59,21 +80,21 @@ func NewBaselineResult(results ...Result) (*BaselineResult, error) {
 		if a.URL.Path != b.URL.Path {
 			res.PathSignificant = false
 		}
+		if a.Length != b.Length {
+			res.LengthSignificant = false
+		}
 	}
 
 	for k, _ := range res.ResponseHeader {
-		k = strings.ToLower(k)
-		if util.StringSliceContains(neverImportant, k) {
+		if util.StringSliceContains(neverImportant, strings.ToLower(k)) {
 			continue
 		}
 		matches := true
-		baseline := results[0].ResponseHeader[k][0]
-		if len(results) > 0 {
-			for _, r := range results[1:] {
-				if r.ResponseHeader[k][0] != baseline {
-					matches = false
-					break
-				}
+		baseline := results[0].ResponseHeader.Get(k)
+		for _, r := range results[1:] {
+			if r.ResponseHeader.Get(k) != baseline {
+				matches = false
+				break
 			}
 		}
 		if matches {
@@ -91,6 +112,14 @@ func (b *BaselineResult) Matches(a *Result) bool {
 	if b.CodeSignificant && b.Code != a.Code {
 		return false
 	}
+	if b.LengthSignificant && b.Length != a.Length {
+		return false
+	}
+	for _, k := range b.HeadersSignificant {
+		if b.ResponseHeader.Get(k) != a.ResponseHeader.Get(k) {
+			return false
+		}
+	}
 	return true
 }
 
@@ -100,7 +129,7 @@ func (drm *DiffResultsManager) AddGroup(baselineResults ...Result) error {
 		return err
 	}
 
-	drm.baselines[baseline.ResultGroup] = baseline
+	drm.baselines.Set(baseline.ResultGroup, baseline)
 	return nil
 }
 
@@ -113,19 +142,55 @@ func (drm *DiffResultsManager) Run(rChan <-chan *Result) {
 			close(drm.done)
 		}()
 		for result := range rChan {
-			if baseline, ok := drm.baselines[result.ResultGroup]; !ok {
-				// No baseline!
-				logging.Debugf("No baseline for group %s", result.ResultGroup)
-				drm.Append(result)
+			drm.findings.add(result, drm.report)
+			if result.Baseline {
+				drm.baselines.AddSample(result)
+				continue
+			}
+			if baseline := drm.baselines.For(result); baseline == nil {
+				// No baseline yet, check again at the end.
+				drm.pending = append(drm.pending, result)
 			} else if !baseline.Matches(result) {
 				drm.Append(result)
 			} else {
 				logging.Debugf("Not logging result: %s", result.String())
 			}
 		}
+		for _, result := range drm.pending {
+			if baseline := drm.baselines.For(result); baseline == nil {
+				logging.Debugf("No baseline for group %s", result.ResultGroup)
+				drm.Append(result)
+			} else if !baseline.Matches(result) {
+				drm.Append(result)
+			}
+		}
 	}()
 }
 
+// Get the directory whose baseline applies to u.  For a directory, this is its
+// parent, since the directory is being compared against its siblings.
+func BaselineDir(u *url.URL) *url.URL {
+	dir := *u
+	dir.RawQuery = ""
+	dir.Fragment = ""
+	p := strings.TrimRight(u.Path, "/")
+	if i := strings.LastIndex(p, "/"); i >= 0 {
+		dir.Path = p[:i+1]
+	} else {
+		dir.Path = "/"
+	}
+	return &dir
+}
+
+// Get the key under which the baseline for u is stored.
+func BaselineKey(u *url.URL) string {
+	return baselineKeyForDir(BaselineDir(u))
+}
+
+func baselineKeyForDir(dir *url.URL) string {
+	return dir.Scheme + "://" + dir.Host + dir.Path
+}
+
 func (drm *DiffResultsManager) Wait() {
 	<-drm.done
 }
@@ -156,5 +221,6 @@ func (drm *DiffResultsManager) WriteResults() error {
 		}
 		fmt.Fprintf(fp, "\n")
 	}
+	drm.findings.writeText(fp)
 	return nil
 }
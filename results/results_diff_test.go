@@ -0,0 +1,134 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type nopWriteCloser struct {
+	*bytes.Buffer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func diffResult(path string, code int, length int64) *Result {
+	u := &url.URL{Scheme: "http", Host: "localhost", Path: path}
+	return &Result{
+		URL:            u,
+		Code:           code,
+		Length:         length,
+		ResponseHeader: make(http.Header),
+	}
+}
+
+func TestNewBaselineResult(t *testing.T) {
+	if _, err := NewBaselineResult(); err == nil {
+		t.Error("Expected error for empty baseline.")
+	}
+	a := diffResult("/a/x1", 404, 100)
+	b := diffResult("/a/x2", 404, 120)
+	a.ResponseHeader.Set("Server", "test")
+	b.ResponseHeader.Set("Server", "test")
+	a.ResponseHeader.Set("Etag", "1")
+	b.ResponseHeader.Set("Etag", "1")
+	baseline, err := NewBaselineResult(*a, *b)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if baseline.PathSignificant {
+		t.Error("Path should not be significant.")
+	}
+	if !baseline.CodeSignificant {
+		t.Error("Code should be significant.")
+	}
+	if baseline.LengthSignificant {
+		t.Error("Length should not be significant.")
+	}
+	if len(baseline.HeadersSignificant) != 1 || baseline.HeadersSignificant[0] != "Server" {
+		t.Errorf("Expected only Server to be significant, got %v", baseline.HeadersSignificant)
+	}
+	if baseline.Matches(diffResult("/a/y", 404, 5)) {
+		t.Error("Expected result without Server header not to match.")
+	}
+	match := diffResult("/a/y", 404, 5)
+	match.ResponseHeader.Set("Server", "test")
+	if !baseline.Matches(match) {
+		t.Error("Expected result to match baseline.")
+	}
+}
+
+func TestBaselineDir(t *testing.T) {
+	cases := map[string]string{
+		"/":         "/",
+		"/a":        "/",
+		"/a/":       "/",
+		"/a/b":      "/a/",
+		"/a/b/":     "/a/",
+		"/a/b/c.js": "/a/b/",
+	}
+	for in, expected := range cases {
+		u := &url.URL{Scheme: "http", Host: "localhost", Path: in, RawQuery: "q=1"}
+		dir := BaselineDir(u)
+		if dir.Path != expected {
+			t.Errorf("BaselineDir(%s): expected %s, got %s", in, expected, dir.Path)
+		}
+		if dir.RawQuery != "" {
+			t.Errorf("BaselineDir(%s): expected query to be dropped.", in)
+		}
+	}
+	u := &url.URL{Scheme: "https", Host: "example.com", Path: "/a/b"}
+	if k := BaselineKey(u); k != "https://example.com/a/" {
+		t.Errorf("Unexpected baseline key: %s", k)
+	}
+}
+
+func TestDiffResultsManager_Baselines(t *testing.T) {
+	buf := &bytes.Buffer{}
+	drm := NewDiffResultsManager(nopWriteCloser{buf})
+	drm.SetSampleSize(2)
+	rchan := make(chan *Result, 10)
+	// Found before calibration, must be held until the end.
+	rchan <- diffResult("/a/early", 200, 10)
+	for _, p := range []string{"/a/rand1", "/a/rand2", "/rand3", "/rand4"} {
+		r := diffResult(p, 404, 10)
+		r.Baseline = true
+		rchan <- r
+	}
+	rchan <- diffResult("/a/missing", 404, 10)
+	rchan <- diffResult("/a/b/missing", 404, 10)
+	rchan <- diffResult("/a/found", 200, 10)
+	rchan <- diffResult("/other", 404, 10)
+	close(rchan)
+	drm.Run(rchan)
+	drm.Wait()
+	out := buf.String()
+	for _, p := range []string{"/a/early", "/a/found"} {
+		if !strings.Contains(out, p) {
+			t.Errorf("Expected %s in output: %s", p, out)
+		}
+	}
+	for _, p := range []string{"missing", "rand", "/other"} {
+		if strings.Contains(out, p) {
+			t.Errorf("Expected %s not to be in output: %s", p, out)
+		}
+	}
+}
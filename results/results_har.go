@@ -0,0 +1,62 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"github.com/Matir/webborer/logging"
+	"io"
+	"os"
+)
+
+// HARResultsManager writes the request and response for each result as a
+// HAR archive, for loading into browsers and proxy tools.
+type HARResultsManager struct {
+	baseResultsManager
+	har      *harWriter
+	fp       *os.File
+	hitsOnly bool
+	findings findingList
+}
+
+// Create a HARResultsManager writing to w.  Every request with a response is
+// written unless hitsOnly is set.
+func NewHARResultsManager(w io.Writer, hitsOnly bool) *HARResultsManager {
+	return &HARResultsManager{har: newHARWriter(w), hitsOnly: hitsOnly}
+}
+
+func (rm *HARResultsManager) Run(res <-chan *Result) {
+	rm.start()
+	go func() {
+		defer func() {
+			if err := rm.har.finish(rm.findings.jsonFindings()); err != nil {
+				logging.Logf(logging.LogWarning, "Error writing HAR output: %s", err.Error())
+			}
+			if rm.fp != nil {
+				rm.fp.Close()
+			}
+			rm.done()
+		}()
+
+		for r := range res {
+			rm.findings.add(r, rm.report)
+			if r.Exchange == nil || (rm.hitsOnly && !rm.report.Result(r)) {
+				continue
+			}
+			if err := rm.har.add(r.Exchange, r.Tags); err != nil {
+				logging.Logf(logging.LogWarning, "Unable to write %s to HAR output: %s", r.URL.String(), err.Error())
+			}
+		}
+	}()
+}
@@ -0,0 +1,44 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestHARResultsManager(t *testing.T) {
+	buf := &bytes.Buffer{}
+	rm := NewHARResultsManager(buf, true)
+	res := make(chan *Result, 3)
+	rm.Run(res)
+	res <- recordedResult("/a", 200)
+	res <- recordedResult("/missing", 404)
+	res <- NewResult(recordedResult("/b", 200).URL, "")
+	close(res)
+	rm.Wait()
+	var har struct {
+		Log struct {
+			Entries []harEntry
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &har); err != nil {
+		t.Fatalf("Invalid HAR: %s", err)
+	}
+	if len(har.Log.Entries) != 1 || har.Log.Entries[0].Request.URL != "http://localhost/a?q=1" {
+		t.Errorf("Expected only the hit with an exchange, got %+v", har.Log.Entries)
+	}
+}
@@ -27,6 +27,9 @@ type HTMLResultsManager struct {
 	writer  io.Writer
 	fp      *os.File
 	BaseURL string
+	// Tags that rules may attach, offered as a filter
+	Tags     []string
+	findings findingList
 }
 
 func (rm *HTMLResultsManager) Run(res <-chan *Result) {
@@ -43,7 +46,8 @@ func (rm *HTMLResultsManager) Run(res <-chan *Result) {
 		}()
 
 		for r := range res {
-			if !ReportResult(r) {
+			rm.findings.add(r, rm.report)
+			if !rm.report.Result(r) {
 				continue
 			}
 			if r.Redir != nil {
@@ -55,15 +59,17 @@ func (rm *HTMLResultsManager) Run(res <-chan *Result) {
 }
 
 func (rm *HTMLResultsManager) writeHeader() {
-	header := `{{define "HEAD"}}<html><head><title>webborer: {{.BaseURL}}</title></head><h2>Results for <a href="{{.BaseURL}}">{{.BaseURL}}</a></h2><table><tr><th>Code</th><th>URL</th><th>Size</th><th>Content-Type</th></tr>{{end}}`
+	header := `{{define "HEAD"}}<html><head><title>webborer: {{.BaseURL}}</title>{{if .Tags}}<script>` + htmlTagFilterScript + `</script>{{end}}</head><h2>Results for <a href="{{.BaseURL}}">{{.BaseURL}}</a></h2>{{if .Tags}}<p>Tag: <select onchange="filterTags(this.value)"><option value="">all</option>{{range .Tags}}<option>{{.}}</option>{{end}}</select></p>{{end}}<table><tr><th>Code</th><th>URL</th><th>Size</th><th>Content-Type</th><th>Confidence</th><th>Interest</th><th>Tags</th><th>Found by</th><th>Time (ms)</th></tr>{{end}}`
 	t, err := template.New("htmlResultsManager").Parse(header)
 	if err != nil {
 		logging.Logf(logging.LogWarning, "Error parsing a template: %s", err.Error())
 	}
 	data := struct {
 		BaseURL string
+		Tags    []string
 	}{
 		BaseURL: rm.BaseURL,
+		Tags:    rm.Tags,
 	}
 	err = t.ExecuteTemplate(rm.writer, "HEAD", data)
 	if err != nil {
@@ -72,20 +78,32 @@ func (rm *HTMLResultsManager) writeHeader() {
 }
 
 func (rm *HTMLResultsManager) writeFooter() {
-	footer := `{{define "FOOTER"}}</table></html>{{end}}`
+	footer := `{{define "FOOTER"}}</table>` + htmlFindingsTemplate + `</html>{{end}}`
 	t, err := template.New("htmlResultsManager").Parse(footer)
 	if err != nil {
 		logging.Logf(logging.LogWarning, "Error parsing a template: %s", err.Error())
 	}
-	err = t.ExecuteTemplate(rm.writer, "FOOTER", nil)
+	err = t.ExecuteTemplate(rm.writer, "FOOTER", rm.findings.sorted())
 	if err != nil {
 		logging.Logf(logging.LogWarning, "Error writing template output: %s", err.Error())
 	}
 }
 
+// Show only the result rows with a tag, or all of them for an empty tag
+const htmlTagFilterScript = `function filterTags(tag) {
+  var rows = document.querySelectorAll("tr[data-tags]");
+  for (var i = 0; i < rows.length; i++) {
+    var tags = rows[i].getAttribute("data-tags").split(" ");
+    rows[i].style.display = (tag === "" || tags.indexOf(tag) >= 0) ? "" : "none";
+  }
+}`
+
+// Table of findings, given a slice of them, for the end of HTML output
+const htmlFindingsTemplate = `{{if .}}<h2>Findings</h2><table><tr><th>Severity</th><th>Category</th><th>Rule</th><th>URL</th><th>Message</th><th>Evidence</th></tr>{{range .}}<tr><td>{{.Severity}}</td><td>{{.Category}}</td><td>{{.Rule}}</td><td>{{with .URL}}<a href="{{.String}}">{{.String}}</a>{{end}}</td><td>{{.Message}}</td><td>{{.Evidence}}</td></tr>{{end}}</table>{{end}}`
+
 func (rm *HTMLResultsManager) writeResult(res *Result) {
 	// TODO: don't rebuild the template with each row
-	tmpl := `{{define "ROW"}}<tr><td>{{.Code}}</td><td><a href="{{.URL.String}}">{{.URL.String}}</a></td><td>{{if ge .Length 0}}{{.Length}}{{end}}</td><td>{{.ContentType}}</td></tr>{{end}}`
+	tmpl := `{{define "ROW"}}<tr data-tags="{{range $i, $t := .Tags}}{{if $i}} {{end}}{{$t}}{{end}}"><td>{{.Code}}</td><td><a href="{{.URL.String}}">{{.URL.String}}</a>{{if .Screenshot}}<br><a href="{{.Screenshot}}"><img src="{{.Screenshot}}" width="320"></a>{{end}}</td><td>{{if ge .Length 0}}{{.Length}}{{end}}</td><td>{{.ContentType}}</td><td>{{.Confidence}}{{if .Transient}} <i>transient</i>{{end}}</td><td>{{.Interest}}</td><td>{{range $i, $t := .Tags}}{{if $i}}, {{end}}{{$t}}{{end}}</td><td>{{.FoundBy}}</td><td>{{if .Slow}}<b>{{.Duration.Milliseconds}}</b>{{else}}{{.Duration.Milliseconds}}{{end}}</td></tr>{{end}}`
 	t, err := template.New("htmlResultsManager").Parse(tmpl)
 	if err != nil {
 		logging.Logf(logging.LogWarning, "Error parsing a template: %s", err.Error())
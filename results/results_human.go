@@ -0,0 +1,183 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"fmt"
+	ss "github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/util"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Width the URL column is padded to, so most redirect targets line up
+const humanURLWidth = 48
+
+// How much HumanResultsManager prints
+type Verbosity int
+
+const (
+	// Only hits
+	VerbosityQuiet = Verbosity(iota)
+	// Hits and a summary
+	VerbosityNormal
+	// Hits, errors, and a summary
+	VerbosityVerbose
+)
+
+// HumanResultsManager prints aligned, optionally colored, lines for reading
+// on a terminal during a scan.
+type HumanResultsManager struct {
+	baseResultsManager
+	writer    io.Writer
+	fp        *os.File
+	color     bool
+	redirs    bool
+	verbosity Verbosity
+	// Number of hits per status code
+	counts map[int]int
+	errors int
+	// Findings to list after the results
+	findings findingList
+}
+
+func NewHumanResultsManager(writer io.Writer, color, redirs bool, verbosity Verbosity) *HumanResultsManager {
+	return &HumanResultsManager{
+		writer:    writer,
+		color:     color,
+		redirs:    redirs,
+		verbosity: verbosity,
+		counts:    make(map[int]int),
+	}
+}
+
+func (rm *HumanResultsManager) Run(res <-chan *Result) {
+	go func() {
+		rm.start()
+		defer func() {
+			rm.findings.writeText(rm.writer)
+			if rm.verbosity > VerbosityQuiet {
+				rm.writeSummary()
+			}
+			if rm.fp != nil {
+				rm.fp.Close()
+			}
+			rm.done()
+		}()
+
+		for r := range res {
+			rm.writeResult(r)
+			rm.findings.add(r, rm.report)
+		}
+	}()
+}
+
+func (rm *HumanResultsManager) writeResult(r *Result) {
+	if r.Error != nil && !r.Baseline {
+		rm.errors++
+		if rm.verbosity >= VerbosityVerbose {
+			line := fmt.Sprintf("ERR %7s  %s  %s", "", r.URL.String(), r.Error.Error())
+			fmt.Fprintln(rm.writer, rm.colorize(500, line))
+		}
+		return
+	}
+	if !rm.report.Result(r) || (r.Redir != nil && !rm.redirs) {
+		return
+	}
+	rm.counts[r.Code]++
+	line := fmt.Sprintf("%3d %7s  %s", r.Code, humanSize(r.Length), r.URL.String())
+	if r.Redir != nil {
+		line = fmt.Sprintf("%-*s -> %s", 13+humanURLWidth, line, r.Redir.String())
+	} else if len(r.RedirectChain) > 0 {
+		line = fmt.Sprintf("%-*s via %s", 13+humanURLWidth, line, redirectChainString(r.RedirectChain))
+	}
+	fmt.Fprintln(rm.writer, rm.colorize(r.Code, line+annotationString(r)))
+}
+
+func (rm *HumanResultsManager) writeSummary() {
+	codes := make([]int, 0, len(rm.counts))
+	total := 0
+	for code, count := range rm.counts {
+		codes = append(codes, code)
+		total += count
+	}
+	sort.Ints(codes)
+	parts := make([]string, len(codes))
+	for i, code := range codes {
+		parts[i] = rm.colorize(code, fmt.Sprintf("%d: %d", code, rm.counts[code]))
+	}
+	summary := fmt.Sprintf("Found %d results", total)
+	if len(parts) > 0 {
+		summary += " (" + strings.Join(parts, ", ") + ")"
+	}
+	if rm.errors > 0 {
+		summary += fmt.Sprintf(", %d errors", rm.errors)
+	}
+	fmt.Fprintln(rm.writer, summary+".")
+}
+
+func (rm *HumanResultsManager) colorize(code int, s string) string {
+	if !rm.color {
+		return s
+	}
+	if color, ok := statusColors[util.StatusCodeGroup(code)]; ok {
+		return color + s + colorReset
+	}
+	return s
+}
+
+// Format a size compactly, e.g. 1.5K.  Negative sizes are unknown.
+func humanSize(n int64) string {
+	if n < 0 {
+		return "-"
+	}
+	if n < 1024 {
+		return fmt.Sprintf("%dB", n)
+	}
+	size := float64(n)
+	for _, unit := range []string{"K", "M", "G"} {
+		size /= 1024
+		if size < 1024 || unit == "G" {
+			return fmt.Sprintf("%.1f%s", size, unit)
+		}
+	}
+	return ""
+}
+
+// Decide whether to color output written to w.
+func UseColor(mode ss.ColorModeOption, w io.Writer) bool {
+	switch mode {
+	case ss.ColorAlways:
+		return true
+	case ss.ColorNever:
+		return false
+	}
+	return isTerminal(w)
+}
+
+// Check if w is a terminal, so colors can be used.
+func isTerminal(w io.Writer) bool {
+	fp, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := fp.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,94 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func runHumanResultsManager(color bool, verbosity Verbosity) string {
+	buf := &bytes.Buffer{}
+	mgr := NewHumanResultsManager(buf, color, true, verbosity)
+	rchan := make(chan *Result)
+	mgr.Run(rchan)
+	for _, r := range makeTestResults() {
+		rchan <- r
+	}
+	rchan <- &Result{
+		URL:   &url.URL{Scheme: "http", Host: "localhost", Path: "/err"},
+		Error: errors.New("connection reset"),
+	}
+	close(rchan)
+	mgr.Wait()
+	return buf.String()
+}
+
+func TestHumanResultsManager(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(runHumanResultsManager(false, VerbosityNormal)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %q", lines)
+	}
+	if !strings.HasPrefix(lines[0], "200      0B  http://localhost/") {
+		t.Errorf("Unexpected hit line: %q", lines[0])
+	}
+	if !strings.Contains(lines[1], strings.Repeat(" ", 10)+" -> https://localhost/.git") {
+		t.Errorf("Expected aligned redirect target, got %q", lines[1])
+	}
+	if lines[2] != "Found 2 results (200: 1, 301: 1), 1 errors." {
+		t.Errorf("Unexpected summary: %q", lines[2])
+	}
+}
+
+func TestHumanResultsManager_Verbosity(t *testing.T) {
+	quiet := runHumanResultsManager(false, VerbosityQuiet)
+	if strings.Count(quiet, "\n") != 2 || strings.Contains(quiet, "Found") {
+		t.Errorf("Expected only hits when quiet, got %q", quiet)
+	}
+	verbose := runHumanResultsManager(false, VerbosityVerbose)
+	if !strings.Contains(verbose, "http://localhost/err  connection reset") {
+		t.Errorf("Expected error when verbose, got %q", verbose)
+	}
+}
+
+func TestHumanResultsManager_Color(t *testing.T) {
+	out := runHumanResultsManager(true, VerbosityNormal)
+	if !strings.HasPrefix(out, statusColors[200]+"200") {
+		t.Errorf("Expected colored output, got %q", out)
+	}
+	if !strings.Contains(out, colorReset) {
+		t.Errorf("Expected color reset, got %q", out)
+	}
+}
+
+func TestHumanSize(t *testing.T) {
+	cases := map[int64]string{
+		-1:                 "-",
+		0:                  "0B",
+		1023:               "1023B",
+		1536:               "1.5K",
+		5 * 1024 * 1024:    "5.0M",
+		3 << 40:            "3072.0G",
+		1024 * 1024 * 1024: "1.0G",
+	}
+	for n, expected := range cases {
+		if got := humanSize(n); got != expected {
+			t.Errorf("humanSize(%d): expected %s, got %s", n, expected, got)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+)
+
+// JSONResult is the record written for each result by the JSON output.
+type JSONResult struct {
+	URL           string   `json:"url"`
+	Host          string   `json:"host,omitempty"`
+	ForwardedHost string   `json:"forwarded_host,omitempty"`
+	ForwardedFor  string   `json:"forwarded_for,omitempty"`
+	Code          int      `json:"code"`
+	Length        int64    `json:"length"`
+	ContentType   string   `json:"content_type,omitempty"`
+	Redirect      string   `json:"redirect,omitempty"`
+	Referrer      string   `json:"referrer,omitempty"`
+	Provenance    string   `json:"provenance,omitempty"`
+	Parent        string   `json:"parent,omitempty"`
+	Depth         int      `json:"depth,omitempty"`
+	Confidence    string   `json:"confidence,omitempty"`
+	Interest      int      `json:"interest,omitempty"`
+	FaviconHash   int32    `json:"favicon_hash,omitempty"`
+	ConnectMS     int64    `json:"connect_ms,omitempty"`
+	TTFBMS        int64    `json:"ttfb_ms,omitempty"`
+	DurationMS    int64    `json:"duration_ms,omitempty"`
+	Slow          bool     `json:"slow,omitempty"`
+	Transient     bool     `json:"transient,omitempty"`
+	Evidence      string   `json:"evidence,omitempty"`
+	BodyFile      string   `json:"body_file,omitempty"`
+	ETag          string   `json:"etag,omitempty"`
+	LastModified  string   `json:"last_modified,omitempty"`
+	Change        string   `json:"change,omitempty"`
+	Notes         []string `json:"notes,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+}
+
+// Build the JSON record for a result.
+func NewJSONResult(r *Result) *JSONResult {
+	return &JSONResult{
+		URL:           r.URL.String(),
+		Host:          r.Host,
+		ForwardedHost: r.RequestHeader.Get("X-Forwarded-Host"),
+		ForwardedFor:  r.RequestHeader.Get("X-Forwarded-For"),
+		Code:          r.Code,
+		Length:        r.Length,
+		ContentType:   r.ContentType,
+		Redirect:      maybeStringURL(r.Redir),
+		Referrer:      maybeStringURL(r.Referrer),
+		Provenance:    r.FoundBy(),
+		Parent:        maybeStringURL(r.ParentURL),
+		Depth:         r.Depth,
+		Confidence:    r.Confidence.String(),
+		Interest:      r.Interest,
+		FaviconHash:   r.FaviconHash,
+		ConnectMS:     r.ConnectTime.Milliseconds(),
+		TTFBMS:        r.TTFB.Milliseconds(),
+		DurationMS:    r.Duration.Milliseconds(),
+		Slow:          r.Slow,
+		Transient:     r.Transient,
+		Evidence:      r.Evidence,
+		BodyFile:      r.BodyFile,
+		ETag:          r.ETag,
+		LastModified:  r.LastModified,
+		Change:        r.Change,
+		Notes:         r.Notes,
+		Tags:          r.Tags,
+	}
+}
+
+// A line of JSON output holding a finding rather than a result
+type jsonFindingRecord struct {
+	Finding *JSONFinding `json:"finding"`
+}
+
+// JSONResultsManager writes one JSON object per line for each result,
+// followed by one for each finding.  The output can be given to -compare in a
+// later scan.
+type JSONResultsManager struct {
+	baseResultsManager
+	enc      *json.Encoder
+	fp       *os.File
+	findings findingList
+}
+
+func NewJSONResultsManager(w io.Writer) *JSONResultsManager {
+	return &JSONResultsManager{enc: json.NewEncoder(w)}
+}
+
+func (rm *JSONResultsManager) Run(res <-chan *Result) {
+	go func() {
+		rm.start()
+		defer func() {
+			for _, f := range rm.findings.jsonFindings() {
+				rm.enc.Encode(&jsonFindingRecord{Finding: f})
+			}
+			if rm.fp != nil {
+				rm.fp.Close()
+			}
+			rm.done()
+		}()
+
+		for r := range res {
+			if !rm.report.Result(r) {
+				continue
+			}
+			rm.enc.Encode(NewJSONResult(r))
+			rm.findings.add(r, rm.report)
+		}
+	}()
+}
@@ -17,7 +17,10 @@ package results
 import (
 	"fmt"
 	"io"
+	"net/url"
 	"os"
+	"strings"
+	"time"
 )
 
 // PlainResultsManager is designed to output a very basic output that is good
@@ -25,15 +28,17 @@ import (
 // output and provides a decent way to review results on-screen.
 type PlainResultsManager struct {
 	baseResultsManager
-	writer io.Writer
-	fp     *os.File
-	redirs bool
+	writer   io.Writer
+	fp       *os.File
+	redirs   bool
+	findings findingList
 }
 
 func (rm *PlainResultsManager) Run(res <-chan *Result) {
 	go func() {
 		rm.start()
 		defer func() {
+			rm.findings.writeText(rm.writer)
 			if rm.fp != nil {
 				rm.fp.Close()
 			}
@@ -41,18 +46,71 @@ func (rm *PlainResultsManager) Run(res <-chan *Result) {
 		}()
 
 		for r := range res {
-			if !ReportResult(r) {
-				continue
-			}
-			if r.Redir == nil {
-				if r.Length >= 0 {
-					fmt.Fprintf(rm.writer, "%d %s (%d bytes)\n", r.Code, r.URL.String(), r.Length)
-				} else {
-					fmt.Fprintf(rm.writer, "%d %s\n", r.Code, r.URL.String())
-				}
-			} else if rm.redirs {
-				fmt.Fprintf(rm.writer, "%d %s -> %s\n", r.Code, r.URL.String(), r.Redir.String())
+			if line, ok := plainLine(r, rm.report, rm.redirs); ok {
+				fmt.Fprintln(rm.writer, line)
 			}
+			rm.findings.add(r, rm.report)
 		}
 	}()
 }
+
+// Format a result as a line of plain output.  Returns false if the result
+// should not be shown.
+func plainLine(r *Result, codes ReportCodes, redirs bool) (string, bool) {
+	if !codes.Result(r) {
+		return "", false
+	}
+	if r.Redir == nil {
+		var via string
+		if len(r.RedirectChain) > 0 {
+			via = fmt.Sprintf(" (via %s)", redirectChainString(r.RedirectChain))
+		}
+		via += annotationString(r)
+		if r.Length >= 0 {
+			return fmt.Sprintf("%d %s (%d bytes)%s", r.Code, r.URL.String(), r.Length, via), true
+		}
+		return fmt.Sprintf("%d %s%s", r.Code, r.URL.String(), via), true
+	} else if redirs {
+		return fmt.Sprintf("%d %s -> %s%s", r.Code, r.URL.String(), r.Redir.String(), annotationString(r)), true
+	}
+	return "", false
+}
+
+// Format a chain of redirects for display
+func redirectChainString(chain []*url.URL) string {
+	hops := make([]string, len(chain))
+	for i, u := range chain {
+		hops[i] = u.String()
+	}
+	return strings.Join(hops, " -> ")
+}
+
+// Format grouping and notes for display
+func annotationString(r *Result) string {
+	var s string
+	if r.GroupCount > 1 {
+		s += fmt.Sprintf(" [%d similar]", r.GroupCount)
+	}
+	if len(r.Notes) > 0 {
+		s += fmt.Sprintf(" [%s]", strings.Join(r.Notes, "; "))
+	}
+	if len(r.Tags) > 0 {
+		s += fmt.Sprintf(" [tags: %s]", strings.Join(r.Tags, ", "))
+	}
+	if r.Confidence != ConfidenceUnknown && r.Confidence != ConfidenceStatus {
+		s += fmt.Sprintf(" [confidence: %s]", r.Confidence)
+	}
+	if r.FaviconHash != 0 {
+		s += fmt.Sprintf(" [favicon hash: %d]", r.FaviconHash)
+	}
+	if r.Interest >= InterestHigh {
+		s += fmt.Sprintf(" [interest: %d]", r.Interest)
+	}
+	if r.Slow {
+		s += fmt.Sprintf(" [slow: %s]", r.Duration.Round(time.Millisecond))
+	}
+	if r.Transient {
+		s += " [transient]"
+	}
+	return s
+}
@@ -16,6 +16,7 @@ package results
 
 import (
 	"bytes"
+	"net/url"
 	"strings"
 	"testing"
 )
@@ -39,3 +40,20 @@ func TestPlainResultsManager_Basic(t *testing.T) {
 		t.Fatalf("Expected 3 lines of output, got %d", len(lines))
 	}
 }
+
+func TestPlainResultsManager_RedirectChain(t *testing.T) {
+	buf := bytes.Buffer{}
+	mgr := &PlainResultsManager{writer: &buf}
+	rchan := make(chan *Result)
+	mgr.Run(rchan)
+	rchan <- &Result{
+		URL:           &url.URL{Scheme: "http", Host: "localhost", Path: "/a"},
+		Code:          200,
+		RedirectChain: []*url.URL{&url.URL{Scheme: "https", Host: "www.localhost", Path: "/a"}},
+	}
+	close(rchan)
+	mgr.Wait()
+	if !strings.Contains(buf.String(), "(via https://www.localhost/a)") {
+		t.Errorf("Expected redirect chain in output, got %q", buf.String())
+	}
+}
@@ -17,6 +17,7 @@ package results
 import (
 	"github.com/Matir/webborer/settings"
 	"net/url"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -68,13 +69,48 @@ func TestFoundSomething(t *testing.T) {
 	}
 }
 
-func TestReportResult(t *testing.T) {
+func TestReportCodes_Result(t *testing.T) {
 	r := &Result{Code: 200}
-	if !ReportResult(r) {
+	if !(ReportCodes{}).Result(r) {
 		t.Error("Expected to report a result of 200.")
 	}
 }
 
+func TestReportCodes_Code(t *testing.T) {
+	var codes ReportCodes
+	if !codes.Code(200) || codes.Code(404) {
+		t.Error("Expected defaults to follow FoundSomething.")
+	}
+	codes = ReportCodes{Exclude: settings.CodeRangeFlag{{Min: 401, Max: 403}}}
+	if codes.Code(403) || !codes.Code(200) {
+		t.Error("Expected 403 excluded and 200 reported.")
+	}
+	codes = ReportCodes{
+		Include: settings.CodeRangeFlag{{Min: 200, Max: 299}, {Min: 404, Max: 404}},
+		Exclude: settings.CodeRangeFlag{{Min: 204, Max: 204}},
+	}
+	for code, want := range map[int]bool{200: true, 204: false, 301: false, 404: true, 500: false} {
+		if got := codes.Code(code); got != want {
+			t.Errorf("Code(%d) = %v, expected %v", code, got, want)
+		}
+	}
+}
+
+func TestGetResultsManager_ReportCodes(t *testing.T) {
+	s := &settings.ScanSettings{
+		OutputFormat: "text",
+		OutputPath:   filepath.Join(t.TempDir(), "out.txt"),
+		IncludeCodes: settings.CodeRangeFlag{{Min: 404, Max: 404}},
+	}
+	rm, err := GetResultsManager(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain := rm.(*PlainResultsManager); !plain.report.Code(404) {
+		t.Error("Expected the manager to report the included code.")
+	}
+}
+
 func TestBaseFunctions(_ *testing.T) {
 	brm := &baseResultsManager{}
 	brm.start()
@@ -0,0 +1,181 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// TreeResultsManager writes the paths found on each host as an indented tree
+// once the scan is finished, to show the structure of deep sites better than a
+// flat list.
+type TreeResultsManager struct {
+	baseResultsManager
+	writer   io.Writer
+	fp       *os.File
+	redirs   bool
+	findings findingList
+}
+
+// A path in the tree, and the result for it if one was reported
+type treeNode struct {
+	name     string
+	result   *Result
+	children map[string]*treeNode
+}
+
+func newTreeNode(name string) *treeNode {
+	return &treeNode{name: name, children: make(map[string]*treeNode)}
+}
+
+func (rm *TreeResultsManager) Run(res <-chan *Result) {
+	rm.start()
+	go func() {
+		defer func() {
+			if rm.fp != nil {
+				rm.fp.Close()
+			}
+			rm.done()
+		}()
+
+		roots := make(map[string]*treeNode)
+		for r := range res {
+			rm.findings.add(r, rm.report)
+			if !rm.report.Result(r) || (r.Redir != nil && !rm.redirs) {
+				continue
+			}
+			key := treeHost(r)
+			root, ok := roots[key]
+			if !ok {
+				root = newTreeNode(key)
+				roots[key] = root
+			}
+			root.insert(r)
+		}
+		for _, key := range sortedTreeKeys(roots) {
+			root := roots[key]
+			fmt.Fprintf(rm.writer, "%s%s\n", root.name, treeLabel(root.result))
+			root.write(rm.writer, "")
+		}
+		rm.findings.writeText(rm.writer)
+	}()
+}
+
+// Name of the tree a result belongs in: its scheme and host, and the virtual
+// host it was requested with, if any.
+func treeHost(r *Result) string {
+	name := r.URL.Scheme + "://" + r.URL.Host
+	if r.Host != "" {
+		name += fmt.Sprintf(" (%s)", r.Host)
+	}
+	return name
+}
+
+// Add a result at its path, creating the directories above it as needed.
+func (n *treeNode) insert(r *Result) {
+	segments := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	node := n
+	for i, seg := range segments {
+		name := seg
+		if i < len(segments)-1 {
+			name += "/"
+		} else if r.URL.RawQuery != "" {
+			name += "?" + r.URL.RawQuery
+		}
+		if name == "" {
+			break
+		}
+		child, ok := node.children[name]
+		if !ok {
+			child = newTreeNode(name)
+			node.children[name] = child
+		}
+		node = child
+	}
+	node.result = r
+}
+
+// Write the children of a node, each line starting with prefix.
+func (n *treeNode) write(w io.Writer, prefix string) {
+	names := sortedTreeKeys(n.children)
+	for i, name := range names {
+		child := n.children[name]
+		branch, indent := "├── ", "│   "
+		if i == len(names)-1 {
+			branch, indent = "└── ", "    "
+		}
+		lines := strings.Split(child.name+treeLabel(child.result), "\n")
+		fmt.Fprintf(w, "%s%s%s\n", prefix, branch, lines[0])
+		for _, line := range lines[1:] {
+			fmt.Fprintf(w, "%s%s%s\n", prefix, indent, strings.TrimPrefix(line, "\t"))
+		}
+		child.write(w, prefix+indent)
+	}
+}
+
+// Describe the result for a node: its code, size or redirect, where it was
+// found from if that isn't the directory above it, and any annotations.
+func treeLabel(r *Result) string {
+	if r == nil {
+		return ""
+	}
+	var s string
+	if r.Redir != nil {
+		s = fmt.Sprintf(" [%d -> %s]", r.Code, r.Redir.String())
+	} else if r.Length >= 0 {
+		s = fmt.Sprintf(" [%d, %d bytes]", r.Code, r.Length)
+	} else {
+		s = fmt.Sprintf(" [%d]", r.Code)
+	}
+	if from := foundFrom(r); from != "" {
+		s += fmt.Sprintf(" (from %s)", from)
+	}
+	return s + annotationString(r)
+}
+
+// Where a result was found from, if not the directory above it in the tree.
+func foundFrom(r *Result) string {
+	p := r.ParentURL
+	if p == nil {
+		return ""
+	}
+	dir := r.URL.Path
+	if strings.HasSuffix(dir, "/") {
+		dir = dir[:len(dir)-1]
+	}
+	dir = dir[:strings.LastIndex(dir, "/")+1]
+	if p.Scheme != r.URL.Scheme || p.Host != r.URL.Host {
+		return p.String()
+	}
+	if p.Path == dir && p.RawQuery == "" {
+		return ""
+	}
+	rel := url.URL{Path: p.Path, RawQuery: p.RawQuery}
+	return rel.String()
+}
+
+func sortedTreeKeys(m map[string]*treeNode) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,71 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"bytes"
+	"net/url"
+	"testing"
+)
+
+func TestTreeResultsManager(t *testing.T) {
+	result := func(path string, code int, length int64, parent string) *Result {
+		r := NewResult(&url.URL{Scheme: "http", Host: "localhost", Path: path}, "")
+		r.Code = code
+		r.Length = length
+		if parent != "" {
+			r.ParentURL = &url.URL{Scheme: "http", Host: "localhost", Path: parent}
+		}
+		return r
+	}
+	redirect := result("/old", 301, 0, "/")
+	redirect.Redir = &url.URL{Scheme: "http", Host: "localhost", Path: "/new"}
+	missing := result("/missing", 404, 0, "/")
+	other := NewResult(&url.URL{Scheme: "https", Host: "example.com", Path: "/x"}, "")
+	other.Code = 200
+	other.Length = -1
+
+	buf := &bytes.Buffer{}
+	rm := &TreeResultsManager{writer: buf, redirs: true}
+	ch := make(chan *Result, 10)
+	for _, r := range []*Result{
+		result("/", 200, 10, ""),
+		result("/admin/users/list", 200, 30, "/admin/users/"),
+		result("/admin/", 403, 0, "/"),
+		result("/about", 200, 20, "/"),
+		result("/admin/config.php", 200, 40, "/index.html"),
+		redirect,
+		missing,
+		other,
+	} {
+		ch <- r
+	}
+	close(ch)
+	rm.Run(ch)
+	rm.Wait()
+	expected := `http://localhost [200, 10 bytes]
+├── about [200, 20 bytes]
+├── admin/ [403, 0 bytes]
+│   ├── config.php [200, 40 bytes] (from /index.html)
+│   └── users/
+│       └── list [200, 30 bytes]
+└── old [301 -> http://localhost/new]
+https://example.com
+└── x [200]
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// Weight given to each severity when computing the exposure score.
+var SeverityWeights = map[Severity]int{
+	SeverityInfo:   0,
+	SeverityLow:    1,
+	SeverityMedium: 5,
+	SeverityHigh:   20,
+}
+
+// HostScore summarizes the findings for a single host.
+type HostScore struct {
+	Host string `json:"host"`
+	// Severity-weighted sum of findings
+	Score int `json:"score"`
+	// Number of findings at each severity
+	Findings map[string]int `json:"findings"`
+	// Number of reported results
+	Results int `json:"results"`
+	// When the score was computed
+	Time time.Time `json:"time"`
+}
+
+// Scorer computes an exposure score per host from the findings on results
+// passing through it.
+type Scorer struct {
+	scores map[string]*HostScore
+	// Status codes the scan reports
+	report ReportCodes
+}
+
+func (s *Scorer) SetReportCodes(codes ReportCodes) {
+	s.report = codes
+}
+
+func NewScorer() *Scorer {
+	return &Scorer{
+		scores: make(map[string]*HostScore),
+	}
+}
+
+func (s *Scorer) Process(in <-chan *Result) <-chan *Result {
+	out := make(chan *Result, cap(in))
+	go func() {
+		defer close(out)
+		for r := range in {
+			s.Add(r)
+			out <- r
+		}
+	}()
+	return out
+}
+
+// Add the findings from a result to the score for its host.
+func (s *Scorer) Add(r *Result) {
+	if !s.report.Result(r) || r.URL == nil {
+		return
+	}
+	host := r.URL.Host
+	score, ok := s.scores[host]
+	if !ok {
+		score = &HostScore{
+			Host:     host,
+			Findings: make(map[string]int),
+		}
+		s.scores[host] = score
+	}
+	score.Results++
+	for _, f := range r.Findings {
+		score.Score += SeverityWeights[f.Severity]
+		score.Findings[f.Severity.String()]++
+	}
+}
+
+// Get the scores for all hosts, highest score first.  Must not be called until
+// Process has finished.
+func (s *Scorer) Scores() []*HostScore {
+	now := time.Now()
+	scores := make([]*HostScore, 0, len(s.scores))
+	for _, score := range s.scores {
+		score.Time = now
+		scores = append(scores, score)
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].Host < scores[j].Host
+	})
+	return scores
+}
+
+// Write a human-readable summary of the scores.
+func (s *Scorer) WriteSummary(w io.Writer) error {
+	for _, score := range s.Scores() {
+		if _, err := fmt.Fprintf(w, "%s: score %d (%d results", score.Host, score.Score, score.Results); err != nil {
+			return err
+		}
+		for sev := SeverityHigh; sev >= SeverityInfo; sev-- {
+			if n := score.Findings[sev.String()]; n > 0 {
+				fmt.Fprintf(w, ", %d %s", n, sev.String())
+			}
+		}
+		if _, err := fmt.Fprintf(w, ")\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Append the scores as JSON lines to a file, so that scores from recurring
+// scans can be compared over time.
+func (s *Scorer) AppendToFile(filename string) error {
+	fp, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	enc := json.NewEncoder(fp)
+	for _, score := range s.Scores() {
+		if err := enc.Encode(score); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func scoredResult(host string, sevs ...Severity) *Result {
+	r := &Result{URL: &url.URL{Scheme: "http", Host: host, Path: "/"}, Code: 200}
+	for _, s := range sevs {
+		r.AddFinding("test", s, "finding")
+	}
+	return r
+}
+
+func TestScorer(t *testing.T) {
+	in := make(chan *Result, 10)
+	in <- scoredResult("a", SeverityLow, SeverityHigh)
+	in <- scoredResult("a", SeverityMedium)
+	in <- scoredResult("b", SeverityInfo)
+	notFound := scoredResult("b", SeverityHigh)
+	notFound.Code = 404
+	in <- notFound
+	close(in)
+	scorer := NewScorer()
+	for range scorer.Process(in) {
+	}
+	scores := scorer.Scores()
+	if len(scores) != 2 {
+		t.Fatalf("Expected 2 scores, got %d", len(scores))
+	}
+	if scores[0].Host != "a" || scores[0].Score != 26 || scores[0].Results != 2 {
+		t.Errorf("Unexpected score for a: %+v", scores[0])
+	}
+	if scores[1].Host != "b" || scores[1].Score != 0 || scores[1].Findings["info"] != 1 {
+		t.Errorf("Unexpected score for b: %+v", scores[1])
+	}
+	buf := &bytes.Buffer{}
+	if err := scorer.WriteSummary(buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "a: score 26 (2 results, 1 high, 1 medium, 1 low)\nb: score 0 (1 results, 1 info)\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected summary: %q", buf.String())
+	}
+}
+
+func TestScorer_AppendToFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webborer")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "scores.json")
+	scorer := NewScorer()
+	scorer.Add(scoredResult("a", SeverityMedium))
+	for i := 0; i < 2; i++ {
+		if err := scorer.AppendToFile(path); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	fp, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Unable to open scores: %v", err)
+	}
+	defer fp.Close()
+	lines := 0
+	scanner := bufio.NewScanner(fp)
+	for scanner.Scan() {
+		lines++
+		score := &HostScore{}
+		if err := json.Unmarshal(scanner.Bytes(), score); err != nil {
+			t.Fatalf("Unable to parse score: %v", err)
+		}
+		if score.Host != "a" || score.Score != 5 {
+			t.Errorf("Unexpected score: %+v", score)
+		}
+	}
+	if lines != 2 {
+		t.Errorf("Expected 2 lines, got %d", lines)
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"github.com/Matir/webborer/logging"
+	ss "github.com/Matir/webborer/settings"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// File extensions for each output format, where it isn't the format's name
+var formatExtensions = map[string]string{
+	"text":  "txt",
+	"human": "txt",
+	"tree":  "txt",
+	"diff":  "txt",
+}
+
+var unsafeHostChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// SplitResultsManager writes a separate report for each host, in the same
+// format as the combined report, as well as passing every result on to the
+// combined report.
+type SplitResultsManager struct {
+	baseResultsManager
+	combined ResultsManager
+	settings *ss.ScanSettings
+	date     string
+	// Channels to each host's report
+	hosts map[string]chan *Result
+	// Reports started, to wait for at the end
+	managers []ResultsManager
+}
+
+// Create a SplitResultsManager that writes reports to settings.SplitDir, and
+// passes all results on to combined.
+func NewSplitResultsManager(settings *ss.ScanSettings, combined ResultsManager) (*SplitResultsManager, error) {
+	if err := os.MkdirAll(settings.SplitDir, 0755); err != nil {
+		return nil, err
+	}
+	return &SplitResultsManager{
+		combined: combined,
+		settings: settings,
+		date:     time.Now().Format("2006-01-02"),
+		hosts:    make(map[string]chan *Result),
+	}, nil
+}
+
+// Set the codes reported by the combined report and each host's report.
+func (rm *SplitResultsManager) SetReportCodes(codes ReportCodes) {
+	rm.report = codes
+	SetReportCodes(rm.combined, codes)
+}
+
+func (rm *SplitResultsManager) Run(res <-chan *Result) {
+	rm.start()
+	all := make(chan *Result, cap(res))
+	rm.combined.Run(all)
+	go func() {
+		defer func() {
+			close(all)
+			for _, c := range rm.hosts {
+				if c != nil {
+					close(c)
+				}
+			}
+			rm.combined.Wait()
+			for _, m := range rm.managers {
+				m.Wait()
+			}
+			rm.done()
+		}()
+
+		for r := range res {
+			all <- r
+			if c := rm.hostChannel(r.URL); c != nil {
+				c <- r
+			}
+		}
+	}()
+}
+
+// Get the channel to the report for the host of u, starting the report if
+// needed.  Returns nil if the report couldn't be started.
+func (rm *SplitResultsManager) hostChannel(u *url.URL) chan *Result {
+	host := strings.ToLower(u.Host)
+	if c, ok := rm.hosts[host]; ok {
+		return c
+	}
+	// Don't try again if this fails
+	rm.hosts[host] = nil
+	settings := *rm.settings
+	settings.BaseURLs = ss.StringSliceFlag{u.Scheme + "://" + u.Host + "/"}
+	path := filepath.Join(rm.settings.SplitDir, rm.fileName(host))
+	fp, err := os.Create(path)
+	if err != nil {
+		logging.Logf(logging.LogWarning, "Unable to create report for %s: %s", host, err.Error())
+		return nil
+	}
+	m, err := newResultsManager(&settings, fp, fp)
+	if err != nil {
+		fp.Close()
+		logging.Logf(logging.LogWarning, "Unable to create report for %s: %s", host, err.Error())
+		return nil
+	}
+	SetReportCodes(m, rm.report)
+	logging.Logf(logging.LogInfo, "Writing results for %s to %s", host, path)
+	c := make(chan *Result, rm.settings.QueueSize)
+	m.Run(c)
+	rm.hosts[host] = c
+	rm.managers = append(rm.managers, m)
+	return c
+}
+
+// Fill in the name template for a host's report.
+func (rm *SplitResultsManager) fileName(host string) string {
+	format := rm.settings.OutputFormat
+	ext, ok := formatExtensions[format]
+	if !ok {
+		ext = format
+	}
+	return strings.NewReplacer(
+		"{host}", unsafeHostChars.ReplaceAllString(host, "_"),
+		"{date}", rm.date,
+		"{format}", format,
+		"{ext}", ext,
+	).Replace(rm.settings.SplitName)
+}
@@ -0,0 +1,67 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"bytes"
+	ss "github.com/Matir/webborer/settings"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitResultsManager(t *testing.T) {
+	dir, err := ioutil.TempDir("", "split")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	settings := &ss.ScanSettings{OutputFormat: "json", SplitDir: dir, SplitName: "{host}_{format}.{ext}"}
+	buf := &bytes.Buffer{}
+	rm, err := NewSplitResultsManager(settings, NewJSONResultsManager(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := make(chan *Result)
+	rm.Run(res)
+	for _, u := range []string{"http://a.example/x", "http://B.example:8080/y", "http://a.example/z"} {
+		parsed, _ := url.Parse(u)
+		r := NewResult(parsed, "")
+		r.Code = 200
+		res <- r
+	}
+	close(res)
+	rm.Wait()
+	if lines := strings.Count(buf.String(), "\n"); lines != 3 {
+		t.Errorf("Expected 3 results in the combined report, got %d", lines)
+	}
+	expected := map[string]int{
+		"a.example_json.json":      2,
+		"b.example_8080_json.json": 1,
+	}
+	for name, count := range expected {
+		report, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("Expected report %s: %s", name, err)
+			continue
+		}
+		if lines := strings.Count(string(report), "\n"); lines != count {
+			t.Errorf("Expected %d results in %s, got %d", count, name, lines)
+		}
+	}
+}
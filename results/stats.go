@@ -0,0 +1,216 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Number of content types listed in the summary
+const topContentTypes = 5
+
+// ScanStats rolls up every response seen during a scan.
+type ScanStats struct {
+	Start time.Time `json:"start"`
+	// Seconds from Start until Finish
+	Duration float64 `json:"duration"`
+	// Number of requests made, including calibration
+	Requests int `json:"requests"`
+	// Requests per second
+	Rate float64 `json:"requests_per_second"`
+	// Number of responses per status class, e.g. "2xx"
+	StatusClasses map[string]int `json:"status_classes"`
+	// Number of failed requests per kind of error
+	Errors map[string]int `json:"errors"`
+	// The most common content types, most common first
+	ContentTypes []ContentTypeCount `json:"content_types"`
+	// Number of reported results per target
+	Hits map[string]int `json:"hits"`
+	// Percentiles of the time spent in each stage of the scan, if measured
+	Stages []StageTiming `json:"stages,omitempty"`
+
+	contentTypes map[string]int
+	// Status codes the scan reports
+	report ReportCodes
+}
+
+func (s *ScanStats) SetReportCodes(codes ReportCodes) {
+	s.report = codes
+}
+
+type ContentTypeCount struct {
+	Type  string `json:"type"`
+	Count int    `json:"count"`
+}
+
+// How long one stage of the scan took for each task, in milliseconds
+type StageTiming struct {
+	Stage string  `json:"stage"`
+	Count int64   `json:"count"`
+	P50   float64 `json:"p50_ms"`
+	P90   float64 `json:"p90_ms"`
+	P95   float64 `json:"p95_ms"`
+	P99   float64 `json:"p99_ms"`
+}
+
+func NewScanStats() *ScanStats {
+	return &ScanStats{
+		Start:         time.Now(),
+		StatusClasses: make(map[string]int),
+		Errors:        make(map[string]int),
+		Hits:          make(map[string]int),
+		contentTypes:  make(map[string]int),
+	}
+}
+
+// Count a result.  Results should be added as they are found, before stages
+// that group or drop them.
+func (s *ScanStats) Add(r *Result) {
+	s.Requests++
+	if r.Error != nil {
+		s.Errors[errorKind(r.Error)]++
+		return
+	}
+	if r.Code != 0 {
+		s.StatusClasses[fmt.Sprintf("%dxx", r.Code/100)]++
+	}
+	if r.ContentType != "" {
+		ctype := strings.TrimSpace(strings.SplitN(r.ContentType, ";", 2)[0])
+		s.contentTypes[strings.ToLower(ctype)]++
+	}
+	if s.report.Result(r) && r.URL != nil {
+		s.Hits[r.URL.Scheme+"://"+r.URL.Host]++
+	}
+}
+
+// Mark the scan as finished, computing the duration and rates.
+func (s *ScanStats) Finish() {
+	duration := time.Since(s.Start)
+	s.Duration = duration.Seconds()
+	if s.Duration > 0 {
+		s.Rate = float64(s.Requests) / s.Duration
+	}
+	s.ContentTypes = make([]ContentTypeCount, 0, len(s.contentTypes))
+	for ctype, count := range s.contentTypes {
+		s.ContentTypes = append(s.ContentTypes, ContentTypeCount{Type: ctype, Count: count})
+	}
+	sort.Slice(s.ContentTypes, func(i, j int) bool {
+		if s.ContentTypes[i].Count != s.ContentTypes[j].Count {
+			return s.ContentTypes[i].Count > s.ContentTypes[j].Count
+		}
+		return s.ContentTypes[i].Type < s.ContentTypes[j].Type
+	})
+	if len(s.ContentTypes) > topContentTypes {
+		s.ContentTypes = s.ContentTypes[:topContentTypes]
+	}
+}
+
+// Write a human-readable summary.  Must not be called before Finish.
+func (s *ScanStats) WriteSummary(w io.Writer) error {
+	fmt.Fprintf(w, "Requests: %d in %s (%.1f/s)\n", s.Requests, time.Duration(s.Duration*float64(time.Second)).Round(time.Millisecond), s.Rate)
+	if len(s.StatusClasses) > 0 {
+		fmt.Fprintf(w, "Status codes: %s\n", countString(s.StatusClasses))
+	}
+	if len(s.Errors) > 0 {
+		fmt.Fprintf(w, "Errors: %s\n", countString(s.Errors))
+	}
+	if len(s.ContentTypes) > 0 {
+		types := make([]string, len(s.ContentTypes))
+		for i, c := range s.ContentTypes {
+			types[i] = fmt.Sprintf("%s: %d", c.Type, c.Count)
+		}
+		fmt.Fprintf(w, "Content types: %s\n", strings.Join(types, ", "))
+	}
+	targets := make([]string, 0, len(s.Hits))
+	for target := range s.Hits {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	for _, target := range targets {
+		fmt.Fprintf(w, "Hits for %s: %d\n", target, s.Hits[target])
+	}
+	_, err := fmt.Fprintf(w, "Total hits: %d\n", s.totalHits())
+	if len(s.Stages) > 0 {
+		fmt.Fprintf(w, "Stage timings (p50/p90/p95/p99 ms):\n")
+		for _, st := range s.Stages {
+			_, err = fmt.Fprintf(w, "  %-26s %.3f/%.3f/%.3f/%.3f over %d\n", st.Stage+":", st.P50, st.P90, st.P95, st.P99, st.Count)
+		}
+	}
+	return err
+}
+
+// Write the stats as JSON to a file.  Must not be called before Finish.
+func (s *ScanStats) WriteToFile(filename string) error {
+	fp, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	enc := json.NewEncoder(fp)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+func (s *ScanStats) totalHits() int {
+	total := 0
+	for _, n := range s.Hits {
+		total += n
+	}
+	return total
+}
+
+// Format counts sorted by key, e.g. "2xx: 5, 4xx: 100"
+func countString(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s: %d", k, counts[k])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Classify an error for the error breakdown.
+func errorKind(err error) string {
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return "timeout"
+	}
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "timeout") || strings.Contains(msg, "deadline exceeded"):
+		return "timeout"
+	case strings.Contains(msg, "connection refused"):
+		return "connection refused"
+	case strings.Contains(msg, "connection reset") || strings.Contains(msg, "broken pipe") || strings.Contains(msg, "eof"):
+		return "connection reset"
+	case strings.Contains(msg, "no such host") || strings.Contains(msg, "lookup "):
+		return "dns"
+	case strings.Contains(msg, "tls") || strings.Contains(msg, "x509") || strings.Contains(msg, "certificate"):
+		return "tls"
+	case strings.Contains(msg, "redirect"):
+		return "redirect"
+	}
+	return "other"
+}
@@ -0,0 +1,106 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testStats() *ScanStats {
+	stats := NewScanStats()
+	for _, r := range makeTestResults() {
+		stats.Add(r)
+	}
+	other := NewResult(&url.URL{Scheme: "https", Host: "example.com", Path: "/a"}, "")
+	other.Code = 200
+	other.ContentType = "text/html; charset=utf-8"
+	stats.Add(other)
+	stats.Add(&Result{URL: &url.URL{Path: "/err"}, Error: errors.New("dial tcp: connection refused")})
+	stats.Add(&Result{URL: &url.URL{Path: "/err"}, Error: errors.New("context deadline exceeded")})
+	stats.Finish()
+	return stats
+}
+
+func TestScanStats(t *testing.T) {
+	stats := testStats()
+	if stats.Requests != 6 {
+		t.Errorf("Expected 6 requests, got %d", stats.Requests)
+	}
+	if stats.StatusClasses["2xx"] != 2 || stats.StatusClasses["3xx"] != 1 || stats.StatusClasses["4xx"] != 1 {
+		t.Errorf("Unexpected status classes: %v", stats.StatusClasses)
+	}
+	if stats.Errors["connection refused"] != 1 || stats.Errors["timeout"] != 1 {
+		t.Errorf("Unexpected errors: %v", stats.Errors)
+	}
+	if len(stats.ContentTypes) != 1 || stats.ContentTypes[0].Type != "text/html" || stats.ContentTypes[0].Count != 2 {
+		t.Errorf("Unexpected content types: %v", stats.ContentTypes)
+	}
+	if stats.Hits["http://localhost"] != 2 || stats.Hits["https://example.com"] != 1 {
+		t.Errorf("Unexpected hits: %v", stats.Hits)
+	}
+}
+
+func TestScanStats_WriteSummary(t *testing.T) {
+	buf := &bytes.Buffer{}
+	stats := testStats()
+	stats.Stages = []StageTiming{{Stage: "requests", Count: 6, P50: 1.5, P90: 2, P95: 4, P99: 10}}
+	if err := stats.WriteSummary(buf); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, expected := range []string{
+		"Requests: 6 in ",
+		"Status codes: 2xx: 2, 3xx: 1, 4xx: 1\n",
+		"Errors: connection refused: 1, timeout: 1\n",
+		"Content types: text/html: 2\n",
+		"Hits for http://localhost: 2\n",
+		"Total hits: 3\n",
+		"requests:                  1.500/2.000/4.000/10.000 over 6\n",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected %q in summary:\n%s", expected, buf.String())
+		}
+	}
+}
+
+func TestScanStats_WriteToFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stats")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "stats.json")
+	if err := testStats().WriteToFile(path); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Unable to read stats: %s", err)
+	}
+	decoded := &ScanStats{}
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatalf("Invalid JSON: %s", err)
+	}
+	if decoded.Requests != 6 || decoded.Hits["https://example.com"] != 1 {
+		t.Errorf("Unexpected decoded stats: %+v", decoded)
+	}
+}
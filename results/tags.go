@@ -0,0 +1,153 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A TagRule attaches a tag to results matching all of its conditions.
+type TagRule struct {
+	// Tag to attach
+	Tag string
+	// Status codes to match, or any if empty
+	Codes []int
+	// Pattern the URL path must match, or nil for any
+	Path *regexp.Regexp
+}
+
+// Parse a rule such as "tag=auth code=401|403" or "tag=api path=^/api/".
+func ParseTagRule(spec string) (*TagRule, error) {
+	rule := &TagRule{}
+	for _, field := range strings.Fields(spec) {
+		pieces := strings.SplitN(field, "=", 2)
+		if len(pieces) != 2 {
+			return nil, fmt.Errorf("Tag rule conditions are key=value: %s", field)
+		}
+		value := pieces[1]
+		switch pieces[0] {
+		case "tag":
+			rule.Tag = value
+		case "code":
+			for _, c := range strings.Split(value, "|") {
+				code, err := strconv.Atoi(c)
+				if err != nil {
+					return nil, fmt.Errorf("Invalid code: %s", c)
+				}
+				rule.Codes = append(rule.Codes, code)
+			}
+		case "path":
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return nil, err
+			}
+			rule.Path = re
+		default:
+			return nil, fmt.Errorf("Unknown tag rule condition: %s", pieces[0])
+		}
+	}
+	if rule.Tag == "" {
+		return nil, fmt.Errorf("Tag rule has no tag: %s", spec)
+	}
+	return rule, nil
+}
+
+// Parse a list of tag rules.
+func ParseTagRules(specs []string) ([]*TagRule, error) {
+	rules := make([]*TagRule, 0, len(specs))
+	for _, spec := range specs {
+		rule, err := ParseTagRule(spec)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Check if a result matches the rule.
+func (rule *TagRule) Match(r *Result) bool {
+	if len(rule.Codes) > 0 {
+		found := false
+		for _, code := range rule.Codes {
+			if r.Code == code {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if rule.Path != nil && !rule.Path.MatchString(r.URL.Path) {
+		return false
+	}
+	return true
+}
+
+// Names of the tags the rules attach, in order and without repeats.
+func TagNames(rules []*TagRule) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		if !seen[rule.Tag] {
+			seen[rule.Tag] = true
+			names = append(names, rule.Tag)
+		}
+	}
+	return names
+}
+
+// Tagger attaches tags to reported results according to a set of rules.
+type Tagger struct {
+	rules []*TagRule
+	// Status codes the scan reports
+	report ReportCodes
+}
+
+func (t *Tagger) SetReportCodes(codes ReportCodes) {
+	t.report = codes
+}
+
+func NewTagger(rules []*TagRule) *Tagger {
+	return &Tagger{rules: rules}
+}
+
+// Attach the tags of all matching rules to a result.
+func (t *Tagger) Tag(r *Result) {
+	if !t.report.Result(r) {
+		return
+	}
+	for _, rule := range t.rules {
+		if rule.Match(r) {
+			r.AddTag(rule.Tag)
+		}
+	}
+}
+
+func (t *Tagger) Process(in <-chan *Result) <-chan *Result {
+	out := make(chan *Result, cap(in))
+	go func() {
+		defer close(out)
+		for r := range in {
+			t.Tag(r)
+			out <- r
+		}
+	}()
+	return out
+}
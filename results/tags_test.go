@@ -0,0 +1,69 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseTagRule(t *testing.T) {
+	rule, err := ParseTagRule(`tag=auth code=401|403 path=^/admin`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if rule.Tag != "auth" || len(rule.Codes) != 2 || rule.Path == nil {
+		t.Errorf("Unexpected rule: %+v", rule)
+	}
+	for _, spec := range []string{"", "code=401", "tag=x code=abc", "tag=x path=(", "tag=x color=red", "tag"} {
+		if _, err := ParseTagRule(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
+
+func TestTagger(t *testing.T) {
+	rules, err := ParseTagRules([]string{
+		"tag=auth code=401|403",
+		"tag=api path=^/api/",
+		"tag=auth path=/login$",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if names := strings.Join(TagNames(rules), ","); names != "auth,api" {
+		t.Errorf("Unexpected tag names: %s", names)
+	}
+	cases := []struct {
+		path string
+		code int
+		tags string
+	}{
+		{"/api/users", 401, "auth,api"},
+		{"/api/login", 200, "api,auth"},
+		{"/index.html", 200, ""},
+		{"/login", 403, "auth"},
+		{"/api/missing", 404, ""},
+	}
+	tagger := NewTagger(rules)
+	for _, c := range cases {
+		r := &Result{URL: &url.URL{Scheme: "http", Host: "localhost", Path: c.path}, Code: c.code}
+		tagger.Tag(r)
+		if tags := strings.Join(r.Tags, ","); tags != c.tags {
+			t.Errorf("Tags for %d %s: expected %q, got %q", c.code, c.path, c.tags, tags)
+		}
+	}
+}
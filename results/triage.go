@@ -0,0 +1,161 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Triage statuses that may be assigned to a URL.
+const (
+	TriageFalsePositive = "false-positive"
+	TriageAccepted      = "accepted"
+	TriageKnown         = "known"
+)
+
+var triageStatuses = []string{
+	TriageFalsePositive,
+	TriageAccepted,
+	TriageKnown,
+}
+
+// A TriageEntry records the outcome of reviewing a result in an earlier scan.
+// If URL ends in '*', it matches all URLs with that prefix.
+type TriageEntry struct {
+	URL    string
+	Status string
+	Note   string
+}
+
+// Triage suppresses or annotates results that were triaged previously.
+// False positives and accepted results are dropped; known results are passed
+// through with a note.
+type Triage struct {
+	exact    map[string]*TriageEntry
+	prefixes []*TriageEntry
+}
+
+// Load triage entries from a file.
+func LoadTriageFile(filename string) (*Triage, error) {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	return ParseTriage(fp)
+}
+
+// Parse triage entries, one per line, in the format:
+//
+//	<url> <status> [note]
+//
+// Blank lines and lines starting with # are ignored.
+func ParseTriage(rdr io.Reader) (*Triage, error) {
+	triage := &Triage{
+		exact: make(map[string]*TriageEntry),
+	}
+	scanner := bufio.NewScanner(rdr)
+	lineno := 0
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("Invalid triage entry on line %d.", lineno)
+		}
+		entry := &TriageEntry{
+			URL:    fields[0],
+			Status: strings.ToLower(fields[1]),
+			Note:   strings.Join(fields[2:], " "),
+		}
+		if !isTriageStatus(entry.Status) {
+			return nil, fmt.Errorf("Invalid triage status %q on line %d, must be one of [%s].", fields[1], lineno, strings.Join(triageStatuses, ","))
+		}
+		triage.Add(entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return triage, nil
+}
+
+func isTriageStatus(status string) bool {
+	for _, s := range triageStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Triage) Add(entry *TriageEntry) {
+	if strings.HasSuffix(entry.URL, "*") {
+		t.prefixes = append(t.prefixes, entry)
+		return
+	}
+	t.exact[entry.URL] = entry
+}
+
+// Find the entry for a result, or nil if it has not been triaged.  Exact
+// matches take precedence over the first matching prefix.
+func (t *Triage) Lookup(r *Result) *TriageEntry {
+	if r.URL == nil {
+		return nil
+	}
+	u := *r.URL
+	u.Fragment = ""
+	key := u.String()
+	if entry, ok := t.exact[key]; ok {
+		return entry
+	}
+	for _, entry := range t.prefixes {
+		if strings.HasPrefix(key, strings.TrimSuffix(entry.URL, "*")) {
+			return entry
+		}
+	}
+	return nil
+}
+
+func (t *Triage) Process(in <-chan *Result) <-chan *Result {
+	out := make(chan *Result, cap(in))
+	go func() {
+		defer close(out)
+		for r := range in {
+			entry := t.Lookup(r)
+			if entry == nil {
+				out <- r
+				continue
+			}
+			switch entry.Status {
+			case TriageFalsePositive, TriageAccepted:
+				continue
+			}
+			if entry.Note != "" {
+				r.AddNote("%s: %s", entry.Status, entry.Note)
+			} else {
+				r.AddNote("%s", entry.Status)
+			}
+			out <- r
+		}
+	}()
+	return out
+}
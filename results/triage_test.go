@@ -0,0 +1,90 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const testTriage = `
+# Reviewed last quarter
+http://localhost/admin accepted Behind VPN
+http://localhost/test.php false-positive
+http://localhost/static/* known
+http://localhost/backup KNOWN
+`
+
+func triageResult(path string) *Result {
+	return &Result{URL: &url.URL{Scheme: "http", Host: "localhost", Path: path}}
+}
+
+func TestParseTriage(t *testing.T) {
+	triage, err := ParseTriage(strings.NewReader(testTriage))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cases := map[string]string{
+		"/admin":       TriageAccepted,
+		"/test.php":    TriageFalsePositive,
+		"/static/a.js": TriageKnown,
+		"/backup":      TriageKnown,
+		"/other":       "",
+	}
+	for path, expected := range cases {
+		entry := triage.Lookup(triageResult(path))
+		status := ""
+		if entry != nil {
+			status = entry.Status
+		}
+		if status != expected {
+			t.Errorf("Lookup(%s): expected %q, got %q", path, expected, status)
+		}
+	}
+	if entry := triage.Lookup(triageResult("/admin")); entry.Note != "Behind VPN" {
+		t.Errorf("Unexpected note: %q", entry.Note)
+	}
+	for _, bad := range []string{"http://localhost/", "http://localhost/ wontfix"} {
+		if _, err := ParseTriage(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected error parsing %q", bad)
+		}
+	}
+}
+
+func TestTriage_Process(t *testing.T) {
+	triage, err := ParseTriage(strings.NewReader(testTriage))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	in := make(chan *Result, 10)
+	for _, p := range []string{"/admin", "/test.php", "/static/x", "/new"} {
+		in <- triageResult(p)
+	}
+	close(in)
+	out := make([]*Result, 0)
+	for r := range triage.Process(in) {
+		out = append(out, r)
+	}
+	if len(out) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(out))
+	}
+	if out[0].URL.Path != "/static/x" || len(out[0].Notes) != 1 || out[0].Notes[0] != TriageKnown {
+		t.Errorf("Expected known annotation, got %v", out[0].Notes)
+	}
+	if out[1].URL.Path != "/new" || len(out[1].Notes) != 0 {
+		t.Errorf("Expected untouched result, got %v", out[1])
+	}
+}
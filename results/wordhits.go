@@ -0,0 +1,164 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/Matir/webborer/task"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Words listed in the summary of hits
+const wordHitsSummaryTop = 10
+
+// WordHits tracks which wordlist entries lead to hits, to build wordlists
+// suited to the targets.  Hits from earlier scans can be merged in.
+type WordHits struct {
+	// Words tried in this scan
+	tried map[string]bool
+	// Words that found something in this scan
+	found map[string]bool
+	// Number of hits for each word
+	hits map[string]int
+	// Status codes the scan reports
+	report ReportCodes
+}
+
+func (wh *WordHits) SetReportCodes(codes ReportCodes) {
+	wh.report = codes
+}
+
+func NewWordHits() *WordHits {
+	return &WordHits{
+		tried: make(map[string]bool),
+		found: make(map[string]bool),
+		hits:  make(map[string]int),
+	}
+}
+
+func (wh *WordHits) Process(in <-chan *Result) <-chan *Result {
+	out := make(chan *Result, cap(in))
+	go func() {
+		defer close(out)
+		for r := range in {
+			wh.Add(r)
+			out <- r
+		}
+	}()
+	return out
+}
+
+// Count a result against the word it was requested for, if any.
+func (wh *WordHits) Add(r *Result) {
+	word := resultWord(r)
+	if word == "" {
+		return
+	}
+	wh.tried[word] = true
+	if wh.report.Result(r) {
+		wh.found[word] = true
+		wh.hits[word]++
+	}
+}
+
+// Get the wordlist entry a result was requested for, or "" if it wasn't from
+// the wordlist.  Entries tried with an extension count for the entry.
+func resultWord(r *Result) string {
+	switch r.Provenance.Origin {
+	case task.OriginWordlist:
+		return r.Provenance.Detail
+	case task.OriginExtension:
+		if r.URL == nil {
+			return ""
+		}
+		name := path.Base(r.URL.Path)
+		if suffix := "." + r.Provenance.Detail; strings.HasSuffix(name, suffix) {
+			return strings.TrimSuffix(name, suffix)
+		}
+	}
+	return ""
+}
+
+// Add hits from earlier scans.
+func (wh *WordHits) Merge(hits map[string]int) {
+	for word, n := range hits {
+		wh.hits[word] += n
+	}
+}
+
+// Get the number of hits for each word, including merged hits.
+func (wh *WordHits) Counts() map[string]int {
+	return wh.hits
+}
+
+// Get the words with hits, most hits first.
+func (wh *WordHits) Learned() []string {
+	words := make([]string, 0, len(wh.hits))
+	for word, n := range wh.hits {
+		if n > 0 {
+			words = append(words, word)
+		}
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if wh.hits[words[i]] != wh.hits[words[j]] {
+			return wh.hits[words[i]] > wh.hits[words[j]]
+		}
+		return words[i] < words[j]
+	})
+	return words
+}
+
+// Write a summary of how much of the wordlist found something in this scan,
+// and the words with the most hits.
+func (wh *WordHits) WriteSummary(w io.Writer) error {
+	pct := 0.0
+	if len(wh.tried) > 0 {
+		pct = 100 * float64(len(wh.found)) / float64(len(wh.tried))
+	}
+	if _, err := fmt.Fprintf(w, "%d of %d wordlist entries tried found something (%.1f%%)\n", len(wh.found), len(wh.tried), pct); err != nil {
+		return err
+	}
+	for i, word := range wh.Learned() {
+		if i == wordHitsSummaryTop {
+			break
+		}
+		if _, err := fmt.Fprintf(w, "  %s: %d\n", word, wh.hits[word]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write the learned wordlist to a file, one word per line.
+func (wh *WordHits) WriteLearned(filename string) error {
+	fp, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(fp)
+	for _, word := range wh.Learned() {
+		fmt.Fprintln(w, word)
+	}
+	if err := w.Flush(); err != nil {
+		fp.Close()
+		return err
+	}
+	return fp.Close()
+}
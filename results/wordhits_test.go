@@ -0,0 +1,56 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"bytes"
+	"github.com/Matir/webborer/task"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func wordResult(path string, code int, origin task.Origin, detail string) *Result {
+	r := NewResult(&url.URL{Scheme: "http", Host: "localhost", Path: path}, "")
+	r.Code = code
+	r.Provenance = task.Provenance{Origin: origin, Detail: detail}
+	return r
+}
+
+func TestWordHits(t *testing.T) {
+	wh := NewWordHits()
+	for _, r := range []*Result{
+		wordResult("/admin", 200, task.OriginWordlist, "admin"),
+		wordResult("/a/admin", 200, task.OriginWordlist, "admin"),
+		wordResult("/backup.php", 200, task.OriginExtension, "php"),
+		wordResult("/missing", 404, task.OriginWordlist, "missing"),
+		wordResult("/linked", 200, task.OriginSpider, ""),
+	} {
+		wh.Add(r)
+	}
+	wh.Merge(map[string]int{"old": 1})
+	expected := []string{"admin", "backup", "old"}
+	if learned := wh.Learned(); !reflect.DeepEqual(learned, expected) {
+		t.Errorf("Expected %v, got %v", expected, learned)
+	}
+	buf := &bytes.Buffer{}
+	if err := wh.WriteSummary(buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "2 of 3 wordlist entries tried found something (66.7%)\n  admin: 2\n") {
+		t.Errorf("Unexpected summary: %q", buf.String())
+	}
+}
@@ -0,0 +1,1015 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package webborer allows a complete scan to be embedded in another Go
+// program.  Build a ScanSettings with settings.DefaultScanSettings(), set the
+// fields of interest, and hand it to NewScanner.
+package webborer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"github.com/Matir/webborer/analysis"
+	"github.com/Matir/webborer/browser"
+	"github.com/Matir/webborer/client"
+	"github.com/Matir/webborer/filter"
+	"github.com/Matir/webborer/hosts"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/notify"
+	"github.com/Matir/webborer/plugins"
+	"github.com/Matir/webborer/remote"
+	"github.com/Matir/webborer/results"
+	ss "github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/shortname"
+	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/util"
+	"github.com/Matir/webborer/wordlist"
+	"github.com/Matir/webborer/worker"
+	"github.com/Matir/webborer/workqueue"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A ResultStage transforms the stream of results before they are delivered.
+// RedirectCollapser, Triage, Scorer and friends all satisfy this.
+type ResultStage interface {
+	Process(<-chan *results.Result) <-chan *results.Result
+}
+
+// Scanner runs a single scan from a ScanSettings.
+type Scanner struct {
+	// Results delivered so far, first for 64-bit alignment
+	delivered   int64
+	settings    *ss.ScanSettings
+	factory     client.ClientFactory
+	words       []string
+	source      io.Reader
+	stages      []ResultStage
+	plugins     *plugins.Set
+	onResult    []func(*results.Result)
+	onFound     []func(*results.Result)
+	onProgress  []func(done, total int64)
+	results     chan *results.Result
+	queue       *workqueue.WorkQueue
+	pool        *worker.Pool
+	filter      *filter.WorkFilter
+	scope       []*url.URL
+	coordinator *remote.Coordinator
+	// Serving the status endpoint, if any
+	statusListener net.Listener
+	rchan          chan *results.Result
+	hosts          *hosts.Registry
+	// Status codes the scan reports
+	report     results.ReportCodes
+	timings    *worker.Timings
+	pressure   *worker.MemoryPressure
+	previous   *results.Comparison
+	startTime  time.Time
+	current    atomic.Value
+	plan       *worker.DryRunPlan
+	wlexpander *filter.WordlistExpander
+	planOutput io.Writer
+	dirs       sync.Map
+	finished   chan bool
+	started    bool
+	stopped    bool
+	err        error
+	sync.Mutex
+}
+
+// How often the heap is checked against the memory limit
+const memoryCheckInterval = 5 * time.Second
+
+var (
+	ErrAlreadyStarted = errors.New("Scanner already started.")
+	ErrNotStarted     = errors.New("Scanner not started.")
+	ErrFinished       = errors.New("Scan already finished.")
+)
+
+// Create a new scanner.  The settings must not be modified once the scan has
+// started.
+func NewScanner(settings *ss.ScanSettings) *Scanner {
+	return &Scanner{
+		settings: settings,
+		plugins:  &plugins.Set{},
+		finished: make(chan bool),
+	}
+}
+
+// Use the given client factory instead of one built from the settings.
+func (s *Scanner) SetClientFactory(factory client.ClientFactory) {
+	s.factory = factory
+}
+
+// Use the given words instead of loading settings.WordlistPath.
+func (s *Scanner) SetWords(words []string) {
+	s.words = words
+}
+
+// Read more starting URLs from r, one per line, as the scan runs.  URLs without
+// a scheme are assumed to be http.  The scan is not finished until r reaches
+// EOF.  Without this, settings.BaseURLs containing ss.StdinURL reads from
+// stdin.
+func (s *Scanner) ReadTargets(r io.Reader) {
+	s.source = r
+}
+
+// List the requests of a dry run to w instead of standard output.
+func (s *Scanner) SetDryRunOutput(w io.Writer) {
+	s.planOutput = w
+}
+
+// Get the number of requests a dry run would have sent, and the number of
+// hosts they are for.
+func (s *Scanner) Planned() (int64, int) {
+	if s.plan == nil {
+		return 0, 0
+	}
+	return s.plan.Count()
+}
+
+// Use a configured plugin in addition to those named in the settings.  The
+// scanner closes it when the scan is done.
+func (s *Scanner) AddPlugin(p plugins.Plugin) {
+	s.plugins.Add(p)
+}
+
+// Register a callback invoked for every result, after all stages.
+func (s *Scanner) OnResult(f func(*results.Result)) {
+	s.onResult = append(s.onResult, f)
+}
+
+// Register a callback invoked for every result as soon as it is found, before
+// any stages hold it back.  It must not block for long, as that stalls the
+// scan.
+func (s *Scanner) OnFound(f func(*results.Result)) {
+	s.onFound = append(s.onFound, f)
+}
+
+// Register a callback invoked whenever the amount of work changes.  It is
+// called with the work counter locked, so it must not block.
+func (s *Scanner) OnProgress(f func(done, total int64)) {
+	s.onProgress = append(s.onProgress, f)
+}
+
+// Add up the time spent generating and filtering tasks, and the time workers
+// spend waiting for work, on requests, on bodies, and on sending results, to
+// find what limits the speed of the scan.
+func (s *Scanner) SetTimings(timings *worker.Timings) {
+	s.timings = timings
+}
+
+// Add a stage to run after the built-in result stages.
+func (s *Scanner) AddResultStage(stage ResultStage) {
+	s.stages = append(s.stages, stage)
+}
+
+// Get a channel of results, closed when the scan finishes.  This must be
+// called before Start and the channel must be drained, or the scan will
+// stall.  Without it, results are only delivered to callbacks.
+func (s *Scanner) Results() <-chan *results.Result {
+	if s.results == nil {
+		s.results = make(chan *results.Result, s.settings.QueueSize)
+	}
+	return s.results
+}
+
+// Start the scan.  Errors setting the scan up are returned here; once it has
+// started, use Wait to find out when it is done.  Cancelling ctx stops the
+// scan early.
+func (s *Scanner) Start(ctx context.Context) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.started {
+		return ErrAlreadyStarted
+	}
+	settings := s.settings
+	s.normalizeSettings()
+	if settings.StatusAddr != "" && s.timings == nil {
+		// For the stage timings on the status endpoint
+		s.timings = worker.NewTimings()
+	}
+	if settings.DryRun {
+		output := s.planOutput
+		if output == nil {
+			output = os.Stdout
+		}
+		s.plan = worker.NewDryRunPlan(output)
+	}
+
+	if err := s.loadWords(); err != nil {
+		return err
+	}
+	if s.factory == nil {
+		logging.Logf(logging.LogDebug, "Creating Client Factory...")
+		factory, err := NewClientFactory(settings)
+		if err != nil {
+			return err
+		}
+		s.factory = factory
+	}
+	scope, err := settings.GetScopes()
+	if err != nil {
+		return err
+	}
+	if err := s.plugins.Load(settings.Plugins); err != nil {
+		s.plugins.Close()
+		return err
+	}
+	if dialer := s.plugins.Dialer(); dialer != nil {
+		if factory, ok := s.factory.(*client.ProxyClientFactory); ok {
+			factory.SetDialer(dialer)
+		} else {
+			logging.Logf(logging.LogWarning, "A dialer plugin can't be used with a custom client factory.")
+		}
+	}
+	s.hosts = hosts.NewRegistry()
+	if settings.ProbeSchemes && !settings.DryRun {
+		scope = s.probeScopes(scope)
+	}
+	if settings.DedupeHosts && !settings.DryRun {
+		scope = s.dedupeScopes(scope)
+	}
+	s.report = results.NewReportCodes(settings)
+	if settings.FaviconDBPath != "" {
+		if err := analysis.LoadFaviconDB(settings.FaviconDBPath); err != nil {
+			s.plugins.Close()
+			return err
+		}
+	}
+	var checker *analysis.SensitiveChecker
+	if settings.SensitiveChecks || settings.ProbeSensitive {
+		rules, err := analysis.LoadSensitiveRules(settings.SensitiveRulesPath)
+		if err == nil {
+			checker, err = analysis.NewSensitiveChecker(rules)
+		}
+		if err != nil {
+			s.plugins.Close()
+			return err
+		}
+	}
+	stages, err := s.buildStages()
+	if err != nil {
+		s.plugins.Close()
+		return err
+	}
+	for _, stage := range stages {
+		results.SetReportCodes(stage, s.report)
+	}
+
+	// Setup the main workqueue
+	logging.Logf(logging.LogDebug, "Starting work queue...")
+	s.queue = workqueue.NewWorkQueue(settings.QueueSize, scope, settings.AllowHTTPSUpgrade)
+	if settings.RunMode == ss.RunModeLinkCheck && settings.CheckExternal {
+		s.queue.AcceptExternalLinks()
+	}
+	s.queue.RunInBackground()
+
+	logging.Logf(logging.LogDebug, "Creating expander and filter...")
+	var expander filter.Expander
+	switch settings.RunMode {
+	case ss.RunModeEnumeration:
+		wlexpander, err := s.newWordlistExpander()
+		if err != nil {
+			s.plugins.Close()
+			return err
+		}
+		expander = wlexpander
+	case ss.RunModeDotProduct:
+		expander = filter.NewDotProductExpander(s.words)
+	case ss.RunModeLinkCheck:
+		// No expander needed
+	default:
+		panic("Unknown run mode!")
+	}
+
+	workChan := s.queue.GetWorkChan()
+	if expander != nil {
+		expanders := []filter.Expander{expander, filter.NewHeaderExpander(settings.OptionalHeader.Header())}
+		if len(settings.VirtualHosts) > 0 {
+			expanders = append(expanders, filter.NewDotProductExpander(settings.VirtualHosts))
+		}
+		expanders = append(expanders, filter.NewExtensionExpander(settings.Extensions))
+		if settings.ProbeCommon {
+			// After the extension expander, so the paths are used as-is
+			expanders = append(expanders, filter.NewProbeExpander(filter.DefaultDotfiles, filter.DefaultWellKnown))
+		}
+		for _, e := range expanders {
+			e.SetAddCount(s.queue.GetAddCount())
+			if s.timings != nil {
+				e.SetTimer(s.timings.Recorder(worker.StageGenerate))
+			}
+			workChan = e.Expand(workChan)
+		}
+	}
+	s.rchan = make(chan *results.Result, settings.QueueSize)
+	s.hosts.SetConcurrency(settings.ThreadsPerHost)
+	s.hosts.OnUpgrade(s.upgradeScope)
+	workFilter := filter.NewWorkFilter(settings, s.queue.GetDoneFunc())
+	workFilter.SetHosts(s.hosts)
+	workFilter.SetBudgetRoots(scope)
+	s.filter = workFilter
+	s.scope = scope
+	if s.timings != nil {
+		workFilter.SetTimer(s.timings.Recorder(worker.StageFilter))
+	}
+	if settings.DirBudget > 0 || settings.TrapLimit > 0 {
+		s.onFound = append(s.onFound, s.rememberDir)
+		workFilter.OnSkip(s.noteSkipped)
+	}
+	if settings.RobotsMode == ss.ObeyRobots {
+		workFilter.AddRobotsFilter(scope, s.factory)
+	}
+	// Mutated tasks are filtered, so plugins can't take the scan out of scope
+	workChan = s.plugins.MutateTasks(workChan, s.queue.GetDoneFunc())
+	workChan = workFilter.RunFilter(workChan)
+	s.plugins.SetAdder(s.queue.GetAddFunc())
+
+	if settings.Coordinator != "" {
+		s.coordinator = remote.NewCoordinator(workChan, s.queue.GetAddFunc(), s.queue.GetDoneFunc(), s.rchan, settings.RemoteToken)
+		s.coordinator.SetSpider(s.queue.GetSpiderFunc())
+		if settings.RemoteCert != "" {
+			if err := s.coordinator.SetTLS(settings.RemoteCert, settings.RemoteKey); err != nil {
+				s.plugins.Close()
+				return err
+			}
+		}
+		if err := s.coordinator.ListenAndServe(settings.Coordinator); err != nil {
+			s.plugins.Close()
+			return err
+		}
+	} else {
+		logging.Logf(logging.LogDebug, "Starting %d workers...", settings.Workers)
+		workers := worker.NewWorkers(settings, s.factory, workChan, s.queue.GetAddFunc(), s.queue.GetDoneFunc(), s.rchan)
+		pageWorkers := s.plugins.PageWorkers()
+		if checker != nil {
+			pageWorkers = append(pageWorkers, checker)
+		}
+		spider := s.queue.GetSpiderFunc()
+		if settings.MemoryLimit > 0 {
+			s.pressure = &worker.MemoryPressure{}
+		}
+		for _, w := range workers {
+			w.SetSpider(spider)
+			w.SetHosts(s.hosts)
+			w.SetTimings(s.timings)
+			w.SetMemoryPressure(s.pressure)
+			w.SetPrevious(s.previous)
+			if s.plan != nil {
+				w.SetDryRun(s.plan)
+			}
+			for _, pw := range pageWorkers {
+				w.AddPageWorker(pw)
+			}
+		}
+		s.pool = worker.NewPool(workers, s.factory)
+		s.pool.Start()
+	}
+	if settings.StatusAddr != "" {
+		if err := s.serveStatus(settings.StatusAddr); err != nil {
+			s.plugins.Close()
+			return err
+		}
+	}
+
+	var resultChan <-chan *results.Result = s.rchan
+	if len(s.onFound) > 0 {
+		resultChan = s.found(resultChan)
+	}
+	for _, stage := range stages {
+		resultChan = stage.Process(resultChan)
+	}
+	go s.deliver(resultChan)
+
+	if len(s.onProgress) > 0 {
+		s.queue.GetCounter().SetStatusCallback(func(done, total int64) {
+			for _, f := range s.onProgress {
+				f(done, total)
+			}
+		})
+	}
+
+	// Kick things off with the seed URL
+	logging.Logf(logging.LogDebug, "Adding starting URLs: %v", scope)
+	task.SetDefaultHeader(settings.Header.Header())
+	tasks := make([]*task.Task, 0, len(scope))
+	for _, u := range scope {
+		t := task.NewTaskFromURL(u)
+		t.Provenance = task.Provenance{Origin: task.OriginSeed}
+		tasks = append(tasks, t)
+	}
+	s.queue.AddTasks(tasks...)
+	if settings.RobotsMode == ss.SeedRobots {
+		s.queue.SeedFromRobots(scope, s.factory)
+	}
+	if settings.ProbeSensitive {
+		s.queue.AddTasks(probeTasks(scope, checker.ProbePaths())...)
+	}
+	if settings.IISShortNames {
+		// Hold the scan open until enumeration is finished
+		s.queue.GetAddCount()(1)
+		go s.seedShortNames(scope)
+	}
+	if s.source == nil && settings.ReadsStdin() {
+		s.source = os.Stdin
+	}
+	if s.source != nil {
+		if settings.RobotsMode == ss.ObeyRobots {
+			logging.Logf(logging.LogWarning, "robots.txt is not obeyed for URLs read as the scan runs.")
+		}
+		// Hold the scan open until the input is finished
+		s.queue.GetAddCount()(1)
+		go s.readTargets(s.source)
+	}
+
+	s.started = true
+	s.startTime = time.Now()
+	s.logOperatorStart(scope)
+	go s.run(ctx)
+	if settings.MemoryLimit > 0 {
+		go s.watchMemory()
+	}
+	return nil
+}
+
+// Wait for the scan to finish and all results to be delivered.  Returns the
+// context's error if the scan was cancelled.
+func (s *Scanner) Wait() error {
+	s.Lock()
+	started := s.started
+	s.Unlock()
+	if !started {
+		return ErrNotStarted
+	}
+	<-s.finished
+	return s.err
+}
+
+// Add a starting URL to a running scan, extending its scope.
+func (s *Scanner) AddTarget(u *url.URL) error {
+	s.Lock()
+	if !s.started {
+		s.Unlock()
+		return ErrNotStarted
+	}
+	if s.stopped {
+		s.Unlock()
+		return ErrFinished
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	logging.Logf(logging.LogInfo, "Adding target %s", u.String())
+	s.queue.AddScope(u)
+	s.scope = append(s.scope, u)
+	t := task.NewTaskFromURL(u)
+	t.Provenance = task.Provenance{Origin: task.OriginSeed}
+	s.queue.AddTasks(t)
+	s.Unlock()
+	if s.settings.RobotsMode == ss.SeedRobots {
+		s.queue.SeedFromRobots([]*url.URL{u}, s.factory)
+	}
+	return nil
+}
+
+// Extend the scope to the https origin of a host that redirects every http
+// request, so links found there are followed.
+func (s *Scanner) upgradeScope(from, to *url.URL) {
+	s.Lock()
+	defer s.Unlock()
+	key := util.CanonicalHost(from.Scheme, from.Host)
+	for _, u := range s.scope {
+		if u.Scheme != from.Scheme || util.CanonicalHost(u.Scheme, u.Host) != key {
+			continue
+		}
+		upgraded := *u
+		upgraded.Scheme = to.Scheme
+		upgraded.Host = to.Host
+		s.queue.AddScope(&upgraded)
+		s.scope = append(s.scope, &upgraded)
+	}
+}
+
+// Add targets read from r until EOF or the scan finishes.
+func (s *Scanner) readTargets(r io.Reader) {
+	defer s.queue.GetDoneFunc()(1)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		u, err := util.ParseTargetURL(line)
+		if err != nil {
+			logging.Logf(logging.LogWarning, "Unable to parse target %s: %s", line, err.Error())
+			continue
+		}
+		targets := []*url.URL{u}
+		if s.settings.ProbeSchemes && !s.settings.DryRun && !util.HasScheme(line) {
+			targets = s.probeTarget(u)
+		}
+		for _, target := range targets {
+			if err := s.AddTarget(target); err != nil {
+				return
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		logging.Logf(logging.LogError, "Error reading targets: %s", err.Error())
+	}
+}
+
+// Enumerate IIS short names in each starting directory and add the full names
+// they suggest.
+func (s *Scanner) seedShortNames(scope []*url.URL) {
+	defer s.queue.GetDoneFunc()(1)
+	enumerator := shortname.NewEnumerator(s.factory.Get())
+	for _, scopeURL := range scope {
+		dir := *scopeURL
+		dir.Path = dir.Path[:strings.LastIndex(dir.Path, "/")+1]
+		names, err := enumerator.Enumerate(&dir)
+		if err != nil {
+			logging.Logf(logging.LogWarning, "Unable to enumerate short names in %s: %s", dir.String(), err.Error())
+			continue
+		}
+		if len(names) == 0 {
+			continue
+		}
+		logging.Logf(logging.LogWarning, "%s discloses IIS short names: %v", dir.String(), names)
+		var tasks []*task.Task
+		for _, name := range names {
+			for _, guess := range shortname.Guesses(name, s.words, s.settings.Extensions) {
+				u := dir
+				u.Path += guess
+				t := task.NewTaskFromURL(&u)
+				t.Provenance = task.NewProvenance(task.OriginShortName, &dir, name.String())
+				t.ParentURL = scopeURL
+				t.Depth = 1
+				tasks = append(tasks, t)
+			}
+		}
+		s.Lock()
+		if !s.stopped {
+			s.queue.AddTasks(tasks...)
+		}
+		s.Unlock()
+	}
+}
+
+// Write a snapshot of the pending work queue to path.
+func (s *Scanner) DumpQueue(path string) error {
+	s.Lock()
+	defer s.Unlock()
+	if !s.started {
+		return ErrNotStarted
+	}
+	return s.queue.DumpSnapshot(path)
+}
+
+// Tasks for paths in each starting directory.
+func probeTasks(scope []*url.URL, paths []string) []*task.Task {
+	tasks := make([]*task.Task, 0, len(scope)*len(paths))
+	for _, scopeURL := range scope {
+		dir := *scopeURL
+		dir.Path = dir.Path[:strings.LastIndex(dir.Path, "/")+1]
+		for _, p := range paths {
+			u := dir
+			u.Path += p
+			t := task.NewTaskFromURL(&u)
+			t.Provenance = task.NewProvenance(task.OriginProbe, &dir, "")
+			t.ParentURL = scopeURL
+			t.Depth = 1
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks
+}
+
+// Load the wordlist, unless words were given with SetWords or a link check
+// doesn't need them.
+func (s *Scanner) loadWords() error {
+	settings := s.settings
+	wordlist.SetCacheDir(settings.WordlistCache)
+	if s.words != nil || settings.RunMode == ss.RunModeLinkCheck {
+		return nil
+	}
+	words, err := wordlist.LoadVerifiedWordlist(settings.WordlistPath, settings.WordlistSHA256)
+	if err != nil {
+		return err
+	}
+	s.words = words
+	return nil
+}
+
+// Build the expander adding the wordlist to each directory, once.
+func (s *Scanner) newWordlistExpander() (*filter.WordlistExpander, error) {
+	if s.wlexpander != nil {
+		return s.wlexpander, nil
+	}
+	settings := s.settings
+	wlexpander := filter.NewWordlistExpander(s.words, settings.AddSlashes, settings.MangleCases)
+	wlexpander.SetEncodings(settings.Encodings, settings.EncodeLimit)
+	wlexpander.SetSlashMode(settings.SlashMode)
+	if err := addTargetWordlists(wlexpander, settings.Targets); err != nil {
+		return nil, err
+	}
+	if err := setPermutations(wlexpander, settings, s.words); err != nil {
+		return nil, err
+	}
+	wlexpander.ProcessWordlist()
+	wlexpander.SetShuffle(settings.Shuffle)
+	s.wlexpander = wlexpander
+	return wlexpander, nil
+}
+
+// Set up the combinations of the prefix and suffix lists with words, if
+// either is given.
+func setPermutations(expander *filter.WordlistExpander, settings *ss.ScanSettings, words []string) error {
+	if settings.PrefixListPath == "" && settings.SuffixListPath == "" {
+		return nil
+	}
+	var prefixes, suffixes []string
+	var err error
+	if settings.PrefixListPath != "" {
+		if prefixes, err = wordlist.LoadWordlist(settings.PrefixListPath); err != nil {
+			return err
+		}
+	}
+	if settings.SuffixListPath != "" {
+		if suffixes, err = wordlist.LoadWordlist(settings.SuffixListPath); err != nil {
+			return err
+		}
+	}
+	expander.SetPermutations(prefixes, words, suffixes, settings.PermutationLimit)
+	total, tried := expander.PermutationCount()
+	if tried < total {
+		logging.Logf(logging.LogWarning, "Trying %d of %d combinations of prefixes, words and suffixes in each directory.", tried, total)
+	} else {
+		logging.Logf(logging.LogInfo, "Trying %d combinations of prefixes, words and suffixes in each directory.", total)
+	}
+	return nil
+}
+
+func addTargetWordlists(expander *filter.WordlistExpander, targets []*ss.Target) error {
+	loaded := make(map[string][]string)
+	for _, target := range targets {
+		if target.WordlistPath == "" {
+			continue
+		}
+		words, ok := loaded[target.WordlistPath]
+		if !ok {
+			var err error
+			if words, err = wordlist.LoadWordlist(target.WordlistPath); err != nil {
+				return err
+			}
+			loaded[target.WordlistPath] = words
+		}
+		expander.AddScopeWordlist(target.URL, words)
+	}
+	return nil
+}
+
+// Resolve settings that imply or exclude others.
+func (s *Scanner) normalizeSettings() {
+	settings := s.settings
+	if settings.HeadersOnly {
+		if settings.GroupDuplicates > 0 || settings.HashBodies || settings.FilterSimilar > 0 {
+			logging.Logf(logging.LogWarning, "Bodies are not read with -headers-only, disabling hashing.")
+		}
+		if settings.FilterRegex != "" {
+			logging.Logf(logging.LogWarning, "Bodies are not read with -headers-only, -filter-regex will never match.")
+		}
+		settings.ParseHTML = false
+		settings.HashBodies = false
+		settings.GroupDuplicates = 0
+		settings.FilterSimilar = 0
+	}
+	if settings.GroupDuplicates > 0 || settings.FilterSimilar > 0 {
+		settings.HashBodies = true
+	}
+	if settings.DryRun {
+		// Nothing may be requested from the targets
+		if settings.RobotsMode != ss.IgnoreRobots {
+			logging.Logf(logging.LogWarning, "robots.txt is not fetched in a dry run.")
+			settings.RobotsMode = ss.IgnoreRobots
+		}
+		if settings.IISShortNames {
+			logging.Logf(logging.LogWarning, "Short names are not enumerated in a dry run.")
+			settings.IISShortNames = false
+		}
+	}
+	for _, warning := range settings.FitMemory() {
+		logging.Logf(logging.LogWarning, "%s", warning)
+	}
+}
+
+// Warn if the heap grows past the memory limit, returning memory to the OS
+// and not keeping recorded bodies while it is, until the scan finishes.
+func (s *Scanner) watchMemory() {
+	limit := uint64(s.settings.MemoryLimit)
+	ticker := time.NewTicker(memoryCheckInterval)
+	defer ticker.Stop()
+	warned := false
+	for {
+		select {
+		case <-s.finished:
+			return
+		case <-ticker.C:
+		}
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		over := stats.HeapAlloc > limit
+		if s.pressure.Set(over) && over && s.settings.Recording() {
+			logging.Logf(logging.LogWarning, "Not recording response bodies while over the memory limit.")
+		}
+		if !over {
+			continue
+		}
+		if !warned {
+			logging.Logf(logging.LogWarning, "Using %dM of memory, over the limit of %s.", stats.HeapAlloc>>20, s.settings.MemoryLimit)
+			warned = true
+		}
+		debug.FreeOSMemory()
+	}
+}
+
+// Build the built-in result stages followed by any added by the caller.
+func (s *Scanner) buildStages() ([]ResultStage, error) {
+	settings := s.settings
+	var stages []ResultStage
+	chain, err := results.NewFilterChainFromSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+	if chain.Len() > 0 {
+		stages = append(stages, chain)
+	}
+	if settings.Verify != ss.VerifyOff {
+		factory := s.factory
+		if settings.VerifyProxy != "" {
+			verifySettings := *settings
+			verifySettings.Proxies = ss.StringSliceFlag{settings.VerifyProxy}
+			var err error
+			if factory, err = NewClientFactory(&verifySettings); err != nil {
+				return nil, err
+			}
+		}
+		stages = append(stages, worker.NewVerifier(settings, factory))
+	}
+	if settings.CollapseRedirects > 0 {
+		collapser := results.NewRedirectCollapser(settings.CollapseRedirects)
+		collapser.SetFanout(settings.RedirectFanout, results.DefaultFanoutMin)
+		stages = append(stages, collapser)
+	}
+	if settings.GroupDuplicates > 0 {
+		stages = append(stages, results.NewDuplicateGrouper(settings.GroupDuplicates, settings.FuzzyDistance))
+	}
+	if settings.TriagePath != "" {
+		triage, err := results.LoadTriageFile(settings.TriagePath)
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, triage)
+	}
+	comparePath := settings.ComparePath
+	if settings.HistoryDir != "" {
+		history, err := results.OpenHistory(settings.HistoryDir)
+		if err != nil {
+			return nil, err
+		}
+		if comparePath == "" {
+			if comparePath, err = history.Latest(); err != nil {
+				return nil, err
+			}
+		}
+		run, err := history.Begin(time.Now())
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, run)
+	}
+	if comparePath != "" {
+		comparison, err := results.LoadComparisonFile(comparePath)
+		if err != nil {
+			return nil, err
+		}
+		if settings.ConditionalRequests {
+			s.previous = comparison
+		}
+		stages = append(stages, comparison)
+	}
+	if settings.Calibrate {
+		stages = append(stages, results.NewConfidenceScorer(settings.CalibrationSamples))
+	}
+	if settings.AnalyzeHeaders {
+		stages = append(stages, analysis.NewAnalyzer(analysis.HeaderRules...))
+	}
+	if settings.TimingAnomalies {
+		stages = append(stages, analysis.NewTimingAnomalies())
+	}
+	stages = append(stages, results.NewInterestScorer(settings.SortInterest))
+	if len(settings.TagRules) > 0 {
+		rules, err := results.ParseTagRules(settings.TagRules)
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, results.NewTagger(rules))
+	}
+	if settings.BodyStoreDir != "" {
+		store, err := results.NewBodyStore(settings.BodyStoreDir)
+		if err != nil {
+			return nil, err
+		}
+		if settings.RecordPath != "" || settings.OutputFormat == "har" {
+			store.KeepExchanges()
+		}
+		stages = append(stages, store)
+	}
+	if settings.RecordPath != "" {
+		recorder, err := results.NewRecorder(settings.RecordPath, settings.RecordHits)
+		if err != nil {
+			return nil, err
+		}
+		if settings.OutputFormat == "har" {
+			recorder.KeepExchanges()
+		}
+		stages = append(stages, recorder)
+	}
+	if settings.ScreenshotDir != "" {
+		screenshotter, err := browser.NewScreenshotter(settings.ChromePath, settings.ScreenshotDir)
+		if err != nil {
+			return nil, err
+		}
+		if settings.OutputPath != "" {
+			screenshotter.SetLinkBase(filepath.Dir(settings.OutputPath))
+		}
+		stages = append(stages, screenshotter)
+	}
+	if s.plugins.Len() > 0 {
+		stages = append(stages, s.plugins)
+	}
+	if len(settings.Notify) > 0 {
+		notifier, err := notify.NewNotifier(settings.Notify)
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range settings.NotifyRules {
+			if err := notifier.AddRule(rule); err != nil {
+				return nil, err
+			}
+		}
+		notifier.SetName(settings.FirstBaseURL())
+		notifier.SetChangesOnly(settings.Monitor.IsSet())
+		stages = append(stages, notifier)
+	}
+	return append(stages, s.stages...), nil
+}
+
+// Code and length of a directory's result
+type dirResult struct {
+	code   int
+	length int64
+}
+
+// Remember the results for directories, to report them again if part of
+// them is skipped.
+func (s *Scanner) rememberDir(r *results.Result) {
+	if r.URL != nil && strings.HasSuffix(r.URL.Path, "/") && s.report.Result(r) {
+		s.dirs.Store(r.URL.String(), dirResult{code: r.Code, length: r.Length})
+	}
+}
+
+// Report a directory again, noting what was skipped.
+func (s *Scanner) noteSkipped(dir *url.URL, note string) {
+	saved, ok := s.dirs.Load(dir.String())
+	if !ok {
+		return
+	}
+	r := results.NewResult(dir, "")
+	r.Code = saved.(dirResult).code
+	r.Length = saved.(dirResult).length
+	r.AddNote("%s", note)
+	// The results channel is closed once the scan is stopped
+	s.Lock()
+	defer s.Unlock()
+	if !s.stopped {
+		s.rchan <- r
+	}
+}
+
+// Hand results to the OnFound callbacks before passing them on.
+func (s *Scanner) found(in <-chan *results.Result) <-chan *results.Result {
+	out := make(chan *results.Result, cap(in))
+	go func() {
+		defer close(out)
+		for r := range in {
+			for _, f := range s.onFound {
+				f(r)
+			}
+			out <- r
+		}
+	}()
+	return out
+}
+
+// Hand results to the callbacks and the results channel.
+func (s *Scanner) deliver(resultChan <-chan *results.Result) {
+	defer close(s.finished)
+	if s.results != nil {
+		defer close(s.results)
+	}
+	defer s.plugins.Close()
+	for r := range resultChan {
+		s.current.Store(r.URL)
+		atomic.AddInt64(&s.delivered, 1)
+		for _, f := range s.onResult {
+			f(r)
+		}
+		if s.results != nil {
+			s.results <- r
+		}
+	}
+}
+
+// Wait for the work to be done or the context to be cancelled, then shut the
+// pipeline down.
+func (s *Scanner) run(ctx context.Context) {
+	workDone := make(chan bool)
+	go func() {
+		s.queue.WaitPipe()
+		close(workDone)
+	}()
+
+	logging.Logf(logging.LogDebug, "Scanner waiting for work...")
+	select {
+	case <-workDone:
+		logging.Logf(logging.LogDebug, "Work done.")
+		s.Lock()
+		s.stopped = true
+		s.Unlock()
+		s.queue.InputFinished()
+	case <-ctx.Done():
+		logging.Logf(logging.LogInfo, "Scan cancelled: %s", ctx.Err().Error())
+		s.Lock()
+		s.stopped = true
+		s.Unlock()
+		s.err = ctx.Err()
+		if s.pool != nil {
+			s.pool.Stop()
+		}
+	}
+	if s.coordinator != nil {
+		s.coordinator.Stop()
+	}
+	if s.statusListener != nil {
+		s.statusListener.Close()
+	}
+	s.reportWildcards()
+	s.reportMultiplexing()
+	s.logOperatorStop()
+	close(s.rchan)
+}
+
+// Say how requests were multiplexed over HTTP/2, if they were.
+func (s *Scanner) reportMultiplexing() {
+	factory, ok := s.factory.(*client.ProxyClientFactory)
+	if !ok {
+		return
+	}
+	if stats, ok := factory.MultiplexStats(); ok {
+		logging.Logf(logging.LogInfo, "Sent %s, instead of up to %d connections per host.", stats, s.settings.Workers)
+	}
+}
+
+// Say how much was skipped on each host with wildcard directories.
+func (s *Scanner) reportWildcards() {
+	if s.hosts == nil {
+		return
+	}
+	s.hosts.Each(func(key string, h *hosts.Host) {
+		if n := h.WildcardSkipped(); n > 0 {
+			logging.Logf(logging.LogWarning, "Skipped %d paths on %s where every path gives the same page.", n, key)
+		}
+	})
+}
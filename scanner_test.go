@@ -0,0 +1,650 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webborer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Matir/webborer/client"
+	"github.com/Matir/webborer/results"
+	ss "github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/task"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testScanSettings(base string) *ss.ScanSettings {
+	settings := ss.DefaultScanSettings()
+	settings.BaseURLs = ss.StringSliceFlag{base}
+	settings.Workers = 2
+	settings.Extensions = nil
+	settings.Mangle = false
+	settings.MangleCases = false
+	settings.ParseHTML = false
+	settings.ProgressBar = false
+	return settings
+}
+
+func TestScanner_Results(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/admin" {
+			w.Write([]byte("admin"))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	scanner := NewScanner(testScanSettings(server.URL + "/"))
+	scanner.SetWords([]string{"admin", "missing"})
+	var callbacks int
+	scanner.OnResult(func(*results.Result) {
+		callbacks++
+	})
+	var live int
+	scanner.OnFound(func(*results.Result) {
+		live++
+	})
+	var progressed bool
+	scanner.OnProgress(func(done, total int64) {
+		progressed = true
+	})
+	resChan := scanner.Results()
+	if err := scanner.Start(context.Background()); err != nil {
+		t.Fatalf("Unexpected error starting scan: %s", err)
+	}
+	if err := scanner.Start(context.Background()); err != ErrAlreadyStarted {
+		t.Errorf("Expected ErrAlreadyStarted, got %v", err)
+	}
+	found := make(map[string]int)
+	var count int
+	for r := range resChan {
+		found[r.URL.Path] = r.Code
+		count++
+	}
+	if err := scanner.Wait(); err != nil {
+		t.Errorf("Unexpected error from Wait: %s", err)
+	}
+	if found["/admin"] != http.StatusOK {
+		t.Errorf("Expected /admin to be found, got %v", found)
+	}
+	if code := found["/missing"]; code != http.StatusNotFound {
+		t.Errorf("Expected 404 for /missing, got %d", code)
+	}
+	if callbacks != count {
+		t.Errorf("Expected %d callbacks, got %d", count, callbacks)
+	}
+	if live != count {
+		t.Errorf("Expected %d found callbacks, got %d", count, live)
+	}
+	if !progressed {
+		t.Error("Expected progress callback.")
+	}
+}
+
+// Drops 404s
+type notFoundFilter struct {
+	closed bool
+}
+
+func (*notFoundFilter) Name() string {
+	return "not-found"
+}
+
+func (*notFoundFilter) Configure(map[string]string) error {
+	return nil
+}
+
+func (f *notFoundFilter) Close() error {
+	f.closed = true
+	return nil
+}
+
+func (*notFoundFilter) Keep(r *results.Result) bool {
+	return r.Code != http.StatusNotFound
+}
+
+func TestScanner_Plugin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/admin" {
+			w.Write([]byte("admin"))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	scanner := NewScanner(testScanSettings(server.URL + "/"))
+	scanner.SetWords([]string{"admin", "missing"})
+	filter := &notFoundFilter{}
+	scanner.AddPlugin(filter)
+	var paths []string
+	scanner.OnResult(func(r *results.Result) {
+		paths = append(paths, r.URL.Path)
+	})
+	if err := scanner.Start(context.Background()); err != nil {
+		t.Fatalf("Unexpected error starting scan: %s", err)
+	}
+	if err := scanner.Wait(); err != nil {
+		t.Errorf("Unexpected error from Wait: %s", err)
+	}
+	for _, p := range paths {
+		if p == "/missing" {
+			t.Error("Expected /missing to be filtered.")
+		}
+	}
+	if !filter.closed {
+		t.Error("Expected plugin to be closed.")
+	}
+}
+
+// Rewrites /missing to /admin
+type rewriteMutator struct {
+	notFoundFilter
+}
+
+func (*rewriteMutator) Mutate(t *task.Task) *task.Task {
+	if t.URL.Path != "/missing" {
+		return t
+	}
+	nt := t.Copy()
+	nt.URL.Path = "/admin"
+	return nt
+}
+
+func TestScanner_MutatedTasksFiltered(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	scanner := NewScanner(testScanSettings(server.URL + "/"))
+	scanner.SetWords([]string{"admin", "missing"})
+	scanner.AddPlugin(&rewriteMutator{})
+	admin := 0
+	scanner.OnResult(func(r *results.Result) {
+		if r.URL.Path == "/admin" {
+			admin++
+		}
+	})
+	if err := scanner.Start(context.Background()); err != nil {
+		t.Fatalf("Unexpected error starting scan: %s", err)
+	}
+	if err := scanner.Wait(); err != nil {
+		t.Errorf("Unexpected error from Wait: %s", err)
+	}
+	if admin != 1 {
+		t.Errorf("Expected the rewritten duplicate to be filtered, got %d results for /admin", admin)
+	}
+}
+
+func TestScanner_Cancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	words := make([]string, 500)
+	for i := range words {
+		words[i] = "word" + strconv.Itoa(i)
+	}
+	scanner := NewScanner(testScanSettings(server.URL + "/"))
+	scanner.SetWords(words)
+	ctx, cancel := context.WithCancel(context.Background())
+	scanner.OnResult(func(*results.Result) {
+		cancel()
+	})
+	if err := scanner.Start(ctx); err != nil {
+		t.Fatalf("Unexpected error starting scan: %s", err)
+	}
+	if err := scanner.Wait(); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestScanner_ReadTargets(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/admin" {
+			w.Write([]byte("admin"))
+			return
+		}
+		http.NotFound(w, r)
+	})
+	first := httptest.NewServer(handler)
+	defer first.Close()
+	second := httptest.NewServer(handler)
+	defer second.Close()
+
+	scanner := NewScanner(testScanSettings(ss.StdinURL))
+	scanner.SetWords([]string{"admin"})
+	pr, pw := io.Pipe()
+	scanner.ReadTargets(pr)
+	firstDone := make(chan bool)
+	found := make(map[string]bool)
+	scanner.OnResult(func(r *results.Result) {
+		found[r.URL.String()] = true
+		if r.URL.Host == first.Listener.Addr().String() && r.URL.Path == "/admin" {
+			close(firstDone)
+		}
+	})
+	if err := scanner.Start(context.Background()); err != nil {
+		t.Fatalf("Unexpected error starting scan: %s", err)
+	}
+	io.WriteString(pw, first.URL+"/\n")
+	select {
+	case <-firstDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for first target.")
+	}
+	io.WriteString(pw, "# comment\n"+strings.TrimPrefix(second.URL, "http://")+"\n")
+	pw.Close()
+	if err := scanner.Wait(); err != nil {
+		t.Errorf("Unexpected error from Wait: %s", err)
+	}
+	if !found[second.URL+"/admin"] {
+		t.Errorf("Expected second target to be scanned, got %v", found)
+	}
+	if err := scanner.AddTarget(&url.URL{Scheme: "http", Host: "localhost"}); err != ErrFinished {
+		t.Errorf("Expected ErrFinished, got %v", err)
+	}
+}
+
+func TestScanner_NotStarted(t *testing.T) {
+	scanner := NewScanner(ss.DefaultScanSettings())
+	if err := scanner.Wait(); err != ErrNotStarted {
+		t.Errorf("Expected ErrNotStarted, got %v", err)
+	}
+	if err := scanner.DumpQueue("/nonexistent"); err != ErrNotStarted {
+		t.Errorf("Expected ErrNotStarted, got %v", err)
+	}
+	if err := scanner.AddTarget(&url.URL{Scheme: "http", Host: "localhost"}); err != ErrNotStarted {
+		t.Errorf("Expected ErrNotStarted, got %v", err)
+	}
+}
+
+func TestScanner_Engagement(t *testing.T) {
+	var tagged, requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("X-Pentest-ID") == "ENG-42" {
+			tagged++
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "operator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	settings := testScanSettings(server.URL + "/")
+	settings.Workers = 1
+	settings.EngagementID = "ENG-42"
+	settings.OperatorLogPath = filepath.Join(dir, "operator.log")
+	scanner := NewScanner(settings)
+	scanner.SetWords([]string{"a", "b"})
+	resChan := scanner.Results()
+	if err := scanner.Start(context.Background()); err != nil {
+		t.Fatalf("Unexpected error starting scan: %s", err)
+	}
+	for range resChan {
+	}
+	if err := scanner.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if requests == 0 || tagged != requests {
+		t.Errorf("Expected every request tagged, got %d of %d", tagged, requests)
+	}
+	fp, err := os.Open(settings.OperatorLogPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fp.Close()
+	var entries []operatorLogEntry
+	lines := bufio.NewScanner(fp)
+	for lines.Scan() {
+		var entry operatorLogEntry
+		if err := json.Unmarshal(lines.Bytes(), &entry); err != nil {
+			t.Fatalf("Invalid log line %q: %s", lines.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 2 || entries[0].Event != "start" || entries[1].Event != "stop" {
+		t.Fatalf("Expected start and stop entries, got %v", entries)
+	}
+	start := entries[0]
+	if start.EngagementID != "ENG-42" || len(start.Targets) != 1 || len(start.SettingsHash) != 64 {
+		t.Errorf("Unexpected start entry: %+v", start)
+	}
+	if len(start.SourceIPs) != 1 || start.SourceIPs[0] != "127.0.0.1" {
+		t.Errorf("Expected the loopback source, got %v", start.SourceIPs)
+	}
+}
+
+func TestScanner_DryRun(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	settings := testScanSettings(server.URL + "/")
+	settings.DryRun = true
+	settings.RobotsMode = ss.SeedRobots
+	settings.Mangle = true
+	scanner := NewScanner(settings)
+	scanner.SetWords([]string{"admin", "index.php"})
+	var planned strings.Builder
+	scanner.SetDryRunOutput(&planned)
+	resChan := scanner.Results()
+	if err := scanner.Start(context.Background()); err != nil {
+		t.Fatalf("Unexpected error starting scan: %s", err)
+	}
+	for r := range resChan {
+		t.Errorf("Unexpected result in a dry run: %s", r.URL)
+	}
+	if err := scanner.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 0 {
+		t.Errorf("Expected no requests, got %d", requests)
+	}
+	for _, expected := range []string{"GET " + server.URL + "/\n", "GET " + server.URL + "/admin\n", "GET " + server.URL + "/index.php~\n"} {
+		if !strings.Contains(planned.String(), expected) {
+			t.Errorf("Expected %q planned, got:\n%s", expected, planned.String())
+		}
+	}
+	count, hosts := scanner.Planned()
+	if lines := int64(strings.Count(planned.String(), "\n")); count != lines || hosts != 1 {
+		t.Errorf("Expected %d requests to 1 host, got %d to %d", lines, count, hosts)
+	}
+}
+
+func TestScanner_Estimate(t *testing.T) {
+	settings := testScanSettings("http://localhost/")
+	settings.BaseURLs = append(settings.BaseURLs, "http://example.com/")
+	settings.Extensions = ss.StringSliceFlag{"php", "bak"}
+	settings.AddSlashes = false
+	settings.Workers = 10
+	settings.SleepTime = time.Second
+	scanner := NewScanner(settings)
+	scanner.SetWords([]string{"admin", "index.html", "backup_{1-3}"})
+	est, err := scanner.Estimate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The directory, admin, admin.php, admin.bak, index.html, and three of
+	// each of the backups
+	if est.PerTarget != 14 || est.Requests != 28 || !est.Unbounded {
+		t.Errorf("Expected 14 requests per target, 28 in all, got %d and %d", est.PerTarget, est.Requests)
+	}
+	if est.Rate != 10 || est.ETA() != 3*time.Second {
+		t.Errorf("Expected 10 requests/s for 3s, got %.1f for %s", est.Rate, est.ETA())
+	}
+	if !strings.Contains(est.String(), "About 28 requests") {
+		t.Errorf("Unexpected summary: %s", est)
+	}
+}
+
+func TestScanner_Control(t *testing.T) {
+	var lock sync.Mutex
+	requested := make(map[string]bool)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requested[r.Host+r.URL.Path] = true
+		lock.Unlock()
+		w.Write([]byte("page"))
+	})
+	first := httptest.NewServer(handler)
+	defer first.Close()
+	second := httptest.NewServer(handler)
+	defer second.Close()
+
+	scanner := NewScanner(testScanSettings(ss.StdinURL))
+	scanner.SetWords([]string{"admin", "other"})
+	pr, pw := io.Pipe()
+	scanner.ReadTargets(pr)
+	if err := scanner.Start(context.Background()); err != nil {
+		t.Fatalf("Unexpected error starting scan: %s", err)
+	}
+	if n, err := scanner.SetWorkers(4); err != nil || n != 4 {
+		t.Errorf("Expected 4 workers, got %d, %v", n, err)
+	}
+	if n, err := scanner.SetWorkers(0); err != nil || n != 1 {
+		t.Errorf("Expected 1 worker, got %d, %v", n, err)
+	}
+	if p, err := scanner.Progress(); err != nil || p.Workers != 1 {
+		t.Errorf("Expected progress with 1 worker, got %+v, %v", p, err)
+	}
+	if err := scanner.Exclude(first.URL + "/admin"); err != nil {
+		t.Errorf("Unexpected error excluding: %s", err)
+	}
+	secondURL, _ := url.Parse(second.URL)
+	if err := scanner.SkipHost(secondURL); err != nil {
+		t.Errorf("Unexpected error skipping host: %s", err)
+	}
+	io.WriteString(pw, first.URL+"/\n"+second.URL+"/\n")
+	pw.Close()
+	if err := scanner.Wait(); err != nil {
+		t.Errorf("Unexpected error from Wait: %s", err)
+	}
+
+	firstHost := first.Listener.Addr().String()
+	if !requested[firstHost+"/other"] {
+		t.Errorf("Expected /other to be requested, got %v", requested)
+	}
+	if requested[firstHost+"/admin"] {
+		t.Errorf("Expected /admin to be excluded, got %v", requested)
+	}
+	for path := range requested {
+		if strings.HasPrefix(path, second.Listener.Addr().String()) {
+			t.Errorf("Expected skipped host not to be requested, got %s", path)
+		}
+	}
+	if _, err := scanner.SetWorkers(2); err != ErrFinished {
+		t.Errorf("Expected ErrFinished, got %v", err)
+	}
+}
+
+func TestScanner_Status(t *testing.T) {
+	target := httptest.NewServer(http.NotFoundHandler())
+	defer target.Close()
+	settings := testScanSettings(ss.StdinURL)
+	// Stage timings are kept for the status endpoint
+	settings.StatusAddr = "127.0.0.1:0"
+	scanner := NewScanner(settings)
+	scanner.SetWords([]string{"admin"})
+	pr, pw := io.Pipe()
+	scanner.ReadTargets(pr)
+	if err := scanner.Start(context.Background()); err != nil {
+		t.Fatalf("Unexpected error starting scan: %s", err)
+	}
+	status := httptest.NewServer(scanner.StatusHandler())
+	defer status.Close()
+
+	getStatus := func(resp *http.Response, err error) *statusResponse {
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", resp.StatusCode)
+		}
+		sr := &statusResponse{}
+		if err := json.NewDecoder(resp.Body).Decode(sr); err != nil {
+			t.Fatalf("Unexpected error decoding status: %s", err)
+		}
+		return sr
+	}
+	if sr := getStatus(http.Get(status.URL + StatusPath)); sr.Workers != 2 {
+		t.Errorf("Expected 2 workers, got %+v", sr)
+	}
+	postWorkers := func(n string) (*http.Response, error) {
+		return http.Post(status.URL+WorkersPath, "application/json", strings.NewReader(`{"n": "`+n+`"}`))
+	}
+	if sr := getStatus(postWorkers("+3")); sr.Workers != 5 {
+		t.Errorf("Expected 5 workers, got %+v", sr)
+	}
+	if resp, err := postWorkers("many"); err != nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid count, got %v, %v", resp, err)
+	}
+	if resp, err := http.PostForm(status.URL+WorkersPath, url.Values{"n": {"+3"}}); err != nil || resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 for a form post, got %v, %v", resp, err)
+	}
+	if sr := getStatus(postWorkers("1000000")); sr.Workers != MaxWorkers {
+		t.Errorf("Expected %d workers, got %+v", MaxWorkers, sr)
+	}
+	if sr := getStatus(postWorkers("2")); sr.Workers != 2 {
+		t.Errorf("Expected 2 workers, got %+v", sr)
+	}
+	if resp, err := http.Get(status.URL + WorkersPath); err != nil || resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for GET of workers, got %v, %v", resp, err)
+	}
+	fmt.Fprintf(pw, "%s/\n", target.URL)
+	var requests *results.StageTiming
+	for i := 0; i < 100 && requests == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+		stages := getStatus(http.Get(status.URL + StatusPath)).Stages
+		for i := range stages {
+			if stages[i].Stage == "requests" {
+				requests = &stages[i]
+			}
+		}
+	}
+	if requests == nil {
+		t.Error("Expected request timings in the status.")
+	} else if requests.Count < 1 || requests.P95 < requests.P50 || requests.P99 < requests.P95 {
+		t.Errorf("Unexpected request timings: %+v", requests)
+	}
+	pw.Close()
+	if err := scanner.Wait(); err != nil {
+		t.Errorf("Unexpected error from Wait: %s", err)
+	}
+	if resp, err := http.Get(status.URL + StatusPath); err != nil || resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 once finished, got %v, %v", resp, err)
+	}
+}
+
+func TestRecipeRunner(t *testing.T) {
+	var mu sync.Mutex
+	requested := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested[r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/admin":
+			http.Redirect(w, r, "/admin/", http.StatusMovedPermanently)
+		case "/admin/", "/admin/config.php", "/admin/config.php.bak", "/index.html":
+			w.Write([]byte("found"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "recipe")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	common := filepath.Join(dir, "common.txt")
+	targeted := filepath.Join(dir, "targeted.txt")
+	ioutil.WriteFile(common, []byte("admin\nindex.html\n"), 0644)
+	ioutil.WriteFile(targeted, []byte("config\n"), 0644)
+	recipe, err := ss.ParseRecipe([]byte(`
+stages:
+  - name: common
+    settings:
+      wordlist: ` + common + `
+  - name: extensions
+    from: dirs
+    settings:
+      wordlist: ` + targeted + `
+      extensions: php
+  - name: mangle
+    from: files
+    settings:
+      mangle: true
+`))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing recipe: %s", err)
+	}
+
+	runner := NewRecipeRunner(testScanSettings(server.URL+"/"), recipe)
+	var stages []string
+	runner.OnStage(func(stage *ss.RecipeStage, _ *Scanner) {
+		stages = append(stages, stage.Name)
+	})
+	resChan := runner.Results()
+	if err := runner.Start(context.Background()); err != nil {
+		t.Fatalf("Unexpected error starting recipe: %s", err)
+	}
+	reported := make(map[string]int)
+	for r := range resChan {
+		if (results.ReportCodes{}).Result(r) {
+			reported[r.URL.Path]++
+		}
+	}
+	if err := runner.Wait(); err != nil {
+		t.Errorf("Unexpected error from Wait: %s", err)
+	}
+	if got := strings.Join(stages, ","); got != "common,extensions,mangle" {
+		t.Errorf("Unexpected stages: %s", got)
+	}
+	for _, path := range []string{"/admin/", "/index.html", "/admin/config.php", "/admin/config.php.bak"} {
+		if reported[path] != 1 {
+			t.Errorf("Expected %s to be reported once, got %d", path, reported[path])
+		}
+	}
+	if requested["/config.php"] != 0 || requested["/index.html/config.php"] != 0 {
+		t.Errorf("Extension stage should only scan directories: %v", requested)
+	}
+	for _, path := range []string{"/index.html.bak", "/admin/config.php.bak"} {
+		if requested[path] != 1 {
+			t.Errorf("Expected the mangle stage to try %s once, got %d", path, requested[path])
+		}
+	}
+}
+
+func TestCookieKeyFunc_Group(t *testing.T) {
+	// With the default output format, so results aren't grouped by host
+	jar := client.NewIsolatedCookieJar(cookieKeyFunc(ss.CookiesPerGroup))
+	a, _ := url.Parse("http://a.example.com/")
+	aTLS, _ := url.Parse("https://a.example.com:8443/")
+	b, _ := url.Parse("http://b.example.com/")
+	cookie := &http.Cookie{Name: "session", Value: "x", Domain: "example.com"}
+	jar.SetCookies(a, []*http.Cookie{cookie})
+	if len(jar.Cookies(aTLS)) != 1 {
+		t.Errorf("Expected cookie for %s", aTLS)
+	}
+	if len(jar.Cookies(b)) != 0 {
+		t.Errorf("Cookie leaked to %s", b)
+	}
+}
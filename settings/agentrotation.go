@@ -0,0 +1,51 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"fmt"
+)
+
+type AgentRotationOption int
+
+const (
+	RotatePerRequest = iota
+	RotatePerWorker
+	RotatePerScan
+	agentRotationMax
+)
+
+var agentRotationStrings = [...]string{
+	"request",
+	"worker",
+	"scan",
+}
+
+func (f *AgentRotationOption) String() string {
+	if f == nil {
+		return agentRotationStrings[RotatePerRequest]
+	}
+	return agentRotationStrings[*f]
+}
+
+func (f *AgentRotationOption) Set(value string) error {
+	for i, val := range agentRotationStrings {
+		if val == value {
+			*f = AgentRotationOption(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown Agent Rotation: %s", value)
+}
@@ -0,0 +1,62 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package settings provides a central interface to webborer settings.
+package settings
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a flag.Value for a number of bytes, with an optional K, M or G
+// suffix for binary multiples.
+type ByteSize int64
+
+var byteSizeUnits = []struct {
+	suffix string
+	size   ByteSize
+}{
+	{"G", 1 << 30},
+	{"M", 1 << 20},
+	{"K", 1 << 10},
+}
+
+func (b ByteSize) String() string {
+	for _, unit := range byteSizeUnits {
+		if b != 0 && b%unit.size == 0 {
+			return fmt.Sprintf("%d%s", b/unit.size, unit.suffix)
+		}
+	}
+	return strconv.FormatInt(int64(b), 10)
+}
+
+func (b *ByteSize) Set(value string) error {
+	num := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(value)), "B")
+	multiple := ByteSize(1)
+	for _, unit := range byteSizeUnits {
+		if strings.HasSuffix(num, unit.suffix) {
+			num = strings.TrimSuffix(num, unit.suffix)
+			multiple = unit.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("Invalid size: %s", value)
+	}
+	*b = ByteSize(n) * multiple
+	return nil
+}
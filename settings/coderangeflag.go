@@ -0,0 +1,90 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package settings provides a central interface to webborer settings.
+package settings
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CodeRange is an inclusive range of HTTP status codes.
+type CodeRange struct {
+	Min int
+	Max int
+}
+
+func (r CodeRange) String() string {
+	if r.Min == r.Max {
+		return strconv.Itoa(r.Min)
+	}
+	return fmt.Sprintf("%d-%d", r.Min, r.Max)
+}
+
+// CodeRangeFlag is a flag.Value that takes a comma-separated list of status
+// codes and ranges, like "200-299,301,401-403".
+type CodeRangeFlag []CodeRange
+
+func (f *CodeRangeFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	tmpslice := []string{}
+	for _, r := range *f {
+		tmpslice = append(tmpslice, r.String())
+	}
+	return strings.Join(tmpslice, ",")
+}
+
+func (f *CodeRangeFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		r, err := parseCodeRange(strings.TrimSpace(v))
+		if err != nil {
+			return err
+		}
+		*f = append(*f, r)
+	}
+	return nil
+}
+
+// Contains returns true if code is in any of the ranges.
+func (f CodeRangeFlag) Contains(code int) bool {
+	for _, r := range f {
+		if code >= r.Min && code <= r.Max {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCodeRange(value string) (CodeRange, error) {
+	lo, hi := value, value
+	if i := strings.Index(value, "-"); i > 0 {
+		lo, hi = value[:i], value[i+1:]
+	}
+	min, err := strconv.Atoi(strings.TrimSpace(lo))
+	if err != nil {
+		return CodeRange{}, fmt.Errorf("Unable to parse %s as status code.", value)
+	}
+	max, err := strconv.Atoi(strings.TrimSpace(hi))
+	if err != nil {
+		return CodeRange{}, fmt.Errorf("Unable to parse %s as status code.", value)
+	}
+	if min < 0 || min > max {
+		return CodeRange{}, fmt.Errorf("Invalid status code range %s.", value)
+	}
+	return CodeRange{Min: min, Max: max}, nil
+}
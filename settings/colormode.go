@@ -0,0 +1,65 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"fmt"
+)
+
+// When to color terminal output
+type ColorModeOption int
+
+const (
+	ColorAuto = iota
+	ColorAlways
+	ColorNever
+	colorModeMax
+)
+
+var colorModeStrings = [...]string{
+	"auto",
+	"always",
+	"never",
+}
+
+func (f *ColorModeOption) String() string {
+	if f == nil {
+		return colorModeStrings[ColorAuto]
+	}
+	return colorModeStrings[*f]
+}
+
+// Allow -color and -color=false as well as the named modes.
+func (f *ColorModeOption) Set(value string) error {
+	switch value {
+	case "true":
+		*f = ColorAlways
+		return nil
+	case "false":
+		*f = ColorNever
+		return nil
+	}
+	for i, val := range colorModeStrings {
+		if val == value {
+			*f = ColorModeOption(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown Color Mode: %s", value)
+}
+
+func (f *ColorModeOption) IsBoolFlag() bool {
+	return true
+}
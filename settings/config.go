@@ -0,0 +1,163 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"sort"
+)
+
+// Keys in a config file that are not flags
+const (
+	configProfilesKey = "profiles"
+	configProfileKey  = "profile"
+)
+
+// Apply a YAML config file to the flags in fs.  Keys are flag names, and lists
+// may be given for flags that can be repeated.  Named profiles under
+// "profiles" override the top level when selected with -profile, or with a
+// top-level "profile" key.  Flags already set are left alone, so the command
+// line always wins.
+//
+//	workers: 8
+//	extensions: [php, html]
+//	profiles:
+//	  stealth:
+//	    workers: 1
+//	    sleep: 2s
+func (settings *ScanSettings) applyConfig(data []byte, fs *flag.FlagSet) error {
+	config := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return err
+	}
+	profiles := make(map[string]map[string]interface{})
+	if raw, ok := config[configProfilesKey]; ok {
+		rawProfiles, ok := stringMap(raw)
+		if !ok {
+			return errors.New("profiles must be a map of profile names to settings")
+		}
+		for name, values := range rawProfiles {
+			if profiles[name], ok = stringMap(values); !ok {
+				return fmt.Errorf("Invalid profile: %s", name)
+			}
+		}
+		delete(config, configProfilesKey)
+	}
+	profile := settings.Profile
+	if raw, ok := config[configProfileKey]; ok {
+		if profile == "" {
+			profile = fmt.Sprint(raw)
+		}
+		delete(config, configProfileKey)
+	}
+	if profile != "" {
+		values, ok := profiles[profile]
+		if !ok {
+			return fmt.Errorf("Unknown profile: %s", profile)
+		}
+		for k, v := range values {
+			config[k] = v
+		}
+		settings.Profile = profile
+	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	keys := make([]string, 0, len(config))
+	for k := range config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if k == "config" {
+			return errors.New("config may not be set from a config file")
+		}
+		if fs.Lookup(k) == nil {
+			return fmt.Errorf("Unknown setting: %s", k)
+		}
+		if explicit[k] {
+			continue
+		}
+		if err := setConfigValue(fs, k, config[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Copy the settings, changing those named in values as a config file would,
+// except that settings given as flags are changed too.  Values for lists add
+// to them.  The copy shares the lists' storage, so it should be made just
+// before it is used.
+func (settings *ScanSettings) WithValues(values map[string]interface{}) (*ScanSettings, error) {
+	derived := &ScanSettings{}
+	fs := flag.NewFlagSet("settings", flag.ContinueOnError)
+	derived.initFlags(fs)
+	// Registering the flags reset them to their defaults
+	*derived = *settings
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if fs.Lookup(k) == nil {
+			return nil, fmt.Errorf("Unknown setting: %s", k)
+		}
+		if err := setConfigValue(fs, k, values[k]); err != nil {
+			return nil, err
+		}
+	}
+	return derived, nil
+}
+
+// Set a flag from a config value, once per element for lists.
+func setConfigValue(fs *flag.FlagSet, name string, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		for _, elem := range v {
+			if err := setConfigValue(fs, name, elem); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[interface{}]interface{}:
+		return fmt.Errorf("Invalid value for %s: expected a value or list", name)
+	}
+	if err := fs.Set(name, fmt.Sprint(value)); err != nil {
+		return fmt.Errorf("Invalid value for %s: %s", name, err.Error())
+	}
+	return nil
+}
+
+// Convert a YAML map to one keyed by strings.
+func stringMap(v interface{}) (map[string]interface{}, bool) {
+	raw, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, false
+	}
+	m := make(map[string]interface{}, len(raw))
+	for k, v := range raw {
+		m[fmt.Sprint(k)] = v
+	}
+	return m, true
+}
@@ -0,0 +1,172 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testConfig = `
+workers: 8
+extensions: [php, html]
+timeout: 10s
+html: false
+profiles:
+  stealth:
+    workers: 1
+    sleep: 2s
+  fast:
+    workers: 64
+`
+
+func testConfigFlags(settings *ScanSettings) *flag.FlagSet {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("config", "", "")
+	fs.IntVar(&settings.Workers, "workers", settings.Workers, "")
+	fs.Var(&settings.Extensions, "extensions", "")
+	fs.Var(DurationFlag{&settings.Timeout}, "timeout", "")
+	fs.Var(DurationFlag{&settings.SleepTime}, "sleep", "")
+	fs.BoolVar(&settings.ParseHTML, "html", settings.ParseHTML, "")
+	return fs
+}
+
+func TestApplyConfig(t *testing.T) {
+	settings := &ScanSettings{Workers: 2, ParseHTML: true}
+	fs := testConfigFlags(settings)
+	if err := settings.applyConfig([]byte(testConfig), fs); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if settings.Workers != 8 {
+		t.Errorf("Expected 8 workers, got %d", settings.Workers)
+	}
+	if len(settings.Extensions) != 2 || settings.Extensions[1] != "html" {
+		t.Errorf("Unexpected extensions: %v", settings.Extensions)
+	}
+	if settings.Timeout != 10*time.Second {
+		t.Errorf("Expected 10s timeout, got %s", settings.Timeout)
+	}
+	if settings.ParseHTML {
+		t.Error("Expected html to be disabled.")
+	}
+}
+
+func TestApplyConfig_Profile(t *testing.T) {
+	settings := &ScanSettings{Profile: "stealth"}
+	fs := testConfigFlags(settings)
+	if err := settings.applyConfig([]byte(testConfig), fs); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if settings.Workers != 1 || settings.SleepTime != 2*time.Second {
+		t.Errorf("Expected stealth profile, got %d workers, %s sleep", settings.Workers, settings.SleepTime)
+	}
+
+	settings = &ScanSettings{}
+	fs = testConfigFlags(settings)
+	if err := settings.applyConfig([]byte(testConfig+"profile: fast\n"), fs); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if settings.Workers != 64 || settings.Profile != "fast" {
+		t.Errorf("Expected default fast profile, got %d workers", settings.Workers)
+	}
+
+	settings = &ScanSettings{Profile: "slow"}
+	if err := settings.applyConfig([]byte(testConfig), testConfigFlags(settings)); err == nil {
+		t.Error("Expected error for unknown profile.")
+	}
+}
+
+func TestApplyConfig_FlagsOverride(t *testing.T) {
+	settings := &ScanSettings{}
+	fs := testConfigFlags(settings)
+	if err := fs.Parse([]string{"-workers", "3", "-extensions", "asp"}); err != nil {
+		t.Fatalf("Unexpected error parsing flags: %s", err)
+	}
+	settings.Profile = "fast"
+	if err := settings.applyConfig([]byte(testConfig), fs); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if settings.Workers != 3 {
+		t.Errorf("Expected flag to override config, got %d workers", settings.Workers)
+	}
+	if len(settings.Extensions) != 1 || settings.Extensions[0] != "asp" {
+		t.Errorf("Expected flag to override config, got %v", settings.Extensions)
+	}
+	if settings.Timeout != 10*time.Second {
+		t.Errorf("Expected timeout from config, got %s", settings.Timeout)
+	}
+}
+
+func TestApplyConfig_Errors(t *testing.T) {
+	for _, bad := range []string{
+		"nonexistent: 1\n",
+		"workers: many\n",
+		"workers: {a: b}\n",
+		"config: other.yaml\n",
+		"profiles: [a, b]\n",
+		"- not a map\n",
+	} {
+		settings := &ScanSettings{}
+		if err := settings.applyConfig([]byte(bad), testConfigFlags(settings)); err == nil {
+			t.Errorf("Expected error for config %q", bad)
+		}
+	}
+}
+
+func TestLoadFromConfigFile_Missing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webborer-config")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	settings := &ScanSettings{flagsSet: true}
+	if err := settings.LoadFromConfigFile(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("Expected error for missing file.")
+	}
+}
+
+func TestWithValues(t *testing.T) {
+	settings := DefaultScanSettings()
+	settings.Workers = 4
+	settings.BaseURLs = StringSliceFlag{"http://localhost/"}
+	settings.Extensions = StringSliceFlag{"html"}
+	derived, err := settings.WithValues(map[string]interface{}{
+		"workers":    10,
+		"extensions": []interface{}{"php", "bak"},
+		"mangle":     false,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if derived.Workers != 10 || derived.Mangle {
+		t.Errorf("Values not applied: %d workers, mangle %v", derived.Workers, derived.Mangle)
+	}
+	if got := derived.Extensions.String(); got != "html,php,bak" {
+		t.Errorf("Unexpected extensions: %s", got)
+	}
+	if len(derived.BaseURLs) != 1 || derived.Timeout != settings.Timeout {
+		t.Errorf("Other settings not kept: %v, %s", derived.BaseURLs, derived.Timeout)
+	}
+	if settings.Workers != 4 || !settings.Mangle || settings.Extensions.String() != "html" {
+		t.Errorf("Original settings changed: %d workers, mangle %v, %s", settings.Workers, settings.Mangle, settings.Extensions.String())
+	}
+	if _, err := settings.WithValues(map[string]interface{}{"colour": "red"}); err == nil {
+		t.Error("Expected error for unknown setting")
+	}
+}
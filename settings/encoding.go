@@ -0,0 +1,78 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package settings provides a central interface to webborer settings.
+package settings
+
+import (
+	"fmt"
+	"strings"
+)
+
+// An alternate encoding of wordlist entries
+type WordEncoding int
+
+const (
+	// Percent-encode every character
+	EncodeURL = iota
+	// Percent-encode every character twice
+	EncodeDouble
+	// Overlong two-byte UTF-8 forms of each character
+	EncodeOverlong
+	// Fullwidth forms, which Unicode normalization turns back into ASCII
+	EncodeFullwidth
+	wordEncodingMax
+)
+
+var wordEncodingStrings = [...]string{
+	"url",
+	"double",
+	"overlong",
+	"fullwidth",
+}
+
+func (e WordEncoding) String() string {
+	return wordEncodingStrings[e]
+}
+
+// A set of encodings, given as a comma-separated list.
+type WordEncodingFlag []WordEncoding
+
+func (f *WordEncodingFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	names := make([]string, len(*f))
+	for i, e := range *f {
+		names[i] = e.String()
+	}
+	return strings.Join(names, ",")
+}
+
+func (f *WordEncodingFlag) Set(value string) error {
+	for _, name := range strings.Split(value, ",") {
+		found := false
+		for i, val := range wordEncodingStrings {
+			if val == name {
+				*f = append(*f, WordEncoding(i))
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("Unknown Encoding: %s", name)
+		}
+	}
+	return nil
+}
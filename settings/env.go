@@ -0,0 +1,100 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Prefix for settings given in the environment
+const envPrefix = "WEBBORER_"
+
+// Flags whose values are never logged
+var secretFlags = map[string]bool{
+	"http-password": true,
+	"notify":        true,
+	"remote-token":  true,
+}
+
+// Name of the environment variable for a flag, e.g. WEBBORER_REMOTE_TOKEN for
+// -remote-token.
+func envName(flagName string) string {
+	name := strings.ToUpper(flagName)
+	name = strings.Replace(name, "-", "_", -1)
+	name = strings.Replace(name, ".", "_", -1)
+	return envPrefix + name
+}
+
+// Apply WEBBORER_* variables from environ to the flags in fs.  Flags that are
+// already set, whether on the command line or from a config file, are left
+// alone.  If only is given, just those flags are considered and unknown
+// variables are not reported.
+func (settings *ScanSettings) applyEnv(environ []string, fs *flag.FlagSet, only ...string) error {
+	values := make(map[string]string)
+	for _, kv := range environ {
+		if !strings.HasPrefix(kv, envPrefix) {
+			continue
+		}
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		values[parts[0]] = parts[1]
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	names := make(map[string]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		names[envName(f.Name)] = f.Name
+	})
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	wanted := make(map[string]bool)
+	for _, name := range only {
+		wanted[name] = true
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		name, ok := names[k]
+		if !ok {
+			if len(only) == 0 {
+				return fmt.Errorf("Unknown setting in environment: %s", k)
+			}
+			continue
+		}
+		if len(only) > 0 && !wanted[name] {
+			continue
+		}
+		if explicit[name] {
+			continue
+		}
+		if err := fs.Set(name, values[k]); err != nil {
+			return fmt.Errorf("Invalid value for %s: %s", k, err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEnvName(t *testing.T) {
+	cases := map[string]string{
+		"workers":      "WEBBORER_WORKERS",
+		"remote-token": "WEBBORER_REMOTE_TOKEN",
+		"url_file":     "WEBBORER_URL_FILE",
+	}
+	for flagName, expected := range cases {
+		if got := envName(flagName); got != expected {
+			t.Errorf("envName(%s): expected %s, got %s", flagName, expected, got)
+		}
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	settings := &ScanSettings{Workers: 2}
+	fs := testConfigFlags(settings)
+	environ := []string{
+		"HOME=/root",
+		"WEBBORER_WORKERS=8",
+		"WEBBORER_EXTENSIONS=php,html",
+		"WEBBORER_SLEEP=2s",
+	}
+	if err := settings.applyEnv(environ, fs); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if settings.Workers != 8 {
+		t.Errorf("Expected 8 workers, got %d", settings.Workers)
+	}
+	if len(settings.Extensions) != 2 {
+		t.Errorf("Unexpected extensions: %v", settings.Extensions)
+	}
+	if settings.SleepTime != 2*time.Second {
+		t.Errorf("Unexpected sleep: %s", settings.SleepTime)
+	}
+}
+
+func TestApplyEnv_Precedence(t *testing.T) {
+	settings := &ScanSettings{Workers: 2}
+	fs := testConfigFlags(settings)
+	if err := fs.Parse([]string{"-workers", "4"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := settings.applyConfig([]byte("sleep: 1s\n"), fs); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	environ := []string{"WEBBORER_WORKERS=8", "WEBBORER_SLEEP=2s", "WEBBORER_TIMEOUT=3s"}
+	if err := settings.applyEnv(environ, fs); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if settings.Workers != 4 {
+		t.Errorf("Expected flag to win, got %d workers", settings.Workers)
+	}
+	if settings.SleepTime != time.Second {
+		t.Errorf("Expected config file to win, got sleep %s", settings.SleepTime)
+	}
+	if settings.Timeout != 3*time.Second {
+		t.Errorf("Expected timeout from environment, got %s", settings.Timeout)
+	}
+}
+
+func TestApplyEnv_Only(t *testing.T) {
+	settings := &ScanSettings{Workers: 2}
+	fs := testConfigFlags(settings)
+	environ := []string{"WEBBORER_WORKERS=8", "WEBBORER_CONFIG=scan.yaml", "WEBBORER_BOGUS=1"}
+	if err := settings.applyEnv(environ, fs, "config"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if settings.Workers != 2 {
+		t.Errorf("Expected workers to be skipped, got %d", settings.Workers)
+	}
+	if v := fs.Lookup("config").Value.String(); v != "scan.yaml" {
+		t.Errorf("Expected config from environment, got %q", v)
+	}
+}
+
+func TestApplyEnv_Errors(t *testing.T) {
+	settings := &ScanSettings{}
+	fs := testConfigFlags(settings)
+	if err := settings.applyEnv([]string{"WEBBORER_BOGUS=1"}, fs); err == nil {
+		t.Errorf("Expected error for unknown setting.")
+	}
+	if err := settings.applyEnv([]string{"WEBBORER_WORKERS=many"}, fs); err == nil {
+		t.Errorf("Expected error for invalid value.")
+	}
+}
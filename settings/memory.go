@@ -0,0 +1,77 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package settings provides a central interface to webborer settings.
+package settings
+
+import (
+	"fmt"
+)
+
+const (
+	// Share of the memory limit for the set of URLs already tried
+	visitedMemoryShare = 4
+	// Share of the memory limit for bodies being read by workers
+	bodyMemoryShare = 4
+	// Share of the memory limit for tasks and results buffered between stages
+	queueMemoryShare = 16
+	// Rough size of a buffered task or result
+	queueSlotSize = 4 * 1024
+	// Number of buffers sized by QueueSize
+	queueBuffers = 4
+	// Smallest queue size worth scanning with
+	minQueueSize = 16
+	// Headless Chrome needs about this much on top of the scan
+	browserMemory = 1 << 30
+)
+
+// Adjust the settings to fit within MemoryLimit, returning a warning for
+// each setting that had to change.
+func (s *ScanSettings) FitMemory() []string {
+	if s.MemoryLimit <= 0 {
+		return nil
+	}
+	var warnings []string
+	limit := int64(s.MemoryLimit)
+	if queueSize := int(limit / queueMemoryShare / (queueSlotSize * queueBuffers)); s.QueueSize > queueSize {
+		if queueSize < minQueueSize {
+			queueSize = minQueueSize
+		}
+		s.QueueSize = queueSize
+		warnings = append(warnings, fmt.Sprintf("Reducing queue sizes to %d to fit the memory limit.", queueSize))
+	}
+	if s.Workers > 0 {
+		bodySize := limit / bodyMemoryShare / int64(s.Workers)
+		if s.ParseHTML && s.MaxHTMLSize > bodySize {
+			s.MaxHTMLSize = bodySize
+			warnings = append(warnings, fmt.Sprintf("Parsing at most %s of each HTML page to fit the memory limit.", ByteSize(bodySize)))
+		}
+	}
+	if limit < browserMemory {
+		if s.ScreenshotDir != "" {
+			s.ScreenshotDir = ""
+			warnings = append(warnings, "Not enough memory for headless Chrome, disabling screenshots.")
+		}
+		if s.RenderDepth >= 0 {
+			s.RenderDepth = -1
+			warnings = append(warnings, "Not enough memory for headless Chrome, disabling rendering.")
+		}
+	}
+	return warnings
+}
+
+// Memory for the set of URLs already tried, or 0 for no limit.
+func (s *ScanSettings) VisitedMemory() int64 {
+	return int64(s.MemoryLimit) / visitedMemoryShare
+}
@@ -0,0 +1,175 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Ports assumed to be web servers when the scanner didn't detect services,
+// as is usual for masscan.
+var defaultWebPorts = map[string]string{
+	"80":   "http",
+	"443":  "https",
+	"8000": "http",
+	"8008": "http",
+	"8080": "http",
+	"8443": "https",
+	"8888": "http",
+	"9443": "https",
+}
+
+// Subset of the nmap XML format, which masscan also produces.
+type nmapRun struct {
+	Scanner string     `xml:"scanner,attr"`
+	Hosts   []nmapHost `xml:"host"`
+}
+
+type nmapHost struct {
+	Status    nmapStatus     `xml:"status"`
+	Addresses []nmapAddress  `xml:"address"`
+	Hostnames []nmapHostname `xml:"hostnames>hostname"`
+	Ports     []nmapPort     `xml:"ports>port"`
+}
+
+type nmapStatus struct {
+	State string `xml:"state,attr"`
+}
+
+type nmapAddress struct {
+	Addr     string `xml:"addr,attr"`
+	AddrType string `xml:"addrtype,attr"`
+}
+
+type nmapHostname struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type nmapPort struct {
+	Protocol string      `xml:"protocol,attr"`
+	PortID   string      `xml:"portid,attr"`
+	State    nmapStatus  `xml:"state"`
+	Service  nmapService `xml:"service"`
+}
+
+type nmapService struct {
+	Name   string `xml:"name,attr"`
+	Tunnel string `xml:"tunnel,attr"`
+}
+
+// Add the open http and https services in an nmap or masscan XML file to the
+// starting URLs.
+func (settings *ScanSettings) LoadNmapFile(path string) error {
+	fp, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	urls, err := ParseNmapXML(fp)
+	if err != nil {
+		return fmt.Errorf("Error in nmap file %s: %s", path, err.Error())
+	}
+	for _, u := range urls {
+		settings.BaseURLs = append(settings.BaseURLs, u.String())
+	}
+	return nil
+}
+
+// Find the URLs of open http and https services in nmap or masscan XML.
+func ParseNmapXML(r io.Reader) ([]*url.URL, error) {
+	run := &nmapRun{}
+	if err := xml.NewDecoder(r).Decode(run); err != nil {
+		return nil, err
+	}
+	// masscan only reports banners, so its service names are just hints
+	guess := run.Scanner == "masscan"
+	var urls []*url.URL
+	seen := make(map[string]bool)
+	for _, host := range run.Hosts {
+		if host.Status.State != "" && host.Status.State != "up" {
+			continue
+		}
+		name := host.name()
+		if name == "" {
+			continue
+		}
+		for _, port := range host.Ports {
+			if port.Protocol != "tcp" || port.State.State != "open" {
+				continue
+			}
+			scheme := port.scheme(guess)
+			if scheme == "" {
+				continue
+			}
+			u := &url.URL{Scheme: scheme, Host: name, Path: "/"}
+			if !(scheme == "http" && port.PortID == "80") && !(scheme == "https" && port.PortID == "443") {
+				u.Host = net.JoinHostPort(name, port.PortID)
+			} else if strings.Contains(name, ":") {
+				u.Host = "[" + name + "]"
+			}
+			if !seen[u.String()] {
+				seen[u.String()] = true
+				urls = append(urls, u)
+			}
+		}
+	}
+	return urls, nil
+}
+
+// Name to connect to a host by, preferring the name it was scanned as.
+func (host *nmapHost) name() string {
+	for _, hostname := range host.Hostnames {
+		if hostname.Type == "user" {
+			return hostname.Name
+		}
+	}
+	for _, addr := range host.Addresses {
+		if addr.AddrType == "ipv4" || addr.AddrType == "ipv6" {
+			return addr.Addr
+		}
+	}
+	return ""
+}
+
+// Determine the URL scheme for a port from the detected service.  If no
+// service was detected, or guess is set and the service is inconclusive, the
+// port number is used.  Returns "" for other services.
+func (port *nmapPort) scheme(guess bool) string {
+	service := port.Service.Name
+	switch {
+	case strings.HasPrefix(service, "https") || strings.HasPrefix(service, "ssl/http"):
+		return "https"
+	case strings.HasPrefix(service, "http"):
+		if port.Service.Tunnel == "ssl" {
+			return "https"
+		}
+		return "http"
+	case service == "ssl" || service == "tls":
+		if defaultWebPorts[port.PortID] == "https" || guess {
+			return "https"
+		}
+		return ""
+	case service == "" || guess:
+		return defaultWebPorts[port.PortID]
+	}
+	return ""
+}
@@ -0,0 +1,94 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"strings"
+	"testing"
+)
+
+const testNmapXML = `<?xml version="1.0" encoding="UTF-8"?>
+<nmaprun scanner="nmap" args="nmap -sV -oX - example.com 10.0.0.2">
+<host><status state="up" reason="syn-ack"/>
+<address addr="93.184.216.34" addrtype="ipv4"/>
+<hostnames><hostname name="example.com" type="user"/><hostname name="edge.example.net" type="PTR"/></hostnames>
+<ports>
+<port protocol="tcp" portid="22"><state state="open"/><service name="ssh"/></port>
+<port protocol="tcp" portid="80"><state state="open"/><service name="http" product="nginx"/></port>
+<port protocol="tcp" portid="443"><state state="open"/><service name="http" tunnel="ssl"/></port>
+<port protocol="tcp" portid="8443"><state state="open"/><service name="https-alt"/></port>
+<port protocol="tcp" portid="8080"><state state="filtered"/><service name="http-proxy"/></port>
+</ports></host>
+<host><status state="up"/>
+<address addr="10.0.0.2" addrtype="ipv4"/>
+<address addr="00:11:22:33:44:55" addrtype="mac"/>
+<ports>
+<port protocol="tcp" portid="8080"><state state="open"/><service name="http-proxy"/></port>
+<port protocol="tcp" portid="9443"><state state="open"/><service name="ssl"/></port>
+<port protocol="tcp" portid="8000"><state state="open"/></port>
+</ports></host>
+<host><status state="down"/><address addr="10.0.0.3" addrtype="ipv4"/></host>
+</nmaprun>`
+
+const testMasscanXML = `<?xml version="1.0"?>
+<nmaprun scanner="masscan" start="1500000000" version="1.0-BETA">
+<host endtime="1500000001"><address addr="10.1.1.1" addrtype="ipv4"/>
+<ports><port protocol="tcp" portid="443"><state state="open" reason="syn-ack"/></port></ports></host>
+<host endtime="1500000001"><address addr="10.1.1.1" addrtype="ipv4"/>
+<ports><port protocol="tcp" portid="80"><state state="open" reason="syn-ack"/><service name="title" banner="Welcome"/></port></ports></host>
+<host endtime="1500000001"><address addr="10.1.1.1" addrtype="ipv4"/>
+<ports><port protocol="tcp" portid="80"><state state="open" reason="syn-ack"/></port></ports></host>
+<host endtime="1500000001"><address addr="fe80::1" addrtype="ipv6"/>
+<ports><port protocol="tcp" portid="80"><state state="open" reason="syn-ack"/></port></ports></host>
+</nmaprun>`
+
+func checkNmapURLs(t *testing.T, data string, expected []string) {
+	urls, err := ParseNmapXML(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	got := make([]string, len(urls))
+	for i, u := range urls {
+		got[i] = u.String()
+	}
+	if strings.Join(got, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestParseNmapXML(t *testing.T) {
+	checkNmapURLs(t, testNmapXML, []string{
+		"http://example.com/",
+		"https://example.com/",
+		"https://example.com:8443/",
+		"http://10.0.0.2:8080/",
+		"https://10.0.0.2:9443/",
+		"http://10.0.0.2:8000/",
+	})
+}
+
+func TestParseNmapXML_Masscan(t *testing.T) {
+	checkNmapURLs(t, testMasscanXML, []string{
+		"https://10.1.1.1/",
+		"http://10.1.1.1/",
+		"http://[fe80::1]/",
+	})
+}
+
+func TestParseNmapXML_Invalid(t *testing.T) {
+	if _, err := ParseNmapXML(strings.NewReader("not xml")); err == nil {
+		t.Errorf("Expected error for invalid XML.")
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package settings provides a central interface to webborer settings.
+package settings
+
+import (
+	"fmt"
+)
+
+// How to handle links and pages the site asks spiders not to follow
+type NofollowModeOption int
+
+const (
+	// Follow every link, as if there were no such requests
+	NofollowIgnore = iota
+	// Don't follow links marked nofollow, or any links on nofollow pages
+	NofollowObey
+	// Follow every link, but log those marked nofollow
+	NofollowLog
+	nofollowModeMax
+)
+
+var nofollowModeStrings = [...]string{
+	"ignore",
+	"obey",
+	"log",
+}
+
+func (f *NofollowModeOption) String() string {
+	if f == nil {
+		return nofollowModeStrings[NofollowIgnore]
+	}
+	return nofollowModeStrings[*f]
+}
+
+func (f *NofollowModeOption) Set(value string) error {
+	for i, val := range nofollowModeStrings {
+		if val == value {
+			*f = NofollowModeOption(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown Nofollow Mode: %s", value)
+}
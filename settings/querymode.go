@@ -0,0 +1,56 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package settings provides a central interface to webborer settings.
+package settings
+
+import (
+	"fmt"
+)
+
+// How to handle query strings of links found while spidering
+type QueryModeOption int
+
+const (
+	// Follow every link with its query string
+	QueryKeep = iota
+	// Follow links without their query strings
+	QueryDrop
+	// Follow a limited number of query strings per set of parameters
+	QueryLimit
+	queryModeMax
+)
+
+var queryModeStrings = [...]string{
+	"keep",
+	"drop",
+	"limit",
+}
+
+func (f *QueryModeOption) String() string {
+	if f == nil {
+		return queryModeStrings[QueryKeep]
+	}
+	return queryModeStrings[*f]
+}
+
+func (f *QueryModeOption) Set(value string) error {
+	for i, val := range queryModeStrings {
+		if val == value {
+			*f = QueryModeOption(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown Query Mode: %s", value)
+}
@@ -0,0 +1,105 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+)
+
+// Results of the previous stage a recipe stage can start from
+const (
+	RecipeFromDirs  = "dirs"
+	RecipeFromFiles = "files"
+	RecipeFromAll   = "all"
+)
+
+// A Recipe is a series of scans, each starting from what the ones before it
+// found.
+type Recipe struct {
+	Stages []*RecipeStage `yaml:"stages"`
+}
+
+// A RecipeStage is one scan in a recipe.
+type RecipeStage struct {
+	// Name of the stage, for logging
+	Name string `yaml:"name"`
+	// Results of the earlier stages to scan, or empty for the starting URLs
+	From string `yaml:"from"`
+	// Settings to change for the stage, by flag name as in a config file
+	Settings map[string]interface{} `yaml:"settings"`
+}
+
+// Load a recipe from a YAML file:
+//
+//	stages:
+//	  - name: common
+//	    settings:
+//	      wordlist: builtin:common
+//	      workers: 10
+//	  - name: extensions
+//	    from: dirs
+//	    settings:
+//	      extensions: [php, bak]
+func LoadRecipe(path string) (*Recipe, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	recipe, err := ParseRecipe(data)
+	if err != nil {
+		return nil, fmt.Errorf("Error in recipe %s: %s", path, err.Error())
+	}
+	return recipe, nil
+}
+
+// Parse a recipe in the format used by LoadRecipe.
+func ParseRecipe(data []byte) (*Recipe, error) {
+	recipe := &Recipe{}
+	if err := yaml.UnmarshalStrict(data, recipe); err != nil {
+		return nil, err
+	}
+	if len(recipe.Stages) == 0 {
+		return nil, errors.New("A recipe needs at least one stage")
+	}
+	fs := flag.NewFlagSet("recipe", flag.ContinueOnError)
+	(&ScanSettings{}).initFlags(fs)
+	for i, stage := range recipe.Stages {
+		if stage.Name == "" {
+			stage.Name = fmt.Sprintf("stage %d", i+1)
+		}
+		switch stage.From {
+		case "":
+		case RecipeFromDirs, RecipeFromFiles, RecipeFromAll:
+			if i == 0 {
+				return nil, fmt.Errorf("%s: the first stage starts from the starting URLs", stage.Name)
+			}
+		default:
+			return nil, fmt.Errorf("%s: unknown results to start from: %s", stage.Name, stage.From)
+		}
+		for k := range stage.Settings {
+			switch {
+			case k == "config" || k == "profile" || k == "recipe":
+				return nil, fmt.Errorf("%s: %s may not be set in a recipe", stage.Name, k)
+			case fs.Lookup(k) == nil:
+				return nil, fmt.Errorf("%s: unknown setting: %s", stage.Name, k)
+			}
+		}
+	}
+	return recipe, nil
+}
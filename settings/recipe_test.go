@@ -0,0 +1,64 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"testing"
+)
+
+const testRecipe = `
+stages:
+  - name: common
+    settings:
+      wordlist: builtin:common
+      workers: 10
+  - from: dirs
+    settings:
+      extensions: [php, bak]
+  - name: mangle
+    from: files
+    settings:
+      mangle: true
+`
+
+func TestParseRecipe(t *testing.T) {
+	recipe, err := ParseRecipe([]byte(testRecipe))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(recipe.Stages) != 3 {
+		t.Fatalf("Expected 3 stages, got %d", len(recipe.Stages))
+	}
+	if recipe.Stages[1].Name != "stage 2" || recipe.Stages[1].From != RecipeFromDirs {
+		t.Errorf("Unexpected second stage: %+v", recipe.Stages[1])
+	}
+	if recipe.Stages[2].From != RecipeFromFiles {
+		t.Errorf("Unexpected third stage: %+v", recipe.Stages[2])
+	}
+	bad := []string{
+		"",
+		"stages: []",
+		"stages: [{from: dirs}]",
+		"stages: [{name: a}, {from: everything}]",
+		"stages: [{settings: {colour: red}}]",
+		"stages: [{settings: {config: other.yaml}}]",
+		"stages: [{name: a, extra: 1}]",
+	}
+	for _, spec := range bad {
+		if _, err := ParseRecipe([]byte(spec)); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
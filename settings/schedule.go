@@ -0,0 +1,85 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package settings provides a central interface to webborer settings.
+package settings
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Schedule is a flag.Value for how often to repeat a scan.  It takes a
+// duration such as "6h" or "@every 6h", or one of "@hourly", "@daily" and
+// "@weekly", which start at the top of the hour, at midnight, and at midnight
+// on Sunday.
+type Schedule struct {
+	spec  string
+	every time.Duration
+}
+
+var scheduleNames = []string{"@hourly", "@daily", "@weekly"}
+
+func (s *Schedule) String() string {
+	if s == nil {
+		return ""
+	}
+	return s.spec
+}
+
+func (s *Schedule) Set(value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		*s = Schedule{}
+		return nil
+	}
+	for _, name := range scheduleNames {
+		if value == name {
+			*s = Schedule{spec: value}
+			return nil
+		}
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(value, "@every")))
+	if err != nil {
+		return fmt.Errorf("Invalid schedule %q: %s", value, err.Error())
+	}
+	if d <= 0 {
+		return fmt.Errorf("Invalid schedule %q: must be positive.", value)
+	}
+	*s = Schedule{spec: value, every: d}
+	return nil
+}
+
+// IsSet returns true if a schedule was given.
+func (s Schedule) IsSet() bool {
+	return s.spec != ""
+}
+
+// Next returns the time of the next scan after one that started at t.
+func (s Schedule) Next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+	year, month, day := t.Date()
+	switch s.spec {
+	case "@hourly":
+		return t.Truncate(time.Hour).Add(time.Hour)
+	case "@daily":
+		return time.Date(year, month, day+1, 0, 0, 0, 0, t.Location())
+	case "@weekly":
+		return time.Date(year, month, day+7-int(t.Weekday()), 0, 0, 0, 0, t.Location())
+	}
+	return t
+}
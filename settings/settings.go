@@ -20,6 +20,8 @@ import (
 	"flag"
 	"fmt"
 	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/util"
+	"io/ioutil"
 	"net/url"
 	"os"
 	"runtime"
@@ -33,92 +35,420 @@ import (
 type ScanSettings struct {
 	// Starting point and scope of scan
 	BaseURLs StringSliceFlag
+	// File of targets with per-target settings
+	TargetsPath string
+	// Targets with settings that override these
+	Targets []*Target
+	// nmap or masscan XML files to find web servers in
+	NmapPaths StringSliceFlag
 	// Number of threads to run
 	Threads int
 	// Number of workers to run
 	Workers int
+	// Workers allowed on any one host at once (0 for no limit)
+	ThreadsPerHost int
 	// Exclusions
 	ExcludePaths StringSliceFlag
 	// Proxies
 	Proxies StringSliceFlag
 	// Operating mode
 	RunMode RunModeOption
+	// Check links to pages outside the scope in link check mode
+	CheckExternal bool
 	// Parse HTML for links?
 	ParseHTML bool
+	// Fetch each static asset once, whatever its query string
+	CacheAssets bool
+	// How to handle query strings of links found in HTML
+	QueryMode QueryModeOption
+	// Query strings to follow per set of parameters, for QueryLimit
+	QueryLimit int
+	// How to handle rel="nofollow", robots meta tags and X-Robots-Tag
+	NofollowMode NofollowModeOption
 	// Time to sleep between requests, per thread
 	SleepTime time.Duration
+	// Maximum random delay added to SleepTime
+	Jitter time.Duration
+	// Times to retry a request after a 429 or a 503 with Retry-After, pausing
+	// its host first (0 to report them as results)
+	RateLimitRetries int
+	// Longest to pause a host for a single rate limited response
+	RateLimitMaxWait time.Duration
+	// Randomize the order of wordlist entries
+	Shuffle bool
 	// Log file path
 	LogfilePath string
 	// Level of logging
 	LogLevel string
 	// Wordlist for scanning
 	WordlistPath string
+	// SHA-256 checksum the wordlist must have
+	WordlistSHA256 string
+	// Directory to cache downloaded wordlists in
+	WordlistCache string
+	// Words to put before and after each wordlist entry, trying every
+	// combination
+	PrefixListPath string
+	SuffixListPath string
+	// Most combinations of prefixes, words and suffixes to try in each
+	// directory (0 for no limit)
+	PermutationLimit int
 	// Extensions for mangling
 	Extensions StringSliceFlag
 	// Whether or not to mangle by adding extensions
 	Mangle bool
 	// How long should internal queues be sized
 	QueueSize int
+	// Memory the scan should adapt to stay within (0 for no limit)
+	MemoryLimit ByteSize
 	// Timeout for network requests
 	Timeout time.Duration
+	// Flag responses that take at least this long (0 to disable)
+	SlowThreshold time.Duration
 	// Output type
 	OutputFormat string
 	// Output path
 	OutputPath string
+	// Directory to also write a report for each host in
+	SplitDir string
+	// Name of each host's report, with {host}, {date}, {format} and {ext}
+	SplitName string
+	// Print hits to stdout as they are found
+	Live bool
+	// When to color terminal output by status code
+	Color ColorModeOption
+	// Only print hits in human output
+	Quiet bool
+	// Also print errors in human output
+	Verbose bool
 	// User-Agent for requests
 	UserAgent string
+	// Named header profile to send with requests
+	HeaderProfile string
+	// Pick random header profiles
+	RandomAgent bool
+	// How often random header profiles are rotated
+	AgentRotation AgentRotationOption
+	// Exact order and casing of request headers, or "profile"
+	HeaderOrder StringSliceFlag
+	// Send request paths exactly as given in wordlists and URLs
+	RawPaths bool
 	// HTTP Method to use
 	Method string
 	// Whether to include redirects in reporting
 	IncludeRedirects bool
+	// Which redirects to follow
+	RedirectPolicy RedirectPolicyOption
+	// Maximum number of redirects to follow
+	MaxRedirects int
+	// Treat /foo -> /foo/ redirects as the same resource
+	IgnoreSlashRedirects bool
+	// Collapse this many redirects to the same place into one result
+	CollapseRedirects int
+	// Analyze response headers for findings
+	AnalyzeHeaders bool
+	// Flag results that took far longer than usual for their host
+	TimingAnomalies bool
+	// Confirm known sensitive files by their content
+	SensitiveChecks bool
+	// Rules for sensitive files, instead of the built-in ones
+	SensitiveRulesPath string
+	// Request the paths of known sensitive files in each starting directory
+	ProbeSensitive bool
+	// Request high-value dotfiles in every directory and /.well-known/
+	// entries on every host
+	ProbeCommon bool
+	// Hash each host's favicon to identify the product
+	FaviconHash bool
+	// File of favicon hashes and products to add to the built-in ones
+	FaviconDBPath string
+	// Hold results until the scan is done and output the most interesting
+	// first
+	SortInterest bool
+	// Print a per-host exposure score summary when done
+	ScoreSummary bool
+	// File to append per-host exposure scores to
+	ScorePath string
+	// Print scan statistics when done
+	StatsSummary bool
+	// File to write scan statistics to as JSON
+	StatsPath string
+	// File to write the wordlist entries that found something to
+	LearnWordlistPath string
+	// Results triaged in previous scans
+	TriagePath string
+	// Previous JSON results to compare against
+	ComparePath string
+	// Directory keeping the results of each scan
+	HistoryDir string
+	// How often to repeat the scan
+	Monitor Schedule
+	// Send If-None-Match and If-Modified-Since from the previous scan
+	ConditionalRequests bool
+	// Address to serve remote agents on
+	Coordinator string
+	// URL of the coordinator to run as an agent for
+	Agent string
+	// Shared secret between coordinator and agents
+	RemoteToken string
+	// Certificate and key the coordinator serves agents with
+	RemoteCert string
+	RemoteKey  string
+	// CA agents trust the coordinator's certificate from
+	RemoteCA string
+	// Address to serve the status of the scan on
+	StatusAddr string
+	// Adapt to servers that drop persistent connections
+	AdaptKeepAlive bool
+	// Close connections after each request
+	NoKeepAlive bool
+	// Idle connections to keep per host (0 for the default)
+	MaxIdlePerHost int
+	// Connections per host across all workers (0 for no limit)
+	MaxConnsPerHost int
+	// Resume TLS sessions on new connections
+	TLSResumption bool
+	// Multiplex HTTPS requests to each host over a few HTTP/2 connections
+	HTTP2 bool
+	// HTTP/2 connections per host, and requests at once on each
+	HTTP2Conns   int
+	HTTP2Streams int
+	// Range of local ports to connect from
+	SourcePorts string
+	// Unix domain socket to send every request to
+	UnixSocket string
+	// DNS server for lookups, instead of the system resolver
+	Resolver string
+	// Addresses for hosts, as host:port:address
+	Resolve RepeatedStringFlag
+	// Connect over IPv4 or IPv6 first, for hosts with both
+	PreferIPv4 bool
+	PreferIPv6 bool
+	// Only read the status line and headers of each response
+	HeadersOnly bool
+	// Maximum number of bytes of each HTML page to parse for links
+	MaxHTMLSize int64
+	// Hash response bodies
+	HashBodies bool
+	// Group this many responses with the same body into one result
+	GroupDuplicates int
+	// Maximum fuzzy hash distance for bodies to be considered duplicates
+	FuzzyDistance int
+	// Hide results with these status codes
+	FilterCodes CodeRangeFlag
+	// Hide results with these content lengths
+	FilterLengths CodeRangeFlag
+	// Hide results whose body matches this regular expression
+	FilterRegex string
+	// Hide results for a URL already reported with the same status code
+	Dedupe bool
+	// Report at most this many results with similar bodies (0 for no limit)
+	FilterSimilar int
+	// Summarize hosts where this fraction of paths redirect
+	RedirectFanout float64
+	// Directory to save screenshots of hits in
+	ScreenshotDir string
+	// Path to Chrome for screenshots and rendering
+	ChromePath string
+	// Render pages at most this deep in headless Chrome (-1 to disable)
+	RenderDepth int
+	// Directory or HAR file to record raw requests and responses in
+	RecordPath string
+	// Only record requests and responses for hits, with -record or HAR output
+	RecordHits bool
+	// Directory to save each distinct response body in, named by its hash
+	BodyStoreDir string
+	// Request random paths in each directory to establish baselines
+	Calibrate bool
+	// Number of calibration requests per directory
+	CalibrationSamples int
+	// How often to refresh directory baselines
+	CalibrationRefresh time.Duration
+	// Skip the rest of a directory when calibration finds every path in it
+	// gives the same response
+	WildcardExit bool
+	// Re-request reported results after the scan and tag or drop those that
+	// don't reproduce
+	Verify VerifyModeOption
+	// Time to wait after the scan before re-requesting results
+	VerifyDelay time.Duration
+	// Proxy to use when re-requesting results
+	VerifyProxy string
+	// Webhook URLs to notify of hits and completion
+	Notify RepeatedStringFlag
+	// Rules for results to send notifications for
+	NotifyRules RepeatedStringFlag
+	// Rules attaching tags to results
+	TagRules RepeatedStringFlag
+	// Plugins to load, as name[:key=value,...]
+	Plugins RepeatedStringFlag
 	// How to handle Robots.txt
 	RobotsMode RobotsModeOption
+	// Enumerate IIS short names before the scan
+	IISShortNames bool
 	// Whether to allow upgrade from http to https
 	AllowHTTPSUpgrade bool
+	// Probe schemes and alternate ports for targets given without a scheme
+	ProbeSchemes bool
+	// Switch hosts that redirect every http request over to https
+	AutoUpgrade bool
+	// Scan one of each set of targets resolving to the same addresses
+	DedupeHosts bool
+	// Also require deduplicated targets to give the same response
+	DedupeContent bool
 	// Spider which http response codes
-	SpiderCodes IntSliceFlag
+	SpiderCodes CodeRangeFlag
+	// Never spider these http response codes
+	SpiderExcludeCodes CodeRangeFlag
+	// Maximum requests under any one directory (0 for no limit)
+	DirBudget int
+	// Maximum steps from a starting URL (0 for no limit)
+	MaxDepth int
+	// URLs allowed to match a crawler trap pattern (0 to disable)
+	TrapLimit int
+	// Report only these http response codes
+	IncludeCodes CodeRangeFlag
+	// Never report these http response codes
+	ExcludeCodes CodeRangeFlag
+	// Retry paths with these http response codes using bypass variants
+	BypassCodes CodeRangeFlag
+	// Keep cookies between requests
+	Cookies bool
+	// How to separate cookies between targets
+	CookieIsolation CookieIsolationOption
 	// HTTP Auth Username
 	HTTPUsername string
 	// HTTP Auth Password
 	HTTPPassword string
+	// File of credentials for groups of targets
+	CredentialsPath string
+	// Command to run, or URL to fetch, for a new bearer token when one stops
+	// working
+	TokenRefreshCommand string
+	TokenRefreshURL     string
+	// List the requests the scan would send without sending them
+	DryRun bool
+	// Ask before starting scans estimated to send more requests than this
+	// (0 never to ask)
+	ConfirmAbove int64
+	// Start without asking
+	AssumeYes bool
 	// Headers *always* sent
 	Header HeaderFlag
+	// Identifier of the engagement, sent in EngagementHeader with every
+	// request
+	EngagementID     string
+	EngagementHeader string
+	// File to append start and stop records of each scan to
+	OperatorLogPath string
 	// Headers sometimes sent
 	OptionalHeader HeaderFlag
+	// Host header values to try every path with
+	VirtualHosts StringSliceFlag
+	// Send X-Forwarded-Host with the Host header in use
+	ForwardedHost bool
+	// Value for X-Forwarded-For
+	ForwardedFor string
+	// Send absolute URIs in request lines
+	AbsoluteURI bool
 	// Progress bar
 	ProgressBar bool
 	// Add slashes
 	AddSlashes bool
+	// Whether wordlist entries are tried as directories, files or both
+	SlashMode SlashModeOption
 	// MangleCases
 	MangleCases bool
+	// Alternate encodings of wordlist entries to try
+	Encodings WordEncodingFlag
+	// Encode only this many words of each wordlist (0 for all)
+	EncodeLimit int
+	// Check whether hosts ignore the case of paths
+	DetectCase bool
+	// Check how hosts treat trailing slashes
+	DetectSlash bool
+	// Measure scan speed, against a built-in test server if no URL is given
+	Benchmark bool
 	// Whether or not to do CPU Profiling
 	DebugCPUProf bool
-	// Config file used when loading (for debugging only)
-	configPath string
+	// Where to write a Go execution trace
+	TracePath string
+	// Where to write queue snapshots on signal
+	QueueDumpPath string
+	// Config file to load settings from
+	ConfigPath string
+	// Named profile from the config file to use
+	Profile string
+	// Recipe file of scan stages to run
+	RecipePath string
 	// Have flags been set up?
 	flagsSet bool
 }
 
 var DefaultUserAgent = "WebBorer 0.01"
+
+// Starting URL meaning that more are read from stdin as the scan runs
+const StdinURL = "-"
+
 var outputFormats []string
 
-// Constructs a ScanSettings struct with all of the defaults to be used.
+// Constructs a ScanSettings struct with all of the defaults to be used, and
+// registers the command line flags for it.
 func NewScanSettings() *ScanSettings {
+	settings := DefaultScanSettings()
+	settings.InitFlags()
+	return settings
+}
+
+// Constructs a ScanSettings struct with all of the defaults, without touching
+// command line flags.  This is suitable for use when embedding webborer.
+func DefaultScanSettings() *ScanSettings {
 	settings := &ScanSettings{
-		Threads:        runtime.NumCPU(),
-		Extensions:     []string{"html", "php", "asp", "aspx", "js", "txt"},
-		Method:         "GET",
-		Mangle:         true,
-		QueueSize:      1024,
-		Timeout:        30 * time.Second,
-		LogLevel:       "WARNING",
-		SpiderCodes:    IntSliceFlag{200},
-		ProgressBar:    true,
-		RunMode:        RunModeEnumeration,
-		Header:         make(HeaderFlag),
-		OptionalHeader: make(HeaderFlag),
+		Threads:            runtime.NumCPU(),
+		Workers:            runtime.NumCPU() * 2,
+		ParseHTML:          true,
+		CheckExternal:      true,
+		DetectCase:         true,
+		DetectSlash:        true,
+		QueryLimit:         5,
+		EngagementHeader:   "X-Pentest-ID",
+		ConfirmAbove:       1000000,
+		RateLimitRetries:   5,
+		RateLimitMaxWait:   5 * time.Minute,
+		EncodeLimit:        100,
+		UserAgent:          DefaultUserAgent,
+		Extensions:         []string{"html", "php", "asp", "aspx", "js", "txt"},
+		Method:             "GET",
+		Mangle:             true,
+		QueueSize:          1024,
+		Timeout:            30 * time.Second,
+		SplitName:          "{host}_{date}.{ext}",
+		PermutationLimit:   100000,
+		LogLevel:           "WARNING",
+		SpiderCodes:        CodeRangeFlag{{Min: 200, Max: 200}},
+		TrapLimit:          500,
+		MaxRedirects:       10,
+		CalibrationSamples: 3,
+		WildcardExit:       true,
+		AnalyzeHeaders:     true,
+		SensitiveChecks:    true,
+		ProbeSchemes:       true,
+		AutoUpgrade:        true,
+		FaviconHash:        true,
+		FuzzyDistance:      -1,
+		RedirectFanout:     0.9,
+		MaxHTMLSize:        10 * 1024 * 1024,
+		HTTP2Conns:         2,
+		HTTP2Streams:       100,
+		RenderDepth:        -1,
+		ProgressBar:        true,
+		RunMode:            RunModeEnumeration,
+		Header:             make(HeaderFlag),
+		OptionalHeader:     make(HeaderFlag),
+	}
+	if len(outputFormats) > 0 {
+		settings.OutputFormat = outputFormats[0]
 	}
-	settings.InitFlags()
 	return settings
 }
 
@@ -127,9 +457,37 @@ func NewScanSettings() *ScanSettings {
 // settings.
 func GetScanSettings() (*ScanSettings, error) {
 	settings := NewScanSettings()
-	settings.LoadFromDefaultConfigFiles()
 	settings.ParseFlags()
+	// The config file itself may be chosen from the environment
+	environ := os.Environ()
+	if err := settings.applyEnv(environ, flag.CommandLine, "config", "profile"); err != nil {
+		return nil, err
+	}
+	var err error
+	if settings.ConfigPath != "" {
+		err = settings.LoadFromConfigFile(settings.ConfigPath)
+	} else {
+		err = settings.LoadFromDefaultConfigFiles()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := settings.applyEnv(environ, flag.CommandLine); err != nil {
+		return nil, err
+	}
+	if settings.TargetsPath != "" {
+		if err := settings.LoadTargetsFile(settings.TargetsPath); err != nil {
+			return nil, err
+		}
+	}
+	for _, path := range settings.NmapPaths {
+		if err := settings.LoadNmapFile(path); err != nil {
+			return nil, err
+		}
+	}
 	if err := settings.Validate(); err != nil {
+		os.Stderr.WriteString("Usage:\n")
+		flag.PrintDefaults()
 		return nil, err
 	}
 	return settings, nil
@@ -140,70 +498,238 @@ func (settings *ScanSettings) InitFlags() {
 	if settings.flagsSet {
 		return
 	}
+	settings.initFlags(flag.CommandLine)
+	settings.flagsSet = true
+}
 
-	flag.Var(&settings.BaseURLs, "url", "Starting `URL` & scopes.")
-	flag.Var(&StringSliceFileFlag{&settings.BaseURLs}, "url_file", "Starting `URL` & scopes, loaded from a file.")
+// Register all of the flags in fs, bound to these settings.  Registering a
+// flag sets its default.
+func (settings *ScanSettings) initFlags(fs *flag.FlagSet) {
+
+	fs.StringVar(&settings.ConfigPath, "config", "", "Load settings from YAML config `file`.  Flags override the file.")
+	fs.StringVar(&settings.Profile, "profile", "", "Use the named `profile` from the config file.")
+	fs.StringVar(&settings.RecipePath, "recipe", "", "Run the stages of the scan recipe in `file` one after another, each scanning what the one before found.")
+	fs.Var(&settings.BaseURLs, "url", "Starting `URL` & scopes, or - to read them from stdin as the scan runs.")
+	fs.Var(&StringSliceFileFlag{&settings.BaseURLs}, "url_file", "Starting `URL` & scopes, loaded from a file.")
+	fs.Var(&settings.NmapPaths, "nmap-xml", "Scan http and https services found in nmap or masscan XML `files`.")
+	fs.StringVar(&settings.TargetsPath, "targets", "", "Load targets with per-target method, headers, wordlist, and depth from `file`.")
 	runModeHelp := fmt.Sprintf("Run `mode`. Options: [%s]", strings.Join(runModeStrings[:], ", "))
-	flag.Var(&settings.RunMode, "mode", runModeHelp)
-	flag.IntVar(&settings.Threads, "threads", runtime.NumCPU(), "Number of worker `threads`.")
-	flag.IntVar(&settings.Workers, "workers", runtime.NumCPU()*2, "Number of `workers`.")
-	flag.Var(&settings.ExcludePaths, "exclude", "List of `paths` to exclude from search.")
-	flag.BoolVar(&settings.ParseHTML, "html", true, "Parse HTML documents for links to follow.")
-	flag.BoolVar(&settings.AllowHTTPSUpgrade, "allow-upgrade", false, "Allow HTTP->HTTPS upgrades.")
+	fs.Var(&settings.RunMode, "mode", runModeHelp)
+	fs.BoolVar(&settings.CheckExternal, "check-external", settings.CheckExternal, "In linkcheck mode, also check links to pages outside the scope with HEAD requests.")
+	fs.IntVar(&settings.Threads, "threads", settings.Threads, "Number of worker `threads`.")
+	fs.IntVar(&settings.Workers, "workers", settings.Workers, "Number of `workers`.")
+	fs.IntVar(&settings.ThreadsPerHost, "threads-per-host", 0, "Allow at most `count` workers on any one host at once (0 for no limit).")
+	fs.Var(&settings.MemoryLimit, "memory-limit", "Adapt the scan to use at most about `size` of memory, e.g. 512M, by shrinking queues, limiting bodies and remembering tried URLs approximately.")
+	fs.Var(&settings.ExcludePaths, "exclude", "List of `paths` to exclude from search.")
+	fs.BoolVar(&settings.ParseHTML, "html", settings.ParseHTML, "Parse HTML documents for links to follow.")
+	fs.BoolVar(&settings.CacheAssets, "cache-assets", false, "Fetch each script, stylesheet, image and font once, reporting other links to it, such as with different query strings, from the first response.")
+	queryModeHelp := fmt.Sprintf("Handle query strings of links found in HTML by `mode`.  Options: [%s]", strings.Join(queryModeStrings[:], ", "))
+	fs.Var(&settings.QueryMode, "query-mode", queryModeHelp)
+	fs.IntVar(&settings.QueryLimit, "query-limit", settings.QueryLimit, "With -query-mode=limit, follow this `many` query strings for each page and set of parameters.")
+	nofollowModeHelp := fmt.Sprintf("Handle links marked nofollow, by rel attributes, robots meta tags or X-Robots-Tag headers, by `mode`.  Options: [%s]", strings.Join(nofollowModeStrings[:], ", "))
+	fs.Var(&settings.NofollowMode, "nofollow", nofollowModeHelp)
+	fs.BoolVar(&settings.AllowHTTPSUpgrade, "allow-upgrade", false, "Allow HTTP->HTTPS upgrades.")
+	fs.BoolVar(&settings.AutoUpgrade, "auto-upgrade", settings.AutoUpgrade, "Once a host redirects every http request to the same path over https, request its remaining paths over https instead.")
+	fs.BoolVar(&settings.DedupeHosts, "dedupe-hosts", false, "Resolve the starting hosts and scan only one of each set that resolves to the same addresses, recording the rest as aliases.")
+	fs.BoolVar(&settings.DedupeContent, "dedupe-content", false, "With -dedupe-hosts, also require the hosts of a set to give the same response to the starting URL.")
+	fs.BoolVar(&settings.ProbeSchemes, "probe-schemes", settings.ProbeSchemes, "For targets given without a scheme, probe http and https on their default ports and 8080 and 8443, and scan the live ones.  Otherwise http is used.")
 	sleepTimeValue := DurationFlag{&settings.SleepTime}
-	flag.Var(sleepTimeValue, "sleep", "Time (as `duration`) to sleep between requests.")
-	flag.StringVar(&settings.LogfilePath, "logfile", "", "Logfile `filename` (defaults to stderr)")
-	flag.StringVar(&settings.WordlistPath, "wordlist", "", "Wordlist `filename` to use (default built-in)")
-	flag.Var(&settings.Extensions, "extensions", "List of `extensions` to mangle with.")
-	flag.BoolVar(&settings.Mangle, "mangle", true, "Mangle by adding extensions.")
-	flag.BoolVar(&settings.MangleCases, "cases", false, "Modify the wordlist with alternate cases.")
-	flag.BoolVar(&settings.AddSlashes, "slashes", false, "Add slashes to paths to check for servers that don't redirect.")
-	flag.Var(&settings.Header, "header", "Headers to send with each request.")
-	flag.Var(&settings.OptionalHeader, "optional-header", "Headers to try sending one at a time.")
-	flag.Var(&settings.Proxies, "proxy", "Proxy or `proxies` to use.")
+	fs.Var(sleepTimeValue, "sleep", "Time (as `duration`) to sleep between requests.")
+	jitterValue := DurationFlag{&settings.Jitter}
+	fs.Var(jitterValue, "jitter", "Maximum random `duration` added to each sleep.")
+	fs.IntVar(&settings.RateLimitRetries, "rate-limit-retries", settings.RateLimitRetries, "Pause the host and retry a request up to `count` times after a 429, or a 503 with Retry-After (0 to report them).")
+	rateLimitMaxWaitValue := DurationFlag{&settings.RateLimitMaxWait}
+	fs.Var(rateLimitMaxWaitValue, "rate-limit-max-wait", "Longest `duration` to pause a host for a single rate limited response.")
+	fs.BoolVar(&settings.Shuffle, "shuffle", false, "Randomize the order wordlist entries are requested.")
+	fs.StringVar(&settings.LogfilePath, "logfile", "", "Logfile `filename` (defaults to stderr)")
+	fs.StringVar(&settings.WordlistPath, "wordlist", "", "Wordlist `filename` to use, or a built-in list: builtin:common, builtin:api-endpoints, builtin:backup-files or builtin:short (default built-in)")
+	fs.StringVar(&settings.WordlistSHA256, "wordlist-sha256", "", "Only use the -wordlist file or download if it has this SHA-256 `checksum`.")
+	fs.StringVar(&settings.PrefixListPath, "prefix-list", "", "Also try every wordlist entry after each word in `file`, combined with -suffix-list if given.")
+	fs.StringVar(&settings.SuffixListPath, "suffix-list", "", "Also try every wordlist entry before each word in `file`, combined with -prefix-list if given.")
+	fs.IntVar(&settings.PermutationLimit, "permutation-limit", settings.PermutationLimit, "Try at most `count` combinations from -prefix-list and -suffix-list in each directory (0 for no limit).")
+	fs.StringVar(&settings.WordlistCache, "wordlist-cache", "", "Keep wordlists downloaded over HTTP(S) in `dir` and use them from there in later scans.")
+	fs.Var(&settings.Extensions, "extensions", "List of `extensions` to mangle with.")
+	fs.BoolVar(&settings.Mangle, "mangle", settings.Mangle, "Mangle by adding extensions.")
+	fs.BoolVar(&settings.MangleCases, "cases", false, "Modify the wordlist with alternate cases.")
+	encodingsHelp := fmt.Sprintf("Also try wordlist entries with these comma-separated `encodings`.  Options: [%s]", strings.Join(wordEncodingStrings[:], ", "))
+	fs.Var(&settings.Encodings, "encodings", encodingsHelp)
+	fs.IntVar(&settings.EncodeLimit, "encode-limit", settings.EncodeLimit, "With -encodings, only encode the first `count` words of each wordlist (0 for all).")
+	fs.BoolVar(&settings.DetectCase, "detect-case", settings.DetectCase, "Detect hosts that ignore the case of paths, and only try each path once on them.")
+	fs.BoolVar(&settings.DetectSlash, "detect-slash", settings.DetectSlash, "Detect hosts that redirect or ignore trailing slashes, and don't try wordlist entries with a slash after trying them without on them.")
+	fs.BoolVar(&settings.AddSlashes, "slashes", false, "Add slashes to paths to check for servers that don't redirect.")
+	slashModeHelp := fmt.Sprintf("Try wordlist entries by `mode`: as-is, as directories with a trailing slash, as files without one, or both.  Options: [%s]", strings.Join(slashModeStrings[:], ", "))
+	fs.Var(&settings.SlashMode, "slash-mode", slashModeHelp)
+	fs.Var(&settings.Header, "header", "Headers to send with each request.  Values may use {{uuid}}, {{path}} and other placeholders.")
+	fs.BoolVar(&settings.DryRun, "dry-run", false, "List the requests the scan would send, without sending any, and count them.")
+	fs.Int64Var(&settings.ConfirmAbove, "confirm-above", settings.ConfirmAbove, "Ask before starting a scan estimated to send more than `count` requests (0 never to ask).")
+	fs.BoolVar(&settings.AssumeYes, "y", false, "Start the scan without asking, however large.")
+	fs.StringVar(&settings.EngagementID, "engagement-id", "", "Tag every request with engagement `id`, in the -engagement-header header.")
+	fs.StringVar(&settings.EngagementHeader, "engagement-header", settings.EngagementHeader, "`Name` of the header carrying -engagement-id.")
+	fs.StringVar(&settings.OperatorLogPath, "operator-log", "", "Append when each scan starts and stops, where from, and a hash of its settings, to `file` as JSON.")
+	fs.Var(&settings.OptionalHeader, "optional-header", "Headers to try sending one at a time.")
+	fs.Var(&settings.VirtualHosts, "vhosts", "Also request every path with each of these comma-separated Host header `values`.")
+	fs.BoolVar(&settings.ForwardedHost, "forwarded-host", false, "Send X-Forwarded-Host with the Host header of each request.")
+	fs.StringVar(&settings.ForwardedFor, "forwarded-for", "", "Send X-Forwarded-For with this `address`.")
+	fs.BoolVar(&settings.AbsoluteURI, "absolute-uri", false, "Send absolute URIs in request lines, as to a proxy.  Requests use HTTP/1.1 only.")
+	fs.Var(&settings.Proxies, "proxy", "Proxy or `proxies` to use.")
 	timeoutValue := DurationFlag{&settings.Timeout}
-	flag.Var(timeoutValue, "timeout", "Network connection timeout (`duration`).")
+	fs.Var(timeoutValue, "timeout", "Network connection timeout (`duration`).")
+	slowThresholdValue := DurationFlag{&settings.SlowThreshold}
+	fs.Var(slowThresholdValue, "slow-threshold", "Flag responses taking at least `duration` in total as slow (0 to disable).")
 	if len(outputFormats) > 1 {
 		formatHelp := fmt.Sprintf("Output `format`.  Options: [%s]", strings.Join(outputFormats, ", "))
-		flag.StringVar(&settings.OutputFormat, "format", outputFormats[0], formatHelp)
+		fs.StringVar(&settings.OutputFormat, "format", settings.OutputFormat, formatHelp)
 	}
-	flag.StringVar(&settings.OutputPath, "outfile", "", "Output `file`, defaults to stdout.")
+	fs.StringVar(&settings.OutputPath, "outfile", "", "Output `file`, defaults to stdout.")
+	fs.StringVar(&settings.SplitDir, "split-dir", "", "Also write a report for each host to a file in `dir`.")
+	fs.StringVar(&settings.SplitName, "split-name", settings.SplitName, "Name `template` for the reports in -split-dir, using {host}, {date}, {format} and {ext}.")
+	fs.BoolVar(&settings.Live, "live", false, "Print hits to stdout as they are found, while the report is written to -outfile.")
+	colorModeHelp := fmt.Sprintf("Color `mode` for terminal output.  Options: [%s]", strings.Join(colorModeStrings[:], ", "))
+	fs.Var(&settings.Color, "color", colorModeHelp)
+	fs.BoolVar(&settings.Quiet, "q", false, "Only print hits in human output, without a summary.")
+	fs.BoolVar(&settings.Verbose, "v", false, "Also print errors in human output.")
 	loglevelHelp := fmt.Sprintf("Log `level`.  Options: [%s]", strings.Join(logging.LogLevelStrings[:], ", "))
-	flag.StringVar(&settings.LogLevel, "loglevel", settings.LogLevel, loglevelHelp)
-	flag.StringVar(&settings.UserAgent, "user-agent", DefaultUserAgent, "`User-Agent` for requests")
-	flag.BoolVar(&settings.IncludeRedirects, "include-redirects", false, "Include redirects in reports.")
-	flag.Var(&settings.SpiderCodes, "spider-codes", "HTTP Response Codes to Continue Spidering On.")
+	fs.StringVar(&settings.LogLevel, "loglevel", settings.LogLevel, loglevelHelp)
+	fs.StringVar(&settings.UserAgent, "user-agent", settings.UserAgent, "`User-Agent` for requests")
+	fs.StringVar(&settings.HeaderProfile, "header-profile", "", "Header `profile` to mimic (e.g. chrome, firefox-mobile, googlebot).")
+	fs.BoolVar(&settings.RandomAgent, "random-agent", false, "Use a random header profile.")
+	agentRotationHelp := fmt.Sprintf("Rotate random agents per `unit`.  Options: [%s]", strings.Join(agentRotationStrings[:], ", "))
+	fs.Var(&settings.AgentRotation, "agent-rotation", agentRotationHelp)
+	fs.Var(&settings.HeaderOrder, "header-order", "Send headers in this comma-separated `order` and casing, or \"profile\" to match the header profile.  Requests use HTTP/1.1 only.")
+	fs.BoolVar(&settings.RawPaths, "raw-paths", false, "Send request paths exactly as given, without normalization or escaping, for payloads like /..;/ or %2e%2e.  Requests use HTTP/1.1 only.")
+	fs.BoolVar(&settings.IncludeRedirects, "include-redirects", false, "Include redirects in reports.")
+	redirectPolicyHelp := fmt.Sprintf("Redirects to follow.  Options: [%s]", strings.Join(redirectPolicyStrings[:], ", "))
+	fs.Var(&settings.RedirectPolicy, "redirects", redirectPolicyHelp)
+	fs.IntVar(&settings.MaxRedirects, "max-redirects", settings.MaxRedirects, "Maximum number of redirect `hops` to follow.")
+	fs.BoolVar(&settings.IgnoreSlashRedirects, "ignore-slash-redirects", settings.IgnoreSlashRedirects, "Treat /dir -> /dir/ redirects as the same resource.")
+	fs.BoolVar(&settings.AnalyzeHeaders, "analyze-headers", settings.AnalyzeHeaders, "Report interesting or missing response headers.")
+	fs.BoolVar(&settings.TimingAnomalies, "timing-anomalies", false, "Report results whose response time is far above the usual for their host.")
+	fs.BoolVar(&settings.SensitiveChecks, "sensitive-checks", settings.SensitiveChecks, "Confirm known sensitive files, like .env and phpinfo pages, by their content and report them as findings.")
+	fs.StringVar(&settings.SensitiveRulesPath, "sensitive-rules", "", "YAML `file` of sensitive file rules to use instead of the built-in ones.")
+	fs.BoolVar(&settings.FaviconHash, "favicon", settings.FaviconHash, "Fetch and hash /favicon.ico on each host, Shodan style, to identify the product.")
+	fs.StringVar(&settings.FaviconDBPath, "favicon-db", "", "`File` of favicon hashes and the products they identify, as \"<hash> <product>\" lines.")
+	fs.BoolVar(&settings.ProbeSensitive, "probe-sensitive", false, "Request the paths of known sensitive files in each starting directory.")
+	fs.BoolVar(&settings.ProbeCommon, "probe-common", settings.ProbeCommon, "Request high-value dotfiles in every directory found, and /.well-known/ entries on every host, whatever the wordlist.")
+	fs.BoolVar(&settings.SortInterest, "sort-interest", false, "Output results most interesting first, by path keywords, status, content type and findings, once the scan is done.")
+	fs.BoolVar(&settings.ScoreSummary, "score", false, "Print a per-host exposure score summary.")
+	fs.StringVar(&settings.ScorePath, "score-file", "", "Append per-host exposure scores as JSON to `file`.")
+	fs.BoolVar(&settings.StatsSummary, "stats", false, "Print scan statistics when done.")
+	fs.StringVar(&settings.StatsPath, "stats-file", "", "Write scan statistics as JSON to `file`.")
+	fs.StringVar(&settings.LearnWordlistPath, "learn-wordlist", "", "Write the wordlist entries that found something to `file`, most hits first, and print how much of the list found something.  With -history, hits are added up across scans.")
+	fs.StringVar(&settings.TriagePath, "triage", "", "Suppress or annotate results listed in triage `file`.")
+	fs.StringVar(&settings.HistoryDir, "history", "", "Keep the results of each scan in `dir` and compare against the last one.")
+	fs.Var(&settings.Monitor, "monitor", "Repeat the scan on a `schedule`: an interval like 6h, or @hourly, @daily or @weekly.  Requires -history.")
+	fs.BoolVar(&settings.ConditionalRequests, "conditional", false, "Send the ETag and Last-Modified of each result of the previous scan, treating 304 Not Modified as unchanged.  Requires -history or -compare.")
+	fs.StringVar(&settings.ComparePath, "compare", "", "Report only results that are new, changed, or removed since the JSON results in `file`.")
+	fs.Float64Var(&settings.RedirectFanout, "redirect-fanout", settings.RedirectFanout, "Summarize hosts where this `fraction` of paths redirect (0 to disable).")
+	fs.StringVar(&settings.Coordinator, "coordinator", "", "Serve tasks to remote agents on `address` instead of running local workers.")
+	fs.StringVar(&settings.Agent, "agent", "", "Run as an agent for the coordinator at `URL`.")
+	fs.StringVar(&settings.RemoteToken, "remote-token", "", "Shared `secret` for coordinator and agents.  Required by both.")
+	fs.StringVar(&settings.RemoteCert, "remote-cert", "", "Serve agents over TLS with the certificate in `file`.  Requires -remote-key.")
+	fs.StringVar(&settings.RemoteKey, "remote-key", "", "Private key for -remote-cert, from `file`.")
+	fs.StringVar(&settings.RemoteCA, "remote-ca", "", "Trust the coordinator's certificate if signed by the CA in `file`.")
+	fs.StringVar(&settings.StatusAddr, "status-addr", "", "Serve the progress of the scan, and control of its workers, over HTTP on `address`, such as 127.0.0.1:8089.")
+	fs.BoolVar(&settings.AdaptKeepAlive, "adapt-keepalive", settings.AdaptKeepAlive, "Retry and slow down when servers drop persistent connections, instead of reporting errors.")
+	fs.BoolVar(&settings.NoKeepAlive, "no-keepalive", false, "Close each connection after a single request.")
+	fs.IntVar(&settings.MaxIdlePerHost, "max-idle-per-host", 0, "Keep up to `count` idle connections to each host in each connection pool (0 for the default).")
+	fs.IntVar(&settings.MaxConnsPerHost, "max-conns-per-host", 0, "Open at most `count` connections to each host, shared by all workers (0 for no limit).")
+	fs.BoolVar(&settings.TLSResumption, "tls-resume", false, "Resume TLS sessions on new connections rather than making a full handshake.")
+	fs.BoolVar(&settings.HTTP2, "http2", false, "Send HTTPS requests to hosts that support HTTP/2 over a few shared connections, many at once on each.")
+	fs.IntVar(&settings.HTTP2Conns, "http2-conns", settings.HTTP2Conns, "With -http2, open up to `count` connections to each host.")
+	fs.IntVar(&settings.HTTP2Streams, "http2-streams", settings.HTTP2Streams, "With -http2, send up to `count` requests at once on each connection.")
+	fs.StringVar(&settings.SourcePorts, "source-ports", "", "Connect from local ports in `range` (e.g. 40000-41000).")
+	fs.StringVar(&settings.UnixSocket, "unix-socket", "", "Send every request to the Unix socket at `path`, with URLs such as http://localhost/ interpreted against it.")
+	fs.StringVar(&settings.Resolver, "resolver", "", "Look up hostnames with the DNS `server` (e.g. 1.1.1.1:53) instead of the system resolver.")
+	fs.BoolVar(&settings.PreferIPv4, "4", false, "Connect to IPv4 addresses first for hosts with both.")
+	fs.BoolVar(&settings.PreferIPv6, "6", false, "Connect to IPv6 addresses first for hosts with both.")
+	fs.Var(&settings.Resolve, "resolve", "Connect to `host:port:address` instead of looking up host, like curl's --resolve.  Port may be * for any port.  May be repeated.")
+	fs.BoolVar(&settings.HeadersOnly, "headers-only", false, "Fast first pass: read only status and headers, never bodies.")
+	fs.Int64Var(&settings.MaxHTMLSize, "max-html-size", settings.MaxHTMLSize, "Parse at most this many `bytes` of each HTML page for links.")
+	fs.BoolVar(&settings.HashBodies, "hash-bodies", false, "Hash response bodies (implied by -group-duplicates).")
+	fs.IntVar(&settings.GroupDuplicates, "group-duplicates", 0, "Group results once this `many` have the same body (0 to disable).")
+	fs.IntVar(&settings.FuzzyDistance, "fuzzy-distance", settings.FuzzyDistance, "Group bodies whose fuzzy hashes differ by at most this many `bits` (-1 for identical only).")
+	fs.Var(&settings.FilterCodes, "filter-codes", "Hide results with these status `codes` from the output, without changing which codes count as found.")
+	fs.Var(&settings.FilterLengths, "filter-lengths", "Hide results with these content `lengths`, e.g. 0,1024-2048.")
+	fs.StringVar(&settings.FilterRegex, "filter-regex", "", "Hide results whose body matches `regex` in its first 64KB.")
+	fs.BoolVar(&settings.Dedupe, "dedupe", false, "Hide results for a URL already reported with the same status code.")
+	fs.IntVar(&settings.FilterSimilar, "filter-similar", 0, "Report at most `count` results with similar bodies, as judged by -fuzzy-distance, as they are found.")
+	fs.StringVar(&settings.ScreenshotDir, "screenshot-dir", "", "Save screenshots of hits to `dir` using headless Chrome.")
+	fs.StringVar(&settings.ChromePath, "chrome", "", "`Path` to Chrome for screenshots and rendering.  (Default: search PATH)")
+	fs.IntVar(&settings.RenderDepth, "render-depth", settings.RenderDepth, "Render HTML pages at most this many `directories` deep in headless Chrome to find script-generated links (-1 to disable).")
+	fs.StringVar(&settings.RecordPath, "record", "", "Record raw requests and responses in `path`, a directory or a file ending in .har.")
+	fs.BoolVar(&settings.RecordHits, "record-hits", false, "Only record requests and responses for hits, with -record or HAR output.")
+	fs.StringVar(&settings.BodyStoreDir, "body-store", "", "Save response bodies in `dir`, once for each distinct body, named by SHA-256 hash.")
+	fs.BoolVar(&settings.Calibrate, "calibrate", false, "Calibrate a baseline for each directory (always on for diff output).")
+	fs.IntVar(&settings.CalibrationSamples, "calibration-samples", settings.CalibrationSamples, "Number of calibration `requests` per directory.")
+	calibrationRefreshValue := DurationFlag{&settings.CalibrationRefresh}
+	fs.Var(calibrationRefreshValue, "calibration-refresh", "Refresh directory baselines after `duration` (0 to never refresh).")
+	fs.BoolVar(&settings.WildcardExit, "wildcard-exit", settings.WildcardExit, "Skip the rest of a directory when calibration finds every path in it gives the same page.")
+	fs.IntVar(&settings.CollapseRedirects, "collapse-redirects", settings.CollapseRedirects, "Collapse redirects when at least `count` go to the same place (0 to disable).")
+	verifyModeHelp := fmt.Sprintf("Re-request results after the scan and handle those that don't reproduce by `mode`.  Options: [%s]", strings.Join(verifyModeStrings[:], ", "))
+	fs.Var(&settings.Verify, "verify", verifyModeHelp)
+	verifyDelayValue := DurationFlag{&settings.VerifyDelay}
+	fs.Var(verifyDelayValue, "verify-delay", "Wait `duration` after the scan before re-requesting results.")
+	fs.StringVar(&settings.VerifyProxy, "verify-proxy", "", "Re-request results through `proxy` instead of the scan proxies.")
+	fs.Var(&settings.Notify, "notify", "Send notifications to Slack, Discord, Telegram, or JSON webhook `URL`.  May be repeated.")
+	fs.Var(&settings.NotifyRules, "notify-on", "Notify of results matching `rule`, e.g. \"code=200 path=/\\.git/\".  May be repeated.")
+	fs.Var(&settings.TagRules, "tag", "Tag results matching `rule`, e.g. \"tag=auth code=401|403\" or \"tag=api path=^/api/\".  May be repeated.")
+	fs.Var(&settings.Plugins, "plugin", "Load plugin `name[:key=value,...]`.  May be repeated.")
+	fs.Var(&settings.SpiderCodes, "spider-codes", "HTTP Response `codes` to Continue Spidering On, e.g. 200-299,401.")
+	fs.Var(&settings.SpiderExcludeCodes, "spider-exclude-codes", "HTTP Response `codes` never to continue spidering on.")
+	fs.IntVar(&settings.TrapLimit, "trap-limit", settings.TrapLimit, "Stop following URLs once `count` of them match a crawler trap pattern such as numbered or repeating paths (0 to disable).")
+	fs.IntVar(&settings.DirBudget, "dir-budget", 0, "Skip the rest of a directory once `count` requests have been made under it (0 for no limit).")
+	fs.IntVar(&settings.MaxDepth, "max-depth", 0, "Skip URLs more than `steps` of wordlist expansion, spidering or redirects from a starting URL (0 for no limit).")
+	fs.Var(&settings.IncludeCodes, "include-codes", "Only report HTTP Response `codes`, e.g. 200-299,301,401-403.")
+	fs.Var(&settings.ExcludeCodes, "exclude-codes", "Never report HTTP Response `codes`.")
+	fs.Var(&settings.BypassCodes, "bypass-codes", "Retry paths answered with HTTP Response `codes`, e.g. 401,403, using encoded paths and headers known to bypass access controls.")
 	robotsModeHelp := fmt.Sprintf("Robots `mode`.  Options: [%s]", strings.Join(robotsModeStrings[:], ", "))
-	flag.Var(&settings.RobotsMode, "robots-mode", robotsModeHelp)
-	flag.StringVar(&settings.HTTPUsername, "http-username", "", "Username to be used for HTTP Auth")
-	flag.StringVar(&settings.HTTPPassword, "http-password", "", "Password to be used for HTTP Auth")
-	flag.BoolVar(&settings.ProgressBar, "progress", true, "Display a progress bar on stderr.")
-	flag.StringVar(&settings.Method, "method", "GET", "HTTP Method to use.")
+	fs.Var(&settings.RobotsMode, "robots-mode", robotsModeHelp)
+	fs.BoolVar(&settings.IISShortNames, "iis-shortnames", false, "Enumerate IIS 8.3 short names in each starting directory and try the full names they suggest.")
+	fs.BoolVar(&settings.Cookies, "cookies", false, "Keep cookies set by the server between requests.")
+	cookieIsolationHelp := fmt.Sprintf("Keep separate cookies per `unit`.  Options: [%s]", strings.Join(cookieIsolationStrings[:], ", "))
+	fs.Var(&settings.CookieIsolation, "cookie-isolation", cookieIsolationHelp)
+	fs.StringVar(&settings.HTTPUsername, "http-username", "", "Username to be used for HTTP Auth")
+	fs.StringVar(&settings.HTTPPassword, "http-password", "", "Password to be used for HTTP Auth")
+	fs.StringVar(&settings.CredentialsPath, "credentials", "", "`File` of credentials to use for groups of hosts.")
+	fs.StringVar(&settings.TokenRefreshCommand, "token-refresh-cmd", "", "Run `command` for a new bearer token when it stops working.  The output is the token, or JSON with an access_token field.")
+	fs.StringVar(&settings.TokenRefreshURL, "token-refresh-url", "", "Fetch a new bearer token from `URL` when it stops working, like -token-refresh-cmd.")
+	fs.BoolVar(&settings.ProgressBar, "progress", settings.ProgressBar, "Display a progress bar on stderr.")
+	fs.StringVar(&settings.Method, "method", settings.Method, "HTTP Method to use.")
 
 	// Debugging flags
-	flag.BoolVar(&settings.DebugCPUProf, "debug-cpuprof", false, "[DEBUG] CPU Profiling")
-
-	settings.flagsSet = true
+	fs.BoolVar(&settings.Benchmark, "benchmark", false, "Report the request rate, allocations, and where workers spend their time, scanning a built-in local server if no URL is given.")
+	fs.BoolVar(&settings.DebugCPUProf, "debug-cpuprof", false, "[DEBUG] CPU Profiling")
+	fs.StringVar(&settings.TracePath, "trace", "", "[DEBUG] Write a Go execution trace, with regions for requests and bodies, to `file`.")
+	fs.StringVar(&settings.QueueDumpPath, "queue-dump", "", "[DEBUG] Write pending queue to `file` on SIGUSR1.")
 }
 
 // Load settings from the first file found in searchPaths
-func (settings *ScanSettings) LoadFromDefaultConfigFiles() {
+func (settings *ScanSettings) LoadFromDefaultConfigFiles() error {
 	for _, path := range defaultConfigPaths {
 		if info, err := os.Stat(path); err == nil {
 			if info.IsDir() {
 				continue
 			}
-			settings.LoadFromConfigFile(path)
-			return
+			return settings.LoadFromConfigFile(path)
 		}
 	}
+	return nil
 }
 
-// Load from the specified file
-func (settings *ScanSettings) LoadFromConfigFile(path string) {
+// Load from the specified file.  Settings already given as flags are not
+// changed.
+func (settings *ScanSettings) LoadFromConfigFile(path string) error {
 	settings.InitFlags()
-	// TODO: load
-	settings.configPath = path
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := settings.applyConfig(data, flag.CommandLine); err != nil {
+		return fmt.Errorf("Error in config file %s: %s", path, err.Error())
+	}
+	settings.ConfigPath = path
+	logging.Logf(logging.LogDebug, "Loaded config file %s", path)
+	return nil
 }
 
 // Parse command line flags into settings
@@ -217,13 +743,41 @@ func (settings *ScanSettings) ParseFlags() {
 
 // Validate settings
 func (settings *ScanSettings) Validate() error {
-	flagError := func(str string) error {
-		os.Stderr.WriteString("Usage:\n")
-		flag.PrintDefaults()
-		return errors.New(str)
+	if len(settings.BaseURLs) == 0 && settings.Agent == "" && !settings.Benchmark {
+		return errors.New("URL is required.")
+	}
+	if settings.Monitor.IsSet() && settings.HistoryDir == "" {
+		return errors.New("-monitor requires -history.")
+	}
+	if settings.AddSlashes && settings.SlashMode != SlashAsIs {
+		return errors.New("-slashes can't be used with -slash-mode.")
+	}
+	if settings.Monitor.IsSet() && settings.RecipePath != "" {
+		return errors.New("-monitor can't be used with -recipe.")
+	}
+	if settings.Monitor.IsSet() && settings.ReadsStdin() {
+		return errors.New("-monitor can't read targets from stdin.")
+	}
+	if (settings.Coordinator != "" || settings.Agent != "") && settings.RemoteToken == "" {
+		return errors.New("-coordinator and -agent require -remote-token.")
+	}
+	if (settings.RemoteCert == "") != (settings.RemoteKey == "") {
+		return errors.New("-remote-cert and -remote-key must be given together.")
+	}
+	if settings.DryRun && settings.Coordinator != "" {
+		return errors.New("-dry-run can't be used with -coordinator.")
+	}
+	if settings.PreferIPv4 && settings.PreferIPv6 {
+		return errors.New("Only one of -4 and -6 can be given.")
+	}
+	if settings.DedupeContent && !settings.DedupeHosts {
+		return errors.New("-dedupe-content requires -dedupe-hosts.")
+	}
+	if settings.UnixSocket != "" && len(settings.Proxies) > 0 {
+		return errors.New("-unix-socket can't be used with -proxy.")
 	}
-	if len(settings.BaseURLs) == 0 {
-		return flagError("URL is required.")
+	if settings.ConditionalRequests && settings.HistoryDir == "" && settings.ComparePath == "" {
+		return errors.New("-conditional requires -history or -compare.")
 	}
 	return nil
 }
@@ -233,29 +787,59 @@ func (settings *ScanSettings) String() string {
 	flags := make([]string, 0)
 
 	flag.VisitAll(func(f *flag.Flag) {
-		flags = append(flags, fmt.Sprintf("-%s=%s", f.Name, f.Value.String()))
+		value := f.Value.String()
+		if secretFlags[f.Name] && value != "" {
+			value = "<redacted>"
+		}
+		flags = append(flags, fmt.Sprintf("-%s=%s", f.Name, value))
 	})
 
 	return strings.Join(flags, " ")
 }
 
-// Convert BaseURL strings to URLs
+// Convert BaseURL strings to URLs.  StdinURL is skipped.
 func (settings *ScanSettings) GetScopes() ([]*url.URL, error) {
-	scopes := make([]*url.URL, len(settings.BaseURLs))
-	for i, baseURL := range settings.BaseURLs {
-		parsed, err := url.Parse(baseURL)
-		scopes[i] = parsed
+	scopes := make([]*url.URL, 0, len(settings.BaseURLs))
+	for _, baseURL := range settings.BaseURLs {
+		if baseURL == StdinURL {
+			continue
+		}
+		parsed, err := util.ParseTargetURL(baseURL)
 		if err != nil {
 			return nil, fmt.Errorf("Unable to parse BaseURL (%s): %s", baseURL, err.Error())
 		}
-		if scopes[i].Path == "" {
-			scopes[i].Path = "/"
-		}
-		logging.Logf(logging.LogDebug, "Added BaseURL: %s", scopes[i].String())
+		logging.Logf(logging.LogDebug, "Added BaseURL: %s", parsed.String())
+		scopes = append(scopes, parsed)
 	}
 	return scopes, nil
 }
 
+// Check if more starting URLs should be read from stdin as the scan runs.
+func (settings *ScanSettings) ReadsStdin() bool {
+	for _, baseURL := range settings.BaseURLs {
+		if baseURL == StdinURL {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if raw requests and responses are kept, for -record, -body-store or
+// HAR output.
+func (settings *ScanSettings) Recording() bool {
+	return settings.RecordPath != "" || settings.BodyStoreDir != "" || settings.OutputFormat == "har"
+}
+
+// Get the first starting URL, for labelling reports.
+func (settings *ScanSettings) FirstBaseURL() string {
+	for _, baseURL := range settings.BaseURLs {
+		if baseURL != StdinURL {
+			return baseURL
+		}
+	}
+	return ""
+}
+
 // Init output formats
 func SetOutputFormats(formats []string) {
 	outputFormats = formats
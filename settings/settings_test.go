@@ -52,6 +52,23 @@ func TestNewScanSettings(t *testing.T) {
 	}
 }
 
+func TestDefaultScanSettings(t *testing.T) {
+	ss := DefaultScanSettings()
+	if ss.flagsSet {
+		t.Errorf("Flags should not be initialized.")
+	}
+	if ss.Workers < 1 || ss.UserAgent == "" || ss.Method != "GET" {
+		t.Errorf("Missing defaults: %d workers, agent %q, method %q", ss.Workers, ss.UserAgent, ss.Method)
+	}
+	if err := ss.Validate(); err == nil {
+		t.Errorf("Expected validation error without URLs.")
+	}
+	ss.BaseURLs = append(ss.BaseURLs, "http://localhost/")
+	if err := ss.Validate(); err != nil {
+		t.Errorf("Unexpected validation error: %v", err)
+	}
+}
+
 func TestStringSliceFlag(t *testing.T) {
 	f := StringSliceFlag{}
 	if f.String() != "" {
@@ -69,6 +86,18 @@ func TestStringSliceFlag(t *testing.T) {
 	}
 }
 
+func TestRepeatedStringFlag(t *testing.T) {
+	f := RepeatedStringFlag{}
+	for _, s := range []string{"exec:cmd=a,tasks=true", "test"} {
+		if err := f.Set(s); err != nil {
+			t.Errorf("Error when setting RepeatedStringFlag: %v", err)
+		}
+	}
+	if len(f) != 2 || f[0] != "exec:cmd=a,tasks=true" {
+		t.Errorf("Unexpected values: %v", f)
+	}
+}
+
 func TestIntSliceFlag(t *testing.T) {
 	f := IntSliceFlag{}
 	if f.String() != "" {
@@ -89,6 +118,65 @@ func TestIntSliceFlag(t *testing.T) {
 	}
 }
 
+func TestCodeRangeFlag(t *testing.T) {
+	f := CodeRangeFlag{}
+	if f.String() != "" {
+		t.Error("Expected empty string for empty CodeRangeFlag.")
+	}
+	s := "200-299,301,401-403"
+	if err := f.Set(s); err != nil {
+		t.Fatalf("Error when setting CodeRangeFlag: %v", err)
+	}
+	if f.String() != s {
+		t.Errorf("Differing strings: \"%s\" vs \"%s\".", f.String(), s)
+	}
+	for _, code := range []int{200, 250, 299, 301, 401, 403} {
+		if !f.Contains(code) {
+			t.Errorf("Expected %d in %s.", code, s)
+		}
+	}
+	for _, code := range []int{0, 300, 302, 400, 404, 500} {
+		if f.Contains(code) {
+			t.Errorf("Expected %d not in %s.", code, s)
+		}
+	}
+	for _, bad := range []string{"xyz", "300-200", "200-", "-200"} {
+		if err := f.Set(bad); err == nil {
+			t.Errorf("Expected error when setting %q.", bad)
+		}
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	start := time.Date(2018, 3, 7, 10, 30, 0, 0, time.UTC)
+	cases := map[string]time.Time{
+		"6h":         start.Add(6 * time.Hour),
+		"@every 90m": start.Add(90 * time.Minute),
+		"@hourly":    time.Date(2018, 3, 7, 11, 0, 0, 0, time.UTC),
+		"@daily":     time.Date(2018, 3, 8, 0, 0, 0, 0, time.UTC),
+		"@weekly":    time.Date(2018, 3, 11, 0, 0, 0, 0, time.UTC),
+	}
+	for spec, expected := range cases {
+		s := Schedule{}
+		if err := s.Set(spec); err != nil {
+			t.Errorf("Unexpected error for %q: %v", spec, err)
+			continue
+		}
+		if !s.IsSet() || s.String() != spec {
+			t.Errorf("Expected %q to be set, got %q", spec, s.String())
+		}
+		if next := s.Next(start); !next.Equal(expected) {
+			t.Errorf("%s: expected %s, got %s", spec, expected, next)
+		}
+	}
+	for _, bad := range []string{"@monthly", "soon", "-1h", "0s"} {
+		s := Schedule{}
+		if err := s.Set(bad); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
 func TestDurationFlag_Empty(t *testing.T) {
 	f := DurationFlag{}
 	if f.String() != "" {
@@ -191,6 +279,23 @@ func TestScanSettings_GetScopes_Error(t *testing.T) {
 	}
 }
 
+func TestScanSettings_GetScopes_Stdin(t *testing.T) {
+	ss := &ScanSettings{
+		BaseURLs: []string{StdinURL, "http://localhost/"},
+	}
+	if !ss.ReadsStdin() {
+		t.Error("Expected settings to read stdin.")
+	}
+	if first := ss.FirstBaseURL(); first != "http://localhost/" {
+		t.Errorf("Unexpected first base URL: %s", first)
+	}
+	if scopes, err := ss.GetScopes(); err != nil {
+		t.Errorf("Expected no error getting scope, got %v.", err)
+	} else if len(scopes) != 1 {
+		t.Errorf("Expected stdin to be skipped, got %v", scopes)
+	}
+}
+
 func TestScanSettings_Validate(t *testing.T) {
 	ss := &ScanSettings{
 		BaseURLs: []string{},
@@ -205,3 +310,146 @@ func TestScanSettings_Validate(t *testing.T) {
 		t.Errorf("Expected no errors with BaseURLs.")
 	}
 }
+
+func TestAgentRotationStrings(t *testing.T) {
+	if len(agentRotationStrings) != agentRotationMax {
+		t.Errorf("AgentRotationStrings != enum: %d vs %d", len(agentRotationStrings), agentRotationMax)
+	}
+	f := AgentRotationOption(0)
+	if err := f.Set("worker"); err != nil || f != RotatePerWorker {
+		t.Errorf("Expected worker rotation, got %v (%v)", f, err)
+	}
+	if err := f.Set("never"); err == nil {
+		t.Error("Expected error setting invalid rotation.")
+	}
+}
+
+func TestRedirectPolicyStrings(t *testing.T) {
+	if len(redirectPolicyStrings) != redirectPolicyMax {
+		t.Errorf("RedirectPolicyStrings != enum: %d vs %d", len(redirectPolicyStrings), redirectPolicyMax)
+	}
+}
+
+func TestCookieIsolationStrings(t *testing.T) {
+	if len(cookieIsolationStrings) != cookieIsolationMax {
+		t.Errorf("CookieIsolationStrings != enum: %d vs %d", len(cookieIsolationStrings), cookieIsolationMax)
+	}
+}
+
+func TestVerifyModeStrings(t *testing.T) {
+	if len(verifyModeStrings) != verifyModeMax {
+		t.Errorf("VerifyModeStrings != enum: %d vs %d", len(verifyModeStrings), verifyModeMax)
+	}
+}
+
+func TestSlashModeStrings(t *testing.T) {
+	if len(slashModeStrings) != slashModeMax {
+		t.Errorf("SlashModeStrings != enum: %d vs %d", len(slashModeStrings), slashModeMax)
+	}
+}
+
+func TestQueryModeStrings(t *testing.T) {
+	if len(queryModeStrings) != queryModeMax {
+		t.Errorf("QueryModeStrings != enum: %d vs %d", len(queryModeStrings), queryModeMax)
+	}
+}
+
+func TestNofollowModeStrings(t *testing.T) {
+	if len(nofollowModeStrings) != nofollowModeMax {
+		t.Errorf("NofollowModeStrings != enum: %d vs %d", len(nofollowModeStrings), nofollowModeMax)
+	}
+}
+
+func TestWordEncodingStrings(t *testing.T) {
+	if len(wordEncodingStrings) != wordEncodingMax {
+		t.Errorf("WordEncodingStrings != enum: %d vs %d", len(wordEncodingStrings), wordEncodingMax)
+	}
+}
+
+func TestWordEncodingFlag(t *testing.T) {
+	var f WordEncodingFlag
+	if err := f.Set("url,fullwidth"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(f) != 2 || f[0] != EncodeURL || f[1] != EncodeFullwidth {
+		t.Errorf("Unexpected encodings: %v", f)
+	}
+	if f.String() != "url,fullwidth" {
+		t.Errorf("Unexpected string: %s", f.String())
+	}
+	if err := f.Set("rot13"); err == nil {
+		t.Error("Expected error for unknown encoding.")
+	}
+}
+
+func TestColorModeStrings(t *testing.T) {
+	if len(colorModeStrings) != colorModeMax {
+		t.Errorf("ColorModeStrings != enum: %d vs %d", len(colorModeStrings), colorModeMax)
+	}
+	var mode ColorModeOption
+	for value, expected := range map[string]ColorModeOption{"true": ColorAlways, "false": ColorNever, "auto": ColorAuto} {
+		if err := mode.Set(value); err != nil || mode != expected {
+			t.Errorf("Set(%s): expected %s, got %s (%v)", value, &expected, &mode, err)
+		}
+	}
+}
+
+func TestByteSize(t *testing.T) {
+	cases := map[string]ByteSize{
+		"100":   100,
+		"64k":   64 << 10,
+		"512M":  512 << 20,
+		"2GB":   2 << 30,
+		" 1g ":  1 << 30,
+		"1500K": 1500 << 10,
+	}
+	for value, expected := range cases {
+		var b ByteSize
+		if err := b.Set(value); err != nil {
+			t.Errorf("Unexpected error for %q: %v", value, err)
+		} else if b != expected {
+			t.Errorf("%q: expected %d, got %d", value, expected, b)
+		}
+	}
+	for _, value := range []string{"", "M", "-1", "1T", "1.5G"} {
+		var b ByteSize
+		if err := b.Set(value); err == nil {
+			t.Errorf("Expected error for %q", value)
+		}
+	}
+	if s := ByteSize(512 << 20).String(); s != "512M" {
+		t.Errorf("Expected 512M, got %s", s)
+	}
+	if s := ByteSize(1000).String(); s != "1000" {
+		t.Errorf("Expected 1000, got %s", s)
+	}
+}
+
+func TestScanSettings_FitMemory(t *testing.T) {
+	settings := DefaultScanSettings()
+	settings.ScreenshotDir = "/tmp/shots"
+	if warnings := settings.FitMemory(); warnings != nil {
+		t.Errorf("Expected no changes without a limit, got %v", warnings)
+	}
+	settings.Workers = 16
+	settings.MemoryLimit = 64 << 20
+	warnings := settings.FitMemory()
+	if len(warnings) != 3 {
+		t.Errorf("Expected 3 warnings, got %v", warnings)
+	}
+	if settings.QueueSize != 256 {
+		t.Errorf("Expected queue size 256, got %d", settings.QueueSize)
+	}
+	if settings.MaxHTMLSize != 1<<20 {
+		t.Errorf("Expected 1M of HTML to be parsed, got %d", settings.MaxHTMLSize)
+	}
+	if settings.ScreenshotDir != "" {
+		t.Error("Expected screenshots to be disabled.")
+	}
+	if settings.VisitedMemory() != 16<<20 {
+		t.Errorf("Expected 16M for visited URLs, got %d", settings.VisitedMemory())
+	}
+	if warnings := settings.FitMemory(); len(warnings) != 0 {
+		t.Errorf("Expected no more changes, got %v", warnings)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"fmt"
+)
+
+// Control the trailing slashes of wordlist entries, and so whether they are
+// tried as directories or files
+type SlashModeOption int
+
+const (
+	// Use entries as they are in the wordlist
+	SlashAsIs = iota
+	// Add a trailing slash to every entry
+	SlashDirs
+	// Remove trailing slashes from entries
+	SlashFiles
+	// Try every entry with and without a trailing slash
+	SlashBoth
+	slashModeMax
+)
+
+var slashModeStrings = [...]string{
+	"as-is",
+	"dirs",
+	"files",
+	"both",
+}
+
+func (f *SlashModeOption) String() string {
+	if f == nil {
+		return slashModeStrings[SlashAsIs]
+	}
+	return slashModeStrings[*f]
+}
+
+func (f *SlashModeOption) Set(value string) error {
+	for i, val := range slashModeStrings {
+		if val == value {
+			*f = SlashModeOption(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown Slash Mode: %s", value)
+}
@@ -38,6 +38,22 @@ func (f *StringSliceFlag) Set(value string) error {
 	return nil
 }
 
+// RepeatedStringFlag is a flag.Value that collects each use of a repeated flag
+// without splitting, for values that may themselves contain commas.
+type RepeatedStringFlag []string
+
+func (f *RepeatedStringFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(*f, " ")
+}
+
+func (f *RepeatedStringFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
 // StringSliceFileFlag is flag.Value that loads from a file into a wrapped
 // StringSliceFlag
 type StringSliceFileFlag struct {
@@ -0,0 +1,205 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/Matir/webborer/util"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// A Target is a starting URL with settings that override the scan settings
+// for everything within its scope.
+type Target struct {
+	// Starting point and scope
+	URL *url.URL
+	// HTTP Method to use, if different
+	Method string
+	// Additional headers to send
+	Header HeaderFlag
+	// Wordlist to use, if different
+	WordlistPath string
+	// Maximum number of directories below URL to descend (-1 for unlimited)
+	Depth int
+}
+
+// Add a target to be scanned.  Its URL becomes one of the scopes.
+func (settings *ScanSettings) AddTarget(target *Target) {
+	settings.Targets = append(settings.Targets, target)
+	settings.BaseURLs = append(settings.BaseURLs, target.URL.String())
+}
+
+// Find the target with the most specific scope containing u, or nil if there
+// is none.
+func (settings *ScanSettings) TargetFor(u *url.URL) *Target {
+	var best *Target
+	for _, target := range settings.Targets {
+		if !util.URLIsSubpath(target.URL, u) {
+			continue
+		}
+		if best == nil || len(target.URL.Path) > len(best.URL.Path) {
+			best = target
+		}
+	}
+	return best
+}
+
+// Check if u is deeper below the target than allowed.  The depth of a URL is
+// that of the directory containing it, so depth 0 allows everything directly
+// within the target's directory.
+func (target *Target) TooDeep(u *url.URL) bool {
+	if target.Depth < 0 {
+		return false
+	}
+	base := strings.TrimSuffix(path.Clean(target.URL.Path), "/")
+	dir := path.Dir(strings.TrimSuffix(path.Clean("/"+u.Path), "/"))
+	rel := strings.Trim(strings.TrimPrefix(dir, base), "/")
+	if rel == "" {
+		return false
+	}
+	return strings.Count(rel, "/")+1 > target.Depth
+}
+
+// Load targets from a file with one per line, as a URL followed by any of
+// these options:
+//
+//	method=POST
+//	header="Authorization: Bearer abc123"  (may be repeated)
+//	wordlist=api.txt
+//	depth=2
+//
+// Blank lines and lines starting with # are ignored.
+func (settings *ScanSettings) LoadTargetsFile(path string) error {
+	fp, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	targets, err := ParseTargets(fp)
+	if err != nil {
+		return fmt.Errorf("Error in targets file %s: %s", path, err.Error())
+	}
+	for _, target := range targets {
+		settings.AddTarget(target)
+	}
+	return nil
+}
+
+// Parse targets in the format used by LoadTargetsFile.
+func ParseTargets(r io.Reader) ([]*Target, error) {
+	var targets []*Target
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		target, err := parseTarget(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNo, err.Error())
+		}
+		targets = append(targets, target)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+func parseTarget(line string) (*Target, error) {
+	fields, err := splitQuoted(line)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(fields[0])
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("Target must be an absolute URL: %s", fields[0])
+	}
+	util.CanonicalizeURLHost(u)
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	target := &Target{
+		URL:    u,
+		Header: make(HeaderFlag),
+		Depth:  -1,
+	}
+	for _, field := range fields[1:] {
+		pieces := strings.SplitN(field, "=", 2)
+		if len(pieces) != 2 {
+			return nil, fmt.Errorf("Options are key=value: %s", field)
+		}
+		value := pieces[1]
+		switch pieces[0] {
+		case "method":
+			target.Method = strings.ToUpper(value)
+		case "header":
+			if err := target.Header.Set(value); err != nil {
+				return nil, err
+			}
+		case "wordlist":
+			target.WordlistPath = value
+		case "depth":
+			if target.Depth, err = strconv.Atoi(value); err != nil || target.Depth < 0 {
+				return nil, fmt.Errorf("Invalid depth: %s", value)
+			}
+		default:
+			return nil, fmt.Errorf("Unknown option: %s", pieces[0])
+		}
+	}
+	return target, nil
+}
+
+// Split a line on whitespace, keeping double-quoted strings together.
+func splitQuoted(line string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	inField, quoted := false, false
+	for _, c := range line {
+		switch {
+		case c == '"':
+			quoted = !quoted
+			inField = true
+		case !quoted && (c == ' ' || c == '\t'):
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(c)
+			inField = true
+		}
+	}
+	if quoted {
+		return nil, errors.New("Unterminated quote")
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const testTargets = `
+# Main application
+https://app.example.com/ method=post header="Authorization: Bearer abc 123" depth=2
+https://app.example.com/api/ wordlist=api.txt header=X-Api:1 header=X-Other:2
+
+https://static.example.com
+`
+
+func TestParseTargets(t *testing.T) {
+	targets, err := ParseTargets(strings.NewReader(testTargets))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(targets) != 3 {
+		t.Fatalf("Expected 3 targets, got %d", len(targets))
+	}
+	app := targets[0]
+	if app.Method != "POST" {
+		t.Errorf("Expected POST, got %s", app.Method)
+	}
+	if v := app.Header.Header().Get("Authorization"); v != "Bearer abc 123" {
+		t.Errorf("Unexpected Authorization header: %q", v)
+	}
+	if app.Depth != 2 {
+		t.Errorf("Expected depth 2, got %d", app.Depth)
+	}
+	api := targets[1]
+	if api.WordlistPath != "api.txt" || len(api.Header) != 2 || api.Depth != -1 {
+		t.Errorf("Unexpected api target: %+v", api)
+	}
+	if targets[2].URL.Path != "/" {
+		t.Errorf("Expected path to default to /, got %q", targets[2].URL.Path)
+	}
+}
+
+func TestParseTargets_Errors(t *testing.T) {
+	for _, line := range []string{
+		"/relative",
+		"https://example.com/ depth=-1",
+		"https://example.com/ bogus=1",
+		"https://example.com/ method",
+		"https://example.com/ header=\"X-Foo: bar",
+		"https://example.com/ header=nocolon",
+	} {
+		if _, err := ParseTargets(strings.NewReader(line)); err == nil {
+			t.Errorf("Expected error for %q", line)
+		}
+	}
+}
+
+func TestTargetFor(t *testing.T) {
+	settings := &ScanSettings{}
+	targets, err := ParseTargets(strings.NewReader(testTargets))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, target := range targets {
+		settings.AddTarget(target)
+	}
+	if len(settings.BaseURLs) != 3 {
+		t.Errorf("Expected targets to be added as scopes, got %v", settings.BaseURLs)
+	}
+	cases := map[string]*Target{
+		"https://app.example.com/login":       targets[0],
+		"https://app.example.com/api/users":   targets[1],
+		"https://static.example.com/logo.png": targets[2],
+		"https://other.example.com/":          nil,
+	}
+	for raw, expected := range cases {
+		u, _ := url.Parse(raw)
+		if got := settings.TargetFor(u); got != expected {
+			t.Errorf("TargetFor(%s): expected %v, got %v", raw, expected, got)
+		}
+	}
+}
+
+func TestTargetTooDeep(t *testing.T) {
+	target := &Target{URL: &url.URL{Path: "/app/"}, Depth: 1}
+	cases := map[string]bool{
+		"/app/":         false,
+		"/app/a":        false,
+		"/app/a/":       false,
+		"/app/a/b":      false,
+		"/app/a/b/":     false,
+		"/app/a/b/c":    true,
+		"/app/a/b/c/d/": true,
+	}
+	for p, expected := range cases {
+		if got := target.TooDeep(&url.URL{Path: p}); got != expected {
+			t.Errorf("TooDeep(%s): expected %v, got %v", p, expected, got)
+		}
+	}
+	target.Depth = -1
+	if target.TooDeep(&url.URL{Path: "/app/a/b/c/d/e"}) {
+		t.Errorf("Expected unlimited depth.")
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"fmt"
+)
+
+// Control what happens to results that don't reproduce when re-checked
+type VerifyModeOption int
+
+const (
+	VerifyOff = iota
+	VerifyTag
+	VerifyDrop
+	verifyModeMax
+)
+
+var verifyModeStrings = [...]string{
+	"off",
+	"tag",
+	"drop",
+}
+
+func (f *VerifyModeOption) String() string {
+	if f == nil {
+		return verifyModeStrings[VerifyOff]
+	}
+	return verifyModeStrings[*f]
+}
+
+func (f *VerifyModeOption) Set(value string) error {
+	for i, val := range verifyModeStrings {
+		if val == value {
+			*f = VerifyModeOption(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown Verify Mode: %s", value)
+}
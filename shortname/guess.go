@@ -0,0 +1,79 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shortname
+
+import (
+	"strings"
+)
+
+// Extensions longer than three characters, which short names truncate
+var longExtensions = []string{
+	"ashx",
+	"asmx",
+	"aspx",
+	"backup",
+	"config",
+	"docx",
+	"html",
+	"jpeg",
+	"json",
+	"pptx",
+	"shtml",
+	"xlsx",
+}
+
+// Guesses returns the names that a short name may stand for: words starting
+// with its base name, or the base name itself, with each extension starting
+// with its extension.  Names without an extension are also tried as
+// directories.
+func Guesses(n ShortName, words, extensions []string) []string {
+	base := strings.ToLower(n.Base)
+	bases := []string{base}
+	for _, w := range words {
+		if len(w) > len(base) && strings.HasPrefix(strings.ToLower(w), base) {
+			bases = append(bases, w)
+		}
+	}
+	var guesses []string
+	seen := make(map[string]bool)
+	add := func(guess string) {
+		if !seen[guess] {
+			seen[guess] = true
+			guesses = append(guesses, guess)
+		}
+	}
+	if n.Ext == "" {
+		for _, b := range bases {
+			add(b)
+			add(b + "/")
+		}
+		return guesses
+	}
+	ext := strings.ToLower(n.Ext)
+	exts := []string{ext}
+	for _, list := range [][]string{extensions, longExtensions} {
+		for _, e := range list {
+			if len(e) > len(ext) && strings.HasPrefix(strings.ToLower(e), ext) {
+				exts = append(exts, e)
+			}
+		}
+	}
+	for _, b := range bases {
+		for _, e := range exts {
+			add(b + "." + e)
+		}
+	}
+	return guesses
+}
@@ -0,0 +1,208 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package shortname enumerates the 8.3 short names of files on IIS servers
+// that disclose them through the tilde character, and guesses the full names
+// they stand for.
+package shortname
+
+import (
+	"fmt"
+	"github.com/Matir/webborer/client"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/util"
+	"net/url"
+	"strings"
+)
+
+// Characters that may appear in short names, which IIS matches without case
+const nameChars = "abcdefghijklmnopqrstuvwxyz0123456789-_"
+
+// Longest base name before the ~N, and longest extension
+const (
+	maxBaseLength = 6
+	maxExtLength  = 3
+)
+
+// Highest ~N tried for each base name
+const maxIndex = 4
+
+// Methods that may reveal short names, in the order tried
+var probeMethods = []string{"GET", "OPTIONS"}
+
+// ShortName is the 8.3 name of a file or directory, e.g. ADMINI~1.ASP.
+type ShortName struct {
+	// Base name, without the ~N
+	Base  string
+	Index int
+	// Extension, empty for directories and files without one
+	Ext string
+}
+
+func (n ShortName) String() string {
+	s := fmt.Sprintf("%s~%d", strings.ToUpper(n.Base), n.Index)
+	if n.Ext != "" {
+		s += "." + strings.ToUpper(n.Ext)
+	}
+	return s
+}
+
+// Enumerator finds short names using a client.
+type Enumerator struct {
+	client client.Client
+	// Request method and status code of a matching pattern, once known
+	method string
+	found  int
+}
+
+func NewEnumerator(c client.Client) *Enumerator {
+	return &Enumerator{client: c}
+}
+
+// Enumerate the short names in the directory dir.  Returns no names if the
+// server does not disclose them.
+func (e *Enumerator) Enumerate(dir *url.URL) ([]ShortName, error) {
+	vulnerable, err := e.detect(dir)
+	if err != nil || !vulnerable {
+		return nil, err
+	}
+	var names []ShortName
+	err = e.walk(dir, "", func(base string) error {
+		for i := 1; i <= maxIndex; i++ {
+			ok, err := e.matches(dir, fmt.Sprintf("%s~%d*", base, i))
+			if err != nil || !ok {
+				return err
+			}
+			exts, err := e.extensions(dir, fmt.Sprintf("%s~%d", base, i))
+			if err != nil {
+				return err
+			}
+			for _, ext := range exts {
+				names = append(names, ShortName{Base: base, Index: i, Ext: ext})
+			}
+		}
+		return nil
+	})
+	return names, err
+}
+
+// Check that a pattern matching any short name is told apart from one that
+// can't match, with any of the methods.
+func (e *Enumerator) detect(dir *url.URL) (bool, error) {
+	for _, method := range probeMethods {
+		any, err := e.status(dir, method, "*~1*")
+		if err != nil {
+			return false, err
+		}
+		none, err := e.status(dir, method, "1234567890*~1*")
+		if err != nil {
+			return false, err
+		}
+		if any != none {
+			logging.Logf(logging.LogDebug, "%s discloses short names with %s: %d vs %d", dir.String(), method, any, none)
+			e.method, e.found = method, any
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Find the base names starting with prefix, depth first, and call found for
+// each.  A base name is complete once no longer one matches.
+func (e *Enumerator) walk(dir *url.URL, prefix string, found func(string) error) error {
+	extended := false
+	if len(prefix) < maxBaseLength {
+		for _, c := range nameChars {
+			next := prefix + string(c)
+			ok, err := e.matches(dir, next+"*~*")
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			extended = true
+			if err := e.walk(dir, next, found); err != nil {
+				return err
+			}
+		}
+	}
+	if prefix == "" {
+		return nil
+	}
+	if !extended || len(prefix) == maxBaseLength {
+		return found(prefix)
+	}
+	// A shorter name may exist alongside longer ones
+	if ok, err := e.matches(dir, prefix+"~*"); err != nil || !ok {
+		return err
+	}
+	return found(prefix)
+}
+
+// Find the extensions of the short name name, or a single empty extension if
+// it has none.
+func (e *Enumerator) extensions(dir *url.URL, name string) ([]string, error) {
+	var exts []string
+	var walk func(prefix string) error
+	walk = func(prefix string) error {
+		extended := false
+		if len(prefix) < maxExtLength {
+			for _, c := range nameChars {
+				next := prefix + string(c)
+				ok, err := e.matches(dir, name+"."+next+"*")
+				if err != nil {
+					return err
+				}
+				if ok {
+					extended = true
+					if err := walk(next); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		if prefix != "" && !extended {
+			exts = append(exts, prefix)
+		}
+		return nil
+	}
+	if err := walk(""); err != nil {
+		return nil, err
+	}
+	if len(exts) == 0 {
+		exts = append(exts, "")
+	}
+	return exts, nil
+}
+
+// Whether any short name in dir matches the pattern.
+func (e *Enumerator) matches(dir *url.URL, pattern string) (bool, error) {
+	code, err := e.status(dir, e.method, pattern)
+	return code == e.found, err
+}
+
+// Request a pattern in dir and return the status code.
+func (e *Enumerator) status(dir *url.URL, method, pattern string) (int, error) {
+	u := *dir
+	u.Path = strings.TrimSuffix(dir.Path, "/") + "/" + pattern + "/a.aspx"
+	u.RawPath = u.Path
+	u.RawQuery = ""
+	resp, err := e.client.Request(&u, "", method, nil)
+	if err != nil && resp == nil {
+		return 0, err
+	}
+	util.DrainBody(resp.Body)
+	return resp.StatusCode, nil
+}
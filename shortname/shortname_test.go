@@ -0,0 +1,94 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shortname
+
+import (
+	"github.com/Matir/webborer/client/mock"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// A client for an IIS server disclosing the given short names in /app/
+type iisClient struct {
+	mock.MockClient
+	names []string
+}
+
+func (c *iisClient) Request(u *url.URL, host, method string, header http.Header) (*http.Response, error) {
+	c.Requests = append(c.Requests, u)
+	resp := mock.ResponseFromString("")
+	resp.StatusCode = http.StatusBadRequest
+	dir, file := path.Split(strings.TrimSuffix(u.Path, "/a.aspx"))
+	if dir != "/app/" || method != "GET" {
+		return resp, nil
+	}
+	for _, name := range c.names {
+		if ok, _ := path.Match(strings.ToUpper(file), name); ok {
+			resp.StatusCode = http.StatusNotFound
+		}
+	}
+	return resp, nil
+}
+
+func TestEnumerate(t *testing.T) {
+	names := []string{"ADMINI~1.ASP", "ADMINI~2.HTM", "UPLOAD~1", "AB~1.TXT"}
+	c := &iisClient{names: names}
+	e := NewEnumerator(c)
+	found, err := e.Enumerate(&url.URL{Scheme: "http", Host: "localhost", Path: "/app/"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var got []string
+	for _, n := range found {
+		got = append(got, n.String())
+	}
+	sort.Strings(got)
+	sort.Strings(names)
+	if strings.Join(got, ",") != strings.Join(names, ",") {
+		t.Errorf("Expected %v, got %v", names, got)
+	}
+}
+
+func TestEnumerate_NotVulnerable(t *testing.T) {
+	c := &iisClient{names: []string{"ADMINI~1.ASP"}}
+	found, err := NewEnumerator(c).Enumerate(&url.URL{Scheme: "http", Host: "localhost", Path: "/other/"})
+	if err != nil || len(found) != 0 {
+		t.Errorf("Expected nothing, got %v, %v", found, err)
+	}
+	if len(c.Requests) != 2*len(probeMethods) {
+		t.Errorf("Expected only detection requests, got %d", len(c.Requests))
+	}
+}
+
+func TestGuesses(t *testing.T) {
+	words := []string{"admin", "administrator", "administration", "backup"}
+	got := Guesses(ShortName{Base: "ADMINI", Index: 1, Ext: "ASP"}, words, []string{"asp", "aspx"})
+	expected := []string{
+		"admini.asp", "admini.aspx",
+		"administrator.asp", "administrator.aspx",
+		"administration.asp", "administration.aspx",
+	}
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	got = Guesses(ShortName{Base: "BACKUP", Index: 1}, words, nil)
+	if strings.Join(got, ",") != "backup,backup/" {
+		t.Errorf("Unexpected directory guesses: %v", got)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webborer
+
+import (
+	"encoding/json"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/results"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Paths served on -status-addr
+const (
+	// GET for the progress of the scan
+	StatusPath = "/status"
+	// POST a JSON workersRequest to change the number of workers
+	WorkersPath = "/workers"
+)
+
+// Body of a POST to WorkersPath, with n as N, +N or -N.  Requiring JSON
+// means a page in a browser can't change the scan with a plain form post.
+type workersRequest struct {
+	N string `json:"n"`
+}
+
+// Progress of a scan as served on StatusPath.
+type statusResponse struct {
+	Done    int64   `json:"done"`
+	Total   int64   `json:"total"`
+	Results int64   `json:"results"`
+	Workers int     `json:"workers"`
+	Elapsed string  `json:"elapsed"`
+	Rate    float64 `json:"rate"`
+	// Percentiles of the time spent in each stage so far
+	Stages []results.StageTiming `json:"stages,omitempty"`
+}
+
+// Build the handler for the status endpoint.
+func (s *Scanner) StatusHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(StatusPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.writeStatus(w)
+	})
+	mux.HandleFunc(WorkersPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct != "application/json" {
+			http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+		req := &workersRequest{}
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1024)).Decode(req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err := s.AdjustWorkers(req.N); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.writeStatus(w)
+	})
+	return mux
+}
+
+func (s *Scanner) writeStatus(w http.ResponseWriter) {
+	progress, err := s.Progress()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	status := &statusResponse{
+		Done:    progress.Done,
+		Total:   progress.Total,
+		Results: progress.Results,
+		Workers: progress.Workers,
+		Elapsed: progress.Elapsed.Round(time.Second).String(),
+		Rate:    progress.Rate(),
+	}
+	if s.timings != nil {
+		status.Stages = s.timings.Summary()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// Start serving the status endpoint on addr in the background, until the
+// scan finishes.
+func (s *Scanner) serveStatus(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	logging.Logf(logging.LogInfo, "Serving scan status on http://%s%s", l.Addr().String(), StatusPath)
+	s.statusListener = l
+	go func() {
+		if err := http.Serve(l, s.StatusHandler()); err != nil {
+			logging.Logf(logging.LogDebug, "Status endpoint stopped: %s", err.Error())
+		}
+	}()
+	return nil
+}
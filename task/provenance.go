@@ -0,0 +1,110 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// How a task came to be requested
+type Origin int
+
+const (
+	// Not recorded
+	OriginUnknown = Origin(iota)
+	// A starting URL
+	OriginSeed
+	// A word from the wordlist added to a directory
+	OriginWordlist
+	// An extension added to a path
+	OriginExtension
+	// A header, virtual host or trailing slash added to a task
+	OriginVariant
+	// Linked from a page
+	OriginSpider
+	// The target of a redirect
+	OriginRedirect
+	// Listed in robots.txt
+	OriginRobots
+	// A backup or swap file name made from a path
+	OriginMangle
+	// A directory containing a URL that was found
+	OriginParent
+	// Guessed from an IIS short name
+	OriginShortName
+	// A known sensitive path
+	OriginProbe
+	// Added by a plugin
+	OriginPlugin
+	// A prefix, word and suffix combined
+	OriginPermutation
+	originMax
+)
+
+var originStrings = [...]string{
+	"unknown",
+	"seed",
+	"wordlist",
+	"extension",
+	"variant",
+	"spider",
+	"redirect",
+	"robots",
+	"mangle",
+	"parent",
+	"shortname",
+	"probe",
+	"plugin",
+	"permutation",
+}
+
+func (o Origin) String() string {
+	if o < 0 || o >= originMax {
+		return originStrings[OriginUnknown]
+	}
+	return originStrings[o]
+}
+
+// Provenance records why a task was requested: how it was found, what it was
+// derived from, and the word, extension or rule used.
+type Provenance struct {
+	Origin Origin `json:"origin"`
+	// URL the task was derived from, if any
+	From string `json:"from,omitempty"`
+	// Word, extension or rule used, if any
+	Detail string `json:"detail,omitempty"`
+}
+
+// Build the provenance of a task derived from the URL from.
+func NewProvenance(origin Origin, from *url.URL, detail string) Provenance {
+	p := Provenance{Origin: origin, Detail: detail}
+	if from != nil {
+		p.From = from.String()
+	}
+	return p
+}
+
+// Describe the provenance, such as `wordlist "admin" from http://host/`.
+func (p Provenance) String() string {
+	s := p.Origin.String()
+	if p.Detail != "" {
+		s = fmt.Sprintf("%s %q", s, p.Detail)
+	}
+	if p.From != "" {
+		s = fmt.Sprintf("%s from %s", s, p.From)
+	}
+	return s
+}
@@ -0,0 +1,54 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestProvenance_String(t *testing.T) {
+	from := &url.URL{Scheme: "http", Host: "localhost", Path: "/a"}
+	cases := []struct {
+		p        Provenance
+		expected string
+	}{
+		{Provenance{Origin: OriginSeed}, "seed"},
+		{NewProvenance(OriginRedirect, from, ""), "redirect from http://localhost/a"},
+		{NewProvenance(OriginExtension, from, "php"), `extension "php" from http://localhost/a`},
+		{Provenance{Origin: originMax}, "unknown"},
+	}
+	for _, c := range cases {
+		if got := c.p.String(); got != c.expected {
+			t.Errorf("Expected %q, got %q", c.expected, got)
+		}
+	}
+	if len(originStrings) != int(originMax) {
+		t.Errorf("originStrings != enum: %d vs %d", len(originStrings), originMax)
+	}
+}
+
+func TestCopy_Provenance(t *testing.T) {
+	orig := NewTaskFromURL(&url.URL{Path: "/a"})
+	orig.Provenance = Provenance{Origin: OriginSpider, From: "http://localhost/"}
+	orig.Referrer = &url.URL{Path: "/"}
+	c := orig.Copy()
+	if c.Provenance != orig.Provenance {
+		t.Errorf("Expected provenance to be copied, got %s", c.Provenance)
+	}
+	if c.Referrer != nil {
+		t.Error("Expected referrer not to be copied.")
+	}
+}
@@ -25,6 +25,19 @@ type Task struct {
 	URL    *url.URL
 	Host   string
 	Header http.Header
+	// Page the URL was linked from, if any.  Not copied, as variations of
+	// the URL were not linked.
+	Referrer *url.URL
+	// Outside the scan, so only checked and never spidered
+	External bool
+	// Why the task was requested.  Copied, so tasks derived from this one
+	// keep it unless they record their own.
+	Provenance Provenance
+	// URL of the task this one was found from, if any
+	ParentURL *url.URL
+	// Steps from a starting URL.  Variants of a task, such as with another
+	// extension, are at the same depth.
+	Depth int
 
 	// Mutex to protect map & data structures
 	sync.Mutex
@@ -52,8 +65,11 @@ func (t *Task) Copy() *Task {
 	defer t.Unlock()
 	tmpU := *t.URL
 	newT := &Task{
-		Host: t.Host,
-		URL:  &tmpU,
+		Host:       t.Host,
+		URL:        &tmpU,
+		Provenance: t.Provenance,
+		ParentURL:  t.ParentURL,
+		Depth:      t.Depth,
 	}
 	newT.Header = make(http.Header)
 	for k, v := range t.Header {
@@ -62,6 +78,18 @@ func (t *Task) Copy() *Task {
 	return newT
 }
 
+// Record that the task was found from parent, one step further from the
+// starting URL.
+func (t *Task) SetParent(parent *Task) {
+	t.ParentURL = parent.URL
+	t.Depth = parent.Depth + 1
+}
+
+// How the task was found.
+func (t *Task) SourceType() Origin {
+	return t.Provenance.Origin
+}
+
 func SetDefaultHeader(header http.Header) {
 	defaultHeader = header
 }
@@ -0,0 +1,34 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestSetParent(t *testing.T) {
+	parent := NewTaskFromURL(&url.URL{Path: "/a/"})
+	parent.Depth = 2
+	child := parent.Copy()
+	child.URL.Path = "/a/b"
+	child.SetParent(parent)
+	if child.ParentURL != parent.URL || child.Depth != 3 {
+		t.Errorf("Expected depth 3 under /a/, got %d under %v", child.Depth, child.ParentURL)
+	}
+	if variant := child.Copy(); variant.Depth != 3 || variant.ParentURL != parent.URL {
+		t.Error("Expected a copy to keep its parent and depth.")
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"hash/fnv"
+	"math/bits"
+)
+
+// Number of bits set for each string, about right at 10 bits per string
+const bloomHashes = 7
+
+// BloomFilter is a fixed-size set of strings that never forgets one, but may
+// wrongly claim to contain one it was never given.
+type BloomFilter struct {
+	bits []uint64
+}
+
+// Create a BloomFilter using size bytes.
+func NewBloomFilter(size int64) *BloomFilter {
+	words := size / 8
+	if words < 1 {
+		words = 1
+	}
+	return &BloomFilter{bits: make([]uint64, words)}
+}
+
+func (b *BloomFilter) Add(s string) {
+	h1, h2 := bloomHash(s)
+	n := uint64(len(b.bits)) * 64
+	for i := uint64(0); i < bloomHashes; i++ {
+		bit := (h1 + i*h2) % n
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+func (b *BloomFilter) Contains(s string) bool {
+	h1, h2 := bloomHash(s)
+	n := uint64(len(b.bits)) * 64
+	for i := uint64(0); i < bloomHashes; i++ {
+		bit := (h1 + i*h2) % n
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Two hashes of s, combined to pick each bit
+func bloomHash(s string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	h1 := h.Sum64()
+	h2 := bits.RotateLeft64(h1*0x9e3779b97f4a7c15, 31) | 1
+	return h1, h2
+}
@@ -0,0 +1,97 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"hash/fnv"
+	"math/bits"
+)
+
+// Longest token considered when computing the fuzzy hash
+const maxFuzzyToken = 64
+
+// BodyHasher computes both a SHA-256 digest and a 64-bit simhash of data
+// written to it.  The simhash is computed over whitespace and tag delimited
+// tokens, so bodies that differ only slightly (such as by an embedded path or
+// timestamp) have hashes that differ in only a few bits.
+type BodyHasher struct {
+	sha     hash.Hash
+	weights [64]int
+	token   []byte
+}
+
+func NewBodyHasher() *BodyHasher {
+	return &BodyHasher{
+		sha:   sha256.New(),
+		token: make([]byte, 0, maxFuzzyToken),
+	}
+}
+
+func (h *BodyHasher) Write(p []byte) (int, error) {
+	h.sha.Write(p)
+	for _, b := range p {
+		switch b {
+		case ' ', '\t', '\r', '\n', '<', '>', '"', '\'', '=':
+			h.endToken()
+		default:
+			if len(h.token) < maxFuzzyToken {
+				h.token = append(h.token, b)
+			}
+		}
+	}
+	return len(p), nil
+}
+
+func (h *BodyHasher) endToken() {
+	if len(h.token) == 0 {
+		return
+	}
+	f := fnv.New64a()
+	f.Write(h.token)
+	v := f.Sum64()
+	for i := 0; i < 64; i++ {
+		if v&(1<<uint(i)) != 0 {
+			h.weights[i]++
+		} else {
+			h.weights[i]--
+		}
+	}
+	h.token = h.token[:0]
+}
+
+// Get the hex-encoded SHA-256 digest of the data written.
+func (h *BodyHasher) SHA256() string {
+	return hex.EncodeToString(h.sha.Sum(nil))
+}
+
+// Get the simhash of the data written.
+func (h *BodyHasher) FuzzyHash() uint64 {
+	h.endToken()
+	var v uint64
+	for i, w := range h.weights {
+		if w > 0 {
+			v |= 1 << uint(i)
+		}
+	}
+	return v
+}
+
+// Number of bits that differ between two fuzzy hashes.
+func FuzzyDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
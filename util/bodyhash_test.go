@@ -0,0 +1,58 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func hashString(s string) *BodyHasher {
+	h := NewBodyHasher()
+	io.Copy(h, strings.NewReader(s))
+	return h
+}
+
+func TestBodyHasher_SHA256(t *testing.T) {
+	h := hashString("hello")
+	expected := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	if h.SHA256() != expected {
+		t.Errorf("Expected %s, got %s", expected, h.SHA256())
+	}
+}
+
+func TestBodyHasher_FuzzyHash(t *testing.T) {
+	page := "<html><head><title>Not Found</title></head><body><h1>Not Found</h1>" +
+		"<p>The requested URL %s was not found on this server.</p>" +
+		"<hr><address>Apache/2.4.29 (Ubuntu) Server at example.com Port 80</address>" +
+		"</body></html>"
+	a := hashString(strings.Replace(page, "%s", "/admin", 1)).FuzzyHash()
+	b := hashString(strings.Replace(page, "%s", "/backup", 1)).FuzzyHash()
+	c := hashString("<html><body>Welcome to the administrative dashboard, please log in to continue.</body></html>").FuzzyHash()
+	if d := FuzzyDistance(a, b); d > 8 {
+		t.Errorf("Expected similar pages to be close, distance %d", d)
+	}
+	if d := FuzzyDistance(a, c); d <= 8 {
+		t.Errorf("Expected different pages to be far apart, distance %d", d)
+	}
+	// Writes split mid-token must not change the hash
+	h := NewBodyHasher()
+	h.Write([]byte("hello wor"))
+	h.Write([]byte("ld"))
+	if h.FuzzyHash() != hashString("hello world").FuzzyHash() {
+		t.Error("Expected split writes to produce the same hash.")
+	}
+}
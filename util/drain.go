@@ -0,0 +1,33 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"io"
+	"io/ioutil"
+)
+
+// Most of a response body to read when discarding it.  Past this, opening a
+// new connection is cheaper than downloading the rest.
+const MaxDrainSize = 64 * 1024
+
+// Read and discard what's left of a response body, up to MaxDrainSize, then
+// close it.  Transports only reuse a connection once its last response has
+// been read to the end; closing a body early drops the connection.
+// ioutil.Discard reads into pooled buffers, so this doesn't allocate.
+func DrainBody(body io.ReadCloser) {
+	io.CopyN(ioutil.Discard, body, MaxDrainSize)
+	body.Close()
+}
@@ -0,0 +1,43 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+type countingBody struct {
+	*strings.Reader
+	closed bool
+}
+
+func (b *countingBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestDrainBody(t *testing.T) {
+	small := &countingBody{Reader: strings.NewReader("short body")}
+	DrainBody(small)
+	if small.Len() != 0 || !small.closed {
+		t.Errorf("Expected small body read and closed, %d left, closed: %v", small.Len(), small.closed)
+	}
+	large := &countingBody{Reader: strings.NewReader(strings.Repeat("x", MaxDrainSize+100))}
+	DrainBody(large)
+	if large.Len() != 100 || !large.closed {
+		t.Errorf("Expected large body closed after %d bytes, %d left, closed: %v", MaxDrainSize, large.Len(), large.closed)
+	}
+}
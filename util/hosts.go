@@ -0,0 +1,114 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Default ports left out of canonical hosts
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Get the default port of a scheme, or "" if it has none.
+func DefaultPort(scheme string) string {
+	return defaultPorts[strings.ToLower(scheme)]
+}
+
+// Get the canonical form of host, with or without a port, for a URL with the
+// given scheme: lowercase, IP addresses in their shortest form with IPv6 in
+// brackets, and without the scheme's default port.  This lets [::1],
+// [0:0::1] and [::1]:80 be recognized as the same host.
+func CanonicalHost(scheme, host string) string {
+	name, port := host, ""
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		name, port = h, p
+	} else {
+		name = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	}
+	name = strings.ToLower(name)
+	// Keep any zone of a link-local address as it is
+	zone := ""
+	if i := strings.Index(name, "%"); i >= 0 {
+		name, zone = name[:i], name[i:]
+	}
+	if ip := net.ParseIP(name); ip != nil {
+		name = ip.String()
+	}
+	name += zone
+	if port == defaultPorts[strings.ToLower(scheme)] {
+		port = ""
+	}
+	if port != "" {
+		return net.JoinHostPort(name, port)
+	}
+	return BracketHost(name)
+}
+
+// Put an IPv6 address in brackets, as needed in URLs and Host headers.
+// Anything else is returned as is.
+func BracketHost(host string) string {
+	if strings.Contains(host, ":") && !strings.HasPrefix(host, "[") {
+		name := host
+		if i := strings.Index(name, "%"); i >= 0 {
+			name = name[:i]
+		}
+		if ip := net.ParseIP(name); ip != nil {
+			return "[" + host + "]"
+		}
+	}
+	return host
+}
+
+// Set the host of u to its canonical form.
+func CanonicalizeURLHost(u *url.URL) {
+	if u.Host != "" {
+		u.Host = CanonicalHost(u.Scheme, u.Host)
+	}
+}
+
+// Check if a starting URL was given with a scheme.
+func HasScheme(target string) bool {
+	return strings.Contains(target, "://")
+}
+
+// Parse a starting URL, which may be given without a scheme, such as a bare
+// host name or IPv6 address, in which case http is used.
+func ParseTargetURL(target string) (*url.URL, error) {
+	if !HasScheme(target) {
+		target = "http://" + BracketHost(target)
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	CanonicalizeURLHost(u)
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u, nil
+}
+
+// Check if two hosts, as in URLs with the given schemes, are the same.
+func sameHost(schemeA, hostA, schemeB, hostB string) bool {
+	if hostA == hostB {
+		return true
+	}
+	return CanonicalHost(schemeA, hostA) == CanonicalHost(schemeB, hostB)
+}
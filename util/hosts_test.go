@@ -0,0 +1,115 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestCanonicalHost(t *testing.T) {
+	tests := []struct {
+		scheme, host, expected string
+	}{
+		{"http", "Example.COM", "example.com"},
+		{"http", "example.com:80", "example.com"},
+		{"https", "example.com:80", "example.com:80"},
+		{"https", "example.com:443", "example.com"},
+		{"http", "[::1]", "[::1]"},
+		{"http", "[0:0::1]:80", "[::1]"},
+		{"http", "[::1]:8080", "[::1]:8080"},
+		{"http", "::1", "[::1]"},
+		{"http", "[FE80::1%eth0]:8080", "[fe80::1%eth0]:8080"},
+		{"http", "[::ffff:127.0.0.1]", "127.0.0.1"},
+		{"http", "127.0.0.1:80", "127.0.0.1"},
+	}
+	for _, test := range tests {
+		if got := CanonicalHost(test.scheme, test.host); got != test.expected {
+			t.Errorf("CanonicalHost(%q, %q) = %q, expected %q", test.scheme, test.host, got, test.expected)
+		}
+	}
+}
+
+func TestBracketHost(t *testing.T) {
+	tests := map[string]string{
+		"::1":           "[::1]",
+		"[::1]":         "[::1]",
+		"fe80::1%eth0":  "[fe80::1%eth0]",
+		"example.com":   "example.com",
+		"127.0.0.1":     "127.0.0.1",
+		"localhost:80":  "localhost:80",
+		"[::1]:8080":    "[::1]:8080",
+		"not:an:ipv6:x": "not:an:ipv6:x",
+	}
+	for host, expected := range tests {
+		if got := BracketHost(host); got != expected {
+			t.Errorf("BracketHost(%q) = %q, expected %q", host, got, expected)
+		}
+	}
+}
+
+func TestParseTargetURL(t *testing.T) {
+	tests := map[string]string{
+		"example.com":             "http://example.com/",
+		"::1":                     "http://[::1]/",
+		"https://[0::1]:443/app":  "https://[::1]/app",
+		"http://[::1]:8080":       "http://[::1]:8080/",
+		"http://10.0.0.1:80/a/b/": "http://10.0.0.1/a/b/",
+	}
+	for target, expected := range tests {
+		u, err := ParseTargetURL(target)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %q: %v", target, err)
+			continue
+		}
+		if u.String() != expected {
+			t.Errorf("ParseTargetURL(%q) = %q, expected %q", target, u.String(), expected)
+		}
+	}
+}
+
+func TestURLIsSubpath_IPv6(t *testing.T) {
+	parent, _ := url.Parse("http://[::1]/app/")
+	tests := map[string]bool{
+		"http://[::1]/app/admin":        true,
+		"http://[0:0::1]:80/app/admin":  true,
+		"http://[::1]:8080/app/admin":   false,
+		"http://[::2]/app/admin":        false,
+		"http://127.0.0.1/app/admin":    false,
+		"https://[::1]:443/app/admin":   false,
+		"http://[::1]/application/x":    false,
+		"http://[0000::0001]/app/x/y/z": true,
+	}
+	for child, expected := range tests {
+		u, _ := url.Parse(child)
+		if got := URLIsSubpath(parent, u); got != expected {
+			t.Errorf("URLIsSubpath(%s, %s) = %v, expected %v", parent, child, got, expected)
+		}
+	}
+}
+
+func TestGetParentPaths_IPv6(t *testing.T) {
+	u, _ := url.Parse("http://[::1]:8080/a/b/c")
+	res := GetParentPaths(u)
+	expected := []string{"http://[::1]:8080/a", "http://[::1]:8080/a/b"}
+	if len(res) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, res)
+	}
+	for i, exp := range expected {
+		if res[i].String() != exp {
+			t.Errorf("Expected %s, got %s", exp, res[i])
+		}
+	}
+}
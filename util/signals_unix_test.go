@@ -0,0 +1,37 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build darwin || dragonfly || freebsd || linux || nacl || netbsd || openbsd || solaris
+// +build darwin dragonfly freebsd linux nacl netbsd openbsd solaris
+
+package util
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestOnSignal(t *testing.T) {
+	called := make(chan bool, 1)
+	stop := OnSignal(syscall.SIGUSR2, func() { called <- true })
+	defer stop()
+	syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+	select {
+	case <-called:
+	case <-time.After(time.Second):
+		t.Error("Signal handler was not called.")
+	}
+}
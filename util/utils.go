@@ -18,6 +18,7 @@ package util
 import (
 	"fmt"
 	"github.com/Matir/webborer/logging"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"os"
@@ -25,6 +26,7 @@ import (
 	"path"
 	"runtime"
 	"runtime/pprof"
+	"runtime/trace"
 	"strings"
 	"syscall"
 )
@@ -67,6 +69,22 @@ func EnableStackTraces() func() {
 	}
 }
 
+// Run f every time sig is received.
+// Returns a function that can be used to stop handling the signal.
+func OnSignal(sig os.Signal, f func()) func() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, sig)
+	go func() {
+		for range sigs {
+			f()
+		}
+	}()
+	return func() {
+		signal.Stop(sigs)
+		close(sigs)
+	}
+}
+
 func DumpStackTrace() {
 	buf := make([]byte, 1<<20)
 	runtime.Stack(buf, true)
@@ -94,16 +112,21 @@ func URLIsSubpath(parent, child *url.URL) bool {
 	if parent.Scheme != "" && child.Scheme != parent.Scheme {
 		return false
 	}
-	if parent.Host != "" && child.Host != parent.Host {
+	parentScheme := parent.Scheme
+	if parentScheme == "" {
+		parentScheme = child.Scheme
+	}
+	if parent.Host != "" && !sameHost(parentScheme, parent.Host, child.Scheme, child.Host) {
 		return false
 	}
 	if parent.Path == "/" {
 		// Everything is in this path
 		return true
 	}
-	// Now split the path
-	pPath := path.Clean(parent.Path)
-	cPath := path.Clean(child.Path)
+	// Now split the path, as escaped so that payloads like %2e%2e stay in the
+	// directory they were added to
+	pPath := path.Clean(parent.EscapedPath())
+	cPath := path.Clean(child.EscapedPath())
 	if len(cPath) < len(pPath) {
 		return false
 	}
@@ -138,10 +161,10 @@ func getParentPathsString(childPath string) []string {
 	return results
 }
 
-// Debug profiling support
-func EnableCPUProfiling() func() {
-	if profFile, err := os.Create("webborer.prof"); err != nil {
-		logging.Logf(logging.LogError, "Unable to open webborer.prof for profiling: %v", err)
+// Debug profiling support, writing the profile to path
+func EnableCPUProfiling(path string) func() {
+	if profFile, err := os.Create(path); err != nil {
+		logging.Logf(logging.LogError, "Unable to open %s for profiling: %v", path, err)
 	} else {
 		pprof.StartCPUProfile(profFile)
 		sigintChan := make(chan os.Signal, 1)
@@ -161,6 +184,35 @@ func EnableCPUProfiling() func() {
 	return nil
 }
 
+// Write a Go execution trace to path until the returned function is called.
+func EnableTracing(path string) func() {
+	traceFile, err := os.Create(path)
+	if err != nil {
+		logging.Logf(logging.LogError, "Unable to open %s for tracing: %v", path, err)
+		return nil
+	}
+	if err := trace.Start(traceFile); err != nil {
+		logging.Logf(logging.LogError, "Unable to start tracing: %v", err)
+		traceFile.Close()
+		return nil
+	}
+	return func() {
+		trace.Stop()
+		traceFile.Close()
+	}
+}
+
+const randomChars = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// Generate a random lowercase alphanumeric string of length n
+func RandomString(n int) string {
+	buf := make([]byte, n)
+	for i := range buf {
+		buf[i] = randomChars[rand.Intn(len(randomChars))]
+	}
+	return string(buf)
+}
+
 // Does a slice of strings contain a string
 func StringSliceContains(haystack []string, needle string) bool {
 	for _, v := range haystack {
@@ -15,7 +15,9 @@
 package util
 
 import (
+	"fmt"
 	"net/url"
+	"path/filepath"
 	"testing"
 )
 
@@ -140,6 +142,7 @@ func TestURLIsSubpath(t *testing.T) {
 		"http://127.0.0.1/foo/bar":             {false, true, false, true},
 		"http://localhost/foo/bar/..":          {false, false, true, true},
 		"http://localhost/foo/baz/../bar/":     {true, true, true, true},
+		"http://localhost/foo/bar/%2e%2e/baz":  {true, true, true, true},
 		"http://localhost/bar":                 {false, false, true, true},
 		"http://localhost/bar/baz/bang/longer": {false, false, true, true},
 		"/foo/bar/baz":                         {false, true, false, true},
@@ -211,6 +214,55 @@ func TestEnableStackTraces(t *testing.T) {
 }
 
 func TestEnableCPUProfiling(t *testing.T) {
-	cancel := EnableCPUProfiling()
+	cancel := EnableCPUProfiling(filepath.Join(t.TempDir(), "webborer.prof"))
+	if cancel == nil {
+		t.Fatal("Expected profiling to start.")
+	}
 	cancel()
 }
+
+func TestBloomFilter(t *testing.T) {
+	b := NewBloomFilter(1024)
+	for i := 0; i < 100; i++ {
+		b.Add(fmt.Sprintf("/path/%d", i))
+	}
+	for i := 0; i < 100; i++ {
+		if !b.Contains(fmt.Sprintf("/path/%d", i)) {
+			t.Errorf("Expected /path/%d to be found", i)
+		}
+	}
+	wrong := 0
+	for i := 100; i < 1100; i++ {
+		if b.Contains(fmt.Sprintf("/path/%d", i)) {
+			wrong++
+		}
+	}
+	if wrong > 20 {
+		t.Errorf("Too many false positives: %d in 1000", wrong)
+	}
+}
+
+func TestMurmur3(t *testing.T) {
+	cases := map[string]int32{
+		"":            0,
+		"hello":       613153351,
+		"hello world": 1586663183,
+	}
+	for input, expected := range cases {
+		if h := int32(Murmur3([]byte(input), 0)); h != expected {
+			t.Errorf("Murmur3(%q) = %d, expected %d", input, h, expected)
+		}
+	}
+}
+
+func TestFaviconHash(t *testing.T) {
+	// Long enough to be wrapped, like Python's base64.encodebytes
+	icon := make([]byte, 100)
+	for i := range icon {
+		icon[i] = byte(i)
+	}
+	encoded := "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8gISIjJCUmJygpKissLS4vMDEyMzQ1Njc4\nOTo7PD0+P0BBQkNERUZHSElKS0xNTk9QUVJTVFVWV1hZWltcXV5fYGFiYw==\n"
+	if h := FaviconHash(icon); h != int32(Murmur3([]byte(encoded), 0)) {
+		t.Errorf("Unexpected favicon hash %d", h)
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package wire holds the serialized forms of tasks and results, shared by
+// remote agents and by plugins running as separate processes.
+package wire
+
+import (
+	"errors"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/task"
+	"net/http"
+	"net/url"
+)
+
+// Task is the serialized form of a task.
+type Task struct {
+	URL        string          `json:"url"`
+	Host       string          `json:"host,omitempty"`
+	Header     http.Header     `json:"header,omitempty"`
+	Provenance task.Provenance `json:"provenance"`
+	ParentURL  string          `json:"parent,omitempty"`
+	Depth      int             `json:"depth,omitempty"`
+	Referrer   string          `json:"referrer,omitempty"`
+	External   bool            `json:"external,omitempty"`
+}
+
+// Result is the serialized form of a result.
+type Result struct {
+	URL            string                      `json:"url"`
+	Host           string                      `json:"host,omitempty"`
+	Code           int                         `json:"code"`
+	Error          string                      `json:"error,omitempty"`
+	Redir          string                      `json:"redir,omitempty"`
+	RedirectChain  []string                    `json:"redirect_chain,omitempty"`
+	Length         int64                       `json:"length"`
+	ContentType    string                      `json:"content_type,omitempty"`
+	RequestHeader  http.Header                 `json:"request_header,omitempty"`
+	ResponseHeader http.Header                 `json:"response_header,omitempty"`
+	ResultGroup    string                      `json:"result_group,omitempty"`
+	Links          map[string]results.LinkType `json:"links,omitempty"`
+	Notes          []string                    `json:"notes,omitempty"`
+	Baseline       bool                        `json:"baseline,omitempty"`
+	Findings       []*results.Finding          `json:"findings,omitempty"`
+	BodyHash       string                      `json:"body_hash,omitempty"`
+	Confidence     results.Confidence          `json:"confidence,omitempty"`
+	FuzzyHash      uint64                      `json:"fuzzy_hash,omitempty"`
+	Provenance     task.Provenance             `json:"provenance"`
+}
+
+// Serialize a task.
+func FromTask(t *task.Task) *Task {
+	return &Task{
+		URL:        t.URL.String(),
+		Host:       t.Host,
+		Header:     t.Header,
+		Provenance: t.Provenance,
+		ParentURL:  maybeString(t.ParentURL),
+		Depth:      t.Depth,
+		Referrer:   maybeString(t.Referrer),
+		External:   t.External,
+	}
+}
+
+func maybeString(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	return u.String()
+}
+
+// Get the task back from its serialized form.
+func (w *Task) Task() (*task.Task, error) {
+	u, err := url.Parse(w.URL)
+	if err != nil {
+		return nil, err
+	}
+	t := task.NewTaskFromURL(u)
+	t.Host = w.Host
+	t.Provenance = w.Provenance
+	t.Depth = w.Depth
+	t.External = w.External
+	if w.ParentURL != "" {
+		if t.ParentURL, err = url.Parse(w.ParentURL); err != nil {
+			return nil, err
+		}
+	}
+	if w.Referrer != "" {
+		if t.Referrer, err = url.Parse(w.Referrer); err != nil {
+			return nil, err
+		}
+	}
+	if w.Header != nil {
+		t.Header = w.Header
+	}
+	return t, nil
+}
+
+// Serialize a result.
+func FromResult(r *results.Result) *Result {
+	w := &Result{
+		Host:           r.Host,
+		Code:           r.Code,
+		Length:         r.Length,
+		ContentType:    r.ContentType,
+		RequestHeader:  r.RequestHeader,
+		ResponseHeader: r.ResponseHeader,
+		ResultGroup:    r.ResultGroup,
+		Links:          r.Links,
+		Notes:          r.Notes,
+		Baseline:       r.Baseline,
+		Findings:       r.Findings,
+		BodyHash:       r.BodyHash,
+		Confidence:     r.Confidence,
+		FuzzyHash:      r.FuzzyHash,
+		Provenance:     r.Provenance,
+	}
+	if r.URL != nil {
+		w.URL = r.URL.String()
+	}
+	if r.Error != nil {
+		w.Error = r.Error.Error()
+	}
+	if r.Redir != nil {
+		w.Redir = r.Redir.String()
+	}
+	for _, u := range r.RedirectChain {
+		w.RedirectChain = append(w.RedirectChain, u.String())
+	}
+	return w
+}
+
+// Get the result back from its serialized form.
+func (w *Result) Result() (*results.Result, error) {
+	u, err := url.Parse(w.URL)
+	if err != nil {
+		return nil, err
+	}
+	r := &results.Result{
+		URL:            u,
+		Host:           w.Host,
+		Code:           w.Code,
+		Length:         w.Length,
+		ContentType:    w.ContentType,
+		RequestHeader:  w.RequestHeader,
+		ResponseHeader: w.ResponseHeader,
+		ResultGroup:    w.ResultGroup,
+		Links:          w.Links,
+		Notes:          w.Notes,
+		Baseline:       w.Baseline,
+		Findings:       w.Findings,
+		BodyHash:       w.BodyHash,
+		Confidence:     w.Confidence,
+		FuzzyHash:      w.FuzzyHash,
+		Provenance:     w.Provenance,
+	}
+	for _, f := range r.Findings {
+		// The URL of a finding isn't sent
+		f.URL = u
+	}
+	if w.Error != "" {
+		r.Error = errors.New(w.Error)
+	}
+	if w.Redir != "" {
+		if r.Redir, err = url.Parse(w.Redir); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range w.RedirectChain {
+		hop, err := url.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		r.RedirectChain = append(r.RedirectChain, hop)
+	}
+	return r, nil
+}
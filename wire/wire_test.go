@@ -0,0 +1,78 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wire
+
+import (
+	"errors"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/task"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestTaskWire(t *testing.T) {
+	orig := task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/a b"})
+	orig.Host = "vhost"
+	orig.Header = http.Header{"X-Test": []string{"1"}}
+	orig.Provenance = task.Provenance{Origin: task.OriginWordlist, From: "http://localhost/", Detail: "a b"}
+	orig.ParentURL = &url.URL{Scheme: "http", Host: "localhost", Path: "/"}
+	orig.Depth = 2
+	orig.Referrer = &url.URL{Scheme: "http", Host: "localhost", Path: "/links"}
+	orig.External = true
+	got, err := FromTask(orig).Task()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got.URL.String() != orig.URL.String() || got.Host != "vhost" || got.Header.Get("X-Test") != "1" || got.Provenance != orig.Provenance || got.Depth != 2 || got.ParentURL.String() != "http://localhost/" {
+		t.Errorf("Task did not survive round trip: %v", got)
+	}
+	if got.Referrer.String() != "http://localhost/links" || !got.External {
+		t.Errorf("Link check fields did not survive round trip: %v", got)
+	}
+	if _, err := (&Task{URL: "://"}).Task(); err == nil {
+		t.Error("Expected error for invalid URL.")
+	}
+}
+
+func TestResultWire(t *testing.T) {
+	orig := &results.Result{
+		URL:           &url.URL{Scheme: "http", Host: "localhost", Path: "/x"},
+		Code:          302,
+		Error:         errors.New("oops"),
+		Redir:         &url.URL{Scheme: "http", Host: "localhost", Path: "/y"},
+		RedirectChain: []*url.URL{{Scheme: "http", Host: "localhost", Path: "/z"}},
+		Length:        -1,
+		Notes:         []string{"note"},
+		BodyHash:      "abc",
+	}
+	orig.AddFinding("rule", results.SeverityHigh, "bad")
+	got, err := FromResult(orig).Result()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got.URL.String() != orig.URL.String() || got.Code != 302 || got.Length != -1 {
+		t.Errorf("Basic fields did not survive round trip: %v", got)
+	}
+	if got.Error == nil || got.Error.Error() != "oops" {
+		t.Errorf("Error did not survive round trip: %v", got.Error)
+	}
+	if got.Redir.Path != "/y" || len(got.RedirectChain) != 1 || got.RedirectChain[0].Path != "/z" {
+		t.Errorf("Redirects did not survive round trip.")
+	}
+	if len(got.Findings) != 1 || got.Findings[0].Severity != results.SeverityHigh || got.BodyHash != "abc" {
+		t.Errorf("Analysis did not survive round trip.")
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wordlist
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Ranges within a wordlist entry, such as {2015-2025}, {01-31} or {a-z}
+var generatorRangeRE = regexp.MustCompile(`\{([0-9]{1,9}|[a-zA-Z])-([0-9]{1,9}|[a-zA-Z])\}`)
+
+// A Generator is a wordlist entry containing ranges, such as
+// backup_{2015-2025}{01-12}, which stands for every word made by putting one
+// value of each range in its place.  The words are made as they are needed.
+type Generator struct {
+	parts []generatorPart
+}
+
+// Either literal text or a range of values
+type generatorPart struct {
+	literal string
+	// Values are start, start+step, ... for count values
+	start, step, count int
+	// Width to zero-pad numbers to
+	width int
+	// Whether the values are letters rather than numbers
+	letters bool
+}
+
+func (p generatorPart) value(i int) string {
+	v := p.start + i*p.step
+	if p.letters {
+		return string(rune(v))
+	}
+	return fmt.Sprintf("%0*d", p.width, v)
+}
+
+// Parse a wordlist entry, returning nil if it has no ranges.  Braces that
+// aren't ranges, such as {id} or {{7*7}}, are left as they are.
+func ParseGenerator(word string) *Generator {
+	matches := generatorRangeRE.FindAllStringSubmatchIndex(word, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	g := &Generator{}
+	last := 0
+	for _, m := range matches {
+		part, ok := parseRange(word[m[2]:m[3]], word[m[4]:m[5]])
+		if !ok {
+			continue
+		}
+		if m[0] > last {
+			g.parts = append(g.parts, generatorPart{literal: word[last:m[0]]})
+		}
+		g.parts = append(g.parts, part)
+		last = m[1]
+	}
+	if last == 0 {
+		return nil
+	}
+	if last < len(word) {
+		g.parts = append(g.parts, generatorPart{literal: word[last:]})
+	}
+	return g
+}
+
+// Parse the ends of a range.  Numbers starting with 0 set the width to pad
+// to, as in {01-31}.  Letters must be the same case.
+func parseRange(from, to string) (generatorPart, bool) {
+	var part generatorPart
+	var start, end int
+	if isLetter(from) || isLetter(to) {
+		if !isLetter(from) || !isLetter(to) || isUpper(from) != isUpper(to) {
+			return part, false
+		}
+		part.letters = true
+		start, end = int(from[0]), int(to[0])
+	} else {
+		start, _ = strconv.Atoi(from)
+		end, _ = strconv.Atoi(to)
+		if len(from) > 1 && from[0] == '0' {
+			part.width = len(from)
+		}
+	}
+	part.start = start
+	part.step = 1
+	if end < start {
+		part.step = -1
+	}
+	part.count = (end-start)*part.step + 1
+	return part, true
+}
+
+func isLetter(s string) bool {
+	return len(s) == 1 && (s[0] < '0' || s[0] > '9')
+}
+
+func isUpper(s string) bool {
+	return strings.ToUpper(s) == s
+}
+
+// Get the number of words the generator makes.
+func (g *Generator) Count() int {
+	count := 1
+	for _, p := range g.parts {
+		if p.count > 0 {
+			count *= p.count
+		}
+	}
+	return count
+}
+
+// Call fn with each word in turn, the last range varying fastest.
+func (g *Generator) Each(fn func(string)) {
+	indices := make([]int, len(g.parts))
+	var b strings.Builder
+	for {
+		b.Reset()
+		for i, p := range g.parts {
+			if p.count == 0 {
+				b.WriteString(p.literal)
+			} else {
+				b.WriteString(p.value(indices[i]))
+			}
+		}
+		fn(b.String())
+		i := len(g.parts) - 1
+		for ; i >= 0; i-- {
+			if g.parts[i].count == 0 {
+				continue
+			}
+			if indices[i]++; indices[i] < g.parts[i].count {
+				break
+			}
+			indices[i] = 0
+		}
+		if i < 0 {
+			return
+		}
+	}
+}
+
+// Replace each entry containing ranges with the words it makes.  Only for
+// short lists, such as prefixes and suffixes, since every word is held in
+// memory.
+func ExpandGenerators(words []string) []string {
+	expanded := make([]string, 0, len(words))
+	for _, w := range words {
+		if g := ParseGenerator(w); g != nil {
+			g.Each(func(word string) {
+				expanded = append(expanded, word)
+			})
+		} else {
+			expanded = append(expanded, w)
+		}
+	}
+	return expanded
+}
@@ -0,0 +1,70 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wordlist
+
+import (
+	"reflect"
+	"testing"
+)
+
+func generate(g *Generator) []string {
+	var words []string
+	g.Each(func(w string) {
+		words = append(words, w)
+	})
+	return words
+}
+
+func TestParseGenerator(t *testing.T) {
+	cases := []struct {
+		word     string
+		expected []string
+	}{
+		{"backup_{2019-2021}", []string{"backup_2019", "backup_2020", "backup_2021"}},
+		{"{08-10}.log", []string{"08.log", "09.log", "10.log"}},
+		{"v{3-1}", []string{"v3", "v2", "v1"}},
+		{"{a-c}{1-2}", []string{"a1", "a2", "b1", "b2", "c1", "c2"}},
+		{"{X-Z}{id}", []string{"X{id}", "Y{id}", "Z{id}"}},
+		{"{a-5}_{1-2}", []string{"{a-5}_1", "{a-5}_2"}},
+	}
+	for _, c := range cases {
+		g := ParseGenerator(c.word)
+		if g == nil {
+			t.Errorf("Expected %q to be a generator", c.word)
+			continue
+		}
+		if got := generate(g); !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("Expected %q to make %v, got %v", c.word, c.expected, got)
+		}
+		if g.Count() != len(c.expected) {
+			t.Errorf("Expected %q to count %d, got %d", c.word, len(c.expected), g.Count())
+		}
+	}
+}
+
+func TestParseGenerator_NoRanges(t *testing.T) {
+	for _, word := range []string{"admin", "{id}", "{{7*7}}", "{a-Z}", "{1-b}"} {
+		if g := ParseGenerator(word); g != nil {
+			t.Errorf("Expected %q not to be a generator, got %v", word, generate(g))
+		}
+	}
+}
+
+func TestExpandGenerators(t *testing.T) {
+	got := ExpandGenerators([]string{"old", "{1-2}"})
+	if expected := []string{"old", "1", "2"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
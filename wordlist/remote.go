@@ -0,0 +1,140 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wordlist
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/Matir/webborer/logging"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Longest time to spend downloading a wordlist
+const remoteTimeout = 5 * time.Minute
+
+// Directory to keep downloaded wordlists in, if any
+var cacheDir string
+
+var remoteClient = &http.Client{Timeout: remoteTimeout}
+
+// Keep wordlists fetched over HTTP(S) in dir, and use them from there rather
+// than downloading them again.  An empty dir disables the cache.
+func SetCacheDir(dir string) {
+	cacheDir = dir
+}
+
+// Check if a wordlist path is a URL to fetch.
+func IsRemote(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// Fetch a wordlist over HTTP(S), reading it as it downloads.  If sum is not
+// empty, the list must have that SHA-256 checksum.
+func LoadRemoteWordlist(u, sum string) ([]string, error) {
+	if cacheDir != "" {
+		words, err := readVerified(cachePath(u), sum)
+		if err == nil {
+			logging.Logf(logging.LogDebug, "Using cached wordlist for %s", u)
+			return words, nil
+		} else if !os.IsNotExist(err) {
+			logging.Logf(logging.LogWarning, "Not using cached wordlist for %s: %s", u, err.Error())
+		}
+	}
+	logging.Logf(logging.LogInfo, "Downloading wordlist %s", u)
+	resp, err := remoteClient.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unable to fetch wordlist %s: %s", u, resp.Status)
+	}
+	hasher := sha256.New()
+	body := io.TeeReader(resp.Body, hasher)
+	var cache *os.File
+	if cacheDir != "" {
+		if cache, err = tempCacheFile(); err != nil {
+			logging.Logf(logging.LogWarning, "Unable to cache wordlist %s: %s", u, err.Error())
+		} else {
+			body = io.TeeReader(body, cache)
+			defer os.Remove(cache.Name())
+		}
+	}
+	words, err := ReadWordlist(body)
+	if err == nil {
+		err = checkSum(hasher, sum)
+	}
+	if cache != nil {
+		cerr := cache.Close()
+		if err == nil && cerr == nil {
+			if rerr := os.Rename(cache.Name(), cachePath(u)); rerr != nil {
+				logging.Logf(logging.LogWarning, "Unable to cache wordlist %s: %s", u, rerr.Error())
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return words, nil
+}
+
+// Read a wordlist file, checking it has the SHA-256 checksum sum if given.
+func readVerified(path, sum string) ([]string, error) {
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	hasher := sha256.New()
+	words, err := ReadWordlist(io.TeeReader(fp, hasher))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSum(hasher, sum); err != nil {
+		return nil, err
+	}
+	return words, nil
+}
+
+func checkSum(h hash.Hash, sum string) error {
+	if sum == "" {
+		return nil
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(got, sum) {
+		return fmt.Errorf("Wordlist checksum mismatch: expected %s, got %s", sum, got)
+	}
+	return nil
+}
+
+// Where the wordlist from u is cached
+func cachePath(u string) string {
+	sum := sha256.Sum256([]byte(u))
+	return filepath.Join(cacheDir, hex.EncodeToString(sum[:])+".txt")
+}
+
+func tempCacheFile() (*os.File, error) {
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return nil, err
+	}
+	return ioutil.TempFile(cacheDir, "download")
+}
@@ -0,0 +1,64 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wordlist
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestLoadRemoteWordlist(t *testing.T) {
+	const list = "admin\nbackup\n"
+	sum := sha256.Sum256([]byte(list))
+	good := hex.EncodeToString(sum[:])
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/list.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(list))
+	}))
+	dir, err := ioutil.TempDir("", "wordlist-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	SetCacheDir(dir)
+	defer SetCacheDir("")
+
+	if _, err := LoadVerifiedWordlist(server.URL+"/list.txt", "00"+good[2:]); err == nil {
+		t.Error("Expected a checksum mismatch.")
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Errorf("Expected nothing cached after a mismatch, got %d files", len(files))
+	}
+	if _, err := LoadWordlist(server.URL + "/missing.txt"); err == nil {
+		t.Error("Expected an error for a missing wordlist.")
+	}
+	words, err := LoadVerifiedWordlist(server.URL+"/list.txt", good)
+	if err != nil || len(words) != 2 || words[0] != "admin" {
+		t.Fatalf("Unexpected wordlist: %v, %v", words, err)
+	}
+	// Later loads come from the cache
+	server.Close()
+	if words, err := LoadWordlist(server.URL + "/list.txt"); err != nil || len(words) != 2 {
+		t.Errorf("Expected the cached wordlist, got %v, %v", words, err)
+	}
+}
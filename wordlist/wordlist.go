@@ -21,14 +21,44 @@ import (
 	"errors"
 	"io"
 	"os"
+	"sort"
 	"strings"
 )
 
-// First try loading from a file, then try loading from built-ins
+// Prefix naming a built-in wordlist, e.g. builtin:common
+const BuiltinPrefix = "builtin:"
+
+// The built-in wordlists, by name
+var builtinWordlists = map[string]string{
+	"default":       DefaultWordlist,
+	"short":         ShortWordlist,
+	"common":        CommonWordlist,
+	"api-endpoints": APIWordlist,
+	"backup-files":  BackupWordlist,
+}
+
+// First try loading from a file, then try loading from built-ins.  Paths
+// starting with builtin: only load built-ins, and http:// and https:// URLs
+// are downloaded.
 func LoadWordlist(path string) ([]string, error) {
+	return LoadVerifiedWordlist(path, "")
+}
+
+// Like LoadWordlist, but a wordlist file or download must have the SHA-256
+// checksum sum, if given.  Built-in wordlists aren't checked.
+func LoadVerifiedWordlist(path, sum string) ([]string, error) {
 	if path == "" {
 		return LoadBuiltinWordlist("default")
 	}
+	if strings.HasPrefix(path, BuiltinPrefix) {
+		return LoadBuiltinWordlist(strings.TrimPrefix(path, BuiltinPrefix))
+	}
+	if IsRemote(path) {
+		return LoadRemoteWordlist(path, sum)
+	}
+	if sum != "" {
+		return readVerified(path, sum)
+	}
 	wl, wl_err := ReadWordlistFile(path)
 	if wl_err == nil {
 		return wl, nil
@@ -68,11 +98,18 @@ func ReadWordlist(rdr io.Reader) ([]string, error) {
 
 // Loads a built-in wordlist for basic scans.
 func LoadBuiltinWordlist(which string) ([]string, error) {
-	switch which {
-	case "default":
-		return ReadWordlist(strings.NewReader(DefaultWordlist))
-	case "short":
-		return ReadWordlist(strings.NewReader(ShortWordlist))
+	if list, ok := builtinWordlists[which]; ok {
+		return ReadWordlist(strings.NewReader(list))
 	}
 	return nil, errors.New("No such built-in wordlist.")
 }
+
+// Get the names of the built-in wordlists.
+func BuiltinWordlists() []string {
+	names := make([]string, 0, len(builtinWordlists))
+	for name := range builtinWordlists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,74 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wordlist
+
+// Common paths of API endpoints and their documentation.
+var APIWordlist = `
+.well-known/openid-configuration
+actuator
+actuator/env
+actuator/health
+actuator/mappings
+admin
+api
+api-docs
+api/swagger.json
+api/v1
+api/v2
+api/v3
+apis
+auth
+graphiql
+graphql
+health
+healthcheck
+healthz
+internal
+jsonrpc
+login
+metrics
+oauth
+oauth/token
+openapi.json
+openapi.yaml
+ping
+private
+public
+readiness
+readyz
+rest
+rpc
+search
+service
+services
+session
+status
+swagger
+swagger-resources
+swagger-ui
+swagger-ui.html
+swagger.json
+swagger.yaml
+token
+user
+users
+v1
+v1/users
+v2
+v2/users
+v3
+version
+ws
+`
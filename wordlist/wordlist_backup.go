@@ -0,0 +1,55 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wordlist
+
+// Common names of backups and database dumps left on servers.
+var BackupWordlist = `
+.env.bak
+.env.old
+backup
+backup.sql
+backup.tar
+backup.tar.gz
+backup.tgz
+backup.zip
+backups
+backups.zip
+config.bak
+config.old
+config.php.bak
+config.php.old
+data.sql
+database.sql
+db.sql
+db.sql.gz
+db.zip
+dump.sql
+dump.sql.gz
+htdocs.zip
+index.php.bak
+index.php.old
+site.tar.gz
+site.zip
+web.config.bak
+web.config.old
+web.zip
+website.zip
+wp-config.php.bak
+wp-config.php.old
+wp-config.php.save
+wp-config.php~
+www.tar.gz
+www.zip
+`
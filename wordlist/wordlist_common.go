@@ -0,0 +1,124 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wordlist
+
+// Common paths on all kinds of sites, for quick scans.
+var CommonWordlist = `
+.env
+.git
+.htaccess
+.svn
+.well-known
+about
+account
+admin
+administrator
+ajax
+api
+app
+apps
+archive
+assets
+auth
+backup
+backups
+bin
+blog
+cache
+cgi-bin
+config
+console
+contact
+content
+cp
+css
+dashboard
+data
+db
+debug
+default
+demo
+dev
+docs
+download
+downloads
+error
+export
+files
+fonts
+forum
+home
+images
+img
+import
+include
+includes
+index
+info
+install
+js
+lib
+library
+log
+login
+logout
+logs
+media
+old
+panel
+phpmyadmin
+portal
+private
+profile
+public
+register
+reports
+resources
+robots.txt
+scripts
+search
+secure
+server-status
+service
+services
+setup
+shop
+sitemap.xml
+src
+staging
+static
+stats
+status
+storage
+system
+temp
+test
+tests
+themes
+tmp
+tools
+upload
+uploads
+user
+users
+vendor
+web
+webadmin
+wp-admin
+wp-content
+wp-login.php
+www
+xmlrpc.php
+`
@@ -19,7 +19,7 @@ import (
 )
 
 func TestLoadBuiltinWordlist(t *testing.T) {
-	for _, wl := range []string{"default", "short"} {
+	for _, wl := range BuiltinWordlists() {
 		if list, err := LoadBuiltinWordlist(wl); err != nil {
 			t.Errorf("Error when loading builtin wordlist %s: %v", wl, err)
 		} else if list == nil {
@@ -67,3 +67,14 @@ func TestLoadWordlist_Default(t *testing.T) {
 		t.Errorf("Expected wordlist on return, got nil.")
 	}
 }
+
+func TestLoadWordlist_BuiltinPrefix(t *testing.T) {
+	if wl, err := LoadWordlist("builtin:api-endpoints"); err != nil {
+		t.Errorf("Expected no error loading wordlist, got: %v", err)
+	} else if len(wl) == 0 {
+		t.Errorf("Expected words in the api-endpoints wordlist.")
+	}
+	if _, err := LoadWordlist("builtin:testdata/testwl"); err == nil {
+		t.Errorf("Expected builtin: to only load built-in wordlists.")
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/util"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Most responses an AssetCache remembers
+const maxCachedAssets = 100000
+
+// Extensions of static assets, whose content doesn't depend on the query
+// string, such as the version numbers added to bust browser caches
+var assetExtensions = map[string]bool{
+	".js": true, ".mjs": true, ".css": true, ".map": true,
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true,
+	".ico": true, ".webp": true, ".bmp": true,
+	".woff": true, ".woff2": true, ".ttf": true, ".otf": true, ".eot": true,
+}
+
+// An AssetCache remembers the response to each static asset, so the same
+// asset linked from many pages, perhaps with different query strings, is
+// only fetched once.  It is shared by all workers.
+type AssetCache struct {
+	entries map[string]*cachedAsset
+	sync.Mutex
+}
+
+// What is kept of an asset's response
+type cachedAsset struct {
+	url         string
+	code        int
+	length      int64
+	contentType string
+	redir       *url.URL
+}
+
+func NewAssetCache() *AssetCache {
+	return &AssetCache{entries: make(map[string]*cachedAsset)}
+}
+
+func (c *AssetCache) get(key string) *cachedAsset {
+	c.Lock()
+	defer c.Unlock()
+	return c.entries[key]
+}
+
+func (c *AssetCache) add(key string, r *results.Result) {
+	c.Lock()
+	defer c.Unlock()
+	if len(c.entries) >= maxCachedAssets {
+		return
+	}
+	c.entries[key] = &cachedAsset{
+		url:         r.URL.String(),
+		code:        r.Code,
+		length:      r.Length,
+		contentType: r.ContentType,
+		redir:       r.Redir,
+	}
+}
+
+func (w *Worker) SetAssetCache(c *AssetCache) {
+	w.assets = c
+}
+
+// Get the key of t in the asset cache: the canonical form of its URL, without
+// the query string.  Returns "" if t isn't for a static asset.
+func assetKey(t *task.Task, method string) string {
+	u := t.URL
+	cleaned := path.Clean("/" + u.Path)
+	if !assetExtensions[strings.ToLower(path.Ext(cleaned))] {
+		return ""
+	}
+	scheme := strings.ToLower(u.Scheme)
+	host := u.Host
+	if t.Host != "" {
+		host = t.Host
+	}
+	host = util.CanonicalHost(scheme, host)
+	return method + " " + scheme + "://" + host + cleaned
+}
+
+// Report t from the asset cache, if the same asset has already been fetched.
+// The cached body isn't parsed again, since any links in it were added when
+// it was first fetched.
+func (w *Worker) fromAssetCache(t *task.Task, method string) (int, bool) {
+	if w.assets == nil {
+		return 0, false
+	}
+	key := assetKey(t, method)
+	if key == "" {
+		return 0, false
+	}
+	asset := w.assets.get(key)
+	if asset == nil {
+		return 0, false
+	}
+	result := results.NewResultForTask(t)
+	result.Code = asset.code
+	result.Length = asset.length
+	result.ContentType = asset.contentType
+	result.Redir = asset.redir
+	result.Confidence = results.ConfidenceStatus
+	result.AddNote("cached response from %s", asset.url)
+	w.sendResult(result)
+	return asset.code, true
+}
+
+// Remember the response for t, if it's a static asset.
+func (w *Worker) cacheAsset(t *task.Task, method string, result *results.Result) {
+	if w.assets == nil || result.Error != nil {
+		return
+	}
+	if key := assetKey(t, method); key != "" {
+		w.assets.add(key, result)
+	}
+}
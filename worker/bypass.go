@@ -0,0 +1,136 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"fmt"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/task"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Path requested with header-based bypasses, so the request line itself is
+// never one the server would allow.
+const bypassDecoyPath = "/webborer-bypass"
+
+// BypassVariant is a way of requesting a denied URL that some servers and
+// proxies route differently from the URL itself.
+type BypassVariant struct {
+	// Description for reporting
+	Name string
+	URL  *url.URL
+	// Headers to add to the request
+	Header http.Header
+}
+
+// A path variant, already escaped
+type bypassPath struct {
+	name string
+	raw  string
+}
+
+// Request a denied task again with each bypass variant, reporting those that
+// get a different response.
+func (w *Worker) TryBypassTask(t *task.Task, code int) {
+	if len(w.settings.BypassCodes) == 0 || !w.settings.BypassCodes.Contains(code) {
+		return
+	}
+	for _, v := range BypassVariants(t.URL) {
+		clone := t.Copy()
+		clone.URL = v.URL
+		for k, vals := range v.Header {
+			clone.Header[k] = vals
+		}
+		result := w.probe(clone)
+		if result == nil || !w.bypassed(code, result.Code) {
+			continue
+		}
+		logging.Logf(logging.LogInfo, "%s bypassed with %s: %d", t.String(), v.Name, result.Code)
+		result.AddNote("%d bypassed with %s", code, v.Name)
+		w.rchan <- result
+	}
+}
+
+// Whether a variant's status code is a change worth reporting.  Redirects
+// and other denials are not.
+func (w *Worker) bypassed(original, code int) bool {
+	if code == original || code == http.StatusNotFound || (code >= 300 && code < 400) {
+		return false
+	}
+	return !w.settings.BypassCodes.Contains(code) && w.report.Code(code)
+}
+
+// Build the bypass variants of a URL: encoded and extra path segments that
+// some servers normalize away after access checks, and headers that some
+// proxies and frameworks route by.
+func BypassVariants(u *url.URL) []BypassVariant {
+	var variants []BypassVariant
+	p := u.EscapedPath()
+	base := strings.TrimSuffix(p, "/")
+	suffix := p[len(base):]
+	slash := strings.LastIndex(base, "/")
+	dir, name := base[:slash+1], base[slash+1:]
+	if name != "" {
+		paths := []bypassPath{
+			{"%2e/", dir + "%2e/" + name + suffix},
+			{"/./", dir + "./" + name + suffix},
+			{"double slash", dir + "/" + name + suffix},
+			{"..;/", dir + "..;/" + name + suffix},
+			{"trailing %20", base + "%20" + suffix},
+			{"trailing /.", base + "/."},
+			{"trailing ..;/", base + "..;/"},
+		}
+		if name[0] != '%' {
+			paths = append(paths, bypassPath{"encoded first character", fmt.Sprintf("%s%%%02x%s%s", dir, name[0], name[1:], suffix)})
+		}
+		for _, v := range paths {
+			if variant := withRawPath(u, v.raw); variant != nil {
+				variants = append(variants, BypassVariant{Name: v.name, URL: variant})
+			}
+		}
+	}
+	decoy := *u
+	decoy.Path, decoy.RawPath, decoy.RawQuery = bypassDecoyPath, "", ""
+	for _, h := range []string{"X-Original-URL", "X-Rewrite-URL"} {
+		variants = append(variants, BypassVariant{
+			Name:   h + ": " + u.RequestURI(),
+			URL:    &decoy,
+			Header: http.Header{http.CanonicalHeaderKey(h): {u.RequestURI()}},
+		})
+	}
+	for _, h := range []string{"X-Forwarded-For", "X-Real-IP", "X-Custom-IP-Authorization"} {
+		same := *u
+		variants = append(variants, BypassVariant{
+			Name:   h + ": 127.0.0.1",
+			URL:    &same,
+			Header: http.Header{http.CanonicalHeaderKey(h): {"127.0.0.1"}},
+		})
+	}
+	return variants
+}
+
+// Copy u with its path set to the escaped path raw, which is kept as given.
+func withRawPath(u *url.URL, raw string) *url.URL {
+	p, err := url.PathUnescape(raw)
+	if err != nil {
+		return nil
+	}
+	variant := *u
+	variant.Path = p
+	variant.RawPath = raw
+	return &variant
+}
@@ -0,0 +1,100 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"github.com/Matir/webborer/client/mock"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/task"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// A client for a server that denies /app/admin unless asked for it by a
+// tolerant path or the X-Original-URL header.
+type deniedClient struct {
+	mock.MockClient
+}
+
+func (c *deniedClient) Request(u *url.URL, host, method string, header http.Header) (*http.Response, error) {
+	c.Requests = append(c.Requests, u)
+	resp := mock.ResponseFromString("")
+	resp.StatusCode = http.StatusNotFound
+	switch {
+	case u.EscapedPath() == "/app/admin":
+		resp.StatusCode = http.StatusForbidden
+	case u.EscapedPath() == "/app/..;/admin":
+		resp.StatusCode = http.StatusOK
+	case u.Path == bypassDecoyPath && header.Get("X-Original-URL") == "/app/admin":
+		resp.StatusCode = http.StatusOK
+	}
+	return resp, nil
+}
+
+func TestBypassVariants(t *testing.T) {
+	u := &url.URL{Scheme: "http", Host: "localhost", Path: "/app/admin/"}
+	paths := make(map[string]bool)
+	for _, v := range BypassVariants(u) {
+		paths[v.URL.EscapedPath()] = true
+	}
+	for _, p := range []string{
+		"/app/%2e/admin/",
+		"/app/./admin/",
+		"/app//admin/",
+		"/app/..;/admin/",
+		"/app/%61dmin/",
+		"/app/admin%20/",
+		"/app/admin/.",
+		"/app/admin..;/",
+		bypassDecoyPath,
+		"/app/admin/",
+	} {
+		if !paths[p] {
+			t.Errorf("Expected variant %s, got %v", p, paths)
+		}
+	}
+}
+
+func TestTryBypassTask(t *testing.T) {
+	client := &deniedClient{}
+	rchan := make(chan *results.Result, 20)
+	ss := &settings.ScanSettings{BypassCodes: settings.CodeRangeFlag{{Min: 401, Max: 403}}}
+	w := &Worker{client: client, settings: ss, rchan: rchan, adder: noopUrl}
+	u := &url.URL{Scheme: "http", Host: "localhost", Path: "/app/admin"}
+	w.TryBypassTask(task.NewTaskFromURL(u), http.StatusForbidden)
+	close(rchan)
+	var notes []string
+	for r := range rchan {
+		if r.Code != http.StatusOK {
+			t.Errorf("Unexpected result: %s", r.String())
+		}
+		notes = append(notes, strings.Join(r.Notes, "; "))
+	}
+	if len(notes) != 2 {
+		t.Fatalf("Expected 2 bypasses, got %v", notes)
+	}
+	if notes[0] != "403 bypassed with ..;/" || notes[1] != "403 bypassed with X-Original-URL: /app/admin" {
+		t.Errorf("Unexpected notes: %v", notes)
+	}
+
+	client.Requests = nil
+	w.TryBypassTask(task.NewTaskFromURL(u), http.StatusOK)
+	if len(client.Requests) != 0 {
+		t.Errorf("Expected no requests for allowed path, got %v", client.Requests)
+	}
+}
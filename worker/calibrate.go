@@ -0,0 +1,146 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/util"
+	"io"
+	"sync"
+	"time"
+)
+
+// Length of the random names requested during calibration
+const calibrationNameLength = 16
+
+// A Calibrator tracks which directories have been calibrated.  It is shared
+// by all workers so that each directory is only calibrated once per refresh
+// interval.
+type Calibrator struct {
+	samples int
+	refresh time.Duration
+	last    map[string]time.Time
+	sync.Mutex
+}
+
+func NewCalibrator(samples int, refresh time.Duration) *Calibrator {
+	return &Calibrator{
+		samples: samples,
+		refresh: refresh,
+		last:    make(map[string]time.Time),
+	}
+}
+
+// Check if the directory with the given key needs calibration, and if so,
+// mark it as calibrated now.
+func (c *Calibrator) needsCalibration(key string) bool {
+	c.Lock()
+	defer c.Unlock()
+	now := time.Now()
+	if last, ok := c.last[key]; ok {
+		if c.refresh == 0 || now.Sub(last) < c.refresh {
+			return false
+		}
+	}
+	c.last[key] = now
+	return true
+}
+
+func (w *Worker) SetCalibrator(c *Calibrator) {
+	w.calibrator = c
+}
+
+// Request random names in the directory containing t so the results manager
+// can learn what "not found" looks like there.
+func (w *Worker) calibrate(t *task.Task) {
+	if w.calibrator == nil {
+		return
+	}
+	dir := results.BaselineDir(t.URL)
+	if !w.calibrator.needsCalibration(results.BaselineKey(t.URL)) {
+		return
+	}
+	logging.Logf(logging.LogDebug, "Calibrating %s", dir.String())
+	var samples []*results.Result
+	for i := 0; i < w.calibrator.samples; i++ {
+		probe := t.Copy()
+		u := *dir
+		u.Path += util.RandomString(calibrationNameLength)
+		u.RawPath = ""
+		probe.URL = &u
+		if result := w.probe(probe); result != nil {
+			result.Baseline = true
+			samples = append(samples, result)
+			w.rchan <- result
+		}
+	}
+	if w.settings.WildcardExit && w.hosts != nil && isFullWildcard(samples) {
+		logging.Logf(logging.LogWarning, "Every path in %s gives the same page, skipping the rest of it.", dir.String())
+		w.hosts.Get(dir).SetWildcard(dir.Path)
+	}
+}
+
+// Whether calibration samples show a directory gives the same successful
+// response for every path, leaving nothing to tell real paths apart by.
+func isFullWildcard(samples []*results.Result) bool {
+	if len(samples) < 2 {
+		return false
+	}
+	first := samples[0]
+	if first.Code < 200 || first.Code >= 300 || first.BodyHash == "" {
+		return false
+	}
+	for _, r := range samples[1:] {
+		if r.Code != first.Code || r.BodyHash != first.BodyHash {
+			return false
+		}
+	}
+	return true
+}
+
+// Skip a task in a directory found to be a full wildcard.
+func (w *Worker) skipWildcard(t *task.Task) bool {
+	if w.hosts == nil || !w.hosts.Get(t.URL).SkipWildcard(t.URL.Path) {
+		return false
+	}
+	logging.Logf(logging.LogDebug, "Skipping %s in wildcard directory.", t.String())
+	return true
+}
+
+// Request a task without spidering or running page workers.  Returns nil on
+// error.
+func (w *Worker) probe(t *task.Task) *results.Result {
+	w.redir = nil
+	w.redirChain = nil
+	w.redirLoop = false
+	defer w.Sleep()
+	method, header := w.requestOptions(t)
+	resp, err := w.client.Request(t.URL, t.Host, method, header)
+	if err != nil && w.redir == nil {
+		logging.Logf(logging.LogInfo, "Error probing %s: %s", t.String(), err.Error())
+		return nil
+	}
+	defer util.DrainBody(resp.Body)
+	result := w.ResultForResponse(t, resp)
+	result.RequestHeader = header
+	// Hashed so calibration can tell when every path gives the same page
+	hasher := util.NewBodyHasher()
+	if _, err := io.Copy(hasher, io.LimitReader(resp.Body, maxHashSize)); err == nil {
+		result.BodyHash = hasher.SHA256()
+	}
+	return result
+}
@@ -0,0 +1,71 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"github.com/Matir/webborer/hosts"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/task"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// Set the registry of host state shared by the workers.
+func (w *Worker) SetHosts(r *hosts.Registry) {
+	w.hosts = r
+}
+
+// Find out whether the host of a found page is case sensitive, by requesting
+// the page again with the case of its path swapped.  Only the first page
+// found on each host is checked.
+func (w *Worker) checkCase(t *task.Task, found *results.Result) {
+	if w.hosts == nil || !w.settings.DetectCase || found.Code != http.StatusOK || found.Redir != nil {
+		return
+	}
+	swapped := swapCase(t.URL.Path)
+	if swapped == t.URL.Path {
+		return
+	}
+	host := w.hosts.Get(t.URL)
+	if !host.StartCaseProbe() {
+		return
+	}
+	probe := t.Copy()
+	probe.URL.Path = swapped
+	probe.URL.RawPath = ""
+	result := w.probe(probe)
+	switch {
+	case result == nil:
+		host.SetCaseSensitivity(hosts.CaseUnknown)
+	case result.Code == found.Code && result.Length == found.Length:
+		logging.Logf(logging.LogInfo, "%s is case insensitive, ignoring case of paths.", hosts.Key(t.URL))
+		host.SetCaseSensitivity(hosts.CaseInsensitive)
+	default:
+		logging.Logf(logging.LogDebug, "%s is case sensitive.", hosts.Key(t.URL))
+		host.SetCaseSensitivity(hosts.CaseSensitive)
+	}
+}
+
+// Swap the case of every letter in s.
+func swapCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, s)
+}
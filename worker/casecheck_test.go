@@ -0,0 +1,81 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"github.com/Matir/webborer/client/mock"
+	"github.com/Matir/webborer/hosts"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/task"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// A client for a server with one page, /Admin
+type casedClient struct {
+	mock.MockClient
+	insensitive bool
+}
+
+func (c *casedClient) Request(u *url.URL, host, method string, header http.Header) (*http.Response, error) {
+	c.Requests = append(c.Requests, u)
+	resp := mock.ResponseFromString("admin")
+	resp.StatusCode = http.StatusNotFound
+	if u.Path == "/Admin" || (c.insensitive && u.Path == "/aDMIN") {
+		resp.StatusCode = http.StatusOK
+		resp.ContentLength = 5
+	}
+	return resp, nil
+}
+
+func TestCheckCase(t *testing.T) {
+	for _, insensitive := range []bool{true, false} {
+		client := &casedClient{insensitive: insensitive}
+		rchan := make(chan *results.Result, 4)
+		reg := hosts.NewRegistry()
+		w := &Worker{
+			client:   client,
+			settings: &settings.ScanSettings{DetectCase: true},
+			rchan:    rchan,
+			adder:    noopUrl,
+		}
+		w.SetHosts(reg)
+		u := &url.URL{Scheme: "http", Host: "localhost", Path: "/Admin"}
+		w.TryTask(task.NewTaskFromURL(u))
+		expected := hosts.CaseSensitive
+		if insensitive {
+			expected = hosts.CaseInsensitive
+		}
+		if got := reg.Get(u).CaseSensitivity(); got != expected {
+			t.Errorf("insensitive=%v: expected %v, got %v", insensitive, expected, got)
+		}
+		if len(client.Requests) != 2 || client.Requests[1].Path != "/aDMIN" {
+			t.Errorf("Unexpected requests: %v", client.Requests)
+		}
+		// Only checked once per host
+		w.TryTask(task.NewTaskFromURL(u))
+		if len(client.Requests) != 3 {
+			t.Errorf("Expected no more probes, got %v", client.Requests)
+		}
+	}
+}
+
+func TestSwapCase(t *testing.T) {
+	if got := swapCase("/Admin/index.PHP"); got != "/aDMIN/INDEX.php" {
+		t.Errorf("Unexpected swapped case: %s", got)
+	}
+}
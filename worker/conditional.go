@@ -0,0 +1,77 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/util"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Use the results of a previous scan for conditional requests.
+func (w *Worker) SetPrevious(c *results.Comparison) {
+	w.previous = c
+}
+
+// Add If-None-Match and If-Modified-Since to header from the previous result
+// for t, returning the new header and that result.  Directories and HTML
+// pages are always fetched in full, since their links are needed to find the
+// rest of the site.
+func (w *Worker) conditionalHeader(t *task.Task, header http.Header) (http.Header, *results.JSONResult) {
+	if w.previous == nil || util.URLIsDir(t.URL) {
+		return header, nil
+	}
+	prev := w.previous.Previous(t.URL, t.Host)
+	if prev == nil || (prev.ETag == "" && prev.LastModified == "") {
+		return header, nil
+	}
+	if strings.HasPrefix(strings.ToLower(prev.ContentType), "text/html") {
+		return header, nil
+	}
+	// The header may be shared with other tasks
+	conditional := make(http.Header, len(header)+2)
+	for k, v := range header {
+		conditional[k] = v
+	}
+	if prev.ETag != "" {
+		conditional.Set("If-None-Match", prev.ETag)
+	}
+	if prev.LastModified != "" {
+		conditional.Set("If-Modified-Since", prev.LastModified)
+	}
+	return conditional, prev
+}
+
+// Fill in a 304 Not Modified result from the previous result, so it is the
+// same as before.
+func notModified(result *results.Result, prev *results.JSONResult) {
+	result.Code = prev.Code
+	result.Length = prev.Length
+	result.ContentType = prev.ContentType
+	if prev.Redirect != "" {
+		result.Redir, _ = url.Parse(prev.Redirect)
+	}
+	if result.ETag == "" {
+		result.ETag = prev.ETag
+	}
+	if result.LastModified == "" {
+		result.LastModified = prev.LastModified
+	}
+	result.NotModified = true
+	result.AddNote("not modified")
+}
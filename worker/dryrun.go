@@ -0,0 +1,88 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"fmt"
+	"github.com/Matir/webborer/hosts"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/util"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// A DryRunPlan lists the requests a scan would send, instead of sending
+// them, to check the scope and size of a scan before it touches the target.
+// It is shared by all workers.
+type DryRunPlan struct {
+	w     io.Writer
+	count int64
+	hosts map[string]bool
+	sync.Mutex
+}
+
+// Create a DryRunPlan listing the requests to w, one per line.
+func NewDryRunPlan(w io.Writer) *DryRunPlan {
+	return &DryRunPlan{w: w, hosts: make(map[string]bool)}
+}
+
+func (p *DryRunPlan) add(method string, t *task.Task) {
+	p.Lock()
+	defer p.Unlock()
+	p.count++
+	p.hosts[hosts.Key(t.URL)] = true
+	if _, err := fmt.Fprintf(p.w, "%s %s\n", method, t.String()); err != nil {
+		logging.Logf(logging.LogWarning, "Unable to write planned request: %s", err.Error())
+	}
+}
+
+// Get the number of requests planned, and the number of hosts they are for.
+func (p *DryRunPlan) Count() (int64, int) {
+	p.Lock()
+	defer p.Unlock()
+	return p.count, len(p.hosts)
+}
+
+// List tasks in plan instead of requesting them.
+func (w *Worker) SetDryRun(plan *DryRunPlan) {
+	w.plan = plan
+}
+
+// Add the requests for t to the plan: the task itself and, for files, its
+// mangled names.  Whether a mangled name is tried depends on the response in
+// a real scan, so they are all listed.
+func (w *Worker) planTask(t *task.Task) {
+	if t.External {
+		w.plan.add(http.MethodHead, t)
+		return
+	}
+	method, _ := w.requestOptions(t)
+	w.plan.add(method, t)
+	if !w.settings.Mangle || util.URLIsDir(t.URL) {
+		return
+	}
+	spos := strings.LastIndex(t.URL.Path, "/")
+	if spos == -1 {
+		return
+	}
+	for _, newname := range Mangle(t.URL.Path[spos+1:]) {
+		clone := t.Copy()
+		clone.URL.Path = t.URL.Path[:spos] + "/" + newname
+		w.plan.add(method, clone)
+	}
+}
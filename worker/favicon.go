@@ -0,0 +1,72 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"fmt"
+	"github.com/Matir/webborer/analysis"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/util"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// Largest favicon worth hashing
+const maxFaviconSize = 1024 * 1024
+
+// Fetch /favicon.ico from the host of a task, once per host, and report its
+// hash along with the product it identifies.
+func (w *Worker) checkFavicon(t *task.Task) {
+	if w.hosts == nil || !w.settings.FaviconHash {
+		return
+	}
+	if !w.hosts.Get(t.URL).ClaimFavicon() {
+		return
+	}
+	icon := t.Copy()
+	icon.URL.Path = "/favicon.ico"
+	icon.URL.RawPath = ""
+	icon.URL.RawQuery = ""
+	w.redir = nil
+	w.redirChain = nil
+	w.redirLoop = false
+	defer w.Sleep()
+	method, header := w.requestOptions(icon)
+	resp, err := w.client.Request(icon.URL, icon.Host, method, header)
+	if err != nil && w.redir == nil {
+		logging.Logf(logging.LogInfo, "Error fetching %s: %s", icon.String(), err.Error())
+		return
+	}
+	defer util.DrainBody(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxFaviconSize))
+	if err != nil || len(data) == 0 {
+		return
+	}
+	result := w.ResultForResponse(icon, resp)
+	result.RequestHeader = header
+	result.FaviconHash = util.FaviconHash(data)
+	if product := analysis.LookupFavicon(result.FaviconHash); product != "" {
+		f := results.NewFinding(results.CategoryFingerprint, "favicon", results.SeverityInfo, "Favicon identifies %s", product)
+		f.Evidence = fmt.Sprintf("favicon hash %d", result.FaviconHash)
+		result.Report(f)
+	}
+	w.sendResult(result)
+}
@@ -0,0 +1,62 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"github.com/Matir/webborer/analysis"
+	"github.com/Matir/webborer/client/mock"
+	"github.com/Matir/webborer/hosts"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/util"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestCheckFavicon(t *testing.T) {
+	icon := "\x00\x00\x01\x00fake icon"
+	hash := util.FaviconHash([]byte(icon))
+	analysis.FaviconProducts[hash] = "Test Product"
+	defer delete(analysis.FaviconProducts, hash)
+
+	resp := mock.ResponseFromString(icon)
+	resp.StatusCode = http.StatusOK
+	client := &mock.MockClient{NextResponse: resp}
+	rchan := make(chan *results.Result, 2)
+	w := &Worker{
+		client:   client,
+		settings: &settings.ScanSettings{FaviconHash: true},
+		rchan:    rchan,
+		hosts:    hosts.NewRegistry(),
+	}
+	tsk := task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/app/page", RawQuery: "a=b"})
+	w.checkFavicon(tsk)
+	w.checkFavicon(tsk)
+	if len(client.Requests) != 1 {
+		t.Fatalf("Expected one favicon request per host, got %d", len(client.Requests))
+	}
+	if path := client.Requests[0].Path; path != "/favicon.ico" {
+		t.Errorf("Expected /favicon.ico to be requested, got %s", path)
+	}
+	r := <-rchan
+	if r.FaviconHash != hash {
+		t.Errorf("Expected hash %d, got %d", hash, r.FaviconHash)
+	}
+	if len(r.Findings) != 1 || r.Findings[0].Message != "Favicon identifies Test Product" {
+		t.Errorf("Unexpected findings: %v", r.Findings)
+	}
+}
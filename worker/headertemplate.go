@@ -0,0 +1,103 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/util"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Length of the values for {{random}}
+const headerRandomLength = 16
+
+// Placeholders allowed in header values, such as {{uuid}}, and how to fill
+// them in for a task.  They are filled in again for every request.
+var headerPlaceholders = map[string]func(*task.Task) string{
+	"uuid": func(*task.Task) string {
+		return util.NewUUID()
+	},
+	"random": func(*task.Task) string {
+		return util.RandomString(headerRandomLength)
+	},
+	"timestamp": func(*task.Task) string {
+		return strconv.FormatInt(time.Now().Unix(), 10)
+	},
+	"url": func(t *task.Task) string {
+		return t.URL.String()
+	},
+	"path": func(t *task.Task) string {
+		return t.URL.EscapedPath()
+	},
+	"host": func(t *task.Task) string {
+		if t.Host != "" {
+			return t.Host
+		}
+		return t.URL.Host
+	},
+}
+
+// Fill in the placeholders in header values for a request for t.  The header
+// is only copied if it contains any.
+func renderHeader(header http.Header, t *task.Task) http.Header {
+	var rendered http.Header
+	for k, vals := range header {
+		for i, v := range vals {
+			if !strings.Contains(v, "{{") {
+				continue
+			}
+			if rendered == nil {
+				rendered = make(http.Header, len(header))
+				for k, vals := range header {
+					rendered[k] = append([]string(nil), vals...)
+				}
+			}
+			rendered[k][i] = renderTemplate(v, t)
+		}
+	}
+	if rendered == nil {
+		return header
+	}
+	return rendered
+}
+
+// Fill in the placeholders in a single value.  Unknown placeholders are left
+// as they are.
+func renderTemplate(v string, t *task.Task) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(v, "{{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(v[start:], "}}")
+		if end < 0 {
+			break
+		}
+		end += start + 2
+		b.WriteString(v[:start])
+		if fill, ok := headerPlaceholders[strings.TrimSpace(v[start+2:end-2])]; ok {
+			b.WriteString(fill(t))
+		} else {
+			b.WriteString(v[start:end])
+		}
+		v = v[end:]
+	}
+	b.WriteString(v)
+	return b.String()
+}
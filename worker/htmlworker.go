@@ -17,6 +17,7 @@ package worker
 import (
 	"github.com/Matir/webborer/logging"
 	"github.com/Matir/webborer/results"
+	ss "github.com/Matir/webborer/settings"
 	"github.com/Matir/webborer/task"
 	"github.com/Matir/webborer/util"
 	"github.com/Matir/webborer/workqueue"
@@ -28,45 +29,126 @@ import (
 )
 
 const (
-	maxHTMLWorkerSize = 10 * 1024 * 1024
+	defaultMaxHTMLWorkerSize = 10 * 1024 * 1024
 )
 
+// Attribute containing the link for each tag of interest
+var linkAttributes = map[string]string{
+	"a":      "href",
+	"img":    "src",
+	"script": "src",
+	"style":  "src",
+}
+
 type HTMLWorker struct {
-	// Function to add future work
-	adder workqueue.QueueAddFunc
+	// Function to add links found on pages
+	spider workqueue.SpiderFunc
+	// Maximum number of bytes of a page to examine
+	maxSize int64
+	// How to follow links with query strings
+	queries *QueryPolicy
+	// How to handle links marked nofollow
+	nofollow ss.NofollowModeOption
+}
+
+// A link found on a page
+type pageLink struct {
+	url string
+	// Marked rel="nofollow"
+	nofollow bool
 }
 
-func NewHTMLWorker(adder workqueue.QueueAddFunc) *HTMLWorker {
-	return &HTMLWorker{adder: adder}
+func NewHTMLWorker(spider workqueue.SpiderFunc) *HTMLWorker {
+	return &HTMLWorker{spider: spider, maxSize: defaultMaxHTMLWorkerSize}
+}
+
+// Set the function that links found on pages are added with.
+func (w *HTMLWorker) SetSpider(spider workqueue.SpiderFunc) {
+	w.spider = spider
+}
+
+// Set the maximum number of bytes of each page to examine for links.  Larger
+// pages are truncated.
+func (w *HTMLWorker) SetMaxSize(size int64) {
+	if size > 0 {
+		w.maxSize = size
+	}
+}
+
+// Set how links with query strings are followed.  By default, every link is
+// followed.
+func (w *HTMLWorker) SetQueryPolicy(policy *QueryPolicy) {
+	w.queries = policy
+}
+
+// Set how links marked nofollow are handled.  By default, they are followed
+// like any other.
+func (w *HTMLWorker) SetNofollowMode(mode ss.NofollowModeOption) {
+	w.nofollow = mode
 }
 
 // Work on this response
 func (w *HTMLWorker) Handle(t *task.Task, body io.Reader, result *results.Result) {
-	limitedBody := io.LimitReader(body, maxHTMLWorkerSize)
-	links := w.GetLinks(limitedBody)
+	limitedBody := io.LimitReader(body, w.maxSize)
+	links, robots := parsePage(limitedBody)
 	logging.Logf(logging.LogInfo, "Found %d links for %s", len(links), t.URL.String())
-	foundURLs := make([]*url.URL, 0, len(links))
+	pageNofollow := false
+	if w.nofollow != ss.NofollowIgnore {
+		robots = append(robots, result.ResponseHeader["X-Robots-Tag"]...)
+		pageNofollow = hasRobotsDirective(robots, "nofollow")
+		if hasRobotsDirective(robots, "noindex") {
+			result.AddNote("page asks not to be indexed")
+		}
+	}
+	newTasks := make([]*task.Task, 0, len(links))
 	for _, l := range links {
-		u, err := url.Parse(l)
+		u, err := url.Parse(l.url)
 		if err != nil {
-			logging.Logf(logging.LogInfo, "Error parsing URL (%s): %s", l, err.Error())
+			logging.Logf(logging.LogInfo, "Error parsing URL (%s): %s", l.url, err.Error())
 			continue
 		}
 		// TODO: use <base> tag
 		resolved := t.URL.ResolveReference(u)
 		result.AddLink(resolved, results.LinkUnknown)
-		foundURLs = append(foundURLs, resolved)
+		if w.nofollow != ss.NofollowIgnore && (l.nofollow || pageNofollow) {
+			if w.nofollow == ss.NofollowObey {
+				logging.Logf(logging.LogDebug, "Not following nofollow link %s on %s", resolved.String(), t.URL.String())
+				continue
+			}
+			logging.Logf(logging.LogInfo, "Following nofollow link %s on %s", resolved.String(), t.URL.String())
+		}
+		if follow := w.follow(resolved); follow != nil {
+			lt := linkTask(t, follow, t.URL)
+			lt.Provenance = task.NewProvenance(task.OriginSpider, t.URL, "")
+			newTasks = append(newTasks, lt)
+		}
 		// Include parents of the found URL.
 		// Worker will remove duplicates
-		foundURLs = append(foundURLs, util.GetParentPaths(resolved)...)
+		for _, parent := range util.GetParentPaths(resolved) {
+			pt := linkTask(t, parent, nil)
+			pt.Provenance = task.NewProvenance(task.OriginParent, resolved, "")
+			newTasks = append(newTasks, pt)
+		}
 	}
-	newTasks := make([]*task.Task, 0, len(foundURLs))
-	for _, u := range foundURLs {
-		t := t.Copy()
-		t.URL = u
-		newTasks = append(newTasks, t)
+	w.spider(newTasks...)
+}
+
+// Make a task for u, found on the page of t.  Only links themselves have a
+// referrer, not the directories containing them.
+func linkTask(t *task.Task, u *url.URL, referrer *url.URL) *task.Task {
+	nt := t.Copy()
+	nt.URL = u
+	nt.Referrer = referrer
+	nt.SetParent(t)
+	return nt
+}
+
+// Get the URL to follow for a link, or nil if it should not be followed.
+func (w *HTMLWorker) follow(u *url.URL) *url.URL {
+	if w.queries == nil {
+		return u
 	}
-	w.adder(newTasks...)
+	return w.queries.Apply(u)
 }
 
 // Check if this response can be handled by this worker
@@ -76,54 +158,106 @@ func (*HTMLWorker) Eligible(resp *http.Response) bool {
 	if strings.ToLower(ct) != "text/html" {
 		return false
 	}
-	// ContentLength is often -1, indicating unknown, so we'll try to parse those
-	return resp.ContentLength == -1 || (resp.ContentLength > 0 && resp.ContentLength < maxHTMLWorkerSize)
+	// ContentLength is often -1, indicating unknown, so we'll try to parse those.
+	// Pages larger than the limit are truncated.
+	return resp.ContentLength != 0
 }
 
-// Get the links for the body.
+// Get the links for the body.  The body is tokenized as it is read, so links
+// can be found in documents too large to parse fully.
 func (*HTMLWorker) GetLinks(body io.Reader) []string {
-	tree, err := html.Parse(body)
-	if err != nil {
-		logging.Logf(logging.LogInfo, "Unable to parse HTML document: %s", err.Error())
-		return nil
+	links, _ := parsePage(body)
+	urls := make([]string, 0, len(links))
+	for _, l := range links {
+		urls = append(urls, l.url)
 	}
-	links := collectElementAttributes(tree, "a", "href")
-	links = append(links, collectElementAttributes(tree, "img", "src")...)
-	links = append(links, collectElementAttributes(tree, "script", "src")...)
-	links = append(links, collectElementAttributes(tree, "style", "src")...)
-	return util.DedupeStrings(links)
-}
-
-func getElementsByTagName(root *html.Node, name string) []*html.Node {
-	results := make([]*html.Node, 0)
-	var handleNode func(*html.Node)
-	handleNode = func(node *html.Node) {
-		if node.Type == html.ElementNode && strings.ToLower(node.Data) == name {
-			results = append(results, node)
-		}
-		for n := node.FirstChild; n != nil; n = n.NextSibling {
-			handleNode(n)
+	return urls
+}
+
+// Get the distinct links in the body, and the content of its robots meta
+// tags.  A link is only nofollow if every copy of it is.
+func parsePage(body io.Reader) ([]pageLink, []string) {
+	links := make([]pageLink, 0)
+	seen := make(map[string]int)
+	var robots []string
+	tokenizer := html.NewTokenizer(body)
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if err := tokenizer.Err(); err != io.EOF {
+				logging.Logf(logging.LogInfo, "Error tokenizing HTML document: %s", err.Error())
+			}
+			return links, robots
+		case html.StartTagToken, html.SelfClosingTagToken:
+			link, content := getTagAttributes(tokenizer)
+			if content != "" {
+				robots = append(robots, content)
+			}
+			if link.url == "" {
+				continue
+			}
+			if i, ok := seen[link.url]; ok {
+				links[i].nofollow = links[i].nofollow && link.nofollow
+				continue
+			}
+			seen[link.url] = len(links)
+			links = append(links, link)
 		}
 	}
-	handleNode(root)
-	return results
 }
 
-func getElementAttribute(node *html.Node, attrName string) *string {
-	for _, a := range node.Attr {
-		if strings.ToLower(a.Key) == attrName {
-			return &a.Val
+// Get the link for the current tag, if it has one, or the content of a robots
+// meta tag.
+func getTagAttributes(tokenizer *html.Tokenizer) (pageLink, string) {
+	var link pageLink
+	name, hasAttr := tokenizer.TagName()
+	if !hasAttr {
+		return link, ""
+	}
+	tag := strings.ToLower(string(name))
+	attrName, ok := linkAttributes[tag]
+	if !ok && tag != "meta" {
+		return link, ""
+	}
+	var metaName, metaContent string
+	for {
+		key, val, more := tokenizer.TagAttr()
+		switch strings.ToLower(string(key)) {
+		case attrName:
+			if link.url == "" {
+				link.url = string(val)
+			}
+		case "rel":
+			link.nofollow = util.StringSliceContains(strings.Fields(strings.ToLower(string(val))), "nofollow")
+		case "name":
+			metaName = string(val)
+		case "content":
+			metaContent = string(val)
+		}
+		if !more {
+			break
 		}
 	}
-	return nil
+	if tag == "meta" && strings.ToLower(metaName) == "robots" {
+		return link, metaContent
+	}
+	return link, ""
 }
 
-func collectElementAttributes(root *html.Node, tagName, attrName string) []string {
-	results := make([]string, 0)
-	for _, el := range getElementsByTagName(root, tagName) {
-		if val := getElementAttribute(el, attrName); val != nil {
-			results = append(results, *val)
+// Check for a directive in comma-separated robots meta tags or X-Robots-Tag
+// headers.  Directives for particular robots, as "name: directive", count
+// too, and "none" implies both "noindex" and "nofollow".
+func hasRobotsDirective(values []string, directive string) bool {
+	for _, v := range values {
+		for _, d := range strings.Split(v, ",") {
+			if i := strings.Index(d, ":"); i >= 0 {
+				d = d[i+1:]
+			}
+			d = strings.ToLower(strings.TrimSpace(d))
+			if d == directive || d == "none" {
+				return true
+			}
 		}
 	}
-	return results
+	return false
 }
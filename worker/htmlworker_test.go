@@ -16,6 +16,7 @@ package worker
 
 import (
 	"github.com/Matir/webborer/results"
+	ss "github.com/Matir/webborer/settings"
 	"github.com/Matir/webborer/task"
 	"net/http"
 	"net/url"
@@ -110,3 +111,110 @@ func TestEligible(t *testing.T) {
 		t.Error("Expected results to be eligible.")
 	}
 }
+
+func TestGetLinks_Truncated(t *testing.T) {
+	resultlist := make([]*task.Task, 0)
+	adder := func(f ...*task.Task) {
+		resultlist = append(resultlist, f...)
+	}
+	htmlWorker := NewHTMLWorker(adder)
+	doc := "<html><body><a href='/first'>x</a>" + strings.Repeat("<p>filler</p>", 100) + "<a href='/second'>y</a></body></html>"
+	htmlWorker.SetMaxSize(int64(strings.Index(doc, "<p>")))
+	base, _ := url.Parse("http://www.example.com/")
+	madeTask := task.NewTaskFromURL(base)
+	htmlWorker.Handle(madeTask, strings.NewReader(doc), results.NewResultForTask(madeTask))
+	if len(resultlist) != 1 || resultlist[0].URL.Path != "/first" {
+		t.Fatalf("Expected only links from the first bytes, got %v", resultlist)
+	}
+}
+
+func TestGetLinks_Attributes(t *testing.T) {
+	doc := `<a name="x" HREF="/a"><img alt="" src="/b.png"/><script src="/c.js"></script><link href="/d.css"><a href="/a">`
+	links := NewHTMLWorker(nil).GetLinks(strings.NewReader(doc))
+	expected := []string{"/a", "/b.png", "/c.js"}
+	if len(links) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, links)
+	}
+	for i := range expected {
+		if links[i] != expected[i] {
+			t.Errorf("Expected %s, got %s", expected[i], links[i])
+		}
+	}
+}
+
+func TestHandle_Referrer(t *testing.T) {
+	var tasks []*task.Task
+	w := NewHTMLWorker(func(f ...*task.Task) {
+		tasks = append(tasks, f...)
+	})
+	base, _ := url.Parse("http://www.example.com/subdir/")
+	madeTask := task.NewTaskFromURL(base)
+	w.Handle(madeTask, strings.NewReader(`<a href="/a/b">x</a>`), results.NewResultForTask(madeTask))
+	if len(tasks) != 2 {
+		t.Fatalf("Expected the link and its directory, got %v", tasks)
+	}
+	if tasks[0].Referrer != base {
+		t.Errorf("Expected the link to be referred by %s, got %v", base, tasks[0].Referrer)
+	}
+	if tasks[1].Referrer != nil {
+		t.Errorf("Expected no referrer for the directory, got %v", tasks[1].Referrer)
+	}
+	if got := tasks[0].Provenance.String(); got != "spider from http://www.example.com/subdir/" {
+		t.Errorf("Unexpected provenance for the link: %s", got)
+	}
+	if got := tasks[1].Provenance.String(); got != "parent from http://www.example.com/a/b" {
+		t.Errorf("Unexpected provenance for the directory: %s", got)
+	}
+}
+
+func TestHandle_Nofollow(t *testing.T) {
+	doc := `<a href="/a" rel="external nofollow">a</a><a href="/b">b</a><a href="/c" rel="nofollow"><a href="/c">`
+	cases := []struct {
+		mode   ss.NofollowModeOption
+		header string
+		meta   string
+		paths  []string
+	}{
+		{ss.NofollowIgnore, "", "", []string{"/a", "/b", "/c"}},
+		{ss.NofollowLog, "", "", []string{"/a", "/b", "/c"}},
+		{ss.NofollowObey, "", "", []string{"/b", "/c"}},
+		{ss.NofollowObey, "googlebot: noindex, nofollow", "", nil},
+		{ss.NofollowObey, "", `<meta name="Robots" content="none">`, nil},
+		{ss.NofollowIgnore, "nofollow", "", []string{"/a", "/b", "/c"}},
+	}
+	for i, c := range cases {
+		var paths []string
+		w := NewHTMLWorker(func(tasks ...*task.Task) {
+			for _, t := range tasks {
+				paths = append(paths, t.URL.Path)
+			}
+		})
+		w.SetNofollowMode(c.mode)
+		base, _ := url.Parse("http://www.example.com/")
+		madeTask := task.NewTaskFromURL(base)
+		result := results.NewResultForTask(madeTask)
+		result.ResponseHeader = make(http.Header)
+		if c.header != "" {
+			result.ResponseHeader.Set("X-Robots-Tag", c.header)
+		}
+		w.Handle(madeTask, strings.NewReader(c.meta+doc), result)
+		if strings.Join(paths, ",") != strings.Join(c.paths, ",") {
+			t.Errorf("Case %d: expected %v, got %v", i, c.paths, paths)
+		}
+		if len(result.Links) != 3 {
+			t.Errorf("Case %d: expected every link to be recorded, got %v", i, result.Links)
+		}
+	}
+}
+
+func TestHasRobotsDirective(t *testing.T) {
+	if !hasRobotsDirective([]string{"index", "NOINDEX, follow"}, "noindex") {
+		t.Error("Expected noindex.")
+	}
+	if hasRobotsDirective([]string{"noindex"}, "nofollow") {
+		t.Error("Expected no nofollow.")
+	}
+	if !hasRobotsDirective([]string{"none"}, "nofollow") {
+		t.Error("Expected none to imply nofollow.")
+	}
+}
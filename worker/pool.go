@@ -0,0 +1,94 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"github.com/Matir/webborer/client"
+	"github.com/Matir/webborer/logging"
+	"sync"
+)
+
+// Pool runs a set of workers reading from the same source, and can grow or
+// shrink while they run.  Workers are removed only between tasks, so no task
+// is dropped.
+type Pool struct {
+	workers []*Worker
+	factory client.ClientFactory
+	// Workers removed from the pool that may still be finishing a task
+	draining sync.WaitGroup
+	sync.Mutex
+}
+
+// Build a pool from workers, such as those from NewWorkers.  Workers added
+// later copy the first one and get clients from factory.
+func NewPool(workers []*Worker, factory client.ClientFactory) *Pool {
+	return &Pool{workers: workers, factory: factory}
+}
+
+func (p *Pool) Start() {
+	p.Lock()
+	defer p.Unlock()
+	for _, w := range p.workers {
+		w.RunInBackground()
+	}
+}
+
+func (p *Pool) Size() int {
+	p.Lock()
+	defer p.Unlock()
+	return len(p.workers)
+}
+
+// Change the number of workers to n, but at least 1, and return the new
+// number.  Removed workers finish their current task in the background.  An
+// empty pool cannot grow, as there is no worker to copy.
+func (p *Pool) Resize(n int) int {
+	p.Lock()
+	defer p.Unlock()
+	if len(p.workers) == 0 {
+		return 0
+	}
+	if n < 1 {
+		n = 1
+	}
+	for len(p.workers) < n {
+		w := p.workers[0].Clone(p.factory)
+		p.workers = append(p.workers, w)
+		w.RunInBackground()
+	}
+	for len(p.workers) > n {
+		w := p.workers[len(p.workers)-1]
+		p.workers = p.workers[:len(p.workers)-1]
+		w.Stop()
+		p.draining.Add(1)
+		go func() {
+			w.Wait()
+			p.draining.Done()
+		}()
+	}
+	logging.Logf(logging.LogInfo, "Worker pool resized to %d.", n)
+	return n
+}
+
+// Stop every worker and wait for them, including those being drained.
+func (p *Pool) Stop() {
+	p.Lock()
+	defer p.Unlock()
+	for _, w := range p.workers {
+		w.Stop()
+		w.Wait()
+	}
+	p.draining.Wait()
+}
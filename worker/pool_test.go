@@ -0,0 +1,59 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"github.com/Matir/webborer/client/mock"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/task"
+	"net/url"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPool_Resize(t *testing.T) {
+	var done int64
+	doneFunc := func(n int) { atomic.AddInt64(&done, int64(n)) }
+	schan := make(chan *task.Task)
+	rchan := make(chan *results.Result, 100)
+	factory := &mock.MockClientFactory{}
+	workers := NewWorkers(&settings.ScanSettings{Workers: 1}, factory, schan, noopUrl, doneFunc, rchan)
+	pool := NewPool(workers, factory)
+	pool.Start()
+	u, _ := url.Parse("http://www.example.com/")
+
+	if n := pool.Resize(3); n != 3 || pool.Size() != 3 {
+		t.Errorf("Expected 3 workers, got %d and size %d", n, pool.Size())
+	}
+	for i := 0; i < 10; i++ {
+		schan <- task.NewTaskFromURL(u)
+	}
+	if n := pool.Resize(0); n != 1 || pool.Size() != 1 {
+		t.Errorf("Expected 1 worker, got %d and size %d", n, pool.Size())
+	}
+	for i := 0; i < 5; i++ {
+		schan <- task.NewTaskFromURL(u)
+	}
+	pool.Stop()
+	if got := atomic.LoadInt64(&done); got != 15 {
+		t.Errorf("Expected 15 tasks done, got %d", got)
+	}
+
+	empty := NewPool(nil, factory)
+	if n := empty.Resize(2); n != 0 {
+		t.Errorf("Expected an empty pool to stay empty, got %d", n)
+	}
+}
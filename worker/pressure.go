@@ -0,0 +1,48 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"sync/atomic"
+)
+
+// MemoryPressure is set while a scan is over its memory limit, so workers
+// can stop keeping response bodies until memory is freed.
+type MemoryPressure struct {
+	high int32
+}
+
+// Note whether the scan is over its memory limit, returning whether that
+// changed.  Setting a nil MemoryPressure does nothing.
+func (p *MemoryPressure) Set(high bool) bool {
+	if p == nil {
+		return false
+	}
+	var v int32
+	if high {
+		v = 1
+	}
+	return atomic.SwapInt32(&p.high, v) != v
+}
+
+// Check if the scan is over its memory limit.  A nil MemoryPressure never is.
+func (p *MemoryPressure) High() bool {
+	return p != nil && atomic.LoadInt32(&p.high) != 0
+}
+
+// Stop keeping recorded bodies while pressure is high.
+func (w *Worker) SetMemoryPressure(p *MemoryPressure) {
+	w.pressure = p
+}
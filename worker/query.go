@@ -0,0 +1,82 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	ss "github.com/Matir/webborer/settings"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// QueryPolicy decides how links with query strings are followed.  It is
+// shared by all of the workers so limits apply to the whole scan.
+type QueryPolicy struct {
+	mode  ss.QueryModeOption
+	limit int
+	// Query strings seen for each page and set of parameters
+	shapes map[string]map[string]bool
+	sync.Mutex
+}
+
+func NewQueryPolicy(mode ss.QueryModeOption, limit int) *QueryPolicy {
+	return &QueryPolicy{
+		mode:   mode,
+		limit:  limit,
+		shapes: make(map[string]map[string]bool),
+	}
+}
+
+// Apply the policy to a link, returning the URL to follow or nil to skip it.
+func (p *QueryPolicy) Apply(u *url.URL) *url.URL {
+	if u.RawQuery == "" && !u.ForceQuery {
+		return u
+	}
+	switch p.mode {
+	case ss.QueryDrop:
+		dropped := *u
+		dropped.RawQuery = ""
+		dropped.ForceQuery = false
+		return &dropped
+	case ss.QueryLimit:
+		p.Lock()
+		defer p.Unlock()
+		shape := queryShape(u)
+		seen, ok := p.shapes[shape]
+		if !ok {
+			seen = make(map[string]bool)
+			p.shapes[shape] = seen
+		}
+		if !seen[u.RawQuery] {
+			if len(seen) >= p.limit {
+				return nil
+			}
+			seen[u.RawQuery] = true
+		}
+	}
+	return u
+}
+
+// The page and sorted parameter names of a URL, e.g.
+// "http://localhost/search?page&q".
+func queryShape(u *url.URL) string {
+	var names []string
+	for name := range u.Query() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return u.Scheme + "://" + u.Host + u.Path + "?" + strings.Join(names, "&")
+}
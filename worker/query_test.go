@@ -0,0 +1,79 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"github.com/Matir/webborer/results"
+	ss "github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/task"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func applyQueryPolicy(p *QueryPolicy, s string) string {
+	u, _ := url.Parse(s)
+	if u = p.Apply(u); u == nil {
+		return ""
+	}
+	return u.String()
+}
+
+func TestQueryPolicy_Drop(t *testing.T) {
+	p := NewQueryPolicy(ss.QueryDrop, 0)
+	if got := applyQueryPolicy(p, "http://localhost/a?b=c"); got != "http://localhost/a" {
+		t.Errorf("Expected query to be dropped, got %s", got)
+	}
+	if got := applyQueryPolicy(p, "http://localhost/a"); got != "http://localhost/a" {
+		t.Errorf("Expected URL without query unchanged, got %s", got)
+	}
+}
+
+func TestQueryPolicy_Limit(t *testing.T) {
+	p := NewQueryPolicy(ss.QueryLimit, 2)
+	cases := []struct {
+		u      string
+		follow bool
+	}{
+		{"http://localhost/s?q=1&page=1", true},
+		{"http://localhost/s?page=2&q=1", true},
+		{"http://localhost/s?q=2&page=1", false},
+		{"http://localhost/s?q=1&page=1", true},
+		{"http://localhost/s?q=3", true},
+		{"http://localhost/t?q=1&page=3", true},
+		{"http://localhost/s", true},
+	}
+	for _, c := range cases {
+		if got := applyQueryPolicy(p, c.u) != ""; got != c.follow {
+			t.Errorf("%s: expected follow=%v, got %v", c.u, c.follow, got)
+		}
+	}
+}
+
+func TestHTMLWorker_QueryPolicy(t *testing.T) {
+	var added []string
+	w := NewHTMLWorker(func(tasks ...*task.Task) {
+		for _, t := range tasks {
+			added = append(added, t.URL.String())
+		}
+	})
+	w.SetQueryPolicy(NewQueryPolicy(ss.QueryDrop, 0))
+	base, _ := url.Parse("http://localhost/dir/")
+	madeTask := task.NewTaskFromURL(base)
+	w.Handle(madeTask, strings.NewReader("<a href='page?id=1'>x</a>"), results.NewResultForTask(madeTask))
+	if len(added) < 1 || added[0] != "http://localhost/dir/page" {
+		t.Errorf("Expected link without query, got %v", added)
+	}
+}
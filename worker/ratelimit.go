@@ -0,0 +1,104 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"github.com/Matir/webborer/hosts"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/util"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Pause after a 429 without Retry-After, doubled for each retry in a row
+const defaultRateLimitWait = 10 * time.Second
+
+// Wait until the host of t is no longer paused for rate limiting.
+func (w *Worker) waitForHost(t *task.Task) {
+	if w.hosts == nil {
+		return
+	}
+	host := w.hosts.Get(t.URL)
+	if d := host.PausedFor(); d > 0 {
+		logging.Logf(logging.LogDebug, "Waiting %s for %s to stop rate limiting.", d, hosts.Key(t.URL))
+		host.WaitPause()
+	}
+}
+
+// Check if resp asks for the request to be retried later.  If so, and there
+// are retries left, the host is paused, the response discarded, and true
+// returned so the task is tried again.
+func (w *Worker) rateLimited(t *task.Task, resp *http.Response) bool {
+	wait, ok := retryAfter(resp, time.Now())
+	if !ok || w.settings.RateLimitRetries <= 0 {
+		w.rateLimitRetries = 0
+		return false
+	}
+	if w.rateLimitRetries >= w.settings.RateLimitRetries {
+		logging.Logf(logging.LogWarning, "Still rate limited after %d retries, giving up on %s.", w.rateLimitRetries, t.String())
+		w.rateLimitRetries = 0
+		return false
+	}
+	if wait < 0 {
+		wait = defaultRateLimitWait << uint(w.rateLimitRetries)
+	}
+	if max := w.settings.RateLimitMaxWait; max > 0 && wait > max {
+		wait = max
+	}
+	w.rateLimitRetries++
+	logging.Logf(logging.LogWarning, "Rate limited (%d) by %s, pausing for %s.", resp.StatusCode, hosts.Key(t.URL), wait)
+	util.DrainBody(resp.Body)
+	if w.hosts != nil {
+		w.hosts.Get(t.URL).Pause(wait)
+	} else {
+		time.Sleep(wait)
+	}
+	return true
+}
+
+// Get how long resp asks to wait before retrying, from its Retry-After
+// header, and whether it is rate limiting at all.  A 503 is only taken as
+// rate limiting with Retry-After.  The wait is -1 if a 429 doesn't say.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+	case http.StatusServiceUnavailable:
+		if value == "" {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	if value == "" {
+		return -1, true
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			secs = 0
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		if d := when.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return -1, true
+}
@@ -0,0 +1,81 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"fmt"
+	"github.com/Matir/webborer/client"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/task"
+	"net/http"
+)
+
+// Most of a response body kept for recording
+const maxRecordSize = 1024 * 1024
+
+// Should the request and response be recorded?
+func (w *Worker) recording(resp *http.Response) bool {
+	if !w.settings.Recording() {
+		return false
+	}
+	return !w.settings.RecordHits || w.report.Code(resp.StatusCode)
+}
+
+// Build the record of a request and response.  The body and how long it took
+// to read are added later.
+func newExchange(t *task.Task, method string, header http.Header, resp *http.Response) *results.Exchange {
+	e := &results.Exchange{
+		Method:         method,
+		URL:            t.URL,
+		Host:           t.Host,
+		Proto:          "HTTP/1.1",
+		RequestHeader:  header,
+		Status:         resp.Status,
+		Code:           resp.StatusCode,
+		ResponseProto:  resp.Proto,
+		ResponseHeader: resp.Header,
+		Blocked:        -1,
+		DNS:            -1,
+		Connect:        -1,
+		SSL:            -1,
+		Send:           -1,
+		Wait:           -1,
+		Receive:        -1,
+	}
+	// The request sent includes any headers added by the client
+	if req := resp.Request; req != nil {
+		e.Method, e.URL, e.Host, e.RequestHeader = req.Method, req.URL, req.Host, req.Header
+		if req.Proto != "" {
+			e.Proto = req.Proto
+		}
+	}
+	if trace := client.TraceFor(resp); trace != nil {
+		if header := trace.Header(); len(header) > 0 {
+			// The Host header is kept separately
+			header.Del("Host")
+			e.RequestHeader = header
+		}
+		timings := trace.Timings()
+		e.Blocked, e.DNS, e.Connect, e.SSL = timings.Blocked, timings.DNS, timings.Connect, timings.SSL
+		e.Send, e.Wait = timings.Send, timings.Wait
+	}
+	if e.Status == "" {
+		e.Status = fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	if e.ResponseProto == "" {
+		e.ResponseProto = "HTTP/1.1"
+	}
+	return e
+}
@@ -0,0 +1,66 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/task"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// A Renderer returns the DOM of a page after its scripts have run.
+type Renderer interface {
+	Render(*url.URL) (string, error)
+}
+
+// RenderWorker is an HTMLWorker that renders shallow pages in a browser
+// before looking for links, so that links created by client-side code are
+// found.  Deeper pages are parsed as static HTML.
+type RenderWorker struct {
+	*HTMLWorker
+	renderer Renderer
+	// Maximum directory depth of pages to render
+	maxDepth int
+}
+
+func NewRenderWorker(html *HTMLWorker, renderer Renderer, maxDepth int) *RenderWorker {
+	return &RenderWorker{
+		HTMLWorker: html,
+		renderer:   renderer,
+		maxDepth:   maxDepth,
+	}
+}
+
+func (w *RenderWorker) Handle(t *task.Task, body io.Reader, result *results.Result) {
+	if pathDepth(t.URL) <= w.maxDepth {
+		logging.Logf(logging.LogDebug, "Rendering %s", t.URL.String())
+		dom, err := w.renderer.Render(t.URL)
+		if err == nil {
+			w.HTMLWorker.Handle(t, strings.NewReader(dom), result)
+			return
+		}
+		logging.Logf(logging.LogWarning, "Unable to render %s: %s", t.URL.String(), err.Error())
+	}
+	w.HTMLWorker.Handle(t, body, result)
+}
+
+// Number of directories in the path of u.  Both / and /index.html are at
+// depth 0.
+func pathDepth(u *url.URL) int {
+	return strings.Count(u.Path, "/") - 1
+}
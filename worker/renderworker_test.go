@@ -0,0 +1,64 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"errors"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/task"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type fakeRenderer struct {
+	dom      string
+	err      error
+	rendered []string
+}
+
+func (r *fakeRenderer) Render(u *url.URL) (string, error) {
+	r.rendered = append(r.rendered, u.String())
+	return r.dom, r.err
+}
+
+func TestRenderWorker_Handle(t *testing.T) {
+	static := "<html><body><div id='app'></div><a href='/static'>s</a></body></html>"
+	renderer := &fakeRenderer{dom: "<html><body><a href='/rendered'>r</a></body></html>"}
+	cases := []struct {
+		path     string
+		err      error
+		expected string
+	}{
+		{"/", nil, "/rendered"},
+		{"/index.html", nil, "/rendered"},
+		{"/app/page", nil, "/static"},
+		{"/", errors.New("no chrome"), "/static"},
+	}
+	for _, c := range cases {
+		renderer.err = c.err
+		added := make([]*task.Task, 0)
+		html := NewHTMLWorker(func(t ...*task.Task) { added = append(added, t...) })
+		w := NewRenderWorker(html, renderer, 0)
+		madeTask := task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: c.path})
+		w.Handle(madeTask, strings.NewReader(static), results.NewResultForTask(madeTask))
+		if len(added) == 0 || added[0].URL.Path != c.expected {
+			t.Errorf("%s: expected link to %s, got %v", c.path, c.expected, added)
+		}
+	}
+	if len(renderer.rendered) != 3 {
+		t.Errorf("Expected 3 pages rendered, got %v", renderer.rendered)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"github.com/Matir/webborer/hosts"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/util"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Find out how the host of a found directory treats its path without the
+// trailing slash, by requesting it that way.  A path without the slash that
+// redirects to the directory settles it without another request.  Only the
+// first directory found on each host is checked.
+func (w *Worker) checkSlash(t *task.Task, found *results.Result) {
+	if w.hosts == nil || !w.settings.DetectSlash || found.Error != nil || t.URL.Path == "/" {
+		return
+	}
+	host := w.hosts.Get(t.URL)
+	if found.Redir != nil {
+		if results.IsSlashRedirect(t.URL, found.Redir) && host.StartSlashProbe() {
+			logging.Logf(logging.LogInfo, "%s redirects to add trailing slashes, trying paths without them.", hosts.Key(t.URL))
+			host.SetSlashBehavior(hosts.SlashRedirects)
+		}
+		return
+	}
+	if found.Code != http.StatusOK || !util.URLIsDir(t.URL) {
+		w.referSlash(t, found)
+		return
+	}
+	if !host.StartSlashProbe() {
+		return
+	}
+	probe := t.Copy()
+	probe.URL.Path = strings.TrimSuffix(t.URL.Path, "/")
+	probe.URL.RawPath = ""
+	result := w.probe(probe)
+	switch {
+	case result == nil:
+		host.SetSlashBehavior(hosts.SlashUnknown)
+	case result.Redir != nil && results.IsSlashRedirect(probe.URL, result.Redir):
+		logging.Logf(logging.LogInfo, "%s redirects to add trailing slashes, trying paths without them.", hosts.Key(t.URL))
+		host.SetSlashBehavior(hosts.SlashRedirects)
+	case result.Redir == nil && result.Code == found.Code && result.Length == found.Length:
+		logging.Logf(logging.LogInfo, "%s ignores trailing slashes, trying paths without them.", hosts.Key(t.URL))
+		host.SetSlashBehavior(hosts.SlashIdentical)
+	default:
+		logging.Logf(logging.LogDebug, "%s distinguishes trailing slashes.", hosts.Key(t.URL))
+		host.SetSlashBehavior(hosts.SlashDistinct)
+	}
+}
+
+// On a host that ignores trailing slashes, the wordlist entry with a slash
+// may have been skipped, so refer a path found without one back as a
+// directory to expand.  Paths that look like files are left alone.
+func (w *Worker) referSlash(t *task.Task, found *results.Result) {
+	if t.Provenance.Origin != task.OriginWordlist || util.URLIsDir(t.URL) || strings.Contains(path.Base(t.URL.Path), ".") {
+		return
+	}
+	if !w.KeepSpidering(found.Code) || w.hosts.Get(t.URL).SlashBehavior() != hosts.SlashIdentical {
+		return
+	}
+	dir := t.Copy()
+	dir.URL.Path += "/"
+	dir.URL.RawPath = ""
+	dir.Provenance = task.NewProvenance(task.OriginVariant, t.URL, "/")
+	dir.SetParent(t)
+	w.adder(dir)
+}
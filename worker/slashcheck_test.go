@@ -0,0 +1,123 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"github.com/Matir/webborer/client"
+	"github.com/Matir/webborer/client/mock"
+	"github.com/Matir/webborer/hosts"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/task"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// A client for a server with one directory, /admin/, treating /admin as
+// behavior says
+type slashClient struct {
+	mock.MockClient
+	behavior hosts.SlashBehavior
+	check    func(*http.Request, []*http.Request) error
+}
+
+func (c *slashClient) Get() client.Client {
+	return c
+}
+
+func (c *slashClient) SetCheckRedirect(check func(*http.Request, []*http.Request) error) {
+	c.check = check
+}
+
+func (c *slashClient) Request(u *url.URL, host, method string, header http.Header) (*http.Response, error) {
+	c.Requests = append(c.Requests, u)
+	resp := mock.ResponseFromString("admin")
+	resp.StatusCode = http.StatusNotFound
+	switch {
+	case u.Path == "/admin/" || (u.Path == "/admin" && c.behavior == hosts.SlashIdentical):
+		resp.StatusCode = http.StatusOK
+		resp.ContentLength = 5
+	case u.Path == "/admin" && c.behavior == hosts.SlashRedirects:
+		resp.StatusCode = http.StatusMovedPermanently
+		target := *u
+		target.Path = "/admin/"
+		return resp, c.check(&http.Request{URL: &target}, []*http.Request{{URL: u}})
+	}
+	return resp, nil
+}
+
+func newSlashWorker(behavior hosts.SlashBehavior) (*Worker, *slashClient, *hosts.Registry, *[]*task.Task) {
+	c := &slashClient{behavior: behavior}
+	ss := settings.DefaultScanSettings()
+	ss.DetectCase = false
+	var added []*task.Task
+	w := NewWorker(ss, c, nil, func(tasks ...*task.Task) {
+		added = append(added, tasks...)
+	}, func(int) {}, make(chan *results.Result, 10))
+	reg := hosts.NewRegistry()
+	w.SetHosts(reg)
+	return w, c, reg, &added
+}
+
+func TestCheckSlash(t *testing.T) {
+	for _, behavior := range []hosts.SlashBehavior{hosts.SlashRedirects, hosts.SlashIdentical, hosts.SlashDistinct} {
+		w, c, reg, _ := newSlashWorker(behavior)
+		u := &url.URL{Scheme: "http", Host: "localhost", Path: "/admin/"}
+		w.TryTask(task.NewTaskFromURL(u))
+		if got := reg.Get(u).SlashBehavior(); got != behavior {
+			t.Errorf("Expected slash behavior %d, got %d", behavior, got)
+		}
+		if len(c.Requests) != 2 || c.Requests[1].Path != "/admin" {
+			t.Errorf("Unexpected requests: %v", c.Requests)
+		}
+		// Only checked once per host
+		w.TryTask(task.NewTaskFromURL(u))
+		if len(c.Requests) != 3 {
+			t.Errorf("Expected no more probes, got %v", c.Requests)
+		}
+	}
+}
+
+func TestCheckSlash_Redirect(t *testing.T) {
+	w, c, reg, _ := newSlashWorker(hosts.SlashRedirects)
+	u := &url.URL{Scheme: "http", Host: "localhost", Path: "/admin"}
+	w.TryTask(task.NewTaskFromURL(u))
+	if got := reg.Get(u).SlashBehavior(); got != hosts.SlashRedirects {
+		t.Errorf("Expected a redirecting host, got %d", got)
+	}
+	if len(c.Requests) != 1 {
+		t.Errorf("Expected no probe for a redirect, got %v", c.Requests)
+	}
+}
+
+func TestReferSlash(t *testing.T) {
+	w, _, reg, added := newSlashWorker(hosts.SlashIdentical)
+	u := &url.URL{Scheme: "http", Host: "localhost", Path: "/admin"}
+	reg.Get(u).SetSlashBehavior(hosts.SlashIdentical)
+	tsk := task.NewTaskFromURL(u)
+	tsk.Provenance = task.Provenance{Origin: task.OriginWordlist}
+	w.TryTask(tsk)
+	if len(*added) != 1 || (*added)[0].URL.Path != "/admin/" || (*added)[0].Provenance.Origin != task.OriginVariant {
+		t.Fatalf("Expected /admin/ to be referred back, got %v", *added)
+	}
+	// Not on hosts where the slash is tried anyway
+	*added = nil
+	reg.Get(u).SetSlashBehavior(hosts.SlashDistinct)
+	w.TryTask(tsk)
+	if len(*added) != 0 {
+		t.Errorf("Unexpected referral: %v", *added)
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"github.com/Matir/webborer/client"
+	"github.com/Matir/webborer/results"
+	"math/bits"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// A part of the scan pipeline, for finding what limits the speed of a scan
+type Stage int
+
+const (
+	// Expanding tasks with words, hosts and extensions
+	StageGenerate Stage = iota
+	// Deciding whether tasks should be tried
+	StageFilter
+	// Waiting for the queue to hand out a task
+	StageWait
+	// Waiting for responses from the target
+	StageRequest
+	// Reading and parsing response bodies
+	StageBody
+	// Waiting for the result stages to take results
+	StageResults
+	stageMax
+)
+
+// The stages making up a worker's time
+const firstWorkerStage = StageWait
+
+// Histogram buckets: exact below 4ns, then 4 per power of two.
+const timingBuckets = 256
+
+var stageNames = [...]string{
+	"generating tasks",
+	"filtering tasks",
+	"waiting for work",
+	"requests",
+	"reading bodies",
+	"sending results",
+}
+
+func (s Stage) String() string {
+	return stageNames[s]
+}
+
+// All of the stages, in order
+func Stages() []Stage {
+	stages := make([]Stage, stageMax)
+	for i := range stages {
+		stages[i] = Stage(i)
+	}
+	return stages
+}
+
+// The stages workers spend their time in, in order
+func WorkerStages() []Stage {
+	return Stages()[firstWorkerStage:]
+}
+
+// Timings adds up the time spent in each stage, and how it is distributed
+// for percentiles.  It may be shared by any number of workers.
+type Timings struct {
+	durations [stageMax]int64
+	buckets   [stageMax][timingBuckets]int64
+	requests  int64
+}
+
+func NewTimings() *Timings {
+	return &Timings{}
+}
+
+// Add time spent in a stage.  Each time added to StageRequest counts as a
+// request.
+func (t *Timings) Add(stage Stage, d time.Duration) {
+	atomic.AddInt64(&t.durations[stage], int64(d))
+	atomic.AddInt64(&t.buckets[stage][timingBucket(d)], 1)
+	if stage == StageRequest {
+		atomic.AddInt64(&t.requests, 1)
+	}
+}
+
+// Total time spent in a stage by all workers
+func (t *Timings) Duration(stage Stage) time.Duration {
+	return time.Duration(atomic.LoadInt64(&t.durations[stage]))
+}
+
+// Number of requests made
+func (t *Timings) Requests() int64 {
+	return atomic.LoadInt64(&t.requests)
+}
+
+// Get a function adding times to a stage, for stages outside the workers.
+func (t *Timings) Recorder(stage Stage) func(time.Duration) {
+	return func(d time.Duration) {
+		t.Add(stage, d)
+	}
+}
+
+// Number of times added to a stage
+func (t *Timings) Count(stage Stage) int64 {
+	total := int64(0)
+	for i := range t.buckets[stage] {
+		total += atomic.LoadInt64(&t.buckets[stage][i])
+	}
+	return total
+}
+
+// The time within which p percent of the times added to a stage fell.  This
+// is accurate to within a quarter of the time.
+func (t *Timings) Percentile(stage Stage, p float64) time.Duration {
+	var counts [timingBuckets]int64
+	total := int64(0)
+	for i := range counts {
+		counts[i] = atomic.LoadInt64(&t.buckets[stage][i])
+		total += counts[i]
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := int64(p / 100 * float64(total))
+	if rank < 1 {
+		rank = 1
+	}
+	seen := int64(0)
+	for i, n := range counts {
+		seen += n
+		if seen >= rank {
+			return bucketLimit(i)
+		}
+	}
+	return bucketLimit(timingBuckets - 1)
+}
+
+// Summarize the percentiles of each stage used, for the scan statistics and
+// status endpoint.
+func (t *Timings) Summary() []results.StageTiming {
+	var summary []results.StageTiming
+	for _, stage := range Stages() {
+		count := t.Count(stage)
+		if count == 0 {
+			continue
+		}
+		summary = append(summary, results.StageTiming{
+			Stage: stage.String(),
+			Count: count,
+			P50:   milliseconds(t.Percentile(stage, 50)),
+			P90:   milliseconds(t.Percentile(stage, 90)),
+			P95:   milliseconds(t.Percentile(stage, 95)),
+			P99:   milliseconds(t.Percentile(stage, 99)),
+		})
+	}
+	return summary
+}
+
+// Histogram bucket for a duration: the position of its highest bit and the
+// two bits below it.
+func timingBucket(d time.Duration) int {
+	ns := uint64(d)
+	if d < 0 {
+		ns = 0
+	}
+	if ns < 4 {
+		return int(ns)
+	}
+	n := bits.Len64(ns)
+	return (n-2)*4 + int((ns>>uint(n-3))&3)
+}
+
+// Longest duration falling in a bucket
+func bucketLimit(i int) time.Duration {
+	if i < 4 {
+		return time.Duration(i)
+	}
+	shift := uint(i/4 - 1)
+	limit := (uint64(4|i%4)+1)<<shift - 1
+	if limit > 1<<63-1 {
+		limit = 1<<63 - 1
+	}
+	return time.Duration(limit)
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// Record the time spent in a stage since start, if timings are kept.
+func (w *Worker) timed(stage Stage, start time.Time) {
+	if w.timings != nil {
+		w.timings.Add(stage, time.Since(start))
+	}
+}
+
+// Add up the time spent in each stage in timings.
+func (w *Worker) SetTimings(timings *Timings) {
+	w.timings = timings
+}
+
+// Record how long the request for a result took, and flag it if it was slow.
+// The request was sent at start, and the client returned the response after
+// waited.
+func (w *Worker) timeResult(result *results.Result, resp *http.Response, start time.Time, waited time.Duration) {
+	result.Duration = time.Since(start)
+	result.TTFB = waited
+	if trace := client.TraceFor(resp); trace != nil {
+		timings := trace.Timings()
+		if timings.TTFB >= 0 {
+			result.TTFB = timings.TTFB
+		}
+		if timings.Connect > 0 {
+			result.ConnectTime = timings.Connect
+		}
+	}
+	if w.settings.SlowThreshold > 0 && result.Duration >= w.settings.SlowThreshold {
+		result.Slow = true
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimingBucket(t *testing.T) {
+	for _, d := range []time.Duration{0, 3, 4, 7, 8, 100, time.Millisecond, time.Hour, 1<<63 - 1} {
+		limit := bucketLimit(timingBucket(d))
+		if limit < d {
+			t.Errorf("Bucket for %d ends at %d", d, limit)
+		}
+		if d > 8 && float64(limit) > float64(d)*1.25 {
+			t.Errorf("Bucket for %d is too wide: ends at %d", d, limit)
+		}
+	}
+}
+
+func TestTimings_Percentile(t *testing.T) {
+	timings := NewTimings()
+	if timings.Percentile(StageFilter, 50) != 0 {
+		t.Errorf("Expected 0 for an unused stage")
+	}
+	for i := 1; i <= 100; i++ {
+		timings.Add(StageRequest, time.Duration(i)*time.Millisecond)
+	}
+	timings.Add(StageFilter, time.Microsecond)
+	if timings.Requests() != 100 || timings.Count(StageRequest) != 100 {
+		t.Errorf("Expected 100 requests, got %d", timings.Requests())
+	}
+	checks := map[float64]time.Duration{
+		50: 50 * time.Millisecond,
+		90: 90 * time.Millisecond,
+		99: 99 * time.Millisecond,
+	}
+	for p, expected := range checks {
+		got := timings.Percentile(StageRequest, p)
+		if got < expected || float64(got) > float64(expected)*1.25 {
+			t.Errorf("p%v: expected about %s, got %s", p, expected, got)
+		}
+	}
+	summary := timings.Summary()
+	if len(summary) != 2 {
+		t.Fatalf("Expected 2 stages in summary, got %v", summary)
+	}
+	if summary[0].Stage != StageFilter.String() || summary[1].Stage != StageRequest.String() {
+		t.Errorf("Unexpected stages in summary: %v", summary)
+	}
+	if summary[1].Count != 100 || summary[1].P50 < 50 || summary[1].P99 < 99 {
+		t.Errorf("Unexpected request timings: %+v", summary[1])
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"github.com/Matir/webborer/hosts"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/task"
+	"net/url"
+	"strings"
+)
+
+// Note whether an http request was redirected to the same path over https.
+// Once its host is found to redirect every request, the rest of its paths are
+// requested over https, which is noted on the result that showed it.
+func (w *Worker) checkUpgrade(t *task.Task, result *results.Result) {
+	if w.hosts == nil || !w.settings.AutoUpgrade || t.URL.Scheme != "http" {
+		return
+	}
+	var target *url.URL
+	if len(w.redirChain) > 0 {
+		target = w.redirChain[0]
+	} else if w.redir != nil {
+		target = w.redir.URL
+	}
+	upgradeHost := ""
+	if isUpgrade(t.URL, target) {
+		upgradeHost = target.Host
+	}
+	if w.hosts.NoteHTTPResponse(t.URL, upgradeHost) {
+		logging.Logf(logging.LogInfo, "%s redirects every request to https, switching to https://%s.", hosts.Key(t.URL), upgradeHost)
+		result.AddNote("every http request redirects to https, remaining paths requested over https")
+	}
+}
+
+// Check if to is the same path as from on the same host, but over https.
+func isUpgrade(from, to *url.URL) bool {
+	if to == nil || to.Scheme != "https" || !strings.EqualFold(from.Hostname(), to.Hostname()) {
+		return false
+	}
+	return strings.TrimSuffix(from.EscapedPath(), "/") == strings.TrimSuffix(to.EscapedPath(), "/")
+}
@@ -0,0 +1,142 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"github.com/Matir/webborer/client"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/results"
+	ss "github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/task"
+	"sync"
+	"time"
+)
+
+// Verifier re-requests every reported result once the scan is finished, and
+// tags or drops those that no longer reproduce, so one-off errors from flaky
+// servers don't end up in reports.  Tagged results are marked as transient.
+// Other results are passed through immediately; reported results are held
+// until the input is finished.
+type Verifier struct {
+	settings *ss.ScanSettings
+	factory  client.ClientFactory
+	// Status codes the scan reports
+	report results.ReportCodes
+	// Time to wait before re-requesting
+	delay time.Duration
+	// Drop results that don't reproduce instead of tagging them
+	drop bool
+	// Number of results to re-request at once
+	parallel int
+}
+
+func NewVerifier(settings *ss.ScanSettings, factory client.ClientFactory) *Verifier {
+	parallel := settings.Workers
+	if parallel < 1 {
+		parallel = 1
+	}
+	return &Verifier{
+		settings: settings,
+		factory:  factory,
+		report:   results.NewReportCodes(settings),
+		delay:    settings.VerifyDelay,
+		drop:     settings.Verify == ss.VerifyDrop,
+		parallel: parallel,
+	}
+}
+
+func (v *Verifier) Process(in <-chan *results.Result) <-chan *results.Result {
+	out := make(chan *results.Result, cap(in))
+	go func() {
+		defer close(out)
+		var held []*results.Result
+		for r := range in {
+			if !v.report.Result(r) {
+				out <- r
+				continue
+			}
+			held = append(held, r)
+		}
+		if len(held) == 0 {
+			return
+		}
+		if v.delay > 0 {
+			logging.Logf(logging.LogInfo, "Waiting %s before verifying %d results.", v.delay, len(held))
+			time.Sleep(v.delay)
+		}
+		reproduced := v.verifyAll(held)
+		for i, r := range held {
+			if !reproduced[i] && v.drop {
+				logging.Logf(logging.LogInfo, "Dropping %s: not reproduced.", r.String())
+				continue
+			}
+			out <- r
+		}
+	}()
+	return out
+}
+
+// Re-request all of the held results, returning which ones reproduced.
+func (v *Verifier) verifyAll(held []*results.Result) []bool {
+	reproduced := make([]bool, len(held))
+	next := make(chan int)
+	wg := sync.WaitGroup{}
+	for i := 0; i < v.parallel && i < len(held); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rchan := make(chan *results.Result, 1)
+			w := NewWorker(v.settings, v.factory, nil, func(...*task.Task) {}, func(int) {}, rchan)
+			for i := range next {
+				reproduced[i] = verifyResult(w, rchan, held[i])
+			}
+		}()
+	}
+	for i := range held {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return reproduced
+}
+
+// Request a result again with the worker, noting on it if it doesn't
+// reproduce.
+func verifyResult(w *Worker, rchan <-chan *results.Result, r *results.Result) bool {
+	t := task.NewTaskFromURL(r.URL)
+	t.Host = r.Host
+	t.Header = r.RequestHeader
+	t.Provenance = r.Provenance
+	w.TryTask(t)
+	var again *results.Result
+	select {
+	case again = <-rchan:
+	default:
+	}
+	switch {
+	case again == nil:
+		r.AddNote("not reproduced on re-check")
+	case again.Error != nil:
+		r.AddNote("not reproduced on re-check: %s", again.Error.Error())
+	case again.Code != r.Code:
+		r.AddNote("not reproduced on re-check: status %d", again.Code)
+	default:
+		logging.Logf(logging.LogDebug, "Verified %s", r.String())
+		return true
+	}
+	logging.Logf(logging.LogInfo, "%s did not reproduce.", r.String())
+	r.Transient = true
+	return false
+}
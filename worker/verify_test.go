@@ -0,0 +1,112 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"errors"
+	"github.com/Matir/webborer/client"
+	"github.com/Matir/webborer/client/mock"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/settings"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// Client that answers with a fixed status code per path
+type verifyClient struct {
+	codes map[string]int
+}
+
+func (c *verifyClient) Get() client.Client {
+	return c
+}
+
+func (c *verifyClient) RequestURL(u *url.URL) (*http.Response, error) {
+	return c.Request(u, "", "GET", nil)
+}
+
+func (c *verifyClient) Request(u *url.URL, _, _ string, _ http.Header) (*http.Response, error) {
+	code, ok := c.codes[u.Path]
+	if !ok {
+		return nil, errors.New("connection reset")
+	}
+	resp := mock.ResponseFromString("")
+	resp.StatusCode = code
+	return resp, nil
+}
+
+func (c *verifyClient) SetCheckRedirect(func(*http.Request, []*http.Request) error) {}
+
+func runVerifier(mode settings.VerifyModeOption) []*results.Result {
+	factory := &verifyClient{codes: map[string]int{
+		"/stable":  200,
+		"/flapped": 404,
+	}}
+	ss := &settings.ScanSettings{Workers: 2, Verify: mode, HeadersOnly: true}
+	in := make(chan *results.Result, 4)
+	for _, path := range []string{"/stable", "/flapped", "/gone"} {
+		r := results.NewResult(&url.URL{Scheme: "http", Host: "localhost", Path: path}, "")
+		r.Code = 200
+		in <- r
+	}
+	missing := results.NewResult(&url.URL{Scheme: "http", Host: "localhost", Path: "/missing"}, "")
+	missing.Code = 404
+	in <- missing
+	close(in)
+	var out []*results.Result
+	for r := range NewVerifier(ss, factory).Process(in) {
+		out = append(out, r)
+	}
+	return out
+}
+
+func TestVerifier_Tag(t *testing.T) {
+	out := runVerifier(settings.VerifyTag)
+	if len(out) != 4 {
+		t.Fatalf("Expected 4 results, got %d", len(out))
+	}
+	notes := make(map[string]string)
+	for _, r := range out {
+		notes[r.URL.Path] = strings.Join(r.Notes, "; ")
+		if want := r.URL.Path == "/flapped" || r.URL.Path == "/gone"; r.Transient != want {
+			t.Errorf("Expected transient %v for %s, got %v", want, r.URL.Path, r.Transient)
+		}
+	}
+	if notes["/stable"] != "" {
+		t.Errorf("Unexpected note on reproduced result: %s", notes["/stable"])
+	}
+	if !strings.Contains(notes["/flapped"], "status 404") {
+		t.Errorf("Expected status note, got %q", notes["/flapped"])
+	}
+	if !strings.Contains(notes["/gone"], "connection reset") {
+		t.Errorf("Expected error note, got %q", notes["/gone"])
+	}
+	if notes["/missing"] != "" {
+		t.Errorf("Unreported results should not be re-checked: %q", notes["/missing"])
+	}
+}
+
+func TestVerifier_Drop(t *testing.T) {
+	out := runVerifier(settings.VerifyDrop)
+	paths := make([]string, 0, len(out))
+	for _, r := range out {
+		paths = append(paths, r.URL.Path)
+	}
+	if got := strings.Join(paths, ","); got != "/missing,/stable" {
+		t.Errorf("Unexpected results after verification: %s", got)
+	}
+}
@@ -17,8 +17,12 @@
 package worker
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"github.com/Matir/webborer/browser"
 	"github.com/Matir/webborer/client"
+	"github.com/Matir/webborer/hosts"
 	"github.com/Matir/webborer/logging"
 	"github.com/Matir/webborer/results"
 	ss "github.com/Matir/webborer/settings"
@@ -26,7 +30,11 @@ import (
 	"github.com/Matir/webborer/util"
 	"github.com/Matir/webborer/workqueue"
 	"io"
+	"io/ioutil"
+	"math/rand"
 	"net/http"
+	"net/url"
+	"runtime/trace"
 	"strings"
 	"time"
 )
@@ -35,6 +43,12 @@ type Stoppable interface {
 	Stop()
 }
 
+// Maximum amount of a body to read for hashing
+const maxHashSize = 10 * 1024 * 1024
+
+// Maximum amount of a body to buffer when several page workers need it
+const maxBufferedBodySize = 10 * 1024 * 1024
+
 type PageWorker interface {
 	Eligible(*http.Response) bool
 	Handle(*task.Task, io.Reader, *results.Result)
@@ -50,18 +64,44 @@ type Worker struct {
 	src <-chan *task.Task
 	// Function to add future work
 	adder workqueue.QueueAddFunc
+	// Function to add work discovered by crawling, if separate from adder
+	spider workqueue.SpiderFunc
 	// Function to mark work done
 	done workqueue.QueueDoneFunc
 	// Channel for scan results
 	rchan chan<- *results.Result
 	// Settings
 	settings *ss.ScanSettings
+	// Status codes the scan reports
+	report results.ReportCodes
 	// HTML worker to parse page
 	pageWorker PageWorker
+	// Additional page workers, e.g. from plugins
+	extraPageWorkers []PageWorker
 	// Channel to trigger stopping
 	stop chan bool
 	// Request for redirection
 	redir *http.Request
+	// Redirects followed for the current request
+	redirChain []*url.URL
+	// Whether the current request was stopped in a redirect loop
+	redirLoop bool
+	// Shared calibration state
+	calibrator *Calibrator
+	// Shared state of each host
+	hosts *hosts.Registry
+	// Retries in a row after rate limiting
+	rateLimitRetries int
+	// Shared responses to static assets, if kept
+	assets *AssetCache
+	// Results of the previous scan, for conditional requests
+	previous *results.Comparison
+	// Requests are listed here instead of sent, for a dry run
+	plan *DryRunPlan
+	// Set while the scan is over its memory limit, if it has one
+	pressure *MemoryPressure
+	// Time spent in each stage, if kept
+	timings *Timings
 	// Channel to signal worker stopping
 	waitq chan bool
 }
@@ -76,16 +116,21 @@ func NewWorker(settings *ss.ScanSettings,
 	w := &Worker{
 		client:   factory.Get(),
 		settings: settings,
+		report:   results.NewReportCodes(settings),
 		src:      src,
 		adder:    adder,
 		done:     done,
 		rchan:    rchan,
-		stop:     make(chan bool),
+		stop:     make(chan bool, 1),
 		waitq:    make(chan bool),
 	}
 
 	// Install redirect handler
-	redirHandler := func(req *http.Request, _ []*http.Request) error {
+	redirHandler := func(req *http.Request, via []*http.Request) error {
+		if w.followRedirect(req, via) {
+			w.redirChain = append(w.redirChain, req.URL)
+			return nil
+		}
 		w.redir = req
 		return fmt.Errorf("Stop redirect.")
 	}
@@ -94,8 +139,62 @@ func NewWorker(settings *ss.ScanSettings,
 	return w
 }
 
+// Decide whether a redirect should be followed according to the redirect
+// policy.  via contains the requests made so far, oldest first.
+func (w *Worker) followRedirect(req *http.Request, via []*http.Request) bool {
+	if len(via) > w.settings.MaxRedirects {
+		return false
+	}
+	for _, prev := range via {
+		if prev.URL.String() == req.URL.String() {
+			w.redirLoop = true
+			return false
+		}
+	}
+	switch w.settings.RedirectPolicy {
+	case ss.RedirectSameHost:
+		return len(via) == 0 || req.URL.Host == via[0].URL.Host
+	case ss.RedirectAll:
+		return true
+	}
+	return false
+}
+
 func (w *Worker) SetPageWorker(pw PageWorker) {
 	w.pageWorker = pw
+	if w.spider != nil {
+		setSpider(pw, w.spider)
+	}
+}
+
+// Set the function for adding work discovered by crawling, such as redirects
+// and links found by the page worker, so the queue can apply its spider
+// policy to it.  Otherwise discovered work is added like any other.
+func (w *Worker) SetSpider(spider workqueue.SpiderFunc) {
+	w.spider = spider
+	setSpider(w.pageWorker, spider)
+}
+
+// Add work discovered by crawling.
+func (w *Worker) discover(tasks ...*task.Task) {
+	if w.spider != nil {
+		w.spider(tasks...)
+	} else {
+		w.adder(tasks...)
+	}
+}
+
+func setSpider(pw PageWorker, spider workqueue.SpiderFunc) {
+	if s, ok := pw.(interface {
+		SetSpider(workqueue.SpiderFunc)
+	}); ok {
+		s.SetSpider(spider)
+	}
+}
+
+// Add a page worker to run in addition to the one set by SetPageWorker.
+func (w *Worker) AddPageWorker(pw PageWorker) {
+	w.extraPageWorkers = append(w.extraPageWorkers, pw)
 }
 
 // Run the worker, processing input from a channel until either signalled to
@@ -105,6 +204,13 @@ func (w *Worker) Run() {
 		w.waitq <- true
 	}()
 	for true {
+		start := time.Now()
+		// Stop before taking another task, even if one is ready
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
 		select {
 		case <-w.stop:
 			return
@@ -112,6 +218,7 @@ func (w *Worker) Run() {
 			if !ok { // channel closed
 				return
 			}
+			w.timed(StageWait, start)
 			w.HandleTask(t)
 		}
 	}
@@ -129,9 +236,56 @@ func (w *Worker) Wait() {
 	<-w.waitq
 }
 
+// Build another worker reading from the same source and sharing the page
+// workers and state of w, so workers can be added while a scan runs.
+func (w *Worker) Clone(factory client.ClientFactory) *Worker {
+	c := NewWorker(w.settings, factory, w.src, w.adder, w.done, w.rchan)
+	c.spider = w.spider
+	c.pageWorker = w.pageWorker
+	c.extraPageWorkers = append([]PageWorker(nil), w.extraPageWorkers...)
+	c.calibrator = w.calibrator
+	c.hosts = w.hosts
+	c.assets = w.assets
+	c.previous = w.previous
+	c.plan = w.plan
+	c.timings = w.timings
+	c.pressure = w.pressure
+	return c
+}
+
 func (w *Worker) HandleTask(t *task.Task) {
 	logging.Logf(logging.LogDebug, "Trying Raw URL (unmangled): %s", t.String())
+	if w.plan != nil {
+		w.planTask(t)
+		w.done(1)
+		return
+	}
+	if w.hosts != nil {
+		// In case the host was upgraded after the task was filtered
+		t.URL, _ = w.hosts.Upgrade(t.URL)
+		host := w.hosts.Get(t.URL)
+		if host.Skipped() {
+			logging.Logf(logging.LogDebug, "Host skipped, dropping %s", t.String())
+			w.done(1)
+			return
+		}
+		host.Acquire()
+		defer host.Release()
+	}
+	if t.External {
+		w.checkExternal(t)
+		w.done(1)
+		return
+	}
+	w.addForwardedHeaders(t)
+	w.calibrate(t)
+	if w.skipWildcard(t) {
+		w.done(1)
+		return
+	}
+	w.checkFavicon(t)
 	code := w.TryTask(t)
+	w.TryBypassTask(t, code)
 	if !util.URLIsDir(t.URL) {
 		if w.KeepSpidering(code) {
 			w.TryMangleTask(t)
@@ -155,6 +309,7 @@ func (w *Worker) TryMangleTask(t *task.Task) {
 	for _, newname := range Mangle(basename) {
 		clone := clone.Copy()
 		clone.URL.Path = dirname + "/" + newname
+		clone.Provenance = task.NewProvenance(task.OriginMangle, t.URL, "")
 		w.TryTask(clone)
 	}
 }
@@ -162,17 +317,35 @@ func (w *Worker) TryMangleTask(t *task.Task) {
 func (w *Worker) TryTask(t *task.Task) int {
 	logging.Logf(logging.LogInfo, "Trying: %s", t.String())
 	w.redir = nil
+	w.redirChain = nil
+	w.redirLoop = false
 	defer w.Sleep()
-	method := w.settings.Method
-	if resp, err := w.client.Request(t.URL, t.Host, method, t.Header); err != nil && w.redir == nil {
+	method, header := w.requestOptions(t)
+	if code, ok := w.fromAssetCache(t, method); ok {
+		return code
+	}
+	header, prev := w.conditionalHeader(t, header)
+	w.waitForHost(t)
+	region := trace.StartRegion(context.Background(), StageRequest.String())
+	start := time.Now()
+	resp, err := w.client.Request(t.URL, t.Host, method, header)
+	waited := time.Since(start)
+	w.timed(StageRequest, start)
+	region.End()
+	if err == nil && w.rateLimited(t, resp) {
+		return w.TryTask(t)
+	}
+	if err != nil && w.redir == nil {
 		result := w.ResultForError(t, resp, err)
-		w.rchan <- result
+		result.RequestHeader = header
+		w.timeResult(result, resp, start, waited)
+		w.sendResult(result)
 		if resp == nil {
 			return 0
 		}
+		resp.Body.Close()
 		return resp.StatusCode
 	} else {
-		defer resp.Body.Close()
 		// Do we keep going?
 		if util.URLIsDir(t.URL) && w.KeepSpidering(resp.StatusCode) {
 			logging.Logf(logging.LogDebug, "Referring %s back for spidering.", t.String())
@@ -180,20 +353,148 @@ func (w *Worker) TryTask(t *task.Task) int {
 		}
 		w.spiderRedirect(t)
 		result := w.ResultForResponse(t, resp)
-		w.runPageWorkers(t, resp, result)
-		w.rchan <- result
-		return resp.StatusCode
+		result.RequestHeader = header
+		w.checkUpgrade(t, result)
+		if prev != nil && resp.StatusCode == http.StatusNotModified {
+			notModified(result, prev)
+		}
+		if w.recording(resp) {
+			result.Exchange = newExchange(t, method, header, resp)
+			result.Exchange.Started = start
+			if result.Exchange.Wait < 0 {
+				result.Exchange.Wait = waited
+			}
+		}
+		if w.settings.HeadersOnly {
+			// Closing an unread body drops the connection instead of
+			// downloading the rest of the response.
+			resp.Body.Close()
+		} else {
+			region = trace.StartRegion(context.Background(), StageBody.String())
+			bodyStart := time.Now()
+			w.handleBody(t, resp, result)
+			// Finish reading the body so the connection can be reused
+			util.DrainBody(resp.Body)
+			w.timed(StageBody, bodyStart)
+			if result.Exchange != nil {
+				result.Exchange.Receive = time.Since(bodyStart)
+			}
+			region.End()
+		}
+		w.timeResult(result, resp, start, waited)
+		w.cacheAsset(t, method, result)
+		if w.isSlashRedirect(t) {
+			logging.Logf(logging.LogDebug, "Not reporting trailing slash redirect for %s", t.String())
+		} else {
+			w.sendResult(result)
+			w.checkCase(t, result)
+		}
+		w.checkSlash(t, result)
+		return result.Code
 	}
 }
 
+// Check a link to a page outside the scan with a HEAD request.  The page is
+// reported, but never spidered.
+func (w *Worker) checkExternal(t *task.Task) {
+	logging.Logf(logging.LogInfo, "Checking external link: %s", t.String())
+	w.redir = nil
+	w.redirChain = nil
+	w.redirLoop = false
+	defer w.Sleep()
+	_, header := w.requestOptions(t)
+	resp, err := w.client.Request(t.URL, t.Host, http.MethodHead, header)
+	if err != nil && w.redir == nil {
+		result := w.ResultForError(t, resp, err)
+		result.RequestHeader = header
+		w.sendResult(result)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return
+	}
+	result := w.ResultForResponse(t, resp)
+	result.RequestHeader = header
+	util.DrainBody(resp.Body)
+	w.sendResult(result)
+}
+
+// Send a result on, recording how long the result stages took to take it.
+func (w *Worker) sendResult(result *results.Result) {
+	start := time.Now()
+	w.rchan <- result
+	w.timed(StageResults, start)
+}
+
+// Add the X-Forwarded headers to a task, so they are recorded in its results
+// and sent again by anything retrying it.
+func (w *Worker) addForwardedHeaders(t *task.Task) {
+	if !w.settings.ForwardedHost && w.settings.ForwardedFor == "" {
+		return
+	}
+	// The header may be shared with other tasks
+	header := make(http.Header, len(t.Header)+2)
+	for k, v := range t.Header {
+		header[k] = v
+	}
+	if w.settings.ForwardedHost {
+		host := t.Host
+		if host == "" {
+			host = t.URL.Host
+		}
+		header.Set("X-Forwarded-Host", host)
+	}
+	if w.settings.ForwardedFor != "" {
+		header.Set("X-Forwarded-For", w.settings.ForwardedFor)
+	}
+	t.Header = header
+}
+
+// Get the method and headers for a request for a task, applying any overrides
+// from the target it belongs to and filling in header templates.
+func (w *Worker) requestOptions(t *task.Task) (string, http.Header) {
+	method, header := w.settings.Method, t.Header
+	if target := w.settings.TargetFor(t.URL); target != nil {
+		if target.Method != "" {
+			method = target.Method
+		}
+		if len(target.Header) > 0 {
+			header = make(http.Header)
+			for k, v := range t.Header {
+				header[k] = v
+			}
+			for k, v := range target.Header {
+				header[k] = v
+			}
+		}
+	}
+	return method, renderHeader(header, t)
+}
+
 func (w *Worker) spiderRedirect(t *task.Task) {
-	if w.redir == nil {
+	var target *url.URL
+	if w.redir != nil {
+		target = w.redir.URL
+	} else if len(w.redirChain) > 0 {
+		target = w.redirChain[len(w.redirChain)-1]
+	} else {
 		return
 	}
-	logging.Logf(logging.LogDebug, "Referring redirect %s back.", w.redir.URL.String())
-	t = t.Copy()
-	t.URL = w.redir.URL
-	w.adder(t)
+	logging.Logf(logging.LogDebug, "Referring redirect %s back.", target.String())
+	next := t.Copy()
+	next.URL = target
+	next.Provenance = task.NewProvenance(task.OriginRedirect, t.URL, "")
+	next.SetParent(t)
+	w.discover(next)
+}
+
+// Check if the current response is just a redirect to add a trailing slash,
+// which is treated as the same resource when configured.
+func (w *Worker) isSlashRedirect(t *task.Task) bool {
+	if !w.settings.IgnoreSlashRedirects || w.redir == nil {
+		return false
+	}
+	return results.IsSlashRedirect(t.URL, w.redir.URL)
 }
 
 func (w *Worker) ResultForError(t *task.Task, resp *http.Response, err error) *results.Result {
@@ -212,23 +513,145 @@ func (w *Worker) ResultForResponse(t *task.Task, resp *http.Response) *results.R
 	rv.Code = resp.StatusCode
 	rv.Length = resp.ContentLength // Not always available :(
 	rv.ContentType = resp.Header.Get("Content-Type")
+	rv.ETag = resp.Header.Get("ETag")
+	rv.LastModified = resp.Header.Get("Last-Modified")
 	rv.ResponseHeader = resp.Header // TODO: filter?
+	rv.Confidence = results.ConfidenceStatus
 	if w.redir != nil {
 		rv.Redir = w.redir.URL
 	}
+	if len(w.redirChain) > 0 {
+		rv.RedirectChain = append([]*url.URL(nil), w.redirChain...)
+	}
+	if w.redirLoop {
+		rv.AddNote("redirect loop")
+	}
 	return rv
 }
 
 func (w *Worker) Sleep() {
-	if w.settings.SleepTime != 0 {
-		time.Sleep(w.settings.SleepTime)
+	if d := w.sleepDuration(); d != 0 {
+		time.Sleep(d)
+	}
+}
+
+// Time to sleep after a request, including any random jitter
+func (w *Worker) sleepDuration() time.Duration {
+	d := w.settings.SleepTime
+	if w.settings.Jitter > 0 {
+		d += time.Duration(rand.Int63n(int64(w.settings.Jitter)))
 	}
+	return d
 }
 
-func (w *Worker) runPageWorkers(t *task.Task, resp *http.Response, result *results.Result) {
+// Read the response body as needed by page workers, body hashing, and judging
+// the content.
+func (w *Worker) handleBody(t *task.Task, resp *http.Response, result *results.Result) {
+	var body io.Reader = resp.Body
+	var hasher *util.BodyHasher
+	if w.settings.HashBodies {
+		hasher = util.NewBodyHasher()
+		body = io.TeeReader(io.LimitReader(body, maxHashSize), hasher)
+	}
+	sample := &sampleWriter{limit: results.ConfidenceSampleSize}
+	if w.settings.FilterRegex != "" {
+		sample.limit = results.FilterSampleSize
+	}
+	body = io.TeeReader(body, sample)
+	var record *sampleWriter
+	if result.Exchange != nil && w.pressure.High() {
+		// Over the memory limit, so the body isn't kept
+		result.Exchange.Truncated = true
+	} else if result.Exchange != nil {
+		// One more byte than is kept, to tell if the body was cut short
+		record = &sampleWriter{limit: maxRecordSize + 1}
+		body = io.TeeReader(body, record)
+	}
+	w.runPageWorkers(t, resp, body, result)
+	if hasher != nil {
+		// Page workers may not have consumed the whole body
+		if _, err := io.Copy(ioutil.Discard, body); err != nil {
+			logging.Logf(logging.LogInfo, "Error reading body for %s: %s", t.String(), err.Error())
+			return
+		}
+		result.BodyHash = hasher.SHA256()
+		result.FuzzyHash = hasher.FuzzyHash()
+	} else if sample.needed() > 0 {
+		io.CopyN(ioutil.Discard, body, int64(sample.needed()))
+	}
+	if record != nil {
+		if n := record.needed(); n > 0 {
+			io.CopyN(ioutil.Discard, body, int64(n))
+		}
+		if len(record.buf) > maxRecordSize {
+			record.buf = record.buf[:maxRecordSize]
+			result.Exchange.Truncated = true
+		}
+		result.Exchange.Body = record.buf
+	}
+	if w.settings.FilterRegex != "" {
+		result.BodySample = sample.buf
+	}
+	// Page workers may already have verified the content
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 && result.Confidence != results.ConfidenceVerified {
+		confidenceSample := sample.buf
+		if len(confidenceSample) > results.ConfidenceSampleSize {
+			confidenceSample = confidenceSample[:results.ConfidenceSampleSize]
+		}
+		confidence, note := results.ContentConfidence(t.URL, confidenceSample)
+		result.Confidence = confidence
+		if note != "" {
+			result.AddNote("%s", note)
+		}
+	}
+}
+
+// Records the start of whatever is written to it
+type sampleWriter struct {
+	buf   []byte
+	limit int
+}
+
+func (s *sampleWriter) Write(p []byte) (int, error) {
+	if n := s.needed(); n > 0 {
+		if len(p) < n {
+			n = len(p)
+		}
+		s.buf = append(s.buf, p[:n]...)
+	}
+	return len(p), nil
+}
+
+// Bytes still needed to fill the sample
+func (s *sampleWriter) needed() int {
+	return s.limit - len(s.buf)
+}
+
+func (w *Worker) runPageWorkers(t *task.Task, resp *http.Response, body io.Reader, result *results.Result) {
+	eligible := make([]PageWorker, 0, 1+len(w.extraPageWorkers))
 	if w.pageWorker != nil && w.pageWorker.Eligible(resp) {
-		logging.Logf(logging.LogDebug, "Running page workers for task %s", t.String())
-		w.pageWorker.Handle(t, resp.Body, result)
+		eligible = append(eligible, w.pageWorker)
+	}
+	for _, pw := range w.extraPageWorkers {
+		if pw.Eligible(resp) {
+			eligible = append(eligible, pw)
+		}
+	}
+	if len(eligible) == 0 {
+		return
+	}
+	logging.Logf(logging.LogDebug, "Running page workers for task %s", t.String())
+	if len(eligible) == 1 {
+		eligible[0].Handle(t, body, result)
+		return
+	}
+	// Each page worker needs its own copy of the body
+	buf, err := ioutil.ReadAll(io.LimitReader(body, maxBufferedBodySize))
+	if err != nil {
+		logging.Logf(logging.LogInfo, "Error reading body for %s: %s", t.String(), err.Error())
+	}
+	for _, pw := range eligible {
+		pw.Handle(t, bytes.NewReader(buf), result)
 	}
 }
 
@@ -237,16 +660,29 @@ func (w *Worker) KeepSpidering(code int) bool {
 	if w.settings.RunMode == ss.RunModeDotProduct {
 		return false
 	}
-	for _, v := range w.settings.SpiderCodes {
-		if code == v {
-			return true
-		}
+	if w.settings.SpiderExcludeCodes.Contains(code) {
+		return false
 	}
-	return false
+	return w.settings.SpiderCodes.Contains(code)
 }
 
 // Starts a batch of workers based on the relevant settings.
 func StartWorkers(settings *ss.ScanSettings,
+	factory client.ClientFactory,
+	src <-chan *task.Task,
+	adder workqueue.QueueAddFunc,
+	done workqueue.QueueDoneFunc,
+	rchan chan<- *results.Result) []*Worker {
+	workers := NewWorkers(settings, factory, src, adder, done, rchan)
+	for _, w := range workers {
+		w.RunInBackground()
+	}
+	return workers
+}
+
+// Builds a batch of workers based on the relevant settings, without starting
+// them.
+func NewWorkers(settings *ss.ScanSettings,
 	factory client.ClientFactory,
 	src <-chan *task.Task,
 	adder workqueue.QueueAddFunc,
@@ -254,11 +690,37 @@ func StartWorkers(settings *ss.ScanSettings,
 	rchan chan<- *results.Result) []*Worker {
 	count := settings.Workers
 	workers := make([]*Worker, count)
+	var renderer Renderer
+	if settings.RenderDepth >= 0 {
+		if r, err := browser.NewRenderer(settings.ChromePath); err != nil {
+			logging.Logf(logging.LogError, "Unable to render pages: %s", err.Error())
+		} else {
+			renderer = r
+		}
+	}
+	queries := NewQueryPolicy(settings.QueryMode, settings.QueryLimit)
+	var calibrator *Calibrator
+	if settings.Calibrate {
+		calibrator = NewCalibrator(settings.CalibrationSamples, settings.CalibrationRefresh)
+	}
+	var assets *AssetCache
+	if settings.CacheAssets {
+		assets = NewAssetCache()
+	}
 	for i := 0; i < count; i++ {
 		workers[i] = NewWorker(settings, factory, src, adder, done, rchan)
-		workers[i].RunInBackground()
+		workers[i].SetCalibrator(calibrator)
+		workers[i].SetAssetCache(assets)
 		if (settings.ParseHTML && settings.RunMode == ss.RunModeEnumeration) || settings.RunMode == ss.RunModeLinkCheck {
-			workers[i].SetPageWorker(NewHTMLWorker(adder))
+			htmlWorker := NewHTMLWorker(workqueue.SpiderFunc(adder))
+			htmlWorker.SetMaxSize(settings.MaxHTMLSize)
+			htmlWorker.SetQueryPolicy(queries)
+			htmlWorker.SetNofollowMode(settings.NofollowMode)
+			if renderer != nil {
+				workers[i].SetPageWorker(NewRenderWorker(htmlWorker, renderer, settings.RenderDepth))
+			} else {
+				workers[i].SetPageWorker(htmlWorker)
+			}
 		}
 	}
 	return workers
@@ -15,15 +15,25 @@
 package worker
 
 import (
+	"fmt"
+	"github.com/Matir/webborer/client"
 	"github.com/Matir/webborer/client/mock"
+	"github.com/Matir/webborer/hosts"
 	"github.com/Matir/webborer/results"
 	"github.com/Matir/webborer/settings"
 	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/util"
+	"github.com/Matir/webborer/workqueue"
 	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func noopInt(_ int)           {}
@@ -45,7 +55,7 @@ func TryTaskHelper(u *task.Task, resp *http.Response) *Worker {
 		client.NextResponse = resp
 	}
 	ss := &settings.ScanSettings{
-		SpiderCodes: []int{200},
+		SpiderCodes: settings.CodeRangeFlag{{Min: 200, Max: 200}},
 	}
 	rchan := make(chan *results.Result)
 	w := &Worker{
@@ -84,7 +94,7 @@ func TestTryMangleURL_Basic(t *testing.T) {
 		ForeverResponse: resp,
 	}
 	ss := &settings.ScanSettings{
-		SpiderCodes: []int{200},
+		SpiderCodes: settings.CodeRangeFlag{{Min: 200, Max: 200}},
 		Mangle:      true,
 	}
 	rchan := make(chan *results.Result)
@@ -111,7 +121,7 @@ func TestTryHandleURL_Basic(t *testing.T) {
 		ForeverResponse: resp,
 	}
 	ss := &settings.ScanSettings{
-		SpiderCodes: []int{200},
+		SpiderCodes: settings.CodeRangeFlag{{Min: 200, Max: 200}},
 		Mangle:      true,
 		Extensions:  []string{"html", "php"},
 	}
@@ -133,6 +143,18 @@ func TestTryHandleURL_Basic(t *testing.T) {
 	// TODO: check which requests were made
 }
 
+func TestKeepSpidering(t *testing.T) {
+	w := &Worker{settings: &settings.ScanSettings{
+		SpiderCodes:        settings.CodeRangeFlag{{Min: 200, Max: 299}, {Min: 401, Max: 403}},
+		SpiderExcludeCodes: settings.CodeRangeFlag{{Min: 401, Max: 403}},
+	}}
+	for code, want := range map[int]bool{200: true, 204: true, 301: false, 401: false, 403: false} {
+		if got := w.KeepSpidering(code); got != want {
+			t.Errorf("KeepSpidering(%d) = %v, expected %v", code, got, want)
+		}
+	}
+}
+
 func TestStartWorkers_SingleIteration(t *testing.T) {
 	ss := &settings.ScanSettings{
 		Workers:   2,
@@ -187,3 +209,681 @@ func TestSetPageWorker(t *testing.T) {
 		t.Fatalf("Pageworker not properly set.")
 	}
 }
+
+type readingPageWorker struct {
+	body string
+}
+
+func (*readingPageWorker) Eligible(_ *http.Response) bool {
+	return true
+}
+
+func (pw *readingPageWorker) Handle(_ *task.Task, body io.Reader, _ *results.Result) {
+	buf, _ := ioutil.ReadAll(body)
+	pw.body = string(buf)
+}
+
+func TestRunPageWorkers_Multiple(t *testing.T) {
+	first, second := &readingPageWorker{}, &readingPageWorker{}
+	w := &Worker{}
+	w.SetPageWorker(first)
+	w.AddPageWorker(second)
+	u, _ := url.Parse("http://localhost/")
+	tsk := task.NewTaskFromURL(u)
+	w.runPageWorkers(tsk, &http.Response{}, strings.NewReader("body"), results.NewResultForTask(tsk))
+	if first.body != "body" || second.body != "body" {
+		t.Errorf("Expected both page workers to read body, got %q and %q", first.body, second.body)
+	}
+}
+
+func TestSleepDuration_Jitter(t *testing.T) {
+	ss := &settings.ScanSettings{
+		SleepTime: time.Millisecond,
+		Jitter:    time.Millisecond,
+	}
+	w := &Worker{settings: ss}
+	for i := 0; i < 10; i++ {
+		d := w.sleepDuration()
+		if d < ss.SleepTime || d >= ss.SleepTime+ss.Jitter {
+			t.Errorf("Sleep duration out of range: %v", d)
+		}
+	}
+}
+
+func TestFollowRedirect(t *testing.T) {
+	parse := func(s string) *http.Request {
+		u, _ := url.Parse(s)
+		return &http.Request{URL: u}
+	}
+	orig := parse("http://localhost/a")
+	same := parse("https://localhost/b")
+	other := parse("http://example.com/")
+	cases := []struct {
+		policy settings.RedirectPolicyOption
+		req    *http.Request
+		follow bool
+	}{
+		{settings.RedirectNever, same, false},
+		{settings.RedirectSameHost, same, true},
+		{settings.RedirectSameHost, other, false},
+		{settings.RedirectAll, other, true},
+	}
+	for _, c := range cases {
+		w := &Worker{settings: &settings.ScanSettings{RedirectPolicy: c.policy, MaxRedirects: 1}}
+		if w.followRedirect(c.req, []*http.Request{orig}) != c.follow {
+			t.Errorf("Policy %s, %s: expected follow=%v", c.policy.String(), c.req.URL, c.follow)
+		}
+	}
+	w := &Worker{settings: &settings.ScanSettings{RedirectPolicy: settings.RedirectAll, MaxRedirects: 1}}
+	if w.followRedirect(other, []*http.Request{orig, same}) {
+		t.Error("Expected redirect limit to stop following.")
+	}
+}
+
+func TestFollowRedirect_Loop(t *testing.T) {
+	u, _ := url.Parse("http://localhost/a")
+	w := &Worker{settings: &settings.ScanSettings{RedirectPolicy: settings.RedirectAll, MaxRedirects: 10}}
+	if w.followRedirect(&http.Request{URL: u}, []*http.Request{&http.Request{URL: u}}) {
+		t.Error("Expected redirect loop not to be followed.")
+	}
+	if !w.redirLoop {
+		t.Error("Expected redirect loop to be recorded.")
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	resp := mock.ResponseFromString("")
+	resp.StatusCode = 404
+	client := &mock.MockClient{
+		ForeverResponse: resp,
+	}
+	rchan := make(chan *results.Result, 10)
+	w := &Worker{
+		client:     client,
+		settings:   &settings.ScanSettings{},
+		rchan:      rchan,
+		calibrator: NewCalibrator(2, 0),
+	}
+	u := &url.URL{Scheme: "http", Host: "localhost", Path: "/a/b"}
+	w.calibrate(task.NewTaskFromURL(u))
+	w.calibrate(task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/a/c"}))
+	close(rchan)
+	if len(client.Requests) != 2 {
+		t.Fatalf("Expected 2 calibration requests, got %d", len(client.Requests))
+	}
+	if client.Requests[0].Path == client.Requests[1].Path {
+		t.Error("Expected calibration paths to differ.")
+	}
+	for r := range rchan {
+		if !r.Baseline {
+			t.Errorf("Expected baseline result for %s", r.URL.String())
+		}
+		if !strings.HasPrefix(r.URL.Path, "/a/") || len(r.URL.Path) != 3+calibrationNameLength {
+			t.Errorf("Unexpected calibration path: %s", r.URL.Path)
+		}
+	}
+	if w.calibrator.needsCalibration("http://localhost/a/") {
+		t.Error("Expected directory to remain calibrated without refresh.")
+	}
+}
+
+func TestHandleTask_External(t *testing.T) {
+	resp := mock.ResponseFromString("<a href='/other'>x</a>")
+	resp.StatusCode = http.StatusNotFound
+	client := &mock.MockClient{NextResponse: resp}
+	rchan := make(chan *results.Result, 1)
+	added := 0
+	w := &Worker{
+		client:   client,
+		settings: &settings.ScanSettings{Mangle: true},
+		rchan:    rchan,
+		adder:    func(tasks ...*task.Task) { added += len(tasks) },
+		done:     noopInt,
+	}
+	w.SetPageWorker(NewHTMLWorker(workqueue.SpiderFunc(w.adder)))
+	tk := task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "example.com", Path: "/page"})
+	tk.Referrer = &url.URL{Scheme: "http", Host: "localhost", Path: "/"}
+	tk.External = true
+	w.HandleTask(tk)
+	if len(client.Methods) != 1 || client.Methods[0] != http.MethodHead {
+		t.Errorf("Expected a single HEAD request, got %v", client.Methods)
+	}
+	if added != 0 {
+		t.Errorf("Expected no spidering, got %d tasks", added)
+	}
+	result := <-rchan
+	if result.Code != http.StatusNotFound || result.Referrer == nil || result.Referrer.Host != "localhost" {
+		t.Errorf("Unexpected result: %v (referrer %v)", result, result.Referrer)
+	}
+}
+
+func TestIsFullWildcard(t *testing.T) {
+	sample := func(code int, hash string) *results.Result {
+		return &results.Result{Code: code, BodyHash: hash}
+	}
+	cases := []struct {
+		samples  []*results.Result
+		wildcard bool
+	}{
+		{[]*results.Result{sample(200, "a"), sample(200, "a"), sample(200, "a")}, true},
+		{[]*results.Result{sample(200, "a")}, false},
+		{[]*results.Result{sample(200, "a"), sample(200, "b")}, false},
+		{[]*results.Result{sample(200, "a"), sample(302, "a")}, false},
+		{[]*results.Result{sample(404, "a"), sample(404, "a")}, false},
+		{[]*results.Result{sample(200, ""), sample(200, "")}, false},
+	}
+	for i, c := range cases {
+		if got := isFullWildcard(c.samples); got != c.wildcard {
+			t.Errorf("Case %d: expected %v, got %v", i, c.wildcard, got)
+		}
+	}
+}
+
+func TestHandleTask_Wildcard(t *testing.T) {
+	reg := hosts.NewRegistry()
+	reg.Get(&url.URL{Scheme: "http", Host: "localhost"}).SetWildcard("/")
+	client := &mock.MockClient{}
+	done := 0
+	w := &Worker{
+		client:   client,
+		settings: &settings.ScanSettings{},
+		hosts:    reg,
+		done:     func(n int) { done += n },
+	}
+	w.HandleTask(task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/admin"}))
+	if len(client.Requests) != 0 {
+		t.Errorf("Expected no requests, got %v", client.Requests)
+	}
+	if done != 1 {
+		t.Errorf("Expected the task to be done, got %d", done)
+	}
+	if n := reg.Get(&url.URL{Scheme: "http", Host: "localhost"}).WildcardSkipped(); n != 1 {
+		t.Errorf("Expected 1 skipped path, got %d", n)
+	}
+}
+
+func TestHandleBody_Confidence(t *testing.T) {
+	cases := []struct {
+		path       string
+		body       string
+		confidence results.Confidence
+	}{
+		{"/backup.zip", "PK\x03\x04rest", results.ConfidenceVerified},
+		{"/backup.zip", "<html>Not Found</html>", results.ConfidenceContradicted},
+		{"/notes.txt", "plain notes", results.ConfidenceContent},
+		{"/index", "anything", results.ConfidenceStatus},
+	}
+	for _, c := range cases {
+		resp := mock.ResponseFromString(c.body)
+		resp.StatusCode = 200
+		w := &Worker{settings: &settings.ScanSettings{}}
+		tsk := task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: c.path})
+		result := results.NewResultForTask(tsk)
+		w.handleBody(tsk, resp, result)
+		if result.Confidence != c.confidence {
+			t.Errorf("%s with %q: expected %s, got %s", c.path, c.body, c.confidence, result.Confidence)
+		}
+	}
+}
+
+func TestHandleBody_Hash(t *testing.T) {
+	resp := mock.ResponseFromString("hello")
+	resp.StatusCode = 200
+	w := &Worker{
+		settings: &settings.ScanSettings{HashBodies: true},
+	}
+	u := task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/"})
+	result := results.NewResultForTask(u)
+	w.handleBody(u, resp, result)
+	expected := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	if result.BodyHash != expected {
+		t.Errorf("Expected hash %s, got %s", expected, result.BodyHash)
+	}
+}
+
+type trackingBody struct {
+	io.Reader
+	read   bool
+	closed bool
+}
+
+func (b *trackingBody) Read(p []byte) (int, error) {
+	b.read = true
+	return b.Reader.Read(p)
+}
+
+func (b *trackingBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestTryTask_HeadersOnly(t *testing.T) {
+	body := &trackingBody{Reader: strings.NewReader("<a href='/x'>x</a>")}
+	resp := &http.Response{
+		StatusCode:    200,
+		Header:        http.Header{"Content-Type": []string{"text/html"}},
+		ContentLength: -1,
+		Body:          body,
+	}
+	added := 0
+	rchan := make(chan *results.Result, 1)
+	w := &Worker{
+		client:   &mock.MockClient{NextResponse: resp},
+		settings: &settings.ScanSettings{HeadersOnly: true, HashBodies: true},
+		rchan:    rchan,
+		adder:    func(t ...*task.Task) { added += len(t) },
+	}
+	w.SetPageWorker(NewHTMLWorker(workqueue.SpiderFunc(w.adder)))
+	w.TryTask(task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/page"}))
+	result := <-rchan
+	if result.Code != 200 {
+		t.Errorf("Expected 200, got %d", result.Code)
+	}
+	if body.read || !body.closed {
+		t.Errorf("Expected body to be closed unread, read: %v, closed: %v", body.read, body.closed)
+	}
+	if added != 0 || result.BodyHash != "" {
+		t.Error("Expected no body processing.")
+	}
+}
+
+func TestWorker_SetSpider(t *testing.T) {
+	added, discovered := 0, 0
+	w := &Worker{adder: func(t ...*task.Task) { added += len(t) }}
+	html := NewHTMLWorker(nil)
+	w.SetPageWorker(html)
+	w.SetSpider(func(t ...*task.Task) { discovered += len(t) })
+	w.redir = &http.Request{URL: &url.URL{Scheme: "http", Host: "localhost", Path: "/next"}}
+	w.spiderRedirect(task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/"}))
+	html.spider(task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/link"}))
+	if discovered != 2 || added != 0 {
+		t.Errorf("Expected discovered work to go to spider, got %d discovered and %d added.", discovered, added)
+	}
+}
+
+func TestIsUpgrade(t *testing.T) {
+	from, _ := url.Parse("http://example.com/foo")
+	cases := []struct {
+		to      string
+		upgrade bool
+	}{
+		{"https://example.com/foo", true},
+		{"https://EXAMPLE.com:8443/foo/", true},
+		{"https://example.com/", false},
+		{"https://www.example.com/foo", false},
+		{"http://example.com/foo/", false},
+	}
+	for _, c := range cases {
+		to, _ := url.Parse(c.to)
+		if isUpgrade(from, to) != c.upgrade {
+			t.Errorf("%s: expected upgrade %v", c.to, c.upgrade)
+		}
+	}
+	if isUpgrade(from, nil) {
+		t.Error("Expected no upgrade without a redirect.")
+	}
+}
+
+func TestRequestOptions(t *testing.T) {
+	ss := &settings.ScanSettings{Method: "GET"}
+	ss.AddTarget(&settings.Target{
+		URL:    &url.URL{Scheme: "http", Host: "localhost", Path: "/api/"},
+		Method: "POST",
+		Header: settings.HeaderFlag{"X-Api-Key": []string{"secret"}},
+	})
+	w := &Worker{settings: ss}
+	tsk := task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/api/users"})
+	tsk.Header = http.Header{"Accept": []string{"*/*"}}
+	method, header := w.requestOptions(tsk)
+	if method != "POST" {
+		t.Errorf("Expected POST, got %s", method)
+	}
+	if header.Get("X-Api-Key") != "secret" || header.Get("Accept") != "*/*" {
+		t.Errorf("Unexpected headers: %v", header)
+	}
+	if tsk.Header.Get("X-Api-Key") != "" {
+		t.Errorf("Task headers should not be modified.")
+	}
+	tsk = task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/other"})
+	if method, _ := w.requestOptions(tsk); method != "GET" {
+		t.Errorf("Expected GET outside target, got %s", method)
+	}
+}
+
+func TestRequestOptions_Template(t *testing.T) {
+	w := &Worker{settings: &settings.ScanSettings{Method: "GET"}}
+	tsk := task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/a b"})
+	tsk.Header = http.Header{
+		"X-Request-Id": []string{"{{uuid}}"},
+		"X-Path":       []string{"path={{path}} {{unknown}}"},
+		"Accept":       []string{"*/*"},
+	}
+	_, first := w.requestOptions(tsk)
+	_, second := w.requestOptions(tsk)
+	if id := first.Get("X-Request-Id"); len(id) != 36 || id == second.Get("X-Request-Id") {
+		t.Errorf("Expected a new UUID for each request, got %q and %q", id, second.Get("X-Request-Id"))
+	}
+	if p := first.Get("X-Path"); p != "path=/a%20b {{unknown}}" {
+		t.Errorf("Unexpected X-Path: %q", p)
+	}
+	if first.Get("Accept") != "*/*" {
+		t.Errorf("Expected other headers to be kept, got %v", first)
+	}
+	if tsk.Header.Get("X-Request-Id") != "{{uuid}}" {
+		t.Errorf("Task headers should not be modified.")
+	}
+}
+
+func TestAddForwardedHeaders(t *testing.T) {
+	ss := &settings.ScanSettings{ForwardedHost: true, ForwardedFor: "127.0.0.1"}
+	w := &Worker{settings: ss}
+	shared := http.Header{"Accept": []string{"*/*"}}
+	tsk := task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/"})
+	tsk.Header = shared
+	w.addForwardedHeaders(tsk)
+	if tsk.Header.Get("X-Forwarded-Host") != "localhost" || tsk.Header.Get("X-Forwarded-For") != "127.0.0.1" {
+		t.Errorf("Unexpected headers: %v", tsk.Header)
+	}
+	if tsk.Header.Get("Accept") != "*/*" || len(shared) != 1 {
+		t.Errorf("Shared headers should be copied, not modified: %v", shared)
+	}
+	tsk = task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/"})
+	tsk.Host = "internal.example"
+	w.addForwardedHeaders(tsk)
+	if tsk.Header.Get("X-Forwarded-Host") != "internal.example" {
+		t.Errorf("Expected the Host override, got %v", tsk.Header)
+	}
+	if r := results.NewResultForTask(tsk); r.RequestHeader.Get("X-Forwarded-Host") != "internal.example" {
+		t.Errorf("Expected headers recorded in result, got %v", r.RequestHeader)
+	}
+}
+
+// Serve bodies of size bytes, counting the connections made.
+func newBenchServer(size int) (*httptest.Server, *int64) {
+	body := strings.Repeat("x", size)
+	var conns int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&conns, 1)
+		}
+	}
+	server.Start()
+	return server, &conns
+}
+
+func BenchmarkTryTask(b *testing.B) {
+	for _, size := range []int{512, 16 * 1024, 256 * 1024} {
+		b.Run(fmt.Sprintf("%dB", size), func(b *testing.B) {
+			server, conns := newBenchServer(size)
+			defer server.Close()
+			factory, _ := client.NewProxyClientFactory(nil, 5*time.Second, "")
+			rchan := make(chan *results.Result, 1)
+			w := NewWorker(&settings.ScanSettings{Method: "GET"}, factory, nil, noopUrl, noopInt, rchan)
+			u, _ := url.Parse(server.URL + "/page")
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				w.TryTask(task.NewTaskFromURL(u))
+				<-rchan
+			}
+			b.ReportMetric(float64(atomic.LoadInt64(conns))/float64(b.N), "conns/op")
+		})
+	}
+}
+
+// Compare closing response bodies unread with draining them first.
+func BenchmarkResponseClose(b *testing.B) {
+	for _, drain := range []bool{false, true} {
+		name := "close"
+		if drain {
+			name = "drain"
+		}
+		b.Run(name, func(b *testing.B) {
+			server, conns := newBenchServer(4096)
+			defer server.Close()
+			cli := &http.Client{Transport: &http.Transport{}}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				resp, err := cli.Get(server.URL)
+				if err != nil {
+					b.Fatalf("Unexpected error: %s", err)
+				}
+				if drain {
+					util.DrainBody(resp.Body)
+				} else {
+					resp.Body.Close()
+				}
+			}
+			b.ReportMetric(float64(atomic.LoadInt64(conns))/float64(b.N), "conns/op")
+		})
+	}
+}
+
+func TestTryTask_Timings(t *testing.T) {
+	rchan := make(chan *results.Result, 1)
+	w := &Worker{
+		client:   &mock.MockClient{NextResponse: mock.ResponseFromString("body")},
+		settings: &settings.ScanSettings{},
+		rchan:    rchan,
+		adder:    noopUrl,
+	}
+	timings := NewTimings()
+	w.SetTimings(timings)
+	w.TryTask(task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/page"}))
+	<-rchan
+	if timings.Requests() != 1 {
+		t.Errorf("Expected 1 request, got %d", timings.Requests())
+	}
+	if len(Stages()) != len(stageNames) {
+		t.Errorf("Stage names don't match stages: %d vs %d", len(stageNames), len(Stages()))
+	}
+}
+
+func TestTryTask_FilterSample(t *testing.T) {
+	rchan := make(chan *results.Result, 1)
+	w := &Worker{
+		client:   &mock.MockClient{NextResponse: mock.ResponseFromString("soft 404 page")},
+		settings: &settings.ScanSettings{FilterRegex: "soft 404"},
+		rchan:    rchan,
+		adder:    noopUrl,
+	}
+	w.TryTask(task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/page"}))
+	if r := <-rchan; string(r.BodySample) != "soft 404 page" {
+		t.Errorf("Expected body sample for filters, got %q", r.BodySample)
+	}
+}
+
+func TestTryTask_Record(t *testing.T) {
+	rchan := make(chan *results.Result, 1)
+	resp := mock.ResponseFromString("hello")
+	resp.StatusCode = 200
+	resp.Header = http.Header{"Content-Type": []string{"text/plain"}}
+	w := &Worker{
+		client:   &mock.MockClient{NextResponse: resp},
+		settings: &settings.ScanSettings{Method: "GET", RecordPath: "evidence"},
+		rchan:    rchan,
+		adder:    noopUrl,
+	}
+	w.TryTask(task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/page"}))
+	e := (<-rchan).Exchange
+	if e == nil {
+		t.Fatal("Expected the exchange to be recorded.")
+	}
+	if e.Method != "GET" || e.URL.Path != "/page" || e.Status != "200 OK" {
+		t.Errorf("Unexpected exchange: %s %v %s", e.Method, e.URL, e.Status)
+	}
+	if string(e.Body) != "hello" || e.Truncated {
+		t.Errorf("Unexpected body: %q, truncated: %v", e.Body, e.Truncated)
+	}
+
+	resp = mock.ResponseFromString("missing")
+	resp.StatusCode = 404
+	w.client = &mock.MockClient{NextResponse: resp}
+	w.settings.RecordHits = true
+	w.TryTask(task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/missing"}))
+	if e := (<-rchan).Exchange; e != nil {
+		t.Error("Expected only hits to be recorded.")
+	}
+}
+
+func TestTryTask_RecordMemoryPressure(t *testing.T) {
+	rchan := make(chan *results.Result, 1)
+	resp := mock.ResponseFromString("hello")
+	resp.StatusCode = 200
+	pressure := &MemoryPressure{}
+	if !pressure.Set(true) || pressure.Set(true) {
+		t.Error("Expected only the first Set to change the pressure.")
+	}
+	w := &Worker{
+		client:   &mock.MockClient{NextResponse: resp},
+		settings: &settings.ScanSettings{Method: "GET", RecordPath: "evidence"},
+		rchan:    rchan,
+		adder:    noopUrl,
+		pressure: pressure,
+	}
+	w.TryTask(task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/page"}))
+	e := (<-rchan).Exchange
+	if e == nil {
+		t.Fatal("Expected the exchange to be recorded.")
+	}
+	if len(e.Body) != 0 || !e.Truncated {
+		t.Errorf("Expected no body under memory pressure, got %q, truncated: %v", e.Body, e.Truncated)
+	}
+}
+
+func TestTryTask_SlowThreshold(t *testing.T) {
+	rchan := make(chan *results.Result, 1)
+	w := &Worker{
+		client:   &mock.MockClient{NextResponse: mock.ResponseFromString("body")},
+		settings: &settings.ScanSettings{SlowThreshold: time.Nanosecond},
+		rchan:    rchan,
+		adder:    noopUrl,
+	}
+	w.TryTask(task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/page"}))
+	r := <-rchan
+	if !r.Slow || r.Duration <= 0 || r.TTFB > r.Duration {
+		t.Errorf("Expected a slow result, got slow: %v, ttfb: %s, duration: %s", r.Slow, r.TTFB, r.Duration)
+	}
+	w.settings.SlowThreshold = time.Hour
+	w.client = &mock.MockClient{NextResponse: mock.ResponseFromString("body")}
+	w.TryTask(task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/page"}))
+	if r := <-rchan; r.Slow {
+		t.Error("Expected a fast result.")
+	}
+}
+
+func TestTryTask_RateLimited(t *testing.T) {
+	resp := mock.ResponseFromString("slow down")
+	resp.StatusCode = http.StatusTooManyRequests
+	resp.Header = http.Header{"Retry-After": []string{"0"}}
+	client := &mock.MockClient{ForeverResponse: resp}
+	rchan := make(chan *results.Result, 1)
+	w := &Worker{
+		client:   client,
+		settings: &settings.ScanSettings{RateLimitRetries: 2},
+		rchan:    rchan,
+		adder:    noopUrl,
+		hosts:    hosts.NewRegistry(),
+	}
+	w.TryTask(task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/page"}))
+	if len(client.Requests) != 3 {
+		t.Errorf("Expected 3 requests, got %d", len(client.Requests))
+	}
+	if r := <-rchan; r.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected the last response reported, got %d", r.Code)
+	}
+	if w.rateLimitRetries != 0 {
+		t.Errorf("Expected retries reset, got %d", w.rateLimitRetries)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		code    int
+		header  string
+		wait    time.Duration
+		limited bool
+	}{
+		{http.StatusOK, "10", 0, false},
+		{http.StatusServiceUnavailable, "", 0, false},
+		{http.StatusServiceUnavailable, "30", 30 * time.Second, true},
+		{http.StatusTooManyRequests, "", -1, true},
+		{http.StatusTooManyRequests, "Wed, 01 Jan 2020 00:01:00 GMT", time.Minute, true},
+		{http.StatusTooManyRequests, "Tue, 31 Dec 2019 00:00:00 GMT", 0, true},
+	}
+	for _, c := range cases {
+		resp := &http.Response{StatusCode: c.code, Header: http.Header{}}
+		if c.header != "" {
+			resp.Header.Set("Retry-After", c.header)
+		}
+		wait, limited := retryAfter(resp, now)
+		if wait != c.wait || limited != c.limited {
+			t.Errorf("Expected %s, %v for %d %q, got %s, %v", c.wait, c.limited, c.code, c.header, wait, limited)
+		}
+	}
+}
+
+func TestTryTask_AssetCache(t *testing.T) {
+	resp := mock.ResponseFromString("var a = 1;")
+	resp.StatusCode = http.StatusOK
+	resp.Header = http.Header{"Content-Type": []string{"application/javascript"}}
+	client := &mock.MockClient{NextResponse: resp}
+	rchan := make(chan *results.Result, 1)
+	w := &Worker{
+		client:   client,
+		settings: &settings.ScanSettings{Method: "GET"},
+		rchan:    rchan,
+		adder:    noopUrl,
+		assets:   NewAssetCache(),
+	}
+	w.TryTask(task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/static/app.js", RawQuery: "v=1"}))
+	<-rchan
+	code := w.TryTask(task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost:80", Path: "/static/../static/app.js", RawQuery: "v=2"}))
+	if len(client.Requests) != 1 {
+		t.Errorf("Expected the asset fetched once, got %d requests", len(client.Requests))
+	}
+	r := <-rchan
+	if code != http.StatusOK || r.Code != http.StatusOK || r.ContentType != "application/javascript" {
+		t.Errorf("Expected the cached response, got %d %s", r.Code, r.ContentType)
+	}
+	if r.URL.RawQuery != "v=2" || len(r.Notes) != 1 {
+		t.Errorf("Expected a noted result for the second URL, got %s %v", r.URL, r.Notes)
+	}
+	if _, ok := w.fromAssetCache(task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/page"}), "GET"); ok {
+		t.Error("Expected pages not to be cached.")
+	}
+}
+
+func TestTryTask_NotModified(t *testing.T) {
+	previous, err := results.ParseComparison(strings.NewReader(
+		`{"url": "http://localhost/app.zip", "code": 200, "length": 42, "content_type": "application/zip", "etag": "\"abc\""}` + "\n" +
+			`{"url": "http://localhost/index.html", "code": 200, "length": 10, "content_type": "text/html", "etag": "\"def\""}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := mock.ResponseFromString("")
+	resp.StatusCode = http.StatusNotModified
+	rchan := make(chan *results.Result, 1)
+	w := &Worker{
+		client:   &mock.MockClient{NextResponse: resp},
+		settings: &settings.ScanSettings{},
+		rchan:    rchan,
+		adder:    noopUrl,
+		previous: previous,
+	}
+	code := w.TryTask(task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/app.zip"}))
+	r := <-rchan
+	if got := r.RequestHeader.Get("If-None-Match"); got != `"abc"` {
+		t.Errorf("Expected If-None-Match sent, got %q", got)
+	}
+	if code != 200 || !r.NotModified || r.Code != 200 || r.Length != 42 || r.ETag != `"abc"` {
+		t.Errorf("Expected the previous result, got %d %d %d %s", code, r.Code, r.Length, r.ETag)
+	}
+	header, prev := w.conditionalHeader(task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/index.html"}), nil)
+	if prev != nil || header.Get("If-None-Match") != "" {
+		t.Error("Expected HTML pages fetched in full.")
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workqueue
+
+import (
+	"encoding/json"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/task"
+	"io"
+	"net/http"
+	"os"
+)
+
+// A single pending task as written in a queue snapshot.
+type snapshotEntry struct {
+	Position   int             `json:"position"`
+	URL        string          `json:"url"`
+	Host       string          `json:"host,omitempty"`
+	Header     http.Header     `json:"header,omitempty"`
+	Referrer   string          `json:"referrer,omitempty"`
+	External   bool            `json:"external,omitempty"`
+	Provenance task.Provenance `json:"provenance"`
+	ParentURL  string          `json:"parent_url,omitempty"`
+	Depth      int             `json:"depth"`
+}
+
+// Get a copy of the tasks currently waiting in the queue, including those
+// buffered for the workers, in the order they will be handed out.  Returns nil if the queue is not running.
+func (q *WorkQueue) Snapshot() []*task.Task {
+	c := make(chan []*task.Task, 1)
+	select {
+	case q.snapshotReq <- c:
+		return <-c
+	case <-q.stopped:
+		return nil
+	}
+}
+
+// Write a snapshot of the pending queue as JSON lines.
+func (q *WorkQueue) WriteSnapshot(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for i, t := range q.Snapshot() {
+		entry := snapshotEntry{
+			Position:   i,
+			URL:        t.URL.String(),
+			Host:       t.Host,
+			Header:     t.Header,
+			External:   t.External,
+			Provenance: t.Provenance,
+			Depth:      t.Depth,
+		}
+		if t.Referrer != nil {
+			entry.Referrer = t.Referrer.String()
+		}
+		if t.ParentURL != nil {
+			entry.ParentURL = t.ParentURL.String()
+		}
+		if err := enc.Encode(&entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write a snapshot of the pending queue to the file at path.
+func (q *WorkQueue) DumpSnapshot(path string) error {
+	fp, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	if err := q.WriteSnapshot(fp); err != nil {
+		return err
+	}
+	logging.Logf(logging.LogInfo, "Wrote queue snapshot to %s.", path)
+	return nil
+}
+
+// Copy the pending tasks.  Must only be called from the queue goroutine.
+func (q *WorkQueue) pending() []*task.Task {
+	q.unbuffer()
+	tasks := make([]*task.Task, 0, q.queueLen)
+	for node := q.head; node != nil; node = node.next {
+		tasks = append(tasks, node.data.Copy())
+	}
+	return tasks
+}
+
+// Move tasks buffered in the work channel back to the front of the queue, so
+// they can be seen.  They are still handed out first.  Must only be called
+// from the queue goroutine, as the only sender on the work channel.
+func (q *WorkQueue) unbuffer() {
+	var head, tail *queueNode
+	n := 0
+	for len(q.dst) > 0 {
+		var t *task.Task
+		select {
+		case t = <-q.dst:
+		default:
+		}
+		if t == nil {
+			// Taken by a worker meanwhile
+			break
+		}
+		node := &queueNode{data: t}
+		if tail != nil {
+			tail.next = node
+		} else {
+			head = node
+		}
+		tail = node
+		n++
+	}
+	if head == nil {
+		return
+	}
+	tail.next = q.head
+	if q.tail == nil {
+		q.tail = tail
+	}
+	q.head = head
+	q.queueLen += n
+}
@@ -0,0 +1,91 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workqueue
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/Matir/webborer/task"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorkqueue_Snapshot(t *testing.T) {
+	queue := NewWorkQueue(5, nil, false)
+	queue.filter = func(_ *task.Task) bool { return true }
+	queue.RunInBackground()
+	// Nothing reads the work channel, so 5 are buffered and the rest queued.
+	parent := &url.URL{Scheme: "http", Host: "localhost", Path: "/"}
+	for i := 0; i < 10; i++ {
+		u := &url.URL{Scheme: "http", Host: "localhost", Path: fmt.Sprintf("/%d", i)}
+		tk := task.NewTaskFromURL(u)
+		tk.Provenance = task.NewProvenance(task.OriginWordlist, parent, fmt.Sprintf("%d", i))
+		tk.ParentURL = parent
+		tk.Depth = 1
+		queue.AddTasks(tk)
+	}
+	// Wait for the queue to take all of the tasks.
+	var snap []*task.Task
+	for i := 0; i < 100; i++ {
+		if snap = queue.Snapshot(); len(snap) == 10 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if len(snap) != 10 {
+		t.Fatalf("Expected 10 pending tasks in snapshot, got %d.", len(snap))
+	}
+	for i, tk := range snap {
+		if want := fmt.Sprintf("/%d", i); tk.URL.Path != want {
+			t.Errorf("Expected %s at position %d, got %s", want, i, tk.URL.Path)
+		}
+	}
+	buf := &bytes.Buffer{}
+	if err := queue.WriteSnapshot(buf); err != nil {
+		t.Fatalf("Error writing snapshot: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(snap) {
+		t.Errorf("Expected %d lines, got %d", len(snap), len(lines))
+	}
+	entry := snapshotEntry{}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Errorf("Unable to decode snapshot entry: %v", err)
+	}
+	if entry.URL != snap[0].URL.String() {
+		t.Errorf("Expected %s, got %s", snap[0].URL.String(), entry.URL)
+	}
+	if entry.Provenance.Detail != "0" || entry.ParentURL != parent.String() || entry.Depth != 1 {
+		t.Errorf("Expected provenance, parent and depth, got %+v", entry)
+	}
+	// Buffered tasks are still handed out first.
+	if tk := <-queue.GetWorkChan(); tk.URL.Path != "/0" {
+		t.Errorf("Expected /0 first, got %s", tk.URL.Path)
+	}
+}
+
+func TestWorkqueue_SnapshotStopped(t *testing.T) {
+	queue := NewWorkQueue(5, nil, false)
+	queue.RunInBackground()
+	queue.InputFinished()
+	for range queue.GetWorkChan() {
+	}
+	if snap := queue.Snapshot(); snap != nil {
+		t.Errorf("Expected nil snapshot from stopped queue, got %v", snap)
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workqueue
+
+import (
+	"github.com/Matir/webborer/task"
+)
+
+// A SpiderFunc adds tasks discovered while crawling: links found on pages,
+// redirects, and the directories containing them.  They are kept apart from
+// the work a scan generates itself, like wordlist expansion and mangling, so
+// each run mode can apply its own policy to what is discovered.
+type SpiderFunc func(...*task.Task)
+
+// Get the function for adding discovered tasks, which are checked against the
+// spider policy rather than the scope before being queued.
+func (q *WorkQueue) GetSpiderFunc() SpiderFunc {
+	return func(tasks ...*task.Task) {
+		q.ctr.Add(int64(len(tasks)))
+		for _, t := range tasks {
+			q.spider <- t
+		}
+	}
+}
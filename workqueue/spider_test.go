@@ -0,0 +1,45 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workqueue
+
+import (
+	"github.com/Matir/webborer/task"
+	"net/url"
+	"testing"
+)
+
+func TestWorkqueue_SpiderFunc(t *testing.T) {
+	scope, _ := url.Parse("http://localhost/foo/")
+	queue := NewWorkQueue(5, []*url.URL{scope}, false)
+	queue.AcceptExternalLinks()
+	queue.RunInBackground()
+	spider := queue.GetSpiderFunc()
+	external, _ := url.Parse("http://example.com/")
+	linked := task.NewTaskFromURL(external)
+	linked.Referrer, _ = url.Parse("http://localhost/foo/bar")
+	unlinked := task.NewTaskFromURL(external)
+	spider(linked, unlinked)
+	out := queue.GetWorkChan()
+	if o := <-out; o != linked || !o.External {
+		t.Errorf("Expected discovered external link to be queued as external.")
+	}
+	queue.ctr.Done(1)
+	// The unlinked task is rejected and marked done by the queue.
+	queue.WaitPipe()
+	queue.InputFinished()
+	if _, ok := <-out; ok {
+		t.Errorf("Expected no more work after discovered tasks.")
+	}
+}
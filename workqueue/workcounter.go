@@ -46,11 +46,9 @@ func (ctr *WorkCounter) Done(done int64) {
 		panic("Done exceeded todo in WorkCounter!")
 	}
 	if ctr.done == ctr.todo {
-		// Mark done
+		// Mark done.  Waiters use the counter's own lock as the sync.Cond
+		// lock, so they can't miss this.
 		logging.Logf(logging.LogInfo, "Work counter thinks we're done.")
-		// These are part of the sync.Cond
-		ctr.L.Lock()
-		defer ctr.L.Unlock()
 		ctr.Broadcast()
 	}
 }
@@ -63,6 +61,23 @@ func (ctr *WorkCounter) Stats() {
 	}
 }
 
+// Wait until all of the work added so far is done.  The sync.Cond lock must
+// be the counter's own.
+func (ctr *WorkCounter) WaitDone() {
+	ctr.Lock()
+	defer ctr.Unlock()
+	for ctr.done != ctr.todo {
+		ctr.Wait()
+	}
+}
+
+// Get the count of work done and the total to be done so far.
+func (ctr *WorkCounter) Counts() (done, total int64) {
+	ctr.Lock()
+	defer ctr.Unlock()
+	return ctr.done, ctr.todo
+}
+
 // Set the status callback for this workcounter
 func (ctr *WorkCounter) SetStatusCallback(f func(int64, int64)) {
 	ctr.doneCb = f
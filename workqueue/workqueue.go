@@ -28,7 +28,9 @@ import (
 
 // WorkQueue is a singleton that maintains the queue of work to be done.
 // It reads from one input channel, verifies that the URL is in scope,
-// queues it, then writes it to the work channel to be done.
+// queues it, then writes it to the work channel to be done.  Tasks discovered
+// by crawling arrive on a separate channel, and pass the spider policy
+// instead of the plain scope check.
 // Internally, it implements a singly-linked list.
 type WorkQueue struct {
 	// Elements to be worked on
@@ -43,10 +45,20 @@ type WorkQueue struct {
 	dst chan *task.Task
 	// filter to determine if a URL should be processed
 	filter func(*task.Task) bool
+	// URLs in scope, which filter checks by default
+	scope *scopeSet
+	// Channel for tasks discovered by crawling
+	spider chan *task.Task
+	// Policy for discovered tasks, which is the scope by default
+	spiderFilter func(*task.Task) bool
 	// channel to track done
 	started chan bool
 	// counter of work being done
 	ctr WorkCounter
+	// requests for a snapshot of the pending queue
+	snapshotReq chan chan []*task.Task
+	// closed when the queue stops running
+	stopped chan bool
 }
 
 type queueNode struct {
@@ -60,14 +72,27 @@ type QueueAddFunc func(...*task.Task)
 type QueueAddCount func(int)
 type QueueDoneFunc func(int)
 
+// Set of scopes that URLs must be within to be queued
+type scopeSet struct {
+	urls          []*url.URL
+	allowUpgrades bool
+	sync.RWMutex
+}
+
 func NewWorkQueue(queueSize int, scope []*url.URL, allowUpgrades bool) *WorkQueue {
+	scopes := newScopeSet(scope, allowUpgrades)
 	q := &WorkQueue{
-		src:     make(chan *task.Task, queueSize),
-		dst:     make(chan *task.Task, queueSize),
-		filter:  makeScopeFunc(scope, allowUpgrades),
-		started: make(chan bool, 1),
+		src:          make(chan *task.Task, queueSize),
+		dst:          make(chan *task.Task, queueSize),
+		filter:       scopes.contains,
+		scope:        scopes,
+		spider:       make(chan *task.Task, queueSize),
+		spiderFilter: scopes.contains,
+		started:      make(chan bool, 1),
+		snapshotReq:  make(chan chan []*task.Task),
+		stopped:      make(chan bool),
 	}
-	q.ctr.L = &sync.Mutex{}
+	q.ctr.L = &q.ctr.Mutex
 	return q
 }
 
@@ -78,7 +103,30 @@ func (q *WorkQueue) AddTasks(tasks ...*task.Task) {
 	}
 }
 
+// Add a scope while the queue is running.  Tasks already rejected as out of
+// scope are not reconsidered.
+func (q *WorkQueue) AddScope(u *url.URL) {
+	q.scope.add(u)
+}
+
+// Also accept discovered links from pages in scope to pages outside it,
+// marking them external so they are checked but not spidered.  Must be called
+// before the queue is run.
+func (q *WorkQueue) AcceptExternalLinks() {
+	q.spiderFilter = func(t *task.Task) bool {
+		if q.scope.contains(t) {
+			return true
+		}
+		if t.Referrer == nil || !q.scope.contains(&task.Task{URL: t.Referrer}) {
+			return false
+		}
+		t.External = true
+		return true
+	}
+}
+
 func (q *WorkQueue) InputFinished() {
+	close(q.spider)
 	close(q.src)
 }
 
@@ -87,6 +135,7 @@ func (q *WorkQueue) GetWorkChan() <-chan *task.Task {
 }
 
 func (q *WorkQueue) Run() {
+	defer close(q.stopped)
 	defer close(q.dst)
 
 	q.started <- true
@@ -113,16 +162,40 @@ func (q *WorkQueue) runStep() bool {
 			} else {
 				q.reject(u)
 			}
+		case u, ok := <-q.spider:
+			if !ok {
+				q.spider = nil
+			} else if q.spiderFilter(u) {
+				q.push(u)
+			} else {
+				q.reject(u)
+			}
 		case q.dst <- q.peek():
 			q.pop()
+		case c := <-q.snapshotReq:
+			c <- q.pending()
 		}
 	} else {
 		// Blocking read and non-blocking send
-		u, ok := <-q.src
-		if !ok {
-			return false
+		var u *task.Task
+		var ok bool
+		filter := q.filter
+		select {
+		case u, ok = <-q.src:
+			if !ok {
+				return false
+			}
+		case u, ok = <-q.spider:
+			if !ok {
+				q.spider = nil
+				return true
+			}
+			filter = q.spiderFilter
+		case c := <-q.snapshotReq:
+			c <- q.pending()
+			return true
 		}
-		if !q.filter(u) {
+		if !filter(u) {
 			q.reject(u)
 			return true
 		}
@@ -141,12 +214,7 @@ func (q *WorkQueue) RunInBackground() {
 
 func (q *WorkQueue) WaitPipe() {
 	<-q.started
-	q.ctr.L.Lock()
-	if q.ctr.todo == q.ctr.done {
-		q.ctr.L.Unlock()
-		return
-	}
-	q.ctr.Wait()
+	q.ctr.WaitDone()
 }
 
 func (q *WorkQueue) GetAddFunc() QueueAddFunc {
@@ -177,7 +245,11 @@ func (q *WorkQueue) SeedFromRobots(scope []*url.URL, clientFactory client.Client
 				pathURL := *scopeURL
 				pathURL.Path = path
 				// Filter will handle if this is out of scope
-				q.AddTasks(task.NewTaskFromURL(scopeURL.ResolveReference(&pathURL)))
+				t := task.NewTaskFromURL(scopeURL.ResolveReference(&pathURL))
+				t.Provenance = task.NewProvenance(task.OriginRobots, scopeURL, "")
+				t.ParentURL = scopeURL
+				t.Depth = 1
+				q.AddTasks(t)
 			}
 		}
 	}
@@ -229,24 +301,37 @@ func (q *WorkQueue) GetCounter() *WorkCounter {
 
 // Build a function to check if the target URL is in scope.
 func makeScopeFunc(scope []*url.URL, allowUpgrades bool) func(*task.Task) bool {
-	allowedScopes := make([]*url.URL, len(scope))
-	copy(allowedScopes, scope)
-	if allowUpgrades {
-		for _, scopeURL := range scope {
-			if scopeURL.Scheme == "http" {
-				deref := *scopeURL
-				clone := &deref // Can't find a way to do this in one statement
-				clone.Scheme = "https"
-				allowedScopes = append(allowedScopes, clone)
-			}
-		}
+	return newScopeSet(scope, allowUpgrades).contains
+}
+
+func newScopeSet(scope []*url.URL, allowUpgrades bool) *scopeSet {
+	s := &scopeSet{allowUpgrades: allowUpgrades}
+	for _, scopeURL := range scope {
+		s.add(scopeURL)
 	}
-	return func(target *task.Task) bool {
-		for _, scopeURL := range allowedScopes {
-			if util.URLIsSubpath(scopeURL, target.URL) {
-				return true
-			}
+	return s
+}
+
+// Add a scope, and its https equivalent if upgrades are allowed.
+func (s *scopeSet) add(scopeURL *url.URL) {
+	s.Lock()
+	defer s.Unlock()
+	s.urls = append(s.urls, scopeURL)
+	if s.allowUpgrades && scopeURL.Scheme == "http" {
+		deref := *scopeURL
+		clone := &deref // Can't find a way to do this in one statement
+		clone.Scheme = "https"
+		s.urls = append(s.urls, clone)
+	}
+}
+
+func (s *scopeSet) contains(target *task.Task) bool {
+	s.RLock()
+	defer s.RUnlock()
+	for _, scopeURL := range s.urls {
+		if util.URLIsSubpath(scopeURL, target.URL) {
+			return true
 		}
-		return false
 	}
+	return false
 }
@@ -110,6 +110,50 @@ func TestWorkqueue_Funcs(_ *testing.T) {
 	queue.GetDoneFunc()
 }
 
+func TestWorkqueue_AddScope(t *testing.T) {
+	queue := NewWorkQueue(5, nil, true)
+	u, _ := url.Parse("http://localhost/foo/bar")
+	if queue.filter(task.NewTaskFromURL(u)) {
+		t.Errorf("Expected URL to be out of scope.")
+	}
+	scope, _ := url.Parse("http://localhost/foo")
+	queue.AddScope(scope)
+	if !queue.filter(task.NewTaskFromURL(u)) {
+		t.Errorf("Expected URL to be in added scope.")
+	}
+	u.Scheme = "https"
+	if !queue.filter(task.NewTaskFromURL(u)) {
+		t.Errorf("Expected https URL to be in added scope with upgrades.")
+	}
+}
+
+func TestWorkqueue_AcceptExternalLinks(t *testing.T) {
+	scope, _ := url.Parse("http://localhost/foo/")
+	queue := NewWorkQueue(5, []*url.URL{scope}, false)
+	queue.AcceptExternalLinks()
+	inScope, _ := url.Parse("http://localhost/foo/bar")
+	if tk := task.NewTaskFromURL(inScope); !queue.spiderFilter(tk) || tk.External {
+		t.Error("Expected URL in scope to be accepted as internal.")
+	}
+	external, _ := url.Parse("http://example.com/")
+	if queue.spiderFilter(task.NewTaskFromURL(external)) {
+		t.Error("Expected URL out of scope without a referrer to be rejected.")
+	}
+	linked := task.NewTaskFromURL(external)
+	linked.Referrer = inScope
+	if !queue.spiderFilter(linked) || !linked.External {
+		t.Error("Expected link from a page in scope to be accepted as external.")
+	}
+	if queue.filter(linked) {
+		t.Error("Expected external link to be rejected when not discovered.")
+	}
+	chained := task.NewTaskFromURL(external)
+	chained.Referrer, _ = url.Parse("http://example.org/")
+	if queue.spiderFilter(chained) {
+		t.Error("Expected link from a page out of scope to be rejected.")
+	}
+}
+
 func TestMakeScopeFunc(t *testing.T) {
 	// TODO: test multuple bases
 	urlParse := func(s string) *url.URL {
@@ -142,3 +186,22 @@ func TestMakeScopeFunc(t *testing.T) {
 		}
 	}
 }
+
+func BenchmarkWorkqueue(b *testing.B) {
+	queue := NewWorkQueue(1024, nil, false)
+	queue.filter = func(_ *task.Task) bool { return true }
+	queue.RunInBackground()
+	u := &url.URL{Path: "/"}
+	b.ResetTimer()
+	go func() {
+		for i := 0; i < b.N; i++ {
+			queue.AddTasks(task.NewTaskFromURL(u))
+		}
+		queue.InputFinished()
+	}()
+	done := queue.GetDoneFunc()
+	for range queue.GetWorkChan() {
+		done(1)
+	}
+	queue.WaitPipe()
+}